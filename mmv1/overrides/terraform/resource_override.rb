@@ -71,6 +71,10 @@ module Overrides
           # An array of function names that determine whether an error is retryable.
           :error_retry_predicates,
 
+          # An array of function names that determine whether an error of the
+          # delete request is retryable, in addition to error_retry_predicates.
+          :delete_error_retry_predicates,
+
           :schema_version,
 
           # If true, skip sweeper generation for this resource
@@ -120,6 +124,7 @@ module Overrides
 
         check :timeouts, type: Api::Timeouts
         check :error_retry_predicates, type: Array, item_type: String
+        check :delete_error_retry_predicates, type: Array, item_type: String
         check :schema_version, type: Integer
         check :skip_sweeper, type: :boolean, default: false
         check :skip_delete, type: :boolean, default: false
@@ -88,6 +88,19 @@ module Provider
       #       }
       attr_reader :test_vars_overrides
 
+      # Hash of vars replaced in tests by a shared fixture bootstrapped in the
+      # test project, instead of created and deleted by every test. The fixture
+      # is created by the first test needing it, see bootstrap_utils_test.go.
+      # If bootstrap_vars["network_name"] = "network"
+      #   - doc config will have `network = "my-vpc"`
+      #   - tests will replace with `"network = %{network_name}"` with context
+      #       map[string]interface{}{
+      #         "network_name": BootstrapSharedTestNetwork(t, "my-example"),
+      #         ...
+      #       }
+      # See BOOTSTRAP_FIXTURES for the fixtures available.
+      attr_reader :bootstrap_vars
+
       # Hash to provider custom override values for generating oics config
       # See test_vars_overrides for more details
       attr_reader :oics_vars_overrides
@@ -156,6 +169,28 @@ module Provider
       # your test so avoid if you can.
       attr_reader :pull_external
 
+      # The Go expressions returning the shared fixtures of bootstrap_vars,
+      # formatted with the id of the example, a valid resource name suffix
+      BOOTSTRAP_FIXTURES = {
+        'network' => 'BootstrapSharedTestNetwork(t, "%<id>s")',
+        'kms_key' => 'BootstrapKMSKey(t).CryptoKey.Name',
+        'kms_key_ring' => 'BootstrapKMSKey(t).KeyRing.Name',
+        'service_account' => 'BootstrapServiceAccount(t, getTestProjectFromEnv(), ' \
+                             'getTestServiceAccountFromEnv(t))'
+      }.freeze
+
+      # The test_vars_overrides of the example, including its bootstrap_vars
+      def test_vars_overrides_with_bootstrap
+        @test_vars_overrides ||= {}
+        @bootstrap_vars ||= {}
+        bootstrapped = bootstrap_vars.map do |k, fixture|
+          # Network names are limited to 63 characters, including the prefix
+          # of shared networks
+          [k, format(BOOTSTRAP_FIXTURES[fixture], id: name.tr('_', '-')[0...46])]
+        end
+        bootstrapped.to_h.merge(test_vars_overrides)
+      end
+
       def config_documentation(pwd)
         docs_defaults = {
           PROJECT_NAME: 'my-project-name',
@@ -255,7 +290,7 @@ module Provider
         end
 
         rand_vars = rand_vars.to_h
-        overrides = test_vars_overrides.map { |k, _| [k, "%{#{k}}"] }.to_h
+        overrides = test_vars_overrides_with_bootstrap.map { |k, _| [k, "%{#{k}}"] }.to_h
         body = lines(compile_file(
                        {
                          vars: rand_vars.merge(overrides),
@@ -338,6 +373,13 @@ module Provider
         check :vars, type: Hash
         check :test_env_vars, type: Hash
         check :test_vars_overrides, type: Hash
+        check :bootstrap_vars, type: Hash
+        @bootstrap_vars&.each do |var, fixture|
+          next if BOOTSTRAP_FIXTURES.key?(fixture)
+
+          raise "Unknown fixture #{fixture} bootstrapping #{var} in #{name}, " \
+                "expected one of #{BOOTSTRAP_FIXTURES.keys.join(', ')}"
+        end
         check :ignore_read_extra, type: Array, item_type: String, default: []
         check :primary_resource_name, type: String
         check :skip_test, type: TrueClass
@@ -252,6 +252,10 @@ module Provider
                         'third_party/terraform/utils/field_helpers.go'],
                        ['converters/google/resources/self_link_helpers.go',
                         'third_party/terraform/utils/self_link_helpers.go'],
+                       ['converters/google/resources/ip_field.go',
+                        'third_party/terraform/utils/ip_field.go'],
+                       ['converters/google/resources/duration_helpers.go',
+                        'third_party/terraform/utils/duration_helpers.go'],
                        ['converters/google/resources/header_transport.go',
                         'third_party/terraform/utils/header_transport.go'],
                        ['converters/google/resources/bigtable_client_factory.go',
@@ -290,10 +294,90 @@ module Provider
                         'third_party/terraform/utils/iam_folder.go'],
                        ['converters/google/resources/iam_project.go',
                         'third_party/terraform/utils/iam_project.go'],
+                       ['converters/google/resources/metadata_client.go',
+                        'third_party/terraform/utils/metadata_client.go'],
+                       ['converters/google/resources/config_resolver.go',
+                        'third_party/terraform/utils/config_resolver.go'],
+                       ['converters/google/resources/operation_metadata.go',
+                        'third_party/terraform/utils/operation_metadata.go'],
+                       ['converters/google/resources/not_found_cache.go',
+                        'third_party/terraform/utils/not_found_cache.go'],
+                       ['converters/google/resources/apply_report.go',
+                        'third_party/terraform/utils/apply_report.go'],
+                       ['converters/google/resources/operation_warnings.go',
+                        'third_party/terraform/utils/operation_warnings.go'],
+                       ['converters/google/resources/id_template.go',
+                        'third_party/terraform/utils/id_template.go'],
+                       ['converters/google/resources/mtls_util.go',
+                        'third_party/terraform/utils/mtls_util.go'],
+                       ['converters/google/resources/default_service_account.go',
+                        'third_party/terraform/utils/default_service_account.go'],
+                       ['converters/google/resources/operation_poll_schedule.go',
+                        'third_party/terraform/utils/operation_poll_schedule.go'],
+                       ['converters/google/resources/access_token_command.go',
+                        'third_party/terraform/utils/access_token_command.go'],
+                       ['converters/google/resources/googleapi_error_body.go',
+                        'third_party/terraform/utils/googleapi_error_body.go'],
+                       ['converters/google/resources/request_hooks.go',
+                        'third_party/terraform/utils/request_hooks.go'],
+                       ['converters/google/resources/available_zones.go',
+                        'third_party/terraform/utils/available_zones.go'],
+                       ['converters/google/resources/server_retry_delay.go',
+                        'third_party/terraform/utils/server_retry_delay.go'],
+                       ['converters/google/resources/request_headers.go',
+                        'third_party/terraform/utils/request_headers.go'],
+                       ['converters/google/resources/kms_key_ref.go',
+                        'third_party/terraform/utils/kms_key_ref.go'],
+                       ['converters/google/resources/resource_exists.go',
+                        'third_party/terraform/utils/resource_exists.go'],
+                       ['converters/google/resources/service_enablement.go',
+                        'third_party/terraform/utils/service_enablement.go'],
+                       ['converters/google/resources/clock.go',
+                        'third_party/terraform/utils/clock.go'],
+                       ['converters/google/resources/request_id.go',
+                        'third_party/terraform/utils/request_id.go'],
+                       ['converters/google/resources/fault_injection.go',
+                        'third_party/terraform/utils/fault_injection.go'],
+                       ['converters/google/resources/shared_vpc.go',
+                        'third_party/terraform/utils/shared_vpc.go'],
+                       ['converters/google/resources/operation_notifications.go',
+                        'third_party/terraform/utils/operation_notifications.go'],
+                       ['converters/google/resources/provider_features.go',
+                        'third_party/terraform/utils/provider_features.go'],
+                       ['converters/google/resources/project_guardrails.go',
+                        'third_party/terraform/utils/project_guardrails.go'],
+                       ['converters/google/resources/resource_warnings.go',
+                        'third_party/terraform/utils/resource_warnings.go'],
+                       ['converters/google/resources/aggregated_refresh.go',
+                        'third_party/terraform/utils/aggregated_refresh.go'],
+                       ['converters/google/resources/effective_policies.go',
+                        'third_party/terraform/utils/effective_policies.go'],
+                       ['converters/google/resources/retry_backoff.go',
+                        'third_party/terraform/utils/retry_backoff.go'],
+                       ['converters/google/resources/proxy_util.go',
+                        'third_party/terraform/utils/proxy_util.go'],
+                       ['converters/google/resources/retry_predicate.go',
+                        'third_party/terraform/utils/retry_predicate.go'],
+                       ['converters/google/resources/iam_member.go',
+                        'third_party/terraform/utils/iam_member.go'],
+                       ['converters/google/resources/resource_usage.go',
+                        'third_party/terraform/utils/resource_usage.go'],
+                       ['converters/google/resources/project_usable.go',
+                        'third_party/terraform/utils/project_usable.go'],
+                       ['converters/google/resources/compute_operation_poller.go',
+                        'third_party/terraform/utils/compute_operation_poller.go'],
                        ['converters/google/resources/privateca_utils.go',
                         'third_party/terraform/utils/privateca_utils.go'],
                        ['converters/google/resources/utils.go',
                         'third_party/terraform/utils/utils.go'],
+                       ['converters/google/resources/multi_error.go',
+                        'third_party/terraform/utils/multi_error.go'],
+                       ['converters/google/resources/config_overrides.go',
+                        'third_party/terraform/utils/config_overrides.go'],
+                       ['converters/google/resources/response_headers.go',
+                        'third_party/terraform/utils/response_headers.go'],
+                       ['converters/google/resources/iam_policy_cache.go',
+                        'third_party/terraform/utils/iam_policy_cache.go'],
                        ['converters/google/resources/iam_bigquery_dataset.go',
                         'third_party/terraform/utils/iam_bigquery_dataset.go'],
                        ['converters/google/resources/dcl_logger.go',
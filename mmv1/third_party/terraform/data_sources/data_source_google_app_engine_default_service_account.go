@@ -47,7 +47,10 @@ func dataSourceGoogleAppEngineDefaultServiceAccountRead(d *schema.ResourceData,
 		return err
 	}
 
-	serviceAccountEmail := fmt.Sprintf("%s@appspot.gserviceaccount.com", project)
+	serviceAccountEmail, err := getDefaultServiceAccount(config, userAgent, project, defaultServiceAccountAppEngine)
+	if err != nil {
+		return err
+	}
 
 	serviceAccountName, err := serviceAccountFQN(serviceAccountEmail, d, config)
 	if err != nil {
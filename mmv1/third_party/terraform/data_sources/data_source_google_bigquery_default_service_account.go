@@ -34,13 +34,13 @@ func dataSourceGoogleBigqueryDefaultServiceAccountRead(d *schema.ResourceData, m
 		return err
 	}
 
-	projectResource, err := config.NewBigQueryClient(userAgent).Projects.GetServiceAccount(project).Do()
+	email, err := getDefaultServiceAccount(config, userAgent, project, defaultServiceAccountBigQuery)
 	if err != nil {
 		return handleNotFoundError(err, d, "BigQuery service account not found")
 	}
 
-	d.SetId(projectResource.Email)
-	if err := d.Set("email", projectResource.Email); err != nil {
+	d.SetId(email)
+	if err := d.Set("email", email); err != nil {
 		return fmt.Errorf("Error setting email: %s", err)
 	}
 	if err := d.Set("project", project); err != nil {
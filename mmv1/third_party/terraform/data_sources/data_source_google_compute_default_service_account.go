@@ -46,12 +46,12 @@ func dataSourceGoogleComputeDefaultServiceAccountRead(d *schema.ResourceData, me
 		return err
 	}
 
-	projectCompResource, err := config.NewComputeClient(userAgent).Projects.Get(project).Do()
+	email, err := getDefaultServiceAccount(config, userAgent, project, defaultServiceAccountCompute)
 	if err != nil {
 		return handleNotFoundError(err, d, "GCE default service account")
 	}
 
-	serviceAccountName, err := serviceAccountFQN(projectCompResource.DefaultServiceAccount, d, config)
+	serviceAccountName, err := serviceAccountFQN(email, d, config)
 	if err != nil {
 		return err
 	}
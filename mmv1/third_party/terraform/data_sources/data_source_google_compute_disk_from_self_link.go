@@ -0,0 +1,9 @@
+package google
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGoogleComputeDiskFromSelfLink() *schema.Resource {
+	return dataSourceFromSelfLink(resourceComputeDisk(), "disks")
+}
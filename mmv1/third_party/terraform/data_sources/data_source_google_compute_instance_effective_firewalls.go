@@ -0,0 +1,71 @@
+package google
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGoogleComputeInstanceEffectiveFirewalls() *schema.Resource {
+	dsSchema := effectiveFirewallsSchema()
+	dsSchema["instance"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+	}
+	dsSchema["network_interface"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Default:  "nic0",
+	}
+	dsSchema["zone"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+	}
+	dsSchema["project"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+	}
+
+	return &schema.Resource{
+		Read:   dataSourceGoogleComputeInstanceEffectiveFirewallsRead,
+		Schema: dsSchema,
+	}
+}
+
+func dataSourceGoogleComputeInstanceEffectiveFirewallsRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	userAgent, err := generateUserAgentString(d, config.userAgent)
+	if err != nil {
+		return err
+	}
+
+	project, err := getProject(d, config)
+	if err != nil {
+		return err
+	}
+	zone, err := getZone(d, config)
+	if err != nil {
+		return err
+	}
+	instance := GetResourceNameFromSelfLink(d.Get("instance").(string))
+	networkInterface := d.Get("network_interface").(string)
+
+	firewalls, err := getInstanceEffectiveFirewalls(config, userAgent, project, zone, instance, networkInterface)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("project", project); err != nil {
+		return fmt.Errorf("Error setting project: %s", err)
+	}
+	if err := d.Set("zone", zone); err != nil {
+		return fmt.Errorf("Error setting zone: %s", err)
+	}
+	if err := setEffectivePolicyFields(d, flattenEffectiveFirewalls(firewalls)); err != nil {
+		return err
+	}
+	d.SetId(fmt.Sprintf("projects/%s/zones/%s/instances/%s/networkInterfaces/%s", project, zone, instance, networkInterface))
+	return nil
+}
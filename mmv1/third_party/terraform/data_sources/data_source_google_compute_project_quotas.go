@@ -0,0 +1,93 @@
+package google
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGoogleComputeProjectQuotas() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceGoogleComputeProjectQuotasRead,
+		Schema: map[string]*schema.Schema{
+			"project": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"region": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"quotas": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"metric": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"usage": {
+							Type:     schema.TypeFloat,
+							Computed: true,
+						},
+						"limit": {
+							Type:     schema.TypeFloat,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"readable": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceGoogleComputeProjectQuotasRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	userAgent, err := generateUserAgentString(d, config.userAgent)
+	if err != nil {
+		return err
+	}
+
+	project, err := getProject(d, config)
+	if err != nil {
+		return err
+	}
+	region := d.Get("region").(string)
+
+	quotas, readable, err := getProjectQuotas(config, userAgent, project, region)
+	if err != nil {
+		return err
+	}
+
+	flattened := make([]map[string]interface{}, 0, len(quotas))
+	for _, q := range quotas {
+		flattened = append(flattened, map[string]interface{}{
+			"metric": q.Metric,
+			"usage":  q.Usage,
+			"limit":  q.Limit,
+		})
+	}
+
+	if err := d.Set("project", project); err != nil {
+		return fmt.Errorf("Error setting project: %s", err)
+	}
+	if err := d.Set("quotas", flattened); err != nil {
+		return fmt.Errorf("Error setting quotas: %s", err)
+	}
+	if err := d.Set("readable", readable); err != nil {
+		return fmt.Errorf("Error setting readable: %s", err)
+	}
+	if region == "" {
+		d.SetId(fmt.Sprintf("projects/%s", project))
+	} else {
+		d.SetId(fmt.Sprintf("projects/%s/regions/%s", project, region))
+	}
+
+	return nil
+}
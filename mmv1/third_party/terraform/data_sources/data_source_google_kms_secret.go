@@ -1,12 +1,10 @@
 package google
 
 import (
-	"google.golang.org/api/cloudkms/v1"
-
-	"encoding/base64"
 	"fmt"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
 func dataSourceGoogleKmsSecret() *schema.Resource {
@@ -18,8 +16,9 @@ func dataSourceGoogleKmsSecret() *schema.Resource {
 				Required: true,
 			},
 			"ciphertext": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateBase64(kmsCiphertextMaxBytes),
 			},
 			"plaintext": {
 				Type:      schema.TypeString,
@@ -27,13 +26,18 @@ func dataSourceGoogleKmsSecret() *schema.Resource {
 				Sensitive: true,
 			},
 			"additional_authenticated_data": {
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateBase64(kmsCiphertextMaxBytes),
 			},
 		},
 	}
 }
 
+// The maximum size of the ciphertext and additional authenticated data of a
+// decrypt request.
+const kmsCiphertextMaxBytes = 64 * 1024
+
 func dataSourceGoogleKmsSecretRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 	userAgent, err := generateUserAgentString(d, config.userAgent)
@@ -49,24 +53,16 @@ func dataSourceGoogleKmsSecretRead(d *schema.ResourceData, meta interface{}) err
 
 	ciphertext := d.Get("ciphertext").(string)
 
-	kmsDecryptRequest := &cloudkms.DecryptRequest{
-		Ciphertext: ciphertext,
-	}
-
+	var opts kmsCryptoOptions
 	if aad, ok := d.GetOk("additional_authenticated_data"); ok {
-		kmsDecryptRequest.AdditionalAuthenticatedData = aad.(string)
-	}
-
-	decryptResponse, err := config.NewKmsClient(userAgent).Projects.Locations.KeyRings.CryptoKeys.Decrypt(cryptoKeyId.cryptoKeyId(), kmsDecryptRequest).Do()
-
-	if err != nil {
-		return fmt.Errorf("Error decrypting ciphertext: %s", err)
+		if opts.AAD, err = decodeBase64Field(aad.(string)); err != nil {
+			return fmt.Errorf("Error decoding additional_authenticated_data: %s", err)
+		}
 	}
 
-	plaintext, err := base64.StdEncoding.DecodeString(decryptResponse.Plaintext)
-
+	plaintext, err := kmsDecrypt(config, userAgent, cryptoKeyId, ciphertext, opts)
 	if err != nil {
-		return fmt.Errorf("Error decoding base64 response: %s", err)
+		return err
 	}
 
 	log.Printf("[INFO] Successfully decrypted ciphertext: %s", ciphertext)
@@ -1,9 +1,6 @@
 package google
 
 import (
-	"google.golang.org/api/cloudkms/v1"
-
-	"encoding/base64"
 	"fmt"
 	"log"
 
@@ -45,25 +42,14 @@ func dataSourceGoogleKmsSecretCiphertextRead(d *schema.ResourceData, meta interf
 		return err
 	}
 
-	plaintext := base64.StdEncoding.EncodeToString([]byte(d.Get("plaintext").(string)))
-
-	kmsEncryptRequest := &cloudkms.EncryptRequest{
-		Plaintext: plaintext,
-	}
-
-	encryptCall := config.NewKmsClient(userAgent).Projects.Locations.KeyRings.CryptoKeys.Encrypt(cryptoKeyId.cryptoKeyId(), kmsEncryptRequest)
-	if config.UserProjectOverride {
-		encryptCall.Header().Set("X-Goog-User-Project", cryptoKeyId.KeyRingId.Project)
-	}
-	encryptResponse, err := encryptCall.Do()
-
+	ciphertext, err := kmsEncrypt(config, userAgent, cryptoKeyId, []byte(d.Get("plaintext").(string)), kmsCryptoOptions{})
 	if err != nil {
-		return fmt.Errorf("Error encrypting plaintext: %s", err)
+		return err
 	}
 
 	log.Printf("[INFO] Successfully encrypted plaintext")
 
-	if err := d.Set("ciphertext", encryptResponse.Ciphertext); err != nil {
+	if err := d.Set("ciphertext", ciphertext); err != nil {
 		return fmt.Errorf("Error setting ciphertext: %s", err)
 	}
 	d.SetId(d.Get("crypto_key").(string))
@@ -0,0 +1,66 @@
+package google
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGoogleProjectBillingInfo() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceGoogleProjectBillingInfoRead,
+		Schema: map[string]*schema.Schema{
+			"project": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"billing_account": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"billing_enabled": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"readable": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceGoogleProjectBillingInfoRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	userAgent, err := generateUserAgentString(d, config.userAgent)
+	if err != nil {
+		return err
+	}
+
+	project, err := getProject(d, config)
+	if err != nil {
+		return err
+	}
+
+	info, err := getProjectBillingInfo(config, userAgent, project)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("project", project); err != nil {
+		return fmt.Errorf("Error setting project: %s", err)
+	}
+	if err := d.Set("billing_account", info.BillingAccount); err != nil {
+		return fmt.Errorf("Error setting billing_account: %s", err)
+	}
+	if err := d.Set("billing_enabled", info.BillingEnabled); err != nil {
+		return fmt.Errorf("Error setting billing_enabled: %s", err)
+	}
+	if err := d.Set("readable", info.Readable); err != nil {
+		return fmt.Errorf("Error setting readable: %s", err)
+	}
+	d.SetId(fmt.Sprintf("projects/%s/billingInfo", project))
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package google
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGoogleProjectEffectiveIamPolicy() *schema.Resource {
+	dsSchema := effectiveIamPolicySchema()
+	dsSchema["project"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+		Computed: true,
+	}
+
+	return &schema.Resource{
+		Read:   dataSourceGoogleProjectEffectiveIamPolicyRead,
+		Schema: dsSchema,
+	}
+}
+
+func dataSourceGoogleProjectEffectiveIamPolicyRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	userAgent, err := generateUserAgentString(d, config.userAgent)
+	if err != nil {
+		return err
+	}
+
+	project, err := getProject(d, config)
+	if err != nil {
+		return err
+	}
+
+	policy, err := getProjectEffectiveIamPolicy(config, userAgent, project)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("project", project); err != nil {
+		return fmt.Errorf("Error setting project: %s", err)
+	}
+	if err := setEffectivePolicyFields(d, flattenEffectiveIamPolicy(policy)); err != nil {
+		return err
+	}
+	d.SetId(fmt.Sprintf("projects/%s", project))
+	return nil
+}
@@ -2,12 +2,10 @@ package google
 
 import (
 	"fmt"
-	"log"
 
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	iamcredentials "google.golang.org/api/iamcredentials/v1"
 )
 
 func dataSourceGoogleServiceAccountAccessToken() *schema.Resource {
@@ -47,7 +45,7 @@ func dataSourceGoogleServiceAccountAccessToken() *schema.Resource {
 			"lifetime": {
 				Type:         schema.TypeString,
 				Optional:     true,
-				ValidateFunc: validateDuration(), // duration <=3600s; TODO: support validateDuration(min,max)
+				ValidateFunc: validateServiceAccountTokenLifetime,
 				Default:      "3600s",
 			},
 		},
@@ -61,23 +59,16 @@ func dataSourceGoogleServiceAccountAccessTokenRead(d *schema.ResourceData, meta
 		return err
 	}
 
-	log.Printf("[INFO] Acquire Service Account AccessToken for %s", d.Get("target_service_account").(string))
-
-	service := config.NewIamCredentialsClient(userAgent)
-
-	name := fmt.Sprintf("projects/-/serviceAccounts/%s", d.Get("target_service_account").(string))
-	tokenRequest := &iamcredentials.GenerateAccessTokenRequest{
-		Lifetime:  d.Get("lifetime").(string),
-		Delegates: convertStringSet(d.Get("delegates").(*schema.Set)),
-		Scope:     canonicalizeServiceScopes(convertStringSet(d.Get("scopes").(*schema.Set))),
-	}
-	at, err := service.Projects.ServiceAccounts.GenerateAccessToken(name, tokenRequest).Do()
+	target := d.Get("target_service_account").(string)
+	scopes := convertStringSet(d.Get("scopes").(*schema.Set))
+	delegates := convertStringSet(d.Get("delegates").(*schema.Set))
+	at, err := generateServiceAccountAccessToken(config, userAgent, target, scopes, delegates, d.Get("lifetime").(string))
 	if err != nil {
 		return err
 	}
 
-	d.SetId(name)
-	if err := d.Set("access_token", at.AccessToken); err != nil {
+	d.SetId(serviceAccountTokenName(target))
+	if err := d.Set("access_token", at.Value()); err != nil {
 		return fmt.Errorf("Error setting access_token: %s", err)
 	}
 
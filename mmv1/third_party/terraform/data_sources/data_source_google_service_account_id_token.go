@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	iamcredentials "google.golang.org/api/iamcredentials/v1"
 	"google.golang.org/api/idtoken"
 	"google.golang.org/api/option"
 
@@ -78,20 +77,15 @@ func dataSourceGoogleServiceAccountIdTokenRead(d *schema.ResourceData, meta inte
 	if creds.JSON == nil {
 		// Use
 		// https://cloud.google.com/iam/docs/reference/credentials/rest/v1/projects.serviceAccounts/generateIdToken
-		service := config.NewIamCredentialsClient(userAgent)
-		name := fmt.Sprintf("projects/-/serviceAccounts/%s", d.Get("target_service_account").(string))
-		tokenRequest := &iamcredentials.GenerateIdTokenRequest{
-			Audience:     targetAudience,
-			IncludeEmail: d.Get("include_email").(bool),
-			Delegates:    convertStringSet(d.Get("delegates").(*schema.Set)),
-		}
-		at, err := service.Projects.ServiceAccounts.GenerateIdToken(name, tokenRequest).Do()
+		target := d.Get("target_service_account").(string)
+		delegates := convertStringSet(d.Get("delegates").(*schema.Set))
+		token, err := generateServiceAccountIdToken(config, userAgent, target, targetAudience, delegates, d.Get("include_email").(bool))
 		if err != nil {
-			return fmt.Errorf("error calling iamcredentials.GenerateIdToken: %v", err)
+			return err
 		}
 
 		d.SetId(d.Get("target_service_account").(string))
-		if err := d.Set("id_token", at.Token); err != nil {
+		if err := d.Set("id_token", token.Value()); err != nil {
 			return fmt.Errorf("Error setting id_token: %s", err)
 		}
 
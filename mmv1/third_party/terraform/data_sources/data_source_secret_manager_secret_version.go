@@ -113,6 +113,8 @@ func dataSourceSecretManagerSecretVersionRead(d *schema.ResourceData, meta inter
 	url = fmt.Sprintf("%s:access", url)
 	resp, err := sendRequest(config, "GET", project, url, userAgent, nil)
 	if err != nil {
+		// Destroyed versions can't be restored
+		err = softDeletedAccessError(fmt.Sprintf("Secret version %s", version["name"]), "", err)
 		return fmt.Errorf("Error retrieving available secret manager secret version access: %s", err.Error())
 	}
 
@@ -202,10 +202,13 @@ func resourceBigtableGCPolicyUpsert(d *schema.ResourceData, meta interface{}) er
 	tableName := d.Get("table").(string)
 	columnFamily := d.Get("column_family").(string)
 
-	err = retryTimeDuration(func() error {
-		reqErr := c.SetGCPolicy(ctx, tableName, columnFamily, gcPolicy)
-		return reqErr
-	}, d.Timeout(schema.TimeoutCreate), isBigTableRetryableError)
+	err = retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			return c.SetGCPolicy(ctx, tableName, columnFamily, gcPolicy)
+		},
+		Timeout:         d.Timeout(schema.TimeoutCreate),
+		RetryPredicates: []RetryPredicate{bigtableTableStateRetryPredicate},
+	})
 	if err != nil {
 		return err
 	}
@@ -288,10 +291,13 @@ func resourceBigtableGCPolicyDestroy(d *schema.ResourceData, meta interface{}) e
 
 	defer c.Close()
 
-	err = retryTimeDuration(func() error {
-		reqErr := c.SetGCPolicy(ctx, d.Get("table").(string), d.Get("column_family").(string), bigtable.NoGcPolicy())
-		return reqErr
-	}, d.Timeout(schema.TimeoutDelete), isBigTableRetryableError)
+	err = retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			return c.SetGCPolicy(ctx, d.Get("table").(string), d.Get("column_family").(string), bigtable.NoGcPolicy())
+		},
+		Timeout:         d.Timeout(schema.TimeoutDelete),
+		RetryPredicates: []RetryPredicate{bigtableTableStateRetryPredicate},
+	})
 	if err != nil {
 		return err
 	}
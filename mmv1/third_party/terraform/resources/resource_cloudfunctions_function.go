@@ -86,7 +86,7 @@ func compareSelfLinkOrResourceNameWithMultipleParts(_, old, new string, _ *schem
 }
 
 func resourceCloudFunctionsFunction() *schema.Resource {
-	return &schema.Resource{
+	return withKmsKeyRefServiceAgentCheck(&schema.Resource{
 		Create: resourceCloudFunctionsCreate,
 		Read:   resourceCloudFunctionsRead,
 		Update: resourceCloudFunctionsUpdate,
@@ -96,6 +96,8 @@ func resourceCloudFunctionsFunction() *schema.Resource {
 			State: schema.ImportStatePassthrough,
 		},
 
+		CustomizeDiff: referenceExistsCustomizeDiff("kms_key_name", kmsCryptoKeyLink),
+
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(5 * time.Minute),
 			Read:   schema.DefaultTimeout(5 * time.Minute),
@@ -159,11 +161,7 @@ func resourceCloudFunctionsFunction() *schema.Resource {
 				Description: `User managed repository created in Artifact Registry optionally with a customer managed encryption key. If specified, deployments will use Artifact Registry for storing images built with Cloud Build.`,
 			},
 
-			"kms_key_name": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: `Resource name of a KMS crypto key (managed by the user) used to encrypt/decrypt function resources.`,
-			},
+			"kms_key_name": kmsKeyRefSchema(`Resource name of a KMS crypto key (managed by the user) used to encrypt/decrypt function resources.`),
 
 			"description": {
 				Type:        schema.TypeString,
@@ -419,7 +417,21 @@ func resourceCloudFunctionsFunction() *schema.Resource {
 			},
 		},
 		UseJSONNumber: true,
+	}, "kms_key_name", cloudFunctionsServiceAgent)
+}
+
+// cloudFunctionsServiceAgent returns the Cloud Functions service agent of the
+// function's project, which uses its KMS key.
+func cloudFunctionsServiceAgent(d kmsKeyRefData, config *Config) (string, error) {
+	project, err := kmsKeyRefProject(d, config)
+	if err != nil {
+		return "", err
+	}
+	p, err := config.NewResourceManagerClient(config.userAgent).Projects.Get(project).Do()
+	if err != nil {
+		return "", fmt.Errorf("Error reading project %s: %s", project, err)
 	}
+	return fmt.Sprintf("service-%d@gcf-admin-robot.iam.gserviceaccount.com", p.ProjectNumber), nil
 }
 
 func resourceCloudFunctionsCreate(d *schema.ResourceData, meta interface{}) error {
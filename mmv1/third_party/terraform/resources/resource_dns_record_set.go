@@ -1,6 +1,7 @@
 package google
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -32,6 +33,9 @@ func rrdatasDnsDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
 			return net.ParseIP(record).String()
 		case "MX", "DS":
 			return strings.ToLower(record)
+		case "CNAME", "NS", "PTR":
+			// names compare like fully qualified names
+			return strings.ToLower(strings.TrimSuffix(record, "."))
 		case "TXT":
 			return strings.ToLower(strings.Trim(record, `"`))
 		default:
@@ -68,6 +72,23 @@ func rrdatasListDiffSuppress(oldList, newList []string, fun func(x string) strin
 	return true
 }
 
+// PTR records are named by the reverse lookup name of an address, not the
+// address itself.
+func dnsRecordSetPtrNameCustomizeDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Get("type").(string) != "PTR" {
+		return nil
+	}
+	name := strings.TrimSuffix(d.Get("name").(string), ".")
+	if net.ParseIP(name) == nil {
+		return nil
+	}
+	ptrName, err := ptrRecordName(name)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("name %q of PTR record set is an IP address, use its reverse lookup name %q instead", d.Get("name"), ptrName)
+}
+
 func resourceDnsRecordSet() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceDnsRecordSetCreate,
@@ -78,6 +99,8 @@ func resourceDnsRecordSet() *schema.Resource {
 			State: resourceDnsRecordSetImportState,
 		},
 
+		CustomizeDiff: dnsRecordSetPtrNameCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"managed_zone": {
 				Type:             schema.TypeString,
@@ -132,14 +132,33 @@ func resourceGoogleProjectCreate(d *schema.ResourceData, meta interface{}) error
 	}
 
 	if _, ok := d.GetOk("labels"); ok {
-		project.Labels = expandLabels(d)
+		project.Labels, err = expandStringMapWithValidation(d, "labels", validateLabelKey, validateLabelValue)
+		if err != nil {
+			return err
+		}
 	}
 
 	var op *cloudresourcemanager.Operation
-	err = retryTimeDuration(func() (reqErr error) {
-		op, reqErr = config.NewResourceManagerClient(userAgent).Projects.Create(project).Do()
-		return reqErr
-	}, d.Timeout(schema.TimeoutCreate))
+	_, err = recoverOrRecreate(func() error {
+		return retryTimeDuration(func() (reqErr error) {
+			op, reqErr = config.NewResourceManagerClient(userAgent).Projects.Create(project).Do()
+			return reqErr
+		}, d.Timeout(schema.TimeoutCreate))
+	}, softDeleteRecovery{
+		Resource: fmt.Sprintf("project %q", pid),
+		// Project IDs of projects pending deletion are taken, as if they existed
+		IsSoftDeleted: func(err error) (bool, error) {
+			if !isGoogleApiErrorWithCode(err, 409) {
+				return false, nil
+			}
+			p, err := config.NewResourceManagerClient(userAgent).Projects.Get(pid).Do()
+			if err != nil {
+				return false, err
+			}
+			return p.LifecycleState == "DELETE_REQUESTED", nil
+		},
+		UndeleteHint: fmt.Sprintf("Undelete it with `gcloud projects undelete %s` and import it, or choose another project_id.", pid),
+	})
 	if err != nil {
 		return fmt.Errorf("error creating project %s (%s): %s. "+
 			"If you received a 403 error, make sure you have the"+
@@ -162,6 +181,11 @@ func resourceGoogleProjectCreate(d *schema.ResourceData, meta interface{}) error
 		return waitErr
 	}
 
+	// Requests in the project fail until it propagates
+	if err := waitForProjectUsable(config, pid, userAgent, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
+	}
+
 	// Set the billing account
 	if _, ok := d.GetOk("billing_account"); ok {
 		err = updateProjectBillingAccount(d, config, userAgent)
@@ -420,7 +444,10 @@ func resourceGoogleProjectUpdate(d *schema.ResourceData, meta interface{}) error
 
 	// Project Labels have changed
 	if ok := d.HasChange("labels"); ok {
-		p.Labels = expandLabels(d)
+		p.Labels, err = expandStringMapWithValidation(d, "labels", validateLabelKey, validateLabelValue)
+		if err != nil {
+			return err
+		}
 
 		// Do Update on project
 		if p, err = updateProject(config, d, project_name, userAgent, p); err != nil {
@@ -457,6 +484,7 @@ func resourceGoogleProjectDelete(d *schema.ResourceData, meta interface{}) error
 			_, delErr := config.NewResourceManagerClient(userAgent).Projects.Delete(pid).Do()
 			return delErr
 		}, d.Timeout(schema.TimeoutDelete)); err != nil {
+			_, err = recoverFailedPrecondition(d, err, fmt.Sprintf("Project %s", pid), projectLienRecovery)
 			return handleNotFoundError(err, d, fmt.Sprintf("Project %s", pid))
 		}
 	}
@@ -464,6 +492,14 @@ func resourceGoogleProjectDelete(d *schema.ResourceData, meta interface{}) error
 	return nil
 }
 
+// Liens prevent a project from being deleted until they're removed, usually by
+// whoever placed them.
+var projectLienRecovery = failedPreconditionRecovery{
+	ViolationType:   "LIEN",
+	MessageContains: "lien",
+	Guidance:        "The project has liens placed on it that prevent it from being deleted. List them with `gcloud alpha resource-manager liens list --project=PROJECT_ID` and remove them before deleting the project.",
+}
+
 func resourceProjectImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
 	parts := strings.Split(d.Id(), "/")
 	pid := parts[len(parts)-1]
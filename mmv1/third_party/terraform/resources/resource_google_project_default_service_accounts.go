@@ -149,6 +149,7 @@ func resourceGoogleProjectDefaultServiceAccountsCreate(d *schema.ResourceData, m
 		return fmt.Errorf("error listing service accounts on project %s: %v", pid, err)
 	}
 	changedServiceAccounts := make(map[string]interface{})
+	errs := &MultiError{}
 	for _, sa := range serviceAccounts {
 		// As per documentation https://cloud.google.com/iam/docs/service-accounts#default
 		// we have just two default SAs and the e-mail may change. So, it is been filtered
@@ -156,17 +157,20 @@ func resourceGoogleProjectDefaultServiceAccountsCreate(d *schema.ResourceData, m
 		if isDefaultServiceAccount(sa.DisplayName) {
 			err := resourceGoogleProjectDefaultServiceAccountsDoAction(d, meta, action, sa.UniqueId, sa.Email, pid)
 			if err != nil {
-				return fmt.Errorf("error doing action %s on Service Account %s: %v", action, sa.Email, err)
+				errs.Add(fmt.Sprintf("Service Account %s", sa.Email), fmt.Errorf("error doing action %s: %v", action, err))
+				continue
 			}
 			changedServiceAccounts[sa.UniqueId] = sa.Email
 		}
 	}
+	// The service accounts changed before a failure are kept in state, even
+	// though the create failed, so they're restored on delete.
 	if err := d.Set("service_accounts", changedServiceAccounts); err != nil {
 		return fmt.Errorf("error setting service_accounts: %s", err)
 	}
 	d.SetId(prefixedProject(pid))
 
-	return nil
+	return errs.ErrorOrNil()
 }
 
 func listServiceAccounts(config *Config, d *schema.ResourceData, userAgent string) ([]*iam.ServiceAccount, error) {
@@ -185,6 +189,8 @@ func resourceGoogleProjectDefaultServiceAccountsDelete(d *schema.ResourceData, m
 	}
 
 	pid := d.Get("project").(string)
+	errs := &MultiError{}
+	unrestored := make(map[string]interface{})
 	for saUniqueID, saEmail := range d.Get("service_accounts").(map[string]interface{}) {
 		origAction := d.Get("action").(string)
 		newAction := ""
@@ -198,10 +204,19 @@ func resourceGoogleProjectDefaultServiceAccountsDelete(d *schema.ResourceData, m
 		if newAction != "" {
 			err := resourceGoogleProjectDefaultServiceAccountsDoAction(d, meta, newAction, saUniqueID, saEmail.(string), pid)
 			if err != nil {
-				return fmt.Errorf("error doing action %s on Service Account %s: %v", newAction, saUniqueID, err)
+				errs.Add(fmt.Sprintf("Service Account %s", saUniqueID), fmt.Errorf("error doing action %s: %v", newAction, err))
+				unrestored[saUniqueID] = saEmail
 			}
 		}
 	}
+	if err := errs.ErrorOrNil(); err != nil {
+		// Only the service accounts that failed are restored again when the
+		// delete is retried.
+		if err := d.Set("service_accounts", unrestored); err != nil {
+			return fmt.Errorf("error setting service_accounts: %s", err)
+		}
+		return err
+	}
 
 	d.SetId("")
 
@@ -143,6 +143,14 @@ func resourceGoogleProjectServiceCreate(d *schema.ResourceData, meta interface{}
 	srv := d.Get("service").(string)
 	id := project + "/" + srv
 
+	userAgent, err := generateUserAgentString(d, config.userAgent)
+	if err != nil {
+		return err
+	}
+	if err := waitForCreateDependencyProject(config, project, userAgent); err != nil {
+		return err
+	}
+
 	// Check if the service has already been enabled
 	servicesRaw, err := BatchRequestReadServices(project, d, config)
 	if err != nil {
@@ -166,6 +174,13 @@ func resourceGoogleProjectServiceCreate(d *schema.ResourceData, meta interface{}
 		return err
 	}
 	d.SetId(id)
+
+	// Renamed services may have been enabled under their other name
+	if _, ok := renamedServicesByOldAndNewServiceNames[srv]; !ok {
+		if err := waitForServiceEnablementPropagation(config, userAgent, project, srv, d.Timeout(schema.TimeoutCreate)); err != nil {
+			return fmt.Errorf("Error waiting for service %s to be enabled on project %s: %s", srv, project, err)
+		}
+	}
 	return resourceGoogleProjectServiceRead(d, meta)
 }
 
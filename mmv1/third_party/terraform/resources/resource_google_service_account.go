@@ -21,6 +21,7 @@ func resourceGoogleServiceAccount() *schema.Resource {
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 		Schema: map[string]*schema.Schema{
 			"email": {
@@ -85,6 +86,9 @@ func resourceGoogleServiceAccountCreate(d *schema.ResourceData, meta interface{}
 	if err != nil {
 		return err
 	}
+	if err := waitForCreateDependencyProject(config, project, userAgent); err != nil {
+		return err
+	}
 	aid := d.Get("account_id").(string)
 	displayName := d.Get("display_name").(string)
 	description := d.Get("description").(string)
@@ -191,7 +195,10 @@ func resourceGoogleServiceAccountDelete(d *schema.ResourceData, meta interface{}
 		return err
 	}
 	name := d.Id()
-	_, err = config.NewIamClient(userAgent).Projects.ServiceAccounts.Delete(name).Do()
+	err = deleteWithDependentRetry(func() error {
+		_, err := config.NewIamClient(userAgent).Projects.ServiceAccounts.Delete(name).Do()
+		return err
+	}, d.Timeout(schema.TimeoutDelete))
 	if err != nil {
 		return err
 	}
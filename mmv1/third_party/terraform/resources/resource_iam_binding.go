@@ -4,12 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"regexp"
 	"strings"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"google.golang.org/api/cloudresourcemanager/v1"
 )
 
@@ -25,37 +23,13 @@ var iamBindingSchema = map[string]*schema.Schema{
 		Elem: &schema.Schema{
 			Type:             schema.TypeString,
 			DiffSuppressFunc: caseDiffSuppress,
-			ValidateFunc:     validation.StringDoesNotMatch(regexp.MustCompile("^deleted:"), "Terraform does not support IAM bindings for deleted principals"),
+			ValidateFunc:     validateIamMember,
 		},
 		Set: func(v interface{}) int {
 			return schema.HashString(strings.ToLower(v.(string)))
 		},
 	},
-	"condition": {
-		Type:     schema.TypeList,
-		Optional: true,
-		MaxItems: 1,
-		ForceNew: true,
-		Elem: &schema.Resource{
-			Schema: map[string]*schema.Schema{
-				"expression": {
-					Type:     schema.TypeString,
-					Required: true,
-					ForceNew: true,
-				},
-				"title": {
-					Type:     schema.TypeString,
-					Required: true,
-					ForceNew: true,
-				},
-				"description": {
-					Type:     schema.TypeString,
-					Optional: true,
-					ForceNew: true,
-				},
-			},
-		},
-	},
+	"condition": iamConditionSchema(true),
 	"etag": {
 		Type:     schema.TypeString,
 		Computed: true,
@@ -73,6 +47,15 @@ func ResourceIamBindingWithBatching(parentSpecificSchema map[string]*schema.Sche
 		o(settings)
 	}
 
+	s := mergeSchemas(iamBindingSchema, parentSpecificSchema)
+	if len(settings.SpecialMembers) > 0 {
+		members := *s["members"]
+		elem := *members.Elem.(*schema.Schema)
+		elem.ValidateFunc = iamMemberValidator(settings.SpecialMembers...)
+		members.Elem = &elem
+		s["members"] = &members
+	}
+
 	return &schema.Resource{
 		Create: resourceIamBindingCreateUpdate(newUpdaterFunc, enableBatching),
 		Read:   resourceIamBindingRead(newUpdaterFunc),
@@ -83,7 +66,7 @@ func ResourceIamBindingWithBatching(parentSpecificSchema map[string]*schema.Sche
 		// resource is used.
 		DeprecationMessage: settings.DeprecationMessage,
 
-		Schema: mergeSchemas(iamBindingSchema, parentSpecificSchema),
+		Schema: s,
 		Importer: &schema.ResourceImporter{
 			State: iamBindingImport(newUpdaterFunc, resourceIdParser),
 		},
@@ -111,7 +94,7 @@ func resourceIamBindingCreateUpdate(newUpdaterFunc newResourceIamUpdaterFunc, en
 			err = BatchRequestModifyIamPolicy(updater, modifyF, config, fmt.Sprintf(
 				"Set IAM Binding for role %q on %q", binding.Role, updater.DescribeResource()))
 		} else {
-			err = iamPolicyReadModifyWrite(updater, modifyF)
+			err = iamPolicyReadModifyWrite(updater, modifyF, config)
 		}
 		if err != nil {
 			return err
@@ -136,7 +119,7 @@ func resourceIamBindingRead(newUpdaterFunc newResourceIamUpdaterFunc) schema.Rea
 
 		eBinding := getResourceIamBinding(d)
 		eCondition := conditionKeyFromCondition(eBinding.Condition)
-		p, err := iamPolicyReadWithRetry(updater)
+		p, err := iamPolicyReadWithRetry(updater, config)
 		if err != nil {
 			return handleNotFoundError(err, d, fmt.Sprintf("Resource %q with IAM Binding (Role %q)", updater.DescribeResource(), eBinding.Role))
 		}
@@ -185,19 +168,18 @@ func iamBindingImport(newUpdaterFunc newResourceIamUpdaterFunc, resourceIdParser
 			return nil, errors.New("Import not supported for this IAM resource.")
 		}
 		config := m.(*Config)
-		s := strings.Fields(d.Id())
+		s := splitIamImportId(d.Id(), 3)
 		var id, role string
 		if len(s) < 2 {
 			d.SetId("")
-			return nil, fmt.Errorf("Wrong number of parts to Binding id %s; expected 'resource_name role [condition_title]'.", s)
+			return nil, fmt.Errorf("Wrong number of parts to Binding id %s; expected 'resource_name role [condition]'.", s)
 		}
 
 		var conditionTitle string
 		if len(s) == 2 {
 			id, role = s[0], s[1]
 		} else {
-			// condition titles can have any characters in them, so re-join the split string
-			id, role, conditionTitle = s[0], s[1], strings.Join(s[2:], " ")
+			id, role, conditionTitle = s[0], s[1], s[2]
 		}
 
 		// Set the ID only to the first part so all IAM types can share the same resourceIdParserFunc.
@@ -223,18 +205,13 @@ func iamBindingImport(newUpdaterFunc newResourceIamUpdaterFunc, resourceIdParser
 		if err != nil {
 			return nil, err
 		}
-		p, err := iamPolicyReadWithRetry(updater)
+		p, err := iamPolicyReadWithRetry(updater, config)
 		if err != nil {
 			return nil, err
 		}
-		var binding *cloudresourcemanager.Binding
-		for _, b := range p.Bindings {
-			if b.Role == role && conditionKeyFromCondition(b.Condition).Title == conditionTitle {
-				if binding != nil {
-					return nil, fmt.Errorf("Cannot import IAM member with condition title %q, it matches multiple conditions", conditionTitle)
-				}
-				binding = b
-			}
+		binding, err := findIamBindingForImport(p.Bindings, role, "", conditionTitle)
+		if err != nil {
+			return nil, err
 		}
 		if binding != nil {
 			if err := d.Set("condition", flattenIamCondition(binding.Condition)); err != nil {
@@ -278,7 +255,7 @@ func resourceIamBindingDelete(newUpdaterFunc newResourceIamUpdaterFunc, enableBa
 			err = BatchRequestModifyIamPolicy(updater, modifyF, config, fmt.Sprintf(
 				"Delete IAM Binding for role %q on %q", binding.Role, updater.DescribeResource()))
 		} else {
-			err = iamPolicyReadModifyWrite(updater, modifyF)
+			err = iamPolicyReadModifyWrite(updater, modifyF, config)
 		}
 		if err != nil {
 			return handleNotFoundError(err, d, fmt.Sprintf("Resource %q for IAM binding with role %q", updater.DescribeResource(), binding.Role))
@@ -299,30 +276,3 @@ func getResourceIamBinding(d *schema.ResourceData) *cloudresourcemanager.Binding
 	}
 	return b
 }
-
-func expandIamCondition(v interface{}) *cloudresourcemanager.Expr {
-	l := v.([]interface{})
-	if len(l) == 0 || l[0] == nil {
-		return nil
-	}
-	original := l[0].(map[string]interface{})
-	return &cloudresourcemanager.Expr{
-		Description:     original["description"].(string),
-		Expression:      original["expression"].(string),
-		Title:           original["title"].(string),
-		ForceSendFields: []string{"Description", "Expression", "Title"},
-	}
-}
-
-func flattenIamCondition(condition *cloudresourcemanager.Expr) []map[string]interface{} {
-	if conditionKeyFromCondition(condition).Empty() {
-		return nil
-	}
-	return []map[string]interface{}{
-		{
-			"expression":  condition.Expression,
-			"title":       condition.Title,
-			"description": condition.Description,
-		},
-	}
-}
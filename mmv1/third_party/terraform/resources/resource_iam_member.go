@@ -4,16 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"regexp"
 	"strings"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"google.golang.org/api/cloudresourcemanager/v1"
 )
 
 func iamMemberCaseDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	oldMember, oldErr := parseIamMember(old)
+	newMember, newErr := parseIamMember(new)
+	if oldErr == nil && newErr == nil {
+		return oldMember.normalized().String() == newMember.normalized().String()
+	}
 	isCaseSensitive := iamMemberIsCaseSensitive(old) || iamMemberIsCaseSensitive(new)
 	if isCaseSensitive {
 		return old == new
@@ -32,33 +35,9 @@ var IamMemberBaseSchema = map[string]*schema.Schema{
 		Required:         true,
 		ForceNew:         true,
 		DiffSuppressFunc: iamMemberCaseDiffSuppress,
-		ValidateFunc:     validation.StringDoesNotMatch(regexp.MustCompile("^deleted:"), "Terraform does not support IAM members for deleted principals"),
-	},
-	"condition": {
-		Type:     schema.TypeList,
-		Optional: true,
-		MaxItems: 1,
-		ForceNew: true,
-		Elem: &schema.Resource{
-			Schema: map[string]*schema.Schema{
-				"expression": {
-					Type:     schema.TypeString,
-					Required: true,
-					ForceNew: true,
-				},
-				"title": {
-					Type:     schema.TypeString,
-					Required: true,
-					ForceNew: true,
-				},
-				"description": {
-					Type:     schema.TypeString,
-					Optional: true,
-					ForceNew: true,
-				},
-			},
-		},
+		ValidateFunc:     validateIamMember,
 	},
+	"condition": iamConditionSchema(true),
 	"etag": {
 		Type:     schema.TypeString,
 		Computed: true,
@@ -71,19 +50,18 @@ func iamMemberImport(newUpdaterFunc newResourceIamUpdaterFunc, resourceIdParser
 			return nil, errors.New("Import not supported for this IAM resource.")
 		}
 		config := m.(*Config)
-		s := strings.Fields(d.Id())
+		s := splitIamImportId(d.Id(), 4)
 		var id, role, member string
 		if len(s) < 3 {
 			d.SetId("")
-			return nil, fmt.Errorf("Wrong number of parts to Member id %s; expected 'resource_name role member [condition_title]'.", s)
+			return nil, fmt.Errorf("Wrong number of parts to Member id %s; expected 'resource_name role member [condition]'.", s)
 		}
 
 		var conditionTitle string
 		if len(s) == 3 {
 			id, role, member = s[0], s[1], s[2]
 		} else {
-			// condition titles can have any characters in them, so re-join the split string
-			id, role, member, conditionTitle = s[0], s[1], s[2], strings.Join(s[3:], " ")
+			id, role, member, conditionTitle = s[0], s[1], s[2], s[3]
 		}
 
 		// Set the ID only to the first part so all IAM types can share the same resourceIdParserFunc.
@@ -109,28 +87,13 @@ func iamMemberImport(newUpdaterFunc newResourceIamUpdaterFunc, resourceIdParser
 		if err != nil {
 			return nil, err
 		}
-		p, err := iamPolicyReadWithRetry(updater)
+		p, err := iamPolicyReadWithRetry(updater, config)
 		if err != nil {
 			return nil, err
 		}
-		var binding *cloudresourcemanager.Binding
-		for _, b := range p.Bindings {
-			if b.Role == role && conditionKeyFromCondition(b.Condition).Title == conditionTitle {
-				containsMember := false
-				for _, m := range b.Members {
-					if strings.ToLower(m) == strings.ToLower(member) {
-						containsMember = true
-					}
-				}
-				if !containsMember {
-					continue
-				}
-
-				if binding != nil {
-					return nil, fmt.Errorf("Cannot import IAM member with condition title %q, it matches multiple conditions", conditionTitle)
-				}
-				binding = b
-			}
+		binding, err := findIamBindingForImport(p.Bindings, role, member, conditionTitle)
+		if err != nil {
+			return nil, err
 		}
 		if binding == nil {
 			return nil, fmt.Errorf("Cannot find binding for %q with role %q, member %q, and condition title %q", updater.DescribeResource(), role, member, conditionTitle)
@@ -157,6 +120,13 @@ func ResourceIamMemberWithBatching(parentSpecificSchema map[string]*schema.Schem
 		o(settings)
 	}
 
+	s := mergeSchemas(IamMemberBaseSchema, parentSpecificSchema)
+	if len(settings.SpecialMembers) > 0 {
+		member := *s["member"]
+		member.ValidateFunc = iamMemberValidator(settings.SpecialMembers...)
+		s["member"] = &member
+	}
+
 	return &schema.Resource{
 		Create: resourceIamMemberCreate(newUpdaterFunc, enableBatching),
 		Read:   resourceIamMemberRead(newUpdaterFunc),
@@ -166,7 +136,7 @@ func ResourceIamMemberWithBatching(parentSpecificSchema map[string]*schema.Schem
 		// resource is used.
 		DeprecationMessage: settings.DeprecationMessage,
 
-		Schema: mergeSchemas(IamMemberBaseSchema, parentSpecificSchema),
+		Schema: s,
 		Importer: &schema.ResourceImporter{
 			State: iamMemberImport(newUpdaterFunc, resourceIdParser),
 		},
@@ -205,7 +175,7 @@ func resourceIamMemberCreate(newUpdaterFunc newResourceIamUpdaterFunc, enableBat
 			err = BatchRequestModifyIamPolicy(updater, modifyF, config,
 				fmt.Sprintf("Create IAM Members %s %+v for %s", memberBind.Role, memberBind.Members[0], updater.DescribeResource()))
 		} else {
-			err = iamPolicyReadModifyWrite(updater, modifyF)
+			err = iamPolicyReadModifyWrite(updater, modifyF, config)
 		}
 		if err != nil {
 			return err
@@ -229,7 +199,7 @@ func resourceIamMemberRead(newUpdaterFunc newResourceIamUpdaterFunc) schema.Read
 
 		eMember := getResourceIamMember(d)
 		eCondition := conditionKeyFromCondition(eMember.Condition)
-		p, err := iamPolicyReadWithRetry(updater)
+		p, err := iamPolicyReadWithRetry(updater, config)
 		if err != nil {
 			return handleNotFoundError(err, d, fmt.Sprintf("Resource %q with IAM Member: Role %q Member %q", updater.DescribeResource(), eMember.Role, eMember.Members[0]))
 		}
@@ -299,7 +269,7 @@ func resourceIamMemberDelete(newUpdaterFunc newResourceIamUpdaterFunc, enableBat
 			err = BatchRequestModifyIamPolicy(updater, modifyF, config,
 				fmt.Sprintf("Delete IAM Members %s %s for %q", memberBind.Role, memberBind.Members[0], updater.DescribeResource()))
 		} else {
-			err = iamPolicyReadModifyWrite(updater, modifyF)
+			err = iamPolicyReadModifyWrite(updater, modifyF, config)
 		}
 		if err != nil {
 			return handleNotFoundError(err, d, fmt.Sprintf("Resource %s for IAM Member (role %q, %q)", updater.GetResourceId(), memberBind.Members[0], memberBind.Role))
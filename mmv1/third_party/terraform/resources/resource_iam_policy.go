@@ -70,7 +70,7 @@ func ResourceIamPolicyCreate(newUpdaterFunc newResourceIamUpdaterFunc) schema.Cr
 			return err
 		}
 
-		if err = setIamPolicyData(d, updater); err != nil {
+		if err = setIamPolicyData(d, updater, config); err != nil {
 			return err
 		}
 
@@ -88,7 +88,7 @@ func ResourceIamPolicyRead(newUpdaterFunc newResourceIamUpdaterFunc) schema.Read
 			return err
 		}
 
-		policy, err := iamPolicyReadWithRetry(updater)
+		policy, err := iamPolicyReadWithRetry(updater, config)
 		if err != nil {
 			return handleNotFoundError(err, d, fmt.Sprintf("Resource %q with IAM Policy", updater.DescribeResource()))
 		}
@@ -114,7 +114,7 @@ func ResourceIamPolicyUpdate(newUpdaterFunc newResourceIamUpdaterFunc) schema.Up
 		}
 
 		if d.HasChange("policy_data") {
-			if err := setIamPolicyData(d, updater); err != nil {
+			if err := setIamPolicyData(d, updater, config); err != nil {
 				return err
 			}
 		}
@@ -139,6 +139,7 @@ func ResourceIamPolicyDelete(newUpdaterFunc newResourceIamUpdaterFunc) schema.De
 		}
 		pol.Version = iamPolicyVersion
 		err = updater.SetResourceIamPolicy(pol)
+		config.iamPolicies.invalidate(iamPolicyCacheKey(updater))
 		if err != nil {
 			return err
 		}
@@ -147,7 +148,7 @@ func ResourceIamPolicyDelete(newUpdaterFunc newResourceIamUpdaterFunc) schema.De
 	}
 }
 
-func setIamPolicyData(d *schema.ResourceData, updater ResourceIamUpdater) error {
+func setIamPolicyData(d *schema.ResourceData, updater ResourceIamUpdater, config *Config) error {
 	policy, err := unmarshalIamPolicy(d.Get("policy_data").(string))
 	if err != nil {
 		return fmt.Errorf("'policy_data' is not valid for %s: %s", updater.DescribeResource(), err)
@@ -155,6 +156,7 @@ func setIamPolicyData(d *schema.ResourceData, updater ResourceIamUpdater) error
 	policy.Version = iamPolicyVersion
 
 	err = updater.SetResourceIamPolicy(policy)
+	config.iamPolicies.invalidate(iamPolicyCacheKey(updater))
 	if err != nil {
 		return err
 	}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"regexp"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -44,8 +43,41 @@ var loggingBucketConfigSchema = map[string]*schema.Schema{
 		Computed:    true,
 		Description: `The bucket's lifecycle such as active or deleted.`,
 	},
+	"cmek_settings": {
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Description: `The CMEK settings of the log bucket. If present, new log entries written to this log bucket are encrypted using the CMEK key provided in this configuration. If a log bucket has CMEK settings, the CMEK settings cannot be disabled later by updating the log bucket. Changing the KMS key is allowed.`,
+		Elem: &schema.Resource{
+			Schema: loggingBucketConfigCmekSettingsSchema,
+		},
+	},
 }
 
+// loggingBucketConfigCmekSettingsFields makes every field of cmek_settings
+// but kms_key_name output only.
+var loggingBucketConfigCmekSettingsFields = outputOnlyFields{Allow: []string{"kms_key_name"}}
+
+var loggingBucketConfigCmekSettingsSchema = loggingBucketConfigCmekSettingsFields.schema(map[string]*schema.Schema{
+	"name": {
+		Type:        schema.TypeString,
+		Description: `The resource name of the CMEK settings.`,
+	},
+	"kms_key_name": {
+		Type:        schema.TypeString,
+		Required:    true,
+		Description: `The resource name for the configured Cloud KMS key. The Cloud Logging service account of the bucket's parent must have the cloudkms.cryptoKeyEncrypterDecrypter role on the key.`,
+	},
+	"kms_key_version_name": {
+		Type:        schema.TypeString,
+		Description: `The CryptoKeyVersion resource name for the configured Cloud KMS key, the primary version of the key at the time of encryption.`,
+	},
+	"service_account_id": {
+		Type:        schema.TypeString,
+		Description: `The service account associated with a project for which CMEK will apply.`,
+	},
+})
+
 type loggingBucketConfigIDFunc func(d *schema.ResourceData, config *Config) (string, error)
 
 // ResourceLoggingBucketConfig creates a resource definition by merging a unique field (eg: folder) to a generic logging bucket
@@ -154,6 +186,13 @@ func resourceLoggingBucketConfigCreate(d *schema.ResourceData, meta interface{},
 	obj["description"] = d.Get("description")
 	obj["retentionDays"] = d.Get("retention_days")
 	obj["locked"] = d.Get("locked")
+	cmekSettings, err := expandLoggingBucketConfigCmekSettings(d.Get("cmek_settings"))
+	if err != nil {
+		return err
+	}
+	if cmekSettings != nil {
+		obj["cmekSettings"] = cmekSettings
+	}
 
 	url, err := replaceVars(d, config, "{{LoggingBasePath}}projects/{{project}}/locations/{{location}}/buckets?bucketId={{bucket_id}}")
 	if err != nil {
@@ -220,6 +259,9 @@ func resourceLoggingBucketConfigRead(d *schema.ResourceData, meta interface{}) e
 	if err := d.Set("retention_days", res["retentionDays"]); err != nil {
 		return fmt.Errorf("Error setting retention_days: %s", err)
 	}
+	if err := d.Set("cmek_settings", flattenLoggingBucketConfigCmekSettings(res["cmekSettings"], d)); err != nil {
+		return fmt.Errorf("Error setting cmek_settings: %s", err)
+	}
 
 	return nil
 }
@@ -240,15 +282,20 @@ func resourceLoggingBucketConfigUpdate(d *schema.ResourceData, meta interface{})
 
 	obj["retentionDays"] = d.Get("retention_days")
 	obj["description"] = d.Get("description")
-
-	updateMask := []string{}
-	if d.HasChange("retention_days") {
-		updateMask = append(updateMask, "retentionDays")
+	cmekSettings, err := expandLoggingBucketConfigCmekSettings(d.Get("cmek_settings"))
+	if err != nil {
+		return err
 	}
-	if d.HasChange("description") {
-		updateMask = append(updateMask, "description")
+	if cmekSettings != nil {
+		obj["cmekSettings"] = cmekSettings
 	}
-	url, err = addQueryParams(url, map[string]string{"updateMask": strings.Join(updateMask, ",")})
+
+	updateMask := buildUpdateMask(d, map[string]string{
+		"retention_days": "",
+		"description":    "",
+		"cmek_settings":  "",
+	})
+	url, err = addQueryParams(url, map[string]string{"updateMask": updateMask})
 	if err != nil {
 		return err
 	}
@@ -284,3 +331,18 @@ func resourceLoggingBucketConfigDelete(d *schema.ResourceData, meta interface{})
 	}
 	return nil
 }
+
+func expandLoggingBucketConfigCmekSettings(v interface{}) (map[string]interface{}, error) {
+	obj, err := expandObject(v, loggingBucketConfigCmekSettingsSchema, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The output only fields in state are removed, as the API rejects them
+	return loggingBucketConfigCmekSettingsFields.expand(obj), nil
+}
+
+func flattenLoggingBucketConfigCmekSettings(v interface{}, d *schema.ResourceData) []interface{} {
+	return loggingBucketConfigCmekSettingsFields.flatten(v, d, "cmek_settings", func(v map[string]interface{}) map[string]interface{} {
+		return flattenObjectFields(v, loggingBucketConfigCmekSettingsSchema, nil)
+	})
+}
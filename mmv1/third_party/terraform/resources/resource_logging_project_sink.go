@@ -6,10 +6,15 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/logging/v2"
 )
 
 const nonUniqueWriterAccount = "serviceAccount:cloud-logs@system.gserviceaccount.com"
 
+// Sinks have no etag, so out of band changes are detected with a fingerprint
+// of the sink, ignoring the timestamps the API sets.
+var loggingProjectSinkFingerprintIgnoredFields = []string{"createTime", "updateTime"}
+
 func resourceLoggingProjectSink() *schema.Resource {
 	schm := &schema.Resource{
 		Create:        resourceLoggingProjectSinkCreate,
@@ -37,6 +42,7 @@ func resourceLoggingProjectSink() *schema.Resource {
 		ForceNew:    true,
 		Description: `Whether or not to create a unique identity associated with this sink. If false (the default), then the writer_identity used is serviceAccount:cloud-logs@system.gserviceaccount.com. If true, then a unique service account is created and used for this sink. If you wish to publish logs across projects, you must set unique_writer_identity to true.`,
 	}
+	schm.Schema[contentFingerprintField] = contentFingerprintSchema()
 	return schm
 }
 
@@ -103,6 +109,12 @@ func resourceLoggingProjectSinkRead(d *schema.ResourceData, meta interface{}) er
 		return handleNotFoundError(err, d, fmt.Sprintf("Project Logging Sink %s", d.Get("name").(string)))
 	}
 
+	return setLoggingProjectSinkState(d, project, sink)
+}
+
+// setLoggingProjectSinkState sets the state of the sink from the API's
+// representation of it, as read or returned by an update.
+func setLoggingProjectSinkState(d *schema.ResourceData, project string, sink *logging.LogSink) error {
 	if err := d.Set("project", project); err != nil {
 		return fmt.Errorf("Error setting project: %s", err)
 	}
@@ -111,6 +123,14 @@ func resourceLoggingProjectSinkRead(d *schema.ResourceData, meta interface{}) er
 		return err
 	}
 
+	obj, err := ConvertToMap(sink)
+	if err != nil {
+		return err
+	}
+	if err := setContentFingerprint(d, obj, loggingProjectSinkFingerprintIgnoredFields...); err != nil {
+		return err
+	}
+
 	if sink.WriterIdentity != nonUniqueWriterAccount {
 		if err := d.Set("unique_writer_identity", true); err != nil {
 			return fmt.Errorf("Error setting unique_writer_identity: %s", err)
@@ -130,16 +150,35 @@ func resourceLoggingProjectSinkUpdate(d *schema.ResourceData, meta interface{})
 		return err
 	}
 
+	client := config.NewLoggingClient(userAgent)
+	current, err := client.Projects.Sinks.Get(d.Id()).Do()
+	if err != nil {
+		return err
+	}
+	obj, err := ConvertToMap(current)
+	if err != nil {
+		return err
+	}
+	if err := checkContentFingerprint(d, config, obj, loggingProjectSinkFingerprintIgnoredFields...); err != nil {
+		return err
+	}
+
 	sink, updateMask := expandResourceLoggingSinkForUpdate(d)
 	uniqueWriterIdentity := d.Get("unique_writer_identity").(bool)
 
-	_, err = config.NewLoggingClient(userAgent).Projects.Sinks.Patch(d.Id(), sink).
+	updated, err := client.Projects.Sinks.Patch(d.Id(), sink).
 		UpdateMask(updateMask).UniqueWriterIdentity(uniqueWriterIdentity).Do()
 	if err != nil {
 		return err
 	}
 
-	return resourceLoggingProjectSinkRead(d, meta)
+	// The sink was read before the update, so the updated sink is used
+	// rather than reading it again.
+	project, err := getProject(d, config)
+	if err != nil {
+		return err
+	}
+	return setLoggingProjectSinkState(d, project, updated)
 }
 
 func resourceLoggingProjectSinkDelete(d *schema.ResourceData, meta interface{}) error {
@@ -2,13 +2,16 @@ package google
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"google.golang.org/api/logging/v2"
 )
 
+// Exclusions are identified by their name, reordering them isn't a change
+var loggingSinkExclusionsEqual = unorderedListComparator(semanticKeyField("name"))
+
 func resourceLoggingSinkSchema() map[string]*schema.Schema {
+	exclusionsDiffSuppress := semanticDiffSuppress("exclusions", loggingSinkExclusionsEqual)
 	return map[string]*schema.Schema{
 		"name": {
 			Type:        schema.TypeString,
@@ -49,25 +52,29 @@ func resourceLoggingSinkSchema() map[string]*schema.Schema {
 			Elem: &schema.Resource{
 				Schema: map[string]*schema.Schema{
 					"name": {
-						Type:        schema.TypeString,
-						Required:    true,
-						Description: `A client-assigned identifier, such as "load-balancer-exclusion". Identifiers are limited to 100 characters and can include only letters, digits, underscores, hyphens, and periods. First character has to be alphanumeric.`,
+						Type:             schema.TypeString,
+						Required:         true,
+						DiffSuppressFunc: exclusionsDiffSuppress,
+						Description:      `A client-assigned identifier, such as "load-balancer-exclusion". Identifiers are limited to 100 characters and can include only letters, digits, underscores, hyphens, and periods. First character has to be alphanumeric.`,
 					},
 					"description": {
-						Type:        schema.TypeString,
-						Optional:    true,
-						Description: `A description of this exclusion.`,
+						Type:             schema.TypeString,
+						Optional:         true,
+						DiffSuppressFunc: exclusionsDiffSuppress,
+						Description:      `A description of this exclusion.`,
 					},
 					"filter": {
-						Type:        schema.TypeString,
-						Required:    true,
-						Description: `An advanced logs filter that matches the log entries to be excluded. By using the sample function, you can exclude less than 100% of the matching log entries`,
+						Type:             schema.TypeString,
+						Required:         true,
+						DiffSuppressFunc: exclusionsDiffSuppress,
+						Description:      `An advanced logs filter that matches the log entries to be excluded. By using the sample function, you can exclude less than 100% of the matching log entries`,
 					},
 					"disabled": {
-						Type:        schema.TypeBool,
-						Optional:    true,
-						Default:     false,
-						Description: `If set to True, then this exclusion is disabled and it does not exclude any log entries`,
+						Type:             schema.TypeBool,
+						Optional:         true,
+						Default:          false,
+						DiffSuppressFunc: exclusionsDiffSuppress,
+						Description:      `If set to True, then this exclusion is disabled and it does not exclude any log entries`,
 					},
 				},
 			},
@@ -157,28 +164,22 @@ func expandResourceLoggingSinkForUpdate(d *schema.ResourceData) (sink *logging.L
 		ForceSendFields: []string{"Destination", "Filter", "Disabled"},
 	}
 
-	updateFields := []string{}
-	if d.HasChange("destination") {
-		updateFields = append(updateFields, "destination")
-	}
-	if d.HasChange("filter") {
-		updateFields = append(updateFields, "filter")
-	}
-	if d.HasChange("description") {
-		updateFields = append(updateFields, "description")
-	}
-	if d.HasChange("disabled") {
-		updateFields = append(updateFields, "disabled")
-	}
-	if d.HasChange("exclusions") {
+	if hasSemanticChange(d, "exclusions", loggingSinkExclusionsEqual) {
 		sink.Exclusions = expandLoggingSinkExclusions(d.Get("exclusions"))
-		updateFields = append(updateFields, "exclusions")
 	}
 	if d.HasChange("bigquery_options") {
 		sink.BigqueryOptions = expandLoggingSinkBigqueryOptions(d.Get("bigquery_options"))
-		updateFields = append(updateFields, "bigqueryOptions")
 	}
-	updateMask = strings.Join(updateFields, ",")
+	updateMask = buildUpdateMaskWithComparators(d, map[string]string{
+		"destination":      "",
+		"filter":           "",
+		"description":      "",
+		"disabled":         "",
+		"exclusions":       "",
+		"bigquery_options": "",
+	}, map[string]semanticComparator{
+		"exclusions": loggingSinkExclusionsEqual,
+	})
 	return
 }
 
@@ -110,19 +110,27 @@ func resourceServiceNetworkingConnectionCreate(d *schema.ResourceData, meta inte
 		project = bp
 	}
 
-	createCall := config.NewServiceNetworkingClient(userAgent).Services.Connections.Patch(parentService+"/connections/-", connection).UpdateMask("reservedPeeringRanges").Force(true)
-	if config.UserProjectOverride {
-		createCall.Header().Add("X-Goog-User-Project", project)
-	}
-	op, err := createCall.Do()
+	mutexKey := networkPeeringMutexKey(networkFieldValue.Project, networkFieldValue.Name)
+	mutexKV.Lock(mutexKey)
+	defer mutexKV.Unlock(mutexKey)
+
+	// The operation may fail because of a concurrent change too, so both
+	// the request and the operation are retried
+	err = retryTimeDuration(func() error {
+		createCall := config.NewServiceNetworkingClient(userAgent).Services.Connections.Patch(parentService+"/connections/-", connection).UpdateMask("reservedPeeringRanges").Force(true)
+		if config.UserProjectOverride {
+			createCall.Header().Add("X-Goog-User-Project", project)
+		}
+		op, err := createCall.Do()
+		if err != nil {
+			return err
+		}
+		return serviceNetworkingOperationWaitTime(config, op, "Create Service Networking Connection", userAgent, project, d.Timeout(schema.TimeoutCreate))
+	}, d.Timeout(schema.TimeoutCreate), isServiceNetworkingOperationInProgressError)
 	if err != nil {
 		return err
 	}
 
-	if err := serviceNetworkingOperationWaitTime(config, op, "Create Service Networking Connection", userAgent, project, d.Timeout(schema.TimeoutCreate)); err != nil {
-		return err
-	}
-
 	connectionId := &connectionId{
 		Network: network,
 		Service: d.Get("service").(string),
@@ -194,7 +202,8 @@ func resourceServiceNetworkingConnectionRead(d *schema.ResourceData, meta interf
 	if err := d.Set("peering", connection.Peering); err != nil {
 		return fmt.Errorf("Error setting peering: %s", err)
 	}
-	if err := d.Set("reserved_peering_ranges", connection.ReservedPeeringRanges); err != nil {
+	ranges := reconcileReservedPeeringRanges(convertStringArr(d.Get("reserved_peering_ranges").([]interface{})), connection.ReservedPeeringRanges)
+	if err := d.Set("reserved_peering_ranges", ranges); err != nil {
 		return fmt.Errorf("Error setting reserved_peering_ranges: %s", err)
 	}
 	return nil
@@ -240,17 +249,24 @@ func resourceServiceNetworkingConnectionUpdate(d *schema.ResourceData, meta inte
 			project = bp
 		}
 
-		patchCall := config.NewServiceNetworkingClient(userAgent).Services.Connections.Patch(parentService+"/connections/-", connection).UpdateMask("reservedPeeringRanges").Force(true)
-		if config.UserProjectOverride {
-			patchCall.Header().Add("X-Goog-User-Project", project)
-		}
-		op, err := patchCall.Do()
+		mutexKey := networkPeeringMutexKey(networkFieldValue.Project, networkFieldValue.Name)
+		mutexKV.Lock(mutexKey)
+		defer mutexKV.Unlock(mutexKey)
+
+		err = retryTimeDuration(func() error {
+			patchCall := config.NewServiceNetworkingClient(userAgent).Services.Connections.Patch(parentService+"/connections/-", connection).UpdateMask("reservedPeeringRanges").Force(true)
+			if config.UserProjectOverride {
+				patchCall.Header().Add("X-Goog-User-Project", project)
+			}
+			op, err := patchCall.Do()
+			if err != nil {
+				return err
+			}
+			return serviceNetworkingOperationWaitTime(config, op, "Update Service Networking Connection", userAgent, project, d.Timeout(schema.TimeoutUpdate))
+		}, d.Timeout(schema.TimeoutUpdate), isServiceNetworkingOperationInProgressError)
 		if err != nil {
 			return err
 		}
-		if err := serviceNetworkingOperationWaitTime(config, op, "Update Service Networking Connection", userAgent, project, d.Timeout(schema.TimeoutUpdate)); err != nil {
-			return err
-		}
 	}
 	return resourceServiceNetworkingConnectionRead(d, meta)
 }
@@ -279,7 +295,11 @@ func resourceServiceNetworkingConnectionDelete(d *schema.ResourceData, meta inte
 	}
 
 	project := networkFieldValue.Project
-	res, err := sendRequestWithTimeout(config, "POST", project, url, userAgent, obj, d.Timeout(schema.TimeoutDelete))
+	mutexKey := networkPeeringMutexKey(project, networkFieldValue.Name)
+	mutexKV.Lock(mutexKey)
+	defer mutexKV.Unlock(mutexKey)
+
+	res, err := sendRequestWithTimeout(config, "POST", project, url, userAgent, obj, d.Timeout(schema.TimeoutDelete), isPeeringOperationInProgress)
 	if err != nil {
 		return handleNotFoundError(err, d, fmt.Sprintf("ServiceNetworkingConnection %q", d.Id()))
 	}
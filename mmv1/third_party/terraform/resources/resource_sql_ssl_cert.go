@@ -111,9 +111,13 @@ func resourceSqlSslCertCreate(d *schema.ResourceData, meta interface{}) error {
 		CommonName: commonName,
 	}
 
-	mutexKV.Lock(instanceMutexKey(project, instance))
-	defer mutexKV.Unlock(instanceMutexKey(project, instance))
-	resp, err := config.NewSqlAdminClient(userAgent).SslCerts.Insert(project, instance, sslCertsInsertRequest).Do()
+	mutexKV.Lock(sqlInstanceMutexKey(project, instance))
+	defer mutexKV.Unlock(sqlInstanceMutexKey(project, instance))
+	var resp *sqladmin.SslCertsInsertResponse
+	err = retryTimeDuration(func() (rerr error) {
+		resp, rerr = config.NewSqlAdminClient(userAgent).SslCerts.Insert(project, instance, sslCertsInsertRequest).Do()
+		return rerr
+	}, d.Timeout(schema.TimeoutCreate), isSqlOperationInProgressError)
 	if err != nil {
 		return fmt.Errorf("Error, failed to insert "+
 			"ssl cert %s into instance %s: %s", commonName, instance, err)
@@ -170,29 +174,18 @@ func resourceSqlSslCertRead(d *schema.ResourceData, meta interface{}) error {
 		return nil
 	}
 
-	if err := d.Set("instance", sslCerts.Instance); err != nil {
-		return fmt.Errorf("Error setting instance: %s", err)
-	}
-	if err := d.Set("project", project); err != nil {
-		return fmt.Errorf("Error setting project: %s", err)
-	}
-	if err := d.Set("sha1_fingerprint", sslCerts.Sha1Fingerprint); err != nil {
-		return fmt.Errorf("Error setting sha1_fingerprint: %s", err)
-	}
-	if err := d.Set("common_name", sslCerts.CommonName); err != nil {
-		return fmt.Errorf("Error setting common_name: %s", err)
-	}
-	if err := d.Set("cert", sslCerts.Cert); err != nil {
-		return fmt.Errorf("Error setting cert: %s", err)
-	}
-	if err := d.Set("cert_serial_number", sslCerts.CertSerialNumber); err != nil {
-		return fmt.Errorf("Error setting cert_serial_number: %s", err)
-	}
-	if err := d.Set("create_time", sslCerts.CreateTime); err != nil {
-		return fmt.Errorf("Error setting create_time: %s", err)
-	}
-	if err := d.Set("expiration_time", sslCerts.ExpirationTime); err != nil {
-		return fmt.Errorf("Error setting expiration_time: %s", err)
+	s := NewStateSetter(d)
+	s.Set("instance", sslCerts.Instance)
+	s.Set("project", project)
+	s.Set("sha1_fingerprint", sslCerts.Sha1Fingerprint)
+	s.Set("common_name", sslCerts.CommonName)
+	s.Set("cert", sslCerts.Cert)
+	s.Set("cert_serial_number", sslCerts.CertSerialNumber)
+	s.Set("create_time", sslCerts.CreateTime)
+	s.Set("expiration_time", sslCerts.ExpirationTime)
+
+	if err := s.Flush(); err != nil {
+		return err
 	}
 
 	d.SetId(fmt.Sprintf("projects/%s/instances/%s/sslCerts/%s", project, instance, fingerprint))
@@ -215,9 +208,13 @@ func resourceSqlSslCertDelete(d *schema.ResourceData, meta interface{}) error {
 	commonName := d.Get("common_name").(string)
 	fingerprint := d.Get("sha1_fingerprint").(string)
 
-	mutexKV.Lock(instanceMutexKey(project, instance))
-	defer mutexKV.Unlock(instanceMutexKey(project, instance))
-	op, err := config.NewSqlAdminClient(userAgent).SslCerts.Delete(project, instance, fingerprint).Do()
+	mutexKV.Lock(sqlInstanceMutexKey(project, instance))
+	defer mutexKV.Unlock(sqlInstanceMutexKey(project, instance))
+	var op *sqladmin.Operation
+	err = retryTimeDuration(func() (rerr error) {
+		op, rerr = config.NewSqlAdminClient(userAgent).SslCerts.Delete(project, instance, fingerprint).Do()
+		return rerr
+	}, d.Timeout(schema.TimeoutDelete), isSqlOperationInProgressError)
 
 	if err != nil {
 		return fmt.Errorf("Error, failed to delete "+
@@ -176,15 +176,15 @@ func resourceSqlUserCreate(d *schema.ResourceData, meta interface{}) error {
 		user.SqlserverUserDetails = ssud
 	}
 
-	mutexKV.Lock(instanceMutexKey(project, instance))
-	defer mutexKV.Unlock(instanceMutexKey(project, instance))
+	mutexKV.Lock(sqlInstanceMutexKey(project, instance))
+	defer mutexKV.Unlock(sqlInstanceMutexKey(project, instance))
 	var op *sqladmin.Operation
 	insertFunc := func() error {
 		op, err = config.NewSqlAdminClient(userAgent).Users.Insert(project, instance,
 			user).Do()
 		return err
 	}
-	err = retryTimeDuration(insertFunc, d.Timeout(schema.TimeoutCreate))
+	err = retryTimeDuration(insertFunc, d.Timeout(schema.TimeoutCreate), isSqlOperationInProgressError)
 
 	if err != nil {
 		return fmt.Errorf("Error, failed to insert "+
@@ -318,14 +318,14 @@ func resourceSqlUserUpdate(d *schema.ResourceData, meta interface{}) error {
 			user.SqlserverUserDetails = ssud
 		}
 
-		mutexKV.Lock(instanceMutexKey(project, instance))
-		defer mutexKV.Unlock(instanceMutexKey(project, instance))
+		mutexKV.Lock(sqlInstanceMutexKey(project, instance))
+		defer mutexKV.Unlock(sqlInstanceMutexKey(project, instance))
 		var op *sqladmin.Operation
 		updateFunc := func() error {
 			op, err = config.NewSqlAdminClient(userAgent).Users.Update(project, instance, user).Host(host).Name(name).Do()
 			return err
 		}
-		err = retryTimeDuration(updateFunc, d.Timeout(schema.TimeoutUpdate))
+		err = retryTimeDuration(updateFunc, d.Timeout(schema.TimeoutUpdate), isSqlOperationInProgressError)
 
 		if err != nil {
 			return fmt.Errorf("Error, failed to update"+
@@ -368,8 +368,8 @@ func resourceSqlUserDelete(d *schema.ResourceData, meta interface{}) error {
 	host := d.Get("host").(string)
 	instance := d.Get("instance").(string)
 
-	mutexKV.Lock(instanceMutexKey(project, instance))
-	defer mutexKV.Unlock(instanceMutexKey(project, instance))
+	mutexKV.Lock(sqlInstanceMutexKey(project, instance))
+	defer mutexKV.Unlock(sqlInstanceMutexKey(project, instance))
 
 	var op *sqladmin.Operation
 	err = retryTimeDuration(func() error {
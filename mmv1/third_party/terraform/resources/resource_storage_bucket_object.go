@@ -6,7 +6,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -21,6 +20,9 @@ import (
 	"google.golang.org/api/storage/v1"
 )
 
+// The maximum size of a Cloud Storage object, 5 TiB
+const storageObjectMaxSize = 5 << 40
+
 func resourceStorageBucketObject() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceStorageBucketObjectCreate,
@@ -168,7 +170,8 @@ func resourceStorageBucketObject() *schema.Resource {
 				ForceNew:         true,
 				Computed:         true,
 				ConflictsWith:    []string{"customer_encryption"},
-				DiffSuppressFunc: compareCryptoKeyVersions,
+				ValidateFunc:     validateKmsKeyRef,
+				DiffSuppressFunc: kmsKeyRefDiffSuppress,
 				Description:      `Resource name of the Cloud KMS key that will be used to encrypt the object. Overrides the object metadata's kmsKeyName value, if any.`,
 			},
 
@@ -253,18 +256,6 @@ func objectGetID(object *storage.Object) string {
 	return object.Bucket + "-" + object.Name
 }
 
-func compareCryptoKeyVersions(_, old, new string, _ *schema.ResourceData) bool {
-	// The API can return cryptoKeyVersions even though it wasn't specified.
-	// format: projects/<project>/locations/<region>/keyRings/<keyring>/cryptoKeys/<key>/cryptoKeyVersions/1
-
-	kmsKeyWithoutVersions := strings.Split(old, "/cryptoKeyVersions")[0]
-	if kmsKeyWithoutVersions == new {
-		return true
-	}
-
-	return false
-}
-
 func resourceStorageBucketObjectCreate(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 	userAgent, err := generateUserAgentString(d, config.userAgent)
@@ -331,9 +322,14 @@ func resourceStorageBucketObjectCreate(d *schema.ResourceData, meta interface{})
 		object.TemporaryHold = v.(bool)
 	}
 
+	upload := newMediaUpload(media, mediaUploadOptions{
+		Name:    fmt.Sprintf("object %s", name),
+		MaxSize: storageObjectMaxSize,
+	})
 	insertCall := objectsService.Insert(bucket, object)
 	insertCall.Name(name)
-	insertCall.Media(media)
+	insertCall.Media(upload.Reader(), upload.MediaOptions()...)
+	insertCall.ProgressUpdater(upload.ProgressUpdater)
 
 	// This is done late as we need to add headers to enable customer encryption
 	if v, ok := d.GetOk("customer_encryption"); ok {
@@ -341,12 +337,20 @@ func resourceStorageBucketObjectCreate(d *schema.ResourceData, meta interface{})
 		setEncryptionHeaders(customerEncryption, insertCall.Header())
 	}
 
-	_, err = insertCall.Do()
+	res, err := insertCall.Do()
 
 	if err != nil {
 		return fmt.Errorf("Error uploading object %s: %s", name, err)
 	}
 
+	if err := upload.Verify(res.Crc32c, res.Md5Hash); err != nil {
+		// Don't leave corrupted content behind, as it isn't tracked in state
+		if derr := objectsService.Delete(bucket, name).Do(); derr != nil {
+			log.Printf("[WARN] Unable to delete object %s after a failed upload: %s", name, derr)
+		}
+		return err
+	}
+
 	return resourceStorageBucketObjectRead(d, meta)
 }
 
@@ -412,53 +416,26 @@ func resourceStorageBucketObjectRead(d *schema.ResourceData, meta interface{}) e
 		return handleNotFoundError(err, d, fmt.Sprintf("Storage Bucket Object %q", d.Get("name").(string)))
 	}
 
-	if err := d.Set("md5hash", res.Md5Hash); err != nil {
-		return fmt.Errorf("Error setting md5hash: %s", err)
-	}
-	if err := d.Set("detect_md5hash", res.Md5Hash); err != nil {
-		return fmt.Errorf("Error setting detect_md5hash: %s", err)
-	}
-	if err := d.Set("crc32c", res.Crc32c); err != nil {
-		return fmt.Errorf("Error setting crc32c: %s", err)
-	}
-	if err := d.Set("cache_control", res.CacheControl); err != nil {
-		return fmt.Errorf("Error setting cache_control: %s", err)
-	}
-	if err := d.Set("content_disposition", res.ContentDisposition); err != nil {
-		return fmt.Errorf("Error setting content_disposition: %s", err)
-	}
-	if err := d.Set("content_encoding", res.ContentEncoding); err != nil {
-		return fmt.Errorf("Error setting content_encoding: %s", err)
-	}
-	if err := d.Set("content_language", res.ContentLanguage); err != nil {
-		return fmt.Errorf("Error setting content_language: %s", err)
-	}
-	if err := d.Set("content_type", res.ContentType); err != nil {
-		return fmt.Errorf("Error setting content_type: %s", err)
-	}
-	if err := d.Set("storage_class", res.StorageClass); err != nil {
-		return fmt.Errorf("Error setting storage_class: %s", err)
-	}
-	if err := d.Set("kms_key_name", res.KmsKeyName); err != nil {
-		return fmt.Errorf("Error setting kms_key_name: %s", err)
-	}
-	if err := d.Set("self_link", res.SelfLink); err != nil {
-		return fmt.Errorf("Error setting self_link: %s", err)
-	}
-	if err := d.Set("output_name", res.Name); err != nil {
-		return fmt.Errorf("Error setting output_name: %s", err)
-	}
-	if err := d.Set("metadata", res.Metadata); err != nil {
-		return fmt.Errorf("Error setting metadata: %s", err)
-	}
-	if err := d.Set("media_link", res.MediaLink); err != nil {
-		return fmt.Errorf("Error setting media_link: %s", err)
-	}
-	if err := d.Set("event_based_hold", res.EventBasedHold); err != nil {
-		return fmt.Errorf("Error setting event_based_hold: %s", err)
-	}
-	if err := d.Set("temporary_hold", res.TemporaryHold); err != nil {
-		return fmt.Errorf("Error setting temporary_hold: %s", err)
+	s := NewStateSetter(d)
+	s.Set("md5hash", res.Md5Hash)
+	s.Set("detect_md5hash", res.Md5Hash)
+	s.Set("crc32c", res.Crc32c)
+	s.Set("cache_control", res.CacheControl)
+	s.Set("content_disposition", res.ContentDisposition)
+	s.Set("content_encoding", res.ContentEncoding)
+	s.Set("content_language", res.ContentLanguage)
+	s.Set("content_type", res.ContentType)
+	s.Set("storage_class", res.StorageClass)
+	s.Set("kms_key_name", res.KmsKeyName)
+	s.Set("self_link", res.SelfLink)
+	s.Set("output_name", res.Name)
+	s.Set("metadata", res.Metadata)
+	s.Set("media_link", res.MediaLink)
+	s.Set("event_based_hold", res.EventBasedHold)
+	s.Set("temporary_hold", res.TemporaryHold)
+
+	if err := s.Flush(); err != nil {
+		return err
 	}
 
 	d.SetId(objectGetID(res))
@@ -40,3 +40,37 @@ data "google_compute_disk" "foo" {
 }
 `, context)
 }
+
+func TestAccDataSourceGoogleComputeDiskFromSelfLink_basic(t *testing.T) {
+	t.Parallel()
+
+	context := map[string]interface{}{
+		"random_suffix": randString(t, 10),
+	}
+
+	vcrTest(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckComputeDiskDestroyProducer(t),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceGoogleComputeDiskFromSelfLink_basic(context),
+				Check: resource.ComposeTestCheckFunc(
+					checkDataSourceStateMatchesResourceState("data.google_compute_disk_from_self_link.foo", "google_compute_disk.foo"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceGoogleComputeDiskFromSelfLink_basic(context map[string]interface{}) string {
+	return Nprintf(`
+resource "google_compute_disk" "foo" {
+  name     = "tf-test-compute-disk-%{random_suffix}"
+}
+
+data "google_compute_disk_from_self_link" "foo" {
+  self_link = google_compute_disk.foo.self_link
+}
+`, context)
+}
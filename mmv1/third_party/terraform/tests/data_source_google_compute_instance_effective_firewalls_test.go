@@ -0,0 +1,79 @@
+package google
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceComputeInstanceEffectiveFirewalls_basic(t *testing.T) {
+	t.Parallel()
+
+	context := map[string]interface{}{
+		"random_suffix": randString(t, 10),
+	}
+
+	vcrTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceComputeInstanceEffectiveFirewalls(context),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.google_compute_instance_effective_firewalls.default", "network_interface", "nic0"),
+					resource.TestCheckTypeSetElemNestedAttrs("data.google_compute_instance_effective_firewalls.default", "firewalls.*", map[string]string{
+						"name":      fmt.Sprintf("tf-test-fw-%s", context["random_suffix"]),
+						"direction": "INGRESS",
+						"priority":  "900",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceComputeInstanceEffectiveFirewalls(context map[string]interface{}) string {
+	return Nprintf(`
+resource "google_compute_network" "default" {
+  name                    = "tf-test-net-%{random_suffix}"
+  auto_create_subnetworks = true
+}
+
+resource "google_compute_firewall" "default" {
+  name     = "tf-test-fw-%{random_suffix}"
+  network  = google_compute_network.default.name
+  priority = 900
+
+  allow {
+    protocol = "tcp"
+    ports    = ["443"]
+  }
+
+  source_ranges = ["10.0.0.0/8"]
+}
+
+resource "google_compute_instance" "default" {
+  name         = "tf-test-instance-%{random_suffix}"
+  machine_type = "e2-medium"
+  zone         = "us-central1-a"
+
+  boot_disk {
+    initialize_params {
+      image = "debian-cloud/debian-11"
+    }
+  }
+
+  network_interface {
+    network = google_compute_network.default.self_link
+  }
+}
+
+data "google_compute_instance_effective_firewalls" "default" {
+  instance = google_compute_instance.default.name
+  zone     = google_compute_instance.default.zone
+
+  depends_on = [google_compute_firewall.default]
+}
+`, context)
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-	"google.golang.org/api/cloudkms/v1"
 )
 
 func TestAccKmsSecret_basic(t *testing.T) {
@@ -98,23 +97,14 @@ func testAccEncryptSecretDataWithCryptoKey(t *testing.T, s *terraform.State, cry
 		return "", nil, err
 	}
 
-	kmsEncryptRequest := &cloudkms.EncryptRequest{
-		Plaintext: base64.StdEncoding.EncodeToString([]byte(plaintext)),
-	}
-
-	if aad != "" {
-		kmsEncryptRequest.AdditionalAuthenticatedData = base64.StdEncoding.EncodeToString([]byte(aad))
-	}
-
-	encryptResponse, err := config.NewKmsClient(config.userAgent).Projects.Locations.KeyRings.CryptoKeys.Encrypt(cryptoKeyId.cryptoKeyId(), kmsEncryptRequest).Do()
-
+	ciphertext, err := kmsEncrypt(config, config.userAgent, cryptoKeyId, []byte(plaintext), kmsCryptoOptions{AAD: []byte(aad), Timeout: kmsCryptoRetryTimeout})
 	if err != nil {
-		return "", nil, fmt.Errorf("Error encrypting plaintext: %s", err)
+		return "", nil, err
 	}
 
-	log.Printf("[INFO] Successfully encrypted plaintext and got ciphertext: %s", encryptResponse.Ciphertext)
+	log.Printf("[INFO] Successfully encrypted plaintext and got ciphertext: %s", ciphertext)
 
-	return encryptResponse.Ciphertext, cryptoKeyId, nil
+	return ciphertext, cryptoKeyId, nil
 }
 
 func testGoogleKmsSecret_datasource(cryptoKeyTerraformId, ciphertext string) string {
@@ -0,0 +1,53 @@
+package google
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceGoogleProjectEffectiveIamPolicy_basic(t *testing.T) {
+	t.Parallel()
+
+	project := getTestProjectFromEnv()
+	account := fmt.Sprintf("tf-test-%d", randInt(t))
+
+	vcrTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceGoogleProjectEffectiveIamPolicy(project, account),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.google_project_effective_iam_policy.policy", "project", project),
+					resource.TestCheckTypeSetElemNestedAttrs("data.google_project_effective_iam_policy.policy", "bindings.*", map[string]string{
+						"attached_to": "projects/" + project,
+						"role":        "roles/compute.viewer",
+					}),
+				),
+			},
+		},
+	})
+}
+
+func testAccDataSourceGoogleProjectEffectiveIamPolicy(project, account string) string {
+	return fmt.Sprintf(`
+resource "google_service_account" "sa" {
+  project    = "%s"
+  account_id = "%s"
+}
+
+resource "google_project_iam_member" "member" {
+  project = "%s"
+  role    = "roles/compute.viewer"
+  member  = "serviceAccount:${google_service_account.sa.email}"
+}
+
+data "google_project_effective_iam_policy" "policy" {
+  project = "%s"
+
+  depends_on = [google_project_iam_member.member]
+}
+`, project, account, project, project)
+}
@@ -2,11 +2,40 @@ package google
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
+// Generated resources wrap the errors of failed requests so the response
+// headers asked for by Google support can be added to them.
+func TestComputeAddressCreate_responseHeaders(t *testing.T) {
+	s := newFakeAPIServer(t)
+	failed := fakeAPIError(400, "Invalid value for field 'resource.name'")
+	failed.Header = http.Header{"X-Goog-Request-Id": []string{"req-1"}}
+	s.Script("POST", "/compute/v1/projects/p/regions/us-central1/addresses", failed)
+
+	config := s.Config()
+	config.ComputeBasePath = s.URL + "/compute/v1/"
+	r := withResponseHeaderDetails(resourceComputeAddress())
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"name":    "address",
+		"project": "p",
+		"region":  "us-central1",
+	})
+
+	err := r.Create(d, config)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if !strings.Contains(err.Error(), "Error creating Address") || !strings.Contains(err.Error(), "X-Goog-Request-Id: req-1") {
+		t.Errorf("expected the error to include the response headers, got %s", err)
+	}
+}
+
 func TestAccComputeAddress_networkTier(t *testing.T) {
 	t.Parallel()
 
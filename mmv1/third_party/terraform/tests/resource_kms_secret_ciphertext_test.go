@@ -1,14 +1,12 @@
 package google
 
 import (
-	"encoding/base64"
 	"fmt"
 	"log"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
-	"google.golang.org/api/cloudkms/v1"
 )
 
 func TestAccKmsSecretCiphertext_basic(t *testing.T) {
@@ -63,22 +61,12 @@ func testAccDecryptSecretDataWithCryptoKey(t *testing.T, s *terraform.State, cry
 		return "", fmt.Errorf("Attribute 'ciphertext' not found in resource '%s'", secretCiphertextResourceName)
 	}
 
-	kmsDecryptRequest := &cloudkms.DecryptRequest{
-		Ciphertext: ciphertext,
-	}
-
-	if aad != "" {
-		kmsDecryptRequest.AdditionalAuthenticatedData = base64.StdEncoding.EncodeToString([]byte(aad))
-	}
-
-	decryptResponse, err := config.NewKmsClient(config.userAgent).Projects.Locations.KeyRings.CryptoKeys.Decrypt(cryptoKeyId, kmsDecryptRequest).Do()
-
+	cryptoKey, err := parseKmsCryptoKeyId(cryptoKeyId, config)
 	if err != nil {
-		return "", fmt.Errorf("Error decrypting ciphertext: %s", err)
+		return "", err
 	}
 
-	plaintextBytes, err := base64.StdEncoding.DecodeString(decryptResponse.Plaintext)
-
+	plaintextBytes, err := kmsDecrypt(config, config.userAgent, cryptoKey, ciphertext, kmsCryptoOptions{AAD: []byte(aad), Timeout: kmsCryptoRetryTimeout})
 	if err != nil {
 		return "", err
 	}
@@ -86,6 +86,72 @@ func TestAccLoggingBucketConfigProject_basic(t *testing.T) {
 	})
 }
 
+func TestAccLoggingBucketConfigProject_cmekSettings(t *testing.T) {
+	t.Parallel()
+
+	kms := BootstrapKMSKeyInLocation(t, "us-central1")
+	context := map[string]interface{}{
+		"project":      getTestProjectFromEnv(),
+		"bucket_id":    "tf-test-bucket-" + randString(t, 10),
+		"kms_key_name": kms.CryptoKey.Name,
+	}
+
+	vcrTest(t, resource.TestCase{
+		PreCheck:  func() { testAccPreCheck(t) },
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLoggingBucketConfigProject_cmekSettings(context, 30),
+			},
+			{
+				ResourceName:            "google_logging_project_bucket_config.basic",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"project"},
+			},
+			{
+				// Updates must leave the output only fields of cmek_settings
+				// out of the request
+				Config: testAccLoggingBucketConfigProject_cmekSettings(context, 20),
+			},
+			{
+				ResourceName:            "google_logging_project_bucket_config.basic",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"project"},
+			},
+		},
+	})
+}
+
+func testAccLoggingBucketConfigProject_cmekSettings(context map[string]interface{}, retention int) string {
+	return fmt.Sprintf(Nprintf(`
+data "google_project" "project" {
+	project_id = "%{project}"
+}
+
+resource "google_kms_crypto_key_iam_member" "logging" {
+	crypto_key_id = "%{kms_key_name}"
+	role          = "roles/cloudkms.cryptoKeyEncrypterDecrypter"
+	member        = "serviceAccount:service-${data.google_project.project.number}@gcp-sa-logging.iam.gserviceaccount.com"
+}
+
+resource "google_logging_project_bucket_config" "basic" {
+	project        = data.google_project.project.project_id
+	location       = "us-central1"
+	retention_days = %d
+	description    = "retention test %d days"
+	bucket_id      = "%{bucket_id}"
+
+	cmek_settings {
+		kms_key_name = "%{kms_key_name}"
+	}
+
+	depends_on = [google_kms_crypto_key_iam_member.logging]
+}
+`, context), retention, retention)
+}
+
 func TestAccLoggingBucketConfigBillingAccount_basic(t *testing.T) {
 	t.Parallel()
 
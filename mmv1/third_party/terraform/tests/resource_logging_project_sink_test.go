@@ -21,6 +21,7 @@ func TestAccLoggingProjectSink_basic(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config: testAccLoggingProjectSink_basic(sinkName, getTestProjectFromEnv(), bucketName),
+				Check:  resource.TestCheckResourceAttrSet("google_logging_project_sink.basic", "content_fingerprint"),
 			},
 			{
 				ResourceName:      "google_logging_project_sink.basic",
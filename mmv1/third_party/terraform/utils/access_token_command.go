@@ -0,0 +1,152 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Commands that hang, eg waiting for interactive input, fail after this.
+	accessTokenCommandTimeout = time.Minute
+	// Tokens printed without an expiry are assumed to expire after this. It's
+	// shorter than the usual 1 hour lifetime, as the command may print a
+	// cached token that is already partly used.
+	accessTokenCommandDefaultLifetime = 30 * time.Minute
+	// Tokens are minted again this long before they expire, so requests that
+	// are slow to be sent don't carry an expired token.
+	accessTokenCommandRefreshMargin = 2 * time.Minute
+	// Command output quoted in errors is truncated to this length.
+	accessTokenCommandMaxOutput = 512
+)
+
+// commandTokenSource mints access tokens by running access_token_command.
+// The command prints either a bare token, like `gcloud auth
+// print-access-token`, or a JSON object with the token in "access_token" or
+// "token" and its lifetime in "expires_in" (seconds) or "expiry" (RFC 3339).
+// It should be wrapped in oauth2.ReuseTokenSource so the command only runs
+// again when the token is about to expire.
+type commandTokenSource struct {
+	args    []string
+	timeout time.Duration
+	now     func() time.Time
+}
+
+func newCommandTokenSource(args []string) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &commandTokenSource{
+		args:    args,
+		timeout: accessTokenCommandTimeout,
+		now:     time.Now,
+	})
+}
+
+func (s *commandTokenSource) Token() (*oauth2.Token, error) {
+	if len(s.args) == 0 {
+		return nil, fmt.Errorf("access_token_command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.args[0], s.args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("[DEBUG] Running access_token_command %q", s.args[0])
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("Error running access_token_command %q: %s; stderr: %s", s.args[0], err, sanitizeCommandOutput(stderr.String()))
+	}
+
+	token, err := parseAccessTokenCommandOutput(stdout.Bytes(), s.now())
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the output of access_token_command %q: %s", s.args[0], err)
+	}
+	log.Printf("[DEBUG] access_token_command %q minted a token valid until %s", s.args[0], token.Expiry.Format(time.RFC3339))
+	return token, nil
+}
+
+// accessTokenCommandOutput is the JSON form of access_token_command's output.
+type accessTokenCommandOutput struct {
+	AccessToken string    `json:"access_token"`
+	Token       string    `json:"token"`
+	ExpiresIn   int64     `json:"expires_in"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// parseAccessTokenCommandOutput returns the token printed by
+// access_token_command at now. The token's expiry is brought forward by
+// accessTokenCommandRefreshMargin.
+func parseAccessTokenCommandOutput(out []byte, now time.Time) (*oauth2.Token, error) {
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return nil, fmt.Errorf("no token was printed")
+	}
+
+	var value string
+	expiry := now.Add(accessTokenCommandDefaultLifetime)
+	if strings.HasPrefix(s, "{") {
+		var parsed accessTokenCommandOutput
+		if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %s", sanitizeCommandOutput(err.Error()))
+		}
+		value = parsed.AccessToken
+		if value == "" {
+			value = parsed.Token
+		}
+		switch {
+		case parsed.ExpiresIn > 0:
+			expiry = now.Add(time.Duration(parsed.ExpiresIn) * time.Second)
+		case !parsed.Expiry.IsZero():
+			expiry = parsed.Expiry
+		}
+	} else {
+		value = s
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("no token was printed")
+	}
+	if strings.ContainsAny(value, " \t\r\n") {
+		return nil, fmt.Errorf("expected a single token, got %d lines", strings.Count(value, "\n")+1)
+	}
+	if !expiry.After(now.Add(accessTokenCommandRefreshMargin)) {
+		return nil, fmt.Errorf("the token expires at %s, which is too soon to use it", expiry.Format(time.RFC3339))
+	}
+
+	return &oauth2.Token{
+		AccessToken: value,
+		TokenType:   "Bearer",
+		Expiry:      expiry.Add(-accessTokenCommandRefreshMargin),
+	}, nil
+}
+
+// Sequences of token characters this long are likely to be tokens or other
+// secrets, eg an OAuth token (ya29.…) or a refresh token echoed by a failing
+// command.
+var commandOutputSecretRegex = regexp.MustCompile(`[A-Za-z0-9_\-./+=]{24,}`)
+
+// sanitizeCommandOutput makes output of access_token_command safe to include
+// in errors and logs, by redacting anything that looks like a secret and
+// truncating it.
+func sanitizeCommandOutput(s string) string {
+	s = commandOutputSecretRegex.ReplaceAllString(strings.TrimSpace(s), "<redacted>")
+	if len(s) > accessTokenCommandMaxOutput {
+		s = s[:accessTokenCommandMaxOutput] + "... (truncated)"
+	}
+	if s == "" {
+		return "<empty>"
+	}
+	return s
+}
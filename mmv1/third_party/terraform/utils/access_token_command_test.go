@@ -0,0 +1,121 @@
+package google
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAccessTokenCommandOutput(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		out    string
+		token  string
+		expiry time.Time
+		err    bool
+	}{
+		"bare token": {
+			out:    "ya29.token\n",
+			token:  "ya29.token",
+			expiry: now.Add(accessTokenCommandDefaultLifetime - accessTokenCommandRefreshMargin),
+		},
+		"json expires_in": {
+			out:    `{"access_token": "ya29.token", "expires_in": 3599}`,
+			token:  "ya29.token",
+			expiry: now.Add(3599*time.Second - accessTokenCommandRefreshMargin),
+		},
+		"json expiry": {
+			out:    `{"token": "ya29.token", "expiry": "2022-01-01T01:00:00Z"}`,
+			token:  "ya29.token",
+			expiry: now.Add(time.Hour - accessTokenCommandRefreshMargin),
+		},
+		"empty": {
+			out: "  \n",
+			err: true,
+		},
+		"json without token": {
+			out: `{"expires_in": 3599}`,
+			err: true,
+		},
+		"invalid json": {
+			out: `{"access_token": `,
+			err: true,
+		},
+		"several lines": {
+			out: "Updates are available\nya29.token\n",
+			err: true,
+		},
+		"expires too soon": {
+			out: `{"access_token": "ya29.token", "expires_in": 60}`,
+			err: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		token, err := parseAccessTokenCommandOutput([]byte(tc.out), now)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if token.AccessToken != tc.token {
+			t.Errorf("%s: expected token %q, got %q", tn, tc.token, token.AccessToken)
+		}
+		if !token.Expiry.Equal(tc.expiry) {
+			t.Errorf("%s: expected expiry %s, got %s", tn, tc.expiry, token.Expiry)
+		}
+	}
+}
+
+func TestSanitizeCommandOutput(t *testing.T) {
+	secret := "ya29.a0ARrdaM-secret_value_that_should_not_leak"
+	got := sanitizeCommandOutput("ERROR: refresh failed for " + secret + "\n")
+	if strings.Contains(got, "secret_value") {
+		t.Errorf("expected the secret to be redacted, got %q", got)
+	}
+	if !strings.Contains(got, "ERROR: refresh failed for <redacted>") {
+		t.Errorf("expected the rest of the output to be kept, got %q", got)
+	}
+
+	if got := sanitizeCommandOutput(strings.Repeat("no secrets ", 100)); len(got) > accessTokenCommandMaxOutput+len("... (truncated)") {
+		t.Errorf("expected long output to be truncated, got %d characters", len(got))
+	}
+	if got := sanitizeCommandOutput(""); got != "<empty>" {
+		t.Errorf("expected empty output to be described, got %q", got)
+	}
+}
+
+func TestCommandTokenSource(t *testing.T) {
+	s := &commandTokenSource{
+		args:    []string{"sh", "-c", "echo ya29.token"},
+		timeout: time.Minute,
+		now:     time.Now,
+	}
+	token, err := s.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if token.AccessToken != "ya29.token" {
+		t.Errorf("expected the printed token, got %q", token.AccessToken)
+	}
+
+	s.args = []string{"sh", "-c", "echo 'refresh token 1//0gSecretRefreshTokenValue123 is invalid' >&2; exit 1"}
+	_, err = s.Token()
+	if err == nil {
+		t.Fatalf("expected an error when the command fails")
+	}
+	if strings.Contains(err.Error(), "SecretRefreshToken") {
+		t.Errorf("expected stderr to be sanitized, got %q", err)
+	}
+
+	s.args = []string{"sh", "-c", "exec sleep 5"}
+	s.timeout = 10 * time.Millisecond
+	if _, err := s.Token(); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("expected the command to time out, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+// Serving reads of Compute Engine resources from aggregated lists, if the
+// aggregated_refresh provider feature is enabled. Refreshing a configuration
+// with hundreds of instances or disks otherwise sends a GET per resource; with
+// it, the first read of a collection in a project lists the collection across
+// every zone and region with a single aggregatedList call, and the reads of
+// the other resources in it are served from that list while it's fresh.
+
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// How long an aggregated list serves reads. It's listed again by the first
+// read after that, so it only needs to cover the reads of one refresh.
+const aggregatedRefreshCacheTTL = time.Minute
+
+// aggregatedRefreshCollections are the Compute Engine collections read from
+// aggregated lists. Their aggregatedList returns the same representation of
+// resources as their get.
+var aggregatedRefreshCollections = map[string]bool{
+	"disks":     true,
+	"instances": true,
+}
+
+var (
+	// Matches a zonal or regional Compute Engine resource, capturing the
+	// base path, project and collection
+	computeResourceURLRegex = regexp.MustCompile(`^(.*/compute/[^/]+/)projects/([^/]+)/(?:zones|regions)/[^/]+/([^/]+)/[^/]+$`)
+	// Matches any Compute Engine URL of a project, capturing the base path
+	// and project
+	computeProjectURLRegex = regexp.MustCompile(`^(.*/compute/[^/]+/)projects/([^/]+)/`)
+)
+
+// aggregatedListCache remembers aggregated lists of Compute Engine
+// collections, keyed by their URL. Concurrent reads of the same collection
+// share a single aggregatedList call.
+type aggregatedListCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]*aggregatedListEntry
+	// bypassed holds the prefixes of the lists of projects changed by this
+	// provider process, see bypass
+	bypassed map[string]bool
+}
+
+type aggregatedListEntry struct {
+	// done is closed once items and err are set.
+	done chan struct{}
+	// items holds the JSON representation of the resources listed, keyed by
+	// their path relative to the API, see getRelativePath
+	items   map[string][]byte
+	err     error
+	expires time.Time
+}
+
+func newAggregatedListCache(ttl time.Duration, clock Clock) *aggregatedListCache {
+	return &aggregatedListCache{
+		ttl:      ttl,
+		clock:    clock,
+		entries:  make(map[string]*aggregatedListEntry),
+		bypassed: make(map[string]bool),
+	}
+}
+
+// get returns the resources listed by listURL, calling list if they aren't
+// cached. Errors aren't cached.
+func (c *aggregatedListCache) get(listURL string, list func() (map[string][]byte, error)) (map[string][]byte, error) {
+	c.mu.Lock()
+	e, ok := c.entries[listURL]
+	if ok {
+		select {
+		case <-e.done:
+			if e.err != nil || !c.clock.Now().Before(e.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		e = &aggregatedListEntry{done: make(chan struct{})}
+		c.entries[listURL] = e
+		c.mu.Unlock()
+
+		e.items, e.err = list()
+		e.expires = c.clock.Now().Add(c.ttl)
+		close(e.done)
+		return e.items, e.err
+	}
+	c.mu.Unlock()
+
+	<-e.done
+	return e.items, e.err
+}
+
+// bypass forgets the lists of project and stops serving reads of its
+// resources for the rest of the process, once a request which may change any
+// of them is sent, eg attaching a disk changes both the instance and the
+// disk. The change is only complete once its operation is, which this
+// transport doesn't see, so no list can be trusted afterwards. Refreshes,
+// which make no changes, are still served from lists.
+func (c *aggregatedListCache) bypass(basePath, project string) {
+	prefix := aggregatedListPrefix(basePath, project)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bypassed[prefix] = true
+	for k := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// isBypassed returns whether reads of the resources of project are no longer
+// served from lists, see bypass.
+func (c *aggregatedListCache) isBypassed(basePath, project string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bypassed[aggregatedListPrefix(basePath, project)]
+}
+
+func aggregatedListPrefix(basePath, project string) string {
+	return fmt.Sprintf("%sprojects/%s/aggregated/", basePath, project)
+}
+
+// aggregatedRefreshTransport is a http.RoundTripper serving GETs of the
+// resources of aggregatedRefreshCollections from aggregated lists, and
+// sending other requests with internal. It's used when the aggregated_refresh
+// feature is enabled, so refreshing hundreds of instances or disks lists each
+// collection once rather than getting every resource. Resources missing from
+// a list, eg created since it was listed or in a zone that couldn't be
+// listed, and resources of projects changed by this process are read with a
+// GET.
+type aggregatedRefreshTransport struct {
+	cache    *aggregatedListCache
+	internal http.RoundTripper
+}
+
+func (t *aggregatedRefreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path)
+	if req.Method != "GET" {
+		if m := computeProjectURLRegex.FindStringSubmatch(u); m != nil {
+			t.cache.bypass(m[1], m[2])
+		}
+		return t.internal.RoundTrip(req)
+	}
+
+	if res, ok := t.fromAggregatedList(req, u); ok {
+		return res, nil
+	}
+	return t.internal.RoundTrip(req)
+}
+
+// fromAggregatedList returns the response to req, a GET of u, served from an
+// aggregated list if it can be.
+func (t *aggregatedRefreshTransport) fromAggregatedList(req *http.Request, u string) (*http.Response, bool) {
+	// Partial responses can't be served from the full resource
+	for k, vs := range req.URL.Query() {
+		if k == "prettyPrint" || (k == "alt" && len(vs) == 1 && vs[0] == "json") {
+			continue
+		}
+		return nil, false
+	}
+	m := computeResourceURLRegex.FindStringSubmatch(u)
+	if m == nil || !aggregatedRefreshCollections[m[3]] || t.cache.isBypassed(m[1], m[2]) {
+		return nil, false
+	}
+
+	listURL := aggregatedListPrefix(m[1], m[2]) + m[3]
+	items, err := t.cache.get(listURL, func() (map[string][]byte, error) {
+		return t.list(req, listURL, m[3])
+	})
+	if err != nil {
+		log.Printf("[DEBUG] Reading %s with a GET, listing %s failed: %s", u, listURL, err)
+		return nil, false
+	}
+	path, err := getRelativePath(u)
+	if err != nil {
+		return nil, false
+	}
+	item, ok := items[path]
+	if !ok {
+		return nil, false
+	}
+
+	log.Printf("[DEBUG] Serving GET of %s from the aggregated list of %s", u, m[3])
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(item)),
+		ContentLength: int64(len(item)),
+		Request:       req,
+	}, true
+}
+
+// list returns the resources of collection listed by listURL, an
+// aggregatedList, sending the requests with the headers of req.
+func (t *aggregatedRefreshTransport) list(req *http.Request, listURL, collection string) (map[string][]byte, error) {
+	items := make(map[string][]byte)
+	pageToken := ""
+	for {
+		u := listURL + "?alt=json&returnPartialSuccess=true"
+		if pageToken != "" {
+			u += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		listReq, err := http.NewRequestWithContext(req.Context(), "GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		listReq.Header = req.Header.Clone()
+
+		res, err := t.internal.RoundTrip(listReq)
+		if err != nil {
+			return nil, err
+		}
+		if err := googleapi.CheckResponse(res); err != nil {
+			googleapi.CloseBody(res)
+			return nil, err
+		}
+		var page struct {
+			// Items holds the resources of each zone or region, under the
+			// name of the collection
+			Items         map[string]map[string]json.RawMessage `json:"items"`
+			NextPageToken string                                `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		googleapi.CloseBody(res)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, scope := range page.Items {
+			raw, ok := scope[collection]
+			if !ok {
+				continue
+			}
+			var resources []json.RawMessage
+			if err := json.Unmarshal(raw, &resources); err != nil {
+				return nil, err
+			}
+			for _, r := range resources {
+				var link struct {
+					SelfLink string `json:"selfLink"`
+				}
+				if err := json.Unmarshal(r, &link); err != nil {
+					return nil, err
+				}
+				if path, err := getRelativePath(link.SelfLink); err == nil {
+					items[path] = r
+				}
+			}
+		}
+
+		if page.NextPageToken == "" {
+			return items, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
@@ -0,0 +1,110 @@
+package google
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAggregatedRefreshTransport(t *testing.T) {
+	s := newFakeAPIServer(t)
+	disk := func(zone, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":     name,
+			"id":       "1234567890123456789",
+			"selfLink": "https://www.googleapis.com/compute/v1/projects/p/zones/" + zone + "/disks/" + name,
+		}
+	}
+	s.Script("GET", "/compute/v1/projects/p/aggregated/disks", fakeAPIResponse{Body: map[string]interface{}{
+		"items": map[string]interface{}{
+			"zones/us-central1-a": map[string]interface{}{"disks": []interface{}{disk("us-central1-a", "a")}},
+			"zones/us-central1-b": map[string]interface{}{"disks": []interface{}{disk("us-central1-b", "b")}},
+			"zones/us-central1-c": map[string]interface{}{"warning": map[string]interface{}{"code": "NO_RESULTS_ON_PAGE"}},
+		},
+	}})
+	s.Script("GET", "/compute/v1/projects/p/zones/us-central1-c/disks/c", fakeAPIResponse{Body: disk("us-central1-c", "c")})
+	s.Script("POST", "/compute/v1/projects/p/zones/us-central1-a/disks/a/resize", fakeAPIResponse{})
+
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client := &http.Client{Transport: &aggregatedRefreshTransport{
+		cache:    newAggregatedListCache(time.Minute, clock),
+		internal: s.Client().Transport,
+	}}
+	get := func(path string) map[string]interface{} {
+		t.Helper()
+		res, err := client.Get(s.URL + path + "?alt=json&prettyPrint=false")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer res.Body.Close()
+		var body map[string]interface{}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return body
+	}
+
+	if d := get("/compute/v1/projects/p/zones/us-central1-a/disks/a"); d["name"] != "a" || d["id"] != "1234567890123456789" {
+		t.Errorf("expected disk a, got %v", d)
+	}
+	if d := get("/compute/v1/projects/p/zones/us-central1-b/disks/b"); d["name"] != "b" {
+		t.Errorf("expected disk b, got %v", d)
+	}
+	if n := s.Requests("GET", "/compute/v1/projects/p/aggregated/disks"); n != 1 {
+		t.Errorf("expected the disks to be listed once, got %d", n)
+	}
+
+	// Disks missing from the list are read with a GET
+	if d := get("/compute/v1/projects/p/zones/us-central1-c/disks/c"); d["name"] != "c" {
+		t.Errorf("expected disk c, got %v", d)
+	}
+	if n := s.Requests("GET", "/compute/v1/projects/p/zones/us-central1-c/disks/c"); n != 1 {
+		t.Errorf("expected disk c to be read with a GET, got %d", n)
+	}
+
+	// And so does time
+	clock.Sleep(time.Minute)
+	get("/compute/v1/projects/p/zones/us-central1-b/disks/b")
+	if n := s.Requests("GET", "/compute/v1/projects/p/aggregated/disks"); n != 2 {
+		t.Errorf("expected the disks to be listed again once the list expired, got %d", n)
+	}
+
+	// Once a change is sent, the resources of the project are read with a
+	// GET for the rest of the process, as the change may complete at any
+	// time after
+	res, err := client.Post(s.URL+"/compute/v1/projects/p/zones/us-central1-a/disks/a/resize", "application/json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res.Body.Close()
+	s.Script("GET", "/compute/v1/projects/p/zones/us-central1-a/disks/a", fakeAPIResponse{Body: disk("us-central1-a", "a")})
+	get("/compute/v1/projects/p/zones/us-central1-a/disks/a")
+	clock.Sleep(time.Minute)
+	get("/compute/v1/projects/p/zones/us-central1-a/disks/a")
+	if n := s.Requests("GET", "/compute/v1/projects/p/aggregated/disks"); n != 2 {
+		t.Errorf("expected the disks not to be listed again after a change, got %d", n)
+	}
+	if n := s.Requests("GET", "/compute/v1/projects/p/zones/us-central1-a/disks/a"); n != 2 {
+		t.Errorf("expected disk a to be read with a GET after a change, got %d", n)
+	}
+}
+
+func TestAggregatedRefreshTransport_partialResponse(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/compute/v1/projects/p/zones/z/instances/i", fakeAPIResponse{Body: map[string]interface{}{"name": "i"}})
+
+	clock := &fakeClock{}
+	client := &http.Client{Transport: &aggregatedRefreshTransport{
+		cache:    newAggregatedListCache(time.Minute, clock),
+		internal: s.Client().Transport,
+	}}
+	res, err := client.Get(s.URL + "/compute/v1/projects/p/zones/z/instances/i?alt=json&fields=name")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res.Body.Close()
+	if n := s.Requests("GET", "/compute/v1/projects/p/aggregated/instances"); n != 0 {
+		t.Errorf("expected partial responses not to be served from a list, got %d lists", n)
+	}
+}
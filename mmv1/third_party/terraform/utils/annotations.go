@@ -0,0 +1,91 @@
+package google
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Annotations are string maps like labels, set by both users and the server.
+// Keys with these prefixes, or these keys, are set by the server or by
+// Google's controllers, and users can't set or remove them. They're dropped
+// from "annotations" fields unless configured, and reported in full by
+// "effective_annotations" fields, eg in google_cloud_run_service.
+var systemAnnotationPrefixes = []string{
+	"cloud.googleapis.com/",
+	"serving.knative.dev/",
+	"components.gke.io/",
+}
+
+var systemAnnotationKeys = map[string]bool{
+	"run.googleapis.com/ingress-status": true,
+	"run.googleapis.com/operation-id":   true,
+}
+
+// isSystemAnnotation returns whether the annotation key is set by the server.
+func isSystemAnnotation(key string) bool {
+	if systemAnnotationKeys[key] {
+		return true
+	}
+	for _, prefix := range systemAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAnnotations pulls the annotations at key out of a
+// TerraformResourceData as a map[string]string.
+func expandAnnotations(d TerraformResourceData, key string) map[string]string {
+	return expandStringMap(d, key)
+}
+
+// flattenAnnotations returns the annotations v read from the API without the
+// system annotations that aren't in the annotations at key, so they don't
+// show up as diffs against the configuration.
+func flattenAnnotations(v interface{}, d TerraformResourceData, key string) interface{} {
+	annotations, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	current, _ := d.Get(key).(map[string]interface{})
+
+	flattened := make(map[string]interface{}, len(annotations))
+	for k, val := range annotations {
+		if _, ok := current[k]; ok || !isSystemAnnotation(k) {
+			flattened[k] = val
+		}
+	}
+	return flattened
+}
+
+// annotationsDiffSuppress suppresses diffs of system annotations removed from
+// the configuration, and diffs of the number of annotations caused only by
+// them. It's used on annotations fields whose state may still hold system
+// annotations, eg read before they were filtered by flattenAnnotations.
+func annotationsDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	i := strings.LastIndex(k, "annotations.")
+	if i < 0 {
+		return false
+	}
+	prefix, annotation := k[:i+len("annotations")], k[i+len("annotations."):]
+
+	if annotation != "%" {
+		return new == "" && isSystemAnnotation(annotation)
+	}
+
+	o, n := d.GetChange(prefix)
+	return countUserAnnotations(o) == countUserAnnotations(n)
+}
+
+func countUserAnnotations(v interface{}) int {
+	annotations, _ := v.(map[string]interface{})
+	count := 0
+	for k := range annotations {
+		if !isSystemAnnotation(k) {
+			count++
+		}
+	}
+	return count
+}
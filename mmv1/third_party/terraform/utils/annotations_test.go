@@ -0,0 +1,73 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlattenAnnotations(t *testing.T) {
+	api := map[string]interface{}{
+		"run.googleapis.com/ingress":        "all",
+		"run.googleapis.com/ingress-status": "all",
+		"serving.knative.dev/creator":       "user@example.com",
+		"cloud.googleapis.com/location":     "us-central1",
+		"team":                              "payments",
+	}
+	d := &ResourceDataMock{
+		FieldsInSchema: map[string]interface{}{
+			"metadata.0.annotations": map[string]interface{}{
+				"run.googleapis.com/ingress":    "all",
+				"cloud.googleapis.com/location": "us-central1",
+			},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"run.googleapis.com/ingress":    "all",
+		"cloud.googleapis.com/location": "us-central1",
+		"team":                          "payments",
+	}
+	if got := flattenAnnotations(api, d, "metadata.0.annotations"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected system annotations that aren't configured to be dropped, got %v", got)
+	}
+	if got := flattenAnnotations(nil, d, "metadata.0.annotations"); got != nil {
+		t.Errorf("expected nil to be kept, got %v", got)
+	}
+}
+
+func TestAnnotationsDiffSuppress(t *testing.T) {
+	cases := map[string]struct {
+		key, old, new string
+		suppress      bool
+	}{
+		"system annotation set by the server": {
+			key:      "metadata.0.annotations.serving.knative.dev/creator",
+			old:      "user@example.com",
+			suppress: true,
+		},
+		"exact system annotation key": {
+			key:      "metadata.0.annotations.run.googleapis.com/ingress-status",
+			old:      "all",
+			suppress: true,
+		},
+		"configured system annotation": {
+			key: "metadata.0.annotations.cloud.googleapis.com/location",
+			old: "us-central1",
+			new: "us-east1",
+		},
+		"user annotation removed": {
+			key: "metadata.0.annotations.run.googleapis.com/ingress",
+			old: "all",
+		},
+		"not an annotation": {
+			key: "metadata.0.labels.cloud.googleapis.com/location",
+			old: "us-central1",
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := annotationsDiffSuppress(tc.key, tc.old, tc.new, nil); got != tc.suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, tc.suppress, got)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package google
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+// Setting this environment variable to a file path makes the provider write a
+// JSON report of the API calls and operations it made, for tracking
+// performance over time in CI. The report covers the lifetime of the provider
+// process, which is a single plan or apply.
+const applyReportPathEnvVar = "GOOGLE_APPLY_REPORT_PATH"
+
+// Reports are rewritten at most once per reportWriteInterval, rather than
+// after every call, as an apply can make thousands of calls.
+const reportWriteInterval = time.Second
+
+// applyReport collects the calls and operations made during a run, grouped by
+// the resource they were made against.
+type applyReport struct {
+	file *reportFile
+
+	mu         sync.Mutex
+	resources  map[string]*applyReportResource
+	operations []applyReportOperation
+	locks      []applyReportLock
+}
+
+// applyReportResource summarises the calls made against a single resource
+// URL (without its query string).
+type applyReportResource struct {
+	Calls      []applyReportCall `json:"calls"`
+	Retries    int               `json:"retries"`
+	DurationMs int64             `json:"duration_ms"`
+	// Status of the last call, eg 200 or 404. 0 if it failed without a
+	// response.
+	Status int `json:"status"`
+}
+
+type applyReportCall struct {
+	Method     string    `json:"method"`
+	Start      time.Time `json:"start"`
+	DurationMs int64     `json:"duration_ms"`
+	Attempts   int       `json:"attempts"`
+	Status     int       `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type applyReportOperation struct {
+	Name       string    `json:"name"`
+	Activity   string    `json:"activity"`
+	Start      time.Time `json:"start"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// applyReportLock records a resource holding a MutexKV lock, eg the lock of a
+// router while one of its interfaces is changed.
+type applyReportLock struct {
+	Key      string    `json:"key"`
+	Start    time.Time `json:"start"`
+	WaitedMs int64     `json:"waited_ms"`
+	HeldMs   int64     `json:"held_ms"`
+}
+
+var (
+	applyReportOnce sync.Once
+	applyReportInst *applyReport
+)
+
+// currentApplyReport returns the report configured through
+// GOOGLE_APPLY_REPORT_PATH, or nil if reports are disabled. All methods of
+// applyReport are no-ops on nil.
+func currentApplyReport() *applyReport {
+	applyReportOnce.Do(func() {
+		if path := os.Getenv(applyReportPathEnvVar); path != "" {
+			log.Printf("[INFO] Writing an apply report to %s", path)
+			applyReportInst = newApplyReport(path)
+		}
+	})
+	return applyReportInst
+}
+
+func newApplyReport(path string) *applyReport {
+	r := &applyReport{
+		resources: make(map[string]*applyReportResource),
+	}
+	r.file = newReportFile("apply report", path, func() ([]byte, error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.marshalLocked()
+	})
+	return r
+}
+
+// recordCall records a call made by sendRequest. attempts includes the first
+// attempt, so a call that succeeded first time has 1 attempt and no retries.
+func (r *applyReport) recordCall(method, rawurl string, start time.Time, attempts, status int, err error) {
+	if r == nil {
+		return
+	}
+
+	key := rawurl
+	if u, perr := url.Parse(rawurl); perr == nil {
+		u.RawQuery = ""
+		key = u.String()
+	}
+	if status == 0 {
+		if gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error); ok && gerr != nil {
+			status = gerr.Code
+		}
+	}
+
+	call := applyReportCall{
+		Method:     method,
+		Start:      start.UTC(),
+		DurationMs: time.Since(start).Milliseconds(),
+		Attempts:   attempts,
+		Status:     status,
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	res, ok := r.resources[key]
+	if !ok {
+		res = &applyReportResource{}
+		r.resources[key] = res
+	}
+	res.Calls = append(res.Calls, call)
+	if attempts > 1 {
+		res.Retries += attempts - 1
+	}
+	res.DurationMs += call.DurationMs
+	res.Status = status
+	r.mu.Unlock()
+	r.file.scheduleWrite()
+}
+
+// recordOperation records waiting for a long-running operation.
+func (r *applyReport) recordOperation(name, activity string, start time.Time, err error) {
+	if r == nil {
+		return
+	}
+
+	op := applyReportOperation{
+		Name:       name,
+		Activity:   activity,
+		Start:      start.UTC(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		op.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.operations = append(r.operations, op)
+	r.mu.Unlock()
+	r.file.scheduleWrite()
+}
+
+// recordLock records a lock being released. start is when the holder started
+// waiting for it.
+func (r *applyReport) recordLock(key string, start time.Time, waited, held time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.locks = append(r.locks, applyReportLock{
+		Key:      key,
+		Start:    start.UTC(),
+		WaitedMs: waited.Milliseconds(),
+		HeldMs:   held.Milliseconds(),
+	})
+	r.mu.Unlock()
+	r.file.scheduleWrite()
+}
+
+// reportFile writes a JSON report produced by encode to path. Writes are
+// batched: scheduleWrite writes the report once reportWriteInterval has
+// passed, and FlushReports writes any pending changes before the provider
+// exits.
+type reportFile struct {
+	name   string
+	path   string
+	encode func() ([]byte, error)
+
+	mu    sync.Mutex
+	timer *time.Timer
+
+	// writeMu serializes writes, so an older report never replaces a newer
+	// one.
+	writeMu sync.Mutex
+}
+
+var (
+	reportFilesMu sync.Mutex
+	reportFiles   []*reportFile
+)
+
+func newReportFile(name, path string, encode func() ([]byte, error)) *reportFile {
+	f := &reportFile{
+		name:   name,
+		path:   path,
+		encode: encode,
+	}
+	reportFilesMu.Lock()
+	defer reportFilesMu.Unlock()
+	reportFiles = append(reportFiles, f)
+	return f
+}
+
+// scheduleWrite writes the report once reportWriteInterval has passed, unless
+// a write is already scheduled.
+func (f *reportFile) scheduleWrite() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.timer == nil {
+		f.timer = time.AfterFunc(reportWriteInterval, f.flush)
+	}
+}
+
+// flush writes the report if a write is scheduled, replacing the file
+// atomically so readers never see a partial report. Failures are logged rather
+// than returned, as a report must never fail an apply.
+func (f *reportFile) flush() {
+	f.mu.Lock()
+	pending := f.timer != nil
+	if pending {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	f.mu.Unlock()
+	if !pending {
+		return
+	}
+
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	b, err := f.encode()
+	if err != nil {
+		log.Printf("[WARN] Unable to encode %s: %s", f.name, err)
+		return
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		log.Printf("[WARN] Unable to write %s: %s", f.name, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		log.Printf("[WARN] Unable to write %s: %s", f.name, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		log.Printf("[WARN] Unable to write %s: %s", f.name, err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		log.Printf("[WARN] Unable to write %s: %s", f.name, err)
+	}
+}
+
+// FlushReports writes the pending changes of every report. main calls it once
+// Terraform has stopped the provider.
+func FlushReports() {
+	reportFilesMu.Lock()
+	defer reportFilesMu.Unlock()
+	for _, f := range reportFiles {
+		f.flush()
+	}
+}
+
+func (r *applyReport) marshalLocked() ([]byte, error) {
+	type resource struct {
+		URL string `json:"url"`
+		*applyReportResource
+	}
+	report := struct {
+		Resources  []resource             `json:"resources"`
+		Operations []applyReportOperation `json:"operations"`
+		Locks      []applyReportLock      `json:"locks"`
+	}{
+		Resources:  make([]resource, 0, len(r.resources)),
+		Operations: r.operations,
+		Locks:      r.locks,
+	}
+	for u, res := range r.resources {
+		report.Resources = append(report.Resources, resource{URL: u, applyReportResource: res})
+	}
+	sort.Slice(report.Resources, func(i, j int) bool {
+		return report.Resources[i].URL < report.Resources[j].URL
+	})
+	if report.Operations == nil {
+		report.Operations = []applyReportOperation{}
+	}
+	if report.Locks == nil {
+		report.Locks = []applyReportLock{}
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
@@ -0,0 +1,105 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+type testApplyReport struct {
+	Resources []struct {
+		URL string `json:"url"`
+		applyReportResource
+	} `json:"resources"`
+	Operations []applyReportOperation `json:"operations"`
+	Locks      []applyReportLock      `json:"locks"`
+}
+
+func TestApplyReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	r := newApplyReport(path)
+
+	start := time.Now()
+	instance := "https://compute.googleapis.com/compute/v1/projects/p/zones/z/instances/foo"
+	r.recordCall("GET", instance+"?alt=json", start, 1, 404, nil)
+	r.recordCall("POST", instance, start, 3, 0, &googleapi.Error{Code: 409, Message: "already exists"})
+	r.recordCall("GET", "https://storage.googleapis.com/storage/v1/b/bar", start, 1, 200, nil)
+	r.recordOperation("operation-1", "Creating Instance", start, fmt.Errorf("quota exceeded"))
+	r.recordLock("router/p/us-central1/r", start, time.Second, 2*time.Second)
+
+	// Writes are batched, so nothing has been written yet
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the report not to be written before the write interval, got %v", err)
+	}
+	r.file.flush()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading the report: %s", err)
+	}
+	var report testApplyReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("unexpected error decoding the report: %s", err)
+	}
+
+	if len(report.Resources) != 2 {
+		t.Fatalf("expected calls to be grouped into 2 resources, got %d", len(report.Resources))
+	}
+	res := report.Resources[0]
+	if res.URL != instance {
+		t.Errorf("expected the first resource to be %s without its query, got %s", instance, res.URL)
+	}
+	if len(res.Calls) != 2 || res.Retries != 2 {
+		t.Errorf("expected 2 calls with 2 retries, got %d calls with %d retries", len(res.Calls), res.Retries)
+	}
+	if res.Status != 409 || res.Calls[1].Error == "" {
+		t.Errorf("expected the status and error of the failed call, got %d and %q", res.Status, res.Calls[1].Error)
+	}
+	if len(report.Operations) != 1 || report.Operations[0].Error != "quota exceeded" {
+		t.Errorf("expected the failed operation, got %v", report.Operations)
+	}
+	if len(report.Locks) != 1 || report.Locks[0].WaitedMs != 1000 || report.Locks[0].HeldMs != 2000 {
+		t.Errorf("expected the lock to be held for 2s after waiting 1s, got %v", report.Locks)
+	}
+}
+
+func TestApplyReport_batchesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	var writes int32
+	f := newReportFile("test report", path, func() ([]byte, error) {
+		atomic.AddInt32(&writes, 1)
+		return []byte("{}"), nil
+	})
+
+	for i := 0; i < 10; i++ {
+		f.scheduleWrite()
+	}
+	f.flush()
+	// Nothing is pending after the flush, so neither the timer nor a second
+	// flush write again
+	f.flush()
+	time.Sleep(reportWriteInterval + 100*time.Millisecond)
+	if n := atomic.LoadInt32(&writes); n != 1 {
+		t.Errorf("expected 1 write, got %d", n)
+	}
+
+	f.scheduleWrite()
+	time.Sleep(reportWriteInterval + 100*time.Millisecond)
+	if n := atomic.LoadInt32(&writes); n != 2 {
+		t.Errorf("expected the scheduled write after the write interval, got %d writes", n)
+	}
+}
+
+func TestApplyReport_nilIsNoop(t *testing.T) {
+	var r *applyReport
+	r.recordCall("GET", "https://example.com", time.Now(), 1, 200, nil)
+	r.recordOperation("operation-1", "Creating", time.Now(), nil)
+	r.recordLock("key", time.Now(), 0, 0)
+}
@@ -0,0 +1,131 @@
+// Helpers for resources that can start a long-running operation without
+// waiting for it, eg to let CI pipelines move on while a resource that takes
+// an hour to provision is created. Resources opt in by adding
+// waitForCompletionSchema() as "wait_for_completion" and
+// pendingOperationSchema() as "pending_operation". When wait_for_completion is
+// false they record the operation with recordPendingOperation instead of
+// waiting for it, and their Read calls reconcilePendingOperation to check on
+// it and report its failure.
+
+package google
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// The kind of operation recorded in pending_operation when it creates the
+// resource. Resources may record other kinds, eg "update".
+const asyncOperationCreate = "create"
+
+func waitForCompletionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     true,
+		Description: `Whether to wait for long-running operations on the resource to complete. If false, Terraform records the operation in pending_operation and checks on it the next time the resource is refreshed, reporting its failure then.`,
+	}
+}
+
+func pendingOperationSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Computed:    true,
+		Description: `The operation started without waiting for it to complete, if it hasn't been seen to complete yet.`,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: `The name of the operation.`,
+				},
+				"kind": {
+					Type:        schema.TypeString,
+					Computed:    true,
+					Description: `Whether the operation creates or updates the resource.`,
+				},
+			},
+		},
+	}
+}
+
+// skipOperationWait returns whether the resource's operations shouldn't be
+// waited for.
+func skipOperationWait(d TerraformResourceData) bool {
+	wait, ok := d.Get("wait_for_completion").(bool)
+	return ok && !wait
+}
+
+// recordPendingOperation records the operation name of kind, started without
+// waiting for it.
+func recordPendingOperation(d *schema.ResourceData, name, kind string) error {
+	log.Printf("[INFO] Not waiting for operation %s to %s %s, it'll be checked on the next refresh", name, kind, d.Id())
+	return d.Set("pending_operation", []interface{}{
+		map[string]interface{}{
+			"name": name,
+			"kind": kind,
+		},
+	})
+}
+
+// asyncOperationError is returned by reconcilePendingOperation when the
+// pending operation failed.
+type asyncOperationError struct {
+	Name string
+	Kind string
+	Err  error
+}
+
+func (e *asyncOperationError) Error() string {
+	return fmt.Sprintf("operation %s to %s the resource, started without waiting for it to complete, failed: %s", e.Name, e.Kind, e.Err)
+}
+
+func (e *asyncOperationError) Unwrap() error {
+	return e.Err
+}
+
+// reconcilePendingOperation checks on the operation recorded in
+// pending_operation, if any, using w to query it. The operation is forgotten
+// once it's complete. If it failed an *asyncOperationError is returned, and
+// it's up to the resource to reconcile its state, eg by removing a resource
+// that failed to be created. The operation is forgotten either way, so the
+// caller should return nil from Read once it has done so.
+func reconcilePendingOperation(d *schema.ResourceData, w Waiter) error {
+	pending, _ := d.Get("pending_operation").([]interface{})
+	if len(pending) == 0 || pending[0] == nil {
+		return nil
+	}
+	p := pending[0].(map[string]interface{})
+	name, kind := p["name"].(string), p["kind"].(string)
+
+	if err := w.SetOp(map[string]interface{}{"name": name}); err != nil {
+		return err
+	}
+	op, err := w.QueryOp()
+	if err != nil {
+		if ok, _ := isOperationNotFoundError(err); ok {
+			// Operations are only kept for a while after they complete.
+			log.Printf("[WARN] Pending operation %s for %s was not found, assuming it completed", name, d.Id())
+			return d.Set("pending_operation", nil)
+		}
+		return fmt.Errorf("Error reading pending operation %s: %s", name, err)
+	}
+	if err := w.SetOp(op); err != nil {
+		return fmt.Errorf("Cannot continue, unable to use operation: %s", err)
+	}
+
+	if opErr := w.Error(); opErr != nil {
+		if err := d.Set("pending_operation", nil); err != nil {
+			return err
+		}
+		return &asyncOperationError{Name: name, Kind: kind, Err: opErr}
+	}
+	if !OperationDone(w) {
+		log.Printf("[INFO] Pending operation %s for %s is still running", name, d.Id())
+		return nil
+	}
+	log.Printf("[DEBUG] Pending operation %s for %s completed", name, d.Id())
+	return d.Set("pending_operation", nil)
+}
@@ -0,0 +1,85 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/googleapi"
+)
+
+// pendingOperationWaiter is a Waiter whose QueryOp returns op or err.
+type pendingOperationWaiter struct {
+	op  map[string]interface{}
+	err error
+	CommonOperationWaiter
+}
+
+func (w *pendingOperationWaiter) QueryOp() (interface{}, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	return w.op, nil
+}
+
+func TestReconcilePendingOperation(t *testing.T) {
+	cases := map[string]struct {
+		op      map[string]interface{}
+		err     error
+		pending bool
+		failed  bool
+	}{
+		"running": {
+			op:      map[string]interface{}{"name": "operations/op-1", "done": false},
+			pending: true,
+		},
+		"done": {
+			op: map[string]interface{}{"name": "operations/op-1", "done": true},
+		},
+		"failed": {
+			op: map[string]interface{}{
+				"name":  "operations/op-1",
+				"done":  true,
+				"error": map[string]interface{}{"code": 9, "message": "Composer environment failed to start"},
+			},
+			failed: true,
+		},
+		"expired": {
+			err: &googleapi.Error{Code: 404, Message: "operation not found"},
+		},
+	}
+
+	s := map[string]*schema.Schema{
+		"wait_for_completion": waitForCompletionSchema(),
+		"pending_operation":   pendingOperationSchema(),
+	}
+	for tn, tc := range cases {
+		d := schema.TestResourceDataRaw(t, s, map[string]interface{}{"wait_for_completion": false})
+		d.SetId("projects/p/locations/l/environments/e")
+		if !skipOperationWait(d) {
+			t.Fatalf("%s: expected the operation not to be waited for", tn)
+		}
+		if err := recordPendingOperation(d, "operations/op-1", asyncOperationCreate); err != nil {
+			t.Fatalf("%s: unexpected error: %s", tn, err)
+		}
+
+		err := reconcilePendingOperation(d, &pendingOperationWaiter{op: tc.op, err: tc.err})
+		failed, ok := err.(*asyncOperationError)
+		if tc.failed != ok || (err != nil && !ok) {
+			t.Errorf("%s: expected failure %t, got error %v", tn, tc.failed, err)
+		}
+		if ok && failed.Kind != asyncOperationCreate {
+			t.Errorf("%s: expected a failed create, got %q", tn, failed.Kind)
+		}
+		if pending := len(d.Get("pending_operation").([]interface{})) > 0; pending != tc.pending {
+			t.Errorf("%s: expected pending operation %t, got %t", tn, tc.pending, pending)
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, s, map[string]interface{}{})
+	if skipOperationWait(d) {
+		t.Errorf("expected operations to be waited for by default")
+	}
+	if err := reconcilePendingOperation(d, &pendingOperationWaiter{err: &googleapi.Error{Code: 500}}); err != nil {
+		t.Errorf("expected no operation to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// How long the zones of a project are remembered. Zones are rarely added or
+// go down, and a stale list only delays that being noticed until the next
+// run, so it's long enough to cover a large plan.
+const zoneListCacheTTL = 10 * time.Minute
+
+// availableZone is a Compute Engine zone, as used by the provider.
+type availableZone struct {
+	Name string
+	// Region is the name of the region the zone is in.
+	Region string
+	// Status is UP or DOWN.
+	Status string
+}
+
+// zoneListCache remembers the zones of projects, so data sources and
+// resources validating zones don't each list them. Concurrent lookups of the
+// same project share a single compute.zones.list call.
+type zoneListCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]*zoneListEntry
+}
+
+type zoneListEntry struct {
+	// done is closed once zones and err are set.
+	done    chan struct{}
+	zones   []availableZone
+	err     error
+	expires time.Time
+}
+
+func newZoneListCache(ttl time.Duration, clock Clock) *zoneListCache {
+	return &zoneListCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]*zoneListEntry),
+	}
+}
+
+// get returns the zones of project, calling list if they aren't cached.
+// Errors aren't cached.
+func (c *zoneListCache) get(project string, list func() ([]availableZone, error)) ([]availableZone, error) {
+	if c == nil {
+		return list()
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[project]
+	if ok {
+		select {
+		case <-e.done:
+			if e.err != nil || !c.clock.Now().Before(e.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		e = &zoneListEntry{done: make(chan struct{})}
+		c.entries[project] = e
+		c.mu.Unlock()
+
+		e.zones, e.err = list()
+		e.expires = c.clock.Now().Add(c.ttl)
+		close(e.done)
+		return e.zones, e.err
+	}
+	c.mu.Unlock()
+
+	<-e.done
+	return e.zones, e.err
+}
+
+// getAvailableZones returns the names of the zones of project in region with
+// status statusFilter, sorted. An empty region or statusFilter matches every
+// zone. Zones are listed once per project and cached for zoneListCacheTTL.
+func getAvailableZones(config *Config, project, region, statusFilter string) ([]string, error) {
+	zones, err := listProjectZones(config, project)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, z := range zones {
+		if region != "" && z.Region != region {
+			continue
+		}
+		if statusFilter != "" && z.Status != statusFilter {
+			continue
+		}
+		names = append(names, z.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// getAvailableZone returns zone of project, or an error listing the zones of
+// its region if project has no such zone.
+func getAvailableZone(config *Config, project, zone string) (*availableZone, error) {
+	zones, err := listProjectZones(config, project)
+	if err != nil {
+		return nil, err
+	}
+
+	name := GetResourceNameFromSelfLink(zone)
+	for _, z := range zones {
+		if z.Name == name {
+			return &z, nil
+		}
+	}
+
+	region := getRegionFromZone(name)
+	inRegion, _ := getAvailableZones(config, project, region, "")
+	if len(inRegion) == 0 {
+		return nil, fmt.Errorf("Zone %q was not found in project %s", name, project)
+	}
+	return nil, fmt.Errorf("Zone %q was not found in project %s, the zones of region %s are: %s", name, project, region, strings.Join(inRegion, ", "))
+}
+
+func listProjectZones(config *Config, project string) ([]availableZone, error) {
+	return config.zoneLists.get(project, func() ([]availableZone, error) {
+		log.Printf("[DEBUG] Listing the zones of project %s", project)
+		zones := []availableZone{}
+		call := config.NewComputeClient(config.userAgent).Zones.List(project)
+		for {
+			res, err := call.Do()
+			if err != nil {
+				return nil, errwrap.Wrapf(fmt.Sprintf("Error listing the zones of project %s: {{err}}", project), err)
+			}
+			for _, z := range res.Items {
+				zones = append(zones, availableZone{
+					Name:   z.Name,
+					Region: GetResourceNameFromSelfLink(z.Region),
+					Status: z.Status,
+				})
+			}
+			if res.NextPageToken == "" {
+				return zones, nil
+			}
+			call.PageToken(res.NextPageToken)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package google
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestZoneListCache(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := newZoneListCache(time.Minute, clock)
+
+	var calls int32
+	list := func() ([]availableZone, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return []availableZone{{Name: "us-central1-a", Region: "us-central1", Status: "UP"}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			zones, err := c.get("p", list)
+			if err != nil || len(zones) != 1 {
+				t.Errorf("expected 1 zone, got %v, %v", zones, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("expected concurrent lookups to share 1 call, got %d", calls)
+	}
+
+	if _, err := c.get("other", list); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected projects to be cached separately, got %d calls", calls)
+	}
+
+	clock.Sleep(time.Minute)
+	if _, err := c.get("p", list); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected expired zones to be listed again, got %d calls", calls)
+	}
+}
+
+func TestZoneListCache_errorsAreNotCached(t *testing.T) {
+	c := newZoneListCache(time.Minute, systemClock{})
+	calls := 0
+	fail := func() ([]availableZone, error) {
+		calls++
+		return nil, fmt.Errorf("quota exceeded")
+	}
+
+	if _, err := c.get("p", fail); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if _, err := c.get("p", fail); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if calls != 2 {
+		t.Errorf("expected failed lookups to be retried, got %d calls", calls)
+	}
+}
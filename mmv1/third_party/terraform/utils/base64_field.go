@@ -0,0 +1,123 @@
+// Helpers for string fields holding base64 encoded binary data, such as KMS
+// ciphertexts, Pub/Sub message data or DER encoded certificates. Users often
+// supply values with line breaks (eg from heredocs or `base64 -w 76`) or
+// without padding, so values are decoded leniently and compared by the data
+// they encode rather than by their encoding.
+
+package google
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// decodeBase64Field decodes a standard base64 value, ignoring whitespace and
+// missing padding.
+func decodeBase64Field(v string) ([]byte, error) {
+	s := strings.Join(strings.Fields(v), "")
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// expandBase64 returns the canonical encoding of a base64 value, as sent to
+// APIs.
+func expandBase64(v interface{}) (string, error) {
+	s, _ := v.(string)
+	if s == "" {
+		return "", nil
+	}
+	b, err := decodeBase64Field(s)
+	if err != nil {
+		return "", fmt.Errorf("could not decode %q as base64: %s", s, err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// flattenBase64 returns the base64 value v returned by an API, or the value
+// of key in d if it encodes the same data, so that differently formatted
+// configured values are kept as is.
+func flattenBase64(v interface{}, d TerraformResourceData, key string) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if configured, ok := d.Get(key).(string); ok && base64DiffSuppress(key, configured, s, nil) {
+		return configured
+	}
+	return s
+}
+
+// base64DiffSuppress suppresses diffs between values encoding the same data.
+func base64DiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	if old == new {
+		return true
+	}
+	o, err := decodeBase64Field(old)
+	if err != nil {
+		return false
+	}
+	n, err := decodeBase64Field(new)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(o, n)
+}
+
+// validateBase64 returns a SchemaValidateFunc checking that values are base64
+// encoded data of at most maxBytes bytes, or of any size if maxBytes is 0. If
+// contentTypes is set, the data's sniffed MIME type (see
+// http.DetectContentType) must be one of them. Values whose decoded data is
+// itself base64 text produce a warning, as they were likely encoded twice.
+func validateBase64(maxBytes int, contentTypes ...string) schema.SchemaValidateFunc {
+	return func(i interface{}, k string) (ws []string, es []error) {
+		v, ok := i.(string)
+		if !ok {
+			es = append(es, fmt.Errorf("expected type of %s to be string", k))
+			return
+		}
+		if v == "" {
+			return
+		}
+
+		b, err := decodeBase64Field(v)
+		if err != nil {
+			es = append(es, fmt.Errorf("%s: could not decode value as base64: %s. Please use the terraform base64 functions such as base64encode() or filebase64() to supply a valid base64 string", k, err))
+			return
+		}
+		if maxBytes > 0 && len(b) > maxBytes {
+			es = append(es, fmt.Errorf("%s: decoded value is %d bytes, which is more than the maximum of %d", k, len(b), maxBytes))
+		}
+		if len(contentTypes) > 0 {
+			sniffed, _, err := mime.ParseMediaType(http.DetectContentType(b))
+			if err != nil {
+				sniffed = "application/octet-stream"
+			}
+			if !stringInSlice(contentTypes, sniffed) {
+				es = append(es, fmt.Errorf("%s: decoded value looks like %s, expected one of %s", k, sniffed, strings.Join(contentTypes, ", ")))
+			}
+		}
+		if len(b) > 0 && isBase64Text(b) {
+			ws = append(ws, fmt.Sprintf("%s: decoded value is itself base64 encoded, check it isn't encoded twice", k))
+		}
+		return
+	}
+}
+
+// isBase64Text returns whether b is printable base64 text of a plausible
+// length, rather than binary data that happens to decode.
+func isBase64Text(b []byte) bool {
+	s := strings.TrimSpace(string(b))
+	if len(s) < 16 || len(s)%4 != 0 {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,143 @@
+package google
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestExpandBase64(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte("hello, world"))
+	cases := map[string]struct {
+		value    string
+		expected string
+		err      bool
+	}{
+		"canonical": {
+			value:    data,
+			expected: data,
+		},
+		"line breaks": {
+			value:    data[:8] + "\n" + data[8:] + "\n",
+			expected: data,
+		},
+		"missing padding": {
+			value:    strings.TrimRight(base64.StdEncoding.EncodeToString([]byte("hi")), "="),
+			expected: base64.StdEncoding.EncodeToString([]byte("hi")),
+		},
+		"empty": {
+			value:    "",
+			expected: "",
+		},
+		"invalid": {
+			value: "not base64!",
+			err:   true,
+		},
+	}
+
+	for tn, tc := range cases {
+		got, err := expandBase64(tc.value)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", tn, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestBase64DiffSuppress(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte("hello, world!"))
+	cases := map[string]struct {
+		old, new string
+		suppress bool
+	}{
+		"same": {
+			old:      data,
+			new:      data,
+			suppress: true,
+		},
+		"trailing newline": {
+			old:      data,
+			new:      data + "\n",
+			suppress: true,
+		},
+		"padding": {
+			old:      data,
+			new:      strings.TrimRight(data, "="),
+			suppress: true,
+		},
+		"different data": {
+			old:      data,
+			new:      base64.StdEncoding.EncodeToString([]byte("goodbye")),
+			suppress: false,
+		},
+		"invalid": {
+			old:      data,
+			new:      "not base64!",
+			suppress: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := base64DiffSuppress("data", tc.old, tc.new, nil); got != tc.suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, tc.suppress, got)
+		}
+	}
+}
+
+func TestValidateBase64(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n0000"))
+	cases := map[string]struct {
+		validate func(interface{}, string) ([]string, []error)
+		value    string
+		errs     int
+		warnings int
+	}{
+		"valid": {
+			validate: validateBase64(0),
+			value:    base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 3}),
+		},
+		"invalid": {
+			validate: validateBase64(0),
+			value:    "not base64!",
+			errs:     1,
+		},
+		"too large": {
+			validate: validateBase64(3),
+			value:    base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 3}),
+			errs:     1,
+		},
+		"expected content type": {
+			validate: validateBase64(0, "image/png"),
+			value:    png,
+		},
+		"unexpected content type": {
+			validate: validateBase64(0, "application/octet-stream"),
+			value:    png,
+			errs:     1,
+		},
+		"encoded twice": {
+			validate: validateBase64(0),
+			value:    base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString([]byte("hello, world")))),
+			warnings: 1,
+		},
+	}
+
+	for tn, tc := range cases {
+		ws, es := tc.validate(tc.value, "data")
+		if len(es) != tc.errs {
+			t.Errorf("%s: expected %d errors, got %v", tn, tc.errs, es)
+		}
+		if len(ws) != tc.warnings {
+			t.Errorf("%s: expected %d warnings, got %v", tn, tc.warnings, ws)
+		}
+	}
+}
@@ -15,11 +15,17 @@ type BigtableClientFactory struct {
 	TokenSource         oauth2.TokenSource
 	BillingProject      string
 	UserProjectOverride bool
+	RequestReason       string
+	RequestHeaders      map[string]string
 }
 
-func (s BigtableClientFactory) NewInstanceAdminClient(project string) (*bigtable.InstanceAdminClient, error) {
+func (s BigtableClientFactory) clientOptions() []option.ClientOption {
 	var opts []option.ClientOption
-	if requestReason := os.Getenv("CLOUDSDK_CORE_REQUEST_REASON"); requestReason != "" {
+	requestReason := s.RequestReason
+	if requestReason == "" {
+		requestReason = os.Getenv("CLOUDSDK_CORE_REQUEST_REASON")
+	}
+	if requestReason != "" {
 		opts = append(opts, option.WithRequestReason(requestReason))
 	}
 
@@ -28,39 +34,19 @@ func (s BigtableClientFactory) NewInstanceAdminClient(project string) (*bigtable
 	}
 
 	opts = append(opts, option.WithTokenSource(s.TokenSource), option.WithUserAgent(s.UserAgent))
+	opts = append(opts, requestHeadersGRPCOptions(s.RequestHeaders)...)
 	opts = append(opts, s.gRPCLoggingOptions...)
+	return opts
+}
 
-	return bigtable.NewInstanceAdminClient(context.Background(), project, opts...)
+func (s BigtableClientFactory) NewInstanceAdminClient(project string) (*bigtable.InstanceAdminClient, error) {
+	return bigtable.NewInstanceAdminClient(context.Background(), project, s.clientOptions()...)
 }
 
 func (s BigtableClientFactory) NewAdminClient(project, instance string) (*bigtable.AdminClient, error) {
-	var opts []option.ClientOption
-	if requestReason := os.Getenv("CLOUDSDK_CORE_REQUEST_REASON"); requestReason != "" {
-		opts = append(opts, option.WithRequestReason(requestReason))
-	}
-
-	if s.UserProjectOverride && s.BillingProject != "" {
-		opts = append(opts, option.WithQuotaProject(s.BillingProject))
-	}
-
-	opts = append(opts, option.WithTokenSource(s.TokenSource), option.WithUserAgent(s.UserAgent))
-	opts = append(opts, s.gRPCLoggingOptions...)
-
-	return bigtable.NewAdminClient(context.Background(), project, instance, opts...)
+	return bigtable.NewAdminClient(context.Background(), project, instance, s.clientOptions()...)
 }
 
 func (s BigtableClientFactory) NewClient(project, instance string) (*bigtable.Client, error) {
-	var opts []option.ClientOption
-	if requestReason := os.Getenv("CLOUDSDK_CORE_REQUEST_REASON"); requestReason != "" {
-		opts = append(opts, option.WithRequestReason(requestReason))
-	}
-
-	if s.UserProjectOverride && s.BillingProject != "" {
-		opts = append(opts, option.WithQuotaProject(s.BillingProject))
-	}
-
-	opts = append(opts, option.WithTokenSource(s.TokenSource), option.WithUserAgent(s.UserAgent))
-	opts = append(opts, s.gRPCLoggingOptions...)
-
-	return bigtable.NewClient(context.Background(), project, instance, opts...)
+	return bigtable.NewClient(context.Background(), project, instance, s.clientOptions()...)
 }
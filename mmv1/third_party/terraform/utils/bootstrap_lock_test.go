@@ -0,0 +1,253 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/storage/v1"
+)
+
+// Acceptance tests run in parallel, often in several processes, and the ones
+// sharing a bootstrapped fixture would all create it the first time they run.
+// The creation of shared fixtures is serialized by a lock, held in the GCS
+// bucket named by GOOGLE_BOOTSTRAP_LOCK_BUCKET if it's set, so it's shared by
+// every machine running tests in the project, or else in a lock file only
+// shared by the processes of this machine.
+var bootstrapLockBucketEnvVars = []string{
+	"GOOGLE_BOOTSTRAP_LOCK_BUCKET",
+}
+
+// A lock older than this is assumed to have been left behind by a process
+// that was killed while creating a fixture, and is broken.
+const bootstrapLockTTL = 30 * time.Minute
+
+// How often a held lock is polled while waiting for it.
+const bootstrapLockPollInterval = 2 * time.Second
+
+// bootstrapLocker acquires the lock serializing the creation of the shared
+// fixture name, returning the function releasing it.
+type bootstrapLocker interface {
+	Lock(name string) (unlock func() error, err error)
+}
+
+// newBootstrapLocker returns the GCS locker if a lock bucket is set, or else
+// the file locker.
+func newBootstrapLocker(config *Config) bootstrapLocker {
+	if bucket := multiEnvSearch(bootstrapLockBucketEnvVars); bucket != "" {
+		return &gcsBootstrapLocker{
+			client: config.NewStorageClient(config.userAgent),
+			bucket: bucket,
+			ttl:    bootstrapLockTTL,
+			poll:   bootstrapLockPollInterval,
+		}
+	}
+	return &fileBootstrapLocker{
+		dir:  os.TempDir(),
+		ttl:  bootstrapLockTTL,
+		poll: bootstrapLockPollInterval,
+	}
+}
+
+// getOrCreateSharedFixture creates the shared fixture name unless get finds
+// it. The fixture is looked for again once the lock is held, so only the
+// first of the tests waiting for the lock creates it.
+func getOrCreateSharedFixture(locker bootstrapLocker, name string, get func() (bool, error), create func() error) error {
+	found, err := get()
+	if err != nil || found {
+		return err
+	}
+
+	log.Printf("[DEBUG] Shared fixture %q not found, acquiring lock to bootstrap it", name)
+	unlock, err := locker.Lock(name)
+	if err != nil {
+		return fmt.Errorf("Error acquiring lock to bootstrap shared fixture %q: %s", name, err)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			log.Printf("[WARN] Error releasing lock of shared fixture %q: %s", name, err)
+		}
+	}()
+
+	found, err = get()
+	if err != nil || found {
+		return err
+	}
+	log.Printf("[DEBUG] Bootstrapping shared fixture %q", name)
+	return create()
+}
+
+// bootstrapLockName returns name with the characters not allowed in file and
+// object names replaced.
+func bootstrapLockName(name string) string {
+	return "tf-bootstrap-lock-" + strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(name)
+}
+
+// fileBootstrapLocker holds locks by exclusively creating a file in dir.
+type fileBootstrapLocker struct {
+	dir  string
+	ttl  time.Duration
+	poll time.Duration
+}
+
+func (l *fileBootstrapLocker) Lock(name string) (func() error, error) {
+	path := filepath.Join(l.dir, bootstrapLockName(name))
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d", os.Getpid())
+			f.Close()
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > l.ttl {
+			log.Printf("[WARN] Breaking stale lock file %s", path)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+		time.Sleep(l.poll)
+	}
+}
+
+// gcsBootstrapLocker holds locks by creating an object in bucket, with a
+// precondition failing if it already exists.
+type gcsBootstrapLocker struct {
+	client *storage.Service
+	bucket string
+	ttl    time.Duration
+	poll   time.Duration
+}
+
+func (l *gcsBootstrapLocker) Lock(name string) (func() error, error) {
+	object := bootstrapLockName(name)
+	for {
+		obj, err := l.client.Objects.Insert(l.bucket, &storage.Object{Name: object}).
+			IfGenerationMatch(0).
+			Media(strings.NewReader(fmt.Sprintf("%d", os.Getpid()))).
+			Do()
+		if err == nil {
+			return func() error {
+				return l.client.Objects.Delete(l.bucket, object).IfGenerationMatch(obj.Generation).Do()
+			}, nil
+		}
+		if !isGoogleApiErrorWithCode(err, 412) {
+			return nil, err
+		}
+
+		held, err := l.client.Objects.Get(l.bucket, object).Do()
+		if err != nil {
+			if isGoogleApiErrorWithCode(err, 404) {
+				continue
+			}
+			return nil, err
+		}
+		if created, err := time.Parse(time.RFC3339, held.TimeCreated); err == nil && time.Since(created) > l.ttl {
+			log.Printf("[WARN] Breaking stale lock gs://%s/%s", l.bucket, object)
+			err := l.client.Objects.Delete(l.bucket, object).IfGenerationMatch(held.Generation).Do()
+			if err != nil && !isGoogleApiErrorWithCode(err, 404) && !isGoogleApiErrorWithCode(err, 412) {
+				return nil, err
+			}
+			continue
+		}
+		time.Sleep(l.poll)
+	}
+}
+
+func TestFileBootstrapLocker(t *testing.T) {
+	locker := &fileBootstrapLocker{dir: t.TempDir(), ttl: time.Hour, poll: time.Millisecond}
+
+	unlock, err := locker.Lock("projects/p/networks/n")
+	if err != nil {
+		t.Fatalf("unexpected error acquiring the lock: %s", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := locker.Lock("projects/p/networks/n")
+		if err != nil {
+			t.Errorf("unexpected error acquiring the lock: %s", err)
+		} else {
+			unlock()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("expected the lock to be held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unexpected error releasing the lock: %s", err)
+	}
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the lock to be acquired once released")
+	}
+}
+
+func TestFileBootstrapLocker_stale(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, bootstrapLockName("n"))
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	locker := &fileBootstrapLocker{dir: dir, ttl: time.Hour, poll: time.Millisecond}
+	unlock, err := locker.Lock("n")
+	if err != nil {
+		t.Fatalf("expected the stale lock to be broken, got error: %s", err)
+	}
+	unlock()
+}
+
+func TestGetOrCreateSharedFixture(t *testing.T) {
+	locker := &fileBootstrapLocker{dir: t.TempDir(), ttl: time.Hour, poll: time.Millisecond}
+
+	var mu sync.Mutex
+	exists, creates := false, 0
+	get := func() (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return exists, nil
+	}
+	create := func() error {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		exists = true
+		creates++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := getOrCreateSharedFixture(locker, "n", get, create); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if creates != 1 {
+		t.Errorf("expected the fixture to be created once, got %d", creates)
+	}
+}
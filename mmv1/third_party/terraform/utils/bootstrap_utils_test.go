@@ -73,17 +73,18 @@ func BootstrapKMSKeyWithPurposeInLocationAndName(t *testing.T, purpose, location
 
 	// Get or Create the hard coded shared keyring for testing
 	kmsClient := config.NewKmsClient(config.userAgent)
-	keyRing, err := kmsClient.Projects.Locations.KeyRings.Get(keyRingName).Do()
+	locker := newBootstrapLocker(config)
+	var keyRing *cloudkms.KeyRing
+	err := getOrCreateSharedFixture(locker, keyRingName, func() (found bool, err error) {
+		keyRing, err = kmsClient.Projects.Locations.KeyRings.Get(keyRingName).Do()
+		return bootstrapFound(err)
+	}, func() (err error) {
+		keyRing, err = kmsClient.Projects.Locations.KeyRings.Create(keyRingParent, &cloudkms.KeyRing{}).
+			KeyRingId(SharedKeyRing).Do()
+		return err
+	})
 	if err != nil {
-		if isGoogleApiErrorWithCode(err, 404) {
-			keyRing, err = kmsClient.Projects.Locations.KeyRings.Create(keyRingParent, &cloudkms.KeyRing{}).
-				KeyRingId(SharedKeyRing).Do()
-			if err != nil {
-				t.Errorf("Unable to bootstrap KMS key. Cannot create keyRing: %s", err)
-			}
-		} else {
-			t.Errorf("Unable to bootstrap KMS key. Cannot retrieve keyRing: %s", err)
-		}
+		t.Errorf("Unable to bootstrap KMS key. Cannot get or create keyRing: %s", err)
 	}
 
 	if keyRing == nil {
@@ -91,32 +92,31 @@ func BootstrapKMSKeyWithPurposeInLocationAndName(t *testing.T, purpose, location
 	}
 
 	// Get or Create the hard coded, shared crypto key for testing
-	cryptoKey, err := kmsClient.Projects.Locations.KeyRings.CryptoKeys.Get(keyName).Do()
-	if err != nil {
-		if isGoogleApiErrorWithCode(err, 404) {
-			algos := map[string]string{
-				"ENCRYPT_DECRYPT":    "GOOGLE_SYMMETRIC_ENCRYPTION",
-				"ASYMMETRIC_SIGN":    "RSA_SIGN_PKCS1_4096_SHA512",
-				"ASYMMETRIC_DECRYPT": "RSA_DECRYPT_OAEP_4096_SHA512",
-			}
-			template := cloudkms.CryptoKeyVersionTemplate{
-				Algorithm: algos[purpose],
-			}
-
-			newKey := cloudkms.CryptoKey{
-				Purpose:         purpose,
-				VersionTemplate: &template,
-			}
-
-			cryptoKey, err = kmsClient.Projects.Locations.KeyRings.CryptoKeys.Create(keyParent, &newKey).
-				CryptoKeyId(keyShortName).Do()
-			if err != nil {
-				t.Errorf("Unable to bootstrap KMS key. Cannot create new CryptoKey: %s", err)
-			}
-
-		} else {
-			t.Errorf("Unable to bootstrap KMS key. Cannot call CryptoKey service: %s", err)
+	var cryptoKey *cloudkms.CryptoKey
+	err = getOrCreateSharedFixture(locker, keyName, func() (found bool, err error) {
+		cryptoKey, err = kmsClient.Projects.Locations.KeyRings.CryptoKeys.Get(keyName).Do()
+		return bootstrapFound(err)
+	}, func() (err error) {
+		algos := map[string]string{
+			"ENCRYPT_DECRYPT":    "GOOGLE_SYMMETRIC_ENCRYPTION",
+			"ASYMMETRIC_SIGN":    "RSA_SIGN_PKCS1_4096_SHA512",
+			"ASYMMETRIC_DECRYPT": "RSA_DECRYPT_OAEP_4096_SHA512",
+		}
+		template := cloudkms.CryptoKeyVersionTemplate{
+			Algorithm: algos[purpose],
 		}
+
+		newKey := cloudkms.CryptoKey{
+			Purpose:         purpose,
+			VersionTemplate: &template,
+		}
+
+		cryptoKey, err = kmsClient.Projects.Locations.KeyRings.CryptoKeys.Create(keyParent, &newKey).
+			CryptoKeyId(keyShortName).Do()
+		return err
+	})
+	if err != nil {
+		t.Errorf("Unable to bootstrap KMS key. Cannot get or create CryptoKey: %s", err)
 	}
 
 	if cryptoKey == nil {
@@ -129,6 +129,15 @@ func BootstrapKMSKeyWithPurposeInLocationAndName(t *testing.T, purpose, location
 	}
 }
 
+// bootstrapFound returns whether the get request of a shared fixture that
+// returned err found it.
+func bootstrapFound(err error) (bool, error) {
+	if isGoogleApiErrorWithCode(err, 404) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 var serviceAccountEmail = "tf-bootstrap-service-account"
 var serviceAccountDisplay = "Bootstrapped Service Account for Terraform tests"
 
@@ -139,25 +148,23 @@ func getOrCreateServiceAccount(config *Config, project string) (*iam.ServiceAcco
 	name := fmt.Sprintf("projects/%s/serviceAccounts/%s@%s.iam.gserviceaccount.com", project, serviceAccountEmail, project)
 	log.Printf("[DEBUG] Verifying %s as bootstrapped service account.\n", name)
 
-	sa, err := config.NewIamClient(config.userAgent).Projects.ServiceAccounts.Get(name).Do()
-	if err != nil && !isGoogleApiErrorWithCode(err, 404) {
-		return nil, err
-	}
-
-	if sa == nil {
+	var sa *iam.ServiceAccount
+	err := getOrCreateSharedFixture(newBootstrapLocker(config), name, func() (found bool, err error) {
+		sa, err = config.NewIamClient(config.userAgent).Projects.ServiceAccounts.Get(name).Do()
+		return bootstrapFound(err)
+	}, func() (err error) {
 		log.Printf("[DEBUG] Account missing. Creating %s as bootstrapped service account.\n", name)
-		sa = &iam.ServiceAccount{
-			DisplayName: serviceAccountDisplay,
-		}
-
 		r := &iam.CreateServiceAccountRequest{
-			AccountId:      serviceAccountEmail,
-			ServiceAccount: sa,
+			AccountId: serviceAccountEmail,
+			ServiceAccount: &iam.ServiceAccount{
+				DisplayName: serviceAccountDisplay,
+			},
 		}
 		sa, err = config.NewIamClient(config.userAgent).Projects.ServiceAccounts.Create("projects/"+project, r).Do()
-		if err != nil {
-			return nil, err
-		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return sa, nil
@@ -272,9 +279,10 @@ func BootstrapSharedTestNetwork(t *testing.T, testId string) string {
 	}
 
 	log.Printf("[DEBUG] Getting shared test network %q", networkName)
-	_, err := config.NewComputeClient(config.userAgent).Networks.Get(project, networkName).Do()
-	if err != nil && isGoogleApiErrorWithCode(err, 404) {
-		log.Printf("[DEBUG] Network %q not found, bootstrapping", networkName)
+	err := getOrCreateSharedFixture(newBootstrapLocker(config), fmt.Sprintf("projects/%s/global/networks/%s", project, networkName), func() (bool, error) {
+		_, err := config.NewComputeClient(config.userAgent).Networks.Get(project, networkName).Do()
+		return bootstrapFound(err)
+	}, func() error {
 		url := fmt.Sprintf("%sprojects/%s/global/networks", config.ComputeBasePath, project)
 		netObj := map[string]interface{}{
 			"name":                  networkName,
@@ -283,14 +291,14 @@ func BootstrapSharedTestNetwork(t *testing.T, testId string) string {
 
 		res, err := sendRequestWithTimeout(config, "POST", project, url, config.userAgent, netObj, 4*time.Minute)
 		if err != nil {
-			t.Fatalf("Error bootstrapping shared test network %q: %s", networkName, err)
+			return err
 		}
 
 		log.Printf("[DEBUG] Waiting for network creation to finish")
-		err = computeOperationWaitTime(config, res, project, "Error bootstrapping shared test network", config.userAgent, 4*time.Minute)
-		if err != nil {
-			t.Fatalf("Error bootstrapping shared test network %q: %s", networkName, err)
-		}
+		return computeOperationWaitTime(config, res, project, "Error bootstrapping shared test network", config.userAgent, 4*time.Minute)
+	})
+	if err != nil {
+		t.Fatalf("Error bootstrapping shared test network %q: %s", networkName, err)
 	}
 
 	network, err := config.NewComputeClient(config.userAgent).Networks.Get(project, networkName).Do()
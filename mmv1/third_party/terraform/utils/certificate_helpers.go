@@ -0,0 +1,195 @@
+// Helpers for fields holding PEM encoded certificates and private keys, eg of
+// SSL certificates and certificate authorities: parsing them, computing their
+// fingerprints, warning at plan time about certificates that expired or are
+// about to, and suppressing diffs between PEM values that only differ in
+// whitespace or in the order of their blocks.
+
+package google
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// How long before a certificate expires validatePemCertificate warns about it.
+const certificateExpiryWarningPeriod = 30 * 24 * time.Hour
+
+// parsePemBlocks decodes every PEM block of s. Lines may be indented, eg in an
+// indented heredoc. It's an error for s to have no blocks, or anything but
+// whitespace outside of them.
+func parsePemBlocks(s string) ([]*pem.Block, error) {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+
+	var blocks []*pem.Block
+	rest := []byte(strings.Join(lines, "\n"))
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no PEM blocks found")
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("unexpected content after the last PEM block")
+	}
+	return blocks, nil
+}
+
+// parsePemCertificates parses a PEM encoded certificate or certificate chain,
+// eg leaf first then intermediates.
+func parsePemCertificates(s string) ([]*x509.Certificate, error) {
+	blocks, err := parsePemBlocks(s)
+	if err != nil {
+		return nil, err
+	}
+	certs := make([]*x509.Certificate, 0, len(blocks))
+	for i, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("PEM block %d is a %s, not a CERTIFICATE", i+1, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("PEM block %d isn't a valid certificate: %s", i+1, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// parsePemPrivateKey parses a PEM encoded PKCS #1, PKCS #8 or EC private key.
+// Other blocks, eg the EC PARAMETERS block openssl writes before EC keys, are
+// skipped, but there must be exactly one key.
+func parsePemPrivateKey(s string) (interface{}, error) {
+	blocks, err := parsePemBlocks(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var key *pem.Block
+	var types []string
+	for _, block := range blocks {
+		types = append(types, block.Type)
+		switch block.Type {
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY":
+			if key != nil {
+				return nil, fmt.Errorf("expected a single private key, found more")
+			}
+			key = block
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("expected a private key, found PEM blocks of types %s", strings.Join(types, ", "))
+	}
+
+	switch key.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(key.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(key.Bytes)
+	}
+	return x509.ParsePKCS8PrivateKey(key.Bytes)
+}
+
+// certificateSha1Fingerprint returns the SHA-1 fingerprint of cert, as
+// colon-separated uppercase hex bytes like openssl prints.
+func certificateSha1Fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return formatCertificateFingerprint(sum[:])
+}
+
+// certificateSha256Fingerprint returns the SHA-256 fingerprint of cert, as
+// colon-separated uppercase hex bytes like openssl prints.
+func certificateSha256Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return formatCertificateFingerprint(sum[:])
+}
+
+func formatCertificateFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// certificateExpiryWarnings returns warnings about certs that aren't valid at
+// now, or stop being valid within certificateExpiryWarningPeriod.
+func certificateExpiryWarnings(k string, certs []*x509.Certificate, now time.Time) []string {
+	var ws []string
+	for _, cert := range certs {
+		switch {
+		case now.After(cert.NotAfter):
+			ws = append(ws, fmt.Sprintf("%q: certificate %q expired at %s", k, cert.Subject, cert.NotAfter.Format(time.RFC3339)))
+		case now.Before(cert.NotBefore):
+			ws = append(ws, fmt.Sprintf("%q: certificate %q isn't valid until %s", k, cert.Subject, cert.NotBefore.Format(time.RFC3339)))
+		case now.Add(certificateExpiryWarningPeriod).After(cert.NotAfter):
+			ws = append(ws, fmt.Sprintf("%q: certificate %q expires soon, at %s", k, cert.Subject, cert.NotAfter.Format(time.RFC3339)))
+		}
+	}
+	return ws
+}
+
+// validatePemCertificate validates a PEM encoded certificate or certificate
+// chain, and warns about certificates in it that expired or expire soon.
+func validatePemCertificate(v interface{}, k string) (ws []string, errs []error) {
+	certs, err := parsePemCertificates(v.(string))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%q isn't a valid PEM encoded certificate: %s", k, err))
+		return
+	}
+	ws = certificateExpiryWarnings(k, certs, time.Now())
+	return
+}
+
+// validatePemPrivateKey validates a PEM encoded private key.
+func validatePemPrivateKey(v interface{}, k string) (ws []string, errs []error) {
+	if _, err := parsePemPrivateKey(v.(string)); err != nil {
+		// The key is sensitive, so it isn't included in the error
+		errs = append(errs, fmt.Errorf("%q isn't a valid PEM encoded private key: %s", k, err))
+	}
+	return
+}
+
+// pemBlocksKey returns a comparable form of the PEM blocks of s, ignoring
+// whitespace and headers. The order of the blocks is kept, as it's
+// meaningful, eg a chain must start with its leaf certificate. ok is false if
+// s isn't PEM.
+func pemBlocksKey(s string) (key string, ok bool) {
+	blocks, err := parsePemBlocks(s)
+	if err != nil {
+		return "", false
+	}
+	keys := make([]string, len(blocks))
+	for i, block := range blocks {
+		keys[i] = block.Type + ":" + hex.EncodeToString(block.Bytes)
+	}
+	return strings.Join(keys, ","), true
+}
+
+// pemDiffSuppress suppresses diffs between PEM values holding the same blocks
+// in the same order, eg a certificate chain with different line endings or
+// trailing newlines than the API returns.
+func pemDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	oldKey, ok := pemBlocksKey(old)
+	if !ok {
+		return false
+	}
+	newKey, ok := pemBlocksKey(new)
+	return ok && oldKey == newKey
+}
@@ -0,0 +1,163 @@
+package google
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPemCertificate returns a self-signed PEM encoded certificate valid from
+// notBefore to notAfter, and its PEM encoded private key.
+func testPemCertificate(t *testing.T, cn string, notBefore, notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pk := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer})
+	return string(cert), string(pk)
+}
+
+func TestParsePemCertificates(t *testing.T) {
+	now := time.Now()
+	leaf, key := testPemCertificate(t, "leaf", now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	intermediate, _ := testPemCertificate(t, "intermediate", now.Add(-time.Hour), now.Add(365*24*time.Hour))
+
+	certs, err := parsePemCertificates(leaf + intermediate)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(certs) != 2 || certs[0].Subject.CommonName != "leaf" || certs[1].Subject.CommonName != "intermediate" {
+		t.Errorf("expected the leaf and intermediate certificates in order, got %v", certs)
+	}
+
+	for tn, s := range map[string]string{
+		"empty":       "",
+		"private key": key,
+		"trailing":    leaf + "not PEM",
+	} {
+		if _, err := parsePemCertificates(s); err == nil {
+			t.Errorf("%s: expected an error", tn)
+		}
+	}
+
+	if _, err := parsePemPrivateKey(key); err != nil {
+		t.Errorf("unexpected error parsing the private key: %s", err)
+	}
+	if _, err := parsePemPrivateKey(leaf); err == nil {
+		t.Errorf("expected an error parsing a certificate as a private key")
+	}
+	if _, err := parsePemPrivateKey(key + key); err == nil {
+		t.Errorf("expected an error parsing two private keys")
+	}
+
+	// openssl ecparam -genkey writes the curve's parameters before the key
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ecDer, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The DER encoding of the P-256 curve's OID
+	params := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}})
+	ecPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDer})
+	if _, err := parsePemPrivateKey(string(params) + string(ecPem)); err != nil {
+		t.Errorf("unexpected error parsing an EC private key after its parameters: %s", err)
+	}
+}
+
+func TestCertificateFingerprints(t *testing.T) {
+	now := time.Now()
+	leaf, _ := testPemCertificate(t, "leaf", now.Add(-time.Hour), now.Add(time.Hour))
+	certs, err := parsePemCertificates(leaf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sha1 := certificateSha1Fingerprint(certs[0])
+	if len(sha1) != 20*3-1 || strings.ToUpper(sha1) != sha1 {
+		t.Errorf("expected a SHA-1 fingerprint like AB:CD:..., got %s", sha1)
+	}
+	sha256 := certificateSha256Fingerprint(certs[0])
+	if len(sha256) != 32*3-1 {
+		t.Errorf("expected a SHA-256 fingerprint like AB:CD:..., got %s", sha256)
+	}
+}
+
+func TestCertificateExpiryWarnings(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		notBefore, notAfter time.Time
+		warning             string
+	}{
+		"valid":    {now.AddDate(0, -1, 0), now.AddDate(1, 0, 0), ""},
+		"expired":  {now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1), "expired"},
+		"expiring": {now.AddDate(-1, 0, 0), now.AddDate(0, 0, 7), "expires soon"},
+		"future":   {now.AddDate(0, 0, 1), now.AddDate(1, 0, 0), "isn't valid until"},
+	}
+	for tn, tc := range cases {
+		cert, _ := testPemCertificate(t, tn, tc.notBefore, tc.notAfter)
+		certs, err := parsePemCertificates(cert)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tn, err)
+		}
+		ws := certificateExpiryWarnings("certificate", certs, now)
+		if tc.warning == "" {
+			if len(ws) != 0 {
+				t.Errorf("%s: expected no warnings, got %v", tn, ws)
+			}
+			continue
+		}
+		if len(ws) != 1 || !strings.Contains(ws[0], tc.warning) {
+			t.Errorf("%s: expected a warning containing %q, got %v", tn, tc.warning, ws)
+		}
+	}
+}
+
+func TestPemDiffSuppress(t *testing.T) {
+	now := time.Now()
+	leaf, _ := testPemCertificate(t, "leaf", now.Add(-time.Hour), now.Add(time.Hour))
+	intermediate, _ := testPemCertificate(t, "intermediate", now.Add(-time.Hour), now.Add(time.Hour))
+	other, _ := testPemCertificate(t, "other", now.Add(-time.Hour), now.Add(time.Hour))
+
+	cases := map[string]struct {
+		old, new string
+		suppress bool
+	}{
+		"same":          {leaf + intermediate, leaf + intermediate, true},
+		"line endings":  {leaf + intermediate, strings.ReplaceAll(leaf+intermediate, "\n", "\r\n"), true},
+		"whitespace":    {leaf + intermediate, "\n  " + leaf + "\n" + intermediate + "\n\n", true},
+		"order":         {leaf + intermediate, intermediate + leaf, false},
+		"different":     {leaf + intermediate, leaf + other, false},
+		"missing block": {leaf + intermediate, leaf, false},
+		"not PEM":       {"foo", "foo", false},
+	}
+	for tn, tc := range cases {
+		if got := pemDiffSuppress("certificate", tc.old, tc.new, nil); got != tc.suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, tc.suppress, got)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Helpers for updating collections of child resources attached to a parent
+// through add and remove calls, such as the instances of an instance group or
+// target pool, rather than as a field of the parent. reconcileChildCollection
+// applies the difference between the current and desired children in
+// batches, and tolerates the failure of some of them if asked to.
+
+package google
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ChildCollectionOptions configures reconcileChildCollection.
+type ChildCollectionOptions struct {
+	// BatchSize is how many children are added or removed per call. Zero adds
+	// and removes them all in a single call each.
+	BatchSize int
+	// ContinueOnError keeps applying the other batches once one fails. The
+	// errors of every failed batch are returned at the end.
+	ContinueOnError bool
+	// RemoveFirst removes children before adding the new ones, freeing the
+	// capacity they take up. Children are added first otherwise, so the
+	// parent is never left without them.
+	RemoveFirst bool
+	// IgnoreRemoveNotFound treats children whose removal fails with a 404 as
+	// already removed.
+	IgnoreRemoveNotFound bool
+	// Timeout is how long each call is retried for, with
+	// ErrorRetryPredicates and ErrorBackoffPredicates. Zero calls them once.
+	Timeout                time.Duration
+	ErrorRetryPredicates   []RetryErrorPredicateFunc
+	ErrorBackoffPredicates []RetryBackoffPredicateFunc
+}
+
+// childCollectionError is returned by reconcileChildCollection with the
+// children it failed to add or remove.
+type childCollectionError struct {
+	FailedAdds    []string
+	FailedRemoves []string
+	err           error
+}
+
+func (e *childCollectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *childCollectionError) WrappedErrors() []error {
+	return []error{e.err}
+}
+
+// reconcileChildCollection adds the children of desired missing from current
+// with addFn, and removes those of current missing from desired with
+// removeFn, see calcAddRemove. Children are passed to addFn and removeFn in
+// batches of opts.BatchSize, each retried per opts. When a batch of several
+// children fails as the API rejects one of them, see isChildCollectionChildError,
+// its children are retried one at a time, so that child doesn't keep the others
+// from being applied. Other errors of a batch are returned unchanged.
+func reconcileChildCollection(current, desired []string, addFn, removeFn func([]string) error, opts ChildCollectionOptions) error {
+	add, remove := calcAddRemove(current, desired)
+	sort.Strings(add)
+	sort.Strings(remove)
+
+	var errs *multierror.Error
+	result := &childCollectionError{}
+	apply := func(verb string, children []string, fn func([]string) error, failed *[]string) bool {
+		if len(children) == 0 {
+			return true
+		}
+		isNotFound := func(err error) bool {
+			return verb == "removing" && opts.IgnoreRemoveNotFound && isGoogleApiErrorWithCode(err, 404)
+		}
+		for _, batch := range childCollectionBatches(children, opts.BatchSize) {
+			log.Printf("[DEBUG] Child collection %s %s", verb, strings.Join(batch, ", "))
+			err := callChildCollectionFn(fn, batch, opts)
+			if err == nil || (len(batch) == 1 && isNotFound(err)) {
+				continue
+			}
+			if len(batch) > 1 && isChildCollectionChildError(err, batch, verb == "removing" && opts.IgnoreRemoveNotFound) {
+				log.Printf("[DEBUG] Error %s children %s, retrying them one at a time: %s", verb, strings.Join(batch, ", "), err)
+				err = nil
+				for _, child := range batch {
+					if cerr := callChildCollectionFn(fn, []string{child}, opts); cerr != nil && !isNotFound(cerr) {
+						*failed = append(*failed, child)
+						errs = multierror.Append(errs, fmt.Errorf("Error %s %s: %s", verb, child, cerr))
+						err = cerr
+					}
+				}
+			} else if len(batch) > 1 {
+				*failed = append(*failed, batch...)
+				errs = multierror.Append(errs, err)
+			} else {
+				*failed = append(*failed, batch...)
+				errs = multierror.Append(errs, fmt.Errorf("Error %s %s: %s", verb, batch[0], err))
+			}
+			if err != nil && !opts.ContinueOnError {
+				return false
+			}
+		}
+		return true
+	}
+
+	if opts.RemoveFirst {
+		if apply("removing", remove, removeFn, &result.FailedRemoves) {
+			apply("adding", add, addFn, &result.FailedAdds)
+		}
+	} else {
+		if apply("adding", add, addFn, &result.FailedAdds) {
+			apply("removing", remove, removeFn, &result.FailedRemoves)
+		}
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		result.err = err
+		return result
+	}
+	return nil
+}
+
+// isChildCollectionChildError returns whether err, returned for batch, is a
+// 400 naming one of its children, or a 404 naming one if notFound is set. Only
+// those errors are specific to a child; others, eg of quotas or permissions,
+// would fail every child alike.
+func isChildCollectionChildError(err error, batch []string, notFound bool) bool {
+	if !isGoogleApiErrorWithCode(err, 400) && !(notFound && isGoogleApiErrorWithCode(err, 404)) {
+		return false
+	}
+	message := err.Error()
+	for _, child := range batch {
+		// Messages name children by their link, path or quoted name
+		name := GetResourceNameFromSelfLink(child)
+		for _, ref := range []string{"/" + name, "'" + name + "'", `"` + name + `"`} {
+			if strings.Contains(message, ref) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callChildCollectionFn calls fn with children, retrying it per opts.
+func callChildCollectionFn(fn func([]string) error, children []string, opts ChildCollectionOptions) error {
+	if opts.Timeout == 0 {
+		return fn(children)
+	}
+	return retryWithOptions(RetryOptions{
+		RetryFunc:              func() error { return fn(children) },
+		Timeout:                opts.Timeout,
+		ErrorRetryPredicates:   opts.ErrorRetryPredicates,
+		ErrorBackoffPredicates: opts.ErrorBackoffPredicates,
+	})
+}
+
+// childCollectionBatches splits children into batches of size, or returns
+// them as a single batch if size isn't positive.
+func childCollectionBatches(children []string, size int) [][]string {
+	if size <= 0 || len(children) <= size {
+		return [][]string{children}
+	}
+	var batches [][]string
+	for len(children) > size {
+		batches = append(batches, children[:size])
+		children = children[size:]
+	}
+	return append(batches, children)
+}
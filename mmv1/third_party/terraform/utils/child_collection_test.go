@@ -0,0 +1,124 @@
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+// fakeChildCollection records the calls made by reconcileChildCollection,
+// failing those including a child of failing.
+type fakeChildCollection struct {
+	calls   []string
+	failing map[string]error
+}
+
+func (c *fakeChildCollection) fn(verb string) func([]string) error {
+	return func(children []string) error {
+		c.calls = append(c.calls, fmt.Sprintf("%s %s", verb, strings.Join(children, ",")))
+		for _, child := range children {
+			if err, ok := c.failing[child]; ok {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestReconcileChildCollection(t *testing.T) {
+	cases := map[string]struct {
+		current, desired []string
+		opts             ChildCollectionOptions
+		failing          map[string]error
+		expectedCalls    []string
+		expectedAdds     []string
+		expectedRemoves  []string
+	}{
+		"adds then removes": {
+			current:       []string{"a", "b"},
+			desired:       []string{"b", "c"},
+			expectedCalls: []string{"add c", "remove a"},
+		},
+		"removes first": {
+			current:       []string{"a", "b"},
+			desired:       []string{"b", "c"},
+			opts:          ChildCollectionOptions{RemoveFirst: true},
+			expectedCalls: []string{"remove a", "add c"},
+		},
+		"batches": {
+			desired:       []string{"e", "d", "c", "b", "a"},
+			opts:          ChildCollectionOptions{BatchSize: 2},
+			expectedCalls: []string{"add a,b", "add c,d", "add e"},
+		},
+		"no changes": {
+			current: []string{"a"},
+			desired: []string{"a"},
+		},
+		"retries a failed batch one at a time": {
+			desired:       []string{"a", "b", "c"},
+			opts:          ChildCollectionOptions{BatchSize: 2},
+			failing:       map[string]error{"b": &googleapi.Error{Code: 400, Message: "Invalid value for field 'resource.instances[1]': 'b'."}},
+			expectedCalls: []string{"add a,b", "add a", "add b"},
+			expectedAdds:  []string{"b"},
+		},
+		"fails a batch without retrying on errors not naming a child": {
+			desired:       []string{"a", "b", "c"},
+			opts:          ChildCollectionOptions{BatchSize: 2},
+			failing:       map[string]error{"b": &googleapi.Error{Code: 403, Message: "Required 'compute.instances.use' permission"}},
+			expectedCalls: []string{"add a,b"},
+			expectedAdds:  []string{"a", "b"},
+		},
+		"fails a batch without retrying on a 400 not naming a child": {
+			desired:       []string{"a", "b"},
+			failing:       map[string]error{"b": &googleapi.Error{Code: 400, Message: "Target pool is being updated."}},
+			expectedCalls: []string{"add a,b"},
+			expectedAdds:  []string{"a", "b"},
+		},
+		"continues on error": {
+			current:       []string{"x"},
+			desired:       []string{"a", "b", "c"},
+			opts:          ChildCollectionOptions{BatchSize: 2, ContinueOnError: true},
+			failing:       map[string]error{"b": &googleapi.Error{Code: 400, Message: "Invalid value 'b'."}},
+			expectedCalls: []string{"add a,b", "add a", "add b", "add c", "remove x"},
+			expectedAdds:  []string{"b"},
+		},
+		"ignores removed children not found": {
+			current:       []string{"a", "b"},
+			opts:          ChildCollectionOptions{IgnoreRemoveNotFound: true},
+			failing:       map[string]error{"a": &googleapi.Error{Code: 404, Message: "The resource 'a' was not found"}},
+			expectedCalls: []string{"remove a,b", "remove a", "remove b"},
+		},
+		"fails removing children not found": {
+			current:         []string{"a"},
+			failing:         map[string]error{"a": &googleapi.Error{Code: 404}},
+			expectedCalls:   []string{"remove a"},
+			expectedRemoves: []string{"a"},
+		},
+	}
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			c := &fakeChildCollection{failing: tc.failing}
+			err := reconcileChildCollection(tc.current, tc.desired, c.fn("add"), c.fn("remove"), tc.opts)
+
+			if !reflect.DeepEqual(c.calls, tc.expectedCalls) {
+				t.Errorf("expected calls %q, got %q", tc.expectedCalls, c.calls)
+			}
+			if len(tc.expectedAdds) == 0 && len(tc.expectedRemoves) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			cerr, ok := err.(*childCollectionError)
+			if !ok {
+				t.Fatalf("expected a childCollectionError, got %v", err)
+			}
+			if !reflect.DeepEqual(cerr.FailedAdds, tc.expectedAdds) || !reflect.DeepEqual(cerr.FailedRemoves, tc.expectedRemoves) {
+				t.Errorf("expected failed adds %q and removes %q, got %q and %q", tc.expectedAdds, tc.expectedRemoves, cerr.FailedAdds, cerr.FailedRemoves)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package google
+
+import "time"
+
+// Clock is the source of time of retries, operation polling and caches. It's
+// set on Config so unit tests can use a fake clock instead of waiting.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// After returns a channel the time is sent on once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the real clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// getClock returns the clock of c, or the real clock if it's unset, eg in a
+// Config built by a test.
+func (c *Config) getClock() Clock {
+	if c == nil || c.clock == nil {
+		return systemClock{}
+	}
+	return c.clock
+}
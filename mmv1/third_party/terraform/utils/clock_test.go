@@ -0,0 +1,79 @@
+package google
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// fakeClock is a Clock that only moves when slept on, or by step every time
+// it's read.
+type fakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// After moves the clock on by d, and returns a channel the new time has
+// already been sent on.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Sleep(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func TestConfigGetClock(t *testing.T) {
+	var config *Config
+	if _, ok := config.getClock().(systemClock); !ok {
+		t.Errorf("expected a nil config to use the system clock")
+	}
+
+	clock := &fakeClock{}
+	config = &Config{clock: clock}
+	if config.getClock() != clock {
+		t.Errorf("expected the config's clock to be used")
+	}
+}
+
+func TestRetryTimeDurationWithClock_serverRetryDelay(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	tooManyRequests := &googleapi.Error{
+		Code:   429,
+		Header: http.Header{"Retry-After": []string{"30"}},
+	}
+
+	attempts := 0
+	err := retryTimeDurationWithClock(clock, func() error {
+		attempts++
+		if attempts == 1 {
+			return tooManyRequests
+		}
+		return nil
+	}, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The delay is jittered by up to 10%
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited < 30*time.Second || waited > 33*time.Second {
+		t.Errorf("expected the server's delay to be waited on the clock, waited %s", waited)
+	}
+}
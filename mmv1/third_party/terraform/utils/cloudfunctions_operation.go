@@ -26,5 +26,5 @@ func cloudFunctionsOperationWait(config *Config, op *cloudfunctions.Operation, a
 	if err := w.SetOp(op); err != nil {
 		return err
 	}
-	return OperationWait(w, activity, timeout, config.PollInterval)
+	return OperationWaitWithClock(w, activity, timeout, config.PollInterval, config.getClock())
 }
@@ -51,6 +51,58 @@ type Waiter interface {
 	TargetStates() []string
 }
 
+// OperationLogger can be implemented by Waiters whose operations report
+// progress, warnings or events while they run. Messages are logged at INFO
+// level the first time they're seen, so users can tell why an operation is
+// taking a long time without waiting for it to finish or time out.
+type OperationLogger interface {
+	// OperationLogs returns the messages the operation currently reports.
+	// It's called after each poll, and may return messages already returned
+	// by earlier calls.
+	OperationLogs() ([]string, error)
+}
+
+// operationLogTail logs the messages reported by a long-running operation
+// that haven't been logged yet. It can also be used directly by resources that
+// poll for completion without a Waiter.
+type operationLogTail struct {
+	seen map[string]struct{}
+}
+
+func (t *operationLogTail) tail(w Waiter) {
+	l, ok := w.(OperationLogger)
+	if !ok {
+		return
+	}
+
+	msgs, err := l.OperationLogs()
+	if err != nil {
+		log.Printf("[DEBUG] Unable to read logs of operation %s: %s", w.OpName(), err)
+		return
+	}
+	t.logNew(fmt.Sprintf("Operation %s", w.OpName()), msgs)
+}
+
+func (t *operationLogTail) logNew(prefix string, msgs []string) {
+	if t.seen == nil {
+		t.seen = make(map[string]struct{})
+	}
+	for _, msg := range msgs {
+		if _, ok := t.seen[msg]; ok || msg == "" {
+			continue
+		}
+		t.seen[msg] = struct{}{}
+		log.Printf("[INFO] %s: %s", prefix, msg)
+	}
+}
+
+// OperationDescriber can be implemented by Waiters able to describe what
+// their operation is doing, eg from its metadata. The description is included
+// in the error returned when waiting for the operation times out.
+type OperationDescriber interface {
+	DescribeOperation() string
+}
+
 type CommonOperationWaiter struct {
 	Op CommonOperation
 }
@@ -89,6 +141,20 @@ func (w *CommonOperationWaiter) OpName() string {
 	return w.Op.Name
 }
 
+// DescribeOperation describes the operation from its metadata, if it has any
+// that can be decoded.
+func (w *CommonOperationWaiter) DescribeOperation() string {
+	if w == nil {
+		return ""
+	}
+
+	desc := decodeOperationMetadata(w.Op.Metadata).String()
+	if desc == "" {
+		return ""
+	}
+	return fmt.Sprintf("operation %s: %s", w.Op.Name, desc)
+}
+
 func (w *CommonOperationWaiter) PendingStates() []string {
 	return []string{"done: false"}
 }
@@ -106,21 +172,75 @@ func OperationDone(w Waiter) bool {
 	return false
 }
 
+// Operations can briefly 404 right after they're created, while they replicate
+// to the region serving the GET. 404s are tolerated for this long after the
+// first one before the operation is assumed to be gone.
+const operationNotFoundGracePeriod = time.Minute
+
+// operationNotFoundGrace tracks how long an operation has been not found for.
+type operationNotFoundGrace struct {
+	period time.Duration
+	clock  Clock
+	since  time.Time
+}
+
+func newOperationNotFoundGrace(clock Clock) *operationNotFoundGrace {
+	return &operationNotFoundGrace{
+		period: operationNotFoundGracePeriod,
+		clock:  clock,
+	}
+}
+
+// tolerate records that the operation wasn't found, and returns whether it's
+// still within the grace period.
+func (g *operationNotFoundGrace) tolerate() bool {
+	now := g.clock.Now()
+	if g.since.IsZero() {
+		g.since = now
+	}
+	return now.Sub(g.since) < g.period
+}
+
+func (g *operationNotFoundGrace) reset() {
+	g.since = time.Time{}
+}
+
 func CommonRefreshFunc(w Waiter) resource.StateRefreshFunc {
+	return commonRefreshFunc(w, newOperationNotFoundGrace(systemClock{}), nil)
+}
+
+// commonRefreshFunc polls w's operation. Errors sent with a Retry-After are
+// recorded in poll, which may be nil.
+func commonRefreshFunc(w Waiter, notFound *operationNotFoundGrace, poll *operationPollSchedule) resource.StateRefreshFunc {
+	logs := &operationLogTail{}
 	return func() (interface{}, string, error) {
 		op, err := w.QueryOp()
 		if err != nil {
-			// Retry 404 when getting operation (not resource state)
-			if isRetryableError(err, isNotFoundRetryableError("GET operation")) {
+			poll.observe(err)
+			// Retry 404 when getting operation (not resource state) for a
+			// short while, as the operation may not have replicated yet.
+			if ok, _ := isOperationNotFoundError(err); ok {
+				if !notFound.tolerate() {
+					return nil, "", fmt.Errorf("operation %s was not found for %s: %s", w.OpName(), notFound.period, err)
+				}
+				log.Printf("[DEBUG] Operation %q was not found, it may not have replicated yet: %s", w.OpName(), err)
+				// Report the operation as pending rather than missing so the
+				// grace period, rather than StateChangeConf's not found
+				// checks, decides when to give up.
+				return w, w.PendingStates()[0], nil
+			}
+			if isRetryableError(err) {
 				log.Printf("[DEBUG] Dismissed retryable error on GET operation %q: %s", w.OpName(), err)
 				return nil, "done: false", nil
 			}
 			return nil, "", fmt.Errorf("error while retrieving operation: %s", err)
 		}
+		notFound.reset()
 
 		if err = w.SetOp(op); err != nil {
 			return nil, "", fmt.Errorf("Cannot continue, unable to use operation: %s", err)
 		}
+		logs.tail(w)
 
 		if err = w.Error(); err != nil {
 			if w.IsRetryable(err) {
@@ -136,6 +256,23 @@ func CommonRefreshFunc(w Waiter) resource.StateRefreshFunc {
 }
 
 func OperationWait(w Waiter, activity string, timeout time.Duration, pollInterval time.Duration) error {
+	return OperationWaitWithClock(w, activity, timeout, pollInterval, systemClock{})
+}
+
+// OperationWaitWithClock is OperationWait, polling the operation on clock.
+func OperationWaitWithClock(w Waiter, activity string, timeout time.Duration, pollInterval time.Duration, clock Clock) error {
+	return operationWaitWithNotifier(w, activity, timeout, pollInterval, clock, nil)
+}
+
+// operationWaitWithNotifier is OperationWaitWithClock, polling the operation
+// when notifier announces it's complete rather than on a schedule. notifier
+// may be nil, to poll on a schedule.
+func operationWaitWithNotifier(w Waiter, activity string, timeout time.Duration, pollInterval time.Duration, clock Clock, notifier *operationNotifier) (err error) {
+	start := time.Now()
+	defer func() {
+		currentApplyReport().recordOperation(w.OpName(), activity, start, err)
+	}()
+
 	if OperationDone(w) {
 		if w.Error() != nil {
 			return w.Error()
@@ -143,16 +280,28 @@ func OperationWait(w Waiter, activity string, timeout time.Duration, pollInterva
 		return nil
 	}
 
+	poll := newOperationPollSchedule(pollInterval, clock)
+	wake, unsubscribe := notifier.subscribe(w.OpName())
+	defer unsubscribe()
+	poll.wake = wake
+	poll.notificationsLive = notifier.live
 	c := &resource.StateChangeConf{
-		Pending:      w.PendingStates(),
-		Target:       w.TargetStates(),
-		Refresh:      CommonRefreshFunc(w),
-		Timeout:      timeout,
-		MinTimeout:   2 * time.Second,
-		PollInterval: pollInterval,
+		Pending: w.PendingStates(),
+		Target:  w.TargetStates(),
+		Refresh: poll.refreshFunc(commonRefreshFunc(w, newOperationNotFoundGrace(clock), poll)),
+		Timeout: timeout,
+		// poll waits between refreshes, so StateChangeConf shouldn't
+		PollInterval: time.Millisecond,
 	}
 	opRaw, err := c.WaitForState()
 	if err != nil {
+		if _, ok := err.(*resource.TimeoutError); ok {
+			if d, ok := w.(OperationDescriber); ok {
+				if desc := d.DescribeOperation(); desc != "" {
+					return fmt.Errorf("Error waiting for %s: %s; last known state of %s", activity, err, desc)
+				}
+			}
+		}
 		return fmt.Errorf("Error waiting for %s: %s", activity, err)
 	}
 
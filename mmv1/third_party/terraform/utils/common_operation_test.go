@@ -0,0 +1,198 @@
+package google
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+// notFoundWaiter is a Waiter whose operation isn't found by its first
+// notFound queries, and is done after that.
+type notFoundWaiter struct {
+	notFound int
+	queries  int
+	err      error
+	done     bool
+}
+
+func (w *notFoundWaiter) State() string {
+	if w.done {
+		return "done: true"
+	}
+	return "done: false"
+}
+
+func (w *notFoundWaiter) Error() error               { return nil }
+func (w *notFoundWaiter) IsRetryable(error) bool     { return false }
+func (w *notFoundWaiter) OpName() string             { return "operation-1" }
+func (w *notFoundWaiter) PendingStates() []string    { return []string{"done: false"} }
+func (w *notFoundWaiter) TargetStates() []string     { return []string{"done: true"} }
+func (w *notFoundWaiter) SetOp(op interface{}) error { w.done = op.(bool); return nil }
+
+func (w *notFoundWaiter) QueryOp() (interface{}, error) {
+	w.queries++
+	if w.queries <= w.notFound {
+		if w.err != nil {
+			return nil, w.err
+		}
+		return nil, &googleapi.Error{Code: 404, Message: "operation not found"}
+	}
+	return true, nil
+}
+
+func testOperationNotFoundGrace(step time.Duration) *operationNotFoundGrace {
+	clock := &fakeClock{now: time.Unix(0, 0), step: step}
+	return &operationNotFoundGrace{period: time.Minute, clock: clock}
+}
+
+func TestCommonRefreshFunc_operationNotFoundWithinGracePeriod(t *testing.T) {
+	w := &notFoundWaiter{notFound: 3}
+	refresh := commonRefreshFunc(w, testOperationNotFoundGrace(10*time.Second), nil)
+
+	for i := 0; i < 3; i++ {
+		res, state, err := refresh()
+		if err != nil {
+			t.Fatalf("query %d: expected 404 to be tolerated, got error: %s", i, err)
+		}
+		// A nil result would count towards StateChangeConf's not found checks
+		if res == nil {
+			t.Fatalf("query %d: expected a placeholder result, got nil", i)
+		}
+		if state != "done: false" {
+			t.Fatalf("query %d: expected pending state, got %q", i, state)
+		}
+	}
+
+	_, state, err := refresh()
+	if err != nil {
+		t.Fatalf("expected operation to be found, got error: %s", err)
+	}
+	if state != "done: true" {
+		t.Fatalf("expected done state, got %q", state)
+	}
+}
+
+func TestCommonRefreshFunc_operationNotFoundAfterGracePeriod(t *testing.T) {
+	w := &notFoundWaiter{notFound: 10}
+	refresh := commonRefreshFunc(w, testOperationNotFoundGrace(30*time.Second), nil)
+
+	// Not found at 0s and 30s, then at 60s the grace period is over
+	for i := 0; i < 2; i++ {
+		if _, _, err := refresh(); err != nil {
+			t.Fatalf("query %d: expected 404 to be tolerated, got error: %s", i, err)
+		}
+	}
+	_, _, err := refresh()
+	if err == nil {
+		t.Fatal("expected an error once the grace period was over")
+	}
+	if !strings.Contains(err.Error(), "operation-1 was not found for 1m0s") {
+		t.Fatalf("expected error to name the operation and grace period, got: %s", err)
+	}
+}
+
+func TestCommonRefreshFunc_operationNotFoundGraceResetsWhenFound(t *testing.T) {
+	w := &notFoundWaiter{notFound: 2}
+	refresh := commonRefreshFunc(w, testOperationNotFoundGrace(40*time.Second), nil)
+
+	// Not found at 0s and 40s, found at 80s
+	for i := 0; i < 2; i++ {
+		if _, _, err := refresh(); err != nil {
+			t.Fatalf("query %d: expected 404 to be tolerated, got error: %s", i, err)
+		}
+	}
+	if _, _, err := refresh(); err != nil {
+		t.Fatalf("expected operation to be found, got error: %s", err)
+	}
+
+	// A 404 at 120s starts a new grace period rather than continuing the first
+	w.queries, w.notFound = 0, 1
+	if _, _, err := refresh(); err != nil {
+		t.Fatalf("expected 404 after the operation was found to be tolerated, got error: %s", err)
+	}
+}
+
+func TestCommonRefreshFunc_operationNotFoundWrapped(t *testing.T) {
+	w := &notFoundWaiter{
+		notFound: 1,
+		err:      errwrap.Wrapf("Error getting operation: {{err}}", &googleapi.Error{Code: 404}),
+	}
+	refresh := commonRefreshFunc(w, testOperationNotFoundGrace(time.Second), nil)
+
+	if _, _, err := refresh(); err != nil {
+		t.Fatalf("expected wrapped 404 to be tolerated, got error: %s", err)
+	}
+}
+
+func TestCommonRefreshFunc_otherErrorsNotTolerated(t *testing.T) {
+	for name, queryErr := range map[string]error{
+		"bad request": &googleapi.Error{Code: 400},
+		"other":       errors.New("invalid operation name"),
+	} {
+		w := &notFoundWaiter{notFound: 1, err: queryErr}
+		refresh := commonRefreshFunc(w, testOperationNotFoundGrace(time.Second), nil)
+
+		if _, _, err := refresh(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// loggingWaiter is a Waiter reporting a growing list of messages.
+type loggingWaiter struct {
+	notFoundWaiter
+	logs [][]string
+	read int
+}
+
+func (w *loggingWaiter) OperationLogs() ([]string, error) {
+	msgs := w.logs[w.read]
+	if w.read < len(w.logs)-1 {
+		w.read++
+	}
+	return msgs, nil
+}
+
+func TestOperationLogTail_logsEachMessageOnce(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	w := &loggingWaiter{logs: [][]string{
+		{"stage CREATE is RUNNING"},
+		{"stage CREATE is RUNNING", "", "node pool condition GCE_STOCKOUT: no capacity"},
+		{"stage CREATE is RUNNING", "node pool condition GCE_STOCKOUT: no capacity"},
+	}}
+	logs := &operationLogTail{}
+	for i := 0; i < 3; i++ {
+		logs.tail(w)
+	}
+
+	out := buf.String()
+	for _, msg := range []string{"stage CREATE is RUNNING", "node pool condition GCE_STOCKOUT: no capacity"} {
+		if n := strings.Count(out, "Operation operation-1: "+msg); n != 1 {
+			t.Errorf("expected %q to be logged once, logged %d times:\n%s", msg, n, out)
+		}
+	}
+	if strings.Count(out, "[INFO]") != 2 {
+		t.Errorf("expected 2 messages to be logged, got:\n%s", out)
+	}
+}
+
+func TestOperationLogTail_ignoresWaitersWithoutLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	(&operationLogTail{}).tail(&notFoundWaiter{})
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be logged, got %q", buf.String())
+	}
+}
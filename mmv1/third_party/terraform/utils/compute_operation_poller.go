@@ -0,0 +1,194 @@
+// Multiplexing the polls of Compute Engine operations. An apply creating
+// hundreds of Compute Engine resources otherwise polls each of their
+// operations with its own GET, on identical schedules, so polls arrive in
+// bursts. With computeOperationPoller, polls of operations in the same project
+// and zone, region or global scope arriving within a short, jittered window
+// are served by a single list call filtered to their names, and at most
+// computeOperationPollConcurrency lists or GETs run at once.
+
+package google
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How long polls wait to be batched with others in their scope, if
+	// other polls of the scope are in progress. Each batch waits up to as
+	// long again, so scopes don't poll in lockstep.
+	computeOperationBatchWindow = 200 * time.Millisecond
+	// Polls of more operations than this are split across batches, to keep
+	// list filters short.
+	computeOperationBatchMaxSize = 50
+	// How many lists or GETs of operations run at once.
+	computeOperationPollConcurrency = 10
+)
+
+// computeOperationScope is the scope operations are batched by, eg
+// {"p", "zones/us-central1-a"} or {"p", "global"}.
+type computeOperationScope struct {
+	Project string
+	Scope   string
+}
+
+// computeOperationListFunc lists the operations of a scope with names,
+// returning them by name. Operations missing from the result are polled with
+// their own GET.
+type computeOperationListFunc func(names []string) (map[string]interface{}, error)
+
+// computeOperationBatch is the polls of a scope waiting to be sent.
+type computeOperationBatch struct {
+	names []string
+	list  computeOperationListFunc
+	done  chan struct{}
+
+	// Set once done is closed
+	ops map[string]interface{}
+	err error
+}
+
+// computeOperationPoller batches the polls of operations by scope. It's safe
+// to use from multiple goroutines, and a nil poller doesn't batch.
+type computeOperationPoller struct {
+	clock  Clock
+	window time.Duration
+	sem    chan struct{}
+
+	mu      sync.Mutex
+	pending map[computeOperationScope]*computeOperationBatch
+	// active counts the polls in progress by scope
+	active map[computeOperationScope]int
+}
+
+func newComputeOperationPoller(clock Clock) *computeOperationPoller {
+	return &computeOperationPoller{
+		clock:   clock,
+		window:  computeOperationBatchWindow,
+		sem:     make(chan struct{}, computeOperationPollConcurrency),
+		pending: make(map[computeOperationScope]*computeOperationBatch),
+		active:  make(map[computeOperationScope]int),
+	}
+}
+
+// poll returns the operation name of scope, listed together with the other
+// operations polled in scope at the same time. list lists operations of
+// scope, and get gets the operation on its own, if the list fails or misses
+// it. Polls stop waiting for the batch once ctx, which may be nil, is done.
+func (p *computeOperationPoller) poll(ctx context.Context, scope computeOperationScope, name string, list computeOperationListFunc, get func() (interface{}, error)) (interface{}, error) {
+	if p == nil {
+		return get()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	others := p.enter(scope)
+	defer p.leave(scope)
+	b, leader := p.join(scope, name, list)
+	if leader {
+		// A poll alone in its scope is sent straight away, as there's
+		// nothing to batch it with
+		if others {
+			select {
+			case <-p.clock.After(p.window + time.Duration(rand.Int63n(int64(p.window)+1))):
+			case <-ctx.Done():
+			}
+		}
+		p.send(scope, b)
+	}
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, errors.New("unable to finish polling, context has been cancelled")
+	}
+
+	if b.err == nil {
+		if op, ok := b.ops[name]; ok {
+			return op, nil
+		}
+	} else {
+		log.Printf("[DEBUG] Unable to list operations in %s, getting operation %s instead: %s", scope.Scope, name, b.err)
+	}
+	p.acquire()
+	defer p.release()
+	return get()
+}
+
+// enter records a poll in progress in scope, returning whether others are.
+func (p *computeOperationPoller) enter(scope computeOperationScope) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active[scope]++
+	return p.active[scope] > 1
+}
+
+func (p *computeOperationPoller) leave(scope computeOperationScope) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active[scope]--; p.active[scope] == 0 {
+		delete(p.active, scope)
+	}
+}
+
+// join adds the poll of name to the batch of scope, returning the batch and
+// whether the caller started it, and must send it.
+func (p *computeOperationPoller) join(scope computeOperationScope, name string, list computeOperationListFunc) (*computeOperationBatch, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b, ok := p.pending[scope]; ok {
+		b.names = append(b.names, name)
+		if len(b.names) >= computeOperationBatchMaxSize {
+			// Later polls start a new batch
+			delete(p.pending, scope)
+		}
+		return b, false
+	}
+	b := &computeOperationBatch{
+		names: []string{name},
+		list:  list,
+		done:  make(chan struct{}),
+	}
+	p.pending[scope] = b
+	return b, true
+}
+
+// send lists the operations of b, unless it has a single one, which is
+// polled with its GET instead.
+func (p *computeOperationPoller) send(scope computeOperationScope, b *computeOperationBatch) {
+	p.mu.Lock()
+	if p.pending[scope] == b {
+		delete(p.pending, scope)
+	}
+	names := append([]string{}, b.names...)
+	p.mu.Unlock()
+	defer close(b.done)
+
+	if len(names) == 1 {
+		b.ops = map[string]interface{}{}
+		return
+	}
+	log.Printf("[DEBUG] Polling %d operations in %s of project %s with a single list", len(names), scope.Scope, scope.Project)
+	p.acquire()
+	defer p.release()
+	b.ops, b.err = b.list(names)
+}
+
+func (p *computeOperationPoller) acquire() { p.sem <- struct{}{} }
+func (p *computeOperationPoller) release() { <-p.sem }
+
+// computeOperationNamesFilter returns a list filter matching the operations
+// with names.
+func computeOperationNamesFilter(names []string) string {
+	clauses := make([]string, len(names))
+	for i, n := range names {
+		clauses[i] = fmt.Sprintf("(name = %q)", n)
+	}
+	return strings.Join(clauses, " OR ")
+}
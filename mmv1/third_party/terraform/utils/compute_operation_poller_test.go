@@ -0,0 +1,163 @@
+package google
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestComputeOperationPoller_batchesScope(t *testing.T) {
+	p := newComputeOperationPoller(&fakeClock{})
+	scope := computeOperationScope{Project: "p", Scope: "zones/us-central1-a"}
+	var listed [][]string
+	list := func(names []string) (map[string]interface{}, error) {
+		listed = append(listed, names)
+		return map[string]interface{}{"op-1": "listed op-1", "op-2": "listed op-2"}, nil
+	}
+
+	b, leader := p.join(scope, "op-1", list)
+	if !leader {
+		t.Fatalf("expected the first poll to start a batch")
+	}
+	for _, name := range []string{"op-2", "op-3"} {
+		if other, leader := p.join(scope, name, list); leader || other != b {
+			t.Fatalf("expected %s to join the pending batch", name)
+		}
+	}
+	if _, leader := p.join(computeOperationScope{Project: "p", Scope: "global"}, "op-4", list); !leader {
+		t.Errorf("expected polls in other scopes to start their own batch")
+	}
+
+	p.send(scope, b)
+	if expected := [][]string{{"op-1", "op-2", "op-3"}}; !reflect.DeepEqual(listed, expected) {
+		t.Errorf("expected a single list of the batched operations, got %v", listed)
+	}
+	if b.ops["op-2"] != "listed op-2" {
+		t.Errorf("expected the listed operations, got %v", b.ops)
+	}
+	if _, leader := p.join(scope, "op-5", list); !leader {
+		t.Errorf("expected polls after a batch was sent to start a new batch")
+	}
+}
+
+func TestComputeOperationPoller_batchMaxSize(t *testing.T) {
+	p := newComputeOperationPoller(&fakeClock{})
+	scope := computeOperationScope{Project: "p", Scope: "global"}
+	first, _ := p.join(scope, "op", nil)
+	for i := 1; i < computeOperationBatchMaxSize; i++ {
+		p.join(scope, "op", nil)
+	}
+	if b, leader := p.join(scope, "op", nil); !leader || b == first {
+		t.Errorf("expected a new batch once the batch is full")
+	}
+}
+
+func TestComputeOperationPoller_poll(t *testing.T) {
+	p := newComputeOperationPoller(&fakeClock{})
+	scope := computeOperationScope{Project: "p", Scope: "regions/us-central1"}
+	var mu sync.Mutex
+	gets := 0
+	get := func() (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gets++
+		return "got op", nil
+	}
+
+	// A poll alone in its batch gets the operation
+	op, err := p.poll(context.Background(), scope, "op-1", func(names []string) (map[string]interface{}, error) {
+		t.Errorf("expected no list of a single operation, got %v", names)
+		return nil, nil
+	}, get)
+	if err != nil || op != "got op" || gets != 1 {
+		t.Errorf("expected the operation to be got, got %v, %v after %d gets", op, err, gets)
+	}
+
+	// Operations missing from the list, or whose list failed, are got
+	for name, list := range map[string]computeOperationListFunc{
+		"missing": func(names []string) (map[string]interface{}, error) { return map[string]interface{}{}, nil },
+		"failed":  func(names []string) (map[string]interface{}, error) { return nil, errors.New("forbidden") },
+	} {
+		gets = 0
+		b, _ := p.join(scope, "op-1", list)
+		p.join(scope, "op-2", list)
+		done := make(chan interface{})
+		go func() {
+			op, _ := p.poll(context.Background(), scope, "op-3", list, get)
+			done <- op
+		}()
+		for joined := false; !joined; {
+			p.mu.Lock()
+			joined = len(b.names) == 3
+			p.mu.Unlock()
+		}
+		p.send(scope, b)
+		if op := <-done; op != "got op" {
+			t.Errorf("%s: expected the operation to be got, got %v", name, op)
+		}
+		if gets != 1 {
+			t.Errorf("%s: expected a single get, got %d", name, gets)
+		}
+	}
+}
+
+func TestComputeOperationPoller_pollWaitsForOthers(t *testing.T) {
+	start := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	p := newComputeOperationPoller(clock)
+	scope := computeOperationScope{Project: "p", Scope: "global"}
+	get := func() (interface{}, error) { return "got op", nil }
+
+	if _, err := p.poll(context.Background(), scope, "op-1", nil, get); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("expected a poll alone in its scope to be sent straight away, waited %s", now.Sub(start))
+	}
+	if len(p.active) != 0 {
+		t.Errorf("expected no polls in progress once it returned, got %v", p.active)
+	}
+
+	// Another poll of the scope is in progress, eg getting its operation
+	p.enter(scope)
+	if _, err := p.poll(context.Background(), scope, "op-2", nil, get); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if waited := clock.Now().Sub(start); waited < p.window {
+		t.Errorf("expected the poll to wait for others to join its batch, waited %s", waited)
+	}
+}
+
+func TestComputeOperationPoller_pollCancelled(t *testing.T) {
+	p := newComputeOperationPoller(&fakeClock{})
+	scope := computeOperationScope{Project: "p", Scope: "global"}
+	// A batch that's never sent
+	p.join(scope, "op-1", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := p.poll(ctx, scope, "op-2", nil, func() (interface{}, error) {
+		t.Errorf("expected no get once the context is cancelled")
+		return nil, nil
+	})
+	if err == nil {
+		t.Errorf("expected an error once the context is cancelled")
+	}
+}
+
+func TestComputeOperationPoller_nil(t *testing.T) {
+	var p *computeOperationPoller
+	op, err := p.poll(context.Background(), computeOperationScope{}, "op", nil, func() (interface{}, error) { return "got op", nil })
+	if err != nil || op != "got op" {
+		t.Errorf("expected a nil poller to get the operation, got %v, %v", op, err)
+	}
+}
+
+func TestComputeOperationNamesFilter(t *testing.T) {
+	if got, expected := computeOperationNamesFilter([]string{"op-1", "op-2"}), `(name = "op-1") OR (name = "op-2")`; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
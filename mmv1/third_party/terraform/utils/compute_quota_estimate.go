@@ -0,0 +1,243 @@
+// Plan-time estimation of regional Compute Engine quota usage. Resources opt
+// in by adding quotaEstimationCustomizeDiff to their CustomizeDiff with an
+// estimator for the quota metrics they consume, and users by enabling the
+// estimate_quotas feature, as estimating reads the API during every plan.
+// Estimation is best-effort: it never fails a plan, and only logs a warning
+// when the planned change is expected to exceed a region quota.
+
+package google
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// quotaDelta is the change in usage of a regional quota metric, eg "CPUS" or
+// "STATIC_ADDRESSES", that a planned change would cause.
+type quotaDelta struct {
+	Metric string
+	Delta  float64
+}
+
+// quotaEstimateData is the resource data quota estimators read: the
+// *schema.ResourceDiff of a plan, or the *schema.ResourceData of an apply.
+type quotaEstimateData interface {
+	HasChange(string) bool
+	GetChange(string) (interface{}, interface{})
+	GetOk(string) (interface{}, bool)
+	Get(string) interface{}
+	Id() string
+}
+
+// quotaEstimatorFunc returns the region and the quota deltas of the change in
+// d. Returning no deltas skips the quota check.
+type quotaEstimatorFunc func(d quotaEstimateData, config *Config) (string, []quotaDelta, error)
+
+// quotaEstimators are the quota estimators of resource types, by type.
+var quotaEstimators = map[string]quotaEstimatorFunc{
+	"google_compute_address":  computeAddressQuotaEstimator,
+	"google_compute_instance": computeInstanceCpuQuotaEstimator,
+}
+
+// withQuotaEstimation warns when a change to r, a resource of resourceType,
+// is expected to exceed a region quota, if the estimate_quotas feature is
+// enabled. CustomizeDiff functions can't return warnings, so plans only log
+// them; creates and updates report them as warning diagnostics through
+// addResourceWarning, which needs withResourceWarningDiagnostics to be
+// applied after this.
+func withQuotaEstimation(resourceType string, r *schema.Resource) *schema.Resource {
+	estimate, ok := quotaEstimators[resourceType]
+	if !ok {
+		return r
+	}
+
+	planned := quotaEstimationCustomizeDiff(estimate)
+	if r.CustomizeDiff != nil {
+		planned = customdiff.All(r.CustomizeDiff, planned)
+	}
+	r.CustomizeDiff = planned
+
+	wrap := func(f func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+		if f == nil {
+			return nil
+		}
+		return func(d *schema.ResourceData, meta interface{}) error {
+			config := meta.(*Config)
+			for _, w := range estimateQuotaWarnings(d, config, estimate) {
+				addResourceWarning(d, config, "Change may exceed a quota", w)
+			}
+			return f(d, meta)
+		}
+	}
+	r.Create = wrap(r.Create)
+	r.Update = wrap(r.Update)
+	return r
+}
+
+// quotaEstimationCustomizeDiff returns a CustomizeDiffFunc logging a warning
+// when the change estimated by estimate would exceed a region quota. It never
+// fails the plan.
+func quotaEstimationCustomizeDiff(estimate quotaEstimatorFunc) schema.CustomizeDiffFunc {
+	return func(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		for _, w := range estimateQuotaWarnings(d, meta.(*Config), estimate) {
+			log.Printf("[WARN] %s", w)
+		}
+		return nil
+	}
+}
+
+// estimateQuotaWarnings returns a warning for each region quota the change
+// estimated by estimate would exceed. It only reads quotas when the
+// estimate_quotas feature is enabled.
+func estimateQuotaWarnings(d quotaEstimateData, config *Config, estimate quotaEstimatorFunc) []string {
+	if !config.Features.EstimateQuotas {
+		return nil
+	}
+
+	region, deltas, err := estimate(d, config)
+	if err != nil {
+		log.Printf("[DEBUG] Unable to estimate quota usage: %s", err)
+		return nil
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	project, err := quotaEstimateProject(d, config)
+	if err != nil {
+		log.Printf("[DEBUG] Unable to estimate quota usage: %s", err)
+		return nil
+	}
+
+	return checkRegionQuotas(config, project, region, deltas)
+}
+
+// quotaEstimateProject reads the "project" field of d, falling back to the
+// provider's value, like getProject.
+func quotaEstimateProject(d quotaEstimateData, config *Config) (string, error) {
+	if v, ok := d.GetOk("project"); ok {
+		return v.(string), nil
+	}
+	if config.Project != "" {
+		return config.Project, nil
+	}
+	return "", fmt.Errorf("%s: required field is not set", "project")
+}
+
+// quotaNewValueKnown returns whether the new value of key is known. It always
+// is when applying.
+func quotaNewValueKnown(d quotaEstimateData, key string) bool {
+	if diff, ok := d.(*schema.ResourceDiff); ok {
+		return diff.NewValueKnown(key)
+	}
+	return true
+}
+
+// checkRegionQuotas returns a warning for each delta that would take usage of
+// its metric over the region's limit.
+func checkRegionQuotas(config *Config, project, region string, deltas []quotaDelta) []string {
+	r, err := config.NewComputeClient(config.userAgent).Regions.Get(project, region).Do()
+	if err != nil {
+		log.Printf("[DEBUG] Unable to read quotas for region %s: %s", region, err)
+		return nil
+	}
+
+	usage := make(map[string][2]float64, len(r.Quotas))
+	for _, q := range r.Quotas {
+		usage[q.Metric] = [2]float64{q.Usage, q.Limit}
+	}
+
+	return quotaWarnings(project, region, usage, deltas)
+}
+
+// usage maps metric to [usage, limit].
+func quotaWarnings(project, region string, usage map[string][2]float64, deltas []quotaDelta) []string {
+	var warnings []string
+	for _, delta := range deltas {
+		if delta.Delta <= 0 {
+			continue
+		}
+		u, ok := usage[delta.Metric]
+		if !ok {
+			continue
+		}
+		if u[0]+delta.Delta > u[1] {
+			warnings = append(warnings, fmt.Sprintf(
+				"Planned change needs %v more %s in project %s region %s, but only %v of %v remain. The apply will likely fail with a quota error.",
+				delta.Delta, delta.Metric, project, region, u[1]-u[0], u[1]))
+		}
+	}
+	return warnings
+}
+
+// computeInstanceCpuQuotaEstimator estimates the CPUS delta of creating or
+// resizing an instance.
+func computeInstanceCpuQuotaEstimator(d quotaEstimateData, config *Config) (string, []quotaDelta, error) {
+	if !d.HasChange("machine_type") || !quotaNewValueKnown(d, "machine_type") {
+		return "", nil, nil
+	}
+
+	zone, ok := d.GetOk("zone")
+	if !ok || !quotaNewValueKnown(d, "zone") {
+		if config.Zone == "" {
+			return "", nil, nil
+		}
+		zone = config.Zone
+	}
+
+	project, err := quotaEstimateProject(d, config)
+	if err != nil {
+		return "", nil, err
+	}
+
+	oldMt, newMt := d.GetChange("machine_type")
+	oldCpus, err := machineTypeGuestCpus(config, project, zone.(string), oldMt.(string))
+	if err != nil {
+		return "", nil, err
+	}
+	newCpus, err := machineTypeGuestCpus(config, project, zone.(string), newMt.(string))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return getRegionFromZone(zone.(string)), []quotaDelta{{Metric: "CPUS", Delta: float64(newCpus - oldCpus)}}, nil
+}
+
+func machineTypeGuestCpus(config *Config, project, zone, machineType string) (int64, error) {
+	if machineType == "" {
+		return 0, nil
+	}
+
+	mt, err := config.NewComputeClient(config.userAgent).MachineTypes.Get(project, zone, GetResourceNameFromSelfLink(machineType)).Do()
+	if err != nil {
+		return 0, fmt.Errorf("error reading machine type %s: %s", machineType, err)
+	}
+	return mt.GuestCpus, nil
+}
+
+// computeAddressQuotaEstimator estimates the address quota consumed by
+// creating a regional address.
+func computeAddressQuotaEstimator(d quotaEstimateData, config *Config) (string, []quotaDelta, error) {
+	if d.Id() != "" {
+		return "", nil, nil
+	}
+
+	region, ok := d.GetOk("region")
+	if !ok || !quotaNewValueKnown(d, "region") {
+		if config.Region == "" {
+			return "", nil, nil
+		}
+		region = config.Region
+	}
+
+	metric := "STATIC_ADDRESSES"
+	if d.Get("address_type").(string) == "INTERNAL" {
+		metric = "INTERNAL_ADDRESSES"
+	}
+
+	return GetResourceNameFromSelfLink(region.(string)), []quotaDelta{{Metric: metric, Delta: 1}}, nil
+}
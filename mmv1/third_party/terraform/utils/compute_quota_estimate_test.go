@@ -0,0 +1,99 @@
+package google
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestQuotaWarnings(t *testing.T) {
+	usage := map[string][2]float64{
+		"CPUS":             {20, 24},
+		"STATIC_ADDRESSES": {8, 8},
+	}
+	cases := map[string]struct {
+		deltas   []quotaDelta
+		expected []string
+	}{
+		"within quota": {
+			deltas: []quotaDelta{{Metric: "CPUS", Delta: 4}},
+		},
+		"exceeds quota": {
+			deltas:   []quotaDelta{{Metric: "CPUS", Delta: 8}},
+			expected: []string{"8 more CPUS"},
+		},
+		"released usage": {
+			deltas: []quotaDelta{{Metric: "STATIC_ADDRESSES", Delta: -1}},
+		},
+		"unknown metric": {
+			deltas: []quotaDelta{{Metric: "GPUS", Delta: 100}},
+		},
+		"several metrics": {
+			deltas:   []quotaDelta{{Metric: "CPUS", Delta: 2}, {Metric: "STATIC_ADDRESSES", Delta: 1}},
+			expected: []string{"1 more STATIC_ADDRESSES"},
+		},
+	}
+	for tn, tc := range cases {
+		got := quotaWarnings("my-project", "us-central1", usage, tc.deltas)
+		if len(got) != len(tc.expected) {
+			t.Errorf("%s: expected %d warnings, got %q", tn, len(tc.expected), got)
+			continue
+		}
+		for i, w := range tc.expected {
+			if !strings.Contains(got[i], w) || !strings.Contains(got[i], "us-central1") {
+				t.Errorf("%s: expected warning about %q in us-central1, got %q", tn, w, got[i])
+			}
+		}
+	}
+}
+
+func TestQuotaEstimationCustomizeDiff_optIn(t *testing.T) {
+	estimated := false
+	f := quotaEstimationCustomizeDiff(func(d quotaEstimateData, config *Config) (string, []quotaDelta, error) {
+		estimated = true
+		return "", nil, nil
+	})
+
+	if err := f(context.Background(), nil, &Config{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if estimated {
+		t.Errorf("expected no estimate without the estimate_quotas feature")
+	}
+
+	if err := f(context.Background(), nil, &Config{Features: Features{EstimateQuotas: true}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !estimated {
+		t.Errorf("expected an estimate with the estimate_quotas feature")
+	}
+}
+
+func TestWithQuotaEstimation(t *testing.T) {
+	r := &schema.Resource{
+		Create: func(d *schema.ResourceData, meta interface{}) error { return nil },
+		Read:   func(d *schema.ResourceData, meta interface{}) error { return nil },
+	}
+	if withQuotaEstimation("google_compute_network", r); r.CustomizeDiff != nil {
+		t.Errorf("expected no quota estimation for a resource without an estimator")
+	}
+
+	r = &schema.Resource{
+		Schema: map[string]*schema.Schema{"region": {Type: schema.TypeString, Optional: true}},
+		Create: func(d *schema.ResourceData, meta interface{}) error { return nil },
+		Update: func(d *schema.ResourceData, meta interface{}) error { return nil },
+	}
+	withQuotaEstimation("google_compute_address", r)
+	if r.CustomizeDiff == nil || r.Create == nil || r.Update == nil {
+		t.Fatalf("expected quota estimation of plans, creates and updates")
+	}
+
+	// Quotas aren't read without the estimate_quotas feature, so the address
+	// is created without a request
+	d := r.TestResourceData()
+	if err := r.Create(d, &Config{Region: "us-central1"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,39 @@
+package google
+
+// WithOverrides returns a copy of c scoped to a single operation on a
+// resource, using project, billingProject and userAgent rather than the
+// provider's. Empty values keep the provider's. The copy shares the HTTP
+// clients, token source and caches of c, so it's cheap to make, and c isn't
+// modified, so resources applied in parallel can each use their own copy.
+func (c *Config) WithOverrides(project, billingProject, userAgent string) *Config {
+	scoped := *c
+	if project != "" {
+		scoped.Project = project
+	}
+	if billingProject != "" {
+		scoped.BillingProject = billingProject
+	}
+	if userAgent != "" {
+		scoped.userAgent = userAgent
+	}
+	return &scoped
+}
+
+// resourceConfig returns config scoped to d: using the project and
+// billing_project fields of d if they're set, and the user agent including
+// the module name of d's provider_meta.
+func resourceConfig(d TerraformResourceData, config *Config) (*Config, error) {
+	userAgent, err := generateUserAgentString(d, config.userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	var project, billingProject string
+	if v, ok := d.GetOk("project"); ok {
+		project = v.(string)
+	}
+	if v, ok := d.GetOk("billing_project"); ok {
+		billingProject = v.(string)
+	}
+	return config.WithOverrides(project, billingProject, userAgent), nil
+}
@@ -0,0 +1,52 @@
+package google
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConfigWithOverrides(t *testing.T) {
+	config := &Config{
+		Project:        "provider-project",
+		BillingProject: "provider-billing-project",
+		userAgent:      "provider-user-agent",
+	}
+
+	scoped := config.WithOverrides("p", "", "ua")
+	if scoped.Project != "p" || scoped.BillingProject != "provider-billing-project" || scoped.userAgent != "ua" {
+		t.Errorf("expected the overrides to replace the provider's values, got %+v", scoped)
+	}
+	if config.Project != "provider-project" || config.userAgent != "provider-user-agent" {
+		t.Errorf("expected the provider's config to be unchanged, got %+v", config)
+	}
+
+	// Scoping concurrently doesn't race on the provider's config
+	var wg sync.WaitGroup
+	for _, p := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+			if scoped := config.WithOverrides(p, p, ""); scoped.Project != p || scoped.BillingProject != p {
+				t.Errorf("expected project %s, got %s", p, scoped.Project)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+func TestResourceConfig(t *testing.T) {
+	config := &Config{Project: "provider-project", BillingProject: "provider-billing-project"}
+	d := &ResourceDataMock{
+		FieldsInSchema: map[string]interface{}{
+			"project": "p",
+		},
+	}
+
+	scoped, err := resourceConfig(d, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if scoped.Project != "p" || scoped.BillingProject != "provider-billing-project" {
+		t.Errorf("expected the project of the resource and the provider's billing project, got %s, %s", scoped.Project, scoped.BillingProject)
+	}
+}
@@ -0,0 +1,266 @@
+// Resolution of provider settings that can come from several places. Each
+// setting is resolved by walking the same precedence chain, stopping at the
+// first source that has a value:
+//
+//   1. the field set explicitly in the provider block
+//   2. environment variables, in the order listed for the setting
+//   3. the active gcloud configuration
+//   4. the GCE/GKE metadata server
+//
+// Not every setting uses every source; eg credentials are never read from
+// gcloud. The source a value was resolved from is recorded so it can be
+// logged, which makes "why is the provider using this project?" answerable
+// from debug logs.
+
+package google
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// configSetting describes where a provider setting may be found, in addition
+// to the provider block.
+type configSetting struct {
+	Name string
+	// Environment variables checked in order; the first non-empty one wins.
+	EnvVars []string
+	// gcloud property in section/key form, eg core/project.
+	GcloudProperty string
+	// Metadata server lookup, only used for ADC users on Google infrastructure.
+	Metadata func(*metadataClient) (string, error)
+}
+
+var (
+	projectSetting = configSetting{
+		Name:           "project",
+		EnvVars:        []string{"GOOGLE_PROJECT", "GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"},
+		GcloudProperty: "core/project",
+		Metadata:       (*metadataClient).ProjectID,
+	}
+
+	billingProjectSetting = configSetting{
+		Name:    "billing_project",
+		EnvVars: []string{"GOOGLE_BILLING_PROJECT"},
+	}
+
+	regionSetting = configSetting{
+		Name:           "region",
+		EnvVars:        []string{"GOOGLE_REGION", "GCLOUD_REGION", "CLOUDSDK_COMPUTE_REGION"},
+		GcloudProperty: "compute/region",
+	}
+
+	zoneSetting = configSetting{
+		Name:           "zone",
+		EnvVars:        []string{"GOOGLE_ZONE", "GCLOUD_ZONE", "CLOUDSDK_COMPUTE_ZONE"},
+		GcloudProperty: "compute/zone",
+		Metadata:       (*metadataClient).Zone,
+	}
+
+	credentialsSetting = configSetting{
+		Name:    "credentials",
+		EnvVars: []string{"GOOGLE_CREDENTIALS", "GOOGLE_CLOUD_KEYFILE_JSON", "GCLOUD_KEYFILE_JSON"},
+	}
+
+	accessTokenSetting = configSetting{
+		Name:    "access_token",
+		EnvVars: []string{"GOOGLE_OAUTH_ACCESS_TOKEN"},
+	}
+
+	clientCertificateSetting = configSetting{
+		Name:    "client_certificate",
+		EnvVars: []string{"GOOGLE_CLIENT_CERTIFICATE"},
+	}
+
+	clientPrivateKeySetting = configSetting{
+		Name:    "client_private_key",
+		EnvVars: []string{"GOOGLE_CLIENT_PRIVATE_KEY"},
+	}
+
+	// HTTPS_PROXY and NO_PROXY are read by the transports themselves, see
+	// proxy_util.go
+	proxyURLSetting = configSetting{
+		Name:    "proxy_url",
+		EnvVars: []string{"GOOGLE_PROXY_URL"},
+	}
+
+	noProxySetting = configSetting{
+		Name:    "no_proxy",
+		EnvVars: []string{"GOOGLE_NO_PROXY"},
+	}
+
+	caCertificatesSetting = configSetting{
+		Name:           "ca_certificates",
+		EnvVars:        []string{"GOOGLE_CA_CERTIFICATES"},
+		GcloudProperty: "core/custom_ca_certs_file",
+	}
+
+	impersonateServiceAccountSetting = configSetting{
+		Name:    "impersonate_service_account",
+		EnvVars: []string{"GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"},
+	}
+
+	// Resolved as a string, and parsed with strconv.ParseBool
+	userProjectOverrideSetting = configSetting{
+		Name:    "user_project_override",
+		EnvVars: []string{"USER_PROJECT_OVERRIDE"},
+	}
+
+	requestReasonSetting = configSetting{
+		Name:    "request_reason",
+		EnvVars: []string{"CLOUDSDK_CORE_REQUEST_REASON"},
+	}
+
+	// Resolved as a string, and parsed with time.ParseDuration
+	createDependencyWaitSetting = configSetting{
+		Name:    "create_dependency_wait",
+		EnvVars: []string{"GOOGLE_CREATE_DEPENDENCY_WAIT"},
+	}
+)
+
+// resolvedSetting is the outcome of resolving a configSetting. Source is a
+// human readable description of where Value came from, and is empty if the
+// setting wasn't found anywhere.
+type resolvedSetting struct {
+	Value  string
+	Source string
+}
+
+// configResolver resolves configSettings. Its lookups are injectable so the
+// precedence chain can be tested without touching the real environment,
+// gcloud installation or metadata server.
+type configResolver struct {
+	getenv func(string) string
+	// loadGcloudProperties returns the properties of the active gcloud
+	// configuration keyed by section/key. It's only called once.
+	loadGcloudProperties func() (map[string]string, error)
+	// nil disables metadata server lookups
+	metadata *metadataClient
+
+	gcloudOnce       sync.Once
+	gcloudProperties map[string]string
+}
+
+func newConfigResolver(metadata *metadataClient) *configResolver {
+	r := &configResolver{
+		getenv:   os.Getenv,
+		metadata: metadata,
+	}
+	r.loadGcloudProperties = func() (map[string]string, error) {
+		return readGcloudProperties(r.getenv)
+	}
+	return r
+}
+
+// resolve returns the value of s, preferring explicit (the value set in the
+// provider block) over every other source.
+func (r *configResolver) resolve(s configSetting, explicit string) resolvedSetting {
+	res := r.lookup(s, explicit)
+	if res.Source != "" {
+		log.Printf("[DEBUG] Resolved provider setting %s from %s", s.Name, res.Source)
+	}
+	return res
+}
+
+func (r *configResolver) lookup(s configSetting, explicit string) resolvedSetting {
+	if explicit != "" {
+		return resolvedSetting{Value: explicit, Source: "provider configuration"}
+	}
+
+	for _, k := range s.EnvVars {
+		if v := r.getenv(k); v != "" {
+			return resolvedSetting{Value: v, Source: fmt.Sprintf("environment variable %s", k)}
+		}
+	}
+
+	if s.GcloudProperty != "" {
+		if v := r.gcloudProperty(s.GcloudProperty); v != "" {
+			return resolvedSetting{Value: v, Source: fmt.Sprintf("gcloud property %s", s.GcloudProperty)}
+		}
+	}
+
+	if s.Metadata != nil && r.metadata != nil {
+		v, err := s.Metadata(r.metadata)
+		if err != nil {
+			log.Printf("[DEBUG] Unable to read %s from the metadata server: %s", s.Name, err)
+		} else if v != "" {
+			return resolvedSetting{Value: v, Source: "metadata server"}
+		}
+	}
+
+	return resolvedSetting{}
+}
+
+func (r *configResolver) gcloudProperty(property string) string {
+	r.gcloudOnce.Do(func() {
+		props, err := r.loadGcloudProperties()
+		if err != nil {
+			log.Printf("[DEBUG] Unable to read gcloud configuration: %s", err)
+		}
+		r.gcloudProperties = props
+	})
+	return r.gcloudProperties[property]
+}
+
+// readGcloudProperties reads the properties of the active gcloud
+// configuration, honouring the same environment variables as gcloud to find
+// it. A missing configuration isn't an error.
+func readGcloudProperties(getenv func(string) string) (map[string]string, error) {
+	dir := getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(getenv("APPDATA"), "gcloud")
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+
+	name := getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")
+	if name == "" {
+		if b, err := os.ReadFile(filepath.Join(dir, "active_config")); err == nil {
+			name = strings.TrimSpace(string(b))
+		}
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	f, err := os.Open(filepath.Join(dir, "configurations", "config_"+name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	props := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || section == "" {
+			continue
+		}
+		props[section+"/"+strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return props, scanner.Err()
+}
@@ -0,0 +1,147 @@
+package google
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigResolver(t *testing.T) {
+	metadataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Metadata-Flavor", "Google")
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte("metadata-project"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer metadataServer.Close()
+
+	cases := map[string]struct {
+		Explicit       string
+		Env            map[string]string
+		Gcloud         map[string]string
+		UseMetadata    bool
+		ExpectedValue  string
+		ExpectedSource string
+	}{
+		"explicit beats everything": {
+			Explicit:       "explicit-project",
+			Env:            map[string]string{"GOOGLE_PROJECT": "env-project"},
+			Gcloud:         map[string]string{"core/project": "gcloud-project"},
+			UseMetadata:    true,
+			ExpectedValue:  "explicit-project",
+			ExpectedSource: "provider configuration",
+		},
+		"env vars are checked in order": {
+			Env:            map[string]string{"GCLOUD_PROJECT": "gcloud-env-project", "GOOGLE_CLOUD_PROJECT": "cloud-env-project"},
+			Gcloud:         map[string]string{"core/project": "gcloud-project"},
+			ExpectedValue:  "cloud-env-project",
+			ExpectedSource: "environment variable GOOGLE_CLOUD_PROJECT",
+		},
+		"gcloud beats metadata": {
+			Gcloud:         map[string]string{"core/project": "gcloud-project"},
+			UseMetadata:    true,
+			ExpectedValue:  "gcloud-project",
+			ExpectedSource: "gcloud property core/project",
+		},
+		"metadata is the last resort": {
+			UseMetadata:    true,
+			ExpectedValue:  "metadata-project",
+			ExpectedSource: "metadata server",
+		},
+		"unset": {},
+	}
+
+	for tn, tc := range cases {
+		r := &configResolver{
+			getenv: func(k string) string { return tc.Env[k] },
+			loadGcloudProperties: func() (map[string]string, error) {
+				return tc.Gcloud, nil
+			},
+		}
+		if tc.UseMetadata {
+			r.metadata = &metadataClient{
+				host:   strings.TrimPrefix(metadataServer.URL, "http://"),
+				client: metadataServer.Client(),
+				cache:  make(map[string]string),
+			}
+		}
+
+		res := r.resolve(projectSetting, tc.Explicit)
+		if res.Value != tc.ExpectedValue || res.Source != tc.ExpectedSource {
+			t.Errorf("%s: expected %q from %q, got %q from %q", tn, tc.ExpectedValue, tc.ExpectedSource, res.Value, res.Source)
+		}
+	}
+}
+
+func TestConfigResolver_explicitFalseBeatsEnvironment(t *testing.T) {
+	r := &configResolver{
+		getenv: func(k string) string {
+			return map[string]string{"USER_PROJECT_OVERRIDE": "true"}[k]
+		},
+		loadGcloudProperties: func() (map[string]string, error) {
+			return nil, nil
+		},
+	}
+
+	if res := r.resolve(userProjectOverrideSetting, "false"); res.Value != "false" {
+		t.Errorf("expected the explicit value false, got %q from %q", res.Value, res.Source)
+	}
+	if res := r.resolve(userProjectOverrideSetting, ""); res.Value != "true" {
+		t.Errorf("expected the environment variable's value true, got %q from %q", res.Value, res.Source)
+	}
+}
+
+func TestReadGcloudProperties(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "configurations"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "active_config"), []byte("work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := `
+[core]
+account = someone@example.com
+project = my-project
+
+# a comment
+[compute]
+region = us-central1
+zone = us-central1-a
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "configurations", "config_work"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{"CLOUDSDK_CONFIG": dir}
+	props, err := readGcloudProperties(func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"core/account":   "someone@example.com",
+		"core/project":   "my-project",
+		"compute/region": "us-central1",
+		"compute/zone":   "us-central1-a",
+	}
+	for k, v := range expected {
+		if props[k] != v {
+			t.Errorf("expected %s to be %q, got %q", k, v, props[k])
+		}
+	}
+
+	// An explicitly selected configuration that doesn't exist yields nothing
+	env["CLOUDSDK_ACTIVE_CONFIG_NAME"] = "missing"
+	props, err = readGcloudProperties(func(k string) string { return env[k] })
+	if err != nil || len(props) != 0 {
+		t.Errorf("expected no properties and no error, got %v, %v", props, err)
+	}
+}
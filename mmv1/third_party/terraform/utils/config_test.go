@@ -3,6 +3,7 @@ package google
 import (
 	"context"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"testing"
 	"time"
@@ -306,3 +307,19 @@ func TestRemoveBasePathVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigOperationPollingClient_timeout(t *testing.T) {
+	pollingClient := &http.Client{Timeout: 30 * time.Second}
+	config := &Config{pollingClient: pollingClient}
+
+	if c := config.operationPollingClient(0); c != pollingClient {
+		t.Errorf("expected the polling client without a timeout")
+	}
+	c := config.operationPollingClient(20 * time.Minute)
+	if c.Timeout != 20*time.Minute {
+		t.Errorf("expected polls to time out after the resource's timeout, got %s", c.Timeout)
+	}
+	if pollingClient.Timeout != 30*time.Second {
+		t.Errorf("expected the polling client not to be changed, got a timeout of %s", pollingClient.Timeout)
+	}
+}
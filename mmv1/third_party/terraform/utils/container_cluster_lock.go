@@ -0,0 +1,16 @@
+package google
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var clusterIdRegex = regexp.MustCompile("projects/(?P<project>[^/]+)/locations/(?P<location>[^/]+)/clusters/(?P<name>[^/]+)")
+
+// containerClusterMutexKey returns the key of the lock held while changing a
+// GKE cluster or its node pools. GKE runs a single operation on a cluster at
+// a time, rejecting others (see isContainerOperationInProgressError), so
+// changes made by this provider process are serialized instead.
+func containerClusterMutexKey(project, location, clusterName string) string {
+	return fmt.Sprintf("google-container-cluster/%s/%s/%s", project, location, clusterName)
+}
@@ -0,0 +1,85 @@
+// Out of band change detection for resources whose APIs don't return an etag
+// or fingerprint. These resources store a hash of the object they last read in
+// a computed field, and compare it to a hash of the current API object before
+// updating. Resources opt in by adding contentFingerprintSchema() to their
+// schema, calling setContentFingerprint in Read, and calling
+// checkContentFingerprint before sending an update.
+
+package google
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const contentFingerprintField = "content_fingerprint"
+
+func contentFingerprintSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: `A hash of the resource as last read from the API, used to detect changes made outside of Terraform.`,
+	}
+}
+
+// computeContentFingerprint returns a stable hash of an API object. Output
+// only fields that change without user action (eg timestamps or status) should
+// be passed as ignoredFields so they don't register as changes.
+func computeContentFingerprint(obj map[string]interface{}, ignoredFields ...string) (string, error) {
+	filtered := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		filtered[k] = v
+	}
+	for _, f := range ignoredFields {
+		delete(filtered, f)
+	}
+
+	// encoding/json sorts map keys, so equal objects produce equal output
+	b, err := json.Marshal(filtered)
+	if err != nil {
+		return "", fmt.Errorf("error computing content fingerprint: %s", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func setContentFingerprint(d *schema.ResourceData, obj map[string]interface{}, ignoredFields ...string) error {
+	fingerprint, err := computeContentFingerprint(obj, ignoredFields...)
+	if err != nil {
+		return err
+	}
+	if err := d.Set(contentFingerprintField, fingerprint); err != nil {
+		return fmt.Errorf("Error setting %s: %s", contentFingerprintField, err)
+	}
+	return nil
+}
+
+// checkContentFingerprint compares the fingerprint of the current API object
+// with the one recorded the last time the resource was read. If they differ,
+// the resource was changed out of band: this is an error if the provider is
+// configured with the error_on_out_of_band_changes feature, and a warning
+// otherwise.
+func checkContentFingerprint(d *schema.ResourceData, config *Config, current map[string]interface{}, ignoredFields ...string) error {
+	last := d.Get(contentFingerprintField).(string)
+	if last == "" {
+		return nil
+	}
+
+	fingerprint, err := computeContentFingerprint(current, ignoredFields...)
+	if err != nil {
+		return err
+	}
+	if fingerprint == last {
+		return nil
+	}
+
+	if config.Features.ErrorOnOutOfBandChanges {
+		return fmt.Errorf("%s was changed outside of Terraform since it was last read. Run `terraform apply -refresh-only` to review the changes, then apply again.", d.Id())
+	}
+	addResourceWarning(d, config, "Resource changed outside of Terraform", fmt.Sprintf("%s was changed outside of Terraform since it was last read; the update will overwrite those changes.", d.Id()))
+	return nil
+}
@@ -0,0 +1,95 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestComputeContentFingerprint(t *testing.T) {
+	a, err := computeContentFingerprint(map[string]interface{}{
+		"name":       "foo",
+		"filter":     "severity>=ERROR",
+		"updateTime": "2022-01-01T00:00:00Z",
+	}, "updateTime")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := computeContentFingerprint(map[string]interface{}{
+		"filter":     "severity>=ERROR",
+		"name":       "foo",
+		"updateTime": "2022-06-01T00:00:00Z",
+	}, "updateTime")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a != b {
+		t.Errorf("expected objects differing only in ignored fields to have equal fingerprints, got %s and %s", a, b)
+	}
+
+	c, err := computeContentFingerprint(map[string]interface{}{
+		"name":   "foo",
+		"filter": "severity>=WARNING",
+	}, "updateTime")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a == c {
+		t.Errorf("expected objects with different fields to have different fingerprints")
+	}
+}
+
+func TestCheckContentFingerprint(t *testing.T) {
+	last := map[string]interface{}{"name": "foo", "filter": "severity>=ERROR"}
+	changed := map[string]interface{}{"name": "foo", "filter": "severity>=WARNING"}
+
+	cases := map[string]struct {
+		recorded    bool
+		current     map[string]interface{}
+		features    Features
+		expectErr   bool
+		expectWarns int
+	}{
+		"never read": {
+			current:  changed,
+			features: Features{ErrorOnOutOfBandChanges: true},
+		},
+		"unchanged": {
+			recorded: true,
+			current:  last,
+			features: Features{ErrorOnOutOfBandChanges: true},
+		},
+		"changed": {
+			recorded:    true,
+			current:     changed,
+			expectWarns: 1,
+		},
+		"changed with error_on_out_of_band_changes": {
+			recorded:  true,
+			current:   changed,
+			features:  Features{ErrorOnOutOfBandChanges: true},
+			expectErr: true,
+		},
+	}
+	for tn, tc := range cases {
+		d := schema.TestResourceDataRaw(t, map[string]*schema.Schema{
+			contentFingerprintField: contentFingerprintSchema(),
+		}, map[string]interface{}{})
+		d.SetId("projects/my-project/sinks/foo")
+		if tc.recorded {
+			if err := setContentFingerprint(d, last); err != nil {
+				t.Fatalf("%s: unexpected error: %s", tn, err)
+			}
+		}
+
+		config := &Config{Features: tc.features, resourceWarnings: newResourceWarnings()}
+		err := checkContentFingerprint(d, config, tc.current)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tn, tc.expectErr, err)
+		}
+		if warns := config.resourceWarnings.take(d); len(warns) != tc.expectWarns {
+			t.Errorf("%s: expected %d warnings, got %v", tn, tc.expectWarns, warns)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package google
 import (
 	"encoding/json"
 	"reflect"
+	"strconv"
 )
 
 // Convert between two types by converting to/from JSON. Intended to switch
@@ -95,3 +96,58 @@ func setOmittedFields(item, out interface{}) {
 		}
 	}
 }
+
+// flattenInt64 flattens an API int64 value to an integer. The API sends int64
+// values as strings, but some fields are sent as JSON numbers, which are
+// decoded as float64s (and rounded above 2^53) unless the response was
+// decoded with UseNumber. Values that can't be converted are returned as-is
+// to let Terraform core handle them.
+func flattenInt64(v interface{}) interface{} {
+	switch n := v.(type) {
+	case string:
+		if i, err := stringToFixed64(n); err == nil {
+			return i
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if f, err := n.Float64(); err == nil {
+			return int(f)
+		}
+	case float64:
+		return int(n)
+	}
+	return v
+}
+
+// flattenInt64String flattens an API int64 value to its decimal string, for
+// fields like project numbers and fingerprints stored as strings.
+func flattenInt64String(v interface{}) interface{} {
+	switch n := v.(type) {
+	case json.Number:
+		return n.String()
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(n)
+	case int64:
+		return strconv.FormatInt(n, 10)
+	}
+	return v
+}
+
+// expandInt64 expands an integer to the decimal string the API expects for
+// int64 fields. Strings, eg from fields storing large IDs as strings, are
+// sent as-is.
+func expandInt64(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return strconv.Itoa(n)
+	case int64:
+		return strconv.FormatInt(n, 10)
+	case json.Number:
+		return n.String()
+	}
+	return v
+}
@@ -1,6 +1,7 @@
 package google
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -79,3 +80,55 @@ func TestSetOmittedFields(t *testing.T) {
 		t.Errorf("Structs were not equivalent after conversion:\nInput:%#v\nOutput: %#v", input, output)
 	}
 }
+
+func TestFlattenInt64(t *testing.T) {
+	cases := map[string]struct {
+		v    interface{}
+		want interface{}
+	}{
+		"string":            {v: "9007199254740993", want: int64(9007199254740993)},
+		"json.Number":       {v: json.Number("9007199254740993"), want: int64(9007199254740993)},
+		"float json.Number": {v: json.Number("3.0"), want: 3},
+		"float64":           {v: float64(3), want: 3},
+		"not a number":      {v: "abc", want: "abc"},
+		"nil":               {v: nil, want: nil},
+	}
+	for tn, tc := range cases {
+		if got := flattenInt64(tc.v); got != tc.want {
+			t.Errorf("%s: expected %#v, got %#v", tn, tc.want, got)
+		}
+	}
+}
+
+func TestFlattenInt64String(t *testing.T) {
+	cases := map[string]struct {
+		v    interface{}
+		want interface{}
+	}{
+		"json.Number": {v: json.Number("9007199254740993"), want: "9007199254740993"},
+		"float64":     {v: float64(123456789012), want: "123456789012"},
+		"int":         {v: 3, want: "3"},
+		"string":      {v: "9007199254740993", want: "9007199254740993"},
+	}
+	for tn, tc := range cases {
+		if got := flattenInt64String(tc.v); got != tc.want {
+			t.Errorf("%s: expected %#v, got %#v", tn, tc.want, got)
+		}
+	}
+}
+
+func TestExpandInt64(t *testing.T) {
+	cases := map[string]struct {
+		v    interface{}
+		want interface{}
+	}{
+		"int":    {v: 9007199254740993, want: "9007199254740993"},
+		"int64":  {v: int64(9007199254740993), want: "9007199254740993"},
+		"string": {v: "9007199254740993", want: "9007199254740993"},
+	}
+	for tn, tc := range cases {
+		if got := expandInt64(tc.v); got != tc.want {
+			t.Errorf("%s: expected %#v, got %#v", tn, tc.want, got)
+		}
+	}
+}
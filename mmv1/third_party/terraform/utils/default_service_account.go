@@ -0,0 +1,109 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// defaultServiceAccountKind is a kind of service account Google creates for
+// a project.
+type defaultServiceAccountKind string
+
+const (
+	// PROJECT_NUMBER-compute@developer.gserviceaccount.com, created when the
+	// Compute Engine API is enabled
+	defaultServiceAccountCompute defaultServiceAccountKind = "Compute Engine"
+	// PROJECT_ID@appspot.gserviceaccount.com, created with an App Engine
+	// application
+	defaultServiceAccountAppEngine defaultServiceAccountKind = "App Engine"
+	// bq-PROJECT_NUMBER@bigquery-encryption.iam.gserviceaccount.com, created
+	// on first use
+	defaultServiceAccountBigQuery defaultServiceAccountKind = "BigQuery"
+)
+
+// defaultServiceAccountCache remembers the default service accounts looked up
+// during a run. They never change for the lifetime of a project, so there's
+// no need to expire them.
+type defaultServiceAccountCache struct {
+	mu     sync.Mutex
+	emails map[string]string
+}
+
+func newDefaultServiceAccountCache() *defaultServiceAccountCache {
+	return &defaultServiceAccountCache{
+		emails: make(map[string]string),
+	}
+}
+
+func (c *defaultServiceAccountCache) get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	email, ok := c.emails[key]
+	return email, ok
+}
+
+func (c *defaultServiceAccountCache) set(key, email string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.emails[key] = email
+}
+
+// getDefaultServiceAccount returns the email of the default service account
+// of the given kind for project. Accounts that can be derived from the project
+// id are never looked up; the others are looked up once per project and
+// cached for the rest of the run. The account isn't guaranteed to exist, eg
+// the Compute Engine one may have been deleted.
+func getDefaultServiceAccount(config *Config, userAgent, project string, kind defaultServiceAccountKind) (string, error) {
+	key := fmt.Sprintf("%s/%s", kind, project)
+	if email, ok := config.defaultServiceAccounts.get(key); ok {
+		return email, nil
+	}
+
+	var email string
+	switch kind {
+	case defaultServiceAccountAppEngine:
+		email = appEngineDefaultServiceAccountEmail(project)
+	case defaultServiceAccountCompute:
+		log.Printf("[DEBUG] Looking up the Compute Engine default service account of project %s", project)
+		res, err := config.NewComputeClient(userAgent).Projects.Get(project).Do()
+		if err != nil {
+			return "", errwrap.Wrapf(fmt.Sprintf("Error looking up the Compute Engine default service account of project %s: {{err}}", project), err)
+		}
+		email = res.DefaultServiceAccount
+	case defaultServiceAccountBigQuery:
+		log.Printf("[DEBUG] Looking up the BigQuery service account of project %s", project)
+		res, err := config.NewBigQueryClient(userAgent).Projects.GetServiceAccount(project).Do()
+		if err != nil {
+			return "", errwrap.Wrapf(fmt.Sprintf("Error looking up the BigQuery service account of project %s: {{err}}", project), err)
+		}
+		email = res.Email
+	default:
+		return "", fmt.Errorf("unknown kind of default service account %q", kind)
+	}
+
+	if email == "" {
+		return "", fmt.Errorf("project %s has no %s default service account", project, kind)
+	}
+	config.defaultServiceAccounts.set(key, email)
+	return email, nil
+}
+
+// appEngineDefaultServiceAccountEmail derives the App Engine default service
+// account of a project. Domain-scoped project ids such as example.com:my-app
+// are reversed into my-app.example.com.
+func appEngineDefaultServiceAccountEmail(project string) string {
+	if parts := strings.SplitN(project, ":", 2); len(parts) == 2 {
+		project = fmt.Sprintf("%s.%s", parts[1], parts[0])
+	}
+	return fmt.Sprintf("%s@appspot.gserviceaccount.com", project)
+}
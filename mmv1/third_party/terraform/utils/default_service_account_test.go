@@ -0,0 +1,35 @@
+package google
+
+import "testing"
+
+func TestAppEngineDefaultServiceAccountEmail(t *testing.T) {
+	cases := map[string]string{
+		"my-project":             "my-project@appspot.gserviceaccount.com",
+		"example.com:my-project": "my-project.example.com@appspot.gserviceaccount.com",
+	}
+
+	for project, expected := range cases {
+		if got := appEngineDefaultServiceAccountEmail(project); got != expected {
+			t.Errorf("%s: expected %q, got %q", project, expected, got)
+		}
+	}
+}
+
+func TestGetDefaultServiceAccount_cached(t *testing.T) {
+	config := &Config{defaultServiceAccounts: newDefaultServiceAccountCache()}
+	config.defaultServiceAccounts.set("Compute Engine/my-project", "123-compute@developer.gserviceaccount.com")
+
+	// A cached account is returned without an API call, which would fail as
+	// config has no client
+	email, err := getDefaultServiceAccount(config, "", "my-project", defaultServiceAccountCompute)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if email != "123-compute@developer.gserviceaccount.com" {
+		t.Errorf("expected the cached account, got %q", email)
+	}
+
+	if _, err := getDefaultServiceAccount(config, "", "my-project", defaultServiceAccountKind("Unknown")); err == nil {
+		t.Errorf("expected an error for an unknown kind")
+	}
+}
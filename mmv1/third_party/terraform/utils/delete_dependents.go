@@ -0,0 +1,74 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// deleteDependent describes resources of one kind that may block deleting a
+// resource, eg the instance group managers using an instance template. APIs
+// reject deleting a resource in use with an opaque resourceInUse error, so
+// resources list their known dependents to name them in the error instead.
+type deleteDependent struct {
+	// Kind names the dependents, eg "instance group manager".
+	Kind string
+	// List returns the names or links of the dependents currently using the
+	// resource.
+	List func() ([]string, error)
+}
+
+// blockingDependents returns the dependents currently using a resource, as
+// "<kind> <name>". Dependents that can't be listed are skipped, as the check
+// is only there to improve on the API's error.
+func blockingDependents(dependents []deleteDependent) []string {
+	var blocking []string
+	for _, dep := range dependents {
+		names, err := dep.List()
+		if err != nil {
+			log.Printf("[DEBUG] Unable to list %s dependents: %s", dep.Kind, err)
+			continue
+		}
+		for _, name := range names {
+			blocking = append(blocking, fmt.Sprintf("%s %s", dep.Kind, name))
+		}
+	}
+	return blocking
+}
+
+func dependentsError(resource string, blocking []string) error {
+	return fmt.Errorf("Cannot delete %s, it's still used by:\n\n  %s\n\nDelete these resources or remove their references to %s first. If they're managed by Terraform, make them depend on %s so they're deleted before it.",
+		resource, strings.Join(blocking, "\n  "), resource, resource)
+}
+
+// deleteWithDependentCheck checks none of dependents is using resource
+// before calling deleteFunc, so deleting a resource in use fails naming what
+// uses it. If the API still rejects the delete as the resource is in use, eg
+// because a dependent was created since the check, the dependents are listed
+// again and named in the returned error, which wraps the API's.
+func deleteWithDependentCheck(resource string, dependents []deleteDependent, deleteFunc func() error) error {
+	if blocking := blockingDependents(dependents); len(blocking) > 0 {
+		return dependentsError(resource, blocking)
+	}
+
+	err := deleteFunc()
+	if err == nil {
+		return nil
+	}
+
+	inUse := false
+	errwrap.Walk(err, func(werr error) {
+		if ok, _ := isResourceInUseError(werr); ok {
+			inUse = true
+		}
+	})
+	if !inUse {
+		return err
+	}
+	if blocking := blockingDependents(dependents); len(blocking) > 0 {
+		return errwrap.Wrap(dependentsError(resource, blocking), err)
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package google
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestDeleteWithDependentCheck(t *testing.T) {
+	inUse := &googleapi.Error{
+		Code:    400,
+		Message: "The instance_template resource 'projects/p/global/instanceTemplates/t' is already being used by 'projects/p/zones/z/instanceGroupManagers/m'",
+		Errors:  []googleapi.ErrorItem{{Reason: errorReasonResourceInUse}},
+	}
+
+	var users []string
+	dependents := []deleteDependent{
+		{Kind: "instance group manager", List: func() ([]string, error) { return users, nil }},
+		{Kind: "instance", List: func() ([]string, error) { return nil, errors.New("permission denied") }},
+	}
+
+	deletes := 0
+	deleteFunc := func() error {
+		deletes++
+		return nil
+	}
+
+	users = []string{"projects/p/zones/z/instanceGroupManagers/m"}
+	err := deleteWithDependentCheck("instance template t", dependents, deleteFunc)
+	if err == nil || !strings.Contains(err.Error(), "instance group manager projects/p/zones/z/instanceGroupManagers/m") {
+		t.Errorf("expected an error naming the dependent, got %v", err)
+	}
+	if deletes != 0 {
+		t.Errorf("expected delete not to be called while dependents exist")
+	}
+
+	users = nil
+	if err := deleteWithDependentCheck("instance template t", dependents, deleteFunc); err != nil {
+		t.Errorf("expected delete to succeed, got %v", err)
+	}
+	if deletes != 1 {
+		t.Errorf("expected delete to be called once, got %d", deletes)
+	}
+
+	// A dependent created after the check.
+	err = deleteWithDependentCheck("instance template t", dependents, func() error {
+		users = []string{"projects/p/zones/z/instanceGroupManagers/late"}
+		return inUse
+	})
+	if err == nil || !strings.Contains(err.Error(), "instanceGroupManagers/late") {
+		t.Errorf("expected an error naming the dependent, got %v", err)
+	}
+	if !isGoogleApiErrorWithCode(err, 400) {
+		t.Errorf("expected the API error to still be wrapped")
+	}
+
+	// In use by a dependent that isn't known.
+	users = nil
+	if err := deleteWithDependentCheck("instance template t", dependents, func() error { return inUse }); err != inUse {
+		t.Errorf("expected the API error to be returned, got %v", err)
+	}
+}
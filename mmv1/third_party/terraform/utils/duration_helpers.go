@@ -0,0 +1,100 @@
+package google
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// GCP APIs represent durations as google.protobuf.Duration values. In JSON
+// these are usually strings of seconds with an "s" suffix ("3600s", "1.5s"),
+// but some APIs return the object form ({"seconds": "3600", "nanos": 0}).
+// These helpers accept either and store the canonical seconds-suffix form in
+// state, so fields using them only need durationDiffSuppress to ignore
+// equivalent user-supplied strings such as "1h".
+// https://developers.google.com/protocol-buffers/docs/reference/google.protobuf#duration
+
+// parseApiDuration parses a Go duration string ("1h"), a protobuf JSON
+// duration string ("3600s", "1.5s") or a protobuf Duration object.
+func parseApiDuration(v interface{}) (time.Duration, error) {
+	switch dur := v.(type) {
+	case string:
+		return time.ParseDuration(dur)
+	case map[string]interface{}:
+		var secs, nanos int64
+		var err error
+		if s, ok := dur["seconds"]; ok {
+			if secs, err = durationComponentToInt64(s); err != nil {
+				return 0, fmt.Errorf("invalid duration seconds %v: %s", s, err)
+			}
+		}
+		if n, ok := dur["nanos"]; ok {
+			if nanos, err = durationComponentToInt64(n); err != nil {
+				return 0, fmt.Errorf("invalid duration nanos %v: %s", n, err)
+			}
+		}
+		return time.Duration(secs)*time.Second + time.Duration(nanos), nil
+	}
+	return 0, fmt.Errorf("unexpected type %T for duration value %v", v, v)
+}
+
+// int64 fields are strings in API JSON, but we may also see numbers.
+func durationComponentToInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	case float64:
+		return int64(n), nil
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("unexpected type %T", v)
+}
+
+// formatApiDuration returns the canonical seconds-suffix form of a duration,
+// eg "3600s" or "1.5s".
+func formatApiDuration(dur time.Duration) string {
+	sign := ""
+	if dur < 0 {
+		sign = "-"
+		dur = -dur
+	}
+
+	secs := int64(dur / time.Second)
+	nanos := int64(dur % time.Second)
+	if nanos == 0 {
+		return fmt.Sprintf("%s%ds", sign, secs)
+	}
+
+	frac := strings.TrimRight(fmt.Sprintf("%09d", nanos), "0")
+	return fmt.Sprintf("%s%d.%ss", sign, secs, frac)
+}
+
+func expandDuration(v interface{}, d TerraformResourceData, config *Config) (interface{}, error) {
+	if v == nil || v.(string) == "" {
+		return nil, nil
+	}
+
+	dur, err := parseApiDuration(v)
+	if err != nil {
+		return nil, err
+	}
+	return formatApiDuration(dur), nil
+}
+
+func flattenDuration(v interface{}, d *schema.ResourceData, config *Config) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	dur, err := parseApiDuration(v)
+	if err != nil {
+		return v
+	}
+	return formatApiDuration(dur)
+}
@@ -0,0 +1,43 @@
+package google
+
+import (
+	"testing"
+)
+
+func TestFlattenDuration(t *testing.T) {
+	cases := map[string]struct {
+		Input    interface{}
+		Expected interface{}
+	}{
+		"seconds":         {Input: "3600s", Expected: "3600s"},
+		"go duration":     {Input: "1h", Expected: "3600s"},
+		"fractional":      {Input: "1.5s", Expected: "1.5s"},
+		"trailing zeros":  {Input: "60.000s", Expected: "60s"},
+		"nanos":           {Input: "0.000000001s", Expected: "0.000000001s"},
+		"negative":        {Input: "-2.5s", Expected: "-2.5s"},
+		"object":          {Input: map[string]interface{}{"seconds": "90", "nanos": 500000000}, Expected: "90.5s"},
+		"object no nanos": {Input: map[string]interface{}{"seconds": "5"}, Expected: "5s"},
+		"unparseable":     {Input: "forever", Expected: "forever"},
+		"nil":             {Input: nil, Expected: nil},
+	}
+
+	for tn, tc := range cases {
+		if got := flattenDuration(tc.Input, nil, nil); got != tc.Expected {
+			t.Errorf("bad: %s, expected %v, got %v", tn, tc.Expected, got)
+		}
+	}
+}
+
+func TestExpandDuration(t *testing.T) {
+	got, err := expandDuration("2m30s", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != "150s" {
+		t.Errorf("expected 150s, got %v", got)
+	}
+
+	if _, err := expandDuration("not a duration", nil, nil); err == nil {
+		t.Errorf("expected error for invalid duration")
+	}
+}
@@ -0,0 +1,431 @@
+// Lookups of the policies in effect for a resource, including those it
+// inherits: the firewall rules and firewall policy rules applied to a network
+// interface of an instance, and the IAM bindings of a project together with
+// those of its folders and organization. They back computed "effective"
+// views, so they're flattened in a stable order: the API returns them in its
+// own order, which isn't a change.
+
+package google
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+)
+
+// How long effective policies are remembered. Every data source reading the
+// effective IAM of projects in the same folder reads the policy of the folder,
+// so it only needs to cover one plan.
+const effectivePolicyCacheTTL = time.Minute
+
+// effectivePolicyCache remembers the responses read by
+// getInstanceEffectiveFirewalls and getProjectEffectiveIamPolicy, keyed by
+// URL, including denied reads. Errors aren't cached.
+type effectivePolicyCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]effectivePolicyEntry
+}
+
+type effectivePolicyEntry struct {
+	// res is nil if the read was denied
+	res     map[string]interface{}
+	expires time.Time
+}
+
+func newEffectivePolicyCache(ttl time.Duration, clock Clock) *effectivePolicyCache {
+	return &effectivePolicyCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]effectivePolicyEntry),
+	}
+}
+
+func (c *effectivePolicyCache) get(url string) (map[string]interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if ok && !c.clock.Now().Before(e.expires) {
+		delete(c.entries, url)
+		return nil, false
+	}
+	return e.res, ok
+}
+
+func (c *effectivePolicyCache) set(url string, res map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = effectivePolicyEntry{res: res, expires: c.clock.Now().Add(c.ttl)}
+}
+
+// readEffectivePolicy sends a request reading a policy, returning a nil
+// response if the caller isn't allowed to read it.
+func readEffectivePolicy(config *Config, method, project, url, userAgent string, body map[string]interface{}) (map[string]interface{}, error) {
+	if res, ok := config.effectivePolicies.get(url); ok {
+		return res, nil
+	}
+
+	res, err := sendRequest(config, method, project, url, userAgent, body)
+	if isGoogleApiErrorWithCode(err, 403) {
+		log.Printf("[WARN] Not allowed to read %s, leaving it out of the effective policy: %s", url, err)
+		config.effectivePolicies.set(url, nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config.effectivePolicies.set(url, res)
+	return res, nil
+}
+
+// getInstanceEffectiveFirewalls returns the firewall rules and firewall
+// policies applied to networkInterface (eg nic0) of an instance.
+func getInstanceEffectiveFirewalls(config *Config, userAgent, project, zone, instance, networkInterface string) (*compute.InstancesGetEffectiveFirewallsResponse, error) {
+	url := fmt.Sprintf("%sprojects/%s/zones/%s/instances/%s/getEffectiveFirewalls?networkInterface=%s", config.ComputeBasePath, project, zone, instance, networkInterface)
+	res, err := readEffectivePolicy(config, "GET", project, url, userAgent, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the effective firewalls of instance %s: %s", instance, err)
+	}
+	if res == nil {
+		return nil, fmt.Errorf("Error reading the effective firewalls of instance %s: it requires compute.instances.getEffectiveFirewalls", instance)
+	}
+
+	firewalls := &compute.InstancesGetEffectiveFirewallsResponse{}
+	if err := Convert(res, firewalls); err != nil {
+		return nil, err
+	}
+	return firewalls, nil
+}
+
+// effectiveIamPolicy is the IAM policy in effect for a project.
+type effectiveIamPolicy struct {
+	// Bindings are the bindings of the project and its ancestors, keyed by
+	// the name of the resource they're attached to, eg folders/123
+	Bindings map[string][]*cloudresourcemanager.Binding
+	// Resources are the names of the project and its ancestors, from the
+	// project up to its organization
+	Resources []string
+	// Unreadable are the names of the resources whose policy the caller
+	// isn't allowed to read, so the bindings may be incomplete. It holds
+	// "ancestry" if the ancestors of the project couldn't be listed.
+	Unreadable []string
+}
+
+// getProjectEffectiveIamPolicy returns the IAM bindings of project, including
+// those inherited from its folders and organization.
+func getProjectEffectiveIamPolicy(config *Config, userAgent, project string) (*effectiveIamPolicy, error) {
+	url := fmt.Sprintf("%sprojects/%s:getAncestry", config.ResourceManagerBasePath, project)
+	res, err := readEffectivePolicy(config, "POST", project, url, userAgent, map[string]interface{}{})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the ancestry of project %s: %s", project, err)
+	}
+
+	// Without the ancestry, the project's own policy is all there is to read
+	resources := []string{"projects/" + project}
+	if res != nil {
+		ancestry := &cloudresourcemanager.GetAncestryResponse{}
+		if err := Convert(res, ancestry); err != nil {
+			return nil, err
+		}
+		resources = resources[:0]
+		for _, a := range ancestry.Ancestor {
+			if a.ResourceId == nil {
+				continue
+			}
+			resources = append(resources, fmt.Sprintf("%ss/%s", a.ResourceId.Type, a.ResourceId.Id))
+		}
+	}
+
+	policy := &effectiveIamPolicy{
+		Bindings:  make(map[string][]*cloudresourcemanager.Binding),
+		Resources: resources,
+	}
+	if res == nil {
+		policy.Unreadable = append(policy.Unreadable, "ancestry")
+	}
+	for _, r := range resources {
+		basePath := config.ResourceManagerBasePath
+		if strings.HasPrefix(r, "folders/") {
+			basePath = config.ResourceManagerV3BasePath
+		}
+		url := fmt.Sprintf("%s%s:getIamPolicy", basePath, r)
+		body := map[string]interface{}{
+			"options": map[string]interface{}{
+				"requestedPolicyVersion": iamPolicyVersion,
+			},
+		}
+		res, err := readEffectivePolicy(config, "POST", project, url, userAgent, body)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading the IAM policy of %s: %s", r, err)
+		}
+		if res == nil {
+			policy.Unreadable = append(policy.Unreadable, r)
+			continue
+		}
+
+		p := &cloudresourcemanager.Policy{}
+		if err := Convert(res, p); err != nil {
+			return nil, err
+		}
+		policy.Bindings[r] = p.Bindings
+	}
+	return policy, nil
+}
+
+// effectiveFirewallsSchema returns the computed schema of the fields set by
+// flattenEffectiveFirewalls.
+func effectiveFirewallsSchema() map[string]*schema.Schema {
+	protocolsSchema := func(protocolField string) *schema.Schema {
+		return &schema.Schema{
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					protocolField: {Type: schema.TypeString, Computed: true},
+					"ports":       {Type: schema.TypeList, Computed: true, Elem: &schema.Schema{Type: schema.TypeString}},
+				},
+			},
+		}
+	}
+	stringList := func() *schema.Schema {
+		return &schema.Schema{Type: schema.TypeList, Computed: true, Elem: &schema.Schema{Type: schema.TypeString}}
+	}
+
+	return map[string]*schema.Schema{
+		"firewalls": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name":                    {Type: schema.TypeString, Computed: true},
+					"self_link":               {Type: schema.TypeString, Computed: true},
+					"direction":               {Type: schema.TypeString, Computed: true},
+					"priority":                {Type: schema.TypeInt, Computed: true},
+					"disabled":                {Type: schema.TypeBool, Computed: true},
+					"source_ranges":           stringList(),
+					"destination_ranges":      stringList(),
+					"source_tags":             stringList(),
+					"target_tags":             stringList(),
+					"source_service_accounts": stringList(),
+					"target_service_accounts": stringList(),
+					"allow":                   protocolsSchema("protocol"),
+					"deny":                    protocolsSchema("protocol"),
+				},
+			},
+		},
+		"firewall_policies": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"name":         {Type: schema.TypeString, Computed: true},
+					"type":         {Type: schema.TypeString, Computed: true},
+					"display_name": {Type: schema.TypeString, Computed: true},
+					"short_name":   {Type: schema.TypeString, Computed: true},
+					"rules": {
+						Type:     schema.TypeList,
+						Computed: true,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"priority":                {Type: schema.TypeInt, Computed: true},
+								"direction":               {Type: schema.TypeString, Computed: true},
+								"action":                  {Type: schema.TypeString, Computed: true},
+								"description":             {Type: schema.TypeString, Computed: true},
+								"disabled":                {Type: schema.TypeBool, Computed: true},
+								"src_ip_ranges":           stringList(),
+								"dest_ip_ranges":          stringList(),
+								"target_resources":        stringList(),
+								"target_service_accounts": stringList(),
+								"layer4_configs":          protocolsSchema("ip_protocol"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// flattenEffectiveFirewalls returns the fields of effectiveFirewallsSchema.
+// Firewall rules are ordered by priority and name, and the rules of each
+// firewall policy by priority. Firewall policies keep the order of the API,
+// which is the order they're evaluated in.
+func flattenEffectiveFirewalls(res *compute.InstancesGetEffectiveFirewallsResponse) map[string]interface{} {
+	firewalls := append([]*compute.Firewall(nil), res.Firewalls...)
+	sort.SliceStable(firewalls, func(i, j int) bool {
+		if firewalls[i].Priority != firewalls[j].Priority {
+			return firewalls[i].Priority < firewalls[j].Priority
+		}
+		return firewalls[i].Name < firewalls[j].Name
+	})
+
+	flattenedFirewalls := make([]interface{}, 0, len(firewalls))
+	for _, f := range firewalls {
+		allow := make([]interface{}, 0, len(f.Allowed))
+		for _, a := range f.Allowed {
+			allow = append(allow, map[string]interface{}{"protocol": a.IPProtocol, "ports": a.Ports})
+		}
+		deny := make([]interface{}, 0, len(f.Denied))
+		for _, d := range f.Denied {
+			deny = append(deny, map[string]interface{}{"protocol": d.IPProtocol, "ports": d.Ports})
+		}
+		flattenedFirewalls = append(flattenedFirewalls, map[string]interface{}{
+			"name":                    f.Name,
+			"self_link":               ConvertSelfLinkToV1(f.SelfLink),
+			"direction":               f.Direction,
+			"priority":                f.Priority,
+			"disabled":                f.Disabled,
+			"source_ranges":           f.SourceRanges,
+			"destination_ranges":      f.DestinationRanges,
+			"source_tags":             f.SourceTags,
+			"target_tags":             f.TargetTags,
+			"source_service_accounts": f.SourceServiceAccounts,
+			"target_service_accounts": f.TargetServiceAccounts,
+			"allow":                   allow,
+			"deny":                    deny,
+		})
+	}
+
+	flattenedPolicies := make([]interface{}, 0, len(res.FirewallPolicys))
+	for _, p := range res.FirewallPolicys {
+		rules := append([]*compute.FirewallPolicyRule(nil), p.Rules...)
+		sort.SliceStable(rules, func(i, j int) bool {
+			return rules[i].Priority < rules[j].Priority
+		})
+
+		flattenedRules := make([]interface{}, 0, len(rules))
+		for _, r := range rules {
+			rule := map[string]interface{}{
+				"priority":                r.Priority,
+				"direction":               r.Direction,
+				"action":                  r.Action,
+				"description":             r.Description,
+				"disabled":                r.Disabled,
+				"target_resources":        r.TargetResources,
+				"target_service_accounts": r.TargetServiceAccounts,
+			}
+			if r.Match != nil {
+				layer4Configs := make([]interface{}, 0, len(r.Match.Layer4Configs))
+				for _, c := range r.Match.Layer4Configs {
+					layer4Configs = append(layer4Configs, map[string]interface{}{"ip_protocol": c.IpProtocol, "ports": c.Ports})
+				}
+				rule["src_ip_ranges"] = r.Match.SrcIpRanges
+				rule["dest_ip_ranges"] = r.Match.DestIpRanges
+				rule["layer4_configs"] = layer4Configs
+			}
+			flattenedRules = append(flattenedRules, rule)
+		}
+
+		flattenedPolicies = append(flattenedPolicies, map[string]interface{}{
+			"name":         p.Name,
+			"type":         p.Type,
+			"display_name": p.DisplayName,
+			"short_name":   p.ShortName,
+			"rules":        flattenedRules,
+		})
+	}
+
+	return map[string]interface{}{
+		"firewalls":         flattenedFirewalls,
+		"firewall_policies": flattenedPolicies,
+	}
+}
+
+// effectiveIamPolicySchema returns the computed schema of the fields set by
+// flattenEffectiveIamPolicy.
+func effectiveIamPolicySchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"bindings": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"attached_to": {Type: schema.TypeString, Computed: true},
+					"role":        {Type: schema.TypeString, Computed: true},
+					"members": {
+						Type:     schema.TypeSet,
+						Computed: true,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+						Set:      schema.HashString,
+					},
+					"condition": {
+						Type:     schema.TypeList,
+						Computed: true,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"expression":  {Type: schema.TypeString, Computed: true},
+								"title":       {Type: schema.TypeString, Computed: true},
+								"description": {Type: schema.TypeString, Computed: true},
+							},
+						},
+					},
+				},
+			},
+		},
+		"unreadable": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+	}
+}
+
+// flattenEffectiveIamPolicy returns the fields of effectiveIamPolicySchema.
+// Bindings are ordered from the project up to its organization, then by role
+// and condition within each resource.
+func flattenEffectiveIamPolicy(policy *effectiveIamPolicy) map[string]interface{} {
+	var bindings []interface{}
+	for _, r := range policy.Resources {
+		rb := append([]*cloudresourcemanager.Binding(nil), policy.Bindings[r]...)
+		sort.SliceStable(rb, func(i, j int) bool {
+			if rb[i].Role != rb[j].Role {
+				return rb[i].Role < rb[j].Role
+			}
+			ki, kj := conditionKeyFromCondition(rb[i].Condition), conditionKeyFromCondition(rb[j].Condition)
+			if ki.Title != kj.Title {
+				return ki.Title < kj.Title
+			}
+			return ki.Expression < kj.Expression
+		})
+
+		for _, b := range rb {
+			bindings = append(bindings, map[string]interface{}{
+				"attached_to": r,
+				"role":        b.Role,
+				"members":     schema.NewSet(schema.HashString, convertStringArrToInterface(b.Members)),
+				"condition":   flattenIamCondition(b.Condition),
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"bindings":   bindings,
+		"unreadable": policy.Unreadable,
+	}
+}
+
+// setEffectivePolicyFields sets the fields returned by a flattener of this
+// file on d.
+func setEffectivePolicyFields(d *schema.ResourceData, fields map[string]interface{}) error {
+	for k, v := range fields {
+		if err := d.Set(k, v); err != nil {
+			return fmt.Errorf("Error setting %s: %s", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/compute/v1"
+)
+
+func TestGetProjectEffectiveIamPolicy(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("POST", "/v1/projects/p:getAncestry", fakeAPIResponse{Body: map[string]interface{}{
+		"ancestor": []interface{}{
+			map[string]interface{}{"resourceId": map[string]interface{}{"type": "project", "id": "p"}},
+			map[string]interface{}{"resourceId": map[string]interface{}{"type": "folder", "id": "123"}},
+			map[string]interface{}{"resourceId": map[string]interface{}{"type": "organization", "id": "456"}},
+		},
+	}})
+	s.Script("POST", "/v1/projects/p:getIamPolicy", fakeAPIResponse{Body: map[string]interface{}{
+		"bindings": []interface{}{
+			map[string]interface{}{"role": "roles/viewer", "members": []interface{}{"user:b@example.com", "user:a@example.com"}},
+			map[string]interface{}{"role": "roles/editor", "members": []interface{}{"user:a@example.com"}},
+		},
+	}})
+	s.Script("POST", "/v3/folders/123:getIamPolicy", fakeAPIResponse{Body: map[string]interface{}{
+		"bindings": []interface{}{
+			map[string]interface{}{"role": "roles/owner", "members": []interface{}{"group:admins@example.com"}},
+		},
+	}})
+	s.Script("POST", "/v1/organizations/456:getIamPolicy", fakeAPIResponse{Status: 403})
+	config := s.Config()
+	config.ResourceManagerBasePath = s.URL + "/v1/"
+	config.ResourceManagerV3BasePath = s.URL + "/v3/"
+	config.effectivePolicies = newEffectivePolicyCache(time.Minute, &fakeClock{})
+
+	for i := 0; i < 2; i++ {
+		policy, err := getProjectEffectiveIamPolicy(config, "", "p")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if expected := []string{"projects/p", "folders/123", "organizations/456"}; !reflect.DeepEqual(policy.Resources, expected) {
+			t.Errorf("expected resources %v, got %v", expected, policy.Resources)
+		}
+		if expected := []string{"organizations/456"}; !reflect.DeepEqual(policy.Unreadable, expected) {
+			t.Errorf("expected unreadable %v, got %v", expected, policy.Unreadable)
+		}
+
+		bindings := flattenEffectiveIamPolicy(policy)["bindings"].([]interface{})
+		var got []string
+		for _, b := range bindings {
+			b := b.(map[string]interface{})
+			got = append(got, b["attached_to"].(string)+" "+b["role"].(string))
+		}
+		if expected := []string{"projects/p roles/editor", "projects/p roles/viewer", "folders/123 roles/owner"}; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected bindings %v, got %v", expected, got)
+		}
+	}
+	if n := s.Requests("POST", "/v3/folders/123:getIamPolicy"); n != 1 {
+		t.Errorf("expected the policy of the folder to be cached, got %d requests", n)
+	}
+	if n := s.Requests("POST", "/v1/organizations/456:getIamPolicy"); n != 1 {
+		t.Errorf("expected the denied policy of the organization to be cached, got %d requests", n)
+	}
+}
+
+func TestFlattenEffectiveFirewalls(t *testing.T) {
+	res := &compute.InstancesGetEffectiveFirewallsResponse{
+		Firewalls: []*compute.Firewall{
+			{Name: "b", Priority: 1000, Allowed: []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"22"}}}},
+			{Name: "c", Priority: 100},
+			{Name: "a", Priority: 1000},
+		},
+		FirewallPolicys: []*compute.InstancesGetEffectiveFirewallsResponseEffectiveFirewallPolicy{
+			{
+				Name: "org-policy",
+				Type: "HIERARCHY",
+				Rules: []*compute.FirewallPolicyRule{
+					{Priority: 2000, Action: "allow"},
+					{Priority: 10, Action: "deny", Match: &compute.FirewallPolicyRuleMatcher{
+						SrcIpRanges:   []string{"0.0.0.0/0"},
+						Layer4Configs: []*compute.FirewallPolicyRuleMatcherLayer4Config{{IpProtocol: "all"}},
+					}},
+				},
+			},
+			{Name: "network-policy", Type: "NETWORK"},
+		},
+	}
+
+	fields := flattenEffectiveFirewalls(res)
+
+	var names []string
+	for _, f := range fields["firewalls"].([]interface{}) {
+		names = append(names, f.(map[string]interface{})["name"].(string))
+	}
+	if expected := []string{"c", "a", "b"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected firewalls ordered %v, got %v", expected, names)
+	}
+
+	policies := fields["firewall_policies"].([]interface{})
+	if len(policies) != 2 || policies[0].(map[string]interface{})["name"] != "org-policy" {
+		t.Fatalf("expected the firewall policies in evaluation order, got %v", policies)
+	}
+	rules := policies[0].(map[string]interface{})["rules"].([]interface{})
+	if rules[0].(map[string]interface{})["action"] != "deny" {
+		t.Errorf("expected the rules ordered by priority, got %v", rules)
+	}
+
+	// The flattened fields match the schema
+	d := schema.TestResourceDataRaw(t, effectiveFirewallsSchema(), map[string]interface{}{})
+	if err := setEffectivePolicyFields(d, fields); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := d.Get("firewall_policies.0.rules.0.layer4_configs.0.ip_protocol"); v != "all" {
+		t.Errorf("expected the layer 4 config to be set, got %v", v)
+	}
+}
+
+func TestFlattenEffectiveIamPolicy_schema(t *testing.T) {
+	policy := &effectiveIamPolicy{
+		Bindings: map[string][]*cloudresourcemanager.Binding{
+			"projects/p": {{
+				Role:      "roles/viewer",
+				Members:   []string{"user:a@example.com"},
+				Condition: &cloudresourcemanager.Expr{Title: "expires", Expression: "request.time < timestamp(\"2023-01-01T00:00:00Z\")"},
+			}},
+		},
+		Resources:  []string{"projects/p"},
+		Unreadable: []string{"ancestry"},
+	}
+
+	d := schema.TestResourceDataRaw(t, effectiveIamPolicySchema(), map[string]interface{}{})
+	if err := setEffectivePolicyFields(d, flattenEffectiveIamPolicy(policy)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := d.Get("bindings.0.condition.0.title"); v != "expires" {
+		t.Errorf("expected the condition to be set, got %v", v)
+	}
+	if v := d.Get("bindings.0.members").(*schema.Set); !v.Contains("user:a@example.com") {
+		t.Errorf("expected the members to be set, got %v", v.List())
+	}
+}
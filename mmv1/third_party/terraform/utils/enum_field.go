@@ -0,0 +1,128 @@
+package google
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// enumField describes a string field holding one of a set of API enum
+// values. Values are matched ignoring case, and aliases are accepted for
+// values the API also knows by another name, eg "SSD" for "PD_SSD".
+//
+// Fields use its methods as their ValidateFunc and DiffSuppressFunc, and
+// normalize the values they send and read with canonical:
+//
+//	"disk_type": {
+//		Type:             schema.TypeString,
+//		Optional:         true,
+//		ValidateFunc:     sqlDiskTypeEnum.validate,
+//		DiffSuppressFunc: sqlDiskTypeEnum.diffSuppress,
+//	},
+type enumField struct {
+	values []string
+	// aliases maps upper cased aliases to values.
+	aliases map[string]string
+}
+
+var enumFields = struct {
+	sync.RWMutex
+	byKey map[string]*enumField
+}{byKey: make(map[string]*enumField)}
+
+// registerEnumField returns the enumField of values with aliases, which map
+// aliases to values, and registers it under key, the resource type and
+// field path, eg google_sql_database_instance.settings.disk_type. It panics
+// if an alias maps to an unknown value, which is a bug in the provider.
+func registerEnumField(key string, values []string, aliases map[string]string) *enumField {
+	f := &enumField{
+		values:  values,
+		aliases: make(map[string]string, len(aliases)),
+	}
+	for alias, value := range aliases {
+		canonical, ok := f.value(value)
+		if !ok {
+			panic(fmt.Sprintf("alias %q of %s maps to unknown value %q", alias, key, value))
+		}
+		f.aliases[strings.ToUpper(alias)] = canonical
+	}
+
+	enumFields.Lock()
+	defer enumFields.Unlock()
+	enumFields.byKey[key] = f
+	return f
+}
+
+// lookupEnumField returns the enumField registered under key.
+func lookupEnumField(key string) (*enumField, bool) {
+	enumFields.RLock()
+	defer enumFields.RUnlock()
+	f, ok := enumFields.byKey[key]
+	return f, ok
+}
+
+// value returns the value matching v, ignoring case.
+func (f *enumField) value(v string) (string, bool) {
+	for _, value := range f.values {
+		if strings.EqualFold(value, v) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// canonical returns the value v stands for, or v if it's neither a value nor
+// an alias, so values the API adds before the provider knows them are kept.
+func (f *enumField) canonical(v string) string {
+	if value, ok := f.value(v); ok {
+		return value
+	}
+	if value, ok := f.aliases[strings.ToUpper(v)]; ok {
+		return value
+	}
+	return v
+}
+
+// validate accepts values and aliases, ignoring case, and empty strings. Other
+// values get a warning rather than an error, as the API may support values the
+// provider doesn't know yet.
+func (f *enumField) validate(i interface{}, k string) ([]string, []error) {
+	v, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+	if v == "" {
+		return nil, nil
+	}
+	if _, ok := f.value(v); ok {
+		return nil, nil
+	}
+	if _, ok := f.aliases[strings.ToUpper(v)]; ok {
+		return nil, nil
+	}
+
+	allowed := append([]string{}, f.values...)
+	for alias := range f.aliases {
+		allowed = append(allowed, alias)
+	}
+	sort.Strings(allowed[len(f.values):])
+	return []string{fmt.Sprintf("%s is %q, which isn't one of the known values %q. It's sent to the API as is.", k, v, allowed)}, nil
+}
+
+// diffSuppress suppresses diffs between values standing for the same value.
+func (f *enumField) diffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	return f.canonical(old) == f.canonical(new)
+}
+
+// flatten returns the canonical value of v, which the API may return in
+// another case or as an alias.
+func (f *enumField) flatten(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	return f.canonical(s)
+}
@@ -0,0 +1,57 @@
+package google
+
+import "testing"
+
+func TestEnumField(t *testing.T) {
+	f := registerEnumField("google_test_thing.disk_type",
+		[]string{"PD_SSD", "PD_HDD"},
+		map[string]string{"SSD": "PD_SSD", "HDD": "pd_hdd"},
+	)
+	if got, ok := lookupEnumField("google_test_thing.disk_type"); !ok || got != f {
+		t.Errorf("expected the registered field to be found")
+	}
+
+	cases := map[string]struct {
+		value     string
+		canonical string
+		warns     bool
+	}{
+		"value":         {value: "PD_SSD", canonical: "PD_SSD"},
+		"lower case":    {value: "pd_hdd", canonical: "PD_HDD"},
+		"alias":         {value: "SSD", canonical: "PD_SSD"},
+		"lower alias":   {value: "hdd", canonical: "PD_HDD"},
+		"empty":         {value: "", canonical: ""},
+		"unknown value": {value: "PD_BALANCED", canonical: "PD_BALANCED", warns: true},
+	}
+	for tn, tc := range cases {
+		if got := f.canonical(tc.value); got != tc.canonical {
+			t.Errorf("%s: expected canonical value %q, got %q", tn, tc.canonical, got)
+		}
+		if got := f.flatten(tc.value); got != tc.canonical {
+			t.Errorf("%s: expected flattened value %q, got %q", tn, tc.canonical, got)
+		}
+		ws, errs := f.validate(tc.value, "disk_type")
+		if len(errs) != 0 {
+			t.Errorf("%s: unexpected errors: %v", tn, errs)
+		}
+		if warns := len(ws) != 0; warns != tc.warns {
+			t.Errorf("%s: expected a warning to be %t, got %v", tn, tc.warns, ws)
+		}
+	}
+
+	if !f.diffSuppress("disk_type", "PD_SSD", "ssd", nil) {
+		t.Errorf("expected diffs between a value and its alias to be suppressed")
+	}
+	if f.diffSuppress("disk_type", "PD_SSD", "PD_HDD", nil) {
+		t.Errorf("expected diffs between values not to be suppressed")
+	}
+}
+
+func TestRegisterEnumField_unknownAlias(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected aliases of unknown values to panic")
+		}
+	}()
+	registerEnumField("google_test_thing.tier", []string{"BASIC"}, map[string]string{"STANDARD": "PREMIUM"})
+}
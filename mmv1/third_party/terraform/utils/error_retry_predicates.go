@@ -1,9 +1,9 @@
 package google
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/url"
 	"regexp"
@@ -11,7 +11,7 @@ import (
 
 	"google.golang.org/api/googleapi"
 	sqladmin "google.golang.org/api/sqladmin/v1beta4"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/codes"
 )
 
 type RetryErrorPredicateFunc func(error) (bool, string)
@@ -24,6 +24,7 @@ var defaultErrorRetryPredicates = []RetryErrorPredicateFunc{
 	isNetworkTimeoutError,
 	isIoEOFError,
 	isConnectionResetNetworkError,
+	isTransientNetworkError,
 
 	// Common GCP error codes
 	isCommonRetryableErrorCode,
@@ -94,6 +95,37 @@ func isConnectionResetNetworkError(err error) (bool, string) {
 	return false, ""
 }
 
+// Messages of transient network errors that may reach us without their
+// original type, eg after being wrapped by a client library.
+var transientNetworkErrorMessages = []string{
+	"i/o timeout",
+	"TLS handshake timeout",
+	"connection reset by peer",
+}
+
+// Transport level failures aren't googleapi.Errors, and are often wrapped
+// several times before they reach a predicate (eg a *net.OpError inside a
+// *url.Error), so unwrap them rather than checking the top level type.
+func isTransientNetworkError(err error) (bool, string) {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && (dnsErr.IsTemporary || dnsErr.IsTimeout) {
+		return true, fmt.Sprintf("temporary DNS error: %v", err)
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Timeout() || opErr.Temporary()) {
+		return true, fmt.Sprintf("temporary network error: %v", err)
+	}
+
+	msg := err.Error()
+	for _, m := range transientNetworkErrorMessages {
+		if strings.Contains(msg, m) {
+			return true, fmt.Sprintf("transient network error: %v", err)
+		}
+	}
+	return false, ""
+}
+
 // Retry 409s because some APIs like Cloud SQL throw a 409 if concurrent calls
 // are being made.
 //
@@ -101,45 +133,102 @@ func isConnectionResetNetworkError(err error) (bool, string) {
 // concurrent calls is to look at the contents of the error message.
 // See https://github.com/hashicorp/terraform-provider-google/issues/3279
 func is409OperationInProgressError(err error) (bool, string) {
-	gerr, ok := err.(*googleapi.Error)
+	body, ok := parseGoogleApiErrorBody(err)
 	if !ok {
 		return false, ""
 	}
 
-	if gerr.Code == 409 && strings.Contains(gerr.Body, "operationInProgress") {
-		log.Printf("[DEBUG] Dismissed an error as retryable based on error code 409 and error reason 'operationInProgress': %s", err)
+	if body.Code == 409 && body.HasReason("operationInProgress") {
 		return true, "Operation still in progress"
 	}
 	return false, ""
 }
 
 func isSubnetworkUnreadyError(err error) (bool, string) {
-	gerr, ok := err.(*googleapi.Error)
+	body, ok := parseGoogleApiErrorBody(err)
 	if !ok {
 		return false, ""
 	}
 
-	if gerr.Code == 400 && strings.Contains(gerr.Body, "resourceNotReady") && strings.Contains(gerr.Body, "subnetworks") {
-		log.Printf("[DEBUG] Dismissed an error as retryable based on error code 400 and error reason 'resourceNotReady' w/ `subnetwork`: %s", err)
+	if body.Code == 400 && body.HasReason("resourceNotReady") && body.Contains("subnetworks") {
 		return true, "Subnetwork not ready"
 	}
 	return false, ""
 }
 
+// Resources that GCE reports as not ready while a Google-managed certificate
+// or a proxy using one is still provisioning. Only the certificate and proxy
+// resources use isCertificateProvisioningError, so errors about anything else
+// not being ready, eg an SSL policy, aren't retried.
+var certificateProvisioningResourceTypes = []string{
+	"sslCertificates",
+	"targetHttpsProxies",
+	"targetSslProxies",
+}
+
+// Retry the 400 "resource is not ready" errors GCE returns while certificates
+// and the proxies using them provision. Retries are bounded by the timeout of
+// the request, which for creates is the resource's create timeout.
+func isCertificateProvisioningError(err error) (bool, string) {
+	body, ok := parseGoogleApiErrorBody(err)
+	if !ok || body.Code != 400 {
+		return false, ""
+	}
+
+	if !body.HasReason("resourceNotReady") && !body.Contains("is not ready") {
+		return false, ""
+	}
+	for _, t := range certificateProvisioningResourceTypes {
+		if body.Contains(t) {
+			return true, "Certificate or proxy still provisioning"
+		}
+	}
+	return false, ""
+}
+
+// Messages of the errors GKE returns when a cluster or one of its node pools
+// is changed while another operation runs on the cluster, eg an upgrade
+// started by GKE itself or a change made outside of this provider process.
+var containerOperationInProgressMessages = []string{
+	"incompatible operation",
+	"is currently being created, deleted, updated or repaired",
+	"is currently upgrading",
+	"please wait and try again once it is done",
+}
+
+// Retry the 400 and 409 errors GKE returns while another operation runs on a
+// cluster. GKE runs a single operation on a cluster at a time, and changes
+// made by this provider process are serialized with containerClusterMutexKey,
+// but operations started elsewhere still conflict with them.
+func isContainerOperationInProgressError(err error) (bool, string) {
+	body, ok := parseGoogleApiErrorBody(err)
+	if !ok || (body.Code != 400 && body.Code != 409) {
+		return false, ""
+	}
+	for _, m := range containerOperationInProgressMessages {
+		if body.ContainsFold(m) {
+			return true, "Waiting for another operation on the GKE cluster"
+		}
+	}
+	return false, ""
+}
+
 // GCE (and possibly other APIs) incorrectly return a 403 rather than a 429 on
 // rate limits.
 func is403QuotaExceededPerMinuteError(err error) (bool, string) {
-	gerr, ok := err.(*googleapi.Error)
+	body, ok := parseGoogleApiErrorBody(err)
 	if !ok {
 		return false, ""
 	}
+	// Rate limits, unlike other quotas, refresh quickly
+	if body.Code == 403 && body.HasReason(errorReasonRateLimitExceeded, errorReasonUserRateLimitExceeded) {
+		return true, "Waiting for rate limit to refresh"
+	}
 	var QuotaRegex = regexp.MustCompile(`Quota exceeded for quota metric '(?P<Metric>.*)' and limit '(?P<Limit>.* per minute)' of service`)
-	if gerr.Code == 403 && QuotaRegex.MatchString(gerr.Body) {
-		matches := QuotaRegex.FindStringSubmatch(gerr.Body)
+	if matches := body.FindStringSubmatch(QuotaRegex); body.Code == 403 && matches != nil {
 		metric := matches[QuotaRegex.SubexpIndex("Metric")]
 		limit := matches[QuotaRegex.SubexpIndex("Limit")]
-		log.Printf("[DEBUG] Dismissed an error as retryable based on error code 403 and error message 'Quota exceeded for quota metric `%s`: %s", metric, err)
-		return true, fmt.Sprintf("Waiting for quota limit %s to refresh", limit)
+		return true, fmt.Sprintf("Waiting for quota limit %s of quota metric %s to refresh", limit, metric)
 	}
 	return false, ""
 }
@@ -154,7 +243,6 @@ func isCommonRetryableErrorCode(err error) (bool, string) {
 	}
 
 	if gerr.Code == 429 || gerr.Code == 500 || gerr.Code == 502 || gerr.Code == 503 {
-		log.Printf("[DEBUG] Dismissed an error as retryable based on error code: %s", err)
 		return true, fmt.Sprintf("Retryable error code %d", gerr.Code)
 	}
 	return false, ""
@@ -175,6 +263,9 @@ func isFingerprintError(err error) (bool, string) {
 		return false, ""
 	}
 
+	if errorHasReason(gerr, 412, errorReasonConditionNotMet) {
+		return true, "fingerprint mismatch"
+	}
 	for _, msg := range FINGERPRINT_FAIL_ERRORS {
 		if strings.Contains(err.Error(), msg) {
 			return true, "fingerprint mismatch"
@@ -188,8 +279,8 @@ func isFingerprintError(err error) (bool, string) {
 // as the resource itself, the permission may not have propagated by the time terraform
 // attempts to create the resource. This allows those errors to be retried until the timeout expires
 func iamMemberMissing(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 400 && strings.Contains(gerr.Body, "permission") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 400 && body.Contains("permission") {
 			return true, "Waiting for IAM member permissions to propagate."
 		}
 	}
@@ -200,9 +291,8 @@ func iamMemberMissing(err error) (bool, string) {
 // organization policy has not propagated.
 // See https://github.com/hashicorp/terraform-provider-google/issues/4349
 func pubsubTopicProjectNotReady(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 400 && strings.Contains(gerr.Body, "retry this operation") {
-			log.Printf("[DEBUG] Dismissed error as a retryable operation: %s", err)
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 400 && body.Contains("retry this operation") {
 			return true, "Waiting for Pubsub topic's project to properly initialize with organiation policy"
 		}
 	}
@@ -225,11 +315,14 @@ func isSqlInternalError(err error) (bool, string) {
 	return false, ""
 }
 
-// Retry if Cloud SQL operation returns a 429 with a specific message for
-// concurrent operations.
+// Retry if Cloud SQL returns a 409 because another operation is running on
+// the instance. Cloud SQL serializes operations per instance, so changes to
+// users, databases and certs of one instance conflict with each other.
+// Changes made by this provider are serialized with sqlInstanceMutexKey, but
+// other clients' aren't.
 func isSqlOperationInProgressError(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == 409 {
-		if strings.Contains(gerr.Body, "instanceAlreadyExists") {
+	if body, ok := parseGoogleApiErrorBody(err); ok && body.Code == 409 {
+		if body.HasReason("instanceAlreadyExists", "alreadyExists") {
 			return false, ""
 		}
 
@@ -243,8 +336,8 @@ func isSqlOperationInProgressError(err error) (bool, string) {
 // together- eg container.googleapis.com in one request followed by compute.g.c
 // in the next (container relies on compute and implicitly activates it)
 func serviceUsageServiceBeingActivated(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == 400 {
-		if strings.Contains(gerr.Body, "Another activation or deactivation is in progress") {
+	if body, ok := parseGoogleApiErrorBody(err); ok && body.Code == 400 {
+		if body.Contains("Another activation or deactivation is in progress") {
 			return true, "Waiting for same service activation/deactivation to finish"
 		}
 
@@ -256,8 +349,8 @@ func serviceUsageServiceBeingActivated(err error) (bool, string) {
 // Retry if Bigquery operation returns a 403 with a specific message for
 // concurrent operations (which are implemented in terms of 'edit quota').
 func isBigqueryIAMQuotaError(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 403 && strings.Contains(strings.ToLower(gerr.Body), "exceeded rate limits") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 403 && body.ContainsFold("exceeded rate limits") {
 			return true, "Waiting for Bigquery edit quota to refresh"
 		}
 	}
@@ -267,9 +360,8 @@ func isBigqueryIAMQuotaError(err error) (bool, string) {
 // Retry if Monitoring operation returns a 409 with a specific message for
 // concurrent operations.
 func isMonitoringConcurrentEditError(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 409 && (strings.Contains(strings.ToLower(gerr.Body), "too many concurrent edits") ||
-			strings.Contains(strings.ToLower(gerr.Body), "could not fulfill the request")) {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 409 && (body.ContainsFold("too many concurrent edits") || body.ContainsFold("could not fulfill the request")) {
 			return true, "Waiting for other Monitoring changes to finish"
 		}
 	}
@@ -290,11 +382,11 @@ func isNotFilestoreQuotaError(err error) (bool, string) {
 // Retry if App Engine operation returns a 409 with a specific message for
 // concurrent operations, or a 404 indicating p4sa has not yet propagated.
 func isAppEngineRetryableError(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 409 && strings.Contains(strings.ToLower(gerr.Body), "operation is already in progress") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 409 && body.ContainsFold("operation is already in progress") {
 			return true, "Waiting for other concurrent App Engine changes to finish"
 		}
-		if gerr.Code == 404 && strings.Contains(strings.ToLower(gerr.Body), "unable to retrieve p4sa") {
+		if body.Code == 404 && body.ContainsFold("unable to retrieve p4sa") {
 			return true, "Waiting for P4SA propagation to GAIA"
 		}
 	}
@@ -303,14 +395,28 @@ func isAppEngineRetryableError(err error) (bool, string) {
 
 // Retry if KMS CryptoKeyVersions returns a 400 for PENDING_GENERATION
 func isCryptoKeyVersionsPendingGeneration(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == 400 {
-		if strings.Contains(gerr.Body, "PENDING_GENERATION") {
+	if body, ok := parseGoogleApiErrorBody(err); ok && body.Code == 400 {
+		if body.Contains("PENDING_GENERATION") {
 			return true, "Waiting for pending key generation"
 		}
 	}
 	return false, ""
 }
 
+// Retry if encrypting or decrypting with a KMS key fails because the key, or
+// the permission to use it, was just created and hasn't propagated yet.
+func isKmsKeyPropagationError(err error) (bool, string) {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 404 {
+			return true, "Waiting for the KMS key to propagate"
+		}
+		if body.Code == 403 && body.Contains("cloudkms.cryptoKeyVersions.useTo") {
+			return true, "Waiting for the permission to use the KMS key to propagate"
+		}
+	}
+	return isCryptoKeyVersionsPendingGeneration(err)
+}
+
 // Retry if getting a resource/operation returns a 404 for specific operations.
 // opType should describe the operation for which 404 can be retryable.
 func isNotFoundRetryableError(opType string) RetryErrorPredicateFunc {
@@ -322,9 +428,18 @@ func isNotFoundRetryableError(opType string) RetryErrorPredicateFunc {
 	}
 }
 
+// isOperationNotFoundError matches the 404 returned when getting an operation
+// that hasn't replicated yet, see commonRefreshFunc.
+func isOperationNotFoundError(err error) (bool, string) {
+	if isGoogleApiErrorWithCode(err, 404) {
+		return true, "Operation not found, it may not have replicated yet"
+	}
+	return false, ""
+}
+
 func isDataflowJobUpdateRetryableError(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 404 && strings.Contains(gerr.Body, "in RUNNING OR DRAINING state") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 404 && body.Contains("in RUNNING OR DRAINING state") {
 			return true, "Waiting for job to be in a valid state"
 		}
 	}
@@ -332,14 +447,51 @@ func isDataflowJobUpdateRetryableError(err error) (bool, string) {
 }
 
 func isPeeringOperationInProgress(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 400 && strings.Contains(gerr.Body, "There is a peering operation in progress") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 400 && body.Contains("There is a peering operation in progress") {
 			return true, "Waiting peering operation to complete"
 		}
 	}
 	return false, ""
 }
 
+// Messages of the errors Service Networking returns when a private services
+// access connection, or the VPC peering backing it, is changed while another
+// change to the network's peerings is in progress.
+var serviceNetworkingOperationInProgressMessages = []string{
+	"cannot modify in parallel",
+	"peering operation in progress",
+	"previous operation on this network",
+	"another operation is in progress",
+}
+
+// Retry the errors Service Networking returns, either from a request or as
+// the error of a failed operation, while another change to the peerings of
+// the network is in progress. Changes made by this provider process are
+// serialized with networkPeeringMutexKey, but resources connected through
+// private services access (eg Cloud SQL instances) and changes made elsewhere
+// still conflict with them.
+func isServiceNetworkingOperationInProgressError(err error) (bool, string) {
+	contains := func(string) bool { return false }
+	var operr *CommonOpError
+	if errors.As(err, &operr) {
+		// FAILED_PRECONDITION and ABORTED
+		if operr.Code == 9 || operr.Code == 10 {
+			message := strings.ToLower(operr.Message)
+			contains = func(s string) bool { return strings.Contains(message, s) }
+		}
+	} else if body, ok := parseGoogleApiErrorBody(err); ok && (body.Code == 400 || body.Code == 409) {
+		contains = body.ContainsFold
+	}
+
+	for _, m := range serviceNetworkingOperationInProgressMessages {
+		if contains(m) {
+			return true, "Waiting for another change to the network's peerings"
+		}
+	}
+	return false, ""
+}
+
 func isCloudFunctionsSourceCodeError(err error) (bool, string) {
 	if operr, ok := err.(*CommonOpError); ok {
 		if operr.Code == 3 && operr.Message == "Failed to retrieve function source code" {
@@ -350,8 +502,8 @@ func isCloudFunctionsSourceCodeError(err error) (bool, string) {
 }
 
 func datastoreIndex409Contention(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 409 && strings.Contains(gerr.Body, "too much contention") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 409 && body.Contains("too much contention") {
 			return true, "too much contention - waiting for less activity"
 		}
 	}
@@ -359,8 +511,8 @@ func datastoreIndex409Contention(err error) (bool, string) {
 }
 
 func iapClient409Operation(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 409 && strings.Contains(strings.ToLower(gerr.Body), "operation was aborted") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 409 && body.ContainsFold("operation was aborted") {
 			return true, "operation was aborted possibly due to concurrency issue - retrying"
 		}
 	}
@@ -368,8 +520,8 @@ func iapClient409Operation(err error) (bool, string) {
 }
 
 func healthcareDatasetNotInitialized(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 404 && strings.Contains(strings.ToLower(gerr.Body), "dataset not initialized") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 404 && body.ContainsFold("dataset not initialized") {
 			return true, "dataset not initialized - retrying"
 		}
 	}
@@ -399,8 +551,8 @@ func isCloudRunCreationConflict(err error) (bool, string) {
 // if the current etag matches the old etag and short-circuit if they do as
 // that indicates the new config is the likely problem.
 func iamServiceAccountNotFound(err error) (bool, string) {
-	if gerr, ok := err.(*googleapi.Error); ok {
-		if gerr.Code == 400 && strings.Contains(gerr.Body, "Service account") && strings.Contains(gerr.Body, "does not exist") {
+	if body, ok := parseGoogleApiErrorBody(err); ok {
+		if body.Code == 400 && body.Contains("Service account") && body.Contains("does not exist") {
 			return true, "service account not found in IAM"
 		}
 	}
@@ -408,14 +560,32 @@ func iamServiceAccountNotFound(err error) (bool, string) {
 	return false, ""
 }
 
+// Deleting a resource that is still referenced by another (eg a network with
+// subnetworks or a service account bound to a running resource) fails with a
+// 400 until the dependent resources finish their own deletion. Only deletes
+// should retry it, as other requests fail with it for good.
+func isResourceInUseError(err error) (bool, string) {
+	body, ok := parseGoogleApiErrorBody(err)
+	if !ok || body.Code != 400 {
+		return false, ""
+	}
+
+	if body.HasReason(errorReasonResourceInUse) {
+		return true, "Waiting for dependent resources to be deleted"
+	}
+	return false, ""
+}
+
 // Big Table uses gRPC and thus does not return errors of type *googleapi.Error.
 // Instead the errors returned are *status.Error. See the types of codes returned
 // here (https://pkg.go.dev/google.golang.org/grpc/codes#Code).
-func isBigTableRetryableError(err error) (bool, string) {
-	statusCode := status.Code(err)
-	if statusCode.String() == "FailedPrecondition" {
-		return true, "Waiting for table to be in a valid state"
-	}
+var bigtableTableStateRetryPredicate = grpcCodeRetryPredicate{
+	codes:   []codes.Code{codes.FailedPrecondition},
+	reason:  "Waiting for table to be in a valid state",
+	backoff: RetryBackoffExponential,
+}
 
-	return false, ""
+func isBigTableRetryableError(err error) (bool, string) {
+	retry, reason, _ := bigtableTableStateRetryPredicate.ShouldRetry(err)
+	return retry, reason
 }
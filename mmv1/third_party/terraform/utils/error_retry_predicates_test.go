@@ -1,9 +1,17 @@
 package google
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
 	"strconv"
+	"syscall"
 	"testing"
 
+	"github.com/hashicorp/errwrap"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -131,3 +139,283 @@ func TestGRPCNotRetryable(t *testing.T) {
 		t.Errorf("Error incorrectly detected as retryable")
 	}
 }
+
+func TestIsResourceInUseError(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"reason": {
+			err: &googleapi.Error{
+				Code: 400,
+				Errors: []googleapi.ErrorItem{
+					{Reason: "resourceInUseByAnotherResource"},
+				},
+			},
+			retryable: true,
+		},
+		"body": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: `{"error": {"errors": [{"reason": "resourceInUseByAnotherResource"}]}}`,
+			},
+			retryable: true,
+		},
+		// Only the reason identifies the error
+		"message": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "The network resource 'projects/p/global/networks/n' is already being used by 'projects/p/regions/r/subnetworks/s'",
+			},
+		},
+		"wrong code": {
+			err: &googleapi.Error{
+				Code: 409,
+				Body: "resourceInUseByAnotherResource",
+			},
+		},
+		"other reason": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "invalid field",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		isRetryable, _ := isResourceInUseError(tc.err)
+		if isRetryable != tc.retryable {
+			t.Errorf("%s: expected retryable to be %t, got %t", tn, tc.retryable, isRetryable)
+		}
+	}
+}
+
+func TestIsTransientNetworkError(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"wrapped connection reset": {
+			err: &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{
+				Op:  "read",
+				Net: "tcp",
+				Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET},
+			}},
+			retryable: true,
+		},
+		"temporary DNS error": {
+			err:       fmt.Errorf("error sending request: %w", &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}),
+			retryable: true,
+		},
+		"unknown host": {
+			err: &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true},
+		},
+		"tls handshake timeout": {
+			err:       errors.New("Get \"https://example.com\": net/http: TLS handshake timeout"),
+			retryable: true,
+		},
+		"i/o timeout": {
+			err:       errors.New("dial tcp 10.0.0.1:443: i/o timeout"),
+			retryable: true,
+		},
+		"api error": {
+			err: &googleapi.Error{Code: 400, Body: "invalid field"},
+		},
+	}
+
+	for tn, tc := range cases {
+		isRetryable, _ := isTransientNetworkError(tc.err)
+		if isRetryable != tc.retryable {
+			t.Errorf("%s: expected retryable to be %t, got %t", tn, tc.retryable, isRetryable)
+		}
+	}
+}
+
+func TestIsCertificateProvisioningError(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"proxy not ready": {
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "The resource 'projects/p/global/sslCertificates/cert' is not ready",
+				Body:    `{"error": {"errors": [{"reason": "resourceNotReady", "message": "The resource 'projects/p/global/sslCertificates/cert' is not ready"}]}}`,
+			},
+			retryable: true,
+		},
+		"target https proxy": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "resourceNotReady: projects/p/global/targetHttpsProxies/proxy",
+			},
+			retryable: true,
+		},
+		"ssl policy not ready": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "resourceNotReady: projects/p/global/sslPolicies/policy",
+			},
+		},
+		"other resource not ready": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "resourceNotReady: projects/p/regions/r/subnetworks/s",
+			},
+		},
+		"wrong code": {
+			err: &googleapi.Error{
+				Code: 404,
+				Body: "resourceNotReady: projects/p/global/sslCertificates/cert",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		isRetryable, _ := isCertificateProvisioningError(tc.err)
+		if isRetryable != tc.retryable {
+			t.Errorf("%s: expected retryable to be %t, got %t", tn, tc.retryable, isRetryable)
+		}
+	}
+}
+
+func TestIsContainerOperationInProgressError(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"incompatible operation": {
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "Cluster is running incompatible operation operation-1234567890.",
+				Body:    `{"error": {"code": 400, "message": "Cluster is running incompatible operation operation-1234567890.", "status": "FAILED_PRECONDITION"}}`,
+			},
+			retryable: true,
+		},
+		"being updated": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "Cluster is currently being created, deleted, updated or repaired and cannot be updated.",
+			},
+			retryable: true,
+		},
+		"conflict": {
+			err: &googleapi.Error{
+				Code: 409,
+				Body: "Operation operation-1234567890 is currently upgrading cluster c. Please wait and try again once it is done.",
+			},
+			retryable: true,
+		},
+		"other failed precondition": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "Node pool np requires a cluster with Workload Identity enabled.",
+			},
+		},
+		"being deleted": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: "Cluster c is currently being deleted.",
+			},
+		},
+		"wrong code": {
+			err: &googleapi.Error{
+				Code: 404,
+				Body: "Cluster is running incompatible operation operation-1234567890.",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		isRetryable, _ := isContainerOperationInProgressError(tc.err)
+		if isRetryable != tc.retryable {
+			t.Errorf("%s: expected retryable to be %t, got %t", tn, tc.retryable, isRetryable)
+		}
+	}
+}
+
+func TestIsServiceNetworkingOperationInProgressError(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"request rejected": {
+			err:       &googleapi.Error{Code: 400, Message: "Cannot modify in parallel: there is a peering operation in progress on network default."},
+			retryable: true,
+		},
+		"failed operation": {
+			err:       &CommonOpError{&cloudresourcemanager.Status{Code: 9, Message: "Previous operation on this network is still in progress."}},
+			retryable: true,
+		},
+		"wrapped failed operation": {
+			err:       fmt.Errorf("Error retrying: %w", &CommonOpError{&cloudresourcemanager.Status{Code: 10, Message: "Another operation is in progress on the network."}}),
+			retryable: true,
+		},
+		"other failed operation": {
+			err:       &CommonOpError{&cloudresourcemanager.Status{Code: 9, Message: "Cannot modify allocated ranges in CreateConnection. Please use UpdateConnection."}},
+			retryable: false,
+		},
+		"other error": {
+			err:       &googleapi.Error{Code: 400, Message: "Invalid network."},
+			retryable: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		isRetryable, _ := isServiceNetworkingOperationInProgressError(tc.err)
+		if isRetryable != tc.retryable {
+			t.Errorf("%s: expected retryable to be %t, got %t", tn, tc.retryable, isRetryable)
+		}
+	}
+}
+
+func TestIsSqlOperationInProgressError(t *testing.T) {
+	cases := map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"operation in progress": {
+			err: &googleapi.Error{
+				Code:    409,
+				Message: "Operation failed because another operation was already in progress.",
+				Body:    `{"error":{"code":409,"message":"Operation failed because another operation was already in progress.","errors":[{"message":"Operation failed because another operation was already in progress.","domain":"global","reason":"operationInProgress"}]}}`,
+				Errors:  []googleapi.ErrorItem{{Reason: "operationInProgress", Message: "Operation failed because another operation was already in progress."}},
+			},
+			retryable: true,
+		},
+		"wrapped operation in progress": {
+			err: errwrap.Wrapf("Error, failed to insert user: {{err}}", &googleapi.Error{
+				Code:   409,
+				Body:   `{"error":{"code":409,"message":"Operation failed because another operation was already in progress.","errors":[{"message":"Operation failed because another operation was already in progress.","domain":"global","reason":"operationInProgress"}]}}`,
+				Errors: []googleapi.ErrorItem{{Reason: "operationInProgress"}},
+			}),
+			retryable: true,
+		},
+		"instance already exists": {
+			err: &googleapi.Error{
+				Code:   409,
+				Body:   `{"error":{"code":409,"message":"The Cloud SQL instance already exists.","errors":[{"message":"The Cloud SQL instance already exists.","domain":"global","reason":"instanceAlreadyExists"}]}}`,
+				Errors: []googleapi.ErrorItem{{Reason: "instanceAlreadyExists"}},
+			},
+		},
+		"already exists": {
+			err: &googleapi.Error{
+				Code:   409,
+				Body:   `{"error":{"code":409,"message":"Resource already exists.","errors":[{"message":"Resource already exists.","domain":"global","reason":"alreadyExists"}]}}`,
+				Errors: []googleapi.ErrorItem{{Reason: "alreadyExists"}},
+			},
+		},
+		"other error": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: `{"error":{"code":400,"message":"Invalid request: Invalid flag name.","errors":[{"message":"Invalid request: Invalid flag name.","domain":"global","reason":"invalid"}]}}`,
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		if retryable, _ := isSqlOperationInProgressError(tc.err); retryable != tc.retryable {
+			t.Errorf("%s: expected retryable to be %t, got %t", tn, tc.retryable, retryable)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+const preconditionFailureType = "type.googleapis.com/google.rpc.PreconditionFailure"
+
+// preconditionViolation is a violation from the google.rpc.PreconditionFailure
+// details of an error, eg {Type: "LIEN", Subject: "liens/p1234-abcd"}.
+type preconditionViolation struct {
+	Type        string
+	Subject     string
+	Description string
+}
+
+// preconditionViolations returns the violations in the details of err, if it's
+// or wraps a googleapi.Error.
+func preconditionViolations(err error) []preconditionViolation {
+	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
+	if !ok || gerr == nil {
+		return nil
+	}
+
+	var violations []preconditionViolation
+	for _, d := range gerr.Details {
+		detail, ok := d.(map[string]interface{})
+		if !ok || detail["@type"] != preconditionFailureType {
+			continue
+		}
+		vs, _ := detail["violations"].([]interface{})
+		for _, v := range vs {
+			raw, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			violation := preconditionViolation{}
+			violation.Type, _ = raw["type"].(string)
+			violation.Subject, _ = raw["subject"].(string)
+			violation.Description, _ = raw["description"].(string)
+			violations = append(violations, violation)
+		}
+	}
+	return violations
+}
+
+// hasFailedPreconditionStatus returns whether err is a 400 error with the
+// FAILED_PRECONDITION status. APIs that only return the canonical status
+// don't include the failedPrecondition reason isFailedPreconditionError
+// checks for.
+func hasFailedPreconditionStatus(err error) bool {
+	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
+	return ok && gerr != nil && gerr.Code == 400 && strings.Contains(gerr.Body, "FAILED_PRECONDITION")
+}
+
+// failedPreconditionRecovery describes a known cause of a failedPrecondition
+// error when deleting a resource, and how to recover from it.
+type failedPreconditionRecovery struct {
+	// ViolationType matches errors with a precondition violation of this type
+	ViolationType string
+	// MessageContains matches errors whose message contains this string, for
+	// APIs that don't return violation details. A recovery with neither
+	// ViolationType nor MessageContains matches any failedPrecondition error.
+	MessageContains string
+	// Guidance is appended to the error when the recovery matches and can't be
+	// performed
+	Guidance string
+	// ForceField is a boolean field that allows Remediate to be performed.
+	// If empty, the recovery only adds Guidance.
+	ForceField string
+	// Remediate fixes the cause of the error, eg by removing the children of
+	// the resource, so that the delete can be retried
+	Remediate func(violations []preconditionViolation) error
+}
+
+func (r failedPreconditionRecovery) matches(err error, violations []preconditionViolation) bool {
+	if r.ViolationType == "" && r.MessageContains == "" {
+		return true
+	}
+	if r.ViolationType != "" {
+		for _, v := range violations {
+			if v.Type == r.ViolationType {
+				return true
+			}
+		}
+	}
+	return r.MessageContains != "" && strings.Contains(err.Error(), r.MessageContains)
+}
+
+// recoverFailedPrecondition handles an error from deleting resource. If err
+// is a 400 failedPrecondition error matching one of recoveries, it performs
+// the matching remediation if the recovery's force field is set and returns
+// true, in which case the delete should be retried. Otherwise, it returns err
+// with the matching guidance. Other errors are returned unchanged.
+func recoverFailedPrecondition(d TerraformResourceData, err error, resource string, recoveries ...failedPreconditionRecovery) (bool, error) {
+	if err == nil || !(isFailedPreconditionError(err) || hasFailedPreconditionStatus(err)) {
+		return false, err
+	}
+
+	violations := preconditionViolations(err)
+	for _, r := range recoveries {
+		if !r.matches(err, violations) {
+			continue
+		}
+
+		if r.ForceField != "" && r.Remediate != nil && d.Get(r.ForceField).(bool) {
+			log.Printf("[DEBUG] %s could not be deleted, attempting to fix it since %s is set: %s", resource, r.ForceField, err)
+			if rerr := r.Remediate(violations); rerr != nil {
+				return false, fmt.Errorf("Error deleting %s: %s. Attempting to fix this since %s is set failed: %s", resource, err, r.ForceField, rerr)
+			}
+			return true, nil
+		}
+
+		guidance := r.Guidance
+		if r.ForceField != "" && r.Remediate != nil {
+			guidance = fmt.Sprintf("%s Alternatively, set %s to true to do this automatically.", guidance, r.ForceField)
+		}
+		var subjects []string
+		for _, v := range violations {
+			if v.Subject != "" {
+				subjects = append(subjects, v.Subject)
+			}
+		}
+		if len(subjects) > 0 {
+			return false, fmt.Errorf("Error deleting %s: %s\n\n%s Affected: %s", resource, err, guidance, strings.Join(subjects, ", "))
+		}
+		return false, fmt.Errorf("Error deleting %s: %s\n\n%s", resource, err, guidance)
+	}
+	return false, err
+}
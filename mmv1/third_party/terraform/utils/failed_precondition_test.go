@@ -0,0 +1,147 @@
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func testLienError() error {
+	return &googleapi.Error{
+		Code:    400,
+		Message: "A lien to prevent deletion was placed on the project",
+		Errors:  []googleapi.ErrorItem{{Reason: errorReasonFailedPrecondition}},
+		Details: []interface{}{
+			map[string]interface{}{
+				"@type": preconditionFailureType,
+				"violations": []interface{}{
+					map[string]interface{}{
+						"type":        "LIEN",
+						"subject":     "liens/p1234-abcd",
+						"description": "A lien to prevent deletion was placed on the project",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPreconditionViolations(t *testing.T) {
+	expected := []preconditionViolation{{
+		Type:        "LIEN",
+		Subject:     "liens/p1234-abcd",
+		Description: "A lien to prevent deletion was placed on the project",
+	}}
+	if got := preconditionViolations(fmt.Errorf("Error deleting project: %w", testLienError())); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := preconditionViolations(fmt.Errorf("not an API error")); got != nil {
+		t.Errorf("expected no violations, got %v", got)
+	}
+}
+
+func TestRecoverFailedPrecondition(t *testing.T) {
+	remediated := 0
+	recovery := failedPreconditionRecovery{
+		ViolationType: "LIEN",
+		Guidance:      "Remove the liens on the project.",
+		ForceField:    "force",
+		Remediate: func(violations []preconditionViolation) error {
+			remediated++
+			if violations[0].Subject == "liens/p1234-stuck" {
+				return fmt.Errorf("permission denied")
+			}
+			return nil
+		},
+	}
+	messageRecovery := failedPreconditionRecovery{
+		MessageContains: "is not empty",
+		Guidance:        "Delete the children first.",
+	}
+	stuckLienError := testLienError().(*googleapi.Error)
+	stuckLienError.Details[0].(map[string]interface{})["violations"].([]interface{})[0].(map[string]interface{})["subject"] = "liens/p1234-stuck"
+
+	cases := map[string]struct {
+		Err           error
+		Force         bool
+		ExpectRetry   bool
+		ExpectErr     []string
+		ExpectRemedy  int
+		ExpectSameErr bool
+	}{
+		"nil": {},
+		"other error": {
+			Err:           &googleapi.Error{Code: 404},
+			ExpectSameErr: true,
+		},
+		"guidance": {
+			Err:       testLienError(),
+			ExpectErr: []string{"Error deleting project p", "Remove the liens on the project. Alternatively, set force to true", "Affected: liens/p1234-abcd"},
+		},
+		"remediated": {
+			Err:          testLienError(),
+			Force:        true,
+			ExpectRetry:  true,
+			ExpectRemedy: 1,
+		},
+		"remediation fails": {
+			Err:          stuckLienError,
+			Force:        true,
+			ExpectErr:    []string{"Attempting to fix this since force is set failed: permission denied"},
+			ExpectRemedy: 1,
+		},
+		"status only": {
+			Err: &googleapi.Error{
+				Code:    400,
+				Message: "Bucket is not empty",
+				Body:    `{"error": {"status": "FAILED_PRECONDITION"}}`,
+			},
+			ExpectErr: []string{"Bucket is not empty\n\nDelete the children first."},
+		},
+		"no matching recovery": {
+			Err: &googleapi.Error{
+				Code:   400,
+				Errors: []googleapi.ErrorItem{{Reason: errorReasonFailedPrecondition}},
+			},
+			ExpectSameErr: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		remediated = 0
+		d := &ResourceDataMock{
+			FieldsInSchema: map[string]interface{}{"force": tc.Force},
+		}
+		retry, err := recoverFailedPrecondition(d, tc.Err, "project p", recovery, messageRecovery)
+		if retry != tc.ExpectRetry {
+			t.Errorf("%s: expected retry to be %t, got %t", tn, tc.ExpectRetry, retry)
+		}
+		if remediated != tc.ExpectRemedy {
+			t.Errorf("%s: expected %d remediations, got %d", tn, tc.ExpectRemedy, remediated)
+		}
+		if tc.ExpectSameErr {
+			if err != tc.Err {
+				t.Errorf("%s: expected the error to be returned unchanged, got %v", tn, err)
+			}
+			continue
+		}
+		if len(tc.ExpectErr) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", tn, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error", tn)
+			continue
+		}
+		for _, e := range tc.ExpectErr {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("%s: expected the error to contain %q, got %s", tn, e, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPIServer is an httptest server standing in for a Google API in unit
+// tests of transport, retry and operation waiting logic. Responses are
+// scripted per method and path:
+//
+//	s := newFakeAPIServer(t)
+//	s.Script("GET", "/v1/projects/p/things/a", s.TooManyRequests(time.Second), fakeAPIResponse{Body: thing})
+//	s.Operation("operations/op-1", 2, thing)
+//	s.Pages("/v1/projects/p/things", "things", page1, page2)
+//	res, err := sendRequest(s.Config(), "GET", "p", s.URL+"/v1/projects/p/things/a", "", nil)
+//
+// Requests that don't match a script fail the test with a 404.
+type fakeAPIServer struct {
+	*httptest.Server
+	t *testing.T
+
+	mu       sync.Mutex
+	scripts  map[string]*fakeAPIScript
+	requests map[string]int
+}
+
+// fakeAPIResponse is a scripted response. Body is encoded as JSON, and
+// defaults to {} for successful responses. Status defaults to 200.
+type fakeAPIResponse struct {
+	Status int
+	Header http.Header
+	Body   interface{}
+}
+
+// fakeAPIScript serves its responses in order, repeating the last one once
+// they've all been served. If handler is set it's used instead.
+type fakeAPIScript struct {
+	responses []fakeAPIResponse
+	served    int
+	handler   func(r *http.Request) fakeAPIResponse
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	s := &fakeAPIServer{
+		t:        t,
+		scripts:  make(map[string]*fakeAPIScript),
+		requests: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func fakeAPIKey(method, path string) string {
+	return method + " " + path
+}
+
+// Config returns a provider config sending requests to the server.
+func (s *fakeAPIServer) Config() *Config {
+	return &Config{
+		client:       s.Client(),
+		PollInterval: 10 * time.Millisecond,
+	}
+}
+
+// Script sets the responses to requests with method to path.
+func (s *fakeAPIServer) Script(method, path string, responses ...fakeAPIResponse) {
+	if len(responses) == 0 {
+		s.t.Fatalf("no responses scripted for %s", fakeAPIKey(method, path))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[fakeAPIKey(method, path)] = &fakeAPIScript{responses: responses}
+}
+
+// TooManyRequests returns a 429 response asking the client to retry after
+// retryAfter, as APIs do when a quota is exceeded.
+func (s *fakeAPIServer) TooManyRequests(retryAfter time.Duration) fakeAPIResponse {
+	return fakeAPIResponse{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": []string{strconv.Itoa(int(retryAfter.Seconds()))}},
+		Body: map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    http.StatusTooManyRequests,
+				"message": "Quota exceeded",
+				"status":  "RESOURCE_EXHAUSTED",
+			},
+		},
+	}
+}
+
+// Operation simulates the long-running operation name, which is pending for
+// the first pendingPolls GETs and done with response after that. It returns
+// the operation as returned by the request that started it.
+func (s *fakeAPIServer) Operation(name string, pendingPolls int, response interface{}) map[string]interface{} {
+	op := map[string]interface{}{"name": name, "done": false}
+	responses := make([]fakeAPIResponse, 0, pendingPolls+1)
+	for i := 0; i < pendingPolls; i++ {
+		responses = append(responses, fakeAPIResponse{Body: op})
+	}
+	responses = append(responses, fakeAPIResponse{Body: map[string]interface{}{
+		"name":     name,
+		"done":     true,
+		"response": response,
+	}})
+	s.Script("GET", "/"+name, responses...)
+	return op
+}
+
+// Pages serves a list at path, split into pages. Each page is returned under
+// itemsField with a nextPageToken, until the last one, and the page returned
+// is the one requested by the pageToken query parameter.
+func (s *fakeAPIServer) Pages(path, itemsField string, pages ...[]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[fakeAPIKey("GET", path)] = &fakeAPIScript{
+		handler: func(r *http.Request) fakeAPIResponse {
+			page := 0
+			if token := r.URL.Query().Get("pageToken"); token != "" {
+				var err error
+				if page, err = strconv.Atoi(strings.TrimPrefix(token, "page-")); err != nil || page >= len(pages) {
+					return fakeAPIError(http.StatusBadRequest, fmt.Sprintf("invalid page token %q", token))
+				}
+			}
+			body := map[string]interface{}{itemsField: pages[page]}
+			if page+1 < len(pages) {
+				body["nextPageToken"] = fmt.Sprintf("page-%d", page+1)
+			}
+			return fakeAPIResponse{Body: body}
+		},
+	}
+}
+
+// Requests returns the number of requests made with method to path.
+func (s *fakeAPIServer) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[fakeAPIKey(method, path)]
+}
+
+func fakeAPIError(status int, message string) fakeAPIResponse {
+	return fakeAPIResponse{
+		Status: status,
+		Body: map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    status,
+				"message": message,
+			},
+		},
+	}
+}
+
+func (s *fakeAPIServer) serve(w http.ResponseWriter, r *http.Request) {
+	key := fakeAPIKey(r.Method, r.URL.Path)
+
+	s.mu.Lock()
+	s.requests[key]++
+	script, ok := s.scripts[key]
+	var res fakeAPIResponse
+	switch {
+	case !ok:
+		s.t.Errorf("unexpected request %s", key)
+		res = fakeAPIError(http.StatusNotFound, fmt.Sprintf("no response scripted for %s", key))
+	case script.handler != nil:
+		res = script.handler(r)
+	default:
+		res = script.responses[script.served]
+		if script.served < len(script.responses)-1 {
+			script.served++
+		}
+	}
+	s.mu.Unlock()
+
+	for k, vs := range res.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	body := res.Body
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if res.Status != 0 {
+		w.WriteHeader(res.Status)
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.t.Errorf("error encoding response to %s: %s", key, err)
+	}
+}
+
+// fakeAPIOperationWaiter polls operations on a fakeAPIServer.
+type fakeAPIOperationWaiter struct {
+	Config *Config
+	URL    string
+	CommonOperationWaiter
+}
+
+func (w *fakeAPIOperationWaiter) QueryOp() (interface{}, error) {
+	return sendRequest(w.Config, "GET", "", fmt.Sprintf("%s/%s", w.URL, w.Op.Name), "", nil)
+}
+
+func TestFakeAPIServer_retriesTooManyRequests(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/v1/projects/p/things/a",
+		s.TooManyRequests(time.Second),
+		s.TooManyRequests(time.Second),
+		fakeAPIResponse{Body: map[string]interface{}{"name": "a"}},
+	)
+	config := s.Config()
+	config.client = ClientWithAdditionalRetries(config.client)
+
+	res, err := sendRequest(config, "GET", "p", s.URL+"/v1/projects/p/things/a", "", nil)
+	if err != nil {
+		t.Fatalf("expected request to succeed after retrying, got error: %s", err)
+	}
+	if res["name"] != "a" {
+		t.Errorf("expected thing a, got %v", res)
+	}
+	if n := s.Requests("GET", "/v1/projects/p/things/a"); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestFakeAPIServer_notFound(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/v1/projects/p/things/a", fakeAPIError(http.StatusNotFound, "thing a not found"))
+
+	_, err := sendRequest(s.Config(), "GET", "p", s.URL+"/v1/projects/p/things/a", "", nil)
+	if !isGoogleApiErrorWithCode(err, 404) {
+		t.Fatalf("expected a 404, got: %v", err)
+	}
+}
+
+func TestFakeAPIServer_paginatedList(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Pages("/v1/projects/p/things", "things",
+		[]interface{}{"a", "b"},
+		[]interface{}{"c"},
+		[]interface{}{"d", "e"},
+	)
+
+	items, err := paginatedList(s.Config(), "p", s.URL+"/v1/projects/p/things", "", ListRequest{ItemsPath: "things"})
+	if err != nil {
+		t.Fatalf("unexpected error listing things: %s", err)
+	}
+	if got := fmt.Sprint(items); got != "[a b c d e]" {
+		t.Errorf("expected things from every page, got %s", got)
+	}
+	if n := s.Requests("GET", "/v1/projects/p/things"); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestFakeAPIServer_operationWait(t *testing.T) {
+	s := newFakeAPIServer(t)
+	op := s.Operation("operations/op-1", 2, map[string]interface{}{"name": "a"})
+	config := s.Config()
+
+	w := &fakeAPIOperationWaiter{Config: config, URL: s.URL}
+	if err := w.SetOp(op); err != nil {
+		t.Fatalf("unexpected error setting operation: %s", err)
+	}
+	if err := OperationWait(w, "Creating thing", time.Minute, config.PollInterval); err != nil {
+		t.Fatalf("unexpected error waiting for operation: %s", err)
+	}
+	if !w.Op.Done {
+		t.Errorf("expected operation to be done")
+	}
+	if n := s.Requests("GET", "/operations/op-1"); n != 3 {
+		t.Errorf("expected 3 polls, got %d", n)
+	}
+}
@@ -0,0 +1,152 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Setting this environment variable makes the provider fail some of the
+// requests it sends without sending them, to check that retries, request IDs
+// and operation waiters cope with misbehaving APIs. It's a comma-separated
+// list of rules, each N:FAULT to fail the Nth request or *N:FAULT to fail
+// every Nth request, where FAULT is an HTTP status code like 429 or 500, or
+// "reset" for a connection reset. Requests are counted from 1 across every
+// client of the provider process, eg GOOGLE_INJECT_FAULTS=1:429,*10:reset.
+// It's only meant for testing.
+const injectFaultsEnvVar = "GOOGLE_INJECT_FAULTS"
+
+// faultReset is the fault of a connection reset by the server.
+const faultReset = "reset"
+
+type faultRule struct {
+	n     int
+	every bool
+	fault string
+}
+
+// faultInjector decides which requests fail, see GOOGLE_INJECT_FAULTS.
+type faultInjector struct {
+	rules []faultRule
+
+	mu       sync.Mutex
+	requests int
+}
+
+func newFaultInjector(spec string) (*faultInjector, error) {
+	f := &faultInjector{}
+	for _, r := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(r), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q isn't N:FAULT or *N:FAULT", r)
+		}
+
+		rule := faultRule{every: strings.HasPrefix(parts[0], "*")}
+		n, err := strconv.Atoi(strings.TrimPrefix(parts[0], "*"))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q doesn't have a positive request number", r)
+		}
+		rule.n = n
+
+		rule.fault = strings.ToLower(parts[1])
+		if rule.fault != faultReset {
+			if code, err := strconv.Atoi(rule.fault); err != nil || code < 400 || code > 599 {
+				return nil, fmt.Errorf("%q doesn't have an error status code or %q as its fault", r, faultReset)
+			}
+		}
+		f.rules = append(f.rules, rule)
+	}
+	return f, nil
+}
+
+// next counts a request, and returns the fault to fail it with, or "" to send
+// it. The first rule matching the request is used.
+func (f *faultInjector) next() (int, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	for _, r := range f.rules {
+		if f.requests == r.n || (r.every && f.requests%r.n == 0) {
+			return f.requests, r.fault
+		}
+	}
+	return f.requests, ""
+}
+
+var (
+	faultInjectorOnce sync.Once
+	faultInjectorInst *faultInjector
+)
+
+// currentFaultInjector returns the injector configured through
+// GOOGLE_INJECT_FAULTS, or nil if no faults are injected.
+func currentFaultInjector() *faultInjector {
+	faultInjectorOnce.Do(func() {
+		spec := os.Getenv(injectFaultsEnvVar)
+		if spec == "" {
+			return
+		}
+		f, err := newFaultInjector(spec)
+		if err != nil {
+			log.Printf("[ERROR] Not injecting faults, %s is invalid: %s", injectFaultsEnvVar, err)
+			return
+		}
+		log.Printf("[WARN] Injecting faults into requests as set by %s=%s", injectFaultsEnvVar, spec)
+		faultInjectorInst = f
+	})
+	return faultInjectorInst
+}
+
+// faultInjectionTransport is a http.RoundTripper failing the requests its
+// faultInjector picks, and sending the others with internal.
+type faultInjectionTransport struct {
+	injector *faultInjector
+	internal http.RoundTripper
+}
+
+func (t *faultInjectionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n, fault := t.injector.next()
+	if fault == "" {
+		return t.internal.RoundTrip(req)
+	}
+
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	log.Printf("[DEBUG] Injecting %s into request %d, %s %s", fault, n, req.Method, req.URL)
+
+	if fault == faultReset {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	}
+
+	code, _ := strconv.Atoi(fault)
+	body, err := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": fmt.Sprintf("Fault injected by %s", injectFaultsEnvVar),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
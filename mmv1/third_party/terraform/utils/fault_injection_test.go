@@ -0,0 +1,58 @@
+package google
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNewFaultInjector(t *testing.T) {
+	for _, spec := range []string{"1:429", "1:429, *10:reset", "*3:503"} {
+		if _, err := newFaultInjector(spec); err != nil {
+			t.Errorf("%q: unexpected error: %s", spec, err)
+		}
+	}
+	for _, spec := range []string{"429", "0:429", "x:429", "1:200", "1:timeout", "1:429,"} {
+		if _, err := newFaultInjector(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestFaultInjectionTransport(t *testing.T) {
+	sent := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	injector, err := newFaultInjector("1:429,*3:reset")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	client := &http.Client{Transport: &faultInjectionTransport{injector: injector, internal: ts.Client().Transport}}
+
+	// Requests 1 to 6
+	expected := []string{"429", "200", "reset", "200", "200", "reset"}
+	for i, want := range expected {
+		res, err := client.Get(ts.URL)
+		got := ""
+		if err != nil {
+			if ok, _ := isConnectionResetNetworkError(err); !ok {
+				t.Fatalf("request %d: expected a connection reset, got %s", i+1, err)
+			}
+			got = "reset"
+		} else {
+			res.Body.Close()
+			got = strconv.Itoa(res.StatusCode)
+		}
+		if got != want {
+			t.Errorf("request %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+	if sent != 3 {
+		t.Errorf("expected 3 requests to be sent, got %d", sent)
+	}
+}
@@ -360,6 +360,10 @@ func parseRegionalFieldValue(resourceType, fieldValue, projectSchemaField, regio
 // - region extracted from the `zoneSchemaField` in resource schema
 // - provider-level region
 // - region extracted from the provider-level zone
+//
+// A region computed from the zone (see locationFieldOptions.ComputedWhen) is
+// stale while the zone changes, so the region in state is only used if the
+// zone is unchanged or the region is changing too.
 func getRegionFromSchema(regionSchemaField, zoneSchemaField string, d TerraformResourceData, config *Config) (string, error) {
 	// if identical such as GKE location, check if it's a zone first and find
 	// the region if so. Otherwise, return as it's a region.
@@ -373,7 +377,8 @@ func getRegionFromSchema(regionSchemaField, zoneSchemaField string, d TerraformR
 		}
 	}
 
-	if v, ok := d.GetOk(regionSchemaField); ok && regionSchemaField != "" {
+	zoneChanged := zoneSchemaField != "" && d.HasChange(zoneSchemaField)
+	if v, ok := d.GetOk(regionSchemaField); ok && regionSchemaField != "" && (!zoneChanged || d.HasChange(regionSchemaField)) {
 		return GetResourceNameFromSelfLink(v.(string)), nil
 	}
 	if v, ok := d.GetOk(zoneSchemaField); ok && zoneSchemaField != "" {
@@ -0,0 +1,290 @@
+package google
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// frameworkResourceData adapts the state and plan of a plugin framework
+// resource to TerraformResourceData and TerraformResourceDiff, so helpers like
+// getProject, getRegion and expandLabels work unchanged for resources that
+// have migrated off SDKv2.
+//
+// Values are read the way they are from a schema.ResourceData: nested values
+// can be read by their full key (eg "node_config.0.machine_type"), and Get
+// returns the planned value, or the prior state if there's no plan (eg on
+// delete). Unknown values are treated as unset.
+//
+// The framework isn't a dependency of the provider, so the adapter is built
+// from the tftypes.Value underlying tfsdk.State and tfsdk.Plan (their Raw
+// field) rather than from the framework types themselves.
+type frameworkResourceData struct {
+	state   map[string]interface{}
+	current map[string]interface{}
+
+	// ProviderMeta is the Raw value of the request's provider_meta, if any.
+	ProviderMeta tftypes.Value
+	// Timeouts by operation, eg "create". Operations without a timeout use
+	// the SDK default of 20 minutes.
+	Timeouts map[string]time.Duration
+
+	// Fields ForceNew was called for, to be reported by the resource's
+	// ModifyPlan as requiring replacement.
+	RequiresReplace []string
+}
+
+// newFrameworkResourceData builds a frameworkResourceData from the Raw values
+// of a request's prior state and plan. Either may be null, eg the state on
+// create or the plan on delete.
+func newFrameworkResourceData(state, plan tftypes.Value) (*frameworkResourceData, error) {
+	d := &frameworkResourceData{
+		state:   make(map[string]interface{}),
+		current: make(map[string]interface{}),
+	}
+
+	stateValues, err := frameworkValueToInterface(state)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading state: %s", err)
+	}
+	if m, ok := stateValues.(map[string]interface{}); ok {
+		for k, v := range m {
+			flattenAttributePaths(d.state, k, v)
+		}
+	}
+
+	current := plan
+	if plan.IsNull() {
+		current = state
+	}
+	currentValues, err := frameworkValueToInterface(current)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading plan: %s", err)
+	}
+	if m, ok := currentValues.(map[string]interface{}); ok {
+		for k, v := range m {
+			flattenAttributePaths(d.current, k, v)
+		}
+	}
+
+	return d, nil
+}
+
+// frameworkValueToInterface converts v to the Go value schema.ResourceData
+// would return for it. Null and unknown values convert to nil, numbers to an
+// int if they're whole or a float64 otherwise, lists, sets and tuples to an
+// []interface{}, and maps and objects to a map[string]interface{}.
+func frameworkValueToInterface(v tftypes.Value) (interface{}, error) {
+	if v.IsNull() || !v.IsKnown() {
+		return nil, nil
+	}
+
+	switch v.Type().(type) {
+	case tftypes.List, tftypes.Set, tftypes.Tuple:
+		var elems []tftypes.Value
+		if err := v.As(&elems); err != nil {
+			return nil, err
+		}
+		l := make([]interface{}, 0, len(elems))
+		for _, e := range elems {
+			ev, err := frameworkValueToInterface(e)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, ev)
+		}
+		return l, nil
+	case tftypes.Map, tftypes.Object:
+		var attrs map[string]tftypes.Value
+		if err := v.As(&attrs); err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, len(attrs))
+		for k, a := range attrs {
+			av, err := frameworkValueToInterface(a)
+			if err != nil {
+				return nil, err
+			}
+			if av != nil {
+				m[k] = av
+			}
+		}
+		return m, nil
+	}
+
+	switch {
+	case v.Type().Is(tftypes.String):
+		var s string
+		err := v.As(&s)
+		return s, err
+	case v.Type().Is(tftypes.Bool):
+		var b bool
+		err := v.As(&b)
+		return b, err
+	case v.Type().Is(tftypes.Number):
+		var f big.Float
+		if err := v.As(&f); err != nil {
+			return nil, err
+		}
+		if f.IsInt() {
+			i, _ := f.Int64()
+			return int(i), nil
+		}
+		f64, _ := f.Float64()
+		return f64, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value type %s", v.Type())
+}
+
+// flattenAttributePaths stores v in fields under key and, for lists and maps,
+// stores each nested value under its full key, eg "labels.env" or
+// "node_config.0.machine_type". The length of a list is stored under
+// "key.#".
+func flattenAttributePaths(fields map[string]interface{}, key string, v interface{}) {
+	fields[key] = v
+	switch v := v.(type) {
+	case []interface{}:
+		fields[key+".#"] = len(v)
+		for i, e := range v {
+			flattenAttributePaths(fields, fmt.Sprintf("%s.%d", key, i), e)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			flattenAttributePaths(fields, key+"."+k, e)
+		}
+	}
+}
+
+// clearAttributePaths removes key and every value nested under it from fields.
+func clearAttributePaths(fields map[string]interface{}, key string) {
+	delete(fields, key)
+	for k := range fields {
+		if strings.HasPrefix(k, key+".") {
+			delete(fields, k)
+		}
+	}
+}
+
+func (d *frameworkResourceData) HasChange(key string) bool {
+	o, n := d.GetChange(key)
+	return !reflect.DeepEqual(o, n)
+}
+
+func (d *frameworkResourceData) GetChange(key string) (interface{}, interface{}) {
+	return d.state[key], d.current[key]
+}
+
+func (d *frameworkResourceData) Get(key string) interface{} {
+	return d.current[key]
+}
+
+func (d *frameworkResourceData) GetOk(key string) (interface{}, bool) {
+	v, ok := d.GetOkExists(key)
+	return v, ok && !isEmptyValue(reflect.ValueOf(v))
+}
+
+func (d *frameworkResourceData) GetOkExists(key string) (interface{}, bool) {
+	v, ok := d.current[key]
+	return v, ok && v != nil
+}
+
+// Set updates the value of key. Values set this way aren't written back to the
+// framework state; resources still need to set the attributes they return.
+func (d *frameworkResourceData) Set(key string, v interface{}) error {
+	clearAttributePaths(d.current, key)
+	flattenAttributePaths(d.current, key, v)
+	return nil
+}
+
+func (d *frameworkResourceData) SetId(id string) {
+	d.current["id"] = id
+}
+
+func (d *frameworkResourceData) Id() string {
+	id, _ := d.current["id"].(string)
+	return id
+}
+
+func (d *frameworkResourceData) Timeout(key string) time.Duration {
+	if t, ok := d.Timeouts[key]; ok {
+		return t
+	}
+	return 20 * time.Minute
+}
+
+// GetProviderMeta decodes ProviderMeta into dst, a pointer to a struct whose
+// fields are tagged with their attribute names like providerMeta. string,
+// *string and map[string]string fields are supported.
+func (d *frameworkResourceData) GetProviderMeta(dst interface{}) error {
+	meta, err := frameworkValueToInterface(d.ProviderMeta)
+	if err != nil {
+		return err
+	}
+	attrs, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("provider meta must be decoded into a pointer to a struct, got %T", dst)
+	}
+	s := rv.Elem()
+	for i := 0; i < s.NumField(); i++ {
+		name := s.Type().Field(i).Tag.Get("cty")
+		v, ok := attrs[name]
+		// Unset attributes are null, and leave their field unset
+		if name == "" || !ok || v == nil {
+			continue
+		}
+		f := s.Field(i)
+		switch f.Interface().(type) {
+		case string:
+			f.SetString(fmt.Sprintf("%v", v))
+		case *string:
+			str := fmt.Sprintf("%v", v)
+			f.Set(reflect.ValueOf(&str))
+		case map[string]string:
+			m := make(map[string]string)
+			if vm, ok := v.(map[string]interface{}); ok {
+				for k, e := range vm {
+					m[k] = fmt.Sprintf("%v", e)
+				}
+			}
+			f.Set(reflect.ValueOf(m))
+		default:
+			return fmt.Errorf("unsupported provider meta field type %s", f.Type())
+		}
+	}
+	return nil
+}
+
+// Clear discards the planned change to key, reverting it to the prior state.
+func (d *frameworkResourceData) Clear(key string) error {
+	clearAttributePaths(d.current, key)
+	if v, ok := d.state[key]; ok {
+		flattenAttributePaths(d.current, key, v)
+	}
+	return nil
+}
+
+// ForceNew records that a change to key requires replacing the resource. The
+// framework has no equivalent call; the resource's ModifyPlan must copy
+// RequiresReplace into its response.
+func (d *frameworkResourceData) ForceNew(key string) error {
+	if !d.HasChange(key) {
+		return fmt.Errorf("ForceNew: No changes for %s", key)
+	}
+	d.RequiresReplace = append(d.RequiresReplace, key)
+	return nil
+}
+
+var (
+	_ TerraformResourceData = &frameworkResourceData{}
+	_ TerraformResourceDiff = &frameworkResourceData{}
+)
@@ -0,0 +1,188 @@
+package google
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+var testFrameworkResourceType = tftypes.Object{
+	AttributeTypes: map[string]tftypes.Type{
+		"name":    tftypes.String,
+		"zone":    tftypes.String,
+		"size":    tftypes.Number,
+		"ratio":   tftypes.Number,
+		"enabled": tftypes.Bool,
+		"labels":  tftypes.Map{ElementType: tftypes.String},
+		"node_config": tftypes.List{ElementType: tftypes.Object{
+			AttributeTypes: map[string]tftypes.Type{"machine_type": tftypes.String},
+		}},
+	},
+}
+
+func testFrameworkResourceValue(name, zone, machineType interface{}) tftypes.Value {
+	nodeConfigType := testFrameworkResourceType.AttributeTypes["node_config"].(tftypes.List)
+	return tftypes.NewValue(testFrameworkResourceType, map[string]tftypes.Value{
+		"name":    tftypes.NewValue(tftypes.String, name),
+		"zone":    tftypes.NewValue(tftypes.String, zone),
+		"size":    tftypes.NewValue(tftypes.Number, big.NewFloat(3)),
+		"ratio":   tftypes.NewValue(tftypes.Number, big.NewFloat(0.5)),
+		"enabled": tftypes.NewValue(tftypes.Bool, false),
+		"labels": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+			"env": tftypes.NewValue(tftypes.String, "prod"),
+		}),
+		"node_config": tftypes.NewValue(nodeConfigType, []tftypes.Value{
+			tftypes.NewValue(nodeConfigType.ElementType, map[string]tftypes.Value{
+				"machine_type": tftypes.NewValue(tftypes.String, machineType),
+			}),
+		}),
+	})
+}
+
+func TestFrameworkResourceData_get(t *testing.T) {
+	state := testFrameworkResourceValue("foo", "us-central1-a", "e2-medium")
+	plan := testFrameworkResourceValue("foo", tftypes.UnknownValue, "e2-standard-4")
+	d, err := newFrameworkResourceData(state, plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := map[string]interface{}{
+		"name":                       "foo",
+		"size":                       3,
+		"ratio":                      0.5,
+		"enabled":                    false,
+		"labels.env":                 "prod",
+		"node_config.#":              1,
+		"node_config.0.machine_type": "e2-standard-4",
+		"zone":                       nil,
+	}
+	for k, expected := range cases {
+		if got := d.Get(k); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %s to be %#v, got %#v", k, expected, got)
+		}
+	}
+
+	if d.HasChange("name") {
+		t.Errorf("expected no change to name")
+	}
+	if !d.HasChange("node_config.0.machine_type") {
+		t.Errorf("expected a change to node_config.0.machine_type")
+	}
+	if o, n := d.GetChange("node_config.0.machine_type"); o != "e2-medium" || n != "e2-standard-4" {
+		t.Errorf("expected machine type to change from e2-medium to e2-standard-4, got %v to %v", o, n)
+	}
+
+	// Unknown values are unset, and false is empty
+	if _, ok := d.GetOk("zone"); ok {
+		t.Errorf("expected the unknown zone to be unset")
+	}
+	if _, ok := d.GetOk("enabled"); ok {
+		t.Errorf("expected GetOk to report false as unset")
+	}
+	if _, ok := d.GetOkExists("enabled"); !ok {
+		t.Errorf("expected GetOkExists to report false as set")
+	}
+}
+
+func TestFrameworkResourceData_nullPlanReadsState(t *testing.T) {
+	state := testFrameworkResourceValue("foo", "us-central1-a", "e2-medium")
+	d, err := newFrameworkResourceData(state, tftypes.NewValue(testFrameworkResourceType, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := d.Get("zone"); got != "us-central1-a" {
+		t.Errorf("expected the zone in state, got %v", got)
+	}
+	if d.HasChange("zone") {
+		t.Errorf("expected no change without a plan")
+	}
+}
+
+func TestFrameworkResourceData_setClearAndForceNew(t *testing.T) {
+	state := testFrameworkResourceValue("foo", "us-central1-a", "e2-medium")
+	plan := testFrameworkResourceValue("foo", "us-central1-a", "e2-standard-4")
+	d, err := newFrameworkResourceData(state, plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := d.Set("labels", map[string]interface{}{"team": "payments"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Get("labels.env") != nil || d.Get("labels.team") != "payments" {
+		t.Errorf("expected Set to replace the nested labels, got %v", d.Get("labels"))
+	}
+
+	if err := d.ForceNew("name"); err == nil {
+		t.Errorf("expected an error forcing replacement for an unchanged field")
+	}
+	if err := d.ForceNew("node_config.0.machine_type"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(d.RequiresReplace, []string{"node_config.0.machine_type"}) {
+		t.Errorf("expected node_config.0.machine_type to require replacement, got %v", d.RequiresReplace)
+	}
+
+	if err := d.Clear("node_config"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.HasChange("node_config.0.machine_type") {
+		t.Errorf("expected Clear to revert the change to node_config")
+	}
+
+	d.SetId("projects/p/zones/us-central1-a/things/foo")
+	if d.Id() != "projects/p/zones/us-central1-a/things/foo" {
+		t.Errorf("expected the ID to be set, got %q", d.Id())
+	}
+}
+
+func TestFrameworkResourceData_timeout(t *testing.T) {
+	d := &frameworkResourceData{Timeouts: map[string]time.Duration{"create": time.Hour}}
+	if d.Timeout("create") != time.Hour {
+		t.Errorf("expected the configured create timeout, got %s", d.Timeout("create"))
+	}
+	if d.Timeout("delete") != 20*time.Minute {
+		t.Errorf("expected the default delete timeout, got %s", d.Timeout("delete"))
+	}
+}
+
+func TestFrameworkResourceData_getProviderMeta(t *testing.T) {
+	metaType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"module_name":  tftypes.String,
+		"team":         tftypes.String,
+		"cost_center":  tftypes.String,
+		"request_tags": tftypes.Map{ElementType: tftypes.String},
+	}}
+	d := &frameworkResourceData{
+		ProviderMeta: tftypes.NewValue(metaType, map[string]tftypes.Value{
+			"module_name": tftypes.NewValue(tftypes.String, nil),
+			"team":        tftypes.NewValue(tftypes.String, "payments"),
+			"cost_center": tftypes.NewValue(tftypes.String, nil),
+			"request_tags": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+				"env": tftypes.NewValue(tftypes.String, "prod"),
+			}),
+		}),
+	}
+
+	var m providerMeta
+	if err := d.GetProviderMeta(&m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.ModuleName != nil || m.CostCenter != nil {
+		t.Errorf("expected null attributes to be unset, got %v and %v", m.ModuleName, m.CostCenter)
+	}
+	if m.Team == nil || *m.Team != "payments" {
+		t.Errorf("expected team payments, got %v", m.Team)
+	}
+	if !reflect.DeepEqual(m.RequestTags, map[string]string{"env": "prod"}) {
+		t.Errorf("expected request tags env=prod, got %v", m.RequestTags)
+	}
+
+	if err := d.GetProviderMeta(m); err == nil {
+		t.Errorf("expected an error decoding into a non-pointer")
+	}
+}
@@ -53,5 +53,5 @@ func isSweepableTestResource(resourceName string) bool {
 			return true
 		}
 	}
-	return false
+	return isRegisteredTestResource(resourceName)
 }
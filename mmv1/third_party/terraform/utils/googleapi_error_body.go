@@ -0,0 +1,136 @@
+package google
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+// googleApiErrorBody is the error in the body of a googleapi.Error, which
+// Google APIs send as
+//
+//	{"error": {"code": 409, "message": "...", "status": "ABORTED", "errors": [...], "details": [...]}}
+//
+// Predicates should match on it rather than on the raw body, as the raw body
+// escapes characters such as quotes (') and its formatting varies.
+type googleApiErrorBody struct {
+	// Code is the HTTP status code of the response.
+	Code    int                    `json:"-"`
+	Message string                 `json:"message"`
+	Status  string                 `json:"status"`
+	Errors  []googleApiErrorItem   `json:"errors"`
+	Details []googleApiErrorDetail `json:"details"`
+
+	// parsed is whether the body was JSON. Bodies that weren't, such as HTML
+	// error pages from proxies, can only be matched as text.
+	parsed bool
+	raw    string
+}
+
+type googleApiErrorItem struct {
+	Reason  string `json:"reason"`
+	Domain  string `json:"domain"`
+	Message string `json:"message"`
+}
+
+// googleApiErrorDetail holds the fields of the details of the error the
+// provider uses: those of google.rpc.ErrorInfo, which newer APIs report
+// reasons in, and of google.rpc.RetryInfo.
+type googleApiErrorDetail struct {
+	Type   string `json:"@type"`
+	Reason string `json:"reason"`
+	Domain string `json:"domain"`
+	// Metadata of google.rpc.ErrorInfo, eg the "permission" denied.
+	Metadata map[string]string `json:"metadata"`
+	// RetryDelay is a JSON encoded google.protobuf.Duration, eg "1.5s".
+	RetryDelay string `json:"retryDelay"`
+}
+
+// parseGoogleApiErrorBody returns the error in the body of err, or of the
+// googleapi.Error it wraps. If the body isn't JSON, the error's message and
+// errors are used, and the body is only matched as text.
+func parseGoogleApiErrorBody(err error) (*googleApiErrorBody, bool) {
+	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
+	if !ok || gerr == nil {
+		return nil, false
+	}
+
+	var envelope struct {
+		Error *googleApiErrorBody `json:"error"`
+	}
+	if jerr := json.Unmarshal([]byte(gerr.Body), &envelope); jerr == nil && envelope.Error != nil {
+		body := envelope.Error
+		body.Code = gerr.Code
+		body.parsed = true
+		body.raw = gerr.Body
+		return body, true
+	}
+
+	body := &googleApiErrorBody{
+		Code:    gerr.Code,
+		Message: gerr.Message,
+		raw:     gerr.Body,
+	}
+	for _, e := range gerr.Errors {
+		body.Errors = append(body.Errors, googleApiErrorItem{Reason: e.Reason, Message: e.Message})
+	}
+	return body, true
+}
+
+// HasReason returns whether any of the errors or details of the body has one
+// of reasons. Bodies that couldn't be parsed match if they contain a reason.
+func (b *googleApiErrorBody) HasReason(reasons ...string) bool {
+	for _, reason := range reasons {
+		for _, e := range b.Errors {
+			if e.Reason == reason {
+				return true
+			}
+		}
+		for _, d := range b.Details {
+			if d.Reason == reason {
+				return true
+			}
+		}
+		if !b.parsed && strings.Contains(b.raw, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns whether the messages, status or reasons of the body
+// contain s. The raw body is matched too, so matches don't depend on the
+// body being parsed.
+func (b *googleApiErrorBody) Contains(s string) bool {
+	return strings.Contains(b.text(), s) || strings.Contains(b.raw, s)
+}
+
+// ContainsFold is like Contains, but ignores case.
+func (b *googleApiErrorBody) ContainsFold(s string) bool {
+	s = strings.ToLower(s)
+	return strings.Contains(strings.ToLower(b.text()), s) || strings.Contains(strings.ToLower(b.raw), s)
+}
+
+// FindStringSubmatch returns the submatches of re in the messages, status or
+// reasons of the body, or nil if it doesn't match.
+func (b *googleApiErrorBody) FindStringSubmatch(re *regexp.Regexp) []string {
+	if m := re.FindStringSubmatch(b.text()); m != nil {
+		return m
+	}
+	return re.FindStringSubmatch(b.raw)
+}
+
+// text returns the human readable parts of the body, one per line.
+func (b *googleApiErrorBody) text() string {
+	parts := []string{b.Message, b.Status}
+	for _, e := range b.Errors {
+		parts = append(parts, e.Reason, e.Message)
+	}
+	for _, d := range b.Details {
+		parts = append(parts, d.Reason)
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,111 @@
+package google
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+func TestParseGoogleApiErrorBody(t *testing.T) {
+	err := &googleapi.Error{
+		Code: 403,
+		Body: `{
+  "error": {
+    "code": 403,
+    "message": "Quota exceeded for quota metric 'Queries' and limit 'Queries per minute' of service 'compute.googleapis.com'.",
+    "status": "PERMISSION_DENIED",
+    "errors": [
+      {
+        "message": "Quota exceeded.",
+        "domain": "usageLimits",
+        "reason": "rateLimitExceeded"
+      }
+    ],
+    "details": [
+      {
+        "@type": "type.googleapis.com/google.rpc.ErrorInfo",
+        "reason": "RATE_LIMIT_EXCEEDED",
+        "domain": "googleapis.com"
+      }
+    ]
+  }
+}`,
+	}
+
+	body, ok := parseGoogleApiErrorBody(errwrap.Wrapf("Error creating instance: {{err}}", err))
+	if !ok {
+		t.Fatalf("expected the wrapped googleapi.Error to be parsed")
+	}
+	if body.Code != 403 || body.Status != "PERMISSION_DENIED" {
+		t.Errorf("expected code 403 and status PERMISSION_DENIED, got %d and %q", body.Code, body.Status)
+	}
+	if !body.HasReason("rateLimitExceeded") || !body.HasReason("RATE_LIMIT_EXCEEDED") {
+		t.Errorf("expected reasons of errors and details to match, got %+v", body)
+	}
+	if body.HasReason("quotaExceeded") {
+		t.Errorf("expected other reasons not to match")
+	}
+	if !body.Contains("quota metric 'Queries'") {
+		t.Errorf("expected escaped characters of the message to match, got %q", body.Message)
+	}
+	if !body.ContainsFold("QUOTA EXCEEDED") {
+		t.Errorf("expected case insensitive matches")
+	}
+}
+
+func TestParseGoogleApiErrorBody_fallback(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		reason   string
+		text     string
+		hasMatch bool
+	}{
+		"plain text body": {
+			err:      &googleapi.Error{Code: 409, Body: "Operation is already in progress"},
+			text:     "already in progress",
+			hasMatch: true,
+		},
+		"reason in plain text body": {
+			err:      &googleapi.Error{Code: 409, Body: "operationInProgress"},
+			reason:   "operationInProgress",
+			hasMatch: true,
+		},
+		"errors without body": {
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "The resource is not ready",
+				Errors:  []googleapi.ErrorItem{{Reason: "resourceNotReady"}},
+			},
+			reason:   "resourceNotReady",
+			text:     "is not ready",
+			hasMatch: true,
+		},
+		"reason only in a JSON message": {
+			err: &googleapi.Error{
+				Code: 400,
+				Body: `{"error": {"code": 400, "message": "operationInProgress is a reason, not this error's"}}`,
+			},
+			reason: "operationInProgress",
+		},
+	}
+
+	for tn, tc := range cases {
+		body, ok := parseGoogleApiErrorBody(tc.err)
+		if !ok {
+			t.Errorf("%s: expected a body", tn)
+			continue
+		}
+		if tc.reason != "" && body.HasReason(tc.reason) != tc.hasMatch {
+			t.Errorf("%s: expected HasReason(%q) to be %t", tn, tc.reason, tc.hasMatch)
+		}
+		if tc.text != "" && body.Contains(tc.text) != tc.hasMatch {
+			t.Errorf("%s: expected Contains(%q) to be %t", tn, tc.text, tc.hasMatch)
+		}
+	}
+
+	if _, ok := parseGoogleApiErrorBody(fmt.Errorf("connection reset")); ok {
+		t.Errorf("expected errors other than googleapi.Errors not to have a body")
+	}
+}
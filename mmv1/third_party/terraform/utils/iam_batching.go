@@ -13,14 +13,21 @@ const (
 	IamBatchingDisabled = false
 )
 
+// batchedIamPolicyModifier is a single modification in a batched IAM policy
+// change, along with the description of the request that added it.
+type batchedIamPolicyModifier struct {
+	desc   string
+	modify iamPolicyModifyFunc
+}
+
 func BatchRequestModifyIamPolicy(updater ResourceIamUpdater, modify iamPolicyModifyFunc, config *Config, reqDesc string) error {
 	batchKey := fmt.Sprintf(batchKeyTmplModifyIamPolicy, updater.GetMutexKey())
 
 	request := &BatchRequest{
 		ResourceName: updater.GetResourceId(),
-		Body:         []iamPolicyModifyFunc{modify},
+		Body:         []batchedIamPolicyModifier{{desc: reqDesc, modify: modify}},
 		CombineF:     combineBatchIamPolicyModifiers,
-		SendF:        sendBatchModifyIamPolicy(updater),
+		SendF:        sendBatchModifyIamPolicy(updater, config),
 		DebugId:      reqDesc,
 	}
 
@@ -29,32 +36,32 @@ func BatchRequestModifyIamPolicy(updater ResourceIamUpdater, modify iamPolicyMod
 }
 
 func combineBatchIamPolicyModifiers(currV interface{}, toAddV interface{}) (interface{}, error) {
-	currModifiers, ok := currV.([]iamPolicyModifyFunc)
+	currModifiers, ok := currV.([]batchedIamPolicyModifier)
 	if !ok {
-		return nil, fmt.Errorf("provider error in batch combiner: expected data to be type []iamPolicyModifyFunc, got %v with type %T", currV, currV)
+		return nil, fmt.Errorf("provider error in batch combiner: expected data to be type []batchedIamPolicyModifier, got %v with type %T", currV, currV)
 	}
 
-	newModifiers, ok := toAddV.([]iamPolicyModifyFunc)
+	newModifiers, ok := toAddV.([]batchedIamPolicyModifier)
 	if !ok {
-		return nil, fmt.Errorf("provider error in batch combiner: expected data to be type []iamPolicyModifyFunc, got %v with type %T", currV, currV)
+		return nil, fmt.Errorf("provider error in batch combiner: expected data to be type []batchedIamPolicyModifier, got %v with type %T", currV, currV)
 	}
 
 	return append(currModifiers, newModifiers...), nil
 }
 
-func sendBatchModifyIamPolicy(updater ResourceIamUpdater) BatcherSendFunc {
+func sendBatchModifyIamPolicy(updater ResourceIamUpdater, config *Config) BatcherSendFunc {
 	return func(resourceName string, body interface{}) (interface{}, error) {
-		modifiers, ok := body.([]iamPolicyModifyFunc)
+		modifiers, ok := body.([]batchedIamPolicyModifier)
 		if !ok {
-			return nil, fmt.Errorf("provider error: expected data to be type []iamPolicyModifyFunc, got %v with type %T", body, body)
+			return nil, fmt.Errorf("provider error: expected data to be type []batchedIamPolicyModifier, got %v with type %T", body, body)
 		}
 		return nil, iamPolicyReadModifyWrite(updater, func(policy *cloudresourcemanager.Policy) error {
-			for _, modifyF := range modifiers {
-				if err := modifyF(policy); err != nil {
-					return err
-				}
+			// Apply every modifier so all failures in the batch are reported.
+			errs := &MultiError{}
+			for _, m := range modifiers {
+				errs.Add(m.desc, m.modify(policy))
 			}
-			return nil
-		})
+			return errs.ErrorOrNil()
+		}, config)
 	}
 }
@@ -24,6 +24,10 @@ var IamBigqueryDatasetSchema = map[string]*schema.Schema{
 	},
 }
 
+// bigqueryDatasetIamSpecialMembers are the special groups of BigQuery datasets
+// that can be used as IAM members, see iamMemberToAccess.
+var bigqueryDatasetIamSpecialMembers = []string{"projectOwners", "projectReaders", "projectWriters"}
+
 var bigqueryAccessPrimitiveToRoleMap = map[string]string{
 	"OWNER":  "roles/bigquery.dataOwner",
 	"WRITER": "roles/bigquery.dataEditor",
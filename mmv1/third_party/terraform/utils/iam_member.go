@@ -0,0 +1,210 @@
+// Parsing and validation of the members of IAM bindings, such as
+// user:alice@example.com, serviceAccount:sa@project.iam.gserviceaccount.com,
+// domain:example.com, principal://iam.googleapis.com/... or allUsers. The API
+// returns the members of deleted principals prefixed with deleted: and
+// suffixed with ?uid={uid}, which are parsed but not accepted in
+// configurations.
+
+package google
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Kinds of IAM member types, deciding how their identifier is validated and
+// normalized.
+const (
+	iamMemberKindSpecial   = "special"
+	iamMemberKindEmail     = "email"
+	iamMemberKindDomain    = "domain"
+	iamMemberKindProject   = "project"
+	iamMemberKindPrincipal = "principal"
+)
+
+// iamMemberKinds maps the types of IAM members to their kind. Types are case
+// sensitive.
+var iamMemberKinds = map[string]string{
+	"allUsers":              iamMemberKindSpecial,
+	"allAuthenticatedUsers": iamMemberKindSpecial,
+	"user":                  iamMemberKindEmail,
+	"group":                 iamMemberKindEmail,
+	"serviceAccount":        iamMemberKindEmail,
+	"domain":                iamMemberKindDomain,
+	"projectOwner":          iamMemberKindProject,
+	"projectEditor":         iamMemberKindProject,
+	"projectViewer":         iamMemberKindProject,
+	"principal":             iamMemberKindPrincipal,
+	"principalSet":          iamMemberKindPrincipal,
+	"principalHierarchy":    iamMemberKindPrincipal,
+}
+
+var (
+	iamMemberEmailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	// Kubernetes service accounts of Workload Identity, eg
+	// serviceAccount:my-project.svc.id.goog[my-namespace/my-ksa]
+	iamMemberWorkloadIdentityRegex = regexp.MustCompile(`^[^\s\[\]]+\.svc\.id\.goog\[[^\s/\[\]]+/[^\s/\[\]]+\]$`)
+	iamMemberDomainRegex           = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+	iamMemberPrincipalRegex        = regexp.MustCompile(`^//([^/\s]+)/(\S+)$`)
+)
+
+// iamMember is a parsed IAM member.
+type iamMember struct {
+	// Type is the part of the member before the first colon, eg user, or the
+	// whole member for allUsers and allAuthenticatedUsers
+	Type string
+	// Id is the email, domain, project or principal identifier after it
+	Id string
+	// Deleted is set for deleted principals, and UID is their unique id
+	Deleted bool
+	UID     string
+}
+
+// parseIamMember parses and validates member, returning why it isn't a valid
+// IAM member otherwise.
+func parseIamMember(member string) (*iamMember, error) {
+	m := &iamMember{}
+	s := member
+	if strings.HasPrefix(s, "deleted:") {
+		m.Deleted = true
+		s = strings.TrimPrefix(s, "deleted:")
+		parts := strings.SplitN(s, "?uid=", 2)
+		if len(parts) == 2 {
+			s, m.UID = parts[0], parts[1]
+		}
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	m.Type = parts[0]
+	kind, ok := iamMemberKinds[m.Type]
+	if !ok {
+		if t, ok := iamMemberTypeFold(m.Type); ok {
+			return nil, fmt.Errorf("IAM member %q has the unknown type %q, types are case sensitive, did you mean %q?", member, m.Type, t)
+		}
+		if len(parts) == 1 {
+			return nil, fmt.Errorf("IAM member %q must be of the form {type}:{id}, eg user:alice@example.com, or be allUsers or allAuthenticatedUsers", member)
+		}
+		return nil, fmt.Errorf("IAM member %q has the unknown type %q, expected one of %s", member, m.Type, strings.Join(iamMemberTypes(), ", "))
+	}
+
+	if kind == iamMemberKindSpecial {
+		if len(parts) == 2 || m.Deleted {
+			return nil, fmt.Errorf("IAM member %q must be exactly %q", member, m.Type)
+		}
+		return m, nil
+	}
+	if len(parts) == 1 || parts[1] == "" {
+		return nil, fmt.Errorf("IAM member %q is missing the identifier after %q", member, m.Type+":")
+	}
+	m.Id = parts[1]
+	if m.Deleted && kind != iamMemberKindEmail {
+		return nil, fmt.Errorf("IAM member %q of type %q can't be deleted", member, m.Type)
+	}
+
+	switch kind {
+	case iamMemberKindEmail:
+		if !iamMemberEmailRegex.MatchString(m.Id) && !(m.Type == "serviceAccount" && iamMemberWorkloadIdentityRegex.MatchString(m.Id)) {
+			return nil, fmt.Errorf("IAM member %q must have an email address after %q, got %q", member, m.Type+":", m.Id)
+		}
+	case iamMemberKindDomain:
+		if !iamMemberDomainRegex.MatchString(m.Id) {
+			return nil, fmt.Errorf("IAM member %q must have a domain name after %q, got %q", member, m.Type+":", m.Id)
+		}
+	case iamMemberKindPrincipal:
+		if !iamMemberPrincipalRegex.MatchString(m.Id) {
+			return nil, fmt.Errorf("IAM member %q must have an identifier of the form //{host}/{path} after %q, got %q", member, m.Type+":", m.Id)
+		}
+	}
+	return m, nil
+}
+
+// iamMemberTypeFold returns the type of IAM members matching t case
+// insensitively.
+func iamMemberTypeFold(t string) (string, bool) {
+	for known := range iamMemberKinds {
+		if strings.EqualFold(known, t) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+func iamMemberTypes() []string {
+	types := make([]string, 0, len(iamMemberKinds))
+	for t := range iamMemberKinds {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (m *iamMember) String() string {
+	s := m.Type
+	if m.Id != "" {
+		s += ":" + m.Id
+	}
+	if m.Deleted {
+		s = "deleted:" + s
+		if m.UID != "" {
+			s += "?uid=" + m.UID
+		}
+	}
+	return s
+}
+
+// normalized returns m with the parts IAM treats case insensitively, emails,
+// domains and the host of principal identifiers, lowercased.
+func (m *iamMember) normalized() *iamMember {
+	n := *m
+	switch iamMemberKinds[m.Type] {
+	case iamMemberKindEmail, iamMemberKindDomain, iamMemberKindProject:
+		n.Id = strings.ToLower(m.Id)
+	case iamMemberKindPrincipal:
+		if parts := iamMemberPrincipalRegex.FindStringSubmatch(m.Id); parts != nil {
+			n.Id = "//" + strings.ToLower(parts[1]) + "/" + parts[2]
+		}
+	}
+	return &n
+}
+
+// validateIamMember validates IAM members in configurations, which can't be
+// deleted principals.
+func validateIamMember(v interface{}, k string) (ws []string, errors []error) {
+	return iamMemberValidator()(v, k)
+}
+
+// iamMemberValidator returns a SchemaValidateFunc validating IAM members like
+// validateIamMember, also allowing the members in specialMembers as they are,
+// for resources with members of their own like BigQuery datasets. Members
+// with a miscased type are only warned about, as IAM accepts some of them.
+func iamMemberValidator(specialMembers ...string) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+		value := v.(string)
+		for _, special := range specialMembers {
+			if value == special {
+				return
+			}
+		}
+
+		m, err := parseIamMember(value)
+		if err != nil {
+			parts := strings.SplitN(value, ":", 2)
+			if t, ok := iamMemberTypeFold(parts[0]); ok && len(parts) == 2 {
+				if _, fixedErr := parseIamMember(t + ":" + parts[1]); fixedErr == nil {
+					ws = append(ws, fmt.Sprintf("%q: IAM member %q has the type %q, types are case sensitive, use %q instead", k, value, parts[0], t))
+					return
+				}
+			}
+			errors = append(errors, fmt.Errorf("%q: %s", k, err))
+			return
+		}
+		if m.Deleted {
+			errors = append(errors, fmt.Errorf("%q: Terraform does not support IAM members for deleted principals, got %q", k, v))
+		}
+		return
+	}
+}
@@ -0,0 +1,137 @@
+package google
+
+import (
+	"testing"
+)
+
+func TestParseIamMember(t *testing.T) {
+	cases := map[string]struct {
+		member      string
+		expected    iamMember
+		normalized  string
+		expectError bool
+	}{
+		"user": {
+			member:     "user:Alice@Example.com",
+			expected:   iamMember{Type: "user", Id: "Alice@Example.com"},
+			normalized: "user:alice@example.com",
+		},
+		"service account": {
+			member:     "serviceAccount:sa@my-project.iam.gserviceaccount.com",
+			expected:   iamMember{Type: "serviceAccount", Id: "sa@my-project.iam.gserviceaccount.com"},
+			normalized: "serviceAccount:sa@my-project.iam.gserviceaccount.com",
+		},
+		"workload identity": {
+			member:     "serviceAccount:my-project.svc.id.goog[my-namespace/my-ksa]",
+			expected:   iamMember{Type: "serviceAccount", Id: "my-project.svc.id.goog[my-namespace/my-ksa]"},
+			normalized: "serviceAccount:my-project.svc.id.goog[my-namespace/my-ksa]",
+		},
+		"domain": {
+			member:     "domain:Example.COM",
+			expected:   iamMember{Type: "domain", Id: "Example.COM"},
+			normalized: "domain:example.com",
+		},
+		"all users": {
+			member:     "allUsers",
+			expected:   iamMember{Type: "allUsers"},
+			normalized: "allUsers",
+		},
+		"principal": {
+			member:     "principal://IAM.googleapis.com/projects/1/locations/global/workloadIdentityPools/Pool/subject/Alice",
+			expected:   iamMember{Type: "principal", Id: "//IAM.googleapis.com/projects/1/locations/global/workloadIdentityPools/Pool/subject/Alice"},
+			normalized: "principal://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/Pool/subject/Alice",
+		},
+		"principal set": {
+			member:     "principalSet://goog/public:all",
+			expected:   iamMember{Type: "principalSet", Id: "//goog/public:all"},
+			normalized: "principalSet://goog/public:all",
+		},
+		"deleted": {
+			member:     "deleted:user:Alice@example.com?uid=123456789012345678901",
+			expected:   iamMember{Type: "user", Id: "Alice@example.com", Deleted: true, UID: "123456789012345678901"},
+			normalized: "deleted:user:alice@example.com?uid=123456789012345678901",
+		},
+		"empty":                {member: "", expectError: true},
+		"no type":              {member: "alice@example.com", expectError: true},
+		"unknown type":         {member: "person:alice@example.com", expectError: true},
+		"type case":            {member: "User:alice@example.com", expectError: true},
+		"missing id":           {member: "user:", expectError: true},
+		"not an email":         {member: "user:alice", expectError: true},
+		"not a domain":         {member: "domain:example", expectError: true},
+		"principal without //": {member: "principal:subject/alice", expectError: true},
+		"all users with id":    {member: "allUsers:foo", expectError: true},
+		"deleted domain":       {member: "deleted:domain:example.com", expectError: true},
+	}
+	for tn, tc := range cases {
+		m, err := parseIamMember(tc.member)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error parsing %q, got %+v", tn, tc.member, m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if *m != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tn, tc.expected, *m)
+		}
+		if m.String() != tc.member {
+			t.Errorf("%s: expected %q to be formatted as it was parsed, got %q", tn, tc.member, m.String())
+		}
+		if got := m.normalized().String(); got != tc.normalized {
+			t.Errorf("%s: expected %q to be normalized to %q, got %q", tn, tc.member, tc.normalized, got)
+		}
+	}
+}
+
+func TestValidateIamMember(t *testing.T) {
+	x := []StringValidationTestCase{
+		// No errors
+		{TestName: "user", Value: "user:alice@example.com"},
+		{TestName: "group", Value: "group:admins@example.com"},
+		{TestName: "all authenticated users", Value: "allAuthenticatedUsers"},
+		{TestName: "project owner", Value: "projectOwner:my-project"},
+		{TestName: "principal set", Value: "principalSet://iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/pool/*"},
+
+		{TestName: "type case", Value: "serviceaccount:sa@example.com"},
+
+		// With errors
+		{TestName: "deleted", Value: "deleted:user:alice@example.com?uid=123", ExpectError: true},
+		{TestName: "missing type", Value: "alice@example.com", ExpectError: true},
+		{TestName: "type case with an invalid id", Value: "User:alice", ExpectError: true},
+		{TestName: "BigQuery special group", Value: "projectOwners", ExpectError: true},
+	}
+
+	es := testStringValidationCases(x, validateIamMember)
+	if len(es) > 0 {
+		t.Errorf("Failed to validate IAM members: %v", es)
+	}
+
+	ws, _ := validateIamMember("serviceaccount:sa@example.com", "member")
+	if len(ws) != 1 {
+		t.Errorf("Expected a warning for a miscased member type, got %v", ws)
+	}
+}
+
+func TestIamMemberValidator_specialMembers(t *testing.T) {
+	x := []StringValidationTestCase{
+		// No errors
+		{TestName: "project owners", Value: "projectOwners"},
+		{TestName: "project readers", Value: "projectReaders"},
+		{TestName: "project writers", Value: "projectWriters"},
+		{TestName: "all authenticated users", Value: "allAuthenticatedUsers"},
+		{TestName: "user", Value: "user:alice@example.com"},
+
+		// With errors
+		{TestName: "special group case", Value: "projectowners", ExpectError: true},
+		{TestName: "special group with id", Value: "projectOwners:my-project", ExpectError: true},
+		{TestName: "missing type", Value: "alice@example.com", ExpectError: true},
+	}
+
+	es := testStringValidationCases(x, iamMemberValidator(bigqueryDatasetIamSpecialMembers...))
+	if len(es) > 0 {
+		t.Errorf("Failed to validate BigQuery dataset IAM members: %v", es)
+	}
+}
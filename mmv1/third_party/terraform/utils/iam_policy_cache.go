@@ -0,0 +1,99 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+)
+
+// How long IAM policies are remembered. Every member, binding and audit
+// config of a project reads the project's policy when it's refreshed, so it
+// only needs to cover the refreshes of one apply.
+const iamPolicyCacheTTL = time.Minute
+
+// iamPolicyCache remembers the IAM policies read through
+// iamPolicyReadWithRetry and iamPolicyReadModifyWrite, keyed by
+// iamPolicyCacheKey, so the resources sharing a policy read it once.
+//
+// Policies are stored serialized and every get returns a new copy, as
+// modifiers change the policies they're given. Entries are replaced by the
+// policy verified after each write of the provider, and dropped when a write
+// fails. A policy changed out of band while cached is caught by its etag: the
+// write based on it fails with a conflict, and is retried with a policy read
+// from the API.
+type iamPolicyCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]iamPolicyEntry
+}
+
+type iamPolicyEntry struct {
+	policy  []byte
+	expires time.Time
+}
+
+func newIamPolicyCache(ttl time.Duration, clock Clock) *iamPolicyCache {
+	return &iamPolicyCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]iamPolicyEntry),
+	}
+}
+
+// iamPolicyCacheKey returns the key of the policy of updater's resource. The
+// type of updater is part of it, as the IDs of different resource types may
+// collide.
+func iamPolicyCacheKey(updater ResourceIamUpdater) string {
+	return fmt.Sprintf("%T %s", updater, updater.GetResourceId())
+}
+
+func (c *iamPolicyCache) get(key string) (*cloudresourcemanager.Policy, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock.Now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	policy := &cloudresourcemanager.Policy{}
+	if err := json.Unmarshal(e.policy, policy); err != nil {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return policy, true
+}
+
+func (c *iamPolicyCache) set(key string, policy *cloudresourcemanager.Policy) {
+	if c == nil || policy == nil {
+		return
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		log.Printf("[WARN] Not caching the IAM policy of %s: %s", key, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = iamPolicyEntry{policy: b, expires: c.clock.Now().Add(c.ttl)}
+}
+
+func (c *iamPolicyCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,130 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// fakeIamUpdater keeps an IAM policy in memory, checking the etags of the
+// policies it's given like the API does.
+type fakeIamUpdater struct {
+	policy     *cloudresourcemanager.Policy
+	version    int
+	gets, sets int
+}
+
+func (u *fakeIamUpdater) copyPolicy() *cloudresourcemanager.Policy {
+	b, _ := json.Marshal(u.policy)
+	p := &cloudresourcemanager.Policy{}
+	json.Unmarshal(b, p)
+	p.Etag = fmt.Sprintf("etag-%d", u.version)
+	return p
+}
+
+func (u *fakeIamUpdater) GetResourceIamPolicy() (*cloudresourcemanager.Policy, error) {
+	u.gets++
+	return u.copyPolicy(), nil
+}
+
+func (u *fakeIamUpdater) SetResourceIamPolicy(policy *cloudresourcemanager.Policy) error {
+	u.sets++
+	if policy.Etag != fmt.Sprintf("etag-%d", u.version) {
+		return &googleapi.Error{Code: 409, Message: "There were concurrent policy changes."}
+	}
+	u.policy = &cloudresourcemanager.Policy{Bindings: policy.Bindings}
+	u.version++
+	return nil
+}
+
+// setOutOfBand changes the policy as if it was changed outside of the
+// provider.
+func (u *fakeIamUpdater) setOutOfBand(bindings ...*cloudresourcemanager.Binding) {
+	u.policy = &cloudresourcemanager.Policy{Bindings: bindings}
+	u.version++
+}
+
+func (u *fakeIamUpdater) GetMutexKey() string      { return "iam-fake" }
+func (u *fakeIamUpdater) GetResourceId() string    { return "projects/p" }
+func (u *fakeIamUpdater) DescribeResource() string { return "fake project p" }
+
+func TestIamPolicyCache(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	c := newIamPolicyCache(time.Minute, clock)
+
+	c.set("key", &cloudresourcemanager.Policy{
+		Bindings: []*cloudresourcemanager.Binding{{Role: "roles/viewer", Members: []string{"user:a@example.com"}}},
+		Etag:     "etag-1",
+	})
+	p, ok := c.get("key")
+	if !ok || p.Etag != "etag-1" || len(p.Bindings) != 1 {
+		t.Fatalf("expected the policy to be cached, got %v", p)
+	}
+	p.Bindings[0].Members = append(p.Bindings[0].Members, "user:b@example.com")
+	if p, _ := c.get("key"); len(p.Bindings[0].Members) != 1 {
+		t.Errorf("expected the cached policy not to change with the copies it returned, got %v", p.Bindings[0].Members)
+	}
+
+	c.invalidate("key")
+	if _, ok := c.get("key"); ok {
+		t.Errorf("expected the policy to be invalidated")
+	}
+
+	c.set("key", &cloudresourcemanager.Policy{Etag: "etag-2"})
+	clock.Sleep(time.Minute)
+	if _, ok := c.get("key"); ok {
+		t.Errorf("expected the entry to expire")
+	}
+
+	var nilCache *iamPolicyCache
+	nilCache.set("key", &cloudresourcemanager.Policy{})
+	nilCache.invalidate("key")
+	if _, ok := nilCache.get("key"); ok {
+		t.Errorf("expected a nil cache never to hit")
+	}
+}
+
+func TestIamPolicyReadModifyWrite_cache(t *testing.T) {
+	updater := &fakeIamUpdater{policy: &cloudresourcemanager.Policy{}}
+	config := &Config{iamPolicies: newIamPolicyCache(time.Minute, &fakeClock{now: time.Now()})}
+
+	for i := 0; i < 3; i++ {
+		if _, err := iamPolicyReadWithRetry(updater, config); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if updater.gets != 1 {
+		t.Errorf("expected the policy to be read once, got %d reads", updater.gets)
+	}
+
+	// The cached policy is now stale, so writing it fails and it's read again
+	updater.setOutOfBand(&cloudresourcemanager.Binding{Role: "roles/owner", Members: []string{"user:owner@example.com"}})
+	updater.gets = 0
+	err := iamPolicyReadModifyWrite(updater, func(p *cloudresourcemanager.Policy) error {
+		p.Bindings = mergeBindings(append(p.Bindings, &cloudresourcemanager.Binding{Role: "roles/viewer", Members: []string{"user:a@example.com"}}))
+		return nil
+	}, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if updater.sets != 2 {
+		t.Errorf("expected the stale policy to be written, then the one read again, got %d writes", updater.sets)
+	}
+	if len(updater.policy.Bindings) != 2 {
+		t.Errorf("expected the out of band binding to be kept, got %v", updater.policy.Bindings)
+	}
+
+	// The policy verified after the write is read by the resources sharing it
+	reads := updater.gets
+	p, err := iamPolicyReadWithRetry(updater, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if updater.gets != reads || p.Etag != fmt.Sprintf("etag-%d", updater.version) {
+		t.Errorf("expected the written policy to be cached, got etag %s after %d more reads", p.Etag, updater.gets-reads)
+	}
+}
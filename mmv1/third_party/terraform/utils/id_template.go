@@ -0,0 +1,354 @@
+package google
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IdTemplate is a parsed resource id or URL template, such as
+// "projects/{{project}}/locations/{{location}}/keyRings/{{name}}". Templates
+// support:
+//
+//   - {{var}}, replaced with the value of the field var, or of the Config
+//     field var (eg {{ComputeBasePath}}). {{project}}, {{region}} and {{zone}}
+//     fall back to the provider's values, and {{project_id_or_project}} uses
+//     the project_id field if set and the project otherwise.
+//   - {{%var}}, which URL path escapes the value.
+//   - [optional segments], which are omitted if any of the variables in them
+//     has no value, eg "instances/{{name}}[/snapshots/{{snapshot}}]".
+//
+// Templates are parsed once and can be used both to build ids with Render and
+// to parse them back into field values with parseImportIdTemplates.
+type IdTemplate struct {
+	template string
+	segments []idTemplateSegment
+}
+
+// idTemplateSegment is a literal, a variable, or an optional group of
+// segments.
+type idTemplateSegment struct {
+	literal  string
+	variable string
+	escape   bool
+	optional []idTemplateSegment
+}
+
+var idTemplateVariableRegex = regexp.MustCompile(`^%?[[:word:]]+$`)
+
+// parseIdTemplate parses tmpl, returning an error describing the position of
+// the problem if it isn't a valid template.
+func parseIdTemplate(tmpl string) (*IdTemplate, error) {
+	return parseTemplate(tmpl, false)
+}
+
+// parseLegacyIdTemplate parses tmpl the way replaceVars always has: brackets
+// are literals rather than optional segments, and anything that isn't a valid
+// variable is left as is. replaceVars is given URLs containing arbitrary ids,
+// so it can't assume they're valid templates.
+func parseLegacyIdTemplate(tmpl string) *IdTemplate {
+	t, _ := parseTemplate(tmpl, true)
+	return t
+}
+
+func parseTemplate(tmpl string, legacy bool) (*IdTemplate, error) {
+	t := &IdTemplate{template: tmpl}
+
+	var literal strings.Builder
+	var group []idTemplateSegment
+	inGroup := false
+	groupStart := 0
+	emit := func(s idTemplateSegment) {
+		if inGroup {
+			group = append(group, s)
+		} else {
+			t.segments = append(t.segments, s)
+		}
+	}
+	flush := func() {
+		if literal.Len() > 0 {
+			emit(idTemplateSegment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(tmpl); {
+		switch {
+		case strings.HasPrefix(tmpl[i:], "{{"):
+			end := strings.Index(tmpl[i+2:], "}}")
+			if end < 0 {
+				if legacy {
+					literal.WriteString("{{")
+					i += 2
+					continue
+				}
+				return nil, fmt.Errorf("invalid template %q: unterminated variable at offset %d", tmpl, i)
+			}
+			name := tmpl[i+2 : i+2+end]
+			if !idTemplateVariableRegex.MatchString(name) {
+				if legacy {
+					literal.WriteString("{{")
+					i += 2
+					continue
+				}
+				return nil, fmt.Errorf("invalid template %q: invalid variable {{%s}} at offset %d", tmpl, name, i)
+			}
+			flush()
+			emit(idTemplateSegment{
+				variable: strings.TrimPrefix(name, "%"),
+				escape:   strings.HasPrefix(name, "%"),
+			})
+			i += end + 4
+		case legacy && (tmpl[i] == '[' || tmpl[i] == ']'):
+			literal.WriteByte(tmpl[i])
+			i++
+		case tmpl[i] == '[':
+			if inGroup {
+				return nil, fmt.Errorf("invalid template %q: optional segment at offset %d is nested in the one at offset %d", tmpl, i, groupStart)
+			}
+			flush()
+			inGroup, groupStart, group = true, i, nil
+			i++
+		case tmpl[i] == ']':
+			if !inGroup {
+				return nil, fmt.Errorf("invalid template %q: unexpected ] at offset %d", tmpl, i)
+			}
+			flush()
+			t.segments = append(t.segments, idTemplateSegment{optional: group})
+			inGroup = false
+			i++
+		default:
+			literal.WriteByte(tmpl[i])
+			i++
+		}
+	}
+	if inGroup {
+		return nil, fmt.Errorf("invalid template %q: optional segment at offset %d is not closed", tmpl, groupStart)
+	}
+	flush()
+
+	return t, nil
+}
+
+// The legacy template cache is cleared once it holds this many templates.
+// Values containing templates are rendered as templates too, so not every
+// template comes from the provider and the cache would otherwise grow with
+// the configuration.
+const legacyIdTemplateCacheSize = 1000
+
+var (
+	legacyIdTemplateCacheMu sync.Mutex
+	legacyIdTemplateCache   = make(map[string]*IdTemplate)
+)
+
+// cachedLegacyIdTemplate returns the legacy template for tmpl, parsing it the
+// first time it's used. replaceVars is called with the same few templates for
+// every resource, so they're only parsed once.
+func cachedLegacyIdTemplate(tmpl string) *IdTemplate {
+	legacyIdTemplateCacheMu.Lock()
+	defer legacyIdTemplateCacheMu.Unlock()
+	if t, ok := legacyIdTemplateCache[tmpl]; ok {
+		return t
+	}
+	t := parseLegacyIdTemplate(tmpl)
+	if len(legacyIdTemplateCache) >= legacyIdTemplateCacheSize {
+		legacyIdTemplateCache = make(map[string]*IdTemplate)
+	}
+	legacyIdTemplateCache[tmpl] = t
+	return t
+}
+
+func (t *IdTemplate) String() string {
+	return t.template
+}
+
+// Variables returns the names of the variables in the template, in order.
+// Variables in optional segments are included.
+func (t *IdTemplate) Variables() []string {
+	var vars []string
+	var walk func([]idTemplateSegment)
+	walk = func(segments []idTemplateSegment) {
+		for _, s := range segments {
+			if s.variable != "" {
+				vars = append(vars, s.variable)
+			}
+			walk(s.optional)
+		}
+	}
+	walk(t.segments)
+	return vars
+}
+
+// idTemplateRenderOptions changes how a template is rendered.
+type idTemplateRenderOptions struct {
+	// Shorten values with GetResourceNameFromSelfLink, see replaceVarsForId
+	Shorten bool
+	// AllowMissing renders variables without a value as empty strings rather
+	// than returning an error, as replaceVars always has
+	AllowMissing bool
+}
+
+// Render returns the template with its variables replaced by their values. It
+// returns an error naming the first variable outside an optional segment that
+// has no value.
+func (t *IdTemplate) Render(d TerraformResourceData, config *Config) (string, error) {
+	return t.render(d, config, idTemplateRenderOptions{}, 0)
+}
+
+// Values that contain variables themselves, eg base paths with a {{region}},
+// are rendered recursively. There are no known cases of more than one level
+// of recursion, so a depth of 10 means a value refers to itself.
+const idTemplateMaxDepth = 10
+
+var idTemplateHasVariableRegex = regexp.MustCompile("{{([%[:word:]]+)}}")
+
+func (t *IdTemplate) render(d TerraformResourceData, config *Config, opts idTemplateRenderOptions, depth int) (string, error) {
+	if depth > idTemplateMaxDepth {
+		return "", errors.New("Recursive substitution detcted")
+	}
+
+	r := &idTemplateRenderer{template: t.template, d: d, config: config, opts: opts, depth: depth, values: make(map[string]string)}
+	var b strings.Builder
+	for _, s := range t.segments {
+		if s.optional == nil {
+			v, err := r.segment(s)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			continue
+		}
+
+		// Optional segments are rendered whole or not at all
+		var group strings.Builder
+		complete := true
+		for _, gs := range s.optional {
+			v, err := r.segment(gs)
+			if err != nil || (gs.variable != "" && v == "") {
+				complete = false
+				break
+			}
+			group.WriteString(v)
+		}
+		if complete {
+			b.WriteString(group.String())
+		}
+	}
+	return b.String(), nil
+}
+
+// idTemplateRenderer resolves the variables of a single render.
+type idTemplateRenderer struct {
+	template string
+	d        TerraformResourceData
+	config   *Config
+	opts     idTemplateRenderOptions
+	depth    int
+	values   map[string]string
+}
+
+func (r *idTemplateRenderer) segment(s idTemplateSegment) (string, error) {
+	if s.variable == "" {
+		return s.literal, nil
+	}
+
+	v, ok, err := r.value(s.variable)
+	if err != nil {
+		return "", err
+	}
+	if !ok && !r.opts.AllowMissing {
+		return "", fmt.Errorf("Error building %q: {{%s}} has no value", r.template, s.variable)
+	}
+
+	if idTemplateHasVariableRegex.MatchString(v) {
+		// Values are never expected to contain optional segments, so they're
+		// parsed leniently whatever the template
+		var err error
+		if v, err = cachedLegacyIdTemplate(v).render(r.d, r.config, r.opts, r.depth+1); err != nil {
+			return "", err
+		}
+	}
+
+	if s.escape {
+		return url.PathEscape(v), nil
+	}
+	return v, nil
+}
+
+// value returns the value of a variable, and whether it has one.
+func (r *idTemplateRenderer) value(name string) (string, bool, error) {
+	if v, ok := r.values[name]; ok {
+		return v, true, nil
+	}
+
+	var v string
+	var err error
+	switch name {
+	case "project":
+		v, err = getProject(r.d, r.config)
+	case "project_id_or_project":
+		if raw, ok := r.d.GetOkExists("project_id"); ok {
+			v, _ = raw.(string)
+		}
+		if v == "" {
+			v, err = getProject(r.d, r.config)
+		}
+	case "region":
+		v, err = getRegion(r.d, r.config)
+	case "zone":
+		v, err = getZone(r.d, r.config)
+	default:
+		if raw, ok := r.d.GetOkExists(name); ok {
+			v = fmt.Sprintf("%v", raw)
+			if r.opts.Shorten {
+				v = GetResourceNameFromSelfLink(v)
+			}
+		} else if r.config != nil {
+			// terraform-google-conversion doesn't provide a provider config in
+			// tests. Attempt to draw values from the provider config if it's
+			// present.
+			if f := reflect.Indirect(reflect.ValueOf(r.config)).FieldByName(name); f.IsValid() {
+				v = f.String()
+			}
+		}
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if v == "" {
+		return "", false, nil
+	}
+	r.values[name] = v
+	return v, true, nil
+}
+
+// Regex returns a regular expression matching ids built from the template,
+// with a named group for each variable, as used by parseImportId. Like the
+// import regexes the provider has always generated, it isn't anchored so that
+// full self links match, and escaped variables match any number of /'s.
+func (t *IdTemplate) Regex() string {
+	var b strings.Builder
+	var write func([]idTemplateSegment)
+	write = func(segments []idTemplateSegment) {
+		for _, s := range segments {
+			switch {
+			case s.optional != nil:
+				b.WriteString("(?:")
+				write(s.optional)
+				b.WriteString(")?")
+			case s.escape:
+				fmt.Fprintf(&b, "(?P<%s>.+)", s.variable)
+			case s.variable != "":
+				fmt.Fprintf(&b, "(?P<%s>[^/]+)", s.variable)
+			default:
+				b.WriteString(regexp.QuoteMeta(s.literal))
+			}
+		}
+	}
+	write(t.segments)
+	return b.String()
+}
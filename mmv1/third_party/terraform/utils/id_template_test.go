@@ -0,0 +1,152 @@
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIdTemplate_errors(t *testing.T) {
+	cases := map[string]struct {
+		Template      string
+		ExpectedError string
+	}{
+		"unterminated variable": {
+			Template:      "projects/{{project}}/instances/{{name",
+			ExpectedError: "unterminated variable at offset 31",
+		},
+		"invalid variable": {
+			Template:      "projects/{{project id}}",
+			ExpectedError: "invalid variable {{project id}} at offset 9",
+		},
+		"nested optional segments": {
+			Template:      "instances/{{name}}[/a/{{a}}[/b/{{b}}]]",
+			ExpectedError: "optional segment at offset 27 is nested in the one at offset 18",
+		},
+		"unclosed optional segment": {
+			Template:      "instances/{{name}}[/a/{{a}}",
+			ExpectedError: "optional segment at offset 18 is not closed",
+		},
+		"unexpected close": {
+			Template:      "instances/{{name}}]",
+			ExpectedError: "unexpected ] at offset 18",
+		},
+	}
+
+	for tn, tc := range cases {
+		_, err := parseIdTemplate(tc.Template)
+		if err == nil {
+			t.Errorf("%s: expected error", tn)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.ExpectedError) {
+			t.Errorf("%s: expected error to contain %q, got %q", tn, tc.ExpectedError, err)
+		}
+	}
+}
+
+func TestIdTemplateRender(t *testing.T) {
+	cases := map[string]struct {
+		Template      string
+		SchemaValues  map[string]interface{}
+		Expected      string
+		ExpectedError string
+	}{
+		"all values": {
+			Template: "projects/{{project}}/locations/{{location}}/keyRings/{{name}}",
+			SchemaValues: map[string]interface{}{
+				"location": "us-central1",
+				"name":     "ring",
+			},
+			Expected: "projects/default-project/locations/us-central1/keyRings/ring",
+		},
+		"missing value": {
+			Template: "projects/{{project}}/locations/{{location}}/keyRings/{{name}}",
+			SchemaValues: map[string]interface{}{
+				"name": "ring",
+			},
+			ExpectedError: "{{location}} has no value",
+		},
+		"escaped value": {
+			Template: "projects/{{project}}/secrets/{{%name}}",
+			SchemaValues: map[string]interface{}{
+				"name": "a/b",
+			},
+			Expected: "projects/default-project/secrets/a%2Fb",
+		},
+		"optional segment with values": {
+			Template: "instances/{{name}}[/snapshots/{{snapshot}}]",
+			SchemaValues: map[string]interface{}{
+				"name":     "instance",
+				"snapshot": "snap",
+			},
+			Expected: "instances/instance/snapshots/snap",
+		},
+		"optional segment without values": {
+			Template: "instances/{{name}}[/snapshots/{{snapshot}}]",
+			SchemaValues: map[string]interface{}{
+				"name": "instance",
+			},
+			Expected: "instances/instance",
+		},
+	}
+
+	for tn, tc := range cases {
+		tmpl, err := parseIdTemplate(tc.Template)
+		if err != nil {
+			t.Errorf("%s: unexpected error parsing template: %s", tn, err)
+			continue
+		}
+
+		d := &ResourceDataMock{
+			FieldsInSchema: tc.SchemaValues,
+		}
+		v, err := tmpl.Render(d, &Config{Project: "default-project"})
+		if tc.ExpectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+				t.Errorf("%s: expected error containing %q, got %v", tn, tc.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tn, err)
+			continue
+		}
+		if v != tc.Expected {
+			t.Errorf("%s: expected %q, got %q", tn, tc.Expected, v)
+		}
+	}
+}
+
+func TestIdTemplateRegex(t *testing.T) {
+	tmpl, err := parseIdTemplate("projects/{{project}}/secrets/{{%name}}[/versions/{{version}}]")
+	if err != nil {
+		t.Fatalf("unexpected error parsing template: %s", err)
+	}
+
+	if vars, expected := tmpl.Variables(), []string{"project", "name", "version"}; !reflect.DeepEqual(vars, expected) {
+		t.Errorf("expected variables %v, got %v", expected, vars)
+	}
+
+	expected := "projects/(?P<project>[^/]+)/secrets/(?P<name>.+)(?:/versions/(?P<version>[^/]+))?"
+	if re := tmpl.Regex(); re != expected {
+		t.Errorf("expected regex %q, got %q", expected, re)
+	}
+}
+
+func TestCachedLegacyIdTemplate(t *testing.T) {
+	tmpl := "projects/{{project}}/cachedtest/{{name}}"
+	if cachedLegacyIdTemplate(tmpl) != cachedLegacyIdTemplate(tmpl) {
+		t.Errorf("expected the template to be parsed once")
+	}
+
+	for i := 0; i < 2*legacyIdTemplateCacheSize; i++ {
+		cachedLegacyIdTemplate(fmt.Sprintf("projects/{{project}}/cachedtest/%d", i))
+	}
+	legacyIdTemplateCacheMu.Lock()
+	defer legacyIdTemplateCacheMu.Unlock()
+	if n := len(legacyIdTemplateCache); n > legacyIdTemplateCacheSize {
+		t.Errorf("expected the cache to hold at most %d templates, got %d", legacyIdTemplateCacheSize, n)
+	}
+}
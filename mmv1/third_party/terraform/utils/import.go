@@ -73,6 +73,29 @@ func parseImportId(idRegexes []string, d TerraformResourceData, config *Config)
 	return fmt.Errorf("Import id %q doesn't match any of the accepted formats: %v", d.Id(), idRegexes)
 }
 
+// parseImportIdTemplates is parseImportId for id templates rather than
+// regexes, eg:
+// - projects/{{project}}/regions/{{region}}/subnetworks/{{name}} (applied first)
+// - {{project}}/{{region}}/{{name}}
+// - {{name}} (applied last)
+func parseImportIdTemplates(idFormats []string, d TerraformResourceData, config *Config) error {
+	idRegexes := make([]string, 0, len(idFormats))
+	matches := false
+	for _, idFormat := range idFormats {
+		t, err := parseIdTemplate(idFormat)
+		if err != nil {
+			return fmt.Errorf("Import is not supported. %s", err)
+		}
+		idRegexes = append(idRegexes, t.Regex())
+		matches = matches || regexp.MustCompile(t.Regex()).MatchString(d.Id())
+	}
+	if !matches {
+		// Name the templates rather than the regexes built from them
+		return fmt.Errorf("Import id %q doesn't match any of the accepted formats: %v", d.Id(), idFormats)
+	}
+	return parseImportId(idRegexes, d, config)
+}
+
 func setDefaultValues(idRegex string, d TerraformResourceData, config *Config) error {
 	if _, ok := d.GetOk("project"); !ok && strings.Contains(idRegex, "?P<project>") {
 		project, err := getProject(d, config)
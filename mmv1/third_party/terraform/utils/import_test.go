@@ -1,6 +1,7 @@
 package google
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -145,3 +146,62 @@ func TestParseImportId(t *testing.T) {
 		}
 	}
 }
+
+func TestParseImportIdTemplates(t *testing.T) {
+	idFormats := []string{
+		"projects/{{project}}/regions/{{region}}/subnetworks/{{name}}",
+		"{{project}}/{{region}}/{{name}}",
+		"{{name}}",
+	}
+
+	cases := map[string]struct {
+		ImportId             string
+		ExpectedSchemaValues map[string]interface{}
+	}{
+		"full self_link": {
+			ImportId: "https://www.googleapis.com/compute/v1/projects/my-project/regions/my-region/subnetworks/my-subnetwork",
+			ExpectedSchemaValues: map[string]interface{}{
+				"project": "my-project",
+				"region":  "my-region",
+				"name":    "my-subnetwork",
+			},
+		},
+		"short id with default project and region": {
+			ImportId: "my-subnetwork",
+			ExpectedSchemaValues: map[string]interface{}{
+				"project": "default-project",
+				"region":  "default-region",
+				"name":    "my-subnetwork",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		d := &ResourceDataMock{
+			FieldsInSchema: make(map[string]interface{}),
+			id:             tc.ImportId,
+		}
+		config := &Config{
+			Project: "default-project",
+			Region:  "default-region",
+		}
+
+		if err := parseImportIdTemplates(idFormats, d, config); err != nil {
+			t.Errorf("%s failed; unexpected error: %s", tn, err)
+			continue
+		}
+		for k, expectedValue := range tc.ExpectedSchemaValues {
+			if v, ok := d.GetOk(k); !ok || v != expectedValue {
+				t.Errorf("%s failed; Expected value %q for field %q, got %q", tn, expectedValue, k, v)
+			}
+		}
+	}
+
+	d := &ResourceDataMock{
+		FieldsInSchema: make(map[string]interface{}),
+		id:             "projects/my-project/zones/my-zone/instances/my-instance",
+	}
+	if err := parseImportIdTemplates(idFormats[:1], d, &Config{}); err == nil || !strings.Contains(err.Error(), idFormats[0]) {
+		t.Errorf("expected an error naming %q, got %v", idFormats[0], err)
+	}
+}
@@ -0,0 +1,98 @@
+// Helpers for DNS names used by compute resources: Compute Engine internal DNS
+// names of instances and internal load balancers, reverse lookup (PTR) names
+// and fully qualified domain names, which APIs return with a trailing dot
+// whether or not they were configured with one.
+
+package google
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const dnsNameMaxLength = 253
+
+var dnsLabelRegex = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// internalDnsProject returns the form of project used in internal DNS names.
+// Domain scoped projects (example.com:my-project) are written my-project.example.com.
+func internalDnsProject(project string) string {
+	if parts := strings.SplitN(project, ":", 2); len(parts) == 2 {
+		return fmt.Sprintf("%s.%s", parts[1], parts[0])
+	}
+	return project
+}
+
+// instanceInternalDnsName returns the internal DNS name of an instance. Zonal
+// names are returned if zone is set, global names otherwise.
+func instanceInternalDnsName(instance, zone, project string) string {
+	if zone == "" {
+		return fmt.Sprintf("%s.c.%s.internal", instance, internalDnsProject(project))
+	}
+	return fmt.Sprintf("%s.%s.c.%s.internal", instance, zone, internalDnsProject(project))
+}
+
+// forwardingRuleInternalDnsName returns the service name of an internal
+// forwarding rule with a service label.
+func forwardingRuleInternalDnsName(serviceLabel, forwardingRule, region, project string) string {
+	return fmt.Sprintf("%s.%s.il4.%s.lb.%s.internal", serviceLabel, forwardingRule, region, internalDnsProject(project))
+}
+
+// isValidDnsName returns whether name is a RFC-1035 valid DNS name, with or
+// without a trailing dot.
+func isValidDnsName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > dnsNameMaxLength {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !dnsLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateDnsName checks that values are RFC-1035 valid fully qualified DNS
+// names of at least two labels.
+func validateDnsName(v interface{}, k string) (ws []string, errs []error) {
+	value := v.(string)
+	if !isValidDnsName(value) || !strings.Contains(strings.TrimSuffix(value, "."), ".") {
+		errs = append(errs, fmt.Errorf("%q (%q) must be a fully qualified DNS name made of labels 1-63 characters long matching [a-z]([-a-z0-9]*[a-z0-9]), concatenated with periods, and at most %d characters long", k, value, dnsNameMaxLength))
+	}
+	return
+}
+
+// ptrRecordName returns the reverse lookup name of an IPv4 or IPv6 address,
+// eg 4.3.2.1.in-addr.arpa. for 1.2.3.4.
+func ptrRecordName(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("%q is not a valid IP address", ip)
+	}
+
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", v4[3], v4[2], v4[1], v4[0]), nil
+	}
+
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	for i := len(addr) - 1; i >= 0; i-- {
+		b.WriteByte(hex[addr[i]&0xf])
+		b.WriteByte('.')
+		b.WriteByte(hex[addr[i]>>4])
+		b.WriteByte('.')
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
+
+// fqdnDiffSuppress suppresses diffs between DNS names that only differ by a
+// trailing dot or by case.
+func fqdnDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	return strings.EqualFold(strings.TrimSuffix(old, "."), strings.TrimSuffix(new, "."))
+}
@@ -0,0 +1,127 @@
+package google
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInternalDnsNames(t *testing.T) {
+	cases := map[string]struct {
+		got, expected string
+	}{
+		"zonal": {
+			got:      instanceInternalDnsName("vm", "us-central1-a", "my-project"),
+			expected: "vm.us-central1-a.c.my-project.internal",
+		},
+		"global": {
+			got:      instanceInternalDnsName("vm", "", "my-project"),
+			expected: "vm.c.my-project.internal",
+		},
+		"domain scoped project": {
+			got:      instanceInternalDnsName("vm", "us-central1-a", "example.com:my-project"),
+			expected: "vm.us-central1-a.c.my-project.example.com.internal",
+		},
+		"forwarding rule": {
+			got:      forwardingRuleInternalDnsName("web", "ilb", "us-central1", "my-project"),
+			expected: "web.ilb.il4.us-central1.lb.my-project.internal",
+		},
+	}
+
+	for tn, tc := range cases {
+		if tc.got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tn, tc.expected, tc.got)
+		}
+	}
+}
+
+func TestValidateDnsName(t *testing.T) {
+	cases := map[string]bool{
+		"vm.example.com":                         true,
+		"vm.example.com.":                        true,
+		"a-1.b2.example":                         true,
+		"vm":                                     false,
+		"VM.example.com":                         false,
+		"-vm.example.com":                        false,
+		"vm-.example.com":                        false,
+		"1vm.example.com":                        false,
+		"vm..example.com":                        false,
+		"":                                       false,
+		strings.Repeat("a", 64) + ".example.com": false,
+		strings.Repeat("a.", 126) + "a":          true,
+		strings.Repeat("a.", 126) + "a.":         true,
+		strings.Repeat("a.", 126) + "ab":         false,
+		"vm_underscore.example.a":                false,
+	}
+
+	for value, valid := range cases {
+		_, errs := validateDnsName(value, "hostname")
+		if valid && len(errs) > 0 {
+			t.Errorf("%q: expected to be valid, got %v", value, errs)
+		}
+		if !valid && len(errs) == 0 {
+			t.Errorf("%q: expected to be invalid", value)
+		}
+	}
+}
+
+func TestPtrRecordName(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3.4":            "4.3.2.1.in-addr.arpa.",
+		"192.168.0.1":        "1.0.168.192.in-addr.arpa.",
+		"2001:db8::567:89ab": "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}
+
+	for ip, expected := range cases {
+		got, err := ptrRecordName(ip)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", ip, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("%s: expected %q, got %q", ip, expected, got)
+		}
+	}
+
+	if _, err := ptrRecordName("not-an-ip"); err == nil {
+		t.Errorf("expected an error for an invalid IP address")
+	}
+}
+
+func TestFqdnDiffSuppress(t *testing.T) {
+	cases := map[string]struct {
+		old, new string
+		suppress bool
+	}{
+		"same": {
+			old:      "example.com.",
+			new:      "example.com.",
+			suppress: true,
+		},
+		"trailing dot": {
+			old:      "example.com.",
+			new:      "example.com",
+			suppress: true,
+		},
+		"case": {
+			old:      "Example.com",
+			new:      "example.com.",
+			suppress: true,
+		},
+		"different": {
+			old:      "example.com.",
+			new:      "example.org",
+			suppress: false,
+		},
+		"subdomain": {
+			old:      "www.example.com.",
+			new:      "example.com.",
+			suppress: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := fqdnDiffSuppress("public_ptr_domain_name", tc.old, tc.new, nil); got != tc.suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, tc.suppress, got)
+		}
+	}
+}
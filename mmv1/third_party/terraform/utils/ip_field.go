@@ -0,0 +1,99 @@
+// Helpers for fields holding an IP address or CIDR range. APIs don't always
+// return addresses in the form they were sent: IPv6 addresses come back
+// compressed and lowercased, and single addresses may come back with (or
+// without) a /32 or /128 suffix. These helpers compare and store the
+// canonical form so equivalent values don't produce diffs.
+
+package google
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// canonicalIpAddress returns the canonical text form of an IP address, eg
+// "2001:db8::1" for "2001:DB8:0:0:0:0:0:1". Values that aren't IP addresses
+// are returned unchanged.
+func canonicalIpAddress(s string) string {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return s
+	}
+	return ip.String()
+}
+
+// canonicalIpCidrRange returns the canonical CIDR form of an IP range. A single
+// address is treated as the range containing only that address, so
+// "10.0.0.1" and "10.0.0.1/32" have the same canonical form. Host bits are
+// preserved, as some fields use them. Values that aren't IP addresses or
+// ranges are returned unchanged.
+func canonicalIpCidrRange(s string) string {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return s
+		}
+		if ip.To4() != nil {
+			return fmt.Sprintf("%s/32", ip)
+		}
+		return fmt.Sprintf("%s/128", ip)
+	}
+
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return s
+	}
+	ones, _ := ipnet.Mask.Size()
+	return fmt.Sprintf("%s/%d", ip, ones)
+}
+
+// canonicalIpField canonicalizes an IP address or CIDR range without changing
+// which of the two it is.
+func canonicalIpField(s string) string {
+	if strings.Contains(s, "/") {
+		return canonicalIpCidrRange(s)
+	}
+	return canonicalIpAddress(s)
+}
+
+// ipFieldDiffSuppress suppresses diffs between equivalent forms of an IP
+// address or CIDR range.
+func ipFieldDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	return canonicalIpCidrRange(old) == canonicalIpCidrRange(new)
+}
+
+// ipCidrRangeHash hashes equivalent IP addresses and CIDR ranges in a set to
+// the same value.
+func ipCidrRangeHash(v interface{}) int {
+	return hashcode(canonicalIpCidrRange(v.(string)))
+}
+
+func validateIpAddressOrCidrRange(v interface{}, k string) (warnings []string, errors []error) {
+	value := v.(string)
+	if strings.Contains(value, "/") {
+		return validateIpCidrRange(v, k)
+	}
+	if net.ParseIP(value) == nil {
+		errors = append(errors, fmt.Errorf("%q (%q) is not a valid IP address or CIDR range", k, value))
+	}
+	return
+}
+
+func expandIpField(v interface{}, d TerraformResourceData, config *Config) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return v, nil
+	}
+	return canonicalIpField(s), nil
+}
+
+func flattenIpField(v interface{}, d *schema.ResourceData, config *Config) interface{} {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return v
+	}
+	return canonicalIpField(s)
+}
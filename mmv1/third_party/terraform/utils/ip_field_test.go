@@ -0,0 +1,98 @@
+package google
+
+import (
+	"testing"
+)
+
+func TestCanonicalIpCidrRange(t *testing.T) {
+	cases := map[string]string{
+		"10.0.0.1":                   "10.0.0.1/32",
+		"10.0.0.1/32":                "10.0.0.1/32",
+		"10.1.2.0/24":                "10.1.2.0/24",
+		"10.1.2.3/24":                "10.1.2.3/24",
+		"2001:DB8:0:0:0:0:0:1":       "2001:db8::1/128",
+		"2001:db8:0000::/64":         "2001:db8::/64",
+		"::ffff:10.0.0.1":            "10.0.0.1/32",
+		"not-an-ip":                  "not-an-ip",
+		"/24":                        "/24",
+		"projects/p/global/networks": "projects/p/global/networks",
+	}
+
+	for input, expected := range cases {
+		if got := canonicalIpCidrRange(input); got != expected {
+			t.Errorf("expected canonicalIpCidrRange(%q) to be %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestIpFieldDiffSuppress(t *testing.T) {
+	cases := map[string]struct {
+		Old, New           string
+		ExpectDiffSuppress bool
+	}{
+		"same address": {
+			Old:                "10.0.0.1",
+			New:                "10.0.0.1",
+			ExpectDiffSuppress: true,
+		},
+		"host suffix": {
+			Old:                "10.0.0.1/32",
+			New:                "10.0.0.1",
+			ExpectDiffSuppress: true,
+		},
+		"ipv6 compression": {
+			Old:                "2001:db8::1",
+			New:                "2001:0DB8:0000:0000:0000:0000:0000:0001",
+			ExpectDiffSuppress: true,
+		},
+		"ipv6 range": {
+			Old:                "2001:db8::/64",
+			New:                "2001:DB8:0:0::/64",
+			ExpectDiffSuppress: true,
+		},
+		"different address": {
+			Old:                "10.0.0.1",
+			New:                "10.0.0.2",
+			ExpectDiffSuppress: false,
+		},
+		"different prefix": {
+			Old:                "10.0.0.0/24",
+			New:                "10.0.0.0/16",
+			ExpectDiffSuppress: false,
+		},
+		"address and wider range": {
+			Old:                "10.0.0.1",
+			New:                "10.0.0.1/24",
+			ExpectDiffSuppress: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if ipFieldDiffSuppress("ip", tc.Old, tc.New, nil) != tc.ExpectDiffSuppress {
+			t.Errorf("bad: %s, %q => %q expect DiffSuppress to return %t", tn, tc.Old, tc.New, tc.ExpectDiffSuppress)
+		}
+	}
+}
+
+func TestValidateIpAddressOrCidrRange(t *testing.T) {
+	cases := map[string]bool{
+		"10.0.0.1":      true,
+		"10.0.0.0/8":    true,
+		"2001:db8::1":   true,
+		"2001:db8::/32": true,
+		"10.0.0.256":    false,
+		"10.0.0.0/33":   false,
+		"":              false,
+		"example.com":   false,
+	}
+
+	for value, valid := range cases {
+		_, errs := validateIpAddressOrCidrRange(value, "ip")
+		if valid && len(errs) > 0 {
+			t.Errorf("expected %q to be valid, got %v", value, errs)
+		}
+		if !valid && len(errs) == 0 {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Helpers encrypting and decrypting data with Cloud KMS keys, shared by the
+// kms_secret data sources and acceptance tests. Ciphertexts are base64
+// encoded, as they're stored in state and configurations, while plaintexts
+// are raw bytes. The CRC32C checksums of the data sent and received are
+// checked, and errors of keys, or of permissions to use them, that were just
+// created are retried until they propagate.
+
+package google
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"google.golang.org/api/cloudkms/v1"
+)
+
+// kmsCryptoRetryTimeout is how long callers that just created a key should
+// retry errors of it propagating for.
+const kmsCryptoRetryTimeout = time.Minute
+
+var kmsCrc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// kmsRestoreVersionHint tells users how to undelete a crypto key version
+// scheduled for destruction, which is possible until it's destroyed.
+const kmsRestoreVersionHint = "If it's scheduled for destruction, restore it with `gcloud kms keys versions restore` before its destroy time."
+
+// kmsCryptoOptions configures kmsEncrypt and kmsDecrypt.
+type kmsCryptoOptions struct {
+	// Version pins the version of the key encrypting the data, eg "1". The
+	// primary version of the key is used if it's empty. Decrypting always
+	// uses the version that encrypted the ciphertext.
+	Version string
+	// AAD is the additional authenticated data, which must be the same when
+	// decrypting as when encrypting.
+	AAD []byte
+	// Timeout is how long to retry errors of a key that was just created, or
+	// the permission to use it, propagating. They aren't retried if it's 0,
+	// as the key or permission is most likely missing instead.
+	Timeout time.Duration
+}
+
+// call calls f, retrying errors of keys propagating for o.Timeout.
+func (o kmsCryptoOptions) call(f func() error) error {
+	if o.Timeout == 0 {
+		return f()
+	}
+	return retryTimeDuration(f, o.Timeout, isKmsKeyPropagationError)
+}
+
+func kmsCrc32c(data []byte) int64 {
+	return int64(crc32.Checksum(data, kmsCrc32cTable))
+}
+
+// kmsEncrypt encrypts plaintext with cryptoKeyId, returning the base64
+// encoded ciphertext. The CRC32C checksums of the request and response are
+// checked, and errors of a key that was just created can be retried, see
+// kmsCryptoOptions.
+func kmsEncrypt(config *Config, userAgent string, cryptoKeyId *kmsCryptoKeyId, plaintext []byte, opts kmsCryptoOptions) (string, error) {
+	name := cryptoKeyId.cryptoKeyId()
+	if opts.Version != "" {
+		name = fmt.Sprintf("%s/cryptoKeyVersions/%s", name, opts.Version)
+	}
+	req := &cloudkms.EncryptRequest{
+		Plaintext:       base64.StdEncoding.EncodeToString(plaintext),
+		PlaintextCrc32c: kmsCrc32c(plaintext),
+		ForceSendFields: []string{"PlaintextCrc32c"},
+	}
+	if len(opts.AAD) > 0 {
+		req.AdditionalAuthenticatedData = base64.StdEncoding.EncodeToString(opts.AAD)
+		req.AdditionalAuthenticatedDataCrc32c = kmsCrc32c(opts.AAD)
+		req.ForceSendFields = append(req.ForceSendFields, "AdditionalAuthenticatedDataCrc32c")
+	}
+
+	var res *cloudkms.EncryptResponse
+	err := opts.call(func() error {
+		call := config.NewKmsClient(userAgent).Projects.Locations.KeyRings.CryptoKeys.Encrypt(name, req)
+		if config.UserProjectOverride {
+			call.Header().Set("X-Goog-User-Project", cryptoKeyId.KeyRingId.Project)
+		}
+		var err error
+		res, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error encrypting plaintext with %s: %s", name, softDeletedAccessError(name, kmsRestoreVersionHint, err))
+	}
+
+	if !res.VerifiedPlaintextCrc32c || (len(opts.AAD) > 0 && !res.VerifiedAdditionalAuthenticatedDataCrc32c) {
+		return "", fmt.Errorf("Error encrypting plaintext with %s: the request was corrupted in transit", name)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(res.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("Error decoding ciphertext: %s", err)
+	}
+	if kmsCrc32c(ciphertext) != res.CiphertextCrc32c {
+		return "", fmt.Errorf("Error encrypting plaintext with %s: the response was corrupted in transit", name)
+	}
+	return res.Ciphertext, nil
+}
+
+// kmsDecrypt decrypts the base64 encoded ciphertext with cryptoKeyId.
+// Whitespace in the ciphertext, eg line breaks in a file it was read from,
+// is ignored.
+func kmsDecrypt(config *Config, userAgent string, cryptoKeyId *kmsCryptoKeyId, ciphertext string, opts kmsCryptoOptions) ([]byte, error) {
+	name := cryptoKeyId.cryptoKeyId()
+	decoded, err := decodeBase64Field(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding ciphertext: %s", err)
+	}
+	req := &cloudkms.DecryptRequest{
+		Ciphertext:       base64.StdEncoding.EncodeToString(decoded),
+		CiphertextCrc32c: kmsCrc32c(decoded),
+		ForceSendFields:  []string{"CiphertextCrc32c"},
+	}
+	if len(opts.AAD) > 0 {
+		req.AdditionalAuthenticatedData = base64.StdEncoding.EncodeToString(opts.AAD)
+		req.AdditionalAuthenticatedDataCrc32c = kmsCrc32c(opts.AAD)
+		req.ForceSendFields = append(req.ForceSendFields, "AdditionalAuthenticatedDataCrc32c")
+	}
+
+	var res *cloudkms.DecryptResponse
+	err = opts.call(func() error {
+		call := config.NewKmsClient(userAgent).Projects.Locations.KeyRings.CryptoKeys.Decrypt(name, req)
+		if config.UserProjectOverride {
+			call.Header().Set("X-Goog-User-Project", cryptoKeyId.KeyRingId.Project)
+		}
+		var err error
+		res, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting ciphertext with %s: %s", name, softDeletedAccessError("The key version encrypting the ciphertext", kmsRestoreVersionHint, err))
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(res.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding plaintext: %s", err)
+	}
+	if kmsCrc32c(plaintext) != res.PlaintextCrc32c {
+		return nil, fmt.Errorf("Error decrypting ciphertext with %s: the response was corrupted in transit", name)
+	}
+	return plaintext, nil
+}
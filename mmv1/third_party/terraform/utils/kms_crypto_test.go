@@ -0,0 +1,126 @@
+package google
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKmsCryptoTestConfig(s *fakeAPIServer) *Config {
+	config := s.Config()
+	config.context = context.Background()
+	config.KMSBasePath = s.URL + "/"
+	return config
+}
+
+var kmsCryptoTestKey = &kmsCryptoKeyId{
+	KeyRingId: kmsKeyRingId{Project: "p", Location: "global", Name: "r"},
+	Name:      "k",
+}
+
+func TestKmsEncrypt(t *testing.T) {
+	s := newFakeAPIServer(t)
+	path := "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/2:encrypt"
+	ciphertext := []byte("encrypted")
+	var req map[string]interface{}
+	s.scripts[fakeAPIKey("POST", path)] = &fakeAPIScript{
+		handler: func(r *http.Request) fakeAPIResponse {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("unexpected error decoding request: %s", err)
+			}
+			return fakeAPIResponse{Body: map[string]interface{}{
+				"ciphertext":              base64.StdEncoding.EncodeToString(ciphertext),
+				"ciphertextCrc32c":        strconv.FormatInt(kmsCrc32c(ciphertext), 10),
+				"verifiedPlaintextCrc32c": true,
+				"verifiedAdditionalAuthenticatedDataCrc32c": true,
+			}}
+		},
+	}
+
+	got, err := kmsEncrypt(newKmsCryptoTestConfig(s), "", kmsCryptoTestKey, []byte("secret"), kmsCryptoOptions{Version: "2", AAD: []byte("context")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != base64.StdEncoding.EncodeToString(ciphertext) {
+		t.Errorf("expected the ciphertext returned by the API, got %q", got)
+	}
+	if req["plaintext"] != base64.StdEncoding.EncodeToString([]byte("secret")) {
+		t.Errorf("expected the plaintext to be sent base64 encoded, got %v", req["plaintext"])
+	}
+	if req["additionalAuthenticatedData"] != base64.StdEncoding.EncodeToString([]byte("context")) {
+		t.Errorf("expected the additional authenticated data to be sent, got %v", req["additionalAuthenticatedData"])
+	}
+	if req["plaintextCrc32c"] == nil || req["additionalAuthenticatedDataCrc32c"] == nil {
+		t.Errorf("expected the checksums of the data to be sent, got %v", req)
+	}
+}
+
+func TestKmsEncrypt_unverified(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("POST", "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/k:encrypt", fakeAPIResponse{Body: map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString([]byte("encrypted")),
+	}})
+
+	_, err := kmsEncrypt(newKmsCryptoTestConfig(s), "", kmsCryptoTestKey, []byte("secret"), kmsCryptoOptions{})
+	if err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Errorf("expected an error if the API didn't verify the plaintext, got %v", err)
+	}
+}
+
+func TestKmsDecrypt_retriesKeyPropagation(t *testing.T) {
+	s := newFakeAPIServer(t)
+	path := "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/k:decrypt"
+	plaintext := []byte("secret")
+	s.Script("POST", path,
+		fakeAPIError(http.StatusNotFound, "CryptoKey projects/p/locations/global/keyRings/r/cryptoKeys/k not found."),
+		fakeAPIResponse{Body: map[string]interface{}{
+			"plaintext":       base64.StdEncoding.EncodeToString(plaintext),
+			"plaintextCrc32c": strconv.FormatInt(kmsCrc32c(plaintext), 10),
+		}},
+	)
+
+	// Line breaks in the ciphertext are ignored
+	ciphertext := "ZW5jcnlw\ndGVk\n"
+	got, err := kmsDecrypt(newKmsCryptoTestConfig(s), "", kmsCryptoTestKey, ciphertext, kmsCryptoOptions{Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(got) != "secret" {
+		t.Errorf("expected the decrypted plaintext, got %q", got)
+	}
+	if n := s.Requests("POST", path); n != 2 {
+		t.Errorf("expected the 404 to be retried once, got %d requests", n)
+	}
+}
+
+func TestKmsDecrypt_noRetryWithoutTimeout(t *testing.T) {
+	s := newFakeAPIServer(t)
+	path := "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/k:decrypt"
+	s.Script("POST", path, fakeAPIError(http.StatusNotFound, "CryptoKey projects/p/locations/global/keyRings/r/cryptoKeys/k not found."))
+
+	_, err := kmsDecrypt(newKmsCryptoTestConfig(s), "", kmsCryptoTestKey, "ZW5jcnlwdGVk", kmsCryptoOptions{})
+	if err == nil {
+		t.Fatalf("expected an error for a missing key")
+	}
+	if n := s.Requests("POST", path); n != 1 {
+		t.Errorf("expected the 404 not to be retried without a timeout, got %d requests", n)
+	}
+}
+
+func TestKmsDecrypt_corruptedResponse(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("POST", "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/k:decrypt", fakeAPIResponse{Body: map[string]interface{}{
+		"plaintext":       base64.StdEncoding.EncodeToString([]byte("secret")),
+		"plaintextCrc32c": strconv.FormatInt(kmsCrc32c([]byte("other")), 10),
+	}})
+
+	_, err := kmsDecrypt(newKmsCryptoTestConfig(s), "", kmsCryptoTestKey, "ZW5jcnlwdGVk", kmsCryptoOptions{})
+	if err == nil || !strings.Contains(err.Error(), "corrupted") {
+		t.Errorf("expected an error if the plaintext doesn't match its checksum, got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+// Helpers for fields referencing a Cloud KMS key used as a customer-managed
+// encryption key (CMEK). Fields accept a key, or a key version, in the form
+// projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{key}.
+// APIs often return the key version used even when only a key was configured,
+// so the two forms are treated as equivalent when diffing. Resources opt in by
+// using kmsKeyRefSchema() for the field, and can warn at plan time when the
+// service agent that encrypts their data can't use the key with
+// kmsKeyRefServiceAgentCustomizeDiff.
+
+package google
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/cloudresourcemanager/v1"
+)
+
+var kmsKeyRefRegex = regexp.MustCompile("^projects/(" + ProjectRegex + ")/locations/([a-z0-9-]+)/keyRings/([a-zA-Z0-9_-]{1,63})/cryptoKeys/([a-zA-Z0-9_-]{1,63})(?:/cryptoKeyVersions/([0-9]+))?$")
+
+// Prefixes of full resource names and self links of keys, which are accepted
+// and removed when normalizing.
+var kmsKeyRefPrefixes = []string{
+	"//cloudkms.googleapis.com/",
+	"https://cloudkms.googleapis.com/v1/",
+}
+
+// kmsKeyRef is a reference to a key, or to a version of it if Version is set.
+type kmsKeyRef struct {
+	CryptoKey kmsCryptoKeyId
+	Version   string
+}
+
+func (r *kmsKeyRef) String() string {
+	if r.Version == "" {
+		return r.CryptoKey.cryptoKeyId()
+	}
+	return fmt.Sprintf("%s/cryptoKeyVersions/%s", r.CryptoKey.cryptoKeyId(), r.Version)
+}
+
+func parseKmsKeyRef(ref string) (*kmsKeyRef, error) {
+	normalized := strings.TrimSuffix(ref, "/")
+	for _, prefix := range kmsKeyRefPrefixes {
+		normalized = strings.TrimPrefix(normalized, prefix)
+	}
+
+	parts := kmsKeyRefRegex.FindStringSubmatch(normalized)
+	if parts == nil {
+		return nil, fmt.Errorf("Invalid KMS key %q, expecting `projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{cryptoKey}`, optionally followed by `/cryptoKeyVersions/{version}`", ref)
+	}
+	return &kmsKeyRef{
+		CryptoKey: kmsCryptoKeyId{
+			KeyRingId: kmsKeyRingId{
+				Project:  parts[1],
+				Location: parts[2],
+				Name:     parts[3],
+			},
+			Name: parts[4],
+		},
+		Version: parts[5],
+	}, nil
+}
+
+// normalizeKmsKeyRef returns ref in its canonical form, as sent to APIs.
+func normalizeKmsKeyRef(ref string) (string, error) {
+	r, err := parseKmsKeyRef(ref)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+func validateKmsKeyRef(v interface{}, k string) (ws []string, errs []error) {
+	ref, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected %s to be a string", k)}
+	}
+	if _, err := parseKmsKeyRef(ref); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %s", k, err))
+	}
+	return
+}
+
+// kmsKeyRefDiffSuppress suppresses diffs between equivalent forms of a key,
+// and between a configured key and a version of it returned by the API.
+func kmsKeyRefDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	if old == new {
+		return true
+	}
+	o, err := parseKmsKeyRef(old)
+	if err != nil {
+		return false
+	}
+	n, err := parseKmsKeyRef(new)
+	if err != nil {
+		return false
+	}
+	if o.CryptoKey.cryptoKeyId() != n.CryptoKey.cryptoKeyId() {
+		return false
+	}
+	return n.Version == "" || n.Version == o.Version
+}
+
+// kmsKeyRefSchema is the schema of a field referencing a key, or a version of
+// it, used for CMEK. APIs often return the key version used when only a key
+// was configured, so the two aren't diffed.
+func kmsKeyRefSchema(description string) *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeString,
+		Optional:         true,
+		ValidateFunc:     validateKmsKeyRef,
+		DiffSuppressFunc: kmsKeyRefDiffSuppress,
+		Description:      description,
+	}
+}
+
+// Roles granting each of the permissions needed to use a key for CMEK.
+var (
+	kmsKeyRefEncryptRoles = map[string]bool{
+		"roles/cloudkms.cryptoKeyEncrypterDecrypter": true,
+		"roles/cloudkms.cryptoKeyEncrypter":          true,
+	}
+	kmsKeyRefDecryptRoles = map[string]bool{
+		"roles/cloudkms.cryptoKeyEncrypterDecrypter": true,
+		"roles/cloudkms.cryptoKeyDecrypter":          true,
+	}
+)
+
+// kmsKeyRefBindings is a role to members view of the policies a key inherits.
+type kmsKeyRefBindings map[string][]string
+
+// canUseKey returns whether member has both the encrypt and decrypt roles.
+// Conditional bindings are counted, as their conditions can't be evaluated
+// at plan time.
+func (b kmsKeyRefBindings) canUseKey(member string) bool {
+	encrypt, decrypt := false, false
+	for role, members := range b {
+		for _, m := range members {
+			if m != member {
+				continue
+			}
+			encrypt = encrypt || kmsKeyRefEncryptRoles[role]
+			decrypt = decrypt || kmsKeyRefDecryptRoles[role]
+		}
+	}
+	return encrypt && decrypt
+}
+
+// kmsKeyRefData is the resource data the service agent check reads: the
+// *schema.ResourceDiff of a plan, or the *schema.ResourceData of an apply.
+type kmsKeyRefData interface {
+	HasChange(string) bool
+	GetOk(string) (interface{}, bool)
+}
+
+// kmsKeyRefServiceAgentFunc returns the email of the service agent that uses
+// the key of a resource.
+type kmsKeyRefServiceAgentFunc func(d kmsKeyRefData, config *Config) (string, error)
+
+// withKmsKeyRefServiceAgentCheck warns when the service agent returned by
+// serviceAgent doesn't appear to be able to use the key set in field of r.
+// CustomizeDiff functions can't return warnings, so plans only log them;
+// creates and updates report them as warning diagnostics through
+// addResourceWarning.
+func withKmsKeyRefServiceAgentCheck(r *schema.Resource, field string, serviceAgent kmsKeyRefServiceAgentFunc) *schema.Resource {
+	planned := kmsKeyRefServiceAgentCustomizeDiff(field, serviceAgent)
+	if r.CustomizeDiff != nil {
+		planned = customdiff.All(r.CustomizeDiff, planned)
+	}
+	r.CustomizeDiff = planned
+
+	wrap := func(f func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+		if f == nil {
+			return nil
+		}
+		return func(d *schema.ResourceData, meta interface{}) error {
+			config := meta.(*Config)
+			if w := kmsKeyRefServiceAgentWarning(d, config, field, serviceAgent); w != "" {
+				addResourceWarning(d, config, "KMS key may not be usable", w)
+			}
+			return f(d, meta)
+		}
+	}
+	r.Create = wrap(r.Create)
+	r.Update = wrap(r.Update)
+	return r
+}
+
+// kmsKeyRefServiceAgentCustomizeDiff returns a CustomizeDiffFunc logging the
+// warning of kmsKeyRefServiceAgentWarning at plan time.
+func kmsKeyRefServiceAgentCustomizeDiff(field string, serviceAgent kmsKeyRefServiceAgentFunc) schema.CustomizeDiffFunc {
+	return func(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		if !d.NewValueKnown(field) {
+			return nil
+		}
+		if w := kmsKeyRefServiceAgentWarning(d, meta.(*Config), field, serviceAgent); w != "" {
+			log.Printf("[WARN] %s", w)
+		}
+		return nil
+	}
+}
+
+// kmsKeyRefServiceAgentWarning checks the service agent returned by
+// serviceAgent has been granted the roles it needs to use the key set in
+// field, and returns a warning if it hasn't. The grant may come from a folder
+// or organization policy, or a google_kms_crypto_key_iam_member applied in the
+// same run, neither of which the check sees, so it never fails. The key's, key
+// ring's and project's policies are checked; if any of them can't be read, eg
+// because the caller can't read IAM policies, the check is skipped.
+func kmsKeyRefServiceAgentWarning(d kmsKeyRefData, config *Config, field string, serviceAgent kmsKeyRefServiceAgentFunc) string {
+	if !d.HasChange(field) {
+		return ""
+	}
+	v, ok := d.GetOk(field)
+	if !ok {
+		return ""
+	}
+	ref, err := parseKmsKeyRef(v.(string))
+	if err != nil {
+		// Reported by the field's validation
+		return ""
+	}
+
+	agent, err := serviceAgent(d, config)
+	if err != nil {
+		log.Printf("[WARN] Skipping check that the service agent can use %s: %s", ref.CryptoKey.cryptoKeyId(), err)
+		return ""
+	}
+	member := "serviceAccount:" + agent
+
+	bindings, err := kmsKeyRefInheritedBindings(config, ref)
+	if err != nil {
+		log.Printf("[WARN] Skipping check that %s can use %s: %s", agent, ref.CryptoKey.cryptoKeyId(), err)
+		return ""
+	}
+	if bindings.canUseKey(member) {
+		return ""
+	}
+	return fmt.Sprintf("%s: service agent %s doesn't appear to be able to use key %s. If the apply fails, grant it roles/cloudkms.cryptoKeyEncrypterDecrypter on the key, eg with a google_kms_crypto_key_iam_member, and make the resource depend on the grant", field, agent, ref.CryptoKey.cryptoKeyId())
+}
+
+// kmsKeyRefProject returns the project of the resource d belongs to, for
+// service agent functions, falling back to the provider's project.
+func kmsKeyRefProject(d kmsKeyRefData, config *Config) (string, error) {
+	if diff, ok := d.(*schema.ResourceDiff); ok && !diff.NewValueKnown("project") {
+		return "", fmt.Errorf("project is not known yet")
+	}
+	if v, ok := d.GetOk("project"); ok {
+		return v.(string), nil
+	}
+	if config.Project != "" {
+		return config.Project, nil
+	}
+	return "", fmt.Errorf("%s: required field is not set", "project")
+}
+
+// kmsKeyRefInheritedBindings returns the bindings of the policies of a key,
+// its key ring and its project. Folder and organization policies aren't
+// read, so grants made on them aren't seen.
+func kmsKeyRefInheritedBindings(config *Config, ref *kmsKeyRef) (kmsKeyRefBindings, error) {
+	userAgent := config.userAgent
+	kms := config.NewKmsClient(userAgent).Projects.Locations.KeyRings
+	bindings := make(kmsKeyRefBindings)
+	addKms := func(p *cloudkms.Policy) {
+		for _, b := range p.Bindings {
+			bindings[b.Role] = append(bindings[b.Role], b.Members...)
+		}
+	}
+
+	keyPolicy, err := kms.CryptoKeys.GetIamPolicy(ref.CryptoKey.cryptoKeyId()).OptionsRequestedPolicyVersion(iamPolicyVersion).Do()
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("Error retrieving IAM policy for %s: {{err}}", ref.CryptoKey.cryptoKeyId()), err)
+	}
+	addKms(keyPolicy)
+
+	keyRingPolicy, err := kms.GetIamPolicy(ref.CryptoKey.KeyRingId.keyRingId()).OptionsRequestedPolicyVersion(iamPolicyVersion).Do()
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("Error retrieving IAM policy for %s: {{err}}", ref.CryptoKey.KeyRingId.keyRingId()), err)
+	}
+	addKms(keyRingPolicy)
+
+	project := ref.CryptoKey.KeyRingId.Project
+	projectPolicy, err := config.NewResourceManagerClient(userAgent).Projects.GetIamPolicy(project,
+		&cloudresourcemanager.GetIamPolicyRequest{
+			Options: &cloudresourcemanager.GetPolicyOptions{
+				RequestedPolicyVersion: iamPolicyVersion,
+			},
+		}).Do()
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("Error retrieving IAM policy for project %s: {{err}}", project), err)
+	}
+	for _, b := range projectPolicy.Bindings {
+		bindings[b.Role] = append(bindings[b.Role], b.Members...)
+	}
+
+	return bindings, nil
+}
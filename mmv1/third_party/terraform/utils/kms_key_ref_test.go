@@ -0,0 +1,212 @@
+package google
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeKmsKeyRef(t *testing.T) {
+	const key = "projects/my-project/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key"
+	cases := map[string]struct {
+		ref      string
+		expected string
+		err      bool
+	}{
+		"key": {
+			ref:      key,
+			expected: key,
+		},
+		"key version": {
+			ref:      key + "/cryptoKeyVersions/3",
+			expected: key + "/cryptoKeyVersions/3",
+		},
+		"full resource name": {
+			ref:      "//cloudkms.googleapis.com/" + key,
+			expected: key,
+		},
+		"self link": {
+			ref:      "https://cloudkms.googleapis.com/v1/" + key + "/",
+			expected: key,
+		},
+		"domain scoped project": {
+			ref:      "projects/example.com:my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key",
+			expected: "projects/example.com:my-project/locations/global/keyRings/my-ring/cryptoKeys/my-key",
+		},
+		"key ring": {
+			ref: "projects/my-project/locations/us-central1/keyRings/my-ring",
+			err: true,
+		},
+		"short form": {
+			ref: "my-project/us-central1/my-ring/my-key",
+			err: true,
+		},
+		"invalid version": {
+			ref: key + "/cryptoKeyVersions/latest",
+			err: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		got, err := normalizeKmsKeyRef(tc.ref)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", tn, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestKmsKeyRefDiffSuppress(t *testing.T) {
+	const key = "projects/my-project/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key"
+	cases := map[string]struct {
+		old, new string
+		suppress bool
+	}{
+		"same key": {
+			old:      key,
+			new:      key,
+			suppress: true,
+		},
+		"version returned by the API": {
+			old:      key + "/cryptoKeyVersions/1",
+			new:      key,
+			suppress: true,
+		},
+		"full resource name": {
+			old:      key,
+			new:      "//cloudkms.googleapis.com/" + key,
+			suppress: true,
+		},
+		"different version": {
+			old:      key + "/cryptoKeyVersions/1",
+			new:      key + "/cryptoKeyVersions/2",
+			suppress: false,
+		},
+		"version configured": {
+			old:      key,
+			new:      key + "/cryptoKeyVersions/1",
+			suppress: false,
+		},
+		"different key": {
+			old:      key,
+			new:      key + "-2",
+			suppress: false,
+		},
+		"key removed": {
+			old:      key,
+			new:      "",
+			suppress: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := kmsKeyRefDiffSuppress("kms_key_name", tc.old, tc.new, nil); got != tc.suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, tc.suppress, got)
+		}
+	}
+}
+
+func TestKmsKeyRefBindingsCanUseKey(t *testing.T) {
+	const agent = "serviceAccount:service-123@gs-project-accounts.iam.gserviceaccount.com"
+	cases := map[string]struct {
+		bindings kmsKeyRefBindings
+		expected bool
+	}{
+		"encrypter decrypter": {
+			bindings: kmsKeyRefBindings{"roles/cloudkms.cryptoKeyEncrypterDecrypter": {agent}},
+			expected: true,
+		},
+		"separate roles": {
+			bindings: kmsKeyRefBindings{
+				"roles/cloudkms.cryptoKeyEncrypter": {agent},
+				"roles/cloudkms.cryptoKeyDecrypter": {"user:someone@example.com", agent},
+			},
+			expected: true,
+		},
+		"encrypter only": {
+			bindings: kmsKeyRefBindings{"roles/cloudkms.cryptoKeyEncrypter": {agent}},
+			expected: false,
+		},
+		"other member": {
+			bindings: kmsKeyRefBindings{"roles/cloudkms.cryptoKeyEncrypterDecrypter": {"user:someone@example.com"}},
+			expected: false,
+		},
+		"other role": {
+			bindings: kmsKeyRefBindings{"roles/cloudkms.admin": {agent}},
+			expected: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := tc.bindings.canUseKey(agent); got != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestKmsKeyRefServiceAgentWarning(t *testing.T) {
+	const (
+		key   = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+		agent = "service-123@gs-project-accounts.iam.gserviceaccount.com"
+	)
+	serviceAgent := func(kmsKeyRefData, *Config) (string, error) {
+		return agent, nil
+	}
+
+	cases := map[string]struct {
+		keyMembers []string
+		changed    bool
+		warns      bool
+	}{
+		"granted": {
+			keyMembers: []string{"serviceAccount:" + agent},
+			changed:    true,
+		},
+		"not granted": {
+			keyMembers: []string{"user:someone@example.com"},
+			changed:    true,
+			warns:      true,
+		},
+		"unchanged": {
+			keyMembers: []string{"user:someone@example.com"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			s := newFakeAPIServer(t)
+			s.Script("GET", "/v1/"+key+":getIamPolicy", fakeAPIResponse{Body: map[string]interface{}{
+				"bindings": []interface{}{
+					map[string]interface{}{"role": "roles/cloudkms.cryptoKeyEncrypterDecrypter", "members": tc.keyMembers},
+				},
+			}})
+			s.Script("GET", "/v1/projects/p/locations/global/keyRings/r:getIamPolicy", fakeAPIResponse{})
+			s.Script("POST", "/v1/projects/p:getIamPolicy", fakeAPIResponse{})
+			config := s.Config()
+			config.context = context.Background()
+			config.KMSBasePath = s.URL + "/"
+			config.ResourceManagerBasePath = s.URL + "/"
+
+			d := &ResourceDataMock{FieldsInSchema: map[string]interface{}{"kms_key_name": key}}
+			if tc.changed {
+				d.FieldsWithHasChange = []string{"kms_key_name"}
+			}
+			w := kmsKeyRefServiceAgentWarning(d, config, "kms_key_name", serviceAgent)
+			if tc.warns != (w != "") {
+				t.Errorf("expected a warning: %t, got %q", tc.warns, w)
+			}
+			if tc.warns && !strings.Contains(w, agent) {
+				t.Errorf("expected the warning to name %s, got %q", agent, w)
+			}
+		})
+	}
+}
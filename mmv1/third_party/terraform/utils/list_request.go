@@ -0,0 +1,113 @@
+package google
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultListTokenField = "nextPageToken"
+
+// ListRequest configures a paginated list call made with paginatedList.
+type ListRequest struct {
+	// Response field holding the token of the next page. Defaults to
+	// nextPageToken.
+	TokenField string
+	// Dot separated path to the list of items in each response, eg
+	// "tensorflowVersions" or "result.items". Ignored if Flattener is set.
+	ItemsPath string
+	// Converts a page of results to a list of items, for responses that need
+	// more than extracting the items at ItemsPath.
+	Flattener func(map[string]interface{}) []interface{}
+	// Sent as the pageSize query parameter if set.
+	PageSize int
+	// Stops listing once this many items have been read, and truncates the
+	// result to it. 0 lists every page.
+	MaxItems int
+	// Additional query parameters sent with every page request, eg a filter.
+	QueryParams map[string]string
+}
+
+// paginatedList sends GET requests to baseUrl, following page tokens until
+// every page (or MaxItems items) has been read, and returns the items of all
+// pages.
+func paginatedList(config *Config, project, baseUrl, userAgent string, req ListRequest) ([]interface{}, error) {
+	tokenField := req.TokenField
+	if tokenField == "" {
+		tokenField = defaultListTokenField
+	}
+
+	params := make(map[string]string, len(req.QueryParams)+2)
+	for k, v := range req.QueryParams {
+		params[k] = v
+	}
+	if req.PageSize > 0 {
+		params["pageSize"] = strconv.Itoa(req.PageSize)
+	}
+
+	var items []interface{}
+	for {
+		url := baseUrl
+		if len(params) > 0 {
+			var err error
+			url, err = addQueryParams(baseUrl, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := sendRequest(config, "GET", project, url, userAgent, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.Flattener != nil {
+			items = append(items, req.Flattener(res)...)
+		} else {
+			page, err := listItemsAtPath(res, req.ItemsPath)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, page...)
+		}
+
+		if req.MaxItems > 0 && len(items) >= req.MaxItems {
+			return items[:req.MaxItems], nil
+		}
+
+		token, _ := res[tokenField].(string)
+		if token == "" {
+			return items, nil
+		}
+		params["pageToken"] = token
+	}
+}
+
+// listItemsAtPath returns the list found at the dot separated path in res. A
+// missing list is empty, as APIs omit empty fields from responses.
+func listItemsAtPath(res map[string]interface{}, path string) ([]interface{}, error) {
+	if path == "" {
+		return nil, fmt.Errorf("list request must set either ItemsPath or Flattener")
+	}
+
+	var v interface{} = res
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object containing %q in list response, got %T", part, v)
+		}
+		if v, ok = m[part]; !ok {
+			return nil, nil
+		}
+	}
+
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list at %q in list response, got %T", path, v)
+	}
+	return items, nil
+}
+
+func paginatedListRequest(project, baseUrl, userAgent string, config *Config, flattener func(map[string]interface{}) []interface{}) ([]interface{}, error) {
+	return paginatedList(config, project, baseUrl, userAgent, ListRequest{Flattener: flattener})
+}
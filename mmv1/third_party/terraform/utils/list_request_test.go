@@ -0,0 +1,62 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListItemsAtPath(t *testing.T) {
+	res := map[string]interface{}{
+		"items": []interface{}{"a", "b"},
+		"result": map[string]interface{}{
+			"versions": []interface{}{"c"},
+		},
+		"nextPageToken": "token",
+	}
+
+	cases := map[string]struct {
+		Path        string
+		Expected    []interface{}
+		ExpectError bool
+	}{
+		"top level": {
+			Path:     "items",
+			Expected: []interface{}{"a", "b"},
+		},
+		"nested": {
+			Path:     "result.versions",
+			Expected: []interface{}{"c"},
+		},
+		"missing list is empty": {
+			Path: "result.other",
+		},
+		"not a list": {
+			Path:        "nextPageToken",
+			ExpectError: true,
+		},
+		"not an object": {
+			Path:        "items.name",
+			ExpectError: true,
+		},
+		"no path": {
+			ExpectError: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		items, err := listItemsAtPath(res, tc.Path)
+		if tc.ExpectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if !reflect.DeepEqual(items, tc.Expected) {
+			t.Errorf("%s: expected %v, got %v", tn, tc.Expected, items)
+		}
+	}
+}
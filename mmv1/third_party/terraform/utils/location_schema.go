@@ -0,0 +1,105 @@
+package google
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	projectFieldDescription = `The ID of the project in which the resource belongs. If it is not provided, the provider project is used.`
+	regionFieldDescription  = `The region of the resource. If it is not provided, the region of the resource's zone or the provider region is used.`
+	zoneFieldDescription    = `The zone of the resource. If it is not provided, the provider zone is used.`
+)
+
+// locationFieldOptions customizes the fields returned by regionSchema and
+// zoneSchema. The zero value is the usual field: optional, defaulting to the
+// provider's value (see getRegion and getZone), and forcing a new resource
+// when changed.
+type locationFieldOptions struct {
+	// Description replaces the default description of the field
+	Description string
+	// Required makes the field required instead of defaulting to the
+	// provider's value
+	Required bool
+	// Updatable makes the field updatable in place instead of forcing a new
+	// resource
+	Updatable bool
+	// ComputedWhen names fields the value of the field is computed from when
+	// it isn't set, eg "zone" for a region. While any of them changes, the
+	// value in state is stale, so getRegionFromSchema ignores it. Resources
+	// with a region computed from their zone also need
+	// regionFromZoneCustomizeDiff
+	ComputedWhen []string
+}
+
+// projectSchema returns the schema of the standard "project" field, read by
+// getProject: optional, defaulting to the provider project, and forcing a new
+// resource when changed.
+func projectSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Computed:    true,
+		ForceNew:    true,
+		Description: projectFieldDescription,
+	}
+}
+
+// regionSchema returns the schema of the standard "region" field, read by
+// getRegion.
+func regionSchema(opts locationFieldOptions) *schema.Schema {
+	return locationSchema(opts, regionFieldDescription)
+}
+
+// zoneSchema returns the schema of the standard "zone" field, read by
+// getZone.
+func zoneSchema(opts locationFieldOptions) *schema.Schema {
+	return locationSchema(opts, zoneFieldDescription)
+}
+
+func locationSchema(opts locationFieldOptions, description string) *schema.Schema {
+	s := &schema.Schema{
+		Type:         schema.TypeString,
+		Required:     opts.Required,
+		Optional:     !opts.Required,
+		Computed:     !opts.Required,
+		ForceNew:     !opts.Updatable,
+		ComputedWhen: opts.ComputedWhen,
+		Description:  description,
+	}
+	if opts.Description != "" {
+		s.Description = opts.Description
+	}
+	return s
+}
+
+// regionFromZoneDiff is the subset of *schema.ResourceDiff used by
+// regionFromZoneCustomizeDiff.
+type regionFromZoneDiff interface {
+	HasChange(string) bool
+	GetRawConfig() cty.Value
+	SetNewComputed(string) error
+}
+
+// regionFromZoneCustomizeDiff marks the region field computed when the zone
+// field changes and the region isn't set in config. The region in state was
+// derived from the previous zone, so getRegionFromSchema must derive it from
+// the new one instead of reading the stale value.
+func regionFromZoneCustomizeDiff(regionField, zoneField string) schema.CustomizeDiffFunc {
+	return func(_ context.Context, diff *schema.ResourceDiff, _ interface{}) error {
+		return regionFromZoneCustomizeDiffFunc(diff, regionField, zoneField)
+	}
+}
+
+func regionFromZoneCustomizeDiffFunc(diff regionFromZoneDiff, regionField, zoneField string) error {
+	if !diff.HasChange(zoneField) {
+		return nil
+	}
+	config := diff.GetRawConfig()
+	if config.IsKnown() && !config.IsNull() && config.Type().IsObjectType() && config.Type().HasAttribute(regionField) && !config.GetAttr(regionField).IsNull() {
+		return nil
+	}
+	return diff.SetNewComputed(regionField)
+}
@@ -0,0 +1,122 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+)
+
+func TestLocationSchema(t *testing.T) {
+	if s := projectSchema(); !s.Optional || !s.Computed || !s.ForceNew || s.Description == "" {
+		t.Errorf("expected an optional, computed, force new project field, got %+v", s)
+	}
+
+	s := regionSchema(locationFieldOptions{ComputedWhen: []string{"zone"}})
+	if !s.Optional || !s.Computed || !s.ForceNew || s.Description != regionFieldDescription {
+		t.Errorf("expected an optional, computed, force new region field, got %+v", s)
+	}
+	if len(s.ComputedWhen) != 1 || s.ComputedWhen[0] != "zone" {
+		t.Errorf("expected the region to be computed when the zone changes, got %v", s.ComputedWhen)
+	}
+
+	s = zoneSchema(locationFieldOptions{Required: true, Updatable: true, Description: "The zone."})
+	if !s.Required || s.Optional || s.Computed || s.ForceNew || s.Description != "The zone." {
+		t.Errorf("expected a required, updatable zone field, got %+v", s)
+	}
+}
+
+func TestGetRegionFromSchema_computedWhenZoneChanges(t *testing.T) {
+	config := &Config{Region: "us-central1"}
+
+	// The region in state was computed from the previous zone
+	d := &ResourceDataMock{
+		FieldsInSchema:      map[string]interface{}{"region": "us-central1", "zone": "europe-west1-b"},
+		FieldsWithHasChange: []string{"zone"},
+	}
+	if region, err := getRegion(d, config); err != nil || region != "europe-west1" {
+		t.Errorf("expected the region of the new zone, got %q, %v", region, err)
+	}
+
+	// Both changed, so the region was set explicitly
+	d.FieldsWithHasChange = []string{"zone", "region"}
+	if region, err := getRegion(d, config); err != nil || region != "us-central1" {
+		t.Errorf("expected the region set, got %q, %v", region, err)
+	}
+
+	d.FieldsWithHasChange = nil
+	if region, err := getRegion(d, config); err != nil || region != "us-central1" {
+		t.Errorf("expected the region in state, got %q, %v", region, err)
+	}
+
+	// A region marked computed by regionFromZoneCustomizeDiff is unset
+	d.FieldsInSchema = map[string]interface{}{"zone": "europe-west1-b"}
+	d.FieldsWithHasChange = []string{"zone"}
+	if region, err := getRegion(d, config); err != nil || region != "europe-west1" {
+		t.Errorf("expected the region of the zone, got %q, %v", region, err)
+	}
+}
+
+type regionFromZoneDiffMock struct {
+	ResourceDiffMock
+	config   cty.Value
+	computed []string
+}
+
+func (d *regionFromZoneDiffMock) GetRawConfig() cty.Value {
+	return d.config
+}
+
+func (d *regionFromZoneDiffMock) SetNewComputed(key string) error {
+	d.computed = append(d.computed, key)
+	return nil
+}
+
+func TestRegionFromZoneCustomizeDiff(t *testing.T) {
+	configOf := func(region cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"region": region,
+			"zone":   cty.StringVal("europe-west1-b"),
+		})
+	}
+	zoneChange := ResourceDiffMock{
+		Before: map[string]interface{}{"region": "us-central1", "zone": "us-central1-a"},
+		After:  map[string]interface{}{"region": "us-central1", "zone": "europe-west1-b"},
+	}
+	cases := map[string]struct {
+		diff     ResourceDiffMock
+		config   cty.Value
+		computed bool
+	}{
+		"zone changed, region unset": {
+			diff:     zoneChange,
+			config:   configOf(cty.NullVal(cty.String)),
+			computed: true,
+		},
+		"zone changed, region set": {
+			diff:   zoneChange,
+			config: configOf(cty.StringVal("us-central1")),
+		},
+		"zone changed, region unknown": {
+			diff:   zoneChange,
+			config: configOf(cty.UnknownVal(cty.String)),
+		},
+		"zone unchanged": {
+			diff: ResourceDiffMock{
+				Before: map[string]interface{}{"region": "us-central1", "zone": "us-central1-a"},
+				After:  map[string]interface{}{"region": "us-central1", "zone": "us-central1-a"},
+			},
+			config: configOf(cty.NullVal(cty.String)),
+		},
+	}
+
+	for tn, tc := range cases {
+		d := &regionFromZoneDiffMock{ResourceDiffMock: tc.diff, config: tc.config}
+		if err := regionFromZoneCustomizeDiffFunc(d, "region", "zone"); err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if computed := len(d.computed) == 1 && d.computed[0] == "region"; computed != tc.computed {
+			t.Errorf("%s: expected the region to be computed: %t, got %v", tn, tc.computed, d.computed)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Helpers for maintenance window and recurring schedule fields, shared by
+// resources like GKE clusters, Cloud SQL instances, Memorystore instances and
+// Filestore instances. APIs describe windows in different ways: days of the
+// week by name (MONDAY) or number (1-7 from Monday), start times as "HH:MM"
+// strings or google.type.TimeOfDay objects, and recurring windows as RFC 5545
+// RRULEs between RFC 3339 timestamps. These helpers convert between them and
+// compare equivalent values so they don't produce diffs.
+
+package google
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// maintenanceWindowDays are the days of the week as named by the API, in the
+// order they're numbered (from 1) by APIs that number them.
+var maintenanceWindowDays = []string{"MONDAY", "TUESDAY", "WEDNESDAY", "THURSDAY", "FRIDAY", "SATURDAY", "SUNDAY"}
+
+// rruleDays are the days of the week as named in RRULE BYDAY parts, in the
+// same order as maintenanceWindowDays.
+var rruleDays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// The parts an RRULE may have, see RFC 5545 section 3.3.10.
+var rruleParts = []string{"FREQ", "UNTIL", "COUNT", "INTERVAL", "BYSECOND", "BYMINUTE", "BYHOUR", "BYDAY", "BYMONTHDAY", "BYYEARDAY", "BYWEEKNO", "BYMONTH", "BYSETPOS", "WKST"}
+
+var validateMaintenanceWindowDay = validation.StringInSlice(maintenanceWindowDays, false)
+
+// validateMaintenanceWindowDayNumber validates days numbered from 1 for
+// Monday, as APIs that number days do.
+var validateMaintenanceWindowDayNumber = validation.IntBetween(1, len(maintenanceWindowDays))
+
+// maintenanceWindowDayNumber returns the number (1-7 from Monday) of a day
+// named like MONDAY, or 0 if it isn't a day.
+func maintenanceWindowDayNumber(day string) int {
+	for i, d := range maintenanceWindowDays {
+		if strings.EqualFold(d, day) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// maintenanceWindowDayName returns the name of the day numbered n (1-7 from
+// Monday), or "" if n isn't a day.
+func maintenanceWindowDayName(n int) string {
+	if n < 1 || n > len(maintenanceWindowDays) {
+		return ""
+	}
+	return maintenanceWindowDays[n-1]
+}
+
+// expandTimeOfDay expands a "HH:MM" start time, see validateRFC3339Time, to a
+// google.type.TimeOfDay.
+func expandTimeOfDay(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return nil, nil
+	}
+	if _, errs := validateRFC3339Time(s, "time"); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	hours, _ := strconv.Atoi(s[:2])
+	minutes, _ := strconv.Atoi(s[3:])
+	return map[string]interface{}{
+		"hours":   hours,
+		"minutes": minutes,
+	}, nil
+}
+
+// flattenStartTimeOfDay flattens a google.type.TimeOfDay to a "HH:MM" start time.
+// Seconds aren't kept, as maintenance windows start on the minute.
+func flattenStartTimeOfDay(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return fmt.Sprintf("%02d:%02d", timeOfDayPart(m["hours"]), timeOfDayPart(m["minutes"]))
+}
+
+// timeOfDayPart returns a field of a google.type.TimeOfDay. Zero values are
+// omitted from responses, eg the hours of a window starting at midnight.
+func timeOfDayPart(v interface{}) int {
+	switch n := flattenInt64(v).(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	}
+	return 0
+}
+
+// parseRecurrence parses an RRULE, with or without its "RRULE:" prefix, into
+// its uppercased parts.
+func parseRecurrence(s string) (map[string]string, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	parts := make(map[string]string)
+	for _, p := range strings.Split(s, ";") {
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("%q isn't a NAME=VALUE part", p)
+		}
+		if !stringInSlice(rruleParts, kv[0]) {
+			return nil, fmt.Errorf("unknown part %s", kv[0])
+		}
+		if _, ok := parts[kv[0]]; ok {
+			return nil, fmt.Errorf("part %s is repeated", kv[0])
+		}
+		parts[kv[0]] = kv[1]
+	}
+	if parts["FREQ"] == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	return parts, nil
+}
+
+// validateRecurrence warns about values that aren't RFC 5545 RRULEs, eg
+// "FREQ=WEEKLY;BYDAY=SA,SU". The API decides which recurrences it accepts, so
+// they're never rejected.
+func validateRecurrence(v interface{}, k string) (ws []string, errs []error) {
+	if _, err := parseRecurrence(v.(string)); err != nil {
+		ws = append(ws, fmt.Sprintf("%q (%q) may not be a valid RFC 5545 recurrence: %s", k, v, err))
+	}
+	return
+}
+
+// canonicalRecurrence returns the canonical form of an RRULE: uppercased,
+// without an "RRULE:" prefix or a redundant INTERVAL=1, with FREQ first and
+// the other parts and BYDAY's days in order. A weekly recurrence on every day
+// is a daily one. Values that aren't RRULEs are returned unchanged.
+func canonicalRecurrence(s string) string {
+	parts, err := parseRecurrence(s)
+	if err != nil {
+		return s
+	}
+	if parts["INTERVAL"] == "1" {
+		delete(parts, "INTERVAL")
+	}
+	if days, ok := parts["BYDAY"]; ok {
+		byDay := strings.Split(days, ",")
+		sort.SliceStable(byDay, func(i, j int) bool {
+			return rruleDayIndex(byDay[i]) < rruleDayIndex(byDay[j])
+		})
+		parts["BYDAY"] = strings.Join(byDay, ",")
+	}
+	if len(parts) == 2 && parts["FREQ"] == "WEEKLY" && parts["BYDAY"] == strings.Join(rruleDays, ",") {
+		parts = map[string]string{"FREQ": "DAILY"}
+	}
+
+	canonical := []string{"FREQ=" + parts["FREQ"]}
+	for _, k := range rruleParts[1:] {
+		if v, ok := parts[k]; ok {
+			canonical = append(canonical, k+"="+v)
+		}
+	}
+	return strings.Join(canonical, ";")
+}
+
+// rruleDayIndex orders BYDAY days, which may be prefixed with an ordinal like
+// 1MO or -1SU.
+func rruleDayIndex(day string) int {
+	for i, d := range rruleDays {
+		if strings.HasSuffix(day, d) {
+			return i
+		}
+	}
+	return len(rruleDays)
+}
+
+// recurrenceDiffSuppress suppresses diffs between equivalent RRULEs, eg the
+// "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR,SA,SU" GKE returns for "FREQ=DAILY".
+func recurrenceDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	return canonicalRecurrence(old) == canonicalRecurrence(new)
+}
+
+// normalizeRFC3339Timestamp returns an RFC 3339 timestamp in UTC, eg
+// "2019-01-01T13:00:00Z" for "2019-01-01T09:00:00-04:00". APIs usually return
+// maintenance window bounds in UTC regardless of the offset they were sent
+// with. Values that aren't timestamps are returned unchanged.
+func normalizeRFC3339Timestamp(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// rfc3339TimestampDiffSuppress suppresses diffs between RFC 3339 timestamps
+// of the same instant in different time zones.
+func rfc3339TimestampDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	return normalizeRFC3339Timestamp(old) == normalizeRFC3339Timestamp(new)
+}
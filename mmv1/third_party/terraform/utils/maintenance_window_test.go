@@ -0,0 +1,115 @@
+package google
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMaintenanceWindowDays(t *testing.T) {
+	if n := maintenanceWindowDayNumber("sunday"); n != 7 {
+		t.Errorf("expected SUNDAY to be day 7, got %d", n)
+	}
+	if n := maintenanceWindowDayNumber("FUNDAY"); n != 0 {
+		t.Errorf("expected an unknown day to be 0, got %d", n)
+	}
+	if d := maintenanceWindowDayName(1); d != "MONDAY" {
+		t.Errorf("expected day 1 to be MONDAY, got %q", d)
+	}
+	if d := maintenanceWindowDayName(8); d != "" {
+		t.Errorf("expected day 8 not to be a day, got %q", d)
+	}
+}
+
+func TestTimeOfDay(t *testing.T) {
+	expanded, err := expandTimeOfDay("09:30")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := map[string]interface{}{"hours": 9, "minutes": 30}; !reflect.DeepEqual(expanded, want) {
+		t.Errorf("expected %v, got %v", want, expanded)
+	}
+	if _, err := expandTimeOfDay("25:00"); err == nil {
+		t.Errorf("expected an error for an invalid hour")
+	}
+
+	cases := map[string]struct {
+		v    interface{}
+		want interface{}
+	}{
+		"float64":     {v: map[string]interface{}{"hours": float64(9), "minutes": float64(30)}, want: "09:30"},
+		"json.Number": {v: map[string]interface{}{"hours": json.Number("23")}, want: "23:00"},
+		"midnight":    {v: map[string]interface{}{}, want: "00:00"},
+		"nil":         {v: nil, want: nil},
+	}
+	for tn, tc := range cases {
+		if got := flattenStartTimeOfDay(tc.v); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tn, tc.want, got)
+		}
+	}
+}
+
+func TestValidateMaintenanceWindowDay(t *testing.T) {
+	if _, errs := validateMaintenanceWindowDay("SUNDAY", "day"); len(errs) != 0 {
+		t.Errorf("expected SUNDAY to be valid, got %v", errs)
+	}
+	if _, errs := validateMaintenanceWindowDay("DAY_OF_WEEK_UNSPECIFIED", "day"); len(errs) == 0 {
+		t.Errorf("expected an unspecified day to be invalid")
+	}
+	if _, errs := validateMaintenanceWindowDayNumber(7, "day"); len(errs) != 0 {
+		t.Errorf("expected day 7 to be valid, got %v", errs)
+	}
+	if _, errs := validateMaintenanceWindowDayNumber(8, "day"); len(errs) == 0 {
+		t.Errorf("expected day 8 to be invalid")
+	}
+}
+
+func TestValidateRecurrence(t *testing.T) {
+	cases := map[string]bool{
+		"FREQ=DAILY":                  true,
+		"RRULE:FREQ=WEEKLY;BYDAY=SA":  true,
+		"FREQ=MONTHLY;BYDAY=1MO,-1SU": true,
+		"FREQ=WEEKLY;BYDAY=SA;":       true,
+		"BYDAY=SA":                    false,
+		"FREQ=WEEKLY;BYDAY":           false,
+		"FREQ=WEEKLY;DAY=SA":          false,
+		"FREQ=WEEKLY;FREQ=DAILY":      false,
+	}
+	for r, valid := range cases {
+		ws, errs := validateRecurrence(r, "recurrence")
+		if len(errs) != 0 {
+			t.Errorf("%q: expected recurrences never to be rejected, got %v", r, errs)
+		}
+		if (len(ws) == 0) != valid {
+			t.Errorf("%q: expected valid to be %t, got warnings %v", r, valid, ws)
+		}
+	}
+}
+
+func TestRecurrenceDiffSuppress(t *testing.T) {
+	cases := map[string]struct {
+		old, new string
+		suppress bool
+	}{
+		"every day":      {old: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR,SA,SU", new: "FREQ=DAILY", suppress: true},
+		"day order":      {old: "FREQ=WEEKLY;BYDAY=SU,SA", new: "freq=weekly;byday=SA,SU", suppress: true},
+		"part order":     {old: "BYDAY=SA;FREQ=WEEKLY", new: "RRULE:FREQ=WEEKLY;BYDAY=SA", suppress: true},
+		"interval":       {old: "FREQ=DAILY;INTERVAL=1", new: "FREQ=DAILY", suppress: true},
+		"other interval": {old: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR,SA,SU;INTERVAL=2", new: "FREQ=DAILY;INTERVAL=2"},
+		"other days":     {old: "FREQ=WEEKLY;BYDAY=SA", new: "FREQ=WEEKLY;BYDAY=SU"},
+	}
+	for tn, tc := range cases {
+		if got := recurrenceDiffSuppress("recurrence", tc.old, tc.new, nil); got != tc.suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, tc.suppress, got)
+		}
+	}
+}
+
+func TestRfc3339TimestampDiffSuppress(t *testing.T) {
+	if !rfc3339TimestampDiffSuppress("start_time", "2019-01-01T13:00:00Z", "2019-01-01T09:00:00-04:00", nil) {
+		t.Errorf("expected the same instant in different time zones to be suppressed")
+	}
+	if rfc3339TimestampDiffSuppress("start_time", "2019-01-01T13:00:00Z", "2019-01-01T13:00:00-04:00", nil) {
+		t.Errorf("expected different instants not to be suppressed")
+	}
+}
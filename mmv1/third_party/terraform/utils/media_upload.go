@@ -0,0 +1,137 @@
+package google
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"log"
+
+	"google.golang.org/api/googleapi"
+)
+
+// mediaUploadOptions configures a mediaUpload.
+type mediaUploadOptions struct {
+	// Name of the uploaded content, used in logs and errors
+	Name string
+	// Content larger than ChunkSize is uploaded with a resumable upload in
+	// chunks of this size, which are retried individually. 0 uses the client
+	// library default of 16MB; a negative value uploads the content in a
+	// single request.
+	ChunkSize int
+	// ContentType is detected from the content if empty
+	ContentType string
+	// MaxSize fails the upload once more than MaxSize bytes have been read.
+	// 0 means no limit.
+	MaxSize int64
+}
+
+// mediaUpload wraps content uploaded with a client library media call (eg
+// storage Objects.Insert) to log upload progress and check the checksums
+// reported by the API against the content that was read. Use it as:
+//
+//	upload := newMediaUpload(content, mediaUploadOptions{Name: name})
+//	call.Media(upload.Reader(), upload.MediaOptions()...)
+//	call.ProgressUpdater(upload.ProgressUpdater)
+//	res, err := call.Do()
+//	...
+//	err = upload.Verify(res.Crc32c, res.Md5Hash)
+type mediaUpload struct {
+	opts mediaUploadOptions
+
+	r      io.Reader
+	crc32c hash.Hash32
+	md5    hash.Hash
+	size   int64
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+func newMediaUpload(content io.Reader, opts mediaUploadOptions) *mediaUpload {
+	return &mediaUpload{
+		opts:   opts,
+		r:      content,
+		crc32c: crc32.New(crc32cTable),
+		md5:    md5.New(),
+	}
+}
+
+// Reader returns the reader to pass to the media call. Content read through it
+// is hashed and counted.
+func (u *mediaUpload) Reader() io.Reader {
+	return uploadReader{u}
+}
+
+// MediaOptions returns the options to pass to the media call.
+func (u *mediaUpload) MediaOptions() []googleapi.MediaOption {
+	var opts []googleapi.MediaOption
+	switch {
+	case u.opts.ChunkSize > 0:
+		opts = append(opts, googleapi.ChunkSize(u.opts.ChunkSize))
+	case u.opts.ChunkSize < 0:
+		opts = append(opts, googleapi.ChunkSize(0))
+	}
+	if u.opts.ContentType != "" {
+		opts = append(opts, googleapi.ContentType(u.opts.ContentType))
+	}
+	return opts
+}
+
+// ProgressUpdater logs the progress of resumable uploads. It's called by the
+// client library after each chunk; total is 0 if the size isn't known yet.
+func (u *mediaUpload) ProgressUpdater(current, total int64) {
+	if total > 0 {
+		log.Printf("[INFO] Uploaded %d of %d bytes of %s (%d%%)", current, total, u.opts.Name, current*100/total)
+		return
+	}
+	log.Printf("[INFO] Uploaded %d bytes of %s", current, u.opts.Name)
+}
+
+// Size returns the number of bytes read from the content so far.
+func (u *mediaUpload) Size() int64 {
+	return u.size
+}
+
+// Verify compares the base64 encoded checksums reported by the API for the
+// uploaded content with the checksums of the content that was read. Checksums
+// the API didn't report, eg the MD5 of composite objects, are skipped.
+func (u *mediaUpload) Verify(crc32c, md5Hash string) error {
+	if crc32c != "" {
+		sum := make([]byte, 4)
+		binary.BigEndian.PutUint32(sum, u.crc32c.Sum32())
+		if got := base64.StdEncoding.EncodeToString(sum); got != crc32c {
+			return fmt.Errorf("Error verifying upload of %s: the API reported a CRC32C checksum of %s but the uploaded content has a checksum of %s", u.opts.Name, crc32c, got)
+		}
+	}
+	if md5Hash != "" {
+		if got := base64.StdEncoding.EncodeToString(u.md5.Sum(nil)); got != md5Hash {
+			return fmt.Errorf("Error verifying upload of %s: the API reported an MD5 hash of %s but the uploaded content has a hash of %s", u.opts.Name, md5Hash, got)
+		}
+	}
+	log.Printf("[DEBUG] Verified checksums of the %d bytes uploaded for %s", u.size, u.opts.Name)
+	return nil
+}
+
+// uploadReader hashes and counts the content read through it. It's a separate
+// type so that mediaUpload itself isn't an io.Reader, which the client library
+// would otherwise accept in place of Reader() by mistake.
+type uploadReader struct {
+	u *mediaUpload
+}
+
+func (r uploadReader) Read(p []byte) (int, error) {
+	u := r.u
+	n, err := u.r.Read(p)
+	if n > 0 {
+		u.crc32c.Write(p[:n])
+		u.md5.Write(p[:n])
+		u.size += int64(n)
+		if u.opts.MaxSize > 0 && u.size > u.opts.MaxSize {
+			return n, fmt.Errorf("%s is larger than the maximum upload size of %d bytes", u.opts.Name, u.opts.MaxSize)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,107 @@
+package google
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func testMediaUploadChecksums(content string) (string, string) {
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.Checksum([]byte(content), crc32.MakeTable(crc32.Castagnoli)))
+	md5Sum := md5.Sum([]byte(content))
+	return base64.StdEncoding.EncodeToString(crc), base64.StdEncoding.EncodeToString(md5Sum[:])
+}
+
+func TestMediaUpload_verify(t *testing.T) {
+	content := "hello world"
+	crc32c, md5Hash := testMediaUploadChecksums(content)
+
+	cases := map[string]struct {
+		Crc32c, Md5Hash string
+		ExpectError     bool
+	}{
+		"matching checksums": {
+			Crc32c:  crc32c,
+			Md5Hash: md5Hash,
+		},
+		"no checksums reported": {},
+		"only crc32c reported": {
+			Crc32c: crc32c,
+		},
+		"crc32c mismatch": {
+			Crc32c:      "AAAAAA==",
+			Md5Hash:     md5Hash,
+			ExpectError: true,
+		},
+		"md5 mismatch": {
+			Crc32c:      crc32c,
+			Md5Hash:     "1B2M2Y8AsgTpgAmY7PhCfg==",
+			ExpectError: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		upload := newMediaUpload(strings.NewReader(content), mediaUploadOptions{Name: "object foo"})
+		if _, err := ioutil.ReadAll(upload.Reader()); err != nil {
+			t.Fatalf("%s: unexpected error reading content: %s", tn, err)
+		}
+		if upload.Size() != int64(len(content)) {
+			t.Errorf("%s: expected %d bytes to be read, got %d", tn, len(content), upload.Size())
+		}
+		err := upload.Verify(tc.Crc32c, tc.Md5Hash)
+		if tc.ExpectError && err == nil {
+			t.Errorf("%s: expected an error", tn)
+		}
+		if !tc.ExpectError && err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+		}
+	}
+}
+
+func TestMediaUpload_maxSize(t *testing.T) {
+	upload := newMediaUpload(strings.NewReader("hello world"), mediaUploadOptions{Name: "object foo", MaxSize: 5})
+	_, err := ioutil.ReadAll(upload.Reader())
+	if err == nil || !strings.Contains(err.Error(), "maximum upload size of 5 bytes") {
+		t.Errorf("expected an error reading more than the maximum size, got %v", err)
+	}
+
+	upload = newMediaUpload(strings.NewReader("hello"), mediaUploadOptions{Name: "object foo", MaxSize: 5})
+	if _, err := ioutil.ReadAll(upload.Reader()); err != nil {
+		t.Errorf("unexpected error reading exactly the maximum size: %s", err)
+	}
+}
+
+func TestMediaUpload_mediaOptions(t *testing.T) {
+	cases := map[string]struct {
+		Opts     mediaUploadOptions
+		Expected int
+	}{
+		"defaults": {
+			Expected: 0,
+		},
+		"chunk size": {
+			Opts:     mediaUploadOptions{ChunkSize: 8 << 20},
+			Expected: 1,
+		},
+		"single request": {
+			Opts:     mediaUploadOptions{ChunkSize: -1},
+			Expected: 1,
+		},
+		"chunk size and content type": {
+			Opts:     mediaUploadOptions{ChunkSize: 8 << 20, ContentType: "text/plain"},
+			Expected: 2,
+		},
+	}
+
+	for tn, tc := range cases {
+		upload := newMediaUpload(strings.NewReader(""), tc.Opts)
+		if got := len(upload.MediaOptions()); got != tc.Expected {
+			t.Errorf("%s: expected %d media options, got %d", tn, tc.Expected, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package google
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+const (
+	// Overrides the metadata server host, matching the Google client libraries.
+	metadataHostEnvVar = "GCE_METADATA_HOST"
+	// Escape hatch for environments where the metadata server is unreachable
+	// and waiting for lookups to time out is undesirable.
+	disableMetadataLookupEnvVar = "GOOGLE_DISABLE_METADATA_LOOKUP"
+
+	defaultMetadataHost     = "169.254.169.254"
+	metadataRequestTimeout  = 2 * time.Second
+	metadataRequestAttempts = 3
+)
+
+// metadataClient reads values from the GCE/GKE instance metadata server. ADC
+// users running on Google infrastructure rely on it to discover their
+// project, zone and service account; lookups use short timeouts, are retried
+// a few times, and their results are cached for the life of the provider.
+// Unless GCE_METADATA_HOST points at a server, no lookups are made when the
+// provider isn't running on GCE, so other users don't wait on timeouts.
+type metadataClient struct {
+	host     string
+	client   *http.Client
+	disabled bool
+	// onGCE reports whether the provider runs on GCE, and is only asked when
+	// the default host is used.
+	onGCE func() bool
+	// clock is the clock retries wait on, the real clock if unset
+	clock Clock
+
+	// checkOnGCE guards the GCE detection, which is made once.
+	checkOnGCE sync.Once
+
+	// mu guards the fields below. It isn't held during requests, so slow
+	// lookups don't block reads of cached values.
+	mu    sync.Mutex
+	cache map[string]string
+	// set once the server is known to be unreachable so we don't wait on
+	// timeouts for every lookup
+	unavailable bool
+}
+
+func newMetadataClient() *metadataClient {
+	host := os.Getenv(metadataHostEnvVar)
+	if host == "" {
+		host = defaultMetadataHost
+	}
+
+	disabled, _ := strconv.ParseBool(os.Getenv(disableMetadataLookupEnvVar))
+
+	return &metadataClient{
+		host:     host,
+		client:   &http.Client{Timeout: metadataRequestTimeout},
+		disabled: disabled,
+		onGCE:    metadata.OnGCE,
+		cache:    make(map[string]string),
+	}
+}
+
+// ProjectID returns the project ID of the project the instance runs in.
+func (m *metadataClient) ProjectID() (string, error) {
+	return m.get("project/project-id")
+}
+
+// Zone returns the zone the instance runs in.
+func (m *metadataClient) Zone() (string, error) {
+	zone, err := m.get("instance/zone")
+	if err != nil {
+		return "", err
+	}
+	// Returned as projects/{project_number}/zones/{zone}
+	return GetResourceNameFromSelfLink(zone), nil
+}
+
+// ServiceAccountEmail returns the email of the instance's default service
+// account.
+func (m *metadataClient) ServiceAccountEmail() (string, error) {
+	return m.get("instance/service-accounts/default/email")
+}
+
+func (m *metadataClient) get(suffix string) (string, error) {
+	if m.disabled {
+		return "", fmt.Errorf("metadata lookup disabled by %s", disableMetadataLookupEnvVar)
+	}
+
+	m.checkOnGCE.Do(func() {
+		if m.host == defaultMetadataHost && !m.onGCE() {
+			log.Printf("[DEBUG] Not running on GCE, skipping metadata server lookups")
+			m.setUnavailable()
+		}
+	})
+
+	m.mu.Lock()
+	v, ok := m.cache[suffix]
+	unavailable := m.unavailable
+	m.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+	if unavailable {
+		return "", fmt.Errorf("metadata server at %s is unavailable", m.host)
+	}
+
+	url := fmt.Sprintf("http://%s/computeMetadata/v1/%s", m.host, suffix)
+	clock := m.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < metadataRequestAttempts; attempt++ {
+		if attempt > 0 {
+			clock.Sleep(time.Duration(attempt) * 250 * time.Millisecond)
+		}
+
+		v, retryable, err := m.doGet(url)
+		if err == nil {
+			m.mu.Lock()
+			m.cache[suffix] = v
+			m.mu.Unlock()
+			return v, nil
+		}
+		lastErr = err
+		if !retryable {
+			return "", err
+		}
+		log.Printf("[DEBUG] Retrying metadata server request for %q: %s", suffix, err)
+	}
+
+	m.setUnavailable()
+	return "", fmt.Errorf("error reading %q from metadata server: %s", suffix, lastErr)
+}
+
+func (m *metadataClient) setUnavailable() {
+	m.mu.Lock()
+	m.unavailable = true
+	m.mu.Unlock()
+}
+
+// doGet returns the value read, or an error and whether it can be retried.
+func (m *metadataClient) doGet(url string) (string, bool, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return "", true, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", true, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return strings.TrimSpace(string(body)), false, nil
+	case res.StatusCode == http.StatusNotFound:
+		return "", false, fmt.Errorf("%s not found on metadata server", url)
+	case res.StatusCode >= 500:
+		return "", true, fmt.Errorf("metadata server returned status %d", res.StatusCode)
+	}
+	return "", false, fmt.Errorf("metadata server returned status %d", res.StatusCode)
+}
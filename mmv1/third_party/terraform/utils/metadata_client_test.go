@@ -0,0 +1,151 @@
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMetadataClient(t *testing.T, h http.HandlerFunc) (*metadataClient, *httptest.Server) {
+	ts := httptest.NewServer(h)
+	m := newMetadataClient()
+	m.host = strings.TrimPrefix(ts.URL, "http://")
+	m.disabled = false
+	return m, ts
+}
+
+func TestMetadataClient_cachesAndRetries(t *testing.T) {
+	calls := 0
+	m, ts := newTestMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			fmt.Fprint(w, "my-project")
+		case "/computeMetadata/v1/instance/zone":
+			fmt.Fprint(w, "projects/123456/zones/us-central1-a")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		project, err := m.ProjectID()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if project != "my-project" {
+			t.Errorf("expected project my-project, got %q", project)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls (one retry, then cached), got %d", calls)
+	}
+
+	zone, err := m.Zone()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if zone != "us-central1-a" {
+		t.Errorf("expected zone us-central1-a, got %q", zone)
+	}
+
+	if _, err := m.ServiceAccountEmail(); err == nil {
+		t.Errorf("expected error for missing metadata value")
+	}
+}
+
+func TestMetadataClient_disabled(t *testing.T) {
+	m, ts := newTestMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to metadata server: %s", r.URL)
+	})
+	defer ts.Close()
+	m.disabled = true
+
+	if _, err := m.ProjectID(); err == nil {
+		t.Errorf("expected error when metadata lookup is disabled")
+	}
+}
+
+func TestMetadataClient_notOnGCE(t *testing.T) {
+	m := newMetadataClient()
+	m.host = defaultMetadataHost
+	m.disabled = false
+	checks := 0
+	m.onGCE = func() bool {
+		checks++
+		return false
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.ProjectID(); err == nil {
+			t.Errorf("expected error when not running on GCE")
+		}
+	}
+	if checks != 1 {
+		t.Errorf("expected GCE detection to run once, ran %d times", checks)
+	}
+}
+
+func TestMetadataClient_hostOverrideSkipsGCEDetection(t *testing.T) {
+	m, ts := newTestMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "my-project")
+	})
+	defer ts.Close()
+	m.onGCE = func() bool {
+		t.Errorf("unexpected GCE detection with an overridden host")
+		return false
+	}
+
+	if project, err := m.ProjectID(); err != nil || project != "my-project" {
+		t.Errorf("expected project my-project, got %q, %v", project, err)
+	}
+}
+
+func TestMetadataClient_lookupsDontBlockCachedValues(t *testing.T) {
+	zoneRequested := make(chan struct{})
+	release := make(chan struct{})
+	m, ts := newTestMetadataClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			fmt.Fprint(w, "my-project")
+		case "/computeMetadata/v1/instance/zone":
+			close(zoneRequested)
+			<-release
+			fmt.Fprint(w, "projects/123456/zones/us-central1-a")
+		}
+	})
+	defer ts.Close()
+	defer close(release)
+
+	if _, err := m.ProjectID(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	go m.Zone()
+	<-zoneRequested
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if project, err := m.ProjectID(); err != nil || project != "my-project" {
+			t.Errorf("expected project my-project, got %q, %v", project, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("cached lookup blocked on an in-flight request")
+	}
+}
@@ -2,8 +2,11 @@ package google
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"log"
 	"net/url"
+	"reflect"
 	"strings"
 
 	"google.golang.org/api/option/internaloption"
@@ -44,3 +47,53 @@ func getMtlsEndpoint(baseEndpoint string) string {
 	}
 	return u.String()
 }
+
+// clientTLSConfig returns the TLS configuration presenting the client
+// certificate configured with client_certificate and client_private_key, or
+// nil if none is configured. Both may be a path or the PEM encoded contents.
+func (c *Config) clientTLSConfig() (*tls.Config, error) {
+	if c.ClientCertificate == "" && c.ClientPrivateKey == "" {
+		return nil, nil
+	}
+	if c.ClientCertificate == "" || c.ClientPrivateKey == "" {
+		// Either may come from the environment, so they're only checked
+		// together once resolved
+		return nil, fmt.Errorf("client_certificate and client_private_key, or GOOGLE_CLIENT_CERTIFICATE and GOOGLE_CLIENT_PRIVATE_KEY, must be set together")
+	}
+
+	cert, _, err := pathOrContents(c.ClientCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading client_certificate: %s", err)
+	}
+	key, _, err := pathOrContents(c.ClientPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading client_private_key: %s", err)
+	}
+	pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing client certificate: %s", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// useMtlsEndpoints switches every base path left at its default to the
+// service's mTLS endpoint, as Google client libraries do when a client
+// certificate is available. Custom endpoints are left as configured.
+func (c *Config) useMtlsEndpoints() {
+	v := reflect.ValueOf(c).Elem()
+	for key, bp := range DefaultBasePaths {
+		f := v.FieldByName(key + "BasePath")
+		if !f.IsValid() || f.Kind() != reflect.String || !f.CanSet() {
+			continue
+		}
+		if f.String() == bp && !strings.Contains(bp, ".mtls.") {
+			mtls := getMtlsEndpoint(bp)
+			log.Printf("[DEBUG] Using mTLS endpoint %s for %s", mtls, key)
+			f.SetString(mtls)
+		}
+	}
+}
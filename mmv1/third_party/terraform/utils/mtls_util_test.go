@@ -1,8 +1,18 @@
 package google
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestUnitMtls_urlSwitching(t *testing.T) {
@@ -14,3 +24,103 @@ func TestUnitMtls_urlSwitching(t *testing.T) {
 		}
 	}
 }
+
+// testClientCertificate returns a PEM encoded self-signed certificate and its
+// private key.
+func testClientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error generating a key: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error creating a certificate: %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error encoding the key: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestUnitMtls_clientTLSConfig(t *testing.T) {
+	cert, key := testClientCertificate(t)
+	_, otherKey := testClientCertificate(t)
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := ioutil.WriteFile(keyPath, []byte(key), 0600); err != nil {
+		t.Fatalf("unexpected error writing the key: %s", err)
+	}
+
+	cases := map[string]struct {
+		Certificate, PrivateKey string
+		ExpectConfig            bool
+		ExpectError             string
+	}{
+		"unset": {},
+		"contents": {
+			Certificate:  cert,
+			PrivateKey:   key,
+			ExpectConfig: true,
+		},
+		"path": {
+			Certificate:  cert,
+			PrivateKey:   keyPath,
+			ExpectConfig: true,
+		},
+		"certificate only": {
+			Certificate: cert,
+			ExpectError: "must be set together",
+		},
+		"private key only": {
+			PrivateKey:  key,
+			ExpectError: "must be set together",
+		},
+		"mismatched key": {
+			Certificate: cert,
+			PrivateKey:  otherKey,
+			ExpectError: "Error parsing client certificate",
+		},
+	}
+
+	for tn, tc := range cases {
+		c := &Config{ClientCertificate: tc.Certificate, ClientPrivateKey: tc.PrivateKey}
+		tlsConfig, err := c.clientTLSConfig()
+		if tc.ExpectError != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectError) {
+				t.Errorf("%s: expected an error containing %q, got %v", tn, tc.ExpectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if got := tlsConfig != nil && len(tlsConfig.Certificates) == 1; got != tc.ExpectConfig {
+			t.Errorf("%s: expected a TLS config with the certificate to be %t, got %v", tn, tc.ExpectConfig, tlsConfig)
+		}
+	}
+}
+
+func TestUnitMtls_useMtlsEndpoints(t *testing.T) {
+	custom := "https://compute.example.com/compute/v1/"
+	c := &Config{
+		ComputeBasePath: DefaultBasePaths[ComputeBasePathKey],
+		StorageBasePath: custom,
+	}
+	c.useMtlsEndpoints()
+
+	if expected := getMtlsEndpoint(DefaultBasePaths[ComputeBasePathKey]); c.ComputeBasePath != expected {
+		t.Errorf("expected the default endpoint to be switched to %s, got %s", expected, c.ComputeBasePath)
+	}
+	if c.StorageBasePath != custom {
+		t.Errorf("expected the custom endpoint to be left as %s, got %s", custom, c.StorageBasePath)
+	}
+}
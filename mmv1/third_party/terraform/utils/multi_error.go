@@ -0,0 +1,55 @@
+package google
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// MultiError collects the errors from an operation made up of several
+// sub-requests (eg each child of a fine-grained resource, or each modifier in
+// a batched IAM change) so that all failures are reported together rather
+// than only the first. Each error is recorded with the identifier of the
+// resource it applies to.
+//
+// MultiError implements errwrap.Wrapper, so helpers such as isRetryableError
+// see the individual errors. isGoogleApiErrorWithCode only matches when every
+// error has the code, so a partial failure isn't taken for a 404.
+type MultiError struct {
+	Errors []error
+}
+
+// Add records err for the resource identified by id. nil errors are ignored.
+func (m *MultiError) Add(id string, err error) {
+	if err == nil {
+		return
+	}
+	m.Errors = append(m.Errors, errwrap.Wrapf(fmt.Sprintf("%s: {{err}}", id), err))
+}
+
+// ErrorOrNil returns nil if no errors were added, so the result of a loop of
+// sub-requests can be returned directly.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) Error() string {
+	if len(m.Errors) == 1 {
+		return m.Errors[0].Error()
+	}
+
+	msgs := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		msgs[i] = fmt.Sprintf("* %s", err)
+	}
+	return fmt.Sprintf("%d errors occurred:\n%s", len(m.Errors), strings.Join(msgs, "\n"))
+}
+
+// WrappedErrors implements errwrap.Wrapper.
+func (m *MultiError) WrappedErrors() []error {
+	return m.Errors
+}
@@ -0,0 +1,47 @@
+package google
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+func TestMultiError(t *testing.T) {
+	errs := &MultiError{}
+	if errs.ErrorOrNil() != nil {
+		t.Fatalf("expected nil error with no errors added")
+	}
+
+	errs.Add("projects/foo", nil)
+	errs.Add("projects/bar", errors.New("something went wrong"))
+	errs.Add("projects/baz", &googleapi.Error{Code: 404, Message: "not found"})
+
+	err := errs.ErrorOrNil()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(errs.Errors) != 2 {
+		t.Errorf("expected 2 errors, got %d", len(errs.Errors))
+	}
+
+	msg := err.Error()
+	for _, want := range []string{"2 errors occurred", "projects/bar: something went wrong", "projects/baz: googleapi: Error 404"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error message to contain %q, got %q", want, msg)
+		}
+	}
+
+	if isGoogleApiErrorWithCode(err, 404) {
+		t.Errorf("expected MultiError with a single 404 not to be a 404")
+	}
+
+	notFound := &MultiError{}
+	notFound.Add("projects/foo", &googleapi.Error{Code: 404, Message: "not found"})
+	notFound.Add("projects/bar", &googleapi.Error{Code: 404, Message: "not found"})
+	if !isGoogleApiErrorWithCode(errwrap.Wrapf("Error deleting: {{err}}", notFound.ErrorOrNil()), 404) {
+		t.Errorf("expected wrapped MultiError of 404s to be a 404")
+	}
+}
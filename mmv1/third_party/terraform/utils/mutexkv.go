@@ -1,8 +1,11 @@
 package google
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 )
 
 // MutexKV is a simple key/value store for arbitrary mutexes. It can be used to
@@ -13,39 +16,110 @@ import (
 // their access to individual security groups based on SG ID.
 type MutexKV struct {
 	lock  sync.Mutex
-	store map[string]*sync.Mutex
+	store map[string]*kvMutex
 }
 
+// kvMutex is a mutex that can be waited on with a context. acquired and
+// waited are only accessed by the holder of the mutex.
+type kvMutex struct {
+	held     chan struct{}
+	acquired time.Time
+	waited   time.Duration
+
+	// refs counts the holder and waiters of the mutex, which is removed from
+	// the store once there are none. It's guarded by MutexKV.lock.
+	refs int
+}
+
+// Waiting longer than this for a lock is logged, as it usually means a long
+// chain of resources serializing on the same key, eg many router interfaces.
+var mutexKVSlowWaitThreshold = time.Minute
+
 // Locks the mutex for the given key. Caller is responsible for calling Unlock
 // for the same key
 func (m *MutexKV) Lock(key string) {
+	// context.Background is never done, so the mutex is always locked
+	_ = m.LockContext(context.Background(), key)
+}
+
+// LockContext locks the mutex for the given key, or returns an error if ctx is
+// done first. Caller is responsible for calling Unlock for the same key if no
+// error is returned.
+func (m *MutexKV) LockContext(ctx context.Context, key string) error {
 	log.Printf("[DEBUG] Locking %q", key)
-	m.get(key).Lock()
-	log.Printf("[DEBUG] Locked %q", key)
+	mutex := m.acquire(key)
+
+	start := time.Now()
+	slow := time.AfterFunc(mutexKVSlowWaitThreshold, func() {
+		log.Printf("[WARN] Waited over %s for %q, which is held by another resource", mutexKVSlowWaitThreshold, key)
+	})
+	defer slow.Stop()
+
+	select {
+	case mutex.held <- struct{}{}:
+	case <-ctx.Done():
+		m.release(key, mutex)
+		waited := time.Since(start)
+		log.Printf("[DEBUG] Gave up locking %q after %s: %s", key, waited, ctx.Err())
+		return fmt.Errorf("Error waiting %s to lock %q: %s", waited.Round(time.Second), key, ctx.Err())
+	}
+
+	mutex.acquired = time.Now()
+	mutex.waited = mutex.acquired.Sub(start)
+	log.Printf("[DEBUG] Locked %q after waiting %s", key, mutex.waited)
+	return nil
 }
 
 // Unlock the mutex for the given key. Caller must have called Lock for the same key first
 func (m *MutexKV) Unlock(key string) {
 	log.Printf("[DEBUG] Unlocking %q", key)
-	m.get(key).Unlock()
-	log.Printf("[DEBUG] Unlocked %q", key)
+	m.lock.Lock()
+	mutex, ok := m.store[key]
+	m.lock.Unlock()
+	if !ok {
+		panic(fmt.Sprintf("unlock of unlocked mutex %q", key))
+	}
+	acquired, waited := mutex.acquired, mutex.waited
+	select {
+	case <-mutex.held:
+	default:
+		panic(fmt.Sprintf("unlock of unlocked mutex %q", key))
+	}
+	m.release(key, mutex)
+	held := time.Since(acquired)
+	log.Printf("[DEBUG] Unlocked %q after holding it for %s", key, held)
+	currentApplyReport().recordLock(key, acquired.Add(-waited), waited, held)
 }
 
-// Returns a mutex for the given key, no guarantee of its lock status
-func (m *MutexKV) get(key string) *sync.Mutex {
+// acquire returns the mutex for the given key, creating it if needed, and
+// counts the caller as one of its holders or waiters until release is
+// called. No guarantee of its lock status.
+func (m *MutexKV) acquire(key string) *kvMutex {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	mutex, ok := m.store[key]
 	if !ok {
-		mutex = &sync.Mutex{}
+		mutex = &kvMutex{held: make(chan struct{}, 1)}
 		m.store[key] = mutex
 	}
+	mutex.refs++
 	return mutex
 }
 
+// release undoes acquire, removing the mutex for key once it has no holder or
+// waiters so the store doesn't grow with every key ever locked.
+func (m *MutexKV) release(key string, mutex *kvMutex) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	mutex.refs--
+	if mutex.refs == 0 {
+		delete(m.store, key)
+	}
+}
+
 // Returns a properly initialized MutexKV
 func NewMutexKV() *MutexKV {
 	return &MutexKV{
-		store: make(map[string]*sync.Mutex),
+		store: make(map[string]*kvMutex),
 	}
 }
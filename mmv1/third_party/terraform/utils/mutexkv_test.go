@@ -0,0 +1,92 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMutexKV_lockUnlock(t *testing.T) {
+	m := NewMutexKV()
+	m.Lock("a")
+	// Keys are independent
+	m.Lock("b")
+	m.Unlock("a")
+	m.Lock("a")
+	m.Unlock("a")
+	m.Unlock("b")
+
+	if len(m.store) != 0 {
+		t.Errorf("expected unlocked mutexes to be removed, got %d left", len(m.store))
+	}
+}
+
+func TestMutexKV_contention(t *testing.T) {
+	m := NewMutexKV()
+	var holders, maxHolders int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i%2)
+			m.Lock(key)
+			defer m.Unlock(key)
+			// Only holders of key-0 are counted
+			if key != "key-0" {
+				return
+			}
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				max := atomic.LoadInt32(&maxHolders)
+				if n <= max || atomic.CompareAndSwapInt32(&maxHolders, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+		}(i)
+	}
+	wg.Wait()
+
+	if maxHolders != 1 {
+		t.Errorf("expected the lock to be held by 1 goroutine at a time, got %d", maxHolders)
+	}
+	if len(m.store) != 0 {
+		t.Errorf("expected unlocked mutexes to be removed, got %d left", len(m.store))
+	}
+}
+
+func TestMutexKV_lockContext(t *testing.T) {
+	m := NewMutexKV()
+	m.Lock("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.LockContext(ctx, "a"); err == nil {
+		t.Fatalf("expected an error locking a held mutex once the context is done")
+	}
+
+	// The waiter that gave up doesn't keep the mutex, or hold it
+	m.Unlock("a")
+	if len(m.store) != 0 {
+		t.Errorf("expected unlocked mutexes to be removed, got %d left", len(m.store))
+	}
+	if err := m.LockContext(context.Background(), "a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m.Unlock("a")
+}
+
+func TestMutexKV_unlockUnlocked(t *testing.T) {
+	m := NewMutexKV()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected unlocking an unlocked mutex to panic")
+		}
+	}()
+	m.Unlock("a")
+}
@@ -0,0 +1,143 @@
+package google
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a URL is remembered as not found. It only needs to outlive the
+// reads made while Terraform walks a single graph, eg the children of a
+// deleted parent being refreshed one after another.
+const notFoundCacheTTL = 30 * time.Second
+
+// notFoundCache remembers URLs of resources handleNotFoundError removed from
+// state because they returned a 404, so repeated GETs of the same URL fail
+// immediately instead of making (and possibly retrying) another request.
+//
+// Entries only come from handleNotFoundError, not from every 404, so reads
+// retried while waiting for a new resource to become visible aren't affected.
+// notFoundCacheTransport keeps the cache from returning stale 404s: any
+// successful request that may create resources clears it, and a successful
+// read of a URL forgets it. URLs are compared without their query.
+type notFoundCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func newNotFoundCache(ttl time.Duration, clock Clock) *notFoundCache {
+	return &notFoundCache{
+		ttl:     ttl,
+		clock:   clock,
+		expires: make(map[string]time.Time),
+	}
+}
+
+func notFoundCacheKey(url string) string {
+	if i := strings.Index(url, "?"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
+
+func (c *notFoundCache) add(url string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[notFoundCacheKey(url)] = c.clock.Now().Add(c.ttl)
+}
+
+func (c *notFoundCache) remove(url string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, notFoundCacheKey(url))
+}
+
+func (c *notFoundCache) contains(url string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := notFoundCacheKey(url)
+	exp, ok := c.expires[key]
+	if ok && !c.clock.Now().Before(exp) {
+		delete(c.expires, key)
+		return false
+	}
+	return ok
+}
+
+func (c *notFoundCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires = make(map[string]time.Time)
+}
+
+// notFoundCacheTransport keeps cache up to date with the requests sent
+// through it, by sendRequest or by client libraries.
+type notFoundCacheTransport struct {
+	cache    *notFoundCache
+	internal http.RoundTripper
+}
+
+func (t *notFoundCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.internal.RoundTrip(req)
+	if err != nil || res.StatusCode >= 400 {
+		return res, err
+	}
+	switch req.Method {
+	case "GET":
+		t.cache.remove(req.URL.String())
+	case "DELETE":
+	default:
+		// The request may have created a resource that was recently not found
+		t.cache.clear()
+	}
+	return res, err
+}
+
+// notFoundURLError wraps a 404 returned by sendRequest with the URL that
+// returned it, so handleNotFoundError can remember the URL. It's transparent
+// to isGoogleApiErrorWithCode and to the error message.
+type notFoundURLError struct {
+	url   string
+	cache *notFoundCache
+	err   error
+}
+
+func (e *notFoundURLError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notFoundURLError) Unwrap() error {
+	return e.err
+}
+
+// WrappedErrors lets errwrap.GetType, used by isGoogleApiErrorWithCode, find
+// the wrapped googleapi.Error.
+func (e *notFoundURLError) WrappedErrors() []error {
+	return []error{e.err}
+}
+
+// rememberNotFound records the URL of err, if it came from sendRequest, as
+// not found.
+func rememberNotFound(err error) {
+	var nf *notFoundURLError
+	if errors.As(err, &nf) {
+		nf.cache.add(nf.url)
+	}
+}
@@ -0,0 +1,106 @@
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestNotFoundCache(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	c := newNotFoundCache(30*time.Second, clock)
+
+	url := "https://compute.googleapis.com/compute/v1/projects/p/global/networks/n"
+	if c.contains(url) {
+		t.Fatalf("expected empty cache not to contain %s", url)
+	}
+
+	c.add(url)
+	if !c.contains(url) {
+		t.Errorf("expected cache to contain %s", url)
+	}
+
+	clock.Sleep(30 * time.Second)
+	if c.contains(url) {
+		t.Errorf("expected %s to have expired", url)
+	}
+
+	c.add(url)
+	c.clear()
+	if c.contains(url) {
+		t.Errorf("expected cleared cache not to contain %s", url)
+	}
+
+	// A nil cache never contains anything
+	var nilCache *notFoundCache
+	nilCache.add(url)
+	if nilCache.contains(url) {
+		t.Errorf("expected nil cache not to contain %s", url)
+	}
+}
+
+func TestRememberNotFound(t *testing.T) {
+	c := newNotFoundCache(30*time.Second, systemClock{})
+	url := "https://compute.googleapis.com/compute/v1/projects/p/global/networks/n"
+	err := &notFoundURLError{url: url, cache: c, err: &googleapi.Error{Code: 404}}
+
+	if !isGoogleApiErrorWithCode(err, 404) {
+		t.Errorf("expected wrapped error to be a 404")
+	}
+
+	rememberNotFound(fmt.Errorf("Error reading network: %w", err))
+	if !c.contains(url) {
+		t.Errorf("expected cache to contain %s", url)
+	}
+
+	// Errors that didn't come from sendRequest are ignored
+	rememberNotFound(&googleapi.Error{Code: 404})
+}
+
+type testStatusRoundTripper struct {
+	status int
+}
+
+func (rt *testStatusRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: rt.status, Request: req}, nil
+}
+
+// Check that requests that may have recreated a resource invalidate the cache
+func TestNotFoundCacheTransport(t *testing.T) {
+	url := "https://compute.googleapis.com/compute/v1/projects/p/global/networks/n"
+	cases := map[string]struct {
+		method    string
+		url       string
+		status    int
+		contained bool
+	}{
+		"successful GET":       {method: "GET", url: url + "?alt=json", status: 200, contained: false},
+		"successful GET other": {method: "GET", url: url + "2", status: 200, contained: true},
+		"failed GET":           {method: "GET", url: url, status: 404, contained: true},
+		"successful POST":      {method: "POST", url: url + "2", status: 200, contained: false},
+		"failed POST":          {method: "POST", url: url, status: 409, contained: true},
+		"successful DELETE":    {method: "DELETE", url: url + "2", status: 200, contained: true},
+		"successful PUT":       {method: "PUT", url: url, status: 200, contained: false},
+	}
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			c := newNotFoundCache(30*time.Second, systemClock{})
+			c.add(url)
+			transport := &notFoundCacheTransport{cache: c, internal: &testStatusRoundTripper{status: tc.status}}
+
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.contains(url); got != tc.contained {
+				t.Errorf("expected cache to contain %s: %t, got %t", url, tc.contained, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// operationMetadata is the context shared by most google.longrunning
+// operation metadata messages. Each service defines its own message, packed
+// into operation.metadata as an Any, so the fields are decoded per @type.
+type operationMetadata struct {
+	CreateTime      string
+	Target          string
+	Verb            string
+	ProgressPercent *int
+	StatusMessage   string
+}
+
+// operationMetadataDecoder decodes the JSON form of an operation metadata
+// message.
+type operationMetadataDecoder func(m map[string]interface{}) operationMetadata
+
+// operationMetadataDecoders holds decoders for metadata messages that don't
+// follow the standard OperationMetadata shape (createTime, target, verb,
+// ...), keyed by @type. Anything else uses decodeStandardOperationMetadata.
+var operationMetadataDecoders = map[string]operationMetadataDecoder{
+	"type.googleapis.com/google.cloud.dataproc.v1.ClusterOperationMetadata": func(m map[string]interface{}) operationMetadata {
+		md := operationMetadata{
+			Target:        metadataString(m, "clusterName"),
+			Verb:          strings.ToLower(metadataString(m, "operationType")),
+			StatusMessage: metadataString(m, "description"),
+		}
+		if status, ok := m["status"].(map[string]interface{}); ok {
+			md.CreateTime = metadataString(status, "stateStartTime")
+			if detail := metadataString(status, "detail"); detail != "" {
+				md.StatusMessage = detail
+			}
+		}
+		return md
+	},
+	"type.googleapis.com/google.spanner.admin.instance.v1.CreateInstanceMetadata": decodeSpannerInstanceMetadata("create"),
+	"type.googleapis.com/google.spanner.admin.instance.v1.UpdateInstanceMetadata": decodeSpannerInstanceMetadata("update"),
+}
+
+func decodeSpannerInstanceMetadata(verb string) operationMetadataDecoder {
+	return func(m map[string]interface{}) operationMetadata {
+		md := operationMetadata{
+			CreateTime: metadataString(m, "startTime"),
+			Verb:       verb,
+		}
+		if instance, ok := m["instance"].(map[string]interface{}); ok {
+			md.Target = metadataString(instance, "name")
+		}
+		return md
+	}
+}
+
+// decodeStandardOperationMetadata decodes metadata following the
+// OperationMetadata message most services use, accepting the field names
+// commonly used for the same information.
+func decodeStandardOperationMetadata(m map[string]interface{}) operationMetadata {
+	md := operationMetadata{
+		CreateTime:    metadataString(m, "createTime", "startTime"),
+		Target:        metadataString(m, "target", "resourceName", "resource"),
+		Verb:          metadataString(m, "verb", "operationType"),
+		StatusMessage: metadataString(m, "statusMessage", "statusDetail"),
+	}
+	for _, k := range []string{"progressPercent", "progressPercentage"} {
+		if p, ok := metadataInt(m, k); ok {
+			md.ProgressPercent = &p
+			break
+		}
+	}
+	return md
+}
+
+// decodeOperationMetadata decodes the metadata of an operation. It returns
+// nil if the operation has no metadata or it can't be decoded.
+func decodeOperationMetadata(raw []byte) *operationMetadata {
+	if len(raw) == 0 {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil || len(m) == 0 {
+		return nil
+	}
+
+	decode, ok := operationMetadataDecoders[metadataString(m, "@type")]
+	if !ok {
+		decode = decodeStandardOperationMetadata
+	}
+	md := decode(m)
+	return &md
+}
+
+// String describes the operation, eg "create projects/p/instances/i, 40% complete, started at 2021-06-01T00:00:00Z".
+func (md *operationMetadata) String() string {
+	if md == nil {
+		return ""
+	}
+
+	var parts []string
+	action := strings.TrimSpace(strings.Join([]string{md.Verb, md.Target}, " "))
+	if action != "" {
+		parts = append(parts, action)
+	}
+	if md.ProgressPercent != nil {
+		parts = append(parts, fmt.Sprintf("%d%% complete", *md.ProgressPercent))
+	}
+	if md.StatusMessage != "" {
+		parts = append(parts, md.StatusMessage)
+	}
+	if md.CreateTime != "" {
+		parts = append(parts, fmt.Sprintf("started at %s", md.CreateTime))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// metadataString returns the first of keys set to a non-empty string in m.
+func metadataString(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// metadataInt reads an integer field of m. Integers may be encoded as JSON
+// numbers or, for 64-bit fields, as strings.
+func metadataInt(m map[string]interface{}, k string) (int, bool) {
+	switch v := m[k].(type) {
+	case float64:
+		return int(v), true
+	case string:
+		var i int
+		if _, err := fmt.Sscanf(v, "%d", &i); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package google
+
+import (
+	"testing"
+)
+
+func TestDecodeOperationMetadata(t *testing.T) {
+	cases := map[string]struct {
+		Metadata string
+		Expected string
+	}{
+		"standard metadata": {
+			Metadata: `{
+				"@type": "type.googleapis.com/google.cloud.redis.v1.OperationMetadata",
+				"createTime": "2021-06-01T00:00:00Z",
+				"target": "projects/p/locations/us-central1/instances/i",
+				"verb": "create",
+				"statusDetail": "Creating instance"
+			}`,
+			Expected: "create projects/p/locations/us-central1/instances/i, Creating instance, started at 2021-06-01T00:00:00Z",
+		},
+		"progress as a string": {
+			Metadata: `{"verb": "update", "target": "t", "progressPercent": "40"}`,
+			Expected: "update t, 40% complete",
+		},
+		"progress as a number": {
+			Metadata: `{"progressPercentage": 75}`,
+			Expected: "75% complete",
+		},
+		"dataproc cluster": {
+			Metadata: `{
+				"@type": "type.googleapis.com/google.cloud.dataproc.v1.ClusterOperationMetadata",
+				"clusterName": "my-cluster",
+				"operationType": "CREATE",
+				"description": "Create cluster with 2 workers",
+				"status": {"state": "RUNNING", "stateStartTime": "2021-06-01T00:00:00Z"}
+			}`,
+			Expected: "create my-cluster, Create cluster with 2 workers, started at 2021-06-01T00:00:00Z",
+		},
+		"spanner instance": {
+			Metadata: `{
+				"@type": "type.googleapis.com/google.spanner.admin.instance.v1.CreateInstanceMetadata",
+				"instance": {"name": "projects/p/instances/i"},
+				"startTime": "2021-06-01T00:00:00Z"
+			}`,
+			Expected: "create projects/p/instances/i, started at 2021-06-01T00:00:00Z",
+		},
+		"no metadata": {
+			Metadata: ``,
+			Expected: "",
+		},
+		"unknown fields": {
+			Metadata: `{"@type": "type.googleapis.com/google.example.v1.Metadata", "foo": "bar"}`,
+			Expected: "",
+		},
+		"invalid json": {
+			Metadata: `{`,
+			Expected: "",
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := decodeOperationMetadata([]byte(tc.Metadata)).String(); got != tc.Expected {
+			t.Errorf("bad: %s, expected %q, got %q", tn, tc.Expected, got)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package google
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// The longest a waiter notified of completions waits for a notification
+// before polling its operation anyway. Notifications only wake waiters early:
+// they can be late or lost, so operations are still polled on their usual
+// schedule, see operationPollSchedule.wait.
+const operationNotificationFallbackInterval = 2 * time.Minute
+
+// How long to wait before pulling again after a pull fails.
+const operationNotificationPullRetryInterval = 10 * time.Second
+
+// How long to wait between pulls, so a busy subscription isn't pulled in a
+// tight loop.
+const operationNotificationPullInterval = 2 * time.Second
+
+// Messages about operations nobody here waits on are redelivered after
+// operationNotificationRedeliveryDelay, for other provider processes sharing
+// the subscription, until they're operationNotificationMaxAge old. Older
+// messages are acknowledged, as whoever waited on them has polled their
+// operations since.
+const (
+	operationNotificationRedeliveryDelay = 10 * time.Second
+	operationNotificationMaxAge          = 10 * time.Minute
+)
+
+// How often operations are polled at most while notifications are being
+// pulled successfully, as they'll be polled early when they complete.
+const operationNotificationLivePollInterval = 30 * time.Second
+
+// operationNotifier pulls messages announcing that operations completed from
+// the Pub/Sub subscription set by operation_notification_subscription, and
+// wakes the waiters of those operations so they poll them straight away,
+// rather than on their schedule. A message names its operation with an
+// "operation" attribute, or is a Cloud Audit Logs entry (eg exported by a log
+// sink to the subscription's topic) whose operation.id is the operation's
+// name and whose operation.last is true.
+type operationNotifier struct {
+	config       *Config
+	subscription string
+
+	mu      sync.Mutex
+	running bool
+	// healthy is whether the last pull succeeded
+	healthy bool
+	// waiting holds a channel for every waiter, by the name of its
+	// operation, see operationNotificationKey
+	waiting map[string][]chan struct{}
+}
+
+func newOperationNotifier(config *Config, subscription string) *operationNotifier {
+	return &operationNotifier{
+		config:       config,
+		subscription: subscription,
+		waiting:      make(map[string][]chan struct{}),
+	}
+}
+
+// subscribe returns a channel signalled when the operation named opName is
+// announced as complete, and a function to call once the operation is no
+// longer waited on. Messages are only pulled while operations are waited on.
+// On a nil notifier, the channel is nil and never signalled.
+func (n *operationNotifier) subscribe(opName string) (<-chan struct{}, func()) {
+	if n == nil {
+		return nil, func() {}
+	}
+
+	key := operationNotificationKey(opName)
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.waiting[key] = append(n.waiting[key], ch)
+	if !n.running {
+		n.running = true
+		go n.pullLoop()
+	}
+
+	return ch, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		chs := n.waiting[key]
+		for i, c := range chs {
+			if c == ch {
+				chs = append(chs[:i], chs[i+1:]...)
+				break
+			}
+		}
+		if len(chs) == 0 {
+			delete(n.waiting, key)
+		} else {
+			n.waiting[key] = chs
+		}
+	}
+}
+
+// pullLoop pulls messages until no operations are waited on.
+func (n *operationNotifier) pullLoop() {
+	for {
+		n.mu.Lock()
+		if len(n.waiting) == 0 {
+			n.running = false
+			n.mu.Unlock()
+			return
+		}
+		n.mu.Unlock()
+
+		err := n.pull()
+		n.mu.Lock()
+		n.healthy = err == nil
+		n.mu.Unlock()
+		if err != nil {
+			log.Printf("[WARN] Error pulling operation notifications from %s, operations are still polled: %s", n.subscription, err)
+			n.config.getClock().Sleep(operationNotificationPullRetryInterval)
+			continue
+		}
+		n.config.getClock().Sleep(operationNotificationPullInterval)
+	}
+}
+
+// live returns whether notifications are being pulled successfully, so
+// operations can be polled less often. It's safe to call on a nil notifier.
+func (n *operationNotifier) live() bool {
+	if n == nil {
+		return false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.running && n.healthy
+}
+
+// pull pulls one batch of messages and wakes the waiters of the operations
+// they announce. Messages about operations waited on here, messages that
+// aren't about operations and messages older than
+// operationNotificationMaxAge are acknowledged. Other messages may be about
+// operations waited on by another provider process sharing the subscription,
+// so they're redelivered after operationNotificationRedeliveryDelay.
+func (n *operationNotifier) pull() error {
+	url := fmt.Sprintf("%s%s:pull", n.config.PubsubBasePath, n.subscription)
+	res, err := sendRequest(n.config, "POST", "", url, n.config.userAgent, map[string]interface{}{
+		"maxMessages": 100,
+	})
+	if err != nil {
+		return err
+	}
+
+	received, _ := res["receivedMessages"].([]interface{})
+	var ackIds, nackIds []interface{}
+	for _, raw := range received {
+		rm, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := rm["ackId"].(string)
+		if !ok {
+			continue
+		}
+		msg, _ := rm["message"].(map[string]interface{})
+		if opName, ok := operationNameFromNotification(msg); ok && !n.notify(opName) && !n.expired(msg) {
+			nackIds = append(nackIds, id)
+		} else {
+			ackIds = append(ackIds, id)
+		}
+	}
+
+	if len(ackIds) > 0 {
+		url = fmt.Sprintf("%s%s:acknowledge", n.config.PubsubBasePath, n.subscription)
+		if _, err := sendRequest(n.config, "POST", "", url, n.config.userAgent, map[string]interface{}{
+			"ackIds": ackIds,
+		}); err != nil {
+			return err
+		}
+	}
+	if len(nackIds) > 0 {
+		url = fmt.Sprintf("%s%s:modifyAckDeadline", n.config.PubsubBasePath, n.subscription)
+		if _, err := sendRequest(n.config, "POST", "", url, n.config.userAgent, map[string]interface{}{
+			"ackIds":             nackIds,
+			"ackDeadlineSeconds": int(operationNotificationRedeliveryDelay.Seconds()),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expired returns whether msg was published more than
+// operationNotificationMaxAge ago. Messages without a valid publish time
+// never expire.
+func (n *operationNotifier) expired(msg map[string]interface{}) bool {
+	published, _ := msg["publishTime"].(string)
+	t, err := time.Parse(time.RFC3339Nano, published)
+	if err != nil {
+		return false
+	}
+	return n.config.getClock().Now().Sub(t) > operationNotificationMaxAge
+}
+
+// notify wakes the waiters of the operation named opName, returning whether
+// it's waited on.
+func (n *operationNotifier) notify(opName string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	chs := n.waiting[operationNotificationKey(opName)]
+	for _, ch := range chs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	return len(chs) > 0
+}
+
+// operationNotificationKey identifies an operation by the last segment of its
+// name, as waiters and notifications may name it differently, eg by its full
+// path or by its name alone.
+func operationNotificationKey(opName string) string {
+	return GetResourceNameFromSelfLink(opName)
+}
+
+// operationNameFromNotification returns the name of the operation a Pub/Sub
+// message announces as complete, if any.
+func operationNameFromNotification(msg map[string]interface{}) (string, bool) {
+	if attrs, ok := msg["attributes"].(map[string]interface{}); ok {
+		if name, ok := attrs["operation"].(string); ok && name != "" {
+			return name, true
+		}
+	}
+
+	data, _ := msg["data"].(string)
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", false
+	}
+	var entry struct {
+		Operation struct {
+			Id   string `json:"id"`
+			Last bool   `json:"last"`
+		} `json:"operation"`
+	}
+	if err := json.Unmarshal(b, &entry); err != nil || entry.Operation.Id == "" || !entry.Operation.Last {
+		return "", false
+	}
+	return entry.Operation.Id, true
+}
@@ -0,0 +1,156 @@
+package google
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOperationNameFromNotification(t *testing.T) {
+	data := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	cases := map[string]struct {
+		msg  map[string]interface{}
+		name string
+	}{
+		"attribute": {
+			msg:  map[string]interface{}{"attributes": map[string]interface{}{"operation": "operation-123"}},
+			name: "operation-123",
+		},
+		"last audit log entry": {
+			msg:  map[string]interface{}{"data": data(`{"operation": {"id": "operation-123", "last": true}}`)},
+			name: "operation-123",
+		},
+		"first audit log entry": {
+			msg: map[string]interface{}{"data": data(`{"operation": {"id": "operation-123", "first": true}}`)},
+		},
+		"other message": {
+			msg: map[string]interface{}{"data": data(`hello`)},
+		},
+	}
+	for tn, tc := range cases {
+		name, ok := operationNameFromNotification(tc.msg)
+		if name != tc.name || ok != (tc.name != "") {
+			t.Errorf("%s: expected %q, got %q", tn, tc.name, name)
+		}
+	}
+}
+
+func TestOperationNotifier_pull(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("POST", "/v1/projects/p/subscriptions/ops:pull", fakeAPIResponse{Body: map[string]interface{}{
+		"receivedMessages": []interface{}{
+			map[string]interface{}{
+				"ackId":   "ack-1",
+				"message": map[string]interface{}{"attributes": map[string]interface{}{"operation": "projects/p/zones/z/operations/operation-123"}},
+			},
+			map[string]interface{}{
+				"ackId": "ack-2",
+				"message": map[string]interface{}{
+					"attributes":  map[string]interface{}{"operation": "operation-456"},
+					"publishTime": "2021-01-01T00:09:00Z",
+				},
+			},
+			map[string]interface{}{
+				"ackId": "ack-3",
+				"message": map[string]interface{}{
+					"attributes":  map[string]interface{}{"operation": "operation-789"},
+					"publishTime": "2021-01-01T00:00:00Z",
+				},
+			},
+		},
+	}})
+	bodies := make(map[string]map[string]interface{})
+	for _, method := range []string{"acknowledge", "modifyAckDeadline"} {
+		method := method
+		s.scripts[fakeAPIKey("POST", "/v1/projects/p/subscriptions/ops:"+method)] = &fakeAPIScript{handler: func(r *http.Request) fakeAPIResponse {
+			var body map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("error decoding %s request: %s", method, err)
+			}
+			bodies[method] = body
+			return fakeAPIResponse{}
+		}}
+	}
+
+	config := s.Config()
+	config.PubsubBasePath = s.URL + "/v1/"
+	config.clock = &fakeClock{now: time.Date(2021, 1, 1, 0, 15, 0, 0, time.UTC)}
+	n := newOperationNotifier(config, "projects/p/subscriptions/ops")
+
+	// Subscribe without starting the pull loop, to pull by hand
+	waiting := make(chan struct{}, 1)
+	n.waiting[operationNotificationKey("operation-123")] = []chan struct{}{waiting}
+
+	if err := n.pull(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case <-waiting:
+	default:
+		t.Errorf("expected the waiter of operation-123 to be woken")
+	}
+	// operation-789 was announced too long ago for anyone to still wait on it
+	if got, want := bodies["acknowledge"]["ackIds"], []interface{}{"ack-1", "ack-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the messages of operation-123 and operation-789 to be acknowledged, got %v", got)
+	}
+	// operation-456 may be waited on by another process
+	if got, want := bodies["modifyAckDeadline"]["ackIds"], []interface{}{"ack-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the message of operation-456 to be redelivered, got %v", got)
+	}
+	if got := bodies["modifyAckDeadline"]["ackDeadlineSeconds"]; got != operationNotificationRedeliveryDelay.Seconds() {
+		t.Errorf("expected the message of operation-456 to be redelivered after %s, got %v seconds", operationNotificationRedeliveryDelay, got)
+	}
+}
+
+func TestOperationNotifier_nil(t *testing.T) {
+	var n *operationNotifier
+	wake, unsubscribe := n.subscribe("operation-123")
+	defer unsubscribe()
+	if wake != nil {
+		t.Errorf("expected a nil notifier not to notify")
+	}
+}
+
+func TestOperationPollSchedule_wake(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s := newOperationPollSchedule(10*time.Second, clock)
+	wake := make(chan struct{}, 1)
+	s.wake = wake
+
+	wake <- struct{}{}
+	s.wait()
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != 0 {
+		t.Errorf("expected a notification to end the wait, waited %s", waited)
+	}
+
+	// Without a notification, the operation is polled on its schedule
+	s.wait()
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != time.Duration(float64(operationPollInitialInterval)*operationPollBackoffFactor) {
+		t.Errorf("expected to poll when the poll is due without a notification, waited %s", waited)
+	}
+}
+
+func TestOperationPollSchedule_notificationsLive(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s := newOperationPollSchedule(10*time.Second, clock)
+	s.wake = make(chan struct{}, 1)
+	live := true
+	s.notificationsLive = func() bool { return live }
+
+	var d time.Duration
+	for i := 0; i < 20; i++ {
+		d = s.next()
+	}
+	if d != operationNotificationLivePollInterval {
+		t.Errorf("expected polls every %s while notifications are live, got %s", operationNotificationLivePollInterval, d)
+	}
+
+	live = false
+	if d := s.next(); d != 10*time.Second {
+		t.Errorf("expected polls every 10s once notifications stop, got %s", d)
+	}
+}
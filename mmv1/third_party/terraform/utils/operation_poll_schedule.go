@@ -0,0 +1,155 @@
+package google
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"google.golang.org/api/googleapi"
+)
+
+// Operations are polled quickly at first, as most finish within seconds, and
+// less often the longer they run, to save quota on operations taking minutes
+// or hours.
+const (
+	operationPollInitialInterval     = time.Second
+	operationPollBackoffFactor       = 1.5
+	operationPollLongRunningAfter    = 5 * time.Minute
+	operationPollLongRunningInterval = 30 * time.Second
+	// Retry-After values longer than this are assumed to be bogus.
+	operationPollMaxRetryAfter = time.Minute
+)
+
+// operationPollSchedule decides how long to wait between polls of an
+// operation. Intervals back off from operationPollInitialInterval to
+// maxInterval, and to operationPollLongRunningInterval once the operation has
+// run for operationPollLongRunningAfter. A Retry-After sent by the server
+// replaces the next interval. Schedules with a wake channel also poll as soon
+// as it's signalled, see operationNotifier, and back off to
+// operationNotificationLivePollInterval while notificationsLive returns true.
+type operationPollSchedule struct {
+	maxInterval       time.Duration
+	clock             Clock
+	wake              <-chan struct{}
+	notificationsLive func() bool
+
+	start      time.Time
+	interval   time.Duration
+	retryAfter time.Duration
+}
+
+func newOperationPollSchedule(maxInterval time.Duration, clock Clock) *operationPollSchedule {
+	if maxInterval <= 0 {
+		maxInterval = 10 * time.Second
+	}
+	return &operationPollSchedule{
+		maxInterval: maxInterval,
+		clock:       clock,
+		start:       clock.Now(),
+	}
+}
+
+// next returns how long to wait before the next poll.
+func (s *operationPollSchedule) next() time.Duration {
+	if s.retryAfter > 0 {
+		d := s.retryAfter
+		s.retryAfter = 0
+		return d
+	}
+
+	max := s.maxInterval
+	if s.clock.Now().Sub(s.start) >= operationPollLongRunningAfter && max < operationPollLongRunningInterval {
+		max = operationPollLongRunningInterval
+	}
+	if s.wake != nil && s.notificationsLive != nil && s.notificationsLive() && max < operationNotificationLivePollInterval {
+		max = operationNotificationLivePollInterval
+	}
+	if s.interval == 0 {
+		s.interval = operationPollInitialInterval
+	} else {
+		s.interval = time.Duration(float64(s.interval) * operationPollBackoffFactor)
+	}
+	if s.interval > max {
+		s.interval = max
+	}
+	return s.interval
+}
+
+// observe records the delay the server asked for with err, if any, see
+// serverRetryDelay. It's safe to call on a nil schedule.
+func (s *operationPollSchedule) observe(err error) {
+	if s == nil {
+		return
+	}
+	if d, ok := serverRetryDelay(err); ok {
+		if d > operationPollMaxRetryAfter {
+			d = operationPollMaxRetryAfter
+		}
+		log.Printf("[DEBUG] Server asked to retry after %s, waiting that long before polling again", d)
+		s.retryAfter = d
+	}
+}
+
+// refreshFunc returns f, waiting for the schedule before every call but the
+// first.
+func (s *operationPollSchedule) refreshFunc(f resource.StateRefreshFunc) resource.StateRefreshFunc {
+	first := true
+	return func() (interface{}, string, error) {
+		if !first {
+			s.wait()
+		}
+		first = false
+		return f()
+	}
+}
+
+// wait waits until the next poll. With a wake channel, that's when it's
+// signalled, or when it's next due if it isn't, waiting no longer than
+// operationNotificationFallbackInterval.
+func (s *operationPollSchedule) wait() {
+	if s.wake == nil || s.retryAfter > 0 {
+		s.clock.Sleep(s.next())
+		return
+	}
+	d := s.next()
+	if d > operationNotificationFallbackInterval {
+		d = operationNotificationFallbackInterval
+	}
+
+	// Prefer a notification already received to the fallback, which a fake
+	// clock fires straight away
+	select {
+	case <-s.wake:
+		return
+	default:
+	}
+	select {
+	case <-s.wake:
+	case <-s.clock.After(d):
+	}
+}
+
+// retryAfterFromError returns the delay in the Retry-After header of a
+// googleapi.Error, given either in seconds or as an HTTP date.
+func retryAfterFromError(err error) (time.Duration, bool) {
+	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
+	if !ok || gerr == nil {
+		return 0, false
+	}
+	v := http.Header(gerr.Header).Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,128 @@
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+func testOperationPollSchedule(maxInterval time.Duration, clock *fakeClock) *operationPollSchedule {
+	return newOperationPollSchedule(maxInterval, clock)
+}
+
+func TestOperationPollSchedule_backsOff(t *testing.T) {
+	s := testOperationPollSchedule(10*time.Second, &fakeClock{now: time.Unix(0, 0)})
+
+	var got []time.Duration
+	for i := 0; i < 8; i++ {
+		got = append(got, s.next())
+	}
+	expected := []time.Duration{
+		time.Second,
+		1500 * time.Millisecond,
+		2250 * time.Millisecond,
+		3375 * time.Millisecond,
+		5062500 * time.Microsecond,
+		7593750 * time.Microsecond,
+		10 * time.Second,
+		10 * time.Second,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected intervals %v, got %v", expected, got)
+	}
+}
+
+func TestOperationPollSchedule_longRunning(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s := testOperationPollSchedule(10*time.Second, clock)
+
+	for i := 0; i < 10; i++ {
+		s.next()
+	}
+	clock.Sleep(operationPollLongRunningAfter)
+
+	var last time.Duration
+	for i := 0; i < 5; i++ {
+		last = s.next()
+	}
+	if last != operationPollLongRunningInterval {
+		t.Errorf("expected long running operations to be polled every %s, got %s", operationPollLongRunningInterval, last)
+	}
+}
+
+func TestOperationPollSchedule_retryAfter(t *testing.T) {
+	s := testOperationPollSchedule(10*time.Second, &fakeClock{now: time.Unix(0, 0)})
+	s.next()
+
+	s.observe(&googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"7"}}})
+	if got := s.next(); got != 7*time.Second {
+		t.Errorf("expected Retry-After to be honored, got %s", got)
+	}
+	if got := s.next(); got != 1500*time.Millisecond {
+		t.Errorf("expected backoff to resume after Retry-After, got %s", got)
+	}
+
+	s.observe(errwrap.Wrapf("wrapped: {{err}}", &googleapi.Error{Code: 503, Header: http.Header{"Retry-After": []string{"3600"}}}))
+	if got := s.next(); got != operationPollMaxRetryAfter {
+		t.Errorf("expected Retry-After to be capped to %s, got %s", operationPollMaxRetryAfter, got)
+	}
+
+	s.observe(&googleapi.Error{Code: 500})
+	if got := s.next(); got != 2250*time.Millisecond {
+		t.Errorf("expected errors without Retry-After to be ignored, got %s", got)
+	}
+}
+
+func TestRetryAfterFromError(t *testing.T) {
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	cases := map[string]struct {
+		err error
+		ok  bool
+	}{
+		"seconds": {
+			err: &googleapi.Error{Header: http.Header{"Retry-After": []string{"5"}}},
+			ok:  true,
+		},
+		"date": {
+			err: &googleapi.Error{Header: http.Header{"Retry-After": []string{date}}},
+			ok:  true,
+		},
+		"invalid": {
+			err: &googleapi.Error{Header: http.Header{"Retry-After": []string{"soon"}}},
+		},
+		"no header": {
+			err: &googleapi.Error{Code: 429},
+		},
+		"not a googleapi error": {
+			err: fmt.Errorf("boom"),
+		},
+	}
+
+	for tn, tc := range cases {
+		if d, ok := retryAfterFromError(tc.err); ok != tc.ok || (ok && d <= 0) {
+			t.Errorf("%s: expected ok to be %t, got %t with %s", tn, tc.ok, ok, d)
+		}
+	}
+}
+
+func TestOperationPollSchedule_refreshFunc(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	s := testOperationPollSchedule(10*time.Second, clock)
+
+	refresh := s.refreshFunc(func() (interface{}, string, error) {
+		return nil, "", nil
+	})
+	refresh()
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != 0 {
+		t.Errorf("expected the first refresh not to wait, waited %s", waited)
+	}
+	refresh()
+	refresh()
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != 2500*time.Millisecond {
+		t.Errorf("expected later refreshes to wait, waited %s", waited)
+	}
+}
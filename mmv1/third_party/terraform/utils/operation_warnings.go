@@ -0,0 +1,146 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// operationWarning is a warning attached to a completed long-running
+// operation, eg a Compute Engine operation creating a disk from a deprecated
+// image.
+type operationWarning struct {
+	Code    string
+	Message string
+	// Data is extra information about the warning, eg {"image": "..."}
+	Data map[string]string
+}
+
+func (w operationWarning) String() string {
+	if len(w.Data) == 0 {
+		return fmt.Sprintf("%s: %s", w.Code, w.Message)
+	}
+	keys := make([]string, 0, len(w.Data))
+	for k := range w.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	data := make([]string, 0, len(keys))
+	for _, k := range keys {
+		data = append(data, fmt.Sprintf("%s=%s", k, w.Data[k]))
+	}
+	return fmt.Sprintf("%s: %s (%s)", w.Code, w.Message, strings.Join(data, ", "))
+}
+
+// operationWarnings holds the warnings of completed operations, keyed by the
+// self link of the resource they targeted, until the resource's CRUD function
+// returns and they can be reported as diagnostics by
+// withOperationWarningDiagnostics. Operation waiters don't have access to the
+// resource, so this is how warnings get from one to the other.
+type operationWarnings struct {
+	mu       sync.Mutex
+	warnings map[string][]operationWarning
+}
+
+func newOperationWarnings() *operationWarnings {
+	return &operationWarnings{
+		warnings: make(map[string][]operationWarning),
+	}
+}
+
+func operationWarningsKey(selfLink string) string {
+	return ConvertSelfLinkToV1(strings.TrimSuffix(selfLink, "/"))
+}
+
+// add records the warnings of an operation against target. Warnings are
+// always logged, as not every resource reports them as diagnostics.
+func (o *operationWarnings) add(target, activity string, warnings []operationWarning) {
+	for _, w := range warnings {
+		log.Printf("[WARN] %s: operation on %s completed with a warning: %s", activity, target, w)
+	}
+	if o == nil || target == "" || len(warnings) == 0 {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := operationWarningsKey(target)
+	o.warnings[key] = append(o.warnings[key], warnings...)
+}
+
+// take returns and forgets the warnings recorded against target.
+func (o *operationWarnings) take(target string) []operationWarning {
+	if o == nil || target == "" {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := operationWarningsKey(target)
+	warnings := o.warnings[key]
+	delete(o.warnings, key)
+	return warnings
+}
+
+// withOperationWarningDiagnostics reports the warnings of the operations a
+// resource's create, update and delete functions waited on as warning
+// diagnostics, so they're shown to users rather than only logged. Only
+// resources with a self_link field are wrapped, as warnings are matched to
+// resources by the self link of the operation target. The resource's
+// functions are converted to their context-aware variants, which are the only
+// ones able to return diagnostics.
+func withOperationWarningDiagnostics(r *schema.Resource) *schema.Resource {
+	if _, ok := r.Schema["self_link"]; !ok {
+		return r
+	}
+
+	wrap := func(f func(*schema.ResourceData, interface{}) error) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		return func(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			// The self link isn't known before create, so it's read after
+			// the function returns. Delete removes it, so it's read before.
+			before, _ := d.Get("self_link").(string)
+			err := f(d, meta)
+			after, _ := d.Get("self_link").(string)
+
+			var diags diag.Diagnostics
+			if err != nil {
+				diags = diag.FromErr(err)
+			}
+			var warnings []operationWarning
+			if config, ok := meta.(*Config); ok {
+				warnings = config.operationWarnings.take(before)
+				if after != before {
+					warnings = append(warnings, config.operationWarnings.take(after)...)
+				}
+			}
+			for _, w := range warnings {
+				diags = append(diags, diag.Diagnostic{
+					Severity: diag.Warning,
+					Summary:  fmt.Sprintf("Operation completed with warning %s", w.Code),
+					Detail:   w.String(),
+				})
+			}
+			return diags
+		}
+	}
+
+	if r.Create != nil {
+		r.CreateContext = wrap(r.Create)
+		r.Create = nil
+	}
+	if r.Update != nil {
+		r.UpdateContext = wrap(r.Update)
+		r.Update = nil
+	}
+	if r.Delete != nil {
+		r.DeleteContext = wrap(r.Delete)
+		r.Delete = nil
+	}
+	return r
+}
@@ -0,0 +1,128 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestOperationWarnings(t *testing.T) {
+	o := newOperationWarnings()
+	deprecated := operationWarning{
+		Code:    "DEPRECATED_RESOURCE_USED",
+		Message: "The resource 'debian-9' is deprecated.",
+		Data:    map[string]string{"resource_name": "debian-9"},
+	}
+
+	// Warnings are matched to resources regardless of the API version of the
+	// link, as the operation may target a beta link of a v1 resource
+	o.add("https://compute.googleapis.com/compute/beta/projects/p/zones/z/disks/d", "Creating Disk", []operationWarning{deprecated})
+
+	if w := o.take("https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/other"); len(w) != 0 {
+		t.Errorf("expected no warnings for another resource, got %v", w)
+	}
+	if w := o.take("https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/d"); !reflect.DeepEqual(w, []operationWarning{deprecated}) {
+		t.Errorf("expected %v, got %v", []operationWarning{deprecated}, w)
+	}
+	if w := o.take("https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/d"); len(w) != 0 {
+		t.Errorf("expected warnings to be taken once, got %v", w)
+	}
+
+	// A nil collector only logs warnings
+	var nilWarnings *operationWarnings
+	nilWarnings.add("https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/d", "Creating Disk", []operationWarning{deprecated})
+	if w := nilWarnings.take("https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/d"); len(w) != 0 {
+		t.Errorf("expected no warnings from a nil collector, got %v", w)
+	}
+}
+
+func TestOperationWarningString(t *testing.T) {
+	cases := map[string]struct {
+		Warning  operationWarning
+		Expected string
+	}{
+		"without data": {
+			Warning:  operationWarning{Code: "LARGE_DEPLOYMENT_WARNING", Message: "Deployment is large."},
+			Expected: "LARGE_DEPLOYMENT_WARNING: Deployment is large.",
+		},
+		"with data": {
+			Warning:  operationWarning{Code: "QUOTA_NEAR_LIMIT", Message: "Quota is nearly exhausted.", Data: map[string]string{"usage": "23", "limit": "24"}},
+			Expected: "QUOTA_NEAR_LIMIT: Quota is nearly exhausted. (limit=24, usage=23)",
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := tc.Warning.String(); got != tc.Expected {
+			t.Errorf("%s: expected %q, got %q", tn, tc.Expected, got)
+		}
+	}
+}
+
+func TestWithOperationWarningDiagnostics(t *testing.T) {
+	selfLink := "https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/d"
+	deprecated := operationWarning{Code: "DEPRECATED_RESOURCE_USED", Message: "The resource 'debian-9' is deprecated."}
+	config := &Config{operationWarnings: newOperationWarnings()}
+
+	var createErr error
+	r := withOperationWarningDiagnostics(&schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"self_link": {Type: schema.TypeString, Computed: true},
+		},
+		Create: func(d *schema.ResourceData, meta interface{}) error {
+			meta.(*Config).operationWarnings.add(selfLink, "Creating Disk", []operationWarning{deprecated})
+			if createErr != nil {
+				return createErr
+			}
+			d.SetId("d")
+			return d.Set("self_link", selfLink)
+		},
+		Delete: func(d *schema.ResourceData, meta interface{}) error {
+			meta.(*Config).operationWarnings.add(selfLink, "Deleting Disk", []operationWarning{deprecated})
+			d.SetId("")
+			return d.Set("self_link", "")
+		},
+	})
+	if r.Create != nil || r.CreateContext == nil || r.Delete != nil || r.DeleteContext == nil {
+		t.Fatalf("expected the functions to be converted to their context-aware variants")
+	}
+
+	// The self link is only known once create returns
+	d := r.TestResourceData()
+	diags := r.CreateContext(context.Background(), d, config)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Detail != deprecated.String() {
+		t.Errorf("expected a warning diagnostic for the create, got %v", diags)
+	}
+
+	// and no longer known once delete returns
+	diags = r.DeleteContext(context.Background(), d, config)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Errorf("expected a warning diagnostic for the delete, got %v", diags)
+	}
+
+	// A create failing before the self link is set can't be matched to its
+	// warnings, which stay recorded
+	createErr = fmt.Errorf("quota exceeded")
+	diags = r.CreateContext(context.Background(), r.TestResourceData(), config)
+	if len(diags) != 1 || diags[0].Severity != diag.Error {
+		t.Errorf("expected only the error diagnostic, got %v", diags)
+	}
+	if w := config.operationWarnings.take(selfLink); len(w) != 1 {
+		t.Errorf("expected the warning of the failed create to be left recorded, got %v", w)
+	}
+
+	// Resources without a self link aren't wrapped
+	create := func(d *schema.ResourceData, meta interface{}) error { return nil }
+	unwrapped := withOperationWarningDiagnostics(&schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {Type: schema.TypeString, Optional: true},
+		},
+		Create: create,
+	})
+	if unwrapped.Create == nil || unwrapped.CreateContext != nil {
+		t.Errorf("expected a resource without a self_link not to be wrapped")
+	}
+}
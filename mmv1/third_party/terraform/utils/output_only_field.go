@@ -0,0 +1,175 @@
+// Helpers for writable nested blocks containing output only fields set by the
+// API, eg the state of a config block. APIs reject requests setting output
+// only fields with "field is output only" errors, and output only fields
+// users can set, or that the API doesn't return in every response, diff
+// forever.
+//
+// Resources describe the output only fields of a block with outputOnlyFields,
+// mark them Computed in the block's schema with its schema method, remove
+// them from requests with expand, and flatten the block with flatten, which
+// keeps the values in state of output only fields the API didn't return.
+
+package google
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// outputOnlyFields describes the output only fields of a nested block by
+// their Terraform names. Fields of blocks nested in the block are separated
+// by dots, eg "status.state".
+type outputOnlyFields struct {
+	// Allow lists the fields users can set, every other field of the block
+	// being output only. Allowing a field allows the fields nested in it.
+	Allow []string
+	// Deny lists the output only fields, if Allow is empty.
+	Deny []string
+	// Names converts the Terraform names of fields to their API names, and
+	// defaults to converting snake_case to camelCase.
+	Names *fieldNameMapper
+}
+
+// isOutputOnly returns whether the field at path is output only.
+func (f outputOnlyFields) isOutputOnly(path string) bool {
+	if len(f.Allow) > 0 {
+		for _, a := range f.Allow {
+			// Fields containing an allowed field aren't output only, but
+			// their other fields are
+			if a == path || strings.HasPrefix(path, a+".") || strings.HasPrefix(a, path+".") {
+				return false
+			}
+		}
+		return true
+	}
+	for _, d := range f.Deny {
+		if d == path || strings.HasPrefix(path, d+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// schema returns a copy of s, the schema of the block, with its output only
+// fields Computed and no longer settable.
+func (f outputOnlyFields) schema(s map[string]*schema.Schema) map[string]*schema.Schema {
+	return f.schemaAt(s, "")
+}
+
+func (f outputOnlyFields) schemaAt(s map[string]*schema.Schema, prefix string) map[string]*schema.Schema {
+	out := make(map[string]*schema.Schema, len(s))
+	for k, v := range s {
+		c := *v
+		if f.isOutputOnly(prefix + k) {
+			c.Computed = true
+			c.Optional = false
+			c.Required = false
+			c.Default = nil
+			c.DefaultFunc = nil
+			c.ValidateFunc = nil
+			c.DiffSuppressFunc = nil
+			c.ConflictsWith = nil
+			c.ExactlyOneOf = nil
+			c.AtLeastOneOf = nil
+			c.MaxItems = 0
+			c.MinItems = 0
+		}
+		if r, ok := c.Elem.(*schema.Resource); ok {
+			c.Elem = &schema.Resource{Schema: f.schemaAt(r.Schema, prefix+k+".")}
+		}
+		out[k] = &c
+	}
+	return out
+}
+
+// expand returns obj, the API value of the block, without its output only
+// fields, so they aren't sent.
+func (f outputOnlyFields) expand(obj map[string]interface{}) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+	return f.stripAt(obj, "")
+}
+
+func (f outputOnlyFields) stripAt(obj map[string]interface{}, prefix string) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		path := prefix + f.Names.ToTerraform(k)
+		if f.isOutputOnly(path) {
+			continue
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			out[k] = f.stripAt(v, path+".")
+		case []interface{}:
+			l := make([]interface{}, len(v))
+			for i, e := range v {
+				if m, ok := e.(map[string]interface{}); ok {
+					l[i] = f.stripAt(m, path+".")
+				} else {
+					l[i] = e
+				}
+			}
+			out[k] = l
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// flatten returns the state value of the block at key of d, a list of a
+// single block, given its API value v flattened by flattenBlock. Output only
+// fields missing from v keep their value in state, as APIs don't return them
+// in every response, eg those of creates.
+func (f outputOnlyFields) flatten(v interface{}, d TerraformResourceData, key string, flattenBlock func(map[string]interface{}) map[string]interface{}) []interface{} {
+	original, ok := v.(map[string]interface{})
+	if !ok || original == nil {
+		return nil
+	}
+	flattened := flattenBlock(original)
+	if l, ok := d.Get(key).([]interface{}); ok && len(l) > 0 {
+		if prior, ok := l[0].(map[string]interface{}); ok {
+			f.mergeAt(flattened, prior, "")
+		}
+	}
+	return []interface{}{flattened}
+}
+
+// mergeAt sets the output only fields of prior missing from flattened on it,
+// recursing into blocks nested in both.
+func (f outputOnlyFields) mergeAt(flattened, prior map[string]interface{}, prefix string) {
+	for k, pv := range prior {
+		path := prefix + k
+		fv, ok := flattened[k]
+		if f.isOutputOnly(path) {
+			if !ok || fv == nil || isEmptyOutputOnlyValue(fv) {
+				flattened[k] = pv
+			}
+			continue
+		}
+		fl, fok := fv.([]interface{})
+		pl, pok := pv.([]interface{})
+		if !fok || !pok || len(fl) != 1 || len(pl) != 1 {
+			continue
+		}
+		fm, fok := fl[0].(map[string]interface{})
+		pm, pok := pl[0].(map[string]interface{})
+		if fok && pok {
+			f.mergeAt(fm, pm, path+".")
+		}
+	}
+}
+
+func isEmptyOutputOnlyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
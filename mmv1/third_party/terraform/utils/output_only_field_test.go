@@ -0,0 +1,163 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestOutputOnlyFields_isOutputOnly(t *testing.T) {
+	cases := map[string]struct {
+		fields   outputOnlyFields
+		path     string
+		expected bool
+	}{
+		"denied":                    {outputOnlyFields{Deny: []string{"state"}}, "state", true},
+		"nested in denied":          {outputOnlyFields{Deny: []string{"status"}}, "status.state", true},
+		"not denied":                {outputOnlyFields{Deny: []string{"state"}}, "name", false},
+		"denied prefix":             {outputOnlyFields{Deny: []string{"state"}}, "state_time", false},
+		"allowed":                   {outputOnlyFields{Allow: []string{"name"}}, "name", false},
+		"not allowed":               {outputOnlyFields{Allow: []string{"name"}}, "state", true},
+		"nested in allowed":         {outputOnlyFields{Allow: []string{"config"}}, "config.size", false},
+		"containing allowed":        {outputOnlyFields{Allow: []string{"config.size"}}, "config", false},
+		"sibling of allowed":        {outputOnlyFields{Allow: []string{"config.size"}}, "config.state", true},
+		"allow overrides deny":      {outputOnlyFields{Allow: []string{"state"}, Deny: []string{"state"}}, "state", false},
+		"no fields are output only": {outputOnlyFields{}, "state", false},
+	}
+	for tn, tc := range cases {
+		if got := tc.fields.isOutputOnly(tc.path); got != tc.expected {
+			t.Errorf("%s: expected isOutputOnly(%q) to be %t, got %t", tn, tc.path, tc.expected, got)
+		}
+	}
+}
+
+func TestOutputOnlyFields_schema(t *testing.T) {
+	fields := outputOnlyFields{Deny: []string{"state", "status.update_time"}}
+	s := map[string]*schema.Schema{
+		"name":  {Type: schema.TypeString, Required: true},
+		"state": {Type: schema.TypeString, Optional: true, Default: "ACTIVE"},
+		"status": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"message":     {Type: schema.TypeString, Optional: true},
+					"update_time": {Type: schema.TypeString, Optional: true},
+				},
+			},
+		},
+	}
+
+	got := fields.schema(s)
+	if !got["name"].Required || got["name"].Computed {
+		t.Errorf("expected name to be unchanged, got %+v", got["name"])
+	}
+	if state := got["state"]; !state.Computed || state.Optional || state.Default != nil {
+		t.Errorf("expected state to be computed only, got %+v", state)
+	}
+	status := got["status"].Elem.(*schema.Resource).Schema
+	if !status["message"].Optional || status["message"].Computed {
+		t.Errorf("expected status.message to be unchanged, got %+v", status["message"])
+	}
+	if !status["update_time"].Computed || status["update_time"].Optional {
+		t.Errorf("expected status.update_time to be computed only, got %+v", status["update_time"])
+	}
+	if !s["state"].Optional || !s["status"].Elem.(*schema.Resource).Schema["update_time"].Optional {
+		t.Errorf("expected the original schema to be unchanged")
+	}
+}
+
+func TestOutputOnlyFields_expand(t *testing.T) {
+	fields := outputOnlyFields{
+		Deny:  []string{"state", "status.update_time", "nodes.ip"},
+		Names: newFieldNameMapper(map[string]string{"node_count": "nodeCnt"}),
+	}
+	obj := map[string]interface{}{
+		"displayName": "foo",
+		"state":       "ACTIVE",
+		"nodeCnt":     3,
+		"status": map[string]interface{}{
+			"message":    "ok",
+			"updateTime": "2021-01-01T00:00:00Z",
+		},
+		"nodes": []interface{}{
+			map[string]interface{}{"name": "a", "ip": "10.0.0.1"},
+			"b",
+		},
+	}
+	expected := map[string]interface{}{
+		"displayName": "foo",
+		"nodeCnt":     3,
+		"status": map[string]interface{}{
+			"message": "ok",
+		},
+		"nodes": []interface{}{
+			map[string]interface{}{"name": "a"},
+			"b",
+		},
+	}
+
+	if got := fields.expand(obj); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected output only fields to be removed, got %v", got)
+	}
+	if _, ok := obj["state"]; !ok {
+		t.Errorf("expected the original object to be unchanged")
+	}
+}
+
+func TestOutputOnlyFields_flatten(t *testing.T) {
+	fields := outputOnlyFields{Allow: []string{"name", "config.size"}}
+	d := &ResourceDataMock{
+		FieldsInSchema: map[string]interface{}{
+			"block": []interface{}{
+				map[string]interface{}{
+					"name":  "old",
+					"state": "ACTIVE",
+					"config": []interface{}{
+						map[string]interface{}{"size": 1, "state": "READY"},
+					},
+				},
+			},
+		},
+	}
+	flatten := func(v map[string]interface{}) map[string]interface{} {
+		transformed := map[string]interface{}{
+			"name":  v["name"],
+			"state": v["state"],
+		}
+		if c, ok := v["config"].(map[string]interface{}); ok {
+			transformed["config"] = []interface{}{
+				map[string]interface{}{"size": c["size"], "state": c["state"]},
+			}
+		}
+		return transformed
+	}
+
+	got := fields.flatten(map[string]interface{}{
+		"name":   "new",
+		"config": map[string]interface{}{"size": 2},
+	}, d, "block", flatten)
+	expected := []interface{}{
+		map[string]interface{}{
+			"name":  "new",
+			"state": "ACTIVE",
+			"config": []interface{}{
+				map[string]interface{}{"size": 2, "state": "READY"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected missing output only fields to keep their value in state, got %v", got)
+	}
+
+	got = fields.flatten(map[string]interface{}{"name": "new", "state": "FAILED"}, d, "block", flatten)
+	if state := got[0].(map[string]interface{})["state"]; state != "FAILED" {
+		t.Errorf("expected output only fields returned by the API to be set, got %v", state)
+	}
+
+	if got := fields.flatten(nil, d, "block", flatten); got != nil {
+		t.Errorf("expected no block if the API returned none, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// iamPermissionRoles maps permissions commonly missing when using the provider
+// to predefined roles containing them, the most narrowly scoped first. It's
+// not exhaustive; errors denying other permissions link to the permissions
+// reference instead.
+var iamPermissionRoles = map[string][]string{
+	"bigquery.datasets.create":                {"roles/bigquery.dataEditor", "roles/bigquery.user"},
+	"bigquery.tables.create":                  {"roles/bigquery.dataEditor"},
+	"cloudfunctions.functions.create":         {"roles/cloudfunctions.developer", "roles/cloudfunctions.admin"},
+	"cloudkms.cryptoKeyVersions.useToDecrypt": {"roles/cloudkms.cryptoKeyDecrypter", "roles/cloudkms.cryptoKeyEncrypterDecrypter"},
+	"cloudkms.cryptoKeyVersions.useToEncrypt": {"roles/cloudkms.cryptoKeyEncrypter", "roles/cloudkms.cryptoKeyEncrypterDecrypter"},
+	"cloudkms.cryptoKeys.create":              {"roles/cloudkms.admin"},
+	"cloudsql.instances.create":               {"roles/cloudsql.admin"},
+	"cloudsql.instances.get":                  {"roles/cloudsql.viewer", "roles/cloudsql.admin"},
+	"cloudsql.users.create":                   {"roles/cloudsql.admin"},
+	"compute.addresses.create":                {"roles/compute.networkAdmin", "roles/compute.admin"},
+	"compute.disks.create":                    {"roles/compute.storageAdmin", "roles/compute.instanceAdmin.v1", "roles/compute.admin"},
+	"compute.firewalls.create":                {"roles/compute.securityAdmin", "roles/compute.admin"},
+	"compute.images.useReadOnly":              {"roles/compute.imageUser"},
+	"compute.instanceTemplates.create":        {"roles/compute.instanceAdmin.v1", "roles/compute.admin"},
+	"compute.instances.create":                {"roles/compute.instanceAdmin.v1", "roles/compute.admin"},
+	"compute.instances.delete":                {"roles/compute.instanceAdmin.v1", "roles/compute.admin"},
+	"compute.instances.get":                   {"roles/compute.viewer", "roles/compute.instanceAdmin.v1"},
+	"compute.instances.setMetadata":           {"roles/compute.instanceAdmin.v1", "roles/compute.admin"},
+	"compute.instances.setServiceAccount":     {"roles/compute.instanceAdmin.v1", "roles/compute.admin"},
+	"compute.networks.create":                 {"roles/compute.networkAdmin", "roles/compute.admin"},
+	"compute.subnetworks.create":              {"roles/compute.networkAdmin", "roles/compute.admin"},
+	"compute.subnetworks.use":                 {"roles/compute.networkUser", "roles/compute.networkAdmin"},
+	"container.clusters.create":               {"roles/container.clusterAdmin", "roles/container.admin"},
+	"container.clusters.get":                  {"roles/container.clusterViewer", "roles/container.viewer"},
+	"container.clusters.update":               {"roles/container.clusterAdmin", "roles/container.admin"},
+	"dns.changes.create":                      {"roles/dns.admin"},
+	"dns.managedZones.create":                 {"roles/dns.admin"},
+	"iam.serviceAccountKeys.create":           {"roles/iam.serviceAccountKeyAdmin"},
+	"iam.serviceAccounts.actAs":               {"roles/iam.serviceAccountUser"},
+	"iam.serviceAccounts.create":              {"roles/iam.serviceAccountAdmin"},
+	"iam.serviceAccounts.getAccessToken":      {"roles/iam.serviceAccountTokenCreator"},
+	"logging.sinks.create":                    {"roles/logging.configWriter", "roles/logging.admin"},
+	"monitoring.alertPolicies.create":         {"roles/monitoring.alertPolicyEditor", "roles/monitoring.editor"},
+	"pubsub.subscriptions.create":             {"roles/pubsub.editor", "roles/pubsub.admin"},
+	"pubsub.topics.attachSubscription":        {"roles/pubsub.subscriber", "roles/pubsub.editor"},
+	"pubsub.topics.create":                    {"roles/pubsub.editor", "roles/pubsub.admin"},
+	"resourcemanager.projects.create":         {"roles/resourcemanager.projectCreator"},
+	"resourcemanager.projects.delete":         {"roles/resourcemanager.projectDeleter", "roles/owner"},
+	"resourcemanager.projects.get":            {"roles/browser", "roles/viewer"},
+	"resourcemanager.projects.getIamPolicy":   {"roles/iam.securityReviewer", "roles/resourcemanager.projectIamAdmin"},
+	"resourcemanager.projects.setIamPolicy":   {"roles/resourcemanager.projectIamAdmin", "roles/owner"},
+	"run.services.create":                     {"roles/run.developer", "roles/run.admin"},
+	"run.services.setIamPolicy":               {"roles/run.admin"},
+	"secretmanager.secrets.create":            {"roles/secretmanager.admin"},
+	"secretmanager.versions.access":           {"roles/secretmanager.secretAccessor"},
+	"serviceusage.services.enable":            {"roles/serviceusage.serviceUsageAdmin"},
+	"serviceusage.services.use":               {"roles/serviceusage.serviceUsageConsumer"},
+	"storage.buckets.create":                  {"roles/storage.admin"},
+	"storage.buckets.get":                     {"roles/storage.legacyBucketReader", "roles/storage.admin"},
+	"storage.buckets.setIamPolicy":            {"roles/storage.admin"},
+	"storage.objects.create":                  {"roles/storage.objectCreator", "roles/storage.objectAdmin"},
+	"storage.objects.delete":                  {"roles/storage.objectAdmin"},
+	"storage.objects.get":                     {"roles/storage.objectViewer", "roles/storage.objectAdmin"},
+}
+
+// The ways APIs name the permission they denied in error messages, eg
+//
+//	Required 'compute.instances.create' permission for 'projects/p/zones/z/instances/i'
+//	Permission 'iam.serviceAccounts.actAs' denied on service account
+//	user@example.com does not have storage.objects.get access to the Google Cloud Storage object.
+var permissionDeniedRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`Required '([a-z][a-z0-9]*\.[A-Za-z0-9]+\.[A-Za-z0-9]+)' permission`),
+	regexp.MustCompile(`(?i)permission ['"]?([a-z][a-z0-9]*\.[A-Za-z0-9]+\.[A-Za-z0-9]+)['"]? (?:is )?denied`),
+	regexp.MustCompile(`does not have ([a-z][a-z0-9]*\.[A-Za-z0-9]+\.[A-Za-z0-9]+) access`),
+}
+
+// deniedPermission returns the permission err, a 403 googleapi.Error, was
+// denied for. Quota errors and errors about disabled APIs, which are 403s too,
+// don't deny a permission.
+func deniedPermission(err error) (string, bool) {
+	body, ok := parseGoogleApiErrorBody(err)
+	if !ok || body.Code != 403 {
+		return "", false
+	}
+	if body.HasReason(errorReasonAccessNotConfigured, errorReasonRateLimitExceeded, errorReasonUserRateLimitExceeded, errorReasonQuotaExceeded, "dailyLimitExceeded", "SERVICE_DISABLED") {
+		return "", false
+	}
+	for _, d := range body.Details {
+		if p := d.Metadata["permission"]; p != "" {
+			return p, true
+		}
+	}
+	return deniedPermissionFromText(body.text() + "\n" + body.raw)
+}
+
+// deniedPermissionFromText returns the permission named in an error message.
+func deniedPermissionFromText(s string) (string, bool) {
+	for _, re := range permissionDeniedRegexes {
+		if m := re.FindStringSubmatch(s); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// permissionHint describes how to be granted permission.
+func permissionHint(permission string) string {
+	if roles, ok := iamPermissionRoles[permission]; ok {
+		return fmt.Sprintf("The caller is missing the IAM permission %s, which is included in the predefined roles %s among others.", permission, strings.Join(roles, ", "))
+	}
+	return fmt.Sprintf("The caller is missing the IAM permission %s. See https://cloud.google.com/iam/docs/permissions-reference for the roles that include it.", permission)
+}
+
+// withPermissionHint adds a permissionHint to err if it denied a permission.
+// The returned error wraps err, so predicates still match it.
+func withPermissionHint(err error) error {
+	permission, ok := deniedPermission(err)
+	if !ok {
+		return err
+	}
+	return errwrap.Wrap(fmt.Errorf("%s\n\n%s", err, permissionHint(permission)), err)
+}
+
+// withPermissionHints adds permission hints to the errors returned by the
+// functions of r, a resource or data source.
+func withPermissionHints(r *schema.Resource) *schema.Resource {
+	wrap := func(f func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+		if f == nil {
+			return nil
+		}
+		return func(d *schema.ResourceData, meta interface{}) error {
+			return withPermissionHint(f(d, meta))
+		}
+	}
+	// Context functions return diagnostics, so only the text of the errors is
+	// left to find permissions in.
+	wrapContext := func(f func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		if f == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			diags := f(ctx, d, meta)
+			for i, dg := range diags {
+				if dg.Severity != diag.Error {
+					continue
+				}
+				if permission, ok := deniedPermissionFromText(dg.Summary + "\n" + dg.Detail); ok {
+					diags[i].Detail = strings.TrimSpace(dg.Detail + "\n\n" + permissionHint(permission))
+				}
+			}
+			return diags
+		}
+	}
+
+	r.Create = wrap(r.Create)
+	r.Read = wrap(r.Read)
+	r.Update = wrap(r.Update)
+	r.Delete = wrap(r.Delete)
+	r.CreateContext = wrapContext(r.CreateContext)
+	r.ReadContext = wrapContext(r.ReadContext)
+	r.UpdateContext = wrapContext(r.UpdateContext)
+	r.DeleteContext = wrapContext(r.DeleteContext)
+	return r
+}
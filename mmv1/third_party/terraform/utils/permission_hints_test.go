@@ -0,0 +1,85 @@
+package google
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+func TestDeniedPermission(t *testing.T) {
+	cases := map[string]struct {
+		err        error
+		permission string
+	}{
+		"compute": {
+			err: &googleapi.Error{
+				Code: 403,
+				Body: `{"error": {"code": 403, "message": "Required 'compute.instances.create' permission for 'projects/p/zones/us-central1-a/instances/i'", "errors": [{"reason": "forbidden"}]}}`,
+			},
+			permission: "compute.instances.create",
+		},
+		"iam": {
+			err: &googleapi.Error{
+				Code:    403,
+				Message: "Permission 'iam.serviceAccounts.actAs' denied on service account sa@p.iam.gserviceaccount.com (or it may not exist).",
+			},
+			permission: "iam.serviceAccounts.actAs",
+		},
+		"storage": {
+			err: &googleapi.Error{
+				Code:    403,
+				Message: "user@example.com does not have storage.objects.get access to the Google Cloud Storage object.",
+			},
+			permission: "storage.objects.get",
+		},
+		"ErrorInfo": {
+			err: &googleapi.Error{
+				Code: 403,
+				Body: `{"error": {"code": 403, "message": "The caller does not have permission", "status": "PERMISSION_DENIED", "details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "IAM_PERMISSION_DENIED", "metadata": {"permission": "secretmanager.versions.access"}}]}}`,
+			},
+			permission: "secretmanager.versions.access",
+		},
+		"unnamed permission": {
+			err: &googleapi.Error{Code: 403, Message: "The caller does not have permission"},
+		},
+		"quota": {
+			err: &googleapi.Error{
+				Code:    403,
+				Message: "Permission 'compute.instances.create' denied: quota exceeded",
+				Errors:  []googleapi.ErrorItem{{Reason: errorReasonQuotaExceeded}},
+			},
+		},
+		"not a 403": {
+			err: &googleapi.Error{Code: 400, Message: "Required 'compute.instances.create' permission"},
+		},
+	}
+
+	for tn, tc := range cases {
+		permission, ok := deniedPermission(tc.err)
+		if ok != (tc.permission != "") || permission != tc.permission {
+			t.Errorf("%s: expected permission %q, got %q", tn, tc.permission, permission)
+		}
+	}
+}
+
+func TestWithPermissionHint(t *testing.T) {
+	gerr := &googleapi.Error{Code: 403, Message: "Permission 'iam.serviceAccounts.actAs' denied on service account"}
+	err := withPermissionHint(errwrap.Wrapf("Error creating Instance: {{err}}", gerr))
+	if !strings.Contains(err.Error(), "Error creating Instance") || !strings.Contains(err.Error(), "roles/iam.serviceAccountUser") {
+		t.Errorf("expected the error to name the role including the permission, got %q", err)
+	}
+	if !isGoogleApiErrorWithCode(err, 403) {
+		t.Errorf("expected the googleapi.Error to still be wrapped")
+	}
+
+	gerr = &googleapi.Error{Code: 403, Message: "Permission 'example.things.create' denied"}
+	if err := withPermissionHint(gerr); !strings.Contains(err.Error(), "permissions-reference") {
+		t.Errorf("expected permissions missing from the table to link to the reference, got %q", err)
+	}
+
+	if err := withPermissionHint(nil); err != nil {
+		t.Errorf("expected nil to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Lookups of a project's billing account and quotas, backing the
+// google_project_billing_info and google_compute_project_quotas data sources
+// that modules use to build guardrails, eg refusing to deploy into a project
+// without billing or close to a quota. Callers often lack
+// billing.resourceAssociations.list or compute.projects.get on the project,
+// so lookups they're denied degrade to an unreadable result instead of
+// failing the plan.
+
+package google
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// How long the billing info and quotas of a project are remembered. Modules
+// usually read them for every environment they deploy, from one plan.
+const projectGuardrailCacheTTL = 5 * time.Minute
+
+// projectGuardrailCache remembers the results of getProjectBillingInfo and
+// getProjectQuotas, including denied lookups. Errors aren't cached.
+type projectGuardrailCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]projectGuardrailEntry
+}
+
+type projectGuardrailEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+func newProjectGuardrailCache(ttl time.Duration, clock Clock) *projectGuardrailCache {
+	return &projectGuardrailCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]projectGuardrailEntry),
+	}
+}
+
+func (c *projectGuardrailCache) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && !c.clock.Now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, ok
+}
+
+func (c *projectGuardrailCache) set(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = projectGuardrailEntry{value: value, expires: c.clock.Now().Add(c.ttl)}
+}
+
+// projectBillingInfo is the billing account association of a project.
+type projectBillingInfo struct {
+	// Readable is false if the caller isn't allowed to read the billing info,
+	// in which case the other fields are empty
+	Readable       bool
+	BillingAccount string
+	BillingEnabled bool
+}
+
+// getProjectBillingInfo returns the billing account association of project.
+func getProjectBillingInfo(config *Config, userAgent, project string) (projectBillingInfo, error) {
+	key := "billingInfo/" + project
+	if v, ok := config.projectGuardrails.get(key); ok {
+		return v.(projectBillingInfo), nil
+	}
+
+	url := fmt.Sprintf("%sprojects/%s/billingInfo", config.CloudBillingBasePath, project)
+	res, err := sendRequest(config, "GET", project, url, userAgent, nil)
+	if isGoogleApiErrorWithCode(err, 403) {
+		log.Printf("[WARN] Not allowed to read the billing info of project %s, it requires the Billing Account Viewer role: %s", project, err)
+		config.projectGuardrails.set(key, projectBillingInfo{})
+		return projectBillingInfo{}, nil
+	}
+	if err != nil {
+		return projectBillingInfo{}, fmt.Errorf("Error reading the billing info of project %s: %s", project, err)
+	}
+
+	info := projectBillingInfo{Readable: true}
+	if v, ok := res["billingAccountName"].(string); ok {
+		info.BillingAccount = v
+	}
+	if v, ok := res["billingEnabled"].(bool); ok {
+		info.BillingEnabled = v
+	}
+	config.projectGuardrails.set(key, info)
+	return info, nil
+}
+
+// projectQuota is the usage and limit of a Compute Engine quota metric.
+type projectQuota struct {
+	Metric string
+	Usage  float64
+	Limit  float64
+}
+
+// getProjectQuotas returns the Compute Engine quotas of project in region, or
+// its global quotas if region is empty. readable is false if the caller isn't
+// allowed to read them.
+func getProjectQuotas(config *Config, userAgent, project, region string) (quotas []projectQuota, readable bool, err error) {
+	url := fmt.Sprintf("%sprojects/%s", config.ComputeBasePath, project)
+	if region != "" {
+		url = fmt.Sprintf("%s/regions/%s", url, region)
+	}
+
+	key := "quotas/" + url
+	if v, ok := config.projectGuardrails.get(key); ok {
+		quotas = v.([]projectQuota)
+		return quotas, quotas != nil, nil
+	}
+
+	res, err := sendRequest(config, "GET", project, url, userAgent, nil)
+	if isGoogleApiErrorWithCode(err, 403) {
+		log.Printf("[WARN] Not allowed to read the quotas of project %s: %s", project, err)
+		config.projectGuardrails.set(key, []projectQuota(nil))
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Error reading the quotas of project %s: %s", project, err)
+	}
+
+	raw, _ := res["quotas"].([]interface{})
+	quotas = make([]projectQuota, 0, len(raw))
+	for _, r := range raw {
+		q, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		quota := projectQuota{}
+		quota.Metric, _ = q["metric"].(string)
+		quota.Usage, _ = q["usage"].(float64)
+		quota.Limit, _ = q["limit"].(float64)
+		quotas = append(quotas, quota)
+	}
+	config.projectGuardrails.set(key, quotas)
+	return quotas, true, nil
+}
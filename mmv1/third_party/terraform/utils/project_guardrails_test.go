@@ -0,0 +1,71 @@
+package google
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetProjectBillingInfo(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/v1/projects/p/billingInfo", fakeAPIResponse{Body: map[string]interface{}{
+		"billingAccountName": "billingAccounts/0123",
+		"billingEnabled":     true,
+	}})
+	s.Script("GET", "/v1/projects/denied/billingInfo", fakeAPIResponse{Status: 403})
+	clock := &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	config := s.Config()
+	config.CloudBillingBasePath = s.URL + "/v1/"
+	config.projectGuardrails = newProjectGuardrailCache(time.Minute, clock)
+
+	for i := 0; i < 2; i++ {
+		info, err := getProjectBillingInfo(config, "", "p")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := projectBillingInfo{Readable: true, BillingAccount: "billingAccounts/0123", BillingEnabled: true}
+		if info != expected {
+			t.Errorf("expected %+v, got %+v", expected, info)
+		}
+	}
+	if n := s.Requests("GET", "/v1/projects/p/billingInfo"); n != 1 {
+		t.Errorf("expected the billing info to be cached, got %d requests", n)
+	}
+
+	info, err := getProjectBillingInfo(config, "", "denied")
+	if err != nil || info.Readable {
+		t.Errorf("expected an unreadable billing info, got %+v, %v", info, err)
+	}
+
+	clock.Sleep(time.Minute)
+	if _, err := getProjectBillingInfo(config, "", "p"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", "/v1/projects/p/billingInfo"); n != 2 {
+		t.Errorf("expected the cached billing info to expire, got %d requests", n)
+	}
+}
+
+func TestGetProjectQuotas(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/compute/v1/projects/p/regions/r", fakeAPIResponse{Body: map[string]interface{}{
+		"quotas": []interface{}{
+			map[string]interface{}{"metric": "CPUS", "usage": 6, "limit": 24},
+		},
+	}})
+	s.Script("GET", "/compute/v1/projects/denied", fakeAPIResponse{Status: 403})
+	config := s.Config()
+	config.ComputeBasePath = s.URL + "/compute/v1/"
+
+	quotas, readable, err := getProjectQuotas(config, "", "p", "r")
+	if err != nil || !readable {
+		t.Fatalf("expected readable quotas, got %v, %v", readable, err)
+	}
+	if len(quotas) != 1 || quotas[0] != (projectQuota{Metric: "CPUS", Usage: 6, Limit: 24}) {
+		t.Errorf("expected the CPUS quota, got %+v", quotas)
+	}
+
+	quotas, readable, err = getProjectQuotas(config, "", "denied", "")
+	if err != nil || readable || len(quotas) != 0 {
+		t.Errorf("expected unreadable quotas, got %+v, %v, %v", quotas, readable, err)
+	}
+}
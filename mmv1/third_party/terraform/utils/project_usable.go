@@ -0,0 +1,116 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// New projects aren't usable as soon as their creation completes: until the
+// project propagates, requests in it fail with 403 permission denied or 404
+// not found errors. Resources creating projects wait for them to be usable,
+// and resources created in new projects do too if create_dependency_wait is
+// set.
+
+// usableProjects remembers the projects known to be usable, by ID and number,
+// so each is only waited for once.
+type usableProjects struct {
+	mu     sync.Mutex
+	usable map[string]bool
+}
+
+func newUsableProjects() *usableProjects {
+	return &usableProjects{usable: make(map[string]bool)}
+}
+
+func (u *usableProjects) record(projects ...string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, p := range projects {
+		if p != "" {
+			u.usable[p] = true
+		}
+	}
+}
+
+func (u *usableProjects) isUsable(project string) bool {
+	if u == nil {
+		return false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.usable[project]
+}
+
+// isProjectNotYetUsableError is a RetryErrorPredicateFunc retrying the errors
+// of requests in projects that haven't propagated yet. Disabled APIs are
+// reported with 403s too, but aren't fixed by waiting. Callers that can't
+// read the project get 403s forever, so waitForProjectUsable only retries the
+// first one.
+func isProjectNotYetUsableError(err error) (bool, string) {
+	if isApiNotEnabledError(err) {
+		return false, ""
+	}
+	if isGoogleApiErrorWithCode(err, 403) || isGoogleApiErrorWithCode(err, 404) {
+		return true, "Waiting for the new project to be usable"
+	}
+	return false, ""
+}
+
+// waitForProjectUsable waits up to timeout for project to be readable and
+// ACTIVE, backing off exponentially while it isn't found or readable yet. A
+// 403 after the first attempt is taken to mean the caller isn't allowed to
+// read the project rather than that it hasn't propagated, so the project is
+// assumed to be usable, without being recorded as such.
+func waitForProjectUsable(config *Config, project, userAgent string, timeout time.Duration) error {
+	if config.usableProjects.isUsable(project) {
+		return nil
+	}
+
+	start := config.getClock().Now()
+	attempts := 0
+	err := retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			attempts++
+			p, err := config.NewResourceManagerClient(userAgent).Projects.Get(project).Do()
+			if err != nil {
+				if attempts > 1 && isGoogleApiErrorWithCode(err, 403) && !isApiNotEnabledError(err) {
+					log.Printf("[DEBUG] Still not allowed to read project %s, assuming it is usable: %s", project, err)
+					return nil
+				}
+				return err
+			}
+			if p.LifecycleState != "ACTIVE" {
+				return fmt.Errorf("project %s is %s", project, p.LifecycleState)
+			}
+			config.usableProjects.record(p.ProjectId, strconv.FormatInt(p.ProjectNumber, 10))
+			return nil
+		},
+		Timeout: timeout,
+		ErrorBackoffPredicates: []RetryBackoffPredicateFunc{
+			withRetryBackoff(isProjectNotYetUsableError, RetryBackoffExponential),
+		},
+		Clock: config.getClock(),
+	})
+	if err != nil {
+		return fmt.Errorf("Error waiting for project %s to be usable: %s", project, err)
+	}
+	log.Printf("[DEBUG] Project %s was usable after %s", project, config.getClock().Now().Sub(start))
+	return nil
+}
+
+// waitForCreateDependencyProject waits for project to be usable before a
+// resource is created in it, if the create_dependency_wait provider setting
+// is set. Resources commonly created right after the project they belong to
+// call it first.
+func waitForCreateDependencyProject(config *Config, project, userAgent string) error {
+	if config.CreateDependencyWait == 0 {
+		return nil
+	}
+	return waitForProjectUsable(config, project, userAgent, config.CreateDependencyWait)
+}
@@ -0,0 +1,114 @@
+package google
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func newProjectUsableTestConfig(s *fakeAPIServer) *Config {
+	config := s.Config()
+	config.ResourceManagerBasePath = s.URL + "/v1/"
+	config.clock = &fakeClock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	config.usableProjects = newUsableProjects()
+	return config
+}
+
+func TestWaitForProjectUsable(t *testing.T) {
+	s := newFakeAPIServer(t)
+	path := "/v1/projects/p"
+	s.Script("GET", path,
+		fakeAPIError(http.StatusForbidden, "The caller does not have permission"),
+		fakeAPIError(http.StatusNotFound, "Project p not found."),
+		fakeAPIResponse{Body: map[string]interface{}{"projectId": "p", "projectNumber": "123", "lifecycleState": "ACTIVE"}},
+	)
+	config := newProjectUsableTestConfig(s)
+
+	if err := waitForProjectUsable(config, "p", "", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", path); n != 3 {
+		t.Errorf("expected the 403 and 404 to be retried, got %d requests", n)
+	}
+	if !config.usableProjects.isUsable("p") || !config.usableProjects.isUsable("123") {
+		t.Errorf("expected the project to be recorded as usable by ID and number")
+	}
+
+	// Usable projects aren't waited for again
+	if err := waitForProjectUsable(config, "p", "", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", path); n != 3 {
+		t.Errorf("expected no request for a usable project, got %d requests", n)
+	}
+}
+
+func TestWaitForProjectUsable_forbidden(t *testing.T) {
+	s := newFakeAPIServer(t)
+	path := "/v1/projects/p"
+	forbidden := fakeAPIError(http.StatusForbidden, "The caller does not have permission")
+	s.Script("GET", path, forbidden, forbidden, forbidden)
+	config := newProjectUsableTestConfig(s)
+
+	if err := waitForProjectUsable(config, "p", "", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", path); n != 2 {
+		t.Errorf("expected only the first 403 to be retried, got %d requests", n)
+	}
+	if config.usableProjects.isUsable("p") {
+		t.Errorf("expected a project that can't be read not to be recorded as usable")
+	}
+}
+
+func TestWaitForProjectUsable_deleted(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/v1/projects/p", fakeAPIResponse{Body: map[string]interface{}{"projectId": "p", "lifecycleState": "DELETE_REQUESTED"}})
+
+	err := waitForProjectUsable(newProjectUsableTestConfig(s), "p", "", time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "DELETE_REQUESTED") {
+		t.Errorf("expected an error for a project pending deletion, got %v", err)
+	}
+}
+
+func TestWaitForCreateDependencyProject(t *testing.T) {
+	s := newFakeAPIServer(t)
+	path := "/v1/projects/p"
+	s.Script("GET", path, fakeAPIResponse{Body: map[string]interface{}{"projectId": "p", "projectNumber": "123", "lifecycleState": "ACTIVE"}})
+	config := newProjectUsableTestConfig(s)
+
+	if err := waitForCreateDependencyProject(config, "p", ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", path); n != 0 {
+		t.Errorf("expected no wait without create_dependency_wait, got %d requests", n)
+	}
+
+	config.CreateDependencyWait = time.Minute
+	if err := waitForCreateDependencyProject(config, "p", ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", path); n != 1 {
+		t.Errorf("expected the project to be read, got %d requests", n)
+	}
+}
+
+func TestIsProjectNotYetUsableError(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"not found":         {&googleapi.Error{Code: 404}, true},
+		"permission denied": {&googleapi.Error{Code: 403, Message: "The caller does not have permission"}, true},
+		"api not enabled":   {errorReasonFixtures["api not enabled"], false},
+		"bad request":       {&googleapi.Error{Code: 400}, false},
+	}
+	for tn, tc := range cases {
+		if got, _ := isProjectNotYetUsableError(tc.err); got != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tn, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package google
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Features are opt-in provider behaviors, set in the provider's `features`
+// block or through environment variables, and read from Config.Features.
+//
+// To add a feature, add a field here and an entry to providerFeatures. The
+// entry generates the field of the `features` block and its environment
+// variable, so nothing needs to be threaded through providerConfigure; code
+// implementing the behavior checks config.Features.
+type Features struct {
+	// ErrorOnOutOfBandChanges makes updates of resources without etags fail
+	// if they changed since they were last read. See content_fingerprint.go
+	ErrorOnOutOfBandChanges bool
+	// DisableCaches disables the caches of API responses kept by the
+	// provider process, eg of deleted resources or of Shared VPC hosts, so
+	// every lookup reads the API
+	DisableCaches bool
+	// AggregatedRefresh serves reads of Compute Engine instances and disks
+	// from aggregated lists of their project. See aggregated_refresh.go
+	AggregatedRefresh bool
+	// HashSensitiveValues stores salted hashes of sensitive fields read from
+	// the API instead of their plaintext. See sensitive_field.go
+	HashSensitiveValues bool
+	// EstimateQuotas checks planned changes against region quotas, and warns
+	// of changes expected to exceed them. See compute_quota_estimate.go
+	EstimateQuotas bool
+}
+
+// providerFeature describes a field of Features.
+type providerFeature struct {
+	// Name is the field of the `features` block
+	Name string
+	// EnvVar enables the feature when set to a true value, see
+	// strconv.ParseBool, if it isn't set in the `features` block
+	EnvVar      string
+	Description string
+	// Field returns the field of the feature in f
+	Field func(f *Features) *bool
+}
+
+var providerFeatures = []providerFeature{
+	{
+		Name:        "error_on_out_of_band_changes",
+		EnvVar:      "GOOGLE_ERROR_ON_OUT_OF_BAND_CHANGES",
+		Description: "Fail updates of resources recording a content_fingerprint if they were changed outside of Terraform since they were last read.",
+		Field:       func(f *Features) *bool { return &f.ErrorOnOutOfBandChanges },
+	},
+	{
+		Name:        "disable_caches",
+		EnvVar:      "GOOGLE_DISABLE_CACHES",
+		Description: "Read the API for every lookup instead of caching responses for the duration of a run.",
+		Field:       func(f *Features) *bool { return &f.DisableCaches },
+	},
+	{
+		Name:        "aggregated_refresh",
+		EnvVar:      "GOOGLE_AGGREGATED_REFRESH",
+		Description: "Read Compute Engine instances and disks with one aggregated list per project, instead of one request per resource.",
+		Field:       func(f *Features) *bool { return &f.AggregatedRefresh },
+	},
+	{
+		Name:        "hash_sensitive_values",
+		EnvVar:      "GOOGLE_HASH_SENSITIVE_VALUES",
+		Description: "Store salted hashes of sensitive values returned by the API, such as the value of a Runtime Configurator variable, in state instead of their plaintext.",
+		Field:       func(f *Features) *bool { return &f.HashSensitiveValues },
+	},
+	{
+		Name:        "estimate_quotas",
+		EnvVar:      "GOOGLE_ESTIMATE_QUOTAS",
+		Description: "Read region quotas when changing resources, and warn when a change is expected to exceed them.",
+		Field:       func(f *Features) *bool { return &f.EstimateQuotas },
+	},
+}
+
+// providerFeaturesSchema returns the schema of the provider's `features`
+// block, with a field for every entry of providerFeatures.
+func providerFeaturesSchema() *schema.Schema {
+	fields := make(map[string]*schema.Schema, len(providerFeatures))
+	for _, f := range providerFeatures {
+		fields[f.Name] = &schema.Schema{
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Description: f.Description,
+		}
+	}
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: fields,
+		},
+	}
+}
+
+// expandProviderFeatures returns the features enabled in the provider's
+// `features` block or by their environment variables. A feature set in the
+// block, even to false, beats its environment variable.
+func expandProviderFeatures(d *schema.ResourceData) Features {
+	var features Features
+	for _, f := range providerFeatures {
+		if v, ok := d.GetOkExists("features.0." + f.Name); ok {
+			*f.Field(&features) = v.(bool)
+			continue
+		}
+		if v := os.Getenv(f.EnvVar); v != "" {
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				log.Printf("[WARN] Ignoring %s=%q, it isn't true or false", f.EnvVar, v)
+				continue
+			}
+			*f.Field(&features) = enabled
+		}
+	}
+	return features
+}
@@ -0,0 +1,64 @@
+package google
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestExpandProviderFeatures(t *testing.T) {
+	old := os.Getenv("GOOGLE_DISABLE_CACHES")
+	defer os.Setenv("GOOGLE_DISABLE_CACHES", old)
+
+	s := map[string]*schema.Schema{"features": providerFeaturesSchema()}
+	cases := map[string]struct {
+		raw      map[string]interface{}
+		env      string
+		expected Features
+	}{
+		"unset": {
+			raw: map[string]interface{}{},
+		},
+		"block": {
+			raw: map[string]interface{}{
+				"features": []interface{}{map[string]interface{}{"error_on_out_of_band_changes": true}},
+			},
+			expected: Features{ErrorOnOutOfBandChanges: true},
+		},
+		"environment variable": {
+			raw:      map[string]interface{}{},
+			env:      "true",
+			expected: Features{DisableCaches: true},
+		},
+		"block beats environment variable": {
+			raw: map[string]interface{}{
+				"features": []interface{}{map[string]interface{}{"disable_caches": false}},
+			},
+			env: "true",
+		},
+		"invalid environment variable": {
+			raw: map[string]interface{}{},
+			env: "yes please",
+		},
+	}
+	for tn, tc := range cases {
+		os.Setenv("GOOGLE_DISABLE_CACHES", tc.env)
+		d := schema.TestResourceDataRaw(t, s, tc.raw)
+		if got := expandProviderFeatures(d); got != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestProviderFeaturesSchema(t *testing.T) {
+	fields := providerFeaturesSchema().Elem.(*schema.Resource).Schema
+	for _, f := range providerFeatures {
+		if _, ok := fields[f.Name]; !ok {
+			t.Errorf("expected a field for feature %s", f.Name)
+		}
+		if f.EnvVar == "" || f.Description == "" || f.Field == nil {
+			t.Errorf("expected feature %s to have an environment variable, a description and a field", f.Name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-mux/tf5muxserver"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// frameworkProviderFunc returns the protocol 5 server of a provider whose
+// resources are served alongside the SDKv2 provider's, eg a plugin framework
+// provider built with providerserver.NewProtocol5. The provider should use the
+// Config from handoff rather than configuring clients of its own, so requests
+// from both use the same clients, user agent and retries.
+type frameworkProviderFunc func(handoff *providerConfigHandoff) tfprotov5.ProviderServer
+
+// frameworkProviders are the providers muxed with the SDKv2 provider. Every
+// resource and data source must be served by exactly one of them, or by the
+// SDKv2 provider.
+var frameworkProviders []frameworkProviderFunc
+
+// providerConfigHandoff hands the Config built by the SDKv2 provider's
+// ConfigureFunc to the framework providers served alongside it.
+type providerConfigHandoff struct {
+	mu     sync.Mutex
+	config *Config
+}
+
+func (h *providerConfigHandoff) set(config *Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.config = config
+}
+
+// Config returns the Config of the SDKv2 provider. The mux server configures
+// the SDKv2 provider before the framework providers, so the Config is
+// available from their ConfigureProvider on.
+func (h *providerConfigHandoff) Config() (*Config, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.config == nil {
+		return nil, fmt.Errorf("the provider hasn't been configured")
+	}
+	return h.config, nil
+}
+
+// ProviderServerFactory returns a protocol 5 server for the provider, muxing
+// the SDKv2 provider with frameworkProviders so resources can be migrated to
+// the plugin framework one at a time. Protocol 5 keeps the provider usable
+// from Terraform 0.12 on, so framework providers must serve it too.
+func ProviderServerFactory(ctx context.Context) (func() tfprotov5.ProviderServer, error) {
+	handoff := &providerConfigHandoff{}
+
+	provider := Provider()
+	configure := provider.ConfigureContextFunc
+	provider.ConfigureContextFunc = func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+		meta, diags := configure(ctx, d)
+		if config, ok := meta.(*Config); ok && !diags.HasError() {
+			handoff.set(config)
+		}
+		return meta, diags
+	}
+
+	sdkServer := provider.GRPCProvider()
+
+	// The SDKv2 provider is listed first so it's configured first.
+	servers := []func() tfprotov5.ProviderServer{
+		func() tfprotov5.ProviderServer { return sdkServer },
+	}
+	for _, f := range frameworkProviders {
+		server := &sharedProviderSchemaServer{
+			ProviderServer: f(handoff),
+			sdk:            sdkServer,
+		}
+		servers = append(servers, func() tfprotov5.ProviderServer { return server })
+	}
+
+	muxServer, err := tf5muxserver.NewMuxServer(ctx, servers...)
+	if err != nil {
+		return nil, err
+	}
+	return muxServer.ProviderServer, nil
+}
+
+// sharedProviderSchemaServer serves a framework provider with the provider
+// and provider_meta schemas of the SDKv2 provider, as every server behind the
+// mux must report the same ones. The framework provider never sees the
+// provider configuration: it's validated and configured with a null config,
+// and reads the SDKv2 provider's Config from the handoff instead. Resources
+// can read provider_meta from the Raw value of their request's ProviderMeta,
+// eg with frameworkResourceData.
+type sharedProviderSchemaServer struct {
+	tfprotov5.ProviderServer
+
+	sdk tfprotov5.ProviderServer
+}
+
+func (s *sharedProviderSchemaServer) GetProviderSchema(ctx context.Context, req *tfprotov5.GetProviderSchemaRequest) (*tfprotov5.GetProviderSchemaResponse, error) {
+	resp, err := s.ProviderServer.GetProviderSchema(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	sdkResp, err := s.sdk.GetProviderSchema(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Provider = sdkResp.Provider
+	resp.ProviderMeta = sdkResp.ProviderMeta
+	return resp, nil
+}
+
+func (s *sharedProviderSchemaServer) PrepareProviderConfig(ctx context.Context, req *tfprotov5.PrepareProviderConfigRequest) (*tfprotov5.PrepareProviderConfigResponse, error) {
+	config, err := nullProviderConfig()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.ProviderServer.PrepareProviderConfig(ctx, &tfprotov5.PrepareProviderConfigRequest{Config: config})
+	if err != nil {
+		return nil, err
+	}
+	// The SDKv2 provider's prepared config is the one used
+	return &tfprotov5.PrepareProviderConfigResponse{Diagnostics: resp.Diagnostics}, nil
+}
+
+func (s *sharedProviderSchemaServer) ConfigureProvider(ctx context.Context, req *tfprotov5.ConfigureProviderRequest) (*tfprotov5.ConfigureProviderResponse, error) {
+	config, err := nullProviderConfig()
+	if err != nil {
+		return nil, err
+	}
+	return s.ProviderServer.ConfigureProvider(ctx, &tfprotov5.ConfigureProviderRequest{
+		TerraformVersion: req.TerraformVersion,
+		Config:           config,
+	})
+}
+
+// nullProviderConfig returns a null provider configuration, which decodes to
+// a null value of any schema's type.
+func nullProviderConfig() (*tfprotov5.DynamicValue, error) {
+	v, err := tfprotov5.NewDynamicValue(tftypes.Object{}, tftypes.NewValue(tftypes.Object{}, nil))
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,83 @@
+package google
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// fakeProtocol5Server records the provider configuration it's sent. Methods
+// it doesn't implement panic.
+type fakeProtocol5Server struct {
+	tfprotov5.ProviderServer
+
+	schema *tfprotov5.GetProviderSchemaResponse
+	config *tfprotov5.DynamicValue
+}
+
+func (s *fakeProtocol5Server) GetProviderSchema(context.Context, *tfprotov5.GetProviderSchemaRequest) (*tfprotov5.GetProviderSchemaResponse, error) {
+	return s.schema, nil
+}
+
+func (s *fakeProtocol5Server) ConfigureProvider(_ context.Context, req *tfprotov5.ConfigureProviderRequest) (*tfprotov5.ConfigureProviderResponse, error) {
+	s.config = req.Config
+	return &tfprotov5.ConfigureProviderResponse{}, nil
+}
+
+func TestSharedProviderSchemaServer(t *testing.T) {
+	sdkSchema := &tfprotov5.Schema{Block: &tfprotov5.SchemaBlock{
+		Attributes: []*tfprotov5.SchemaAttribute{{Name: "project", Type: tftypes.String, Optional: true}},
+	}}
+	sdk := &fakeProtocol5Server{schema: &tfprotov5.GetProviderSchemaResponse{Provider: sdkSchema}}
+	framework := &fakeProtocol5Server{schema: &tfprotov5.GetProviderSchemaResponse{
+		Provider: &tfprotov5.Schema{Block: &tfprotov5.SchemaBlock{}},
+		ResourceSchemas: map[string]*tfprotov5.Schema{
+			"google_example": {Block: &tfprotov5.SchemaBlock{}},
+		},
+	}}
+	s := &sharedProviderSchemaServer{ProviderServer: framework, sdk: sdk}
+
+	resp, err := s.GetProviderSchema(context.Background(), &tfprotov5.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Provider != sdkSchema {
+		t.Errorf("expected the SDKv2 provider's schema to be served")
+	}
+	if _, ok := resp.ResourceSchemas["google_example"]; !ok {
+		t.Errorf("expected the framework provider's resources to be served")
+	}
+
+	configType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{"project": tftypes.String}}
+	config, err := tfprotov5.NewDynamicValue(configType, tftypes.NewValue(configType, map[string]tftypes.Value{
+		"project": tftypes.NewValue(tftypes.String, "my-project"),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := s.ConfigureProvider(context.Background(), &tfprotov5.ConfigureProviderRequest{Config: &config}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v, err := framework.config.Unmarshal(tftypes.Object{})
+	if err != nil {
+		t.Fatalf("expected the framework provider's config to decode, got %s", err)
+	}
+	if !v.IsNull() {
+		t.Errorf("expected the framework provider to be configured with a null config, got %s", v)
+	}
+}
+
+func TestProviderConfigHandoff(t *testing.T) {
+	handoff := &providerConfigHandoff{}
+	if _, err := handoff.Config(); err == nil {
+		t.Errorf("expected an error before the provider is configured")
+	}
+
+	config := &Config{Project: "my-project"}
+	handoff.set(config)
+	if got, err := handoff.Config(); err != nil || got != config {
+		t.Errorf("expected the SDKv2 provider's config, got %v and %v", got, err)
+	}
+}
@@ -0,0 +1,221 @@
+// Sending the provider's requests through an HTTPS proxy and trusting extra CA
+// certificates, for networks where a proxy intercepts TLS. As for any Go
+// program, the proxy defaults to the one set with HTTPS_PROXY and NO_PROXY;
+// proxy_url and no_proxy override it for the provider alone.
+
+package google
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// hasNetworkOverrides returns whether a proxy or CA certificates are
+// configured, which the default transports don't know about.
+func (c *Config) hasNetworkOverrides() bool {
+	return c.ProxyURL != "" || c.NoProxy != "" || c.CACertificates != ""
+}
+
+// proxyFunc returns the function picking the proxy of each request, which is
+// read from the environment unless overridden with proxy_url and no_proxy.
+func (c *Config) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("proxy_url must be an http:// or https:// URL, got %q", c.ProxyURL)
+		}
+		cfg.HTTPProxy = c.ProxyURL
+		cfg.HTTPSProxy = c.ProxyURL
+	}
+	if c.NoProxy != "" {
+		cfg.NoProxy = c.NoProxy
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// caCertPool returns the system's certificate pool with the certificates of
+// ca_certificates added, or nil if none are configured.
+func (c *Config) caCertPool() (*x509.CertPool, error) {
+	if c.CACertificates == "" {
+		return nil, nil
+	}
+	certs, _, err := pathOrContents(c.CACertificates)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading ca_certificates: %s", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(certs)) {
+		return nil, fmt.Errorf("Error loading ca_certificates: no PEM encoded certificates found")
+	}
+	return pool, nil
+}
+
+// transportTLSConfig returns clientTLS, the client certificate configuration
+// which may be nil, trusting the certificates of ca_certificates. It returns
+// nil if neither is configured, so the default TLS configuration is used.
+func (c *Config) transportTLSConfig(clientTLS *tls.Config) (*tls.Config, error) {
+	pool, err := c.caCertPool()
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return clientTLS, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientTLS != nil {
+		tlsConfig = clientTLS.Clone()
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// configureTransport sets the proxy of t, and its TLS configuration to
+// tlsConfig if it isn't nil.
+func (c *Config) configureTransport(t *http.Transport, tlsConfig *tls.Config) error {
+	proxy, err := c.proxyFunc()
+	if err != nil {
+		return err
+	}
+	t.Proxy = proxy
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+	}
+	return nil
+}
+
+// baseContext returns ctx with the HTTP client that oauth2.NewClient builds
+// authenticated clients on, and tokens are fetched with, set to one using the
+// configured proxy and TLS configuration.
+func (c *Config) baseContext(ctx context.Context, tlsConfig *tls.Config) (context.Context, error) {
+	base := cleanhttp.DefaultPooledTransport()
+	if err := c.configureTransport(base, tlsConfig); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: base}), nil
+}
+
+// tokenContext returns the context application default credentials fetch
+// tokens with, which uses the base client of c.context if it has one.
+func (c *Config) tokenContext() context.Context {
+	ctx := context.Background()
+	if c.context == nil {
+		return ctx
+	}
+	if client, ok := c.context.Value(oauth2.HTTPClient).(*http.Client); ok {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	return ctx
+}
+
+// grpcNetworkOptions returns the options making gRPC clients use tlsConfig,
+// if it isn't nil, and the proxy configured with proxy_url and no_proxy. gRPC
+// reads HTTPS_PROXY and NO_PROXY from the environment by itself otherwise.
+func (c *Config) grpcNetworkOptions(tlsConfig *tls.Config) ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+	if tlsConfig != nil {
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))))
+	}
+	if c.ProxyURL != "" || c.NoProxy != "" {
+		proxy, err := c.proxyFunc()
+		if err != nil {
+			return nil, err
+		}
+		var rootCAs *x509.CertPool
+		if tlsConfig != nil {
+			rootCAs = tlsConfig.RootCAs
+		}
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithContextDialer(proxyDialer(proxy, rootCAs))))
+	}
+	return opts, nil
+}
+
+// proxyDialer returns a dialer connecting to addresses through the proxy
+// picked by proxy with an HTTP CONNECT request, or directly if there is none.
+// The certificates of https:// proxies are verified with rootCAs, or the
+// system's certificates if nil.
+func proxyDialer(proxy func(*http.Request) (*url.URL, error), rootCAs *x509.CertPool) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		var d net.Dialer
+		proxyURL, err := proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: addr}})
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL == nil {
+			return d.DialContext(ctx, "tcp", addr)
+		}
+
+		proxyAddr := proxyURL.Host
+		if proxyURL.Port() == "" {
+			port := "80"
+			if proxyURL.Scheme == "https" {
+				port = "443"
+			}
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+		}
+		conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{
+				ServerName: proxyURL.Hostname(),
+				RootCAs:    rootCAs,
+				MinVersion: tls.VersionTLS12,
+			})
+			if err := tlsConn.Handshake(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+
+		req := &http.Request{
+			Method: "CONNECT",
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if u := proxyURL.User; u != nil {
+			password, _ := u.Password()
+			auth := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+			req.Header.Set("Proxy-Authorization", "Basic "+auth)
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		res, err := http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("Error connecting to %s through proxy %s: %s", addr, proxyURL.Host, res.Status)
+		}
+		return conn, nil
+	}
+}
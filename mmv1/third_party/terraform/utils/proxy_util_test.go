@@ -0,0 +1,133 @@
+package google
+
+import (
+	"bufio"
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestConfigProxyFunc(t *testing.T) {
+	c := &Config{
+		ProxyURL: "http://proxy.example.com:3128",
+		NoProxy:  ".internal.example.com",
+	}
+	proxy, err := c.proxyFunc()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := map[string]string{
+		"https://compute.googleapis.com/compute/v1/projects/p":        "http://proxy.example.com:3128",
+		"https://private.internal.example.com/compute/v1/projects/p":  "",
+		"https://storage.googleapis.com/storage/v1/b?project=project": "http://proxy.example.com:3128",
+	}
+	for rawurl, expected := range cases {
+		req, _ := http.NewRequest("GET", rawurl, nil)
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatalf("unexpected error picking the proxy of %s: %s", rawurl, err)
+		}
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if got != expected {
+			t.Errorf("expected %s to use proxy %q, got %q", rawurl, expected, got)
+		}
+	}
+
+	c = &Config{ProxyURL: "socks5://proxy.example.com:1080"}
+	if _, err := c.proxyFunc(); err == nil {
+		t.Errorf("expected an error for an unsupported proxy scheme")
+	}
+}
+
+func TestConfigBaseContext_caCertificates(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	get := func(c *Config) error {
+		tlsConfig, err := c.transportTLSConfig(nil)
+		if err != nil {
+			return err
+		}
+		ctx, err := c.baseContext(context.Background(), tlsConfig)
+		if err != nil {
+			return err
+		}
+		res, err := ctx.Value(oauth2.HTTPClient).(*http.Client).Get(s.URL)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		return nil
+	}
+
+	if err := get(&Config{}); err == nil {
+		t.Fatalf("expected the server's certificate not to be trusted by default")
+	}
+	serverCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := get(&Config{CACertificates: string(serverCert)}); err != nil {
+		t.Errorf("expected the server's certificate to be trusted once configured, got error: %s", err)
+	}
+
+	if _, err := (&Config{CACertificates: "not a certificate"}).caCertPool(); err == nil {
+		t.Errorf("expected an error for ca_certificates without certificates")
+	}
+}
+
+func TestProxyDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// A proxy accepting a single CONNECT request, then greeting the client as
+	// the target would
+	connected := make(chan *http.Request, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			close(connected)
+			return
+		}
+		connected <- req
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nhello"))
+	}()
+
+	proxyURL := &url.URL{Scheme: "http", User: url.UserPassword("user", "secret"), Host: l.Addr().String()}
+	dial := proxyDialer(func(*http.Request) (*url.URL, error) { return proxyURL, nil }, nil)
+	conn, err := dial(context.Background(), "bigtableadmin.googleapis.com:443")
+	if err != nil {
+		t.Fatalf("unexpected error dialing through the proxy: %s", err)
+	}
+	defer conn.Close()
+
+	req := <-connected
+	if req == nil || req.Method != "CONNECT" || req.Host != "bigtableadmin.googleapis.com:443" {
+		t.Fatalf("expected a CONNECT request to the target, got %v", req)
+	}
+	if user, password, ok := (&http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}}).BasicAuth(); !ok || user != "user" || password != "secret" {
+		t.Errorf("expected the proxy credentials to be sent, got %q", req.Header.Get("Proxy-Authorization"))
+	}
+	greeting, err := ioutil.ReadAll(conn)
+	if err != nil || string(greeting) != "hello" {
+		t.Errorf("expected the connection to be tunneled to the target, got %q (%v)", greeting, err)
+	}
+}
@@ -0,0 +1,254 @@
+package google
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// ReferenceStateForm is the form a ReferenceField is stored in state.
+type ReferenceStateForm int
+
+const (
+	// The full self link, eg https://compute.googleapis.com/compute/v1/projects/p/global/networks/n
+	ReferenceStateSelfLink ReferenceStateForm = iota
+	// The relative link, eg projects/p/global/networks/n
+	ReferenceStateRelativeLink
+	// The resource name only, eg n
+	ReferenceStateName
+)
+
+// referenceFieldData is the part of a resource's data a ReferenceField needs
+// to fill in defaults. It's satisfied by both *schema.ResourceData and
+// *schema.ResourceDiff so references can be resolved at plan time.
+type referenceFieldData interface {
+	GetOk(string) (interface{}, bool)
+}
+
+// ReferenceField describes a field referencing another Compute resource. Users
+// may set the field to a name, a partial URI (eg regions/{region}/subnetworks/{name}),
+// a relative link or a full self link. All of them are resolved to the same
+// canonical reference, filling in the project and location from the
+// resource's own fields or the provider defaults.
+type ReferenceField struct {
+	// Collection of the referenced resource, eg "networks"
+	ResourceType string
+	Location     LocationType
+
+	// Fields of the referencing resource used as defaults for the reference.
+	// An empty field name falls back directly to the provider default.
+	ProjectField string
+	RegionField  string
+	ZoneField    string
+
+	StoreAs ReferenceStateForm
+}
+
+var (
+	networkReference = ReferenceField{
+		ResourceType: "networks",
+		Location:     Global,
+		ProjectField: "project",
+	}
+
+	subnetworkReference = ReferenceField{
+		ResourceType: "subnetworks",
+		Location:     Regional,
+		ProjectField: "project",
+		RegionField:  "region",
+		ZoneField:    "zone",
+	}
+
+	instanceReference = ReferenceField{
+		ResourceType: "instances",
+		Location:     Zonal,
+		ProjectField: "project",
+		ZoneField:    "zone",
+	}
+)
+
+// referenceParts is a reference resolved to its components. Location is
+// empty for global resources.
+type referenceParts struct {
+	Project  string
+	Location string
+	Name     string
+}
+
+func (f ReferenceField) locationCollection() string {
+	switch f.Location {
+	case Zonal:
+		return "zones"
+	case Regional:
+		return "regions"
+	}
+	return "global"
+}
+
+func (f ReferenceField) relativeLink(p referenceParts) string {
+	if f.Location == Global {
+		return fmt.Sprintf(globalLinkTemplate, p.Project, f.ResourceType, p.Name)
+	}
+	return fmt.Sprintf("projects/%s/%s/%s/%s/%s", p.Project, f.locationCollection(), p.Location, f.ResourceType, p.Name)
+}
+
+// parts resolves value into its components. An empty value resolves to empty
+// parts.
+func (f ReferenceField) parts(value string, d referenceFieldData, config *Config) (referenceParts, error) {
+	if value == "" {
+		return referenceParts{}, nil
+	}
+
+	if f.Location == Global {
+		r := regexp.MustCompile(fmt.Sprintf(globalLinkBasePattern, f.ResourceType))
+		if parts := r.FindStringSubmatch(value); parts != nil {
+			return referenceParts{Project: parts[1], Name: parts[2]}, nil
+		}
+	} else {
+		r := regexp.MustCompile(fmt.Sprintf("projects/([^/]+)/%s/([^/]+)/%s/([^/]+)$", f.locationCollection(), f.ResourceType))
+		if parts := r.FindStringSubmatch(value); parts != nil {
+			return referenceParts{Project: parts[1], Location: parts[2], Name: parts[3]}, nil
+		}
+	}
+
+	project, err := f.defaultProject(d, config)
+	if err != nil {
+		return referenceParts{}, err
+	}
+
+	if f.Location != Global {
+		r := regexp.MustCompile(fmt.Sprintf("^%s/([^/]+)/%s/([^/]+)$", f.locationCollection(), f.ResourceType))
+		if parts := r.FindStringSubmatch(value); parts != nil {
+			return referenceParts{Project: project, Location: parts[1], Name: parts[2]}, nil
+		}
+	}
+
+	location, err := f.defaultLocation(d, config)
+	if err != nil {
+		return referenceParts{}, err
+	}
+
+	return referenceParts{Project: project, Location: location, Name: GetResourceNameFromSelfLink(value)}, nil
+}
+
+func (f ReferenceField) defaultProject(d referenceFieldData, config *Config) (string, error) {
+	if f.ProjectField != "" {
+		if v, ok := d.GetOk(f.ProjectField); ok {
+			return v.(string), nil
+		}
+	}
+	if config.Project != "" {
+		return config.Project, nil
+	}
+	return "", fmt.Errorf("Cannot determine the project of the referenced %s: set %q or the provider-level project", f.ResourceType, f.ProjectField)
+}
+
+// defaultLocation mirrors getZone and getRegionFromSchema: the resource's own
+// fields win over the provider defaults, and a region may be derived from a
+// zone.
+func (f ReferenceField) defaultLocation(d referenceFieldData, config *Config) (string, error) {
+	switch f.Location {
+	case Zonal:
+		if f.ZoneField != "" {
+			if v, ok := d.GetOk(f.ZoneField); ok {
+				return GetResourceNameFromSelfLink(v.(string)), nil
+			}
+		}
+		if config.Zone != "" {
+			return config.Zone, nil
+		}
+		return "", fmt.Errorf("Cannot determine the zone of the referenced %s: set it in the reference, in this resource, or set the provider-level zone", f.ResourceType)
+	case Regional:
+		if f.RegionField != "" {
+			if v, ok := d.GetOk(f.RegionField); ok {
+				return GetResourceNameFromSelfLink(v.(string)), nil
+			}
+		}
+		if f.ZoneField != "" {
+			if v, ok := d.GetOk(f.ZoneField); ok {
+				return getRegionFromZone(GetResourceNameFromSelfLink(v.(string))), nil
+			}
+		}
+		if config.Region != "" {
+			return config.Region, nil
+		}
+		if config.Zone != "" {
+			return getRegionFromZone(config.Zone), nil
+		}
+		return "", fmt.Errorf("Cannot determine the region of the referenced %s: set it in the reference, in this resource, or set the provider-level region or zone", f.ResourceType)
+	}
+	return "", nil
+}
+
+// RelativeLink resolves value to the relative link of the referenced
+// resource, eg projects/{project}/global/networks/{name}.
+func (f ReferenceField) RelativeLink(value string, d referenceFieldData, config *Config) (string, error) {
+	p, err := f.parts(value, d, config)
+	if err != nil || p.Name == "" {
+		return "", err
+	}
+	return f.relativeLink(p), nil
+}
+
+// SelfLink resolves value to the full self link of the referenced resource.
+func (f ReferenceField) SelfLink(value string, d referenceFieldData, config *Config) (string, error) {
+	link, err := f.RelativeLink(value, d, config)
+	if err != nil || link == "" {
+		return "", err
+	}
+	return config.ComputeBasePath + link, nil
+}
+
+// Expand resolves the configured reference to a self link to send to the API.
+func (f ReferenceField) Expand(v interface{}, d TerraformResourceData, config *Config) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	return f.SelfLink(s, d, config)
+}
+
+// Flatten converts the reference returned by the API to the form stored in
+// state.
+func (f ReferenceField) Flatten(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return v
+	}
+	switch f.StoreAs {
+	case ReferenceStateName:
+		return GetResourceNameFromSelfLink(s)
+	case ReferenceStateRelativeLink:
+		if link, err := getRelativePath(s); err == nil {
+			return link
+		}
+		return s
+	}
+	return ConvertSelfLinkToV1(s)
+}
+
+// DiffSuppress suppresses diffs between a reference in state and the same
+// reference written in another form. Bare names and partial URIs can only be
+// compared by name, as their project and location aren't known here.
+func (f ReferenceField) DiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || new == "" {
+		return old == new
+	}
+	if compareSelfLinkRelativePaths(k, old, new, d) {
+		return true
+	}
+	if _, err := getRelativePath(new); err != nil {
+		return GetResourceNameFromSelfLink(old) == GetResourceNameFromSelfLink(new)
+	}
+	return false
+}
+
+// ValidateExistsCustomizeDiff returns a CustomizeDiffFunc checking at plan
+// time that the resource referenced by field exists, so a typo fails the plan
+// instead of the apply. References that aren't known yet are skipped.
+func (f ReferenceField) ValidateExistsCustomizeDiff(field string) schema.CustomizeDiffFunc {
+	return referenceExistsCustomizeDiff(field, func(value string, d *schema.ResourceDiff, config *Config) (string, error) {
+		return f.SelfLink(value, d, config)
+	})
+}
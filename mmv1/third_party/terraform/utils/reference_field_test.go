@@ -0,0 +1,189 @@
+package google
+
+import (
+	"testing"
+)
+
+func TestReferenceField_RelativeLink(t *testing.T) {
+	config := &Config{
+		Project: "default-project",
+		Region:  "default-region",
+		Zone:    "default-zone-a",
+	}
+
+	cases := map[string]struct {
+		Field         ReferenceField
+		Value         string
+		Fields        map[string]interface{}
+		Config        *Config
+		ExpectedLink  string
+		ExpectedError bool
+	}{
+		"network name": {
+			Field:        networkReference,
+			Value:        "my-network",
+			ExpectedLink: "projects/default-project/global/networks/my-network",
+		},
+		"network name with project field": {
+			Field:        networkReference,
+			Value:        "my-network",
+			Fields:       map[string]interface{}{"project": "my-project"},
+			ExpectedLink: "projects/my-project/global/networks/my-network",
+		},
+		"network partial uri": {
+			Field:        networkReference,
+			Value:        "global/networks/my-network",
+			ExpectedLink: "projects/default-project/global/networks/my-network",
+		},
+		"network self link": {
+			Field:        networkReference,
+			Value:        "https://www.googleapis.com/compute/beta/projects/other-project/global/networks/my-network",
+			Fields:       map[string]interface{}{"project": "my-project"},
+			ExpectedLink: "projects/other-project/global/networks/my-network",
+		},
+		"subnetwork name with region field": {
+			Field:        subnetworkReference,
+			Value:        "my-subnetwork",
+			Fields:       map[string]interface{}{"region": "us-east1"},
+			ExpectedLink: "projects/default-project/regions/us-east1/subnetworks/my-subnetwork",
+		},
+		"subnetwork name with zone field": {
+			Field:        subnetworkReference,
+			Value:        "my-subnetwork",
+			Fields:       map[string]interface{}{"zone": "us-east1-b"},
+			ExpectedLink: "projects/default-project/regions/us-east1/subnetworks/my-subnetwork",
+		},
+		"subnetwork name with provider zone": {
+			Field:        subnetworkReference,
+			Value:        "my-subnetwork",
+			Config:       &Config{Project: "default-project", Zone: "us-west1-a"},
+			ExpectedLink: "projects/default-project/regions/us-west1/subnetworks/my-subnetwork",
+		},
+		"subnetwork partial uri": {
+			Field:        subnetworkReference,
+			Value:        "regions/us-east1/subnetworks/my-subnetwork",
+			ExpectedLink: "projects/default-project/regions/us-east1/subnetworks/my-subnetwork",
+		},
+		"subnetwork relative link": {
+			Field:        subnetworkReference,
+			Value:        "projects/my-project/regions/us-east1/subnetworks/my-subnetwork",
+			ExpectedLink: "projects/my-project/regions/us-east1/subnetworks/my-subnetwork",
+		},
+		"instance name": {
+			Field:        instanceReference,
+			Value:        "my-instance",
+			ExpectedLink: "projects/default-project/zones/default-zone-a/instances/my-instance",
+		},
+		"instance zone field": {
+			Field:        instanceReference,
+			Value:        "my-instance",
+			Fields:       map[string]interface{}{"zone": "projects/my-project/zones/us-east1-b"},
+			ExpectedLink: "projects/default-project/zones/us-east1-b/instances/my-instance",
+		},
+		"instance without zone": {
+			Field:         instanceReference,
+			Value:         "my-instance",
+			Config:        &Config{Project: "default-project"},
+			ExpectedError: true,
+		},
+		"empty": {
+			Field:        instanceReference,
+			Value:        "",
+			ExpectedLink: "",
+		},
+	}
+
+	for tn, tc := range cases {
+		c := config
+		if tc.Config != nil {
+			c = tc.Config
+		}
+		fields := tc.Fields
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		d := &ResourceDataMock{FieldsInSchema: fields}
+
+		link, err := tc.Field.RelativeLink(tc.Value, d, c)
+		if tc.ExpectedError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", tn, link)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if link != tc.ExpectedLink {
+			t.Errorf("%s: expected %q, got %q", tn, tc.ExpectedLink, link)
+		}
+	}
+}
+
+func TestReferenceField_Flatten(t *testing.T) {
+	selfLink := "https://www.googleapis.com/compute/beta/projects/my-project/global/networks/my-network"
+
+	cases := map[ReferenceStateForm]string{
+		ReferenceStateSelfLink:     "https://www.googleapis.com/compute/v1/projects/my-project/global/networks/my-network",
+		ReferenceStateRelativeLink: "projects/my-project/global/networks/my-network",
+		ReferenceStateName:         "my-network",
+	}
+
+	for form, expected := range cases {
+		f := networkReference
+		f.StoreAs = form
+		if got := f.Flatten(selfLink); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestReferenceField_DiffSuppress(t *testing.T) {
+	cases := map[string]struct {
+		Old, New           string
+		ExpectDiffSuppress bool
+	}{
+		"same self link, different version": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "https://www.googleapis.com/compute/beta/projects/p/global/networks/n",
+			ExpectDiffSuppress: true,
+		},
+		"self link and relative link": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "projects/p/global/networks/n",
+			ExpectDiffSuppress: true,
+		},
+		"self link and name": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "n",
+			ExpectDiffSuppress: true,
+		},
+		"self link and partial uri": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "global/networks/n",
+			ExpectDiffSuppress: true,
+		},
+		"different project": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "projects/other/global/networks/n",
+			ExpectDiffSuppress: false,
+		},
+		"different name": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "other",
+			ExpectDiffSuppress: false,
+		},
+		"unset": {
+			Old:                "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			New:                "",
+			ExpectDiffSuppress: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if networkReference.DiffSuppress("network", tc.Old, tc.New, nil) != tc.ExpectDiffSuppress {
+			t.Errorf("bad: %s, %q => %q expect DiffSuppress to return %t", tn, tc.Old, tc.New, tc.ExpectDiffSuppress)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Headers request_headers can't set, as the provider sets them itself or
+// they're set by other provider fields.
+var reservedRequestHeaders = map[string]string{
+	"Authorization":         "credentials or access_token",
+	"Content-Length":        "",
+	"Content-Type":          "",
+	"Host":                  "",
+	"User-Agent":            "",
+	"X-Goog-Api-Client":     "",
+	"X-Goog-Request-Reason": "request_reason",
+	"X-Goog-User-Project":   "billing_project and user_project_override",
+}
+
+// HTTP header names are tokens, see RFC 7230 section 3.2.6.
+var requestHeaderNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// validateRequestHeaders validates the request_headers provider field.
+func validateRequestHeaders(v interface{}, k string) (ws []string, errors []error) {
+	headers, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, []error{fmt.Errorf("expected %s to be a map", k)}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !requestHeaderNameRegex.MatchString(name) {
+			errors = append(errors, fmt.Errorf("%s: %q is not a valid header name", k, name))
+			continue
+		}
+		if field, ok := reservedRequestHeaders[textproto.CanonicalMIMEHeaderKey(name)]; ok {
+			if field != "" {
+				errors = append(errors, fmt.Errorf("%s: %s can't be set, use %s instead", k, name, field))
+			} else {
+				errors = append(errors, fmt.Errorf("%s: %s is set by the provider and can't be set", k, name))
+			}
+			continue
+		}
+		if s, _ := headers[name].(string); strings.ContainsAny(s, "\r\n") {
+			errors = append(errors, fmt.Errorf("%s: the value of %s can't contain line breaks", k, name))
+		}
+	}
+	return
+}
+
+// setRequestHeaders sets headers on h.
+func setRequestHeaders(h http.Header, headers map[string]string) {
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+}
+
+// requestHeadersGRPCOptions returns the options adding headers to the
+// metadata of every call made by a gRPC client, as request_headers adds
+// them to HTTP requests.
+func requestHeadersGRPCOptions(headers map[string]string) []option.ClientOption {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	kv := make([]string, 0, 2*len(headers))
+	for name, value := range headers {
+		kv = append(kv, strings.ToLower(name), value)
+	}
+
+	return []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				return invoker(metadata.AppendToOutgoingContext(ctx, kv...), method, req, reply, cc, opts...)
+			})),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(
+			func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return streamer(metadata.AppendToOutgoingContext(ctx, kv...), desc, cc, method, opts...)
+			})),
+	}
+}
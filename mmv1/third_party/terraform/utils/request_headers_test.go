@@ -0,0 +1,50 @@
+package google
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRequestHeaders(t *testing.T) {
+	cases := map[string]struct {
+		headers map[string]interface{}
+		err     string
+	}{
+		"custom headers": {
+			headers: map[string]interface{}{"X-Change-Ticket": "CHG-1234", "x-team": "payments"},
+		},
+		"invalid name": {
+			headers: map[string]interface{}{"X Team": "payments"},
+			err:     "not a valid header name",
+		},
+		"reserved header": {
+			headers: map[string]interface{}{"authorization": "Bearer ya29.token"},
+			err:     "use credentials or access_token instead",
+		},
+		"header set by the provider": {
+			headers: map[string]interface{}{"User-Agent": "curl"},
+			err:     "set by the provider",
+		},
+		"request reason": {
+			headers: map[string]interface{}{"X-Goog-Request-Reason": "audit"},
+			err:     "use request_reason instead",
+		},
+		"line break": {
+			headers: map[string]interface{}{"X-Team": "payments\r\nX-Injected: true"},
+			err:     "line breaks",
+		},
+	}
+
+	for tn, tc := range cases {
+		_, errs := validateRequestHeaders(tc.headers, "request_headers")
+		if tc.err == "" {
+			if len(errs) > 0 {
+				t.Errorf("%s: unexpected errors: %v", tn, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", tn, tc.err, errs)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package google
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RequestHook customizes the requests sendRequestWithOptions sends for a
+// resource type, for resources whose API needs more than their
+// encoder/decoder custom code can do, eg a header on every request or a
+// response field renamed between API versions. Either function may be nil.
+type RequestHook struct {
+	// Name identifies the hook in errors.
+	Name string
+
+	// PreSend is called before the request is sent, and may change its URL,
+	// body or headers.
+	PreSend func(opt *SendRequestOptions) error
+
+	// PostReceive is called with the decoded response of successful requests,
+	// which is nil for empty responses, and returns the response to use.
+	PostReceive func(opt SendRequestOptions, res map[string]interface{}) (map[string]interface{}, error)
+}
+
+var requestHooks = struct {
+	sync.RWMutex
+	byType map[string][]RequestHook
+}{byType: make(map[string][]RequestHook)}
+
+// RegisterRequestHook adds hook to the requests of resourceType, eg
+// google_compute_instance. Hooks of a type are chained in the order they're
+// registered: each PreSend sees the options left by the previous one, and
+// each PostReceive the response returned by the previous one. Hooks are
+// usually registered from init functions.
+func RegisterRequestHook(resourceType string, hook RequestHook) {
+	requestHooks.Lock()
+	defer requestHooks.Unlock()
+	requestHooks.byType[resourceType] = append(requestHooks.byType[resourceType], hook)
+}
+
+func requestHooksFor(resourceType string) []RequestHook {
+	if resourceType == "" {
+		return nil
+	}
+	requestHooks.RLock()
+	defer requestHooks.RUnlock()
+	return requestHooks.byType[resourceType]
+}
+
+// runPreSendHooks runs the PreSend functions of hooks on opt.
+func runPreSendHooks(hooks []RequestHook, opt *SendRequestOptions) error {
+	for _, h := range hooks {
+		if h.PreSend == nil {
+			continue
+		}
+		if err := h.PreSend(opt); err != nil {
+			return fmt.Errorf("Error preparing %s request for %s with hook %q: %s", opt.Method, opt.ResourceType, h.Name, err)
+		}
+	}
+	return nil
+}
+
+// runPostReceiveHooks runs the PostReceive functions of hooks on res.
+func runPostReceiveHooks(hooks []RequestHook, opt SendRequestOptions, res map[string]interface{}) (map[string]interface{}, error) {
+	for _, h := range hooks {
+		if h.PostReceive == nil {
+			continue
+		}
+		var err error
+		res, err = h.PostReceive(opt, res)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s response for %s with hook %q: %s", opt.Method, opt.ResourceType, h.Name, err)
+		}
+	}
+	return res, nil
+}
+
+// renameFieldHook returns a RequestHook for resources whose top level field
+// is named apiField in the API version the provider uses, eg
+//
+//	RegisterRequestHook("google_container_analysis_note", renameFieldHook("attestationAuthority", "attestation"))
+func renameFieldHook(field, apiField string) RequestHook {
+	rename := func(m map[string]interface{}, from, to string) map[string]interface{} {
+		v, ok := m[from]
+		if !ok {
+			return m
+		}
+		renamed := make(map[string]interface{}, len(m))
+		for k, mv := range m {
+			renamed[k] = mv
+		}
+		delete(renamed, from)
+		renamed[to] = v
+		return renamed
+	}
+
+	return RequestHook{
+		Name: fmt.Sprintf("rename %s", field),
+		PreSend: func(opt *SendRequestOptions) error {
+			if opt.Body != nil {
+				opt.Body = rename(opt.Body, field, apiField)
+			}
+			return nil
+		},
+		PostReceive: func(opt SendRequestOptions, res map[string]interface{}) (map[string]interface{}, error) {
+			if res == nil {
+				return nil, nil
+			}
+			return rename(res, apiField, field), nil
+		},
+	}
+}
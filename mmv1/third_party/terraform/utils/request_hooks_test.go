@@ -0,0 +1,138 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func registerTestRequestHooks(t *testing.T, resourceType string, hooks ...RequestHook) {
+	for _, h := range hooks {
+		RegisterRequestHook(resourceType, h)
+	}
+	t.Cleanup(func() {
+		requestHooks.Lock()
+		defer requestHooks.Unlock()
+		delete(requestHooks.byType, resourceType)
+	})
+}
+
+func TestSendResourceRequest_hooks(t *testing.T) {
+	s := newFakeAPIServer(t)
+	var header string
+	s.scripts[fakeAPIKey("POST", "/v1/projects/p/things")] = &fakeAPIScript{
+		handler: func(r *http.Request) fakeAPIResponse {
+			header = r.Header.Get("X-Goog-Request-Params")
+			return fakeAPIResponse{Body: map[string]interface{}{"displayName": "a"}}
+		},
+	}
+
+	var order []string
+	registerTestRequestHooks(t, "google_test_thing",
+		RequestHook{
+			Name: "routing",
+			PreSend: func(opt *SendRequestOptions) error {
+				order = append(order, "routing")
+				opt.Headers = make(http.Header)
+				opt.Headers.Set("X-Goog-Request-Params", "parent=projects/p")
+				return nil
+			},
+		},
+		RequestHook{
+			Name: "rename",
+			PreSend: func(opt *SendRequestOptions) error {
+				order = append(order, "rename")
+				if opt.Headers.Get("X-Goog-Request-Params") == "" {
+					return fmt.Errorf("expected the header set by the previous hook")
+				}
+				return nil
+			},
+			PostReceive: func(opt SendRequestOptions, res map[string]interface{}) (map[string]interface{}, error) {
+				res["name"] = res["displayName"]
+				delete(res, "displayName")
+				return res, nil
+			},
+		},
+		RequestHook{
+			Name: "check",
+			PostReceive: func(opt SendRequestOptions, res map[string]interface{}) (map[string]interface{}, error) {
+				if _, ok := res["name"]; !ok {
+					return nil, fmt.Errorf("expected the response returned by the previous hook")
+				}
+				return res, nil
+			},
+		},
+	)
+
+	res, err := sendResourceRequest(s.Config(), "google_test_thing", "POST", "p", s.URL+"/v1/projects/p/things", "", map[string]interface{}{}, DefaultRequestTimeout)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := strings.Join(order, ","); got != "routing,rename" {
+		t.Errorf("expected hooks to run in the order they were registered, got %s", got)
+	}
+	if header != "parent=projects/p" {
+		t.Errorf("expected the header set by a hook to be sent, got %q", header)
+	}
+	if res["name"] != "a" {
+		t.Errorf("expected the response changed by hooks, got %v", res)
+	}
+
+	// Requests for other types, or without a type, don't run the hooks.
+	order = nil
+	if _, err := sendRequest(s.Config(), "POST", "p", s.URL+"/v1/projects/p/things", "", map[string]interface{}{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(order) != 0 {
+		t.Errorf("expected no hooks to run, got %v", order)
+	}
+}
+
+func TestSendResourceRequest_hookError(t *testing.T) {
+	s := newFakeAPIServer(t)
+	registerTestRequestHooks(t, "google_test_thing", RequestHook{
+		Name: "reject",
+		PreSend: func(opt *SendRequestOptions) error {
+			return fmt.Errorf("rejected")
+		},
+	})
+
+	_, err := sendResourceRequest(s.Config(), "google_test_thing", "GET", "p", s.URL+"/v1/projects/p/things/a", "", nil, DefaultRequestTimeout)
+	if err == nil || !strings.Contains(err.Error(), `hook "reject"`) {
+		t.Fatalf("expected the hook's error, got %v", err)
+	}
+	if n := s.Requests("GET", "/v1/projects/p/things/a"); n != 0 {
+		t.Errorf("expected no request to be sent, got %d", n)
+	}
+}
+
+func TestRenameFieldHook(t *testing.T) {
+	s := newFakeAPIServer(t)
+	var sent map[string]interface{}
+	s.scripts[fakeAPIKey("POST", "/v1/projects/p/notes")] = &fakeAPIScript{
+		handler: func(r *http.Request) fakeAPIResponse {
+			if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+				t.Errorf("unexpected error decoding request: %s", err)
+			}
+			return fakeAPIResponse{Body: map[string]interface{}{"name": "n", "attestation": sent["attestation"]}}
+		},
+	}
+	registerTestRequestHooks(t, "google_test_note", renameFieldHook("attestationAuthority", "attestation"))
+
+	obj := map[string]interface{}{"name": "n", "attestationAuthority": "a"}
+	res, err := sendResourceRequest(s.Config(), "google_test_note", "POST", "p", s.URL+"/v1/projects/p/notes", "", obj, DefaultRequestTimeout)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := sent["attestationAuthority"]; ok || sent["attestation"] != "a" {
+		t.Errorf("expected the field to be sent under its API name, got %v", sent)
+	}
+	if _, ok := obj["attestation"]; ok {
+		t.Errorf("expected the request body not to be modified, got %v", obj)
+	}
+	if _, ok := res["attestation"]; ok || res["attestationAuthority"] != "a" {
+		t.Errorf("expected the field to be returned under its schema name, got %v", res)
+	}
+}
@@ -0,0 +1,40 @@
+package google
+
+import (
+	"net/url"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+// withRequestId returns rawurl with a new UUID in the query parameter param,
+// unless it already has one, eg because the caller is retrying a request.
+func withRequestId(rawurl, param string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	if u.Query().Get(param) != "" {
+		return rawurl, nil
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	return addQueryParams(rawurl, map[string]string{param: id})
+}
+
+// requestIdHook returns a RequestHook sending the requests of a resource type
+// with an idempotency key in the query parameter param, for APIs that accept
+// one, eg
+//
+//	RegisterRequestHook("google_compute_address", requestIdHook("requestId"))
+func requestIdHook(param string) RequestHook {
+	return RequestHook{
+		Name: "request ID",
+		PreSend: func(opt *SendRequestOptions) error {
+			opt.RequestIdParam = param
+			return nil
+		},
+	}
+}
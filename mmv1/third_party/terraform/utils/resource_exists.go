@@ -0,0 +1,143 @@
+// Plan-time checks that resources referenced by a resource exist. Resources
+// opt in by adding referenceExistsCustomizeDiff to their CustomizeDiff for
+// each reference field to check, so a typo in eg a network or key name fails
+// the plan with a clear error instead of failing partway through an apply.
+
+package google
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// How long the existence of a resource is remembered. Resources are often
+// referenced by many others in the same configuration, and a plan only needs
+// each of them checked once.
+const resourceExistsCacheTTL = time.Minute
+
+var selfLinkProjectRegex = regexp.MustCompile("/projects/([^/]+)/")
+
+// resourceExistsCache remembers whether self links were found by
+// checkResourceExists. Only definite answers are cached, not errors.
+type resourceExistsCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]resourceExistsEntry
+}
+
+type resourceExistsEntry struct {
+	exists  bool
+	expires time.Time
+}
+
+func newResourceExistsCache(ttl time.Duration, clock Clock) *resourceExistsCache {
+	return &resourceExistsCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]resourceExistsEntry),
+	}
+}
+
+func (c *resourceExistsCache) get(selfLink string) (exists, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[selfLink]
+	if ok && !c.clock.Now().Before(e.expires) {
+		delete(c.entries, selfLink)
+		return false, false
+	}
+	return e.exists, ok
+}
+
+func (c *resourceExistsCache) set(selfLink string, exists bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[selfLink] = resourceExistsEntry{exists: exists, expires: c.clock.Now().Add(c.ttl)}
+}
+
+// checkResourceExists returns whether the resource at selfLink exists. An
+// error is returned if that can't be determined, eg because the caller can't
+// read the resource.
+func checkResourceExists(config *Config, selfLink string) (bool, error) {
+	if exists, ok := config.resourceExists.get(selfLink); ok {
+		return exists, nil
+	}
+
+	// The project is only used for billing, so it's fine for it to be empty
+	// when the link doesn't name one.
+	project := ""
+	if m := selfLinkProjectRegex.FindStringSubmatch(selfLink); m != nil {
+		project = m[1]
+	}
+
+	_, err := sendRequest(config, "GET", project, selfLink, config.userAgent, nil)
+	if isGoogleApiErrorWithCode(err, 404) {
+		config.resourceExists.set(selfLink, false)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	config.resourceExists.set(selfLink, true)
+	return true, nil
+}
+
+// referenceLinkFunc resolves the value of a reference field to the self link
+// of the referenced resource. An empty link skips the check.
+type referenceLinkFunc func(value string, d *schema.ResourceDiff, config *Config) (string, error)
+
+// kmsCryptoKeyLink resolves a reference to a KMS key or key version to the
+// self link of the key.
+func kmsCryptoKeyLink(value string, _ *schema.ResourceDiff, config *Config) (string, error) {
+	ref, err := parseKmsKeyRef(value)
+	if err != nil {
+		return "", err
+	}
+	return config.KMSBasePath + ref.CryptoKey.cryptoKeyId(), nil
+}
+
+// referenceExistsCustomizeDiff returns a CustomizeDiffFunc checking at plan
+// time that the resource referenced by field exists. References that aren't
+// known yet, eg to resources created in the same apply, are skipped. The
+// check is advisory: if existence can't be determined the plan isn't failed.
+func referenceExistsCustomizeDiff(field string, link referenceLinkFunc) schema.CustomizeDiffFunc {
+	return func(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+		if !d.HasChange(field) || !d.NewValueKnown(field) {
+			return nil
+		}
+		v, ok := d.GetOk(field)
+		if !ok {
+			return nil
+		}
+		config := meta.(*Config)
+
+		selfLink, err := link(v.(string), d, config)
+		if err != nil || selfLink == "" {
+			return err
+		}
+
+		exists, err := checkResourceExists(config, selfLink)
+		if err != nil {
+			log.Printf("[DEBUG] Unable to check that %s exists: %s", selfLink, err)
+			return nil
+		}
+		if !exists {
+			return fmt.Errorf("%s: referenced resource not found: %s", field, selfLink)
+		}
+		return nil
+	}
+}
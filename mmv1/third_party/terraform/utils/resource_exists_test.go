@@ -0,0 +1,85 @@
+package google
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckResourceExists(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/compute/v1/projects/p/global/networks/default", fakeAPIResponse{Body: map[string]interface{}{"name": "default"}})
+	s.Script("GET", "/compute/v1/projects/p/global/networks/typo", fakeAPIError(404, "The resource 'projects/p/global/networks/typo' was not found"))
+	s.Script("GET", "/compute/v1/projects/p/global/networks/private", fakeAPIError(403, "Required 'compute.networks.get' permission"))
+
+	config := s.Config()
+	config.resourceExists = newResourceExistsCache(resourceExistsCacheTTL, systemClock{})
+
+	cases := map[string]struct {
+		exists bool
+		err    bool
+	}{
+		"default": {exists: true},
+		"typo":    {exists: false},
+		"private": {err: true},
+	}
+	for name, tc := range cases {
+		for i := 0; i < 2; i++ {
+			exists, err := checkResourceExists(config, s.URL+"/compute/v1/projects/p/global/networks/"+name)
+			if (err != nil) != tc.err || exists != tc.exists {
+				t.Errorf("%s: expected exists %t and error %t, got %t and %v", name, tc.exists, tc.err, exists, err)
+			}
+		}
+	}
+
+	if n := s.Requests("GET", "/compute/v1/projects/p/global/networks/default"); n != 1 {
+		t.Errorf("expected an existing resource to be checked once, got %d requests", n)
+	}
+	if n := s.Requests("GET", "/compute/v1/projects/p/global/networks/typo"); n != 1 {
+		t.Errorf("expected a missing resource to be checked once, got %d requests", n)
+	}
+	if n := s.Requests("GET", "/compute/v1/projects/p/global/networks/private"); n != 2 {
+		t.Errorf("expected errors not to be cached, got %d requests", n)
+	}
+}
+
+func TestResourceExistsCache_expiry(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	c := newResourceExistsCache(time.Minute, clock)
+
+	c.set("link", true)
+	if exists, ok := c.get("link"); !ok || !exists {
+		t.Errorf("expected the link to be cached as existing")
+	}
+
+	clock.Sleep(time.Minute)
+	if _, ok := c.get("link"); ok {
+		t.Errorf("expected the entry to expire")
+	}
+
+	var nilCache *resourceExistsCache
+	nilCache.set("link", true)
+	if _, ok := nilCache.get("link"); ok {
+		t.Errorf("expected a nil cache to be empty")
+	}
+}
+
+func TestKmsCryptoKeyLink(t *testing.T) {
+	config := &Config{KMSBasePath: "https://cloudkms.googleapis.com/v1/"}
+	want := "https://cloudkms.googleapis.com/v1/projects/p/locations/us/keyRings/r/cryptoKeys/k"
+	for _, ref := range []string{
+		"projects/p/locations/us/keyRings/r/cryptoKeys/k",
+		"projects/p/locations/us/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+	} {
+		got, err := kmsCryptoKeyLink(ref, nil, config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", ref, err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", ref, want, got)
+		}
+	}
+
+	if _, err := kmsCryptoKeyLink("my-key", nil, config); err == nil {
+		t.Errorf("expected an error for an invalid key")
+	}
+}
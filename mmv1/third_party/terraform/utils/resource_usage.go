@@ -0,0 +1,235 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Setting this environment variable to a file path makes the provider write a
+// JSON summary of the resources it created, updated and deleted, by service
+// and location, for platform teams auditing the volume of changes. Like the
+// apply report, it covers the lifetime of the provider process, across all of
+// its provider configurations. The id of the process is added to the file
+// name, as eg google and google-beta run in processes of their own.
+const resourceUsagePathEnvVar = "GOOGLE_RESOURCE_USAGE_PATH"
+
+// Kinds of resource operations counted by resourceUsage.
+const (
+	resourceUsageCreated = "created"
+	resourceUsageUpdated = "updated"
+	resourceUsageDeleted = "deleted"
+)
+
+// resourceUsageCounts counts the resources created, updated and deleted.
+type resourceUsageCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+}
+
+func (c *resourceUsageCounts) add(op string) {
+	switch op {
+	case resourceUsageCreated:
+		c.Created++
+	case resourceUsageUpdated:
+		c.Updated++
+	case resourceUsageDeleted:
+		c.Deleted++
+	}
+}
+
+// resourceUsageKey groups the operations of resourceUsage.
+type resourceUsageKey struct {
+	Service  string `json:"service"`
+	Location string `json:"location"`
+}
+
+// resourceUsageEntry is the counts of a service and location in the summary.
+type resourceUsageEntry struct {
+	resourceUsageKey
+	resourceUsageCounts
+}
+
+// resourceUsageSummary is the summary of the operations of an apply, as
+// written to the export file.
+type resourceUsageSummary struct {
+	Started  time.Time            `json:"started"`
+	Updated  time.Time            `json:"updated"`
+	Total    resourceUsageCounts  `json:"total"`
+	Services []resourceUsageEntry `json:"services"`
+}
+
+// resourceUsage counts the resources created, updated and deleted by the
+// provider process by service and location. The totals are logged after
+// every change, and the summary written to file if it's set.
+type resourceUsage struct {
+	file  *reportFile
+	clock Clock
+
+	mu      sync.Mutex
+	started time.Time
+	counts  map[resourceUsageKey]*resourceUsageCounts
+}
+
+var (
+	resourceUsageOnce sync.Once
+	resourceUsageInst *resourceUsage
+)
+
+// currentResourceUsage returns the resourceUsage of the provider process,
+// shared by all of its provider configurations, writing its summary to the
+// path set by GOOGLE_RESOURCE_USAGE_PATH if any.
+func currentResourceUsage(clock Clock) *resourceUsage {
+	resourceUsageOnce.Do(func() {
+		path := os.Getenv(resourceUsagePathEnvVar)
+		if path != "" {
+			path = resourceUsageProcessPath(path, os.Getpid())
+			log.Printf("[INFO] Writing a summary of resource usage to %s", path)
+		}
+		resourceUsageInst = newResourceUsage(path, clock)
+	})
+	return resourceUsageInst
+}
+
+func newResourceUsage(path string, clock Clock) *resourceUsage {
+	u := &resourceUsage{
+		clock:   clock,
+		started: clock.Now(),
+		counts:  make(map[resourceUsageKey]*resourceUsageCounts),
+	}
+	if path != "" {
+		u.file = newReportFile("resource usage", path, func() ([]byte, error) {
+			u.mu.Lock()
+			defer u.mu.Unlock()
+			return json.MarshalIndent(u.summaryLocked(), "", "  ")
+		})
+	}
+	return u
+}
+
+// resourceUsageProcessPath adds pid to the file name of path, before its
+// extension, eg resource-usage.1234.json for resource-usage.json.
+func resourceUsageProcessPath(path string, pid int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), pid, ext)
+}
+
+// record counts an operation op of a resource of service in location.
+func (u *resourceUsage) record(service, location, op string) {
+	if u == nil {
+		return
+	}
+
+	u.mu.Lock()
+	key := resourceUsageKey{Service: service, Location: location}
+	c, ok := u.counts[key]
+	if !ok {
+		c = &resourceUsageCounts{}
+		u.counts[key] = c
+	}
+	c.add(op)
+	var total resourceUsageCounts
+	for _, c := range u.counts {
+		total.Created += c.Created
+		total.Updated += c.Updated
+		total.Deleted += c.Deleted
+	}
+	u.mu.Unlock()
+
+	log.Printf("[INFO] Resources changed so far: %d created, %d updated, %d deleted", total.Created, total.Updated, total.Deleted)
+	if u.file != nil {
+		u.file.scheduleWrite()
+	}
+}
+
+func (u *resourceUsage) summaryLocked() resourceUsageSummary {
+	s := resourceUsageSummary{
+		Started:  u.started,
+		Updated:  u.clock.Now(),
+		Services: make([]resourceUsageEntry, 0, len(u.counts)),
+	}
+	for k, c := range u.counts {
+		s.Services = append(s.Services, resourceUsageEntry{k, *c})
+		s.Total.Created += c.Created
+		s.Total.Updated += c.Updated
+		s.Total.Deleted += c.Deleted
+	}
+	sort.Slice(s.Services, func(i, j int) bool {
+		if s.Services[i].Service != s.Services[j].Service {
+			return s.Services[i].Service < s.Services[j].Service
+		}
+		return s.Services[i].Location < s.Services[j].Location
+	})
+	return s
+}
+
+// resourceUsageService returns the service of resourceType, the product in
+// its name, eg compute for google_compute_instance.
+func resourceUsageService(resourceType string) string {
+	parts := strings.SplitN(strings.TrimPrefix(resourceType, "google_"), "_", 2)
+	return parts[0]
+}
+
+// resourceUsageLocation returns the region or location of the resource d
+// belongs to, the region of its zone for zonal resources, or global.
+func resourceUsageLocation(r *schema.Resource, d *schema.ResourceData) string {
+	for _, field := range []string{"region", "location"} {
+		if _, ok := r.Schema[field]; !ok {
+			continue
+		}
+		if v, ok := d.Get(field).(string); ok && v != "" {
+			location := GetResourceNameFromSelfLink(v)
+			// The location of eg zonal GKE clusters is a zone
+			if isZone(location) {
+				return getRegionFromZone(location)
+			}
+			return location
+		}
+	}
+	if _, ok := r.Schema["zone"]; ok {
+		if v, ok := d.Get("zone").(string); ok && v != "" {
+			return getRegionFromZone(GetResourceNameFromSelfLink(v))
+		}
+	}
+	return "global"
+}
+
+// withResourceUsageTracking counts the successful creates, updates and
+// deletes of r, a resourceType, in the resourceUsage of the provider. The
+// resource's functions are converted to their context-aware variants.
+func withResourceUsageTracking(resourceType string, r *schema.Resource) *schema.Resource {
+	service := resourceUsageService(resourceType)
+	wrap := func(op string, f func(*schema.ResourceData, interface{}) error, fc func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		if f == nil && fc == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			var diags diag.Diagnostics
+			if fc != nil {
+				diags = fc(ctx, d, meta)
+			} else {
+				diags = diag.FromErr(f(d, meta))
+			}
+			if config, ok := meta.(*Config); ok && !diags.HasError() {
+				config.resourceUsage.record(service, resourceUsageLocation(r, d), op)
+			}
+			return diags
+		}
+	}
+
+	r.CreateContext, r.Create = wrap(resourceUsageCreated, r.Create, r.CreateContext), nil
+	r.UpdateContext, r.Update = wrap(resourceUsageUpdated, r.Update, r.UpdateContext), nil
+	r.DeleteContext, r.Delete = wrap(resourceUsageDeleted, r.Delete, r.DeleteContext), nil
+	return r
+}
@@ -0,0 +1,131 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestWithResourceUsageTracking(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	clock := &fakeClock{now: time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)}
+	usage := newResourceUsage(path, clock)
+	config := &Config{resourceUsage: usage}
+
+	fail := false
+	r := withResourceUsageTracking("google_compute_disk", &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"zone": {Type: schema.TypeString, Optional: true},
+		},
+		Create: func(d *schema.ResourceData, meta interface{}) error {
+			if fail {
+				return fmt.Errorf("quota exceeded")
+			}
+			d.SetId("disk")
+			return nil
+		},
+		Update: func(d *schema.ResourceData, meta interface{}) error { return nil },
+		Delete: func(d *schema.ResourceData, meta interface{}) error {
+			d.SetId("")
+			return nil
+		},
+	})
+	if r.Create != nil || r.CreateContext == nil || r.Delete != nil || r.DeleteContext == nil {
+		t.Fatalf("expected the functions to be converted to their context-aware variants")
+	}
+
+	zonal := r.TestResourceData()
+	if err := zonal.Set("zone", "us-central1-a"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, f := range []func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics{r.CreateContext, r.UpdateContext, r.DeleteContext} {
+		if diags := f(context.Background(), zonal, config); diags.HasError() {
+			t.Fatalf("unexpected error: %v", diags)
+		}
+	}
+	if diags := r.CreateContext(context.Background(), r.TestResourceData(), config); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	fail = true
+	if diags := r.CreateContext(context.Background(), r.TestResourceData(), config); !diags.HasError() {
+		t.Fatalf("expected the create to fail")
+	}
+
+	usage.file.flush()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the summary to be written: %s", err)
+	}
+	var summary resourceUsageSummary
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatalf("unexpected error decoding the summary: %s", err)
+	}
+	expected := []resourceUsageEntry{
+		{resourceUsageKey{"compute", "global"}, resourceUsageCounts{Created: 1}},
+		{resourceUsageKey{"compute", "us-central1"}, resourceUsageCounts{Created: 1, Updated: 1, Deleted: 1}},
+	}
+	if fmt.Sprint(summary.Services) != fmt.Sprint(expected) {
+		t.Errorf("expected changes counted by location, without the failed create, got %v", summary.Services)
+	}
+	if summary.Total != (resourceUsageCounts{Created: 2, Updated: 1, Deleted: 1}) {
+		t.Errorf("expected totals of every location, got %+v", summary.Total)
+	}
+}
+
+func TestResourceUsageService(t *testing.T) {
+	cases := map[string]string{
+		"google_compute_instance":          "compute",
+		"google_storage_bucket_iam_member": "storage",
+		"google_project":                   "project",
+	}
+	for resourceType, expected := range cases {
+		if got := resourceUsageService(resourceType); got != expected {
+			t.Errorf("expected the service of %s to be %s, got %s", resourceType, expected, got)
+		}
+	}
+}
+
+func TestResourceUsageLocation(t *testing.T) {
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"location": {Type: schema.TypeString, Optional: true},
+		},
+	}
+	cases := map[string]string{
+		"":                        "global",
+		"us-central1":             "us-central1",
+		"US":                      "US",
+		"us-central1-a":           "us-central1",
+		"zones/europe-west1-b":    "europe-west1",
+		"regions/asia-northeast1": "asia-northeast1",
+	}
+	for location, expected := range cases {
+		d := r.TestResourceData()
+		if err := d.Set("location", location); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := resourceUsageLocation(r, d); got != expected {
+			t.Errorf("expected the location of %q to be %q, got %q", location, expected, got)
+		}
+	}
+}
+
+func TestResourceUsageProcessPath(t *testing.T) {
+	cases := map[string]string{
+		"resource-usage.json":       "resource-usage.1234.json",
+		"/tmp/usage":                "/tmp/usage.1234",
+		"out.d/resource-usage.json": "out.d/resource-usage.1234.json",
+	}
+	for path, expected := range cases {
+		if got := resourceUsageProcessPath(path, 1234); got != expected {
+			t.Errorf("expected the path for %q to be %q, got %q", path, expected, got)
+		}
+	}
+}
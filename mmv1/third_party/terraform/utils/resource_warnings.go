@@ -0,0 +1,141 @@
+package google
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// addResourceWarning reports a warning about the resource d belongs to, eg
+// that it was changed outside of Terraform, as a warning diagnostic once the
+// resource's function returns. It's meant for shared code, like expanders
+// and importers, which has no way to return diagnostics itself. Warnings are
+// always logged, and only reported for a *schema.ResourceData: other resource
+// data, like the *schema.ResourceDiff of a CustomizeDiff, isn't passed to a
+// function that could report them, so they'd never be taken.
+func addResourceWarning(d TerraformResourceData, config *Config, summary, detail string) {
+	log.Printf("[WARN] %s: %s", summary, detail)
+	rd, ok := d.(*schema.ResourceData)
+	if config == nil || !ok {
+		return
+	}
+	config.resourceWarnings.add(rd, diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  summary,
+		Detail:   detail,
+	})
+}
+
+// resourceWarnings holds the warnings added by addResourceWarning, keyed by
+// the resource data they were added for, until the resource's function
+// returns and withResourceWarningDiagnostics reports them. Warnings added by
+// importers are keyed by the imported resource's ID instead, as they're
+// reported by the read following the import, which gets other resource data.
+type resourceWarnings struct {
+	mu       sync.Mutex
+	warnings map[interface{}]diag.Diagnostics
+}
+
+// importedResourceWarningsKey is the key of the warnings of an import.
+type importedResourceWarningsKey struct {
+	resource *schema.Resource
+	id       string
+}
+
+func newResourceWarnings() *resourceWarnings {
+	return &resourceWarnings{
+		warnings: make(map[interface{}]diag.Diagnostics),
+	}
+}
+
+func (w *resourceWarnings) add(key interface{}, diags ...diag.Diagnostic) {
+	if w == nil || key == nil || len(diags) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warnings[key] = append(w.warnings[key], diags...)
+}
+
+// take returns and forgets the warnings added for key.
+func (w *resourceWarnings) take(key interface{}) diag.Diagnostics {
+	if w == nil || key == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	diags := w.warnings[key]
+	delete(w.warnings, key)
+	return diags
+}
+
+// withResourceWarningDiagnostics reports the warnings added by
+// addResourceWarning while running the functions of r, a resource or data
+// source, as warning diagnostics. The functions are converted to their
+// context-aware variants, which are the only ones able to return diagnostics.
+func withResourceWarningDiagnostics(r *schema.Resource) *schema.Resource {
+	wrap := func(f func(*schema.ResourceData, interface{}) error, fc func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics, imported bool) func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics {
+		if f == nil && fc == nil {
+			return nil
+		}
+		return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+			// The ID is read before the function, which may clear it
+			id := d.Id()
+			var diags diag.Diagnostics
+			if fc != nil {
+				diags = fc(ctx, d, meta)
+			} else {
+				diags = diag.FromErr(f(d, meta))
+			}
+			if config, ok := meta.(*Config); ok {
+				if imported {
+					diags = append(diags, config.resourceWarnings.take(importedResourceWarningsKey{r, id})...)
+				}
+				diags = append(diags, config.resourceWarnings.take(d)...)
+			}
+			return diags
+		}
+	}
+
+	r.CreateContext, r.Create = wrap(r.Create, r.CreateContext, false), nil
+	r.ReadContext, r.Read = wrap(r.Read, r.ReadContext, true), nil
+	r.UpdateContext, r.Update = wrap(r.Update, r.UpdateContext, false), nil
+	r.DeleteContext, r.Delete = wrap(r.Delete, r.DeleteContext, false), nil
+
+	if r.Importer != nil && (r.Importer.State != nil || r.Importer.StateContext != nil) {
+		state, stateContext := r.Importer.State, r.Importer.StateContext
+		r.Importer.State = nil
+		r.Importer.StateContext = func(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+			var imported []*schema.ResourceData
+			var err error
+			if stateContext != nil {
+				imported, err = stateContext(ctx, d, meta)
+			} else {
+				imported, err = state(d, meta)
+			}
+			config, ok := meta.(*Config)
+			if !ok {
+				return imported, err
+			}
+			diags := config.resourceWarnings.take(d)
+			if err != nil {
+				// The import failed, so there's no read to report them.
+				// They've been logged already.
+				return imported, err
+			}
+			for _, id := range imported {
+				diags = append(diags, config.resourceWarnings.take(id)...)
+			}
+			if len(imported) > 0 {
+				config.resourceWarnings.add(importedResourceWarningsKey{r, imported[0].Id()}, diags...)
+			}
+			return imported, nil
+		}
+	}
+	return r
+}
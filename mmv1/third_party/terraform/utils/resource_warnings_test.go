@@ -0,0 +1,74 @@
+package google
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestWithResourceWarningDiagnostics(t *testing.T) {
+	config := &Config{resourceWarnings: newResourceWarnings()}
+	r := withResourceWarningDiagnostics(&schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {Type: schema.TypeString, Optional: true},
+		},
+		Read: func(d *schema.ResourceData, meta interface{}) error {
+			addResourceWarning(d, meta.(*Config), `Field "name" is deprecated`, "Use labels instead.")
+			return nil
+		},
+		Importer: &schema.ResourceImporter{
+			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				addResourceWarning(d, meta.(*Config), "Imported a legacy resource", "It will be migrated on the next apply.")
+				return []*schema.ResourceData{d}, nil
+			},
+		},
+	})
+	if r.Read != nil || r.ReadContext == nil || r.Importer.State != nil || r.Importer.StateContext == nil {
+		t.Fatalf("expected the functions to be converted to their context-aware variants")
+	}
+
+	d := r.TestResourceData()
+	d.SetId("a")
+	diags := r.ReadContext(context.Background(), d, config)
+	if len(diags) != 1 || diags[0].Severity != diag.Warning || diags[0].Summary != `Field "name" is deprecated` {
+		t.Errorf("expected a deprecation warning, got %v", diags)
+	}
+	if diags := r.ReadContext(context.Background(), d, config); len(diags) != 1 {
+		t.Errorf("expected warnings to be reported once per call, got %v", diags)
+	}
+
+	// Warnings added by the importer are reported by the next read
+	d = r.TestResourceData()
+	d.SetId("b")
+	if _, err := r.Importer.StateContext(context.Background(), d, config); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	read := r.TestResourceData()
+	read.SetId("b")
+	diags = r.ReadContext(context.Background(), read, config)
+	if len(diags) != 2 || diags[0].Summary != "Imported a legacy resource" {
+		t.Errorf("expected the import warning and the deprecation warning, got %v", diags)
+	}
+	if len(config.resourceWarnings.warnings) != 0 {
+		t.Errorf("expected all warnings to be taken, got %v", config.resourceWarnings.warnings)
+	}
+}
+
+func TestAddResourceWarning_onlyLogged(t *testing.T) {
+	// Without a config, or for resource data that isn't a ResourceData, the
+	// warning is only logged
+	addResourceWarning(&ResourceDataMock{}, nil, "summary", "detail")
+	config := &Config{resourceWarnings: newResourceWarnings()}
+	addResourceWarning(&ResourceDataMock{}, config, "summary", "detail")
+	if len(config.resourceWarnings.warnings) != 0 {
+		t.Errorf("expected no warnings to be recorded for a mock, got %v", config.resourceWarnings.warnings)
+	}
+
+	var nilWarnings *resourceWarnings
+	nilWarnings.add("key", diag.Diagnostic{Summary: "summary"})
+	if diags := nilWarnings.take("key"); len(diags) != 0 {
+		t.Errorf("expected no warnings from a nil collector, got %v", diags)
+	}
+}
@@ -0,0 +1,105 @@
+// Capturing the response headers Google support asks for when investigating
+// a failed request, such as request and upload IDs, so users can give them
+// without rerunning with request logging or a packet capture. They're logged
+// for every response, and added to the errors of failed requests returned by
+// resources and data sources.
+
+package google
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/googleapi"
+)
+
+// capturedResponseHeaders are the response headers identifying a request to
+// Google, or explaining why it was throttled.
+var capturedResponseHeaders = []string{
+	"X-GUploader-UploadID",
+	"X-Goog-Request-Id",
+	"X-Request-Id",
+	"X-Cloud-Trace-Context",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"Retry-After",
+}
+
+// formatResponseHeaders returns the capturedResponseHeaders of header, or ""
+// if it has none of them.
+func formatResponseHeaders(header http.Header) string {
+	var captured []string
+	for _, k := range capturedResponseHeaders {
+		if v := header.Values(k); len(v) > 0 {
+			captured = append(captured, fmt.Sprintf("%s: %s", k, strings.Join(v, ", ")))
+		}
+	}
+	return strings.Join(captured, ", ")
+}
+
+// responseHeadersTransport is a http.RoundTripper logging the captured headers
+// of responses. The headers of failed responses are also kept by the
+// googleapi.Error they're turned into, see withResponseHeaders.
+type responseHeadersTransport struct {
+	internal http.RoundTripper
+}
+
+func (t *responseHeadersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.internal.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	if headers := formatResponseHeaders(res.Header); headers != "" {
+		log.Printf("[DEBUG] Google API response %d to %s %s%s: %s", res.StatusCode, req.Method, req.URL.Host, req.URL.Path, headers)
+	}
+	return res, nil
+}
+
+// withResponseHeaders adds the captured headers of the failed response of
+// err, a googleapi.Error or an error wrapping one with errwrap or %w, to err.
+// The returned error wraps err, so predicates still match it. Errors whose
+// googleapi.Error was formatted into text with %s have lost their headers,
+// which were only logged, so resources wrap errors with errwrap.Wrapf.
+func withResponseHeaders(err error) error {
+	if err == nil {
+		return nil
+	}
+	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
+	if !ok || gerr == nil {
+		if !errors.As(err, &gerr) {
+			return err
+		}
+	}
+	headers := formatResponseHeaders(gerr.Header)
+	if headers == "" {
+		return err
+	}
+	return errwrap.Wrap(fmt.Errorf("%s\n\nIf you contact Google support about this error, include the response headers %s", err, headers), err)
+}
+
+// withResponseHeaderDetails adds the captured headers of failed responses to
+// the errors returned by the functions of r, a resource or data source.
+// Functions returning diagnostics are left alone, as diagnostics don't keep
+// the googleapi.Error the headers are read from.
+func withResponseHeaderDetails(r *schema.Resource) *schema.Resource {
+	wrap := func(f func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+		if f == nil {
+			return nil
+		}
+		return func(d *schema.ResourceData, meta interface{}) error {
+			return withResponseHeaders(f(d, meta))
+		}
+	}
+
+	r.Create = wrap(r.Create)
+	r.Read = wrap(r.Read)
+	r.Update = wrap(r.Update)
+	r.Delete = wrap(r.Delete)
+	return r
+}
@@ -0,0 +1,83 @@
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"google.golang.org/api/googleapi"
+)
+
+func TestResponseHeadersTransport(t *testing.T) {
+	s := newFakeAPIServer(t)
+	failed := fakeAPIError(400, "Invalid bucket name: 'Bucket'")
+	failed.Header = http.Header{"X-Guploader-Uploadid": []string{"upload-1"}}
+	s.Script("POST", "/storage/v1/b", failed)
+
+	config := s.Config()
+	config.client = &http.Client{Transport: &responseHeadersTransport{
+		internal: s.Client().Transport,
+	}}
+	_, err := sendRequest(config, "POST", "p", s.URL+"/storage/v1/b", "", map[string]interface{}{"name": "Bucket"})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	// The headers are kept by the error of the failed response
+	wrapped := withResponseHeaders(err)
+	if !strings.Contains(wrapped.Error(), "X-GUploader-UploadID: upload-1") {
+		t.Errorf("expected the error to include the headers of its response, got %s", wrapped)
+	}
+}
+
+func TestWithResponseHeaders(t *testing.T) {
+	err := &googleapi.Error{
+		Code:    429,
+		Message: "Quota exceeded",
+		Header:  http.Header{"X-Goog-Request-Id": []string{"abc"}, "Retry-After": []string{"30"}},
+	}
+	wrapped := withResponseHeaders(err)
+	if !strings.Contains(wrapped.Error(), "X-Goog-Request-Id: abc, Retry-After: 30") {
+		t.Errorf("expected the error to include the response headers, got %s", wrapped)
+	}
+	if !isGoogleApiErrorWithCode(wrapped, 429) {
+		t.Errorf("expected the error to wrap the googleapi.Error")
+	}
+
+	// Errors without captured headers are left alone
+	plain := &googleapi.Error{Code: 400, Message: "Bad request"}
+	if got := withResponseHeaders(plain); got != plain {
+		t.Errorf("expected the error to be unchanged, got %s", got)
+	}
+
+	// Errors wrapped with %w keep their headers
+	if got := withResponseHeaders(fmt.Errorf("Error creating Bucket: %w", err)); !strings.Contains(got.Error(), "X-Goog-Request-Id: abc") {
+		t.Errorf("expected an error wrapped with %%w to include the response headers, got %s", got)
+	}
+
+	// Errors formatted as text are left alone, even if another failed
+	// response had the same message
+	text := fmt.Errorf("Error creating Bucket: %s", err)
+	if got := withResponseHeaders(text); got != text {
+		t.Errorf("expected an error formatted as text to be unchanged, got %s", got)
+	}
+}
+
+func TestWithResponseHeaderDetails(t *testing.T) {
+	failed := &googleapi.Error{
+		Code:    400,
+		Message: "Instance is not running",
+		Header:  http.Header{"X-Request-Id": []string{"req-1"}},
+	}
+	r := withResponseHeaderDetails(&schema.Resource{
+		Read: func(*schema.ResourceData, interface{}) error {
+			return failed
+		},
+	})
+
+	if err := r.Read(nil, &Config{}); err == nil || !strings.Contains(err.Error(), "X-Request-Id: req-1") {
+		t.Errorf("expected the error to include the response headers, got %v", err)
+	}
+}
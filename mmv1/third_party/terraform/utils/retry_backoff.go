@@ -0,0 +1,174 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// RetryBackoff is how long to wait before retrying an error, as classified by
+// a RetryBackoffPredicateFunc.
+type RetryBackoff int
+
+const (
+	// RetryBackoffExponential waits longer after every attempt, from
+	// retryBackoffMin up to retryBackoffMax.
+	RetryBackoffExponential RetryBackoff = iota
+	// RetryBackoffImmediate retries at once, for errors that another attempt
+	// fixes by itself, such as a fingerprint mismatch.
+	RetryBackoffImmediate
+	// RetryBackoffShort waits retryBackoffShort, for state expected to settle
+	// within seconds.
+	RetryBackoffShort
+	// RetryBackoffLong waits retryBackoffLong, for quotas refreshing every
+	// minute.
+	RetryBackoffLong
+	// RetryBackoffServerHint waits as long as the server asked to, see
+	// serverRetryDelay, or else backs off exponentially.
+	RetryBackoffServerHint
+)
+
+const (
+	retryBackoffMin   = 500 * time.Millisecond
+	retryBackoffMax   = 10 * time.Second
+	retryBackoffShort = 2 * time.Second
+	retryBackoffLong  = time.Minute
+)
+
+func (b RetryBackoff) String() string {
+	switch b {
+	case RetryBackoffImmediate:
+		return "immediate"
+	case RetryBackoffShort:
+		return "short"
+	case RetryBackoffLong:
+		return "long"
+	case RetryBackoffServerHint:
+		return "server hint"
+	default:
+		return "exponential"
+	}
+}
+
+// RetryBackoffPredicateFunc returns whether an error is retryable like a
+// RetryErrorPredicateFunc, and how long to wait before retrying it.
+type RetryBackoffPredicateFunc func(error) (bool, RetryBackoff, string)
+
+// withRetryBackoff returns a RetryBackoffPredicateFunc retrying the errors
+// predicate retries with backoff.
+func withRetryBackoff(predicate RetryErrorPredicateFunc, backoff RetryBackoff) RetryBackoffPredicateFunc {
+	return func(err error) (bool, RetryBackoff, string) {
+		retry, reason := predicate(err)
+		return retry, backoff, reason
+	}
+}
+
+// Backoff predicates that should apply to every retry are added here. They're
+// checked before the retry predicates, which retry with
+// RetryBackoffServerHint.
+var defaultErrorBackoffPredicates = []RetryBackoffPredicateFunc{
+	// Per minute quotas refresh slowly, and retrying sooner only uses up more
+	// of the next minute's quota.
+	withRetryBackoff(is403QuotaExceededPerMinuteError, RetryBackoffLong),
+}
+
+// RetryOptions configures retryWithOptions.
+type RetryOptions struct {
+	RetryFunc func() error
+	// Timeout is how long to retry for. Zero retries until MaxAttempts.
+	Timeout time.Duration
+	// MaxAttempts is how many times RetryFunc is called at most. Zero is no
+	// limit.
+	MaxAttempts int
+	// ErrorRetryPredicates are retried, in addition to
+	// defaultErrorRetryPredicates, with RetryBackoffServerHint.
+	ErrorRetryPredicates []RetryErrorPredicateFunc
+	// ErrorBackoffPredicates are retried, in addition to
+	// defaultErrorBackoffPredicates, with the backoff they return. They're
+	// checked before the defaults, and before ErrorRetryPredicates.
+	ErrorBackoffPredicates []RetryBackoffPredicateFunc
+	// RetryPredicates are checked before every other predicate, eg to retry
+	// gRPC errors with grpcCodeRetryPredicate.
+	RetryPredicates []RetryPredicate
+	// Clock defaults to the system clock.
+	Clock Clock
+}
+
+// retryWithOptions calls opt.RetryFunc until it succeeds, returns an error
+// that isn't retryable, or opt.Timeout or opt.MaxAttempts is reached. The
+// last error is returned then.
+func retryWithOptions(opt RetryOptions) error {
+	clock := opt.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if opt.Timeout == 0 && opt.MaxAttempts == 0 {
+		return fmt.Errorf("Error retrying: neither a timeout nor a maximum number of attempts was set")
+	}
+
+	var deadline time.Time
+	if opt.Timeout > 0 {
+		deadline = clock.Now().Add(opt.Timeout)
+	}
+	exponential := retryBackoffMin
+	for attempt := 1; ; attempt++ {
+		err := opt.RetryFunc()
+		if err == nil {
+			return nil
+		}
+
+		ok, reason, backoff := checkRetryPredicates(err, opt.retryPredicates())
+		if !ok || (opt.MaxAttempts > 0 && attempt >= opt.MaxAttempts) {
+			return err
+		}
+
+		var d time.Duration
+		switch backoff {
+		case RetryBackoffImmediate:
+		case RetryBackoffShort:
+			d = retryBackoffShort
+		case RetryBackoffLong:
+			d = retryBackoffLong
+		default:
+			if hint, ok := serverRetryDelay(err); ok && backoff == RetryBackoffServerHint {
+				d = jitterServerRetryDelay(hint)
+				break
+			}
+			d = exponential
+			if exponential *= 2; exponential > retryBackoffMax {
+				exponential = retryBackoffMax
+			}
+		}
+
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(clock.Now())
+			if remaining <= 0 {
+				return err
+			}
+			if d > remaining {
+				d = remaining
+			}
+		}
+		log.Printf("[DEBUG] Retrying after %s (%s backoff): %s", d, backoff, reason)
+		if d > 0 {
+			clock.Sleep(d)
+		}
+	}
+}
+
+// retryPredicates returns the predicates retryWithOptions checks errors
+// against, in order.
+func (opt RetryOptions) retryPredicates() []RetryPredicate {
+	preds := append([]RetryPredicate{}, opt.RetryPredicates...)
+	for _, pred := range opt.ErrorBackoffPredicates {
+		preds = append(preds, pred)
+	}
+	for _, pred := range defaultErrorBackoffPredicates {
+		preds = append(preds, pred)
+	}
+	preds = append(preds, defaultRetryPredicates()...)
+	for _, pred := range opt.ErrorRetryPredicates {
+		preds = append(preds, pred)
+	}
+	return preds
+}
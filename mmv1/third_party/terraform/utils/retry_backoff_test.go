@@ -0,0 +1,163 @@
+package google
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetryWithOptions_backoff(t *testing.T) {
+	fingerprintMismatch := &googleapi.Error{Code: 412, Message: "Supplied fingerprint does not match current metadata fingerprint."}
+	perMinuteQuota := &googleapi.Error{
+		Code: 403,
+		Body: "Quota exceeded for quota metric 'Queries' and limit 'Queries per minute' of service 'compute.googleapis.com' for consumer 'project_number:11111111'.",
+	}
+	notReady := fmt.Errorf("resource is not ready")
+	isNotReady := func(err error) (bool, string) {
+		return err == notReady, "not ready"
+	}
+
+	cases := map[string]struct {
+		err                    error
+		errorRetryPredicates   []RetryErrorPredicateFunc
+		errorBackoffPredicates []RetryBackoffPredicateFunc
+		expectedWait           time.Duration
+	}{
+		"immediate": {
+			err:                    fingerprintMismatch,
+			errorBackoffPredicates: []RetryBackoffPredicateFunc{withRetryBackoff(isFingerprintError, RetryBackoffImmediate)},
+			expectedWait:           0,
+		},
+		"short": {
+			err:                    notReady,
+			errorBackoffPredicates: []RetryBackoffPredicateFunc{withRetryBackoff(isNotReady, RetryBackoffShort)},
+			expectedWait:           retryBackoffShort,
+		},
+		"long by default for per minute quotas": {
+			err:          perMinuteQuota,
+			expectedWait: retryBackoffLong,
+		},
+		"exponential without a server hint": {
+			err:          &googleapi.Error{Code: 503},
+			expectedWait: retryBackoffMin,
+		},
+		"retry predicates back off exponentially": {
+			err:                  notReady,
+			errorRetryPredicates: []RetryErrorPredicateFunc{isNotReady},
+			expectedWait:         retryBackoffMin,
+		},
+	}
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(0, 0)}
+			attempts := 0
+			err := retryWithOptions(RetryOptions{
+				RetryFunc: func() error {
+					attempts++
+					if attempts == 1 {
+						return tc.err
+					}
+					return nil
+				},
+				Timeout:                time.Hour,
+				ErrorRetryPredicates:   tc.errorRetryPredicates,
+				ErrorBackoffPredicates: tc.errorBackoffPredicates,
+				Clock:                  clock,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if waited := clock.now.Sub(time.Unix(0, 0)); waited != tc.expectedWait {
+				t.Errorf("expected to wait %s before retrying, waited %s", tc.expectedWait, waited)
+			}
+		})
+	}
+}
+
+func TestRetryWithOptions_exponential(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts := 0
+	err := retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			attempts++
+			if attempts <= 7 {
+				return &googleapi.Error{Code: 500}
+			}
+			return nil
+		},
+		Timeout: time.Hour,
+		Clock:   clock,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 0.5s, 1s, 2s, 4s, 8s, then capped at 10s
+	expected := 35500 * time.Millisecond
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != expected {
+		t.Errorf("expected to wait %s in total, waited %s", expected, waited)
+	}
+}
+
+func TestRetryWithOptions_limits(t *testing.T) {
+	unavailable := &googleapi.Error{Code: 503}
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts := 0
+	err := retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			attempts++
+			return unavailable
+		},
+		MaxAttempts: 3,
+		Clock:       clock,
+	})
+	if err != unavailable || attempts != 3 {
+		t.Errorf("expected the last error after 3 attempts, got %v after %d", err, attempts)
+	}
+
+	clock = &fakeClock{now: time.Unix(0, 0)}
+	err = retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			return unavailable
+		},
+		Timeout: time.Minute,
+		Clock:   clock,
+	})
+	if err != unavailable {
+		t.Errorf("expected the last error once timed out, got %v", err)
+	}
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != time.Minute {
+		t.Errorf("expected to stop retrying after the timeout, waited %s", waited)
+	}
+
+	attempts = 0
+	notRetryable := &googleapi.Error{Code: 400}
+	err = retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			attempts++
+			return notRetryable
+		},
+		Timeout: time.Minute,
+		Clock:   &fakeClock{},
+	})
+	if err != notRetryable || attempts != 1 {
+		t.Errorf("expected the error without retrying, got %v after %d attempts", err, attempts)
+	}
+}
+
+func TestMetadataRetryWrapper(t *testing.T) {
+	attempts := 0
+	err := MetadataRetryWrapper(func() error {
+		attempts++
+		return &googleapi.Error{Code: 412, Message: "Invalid fingerprint."}
+	})
+	if err == nil || err.Error() != "Failed to update metadata after 10 retries" {
+		t.Errorf("expected the retries to run out, got %v", err)
+	}
+	if attempts != METADATA_FINGERPRINT_RETRIES {
+		t.Errorf("expected %d attempts, got %d", METADATA_FINGERPRINT_RETRIES, attempts)
+	}
+}
@@ -0,0 +1,99 @@
+package google
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPredicate decides whether an error is retried, why, and how long to
+// wait before retrying it. RetryErrorPredicateFunc and
+// RetryBackoffPredicateFunc adapt the predicates of REST errors to it, and
+// grpcCodeRetryPredicate retries gRPC errors by their code.
+type RetryPredicate interface {
+	ShouldRetry(err error) (retry bool, reason string, backoff RetryBackoff)
+}
+
+// ShouldRetry retries the errors f retries with RetryBackoffServerHint.
+func (f RetryErrorPredicateFunc) ShouldRetry(err error) (bool, string, RetryBackoff) {
+	retry, reason := f(err)
+	return retry, reason, RetryBackoffServerHint
+}
+
+func (f RetryBackoffPredicateFunc) ShouldRetry(err error) (bool, string, RetryBackoff) {
+	retry, backoff, reason := f(err)
+	return retry, reason, backoff
+}
+
+// grpcCodeRetryPredicate retries the errors of gRPC clients, which are
+// *status.Error rather than *googleapi.Error, with one of codes.
+type grpcCodeRetryPredicate struct {
+	codes   []codes.Code
+	reason  string
+	backoff RetryBackoff
+}
+
+func (p grpcCodeRetryPredicate) ShouldRetry(err error) (bool, string, RetryBackoff) {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false, "", p.backoff
+	}
+	for _, c := range p.codes {
+		if s.Code() == c {
+			return true, fmt.Sprintf("%s (gRPC code %s)", p.reason, c), p.backoff
+		}
+	}
+	return false, "", p.backoff
+}
+
+// Retry predicates of gRPC errors that should apply to every retry, like
+// isCommonRetryableErrorCode for REST errors.
+var defaultGrpcRetryPredicates = []RetryPredicate{
+	grpcCodeRetryPredicate{
+		codes:   []codes.Code{codes.Unavailable},
+		reason:  "Retryable error code",
+		backoff: RetryBackoffExponential,
+	},
+	grpcCodeRetryPredicate{
+		codes:   []codes.Code{codes.ResourceExhausted},
+		reason:  "Waiting for quota to refresh",
+		backoff: RetryBackoffLong,
+	},
+}
+
+// defaultRetryPredicates returns defaultErrorRetryPredicates and
+// defaultGrpcRetryPredicates.
+func defaultRetryPredicates() []RetryPredicate {
+	preds := make([]RetryPredicate, 0, len(defaultErrorRetryPredicates)+len(defaultGrpcRetryPredicates))
+	for _, pred := range defaultErrorRetryPredicates {
+		preds = append(preds, pred)
+	}
+	return append(preds, defaultGrpcRetryPredicates...)
+}
+
+// checkRetryPredicates returns the decision of the first of predicates
+// retrying topErr or an error it wraps, or false if none does. The reason is
+// logged here, so every retry loop reports it the same way.
+func checkRetryPredicates(topErr error, predicates []RetryPredicate) (bool, string, RetryBackoff) {
+	for _, pred := range predicates {
+		var reason string
+		var backoff RetryBackoff
+		found := false
+		errwrap.Walk(topErr, func(werr error) {
+			if found {
+				return
+			}
+			if retry, r, b := pred.ShouldRetry(werr); retry {
+				log.Printf("[DEBUG] Dismissed an error as retryable. %s - %s", r, werr)
+				reason, backoff, found = r, b, true
+			}
+		})
+		if found {
+			return true, reason, backoff
+		}
+	}
+	return false, "", RetryBackoffExponential
+}
@@ -0,0 +1,123 @@
+package google
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckRetryPredicates(t *testing.T) {
+	notReady := fmt.Errorf("resource is not ready")
+	isNotReady := RetryErrorPredicateFunc(func(err error) (bool, string) {
+		return err == notReady, "Waiting for the resource"
+	})
+
+	cases := map[string]struct {
+		err             error
+		predicates      []RetryPredicate
+		expectedRetry   bool
+		expectedBackoff RetryBackoff
+	}{
+		"REST predicate": {
+			err:             &googleapi.Error{Code: 503},
+			predicates:      defaultRetryPredicates(),
+			expectedRetry:   true,
+			expectedBackoff: RetryBackoffServerHint,
+		},
+		"wrapped REST predicate": {
+			err:             errwrap.Wrapf("Error creating: {{err}}", notReady),
+			predicates:      []RetryPredicate{isNotReady},
+			expectedRetry:   true,
+			expectedBackoff: RetryBackoffServerHint,
+		},
+		"backoff predicate": {
+			err:             notReady,
+			predicates:      []RetryPredicate{withRetryBackoff(isNotReady, RetryBackoffShort)},
+			expectedRetry:   true,
+			expectedBackoff: RetryBackoffShort,
+		},
+		"gRPC predicate": {
+			err:             status.Error(codes.Unavailable, "unavailable"),
+			predicates:      defaultRetryPredicates(),
+			expectedRetry:   true,
+			expectedBackoff: RetryBackoffExponential,
+		},
+		"gRPC quota": {
+			err:             status.Error(codes.ResourceExhausted, "quota exceeded"),
+			predicates:      defaultRetryPredicates(),
+			expectedRetry:   true,
+			expectedBackoff: RetryBackoffLong,
+		},
+		"gRPC errors aren't REST errors": {
+			err:        status.Error(codes.InvalidArgument, "invalid"),
+			predicates: defaultRetryPredicates(),
+		},
+		"REST errors aren't gRPC errors": {
+			err:        &googleapi.Error{Code: 400},
+			predicates: []RetryPredicate{bigtableTableStateRetryPredicate},
+		},
+		"first matching predicate": {
+			err:             notReady,
+			predicates:      []RetryPredicate{withRetryBackoff(isNotReady, RetryBackoffImmediate), isNotReady},
+			expectedRetry:   true,
+			expectedBackoff: RetryBackoffImmediate,
+		},
+	}
+	for tn, tc := range cases {
+		retry, reason, backoff := checkRetryPredicates(tc.err, tc.predicates)
+		if retry != tc.expectedRetry {
+			t.Errorf("%s: expected retry to be %t, got %t", tn, tc.expectedRetry, retry)
+			continue
+		}
+		if !retry {
+			continue
+		}
+		if backoff != tc.expectedBackoff {
+			t.Errorf("%s: expected %s backoff, got %s", tn, tc.expectedBackoff, backoff)
+		}
+		if reason == "" {
+			t.Errorf("%s: expected a reason to retry", tn)
+		}
+	}
+}
+
+func TestRetryWithOptions_grpc(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	attempts := 0
+	err := retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			attempts++
+			if attempts == 1 {
+				return status.Error(codes.FailedPrecondition, "table is being modified")
+			}
+			return nil
+		},
+		Timeout:         time.Hour,
+		RetryPredicates: []RetryPredicate{bigtableTableStateRetryPredicate},
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if waited := clock.now.Sub(time.Unix(0, 0)); waited != retryBackoffMin {
+		t.Errorf("expected to wait %s before retrying, waited %s", retryBackoffMin, waited)
+	}
+
+	attempts = 0
+	err = retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			attempts++
+			return status.Error(codes.FailedPrecondition, "table is being modified")
+		},
+		Timeout: time.Hour,
+		Clock:   clock,
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("expected FailedPrecondition not to be retried by default, got %v after %d attempts", err, attempts)
+	}
+}
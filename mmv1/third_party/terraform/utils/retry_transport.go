@@ -36,8 +36,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -71,9 +73,29 @@ func (t *retryTransport) WithAddedPredicates(predicates ...RetryErrorPredicateFu
 	return &copyT
 }
 
+// Returns a shallow copy of the retry transport that gives up after
+// maxAttempts requests, even if the request could still be retried before the
+// context deadline. 0 means no limit.
+func (t *retryTransport) WithMaxAttempts(maxAttempts int) *retryTransport {
+	copyT := *t
+	copyT.maxAttempts = maxAttempts
+	return &copyT
+}
+
+// Returns a shallow copy of the retry transport that waits between retries on
+// clock.
+func (t *retryTransport) WithClock(clock Clock) *retryTransport {
+	copyT := *t
+	copyT.clock = clock
+	return &copyT
+}
+
 type retryTransport struct {
 	retryPredicates []RetryErrorPredicateFunc
 	internal        http.RoundTripper
+	maxAttempts     int
+	// clock is the clock retries wait on, the real clock if unset
+	clock Clock
 }
 
 // RoundTrip implements the RoundTripper interface method.
@@ -93,6 +115,11 @@ func (t *retryTransport) RoundTrip(req *http.Request) (resp *http.Response, resp
 		}()
 	}
 
+	clock := t.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
 	attempts := 0
 	backoff := time.Millisecond * 500
 	nextBackoff := time.Millisecond * 500
@@ -133,14 +160,28 @@ Retry:
 			log.Printf("[DEBUG] Retry Transport: Stopping retries, last request failed with non-retryable error: %s", retryErr.Err)
 			break Retry
 		}
+		if isUnsafeTimeoutRetry(req, respErr) {
+			log.Printf("[DEBUG] Retry Transport: Stopping retries, last %s request timed out and may have reached the server: %s", req.Method, retryErr.Err)
+			break Retry
+		}
+		if t.maxAttempts > 0 && attempts >= t.maxAttempts {
+			log.Printf("[DEBUG] Retry Transport: Stopping retries, reached max attempts (%d): %s", t.maxAttempts, retryErr.Err)
+			break Retry
+		}
 
-		log.Printf("[DEBUG] Retry Transport: Waiting %s before trying request again", backoff)
+		// Wait as long as the server asked to instead, if it did.
+		wait := backoff
+		if d, ok := serverRetryDelay(retryErr.Err); ok {
+			wait = jitterServerRetryDelay(d)
+		}
+
+		log.Printf("[DEBUG] Retry Transport: Waiting %s before trying request again", wait)
 		select {
 		case <-ctx.Done():
 			log.Printf("[DEBUG] Retry Transport: Stopping retries, context done: %v", ctx.Err())
 			break Retry
-		case <-time.After(backoff):
-			log.Printf("[DEBUG] Retry Transport: Finished waiting %s before next retry", backoff)
+		case <-clock.After(wait):
+			log.Printf("[DEBUG] Retry Transport: Finished waiting %s before next retry", wait)
 
 			// Fibonnaci backoff - 0.5, 1, 1.5, 2.5, 4, 6.5, 10.5, ...
 			lastBackoff := backoff
@@ -153,6 +194,44 @@ Retry:
 	return resp, respErr
 }
 
+// isUnsafeTimeoutRetry returns whether err is a timeout of req that can't be
+// retried safely. Timed out requests may have reached the server, so sending a
+// non-idempotent one again, eg the POST creating a resource, could apply it
+// twice. They're only retried if their method is idempotent, or they're known
+// not to have been sent.
+func isUnsafeTimeoutRetry(req *http.Request, err error) bool {
+	if err == nil || isIdempotentHttpMethod(req.Method) || isUnsentRequestError(err) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return strings.Contains(err.Error(), "i/o timeout")
+}
+
+func isIdempotentHttpMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isUnsentRequestError returns whether err happened before the request was
+// sent, ie while resolving, connecting to or negotiating TLS with the server.
+func isUnsentRequestError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return strings.Contains(err.Error(), "TLS handshake timeout")
+}
+
 // copyHttpRequest provides an copy of the given HTTP request for one RoundTrip.
 // If the request has a non-empty body (io.ReadCloser), the body is deep copied
 // so it can be consumed.
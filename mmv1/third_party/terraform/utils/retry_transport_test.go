@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"google.golang.org/api/googleapi"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -103,6 +104,81 @@ func TestRetryTransport_ContextTimeout(t *testing.T) {
 	testRetryTransport_checkFailedWhileRetrying(t, resp, err)
 }
 
+func TestRetryTransport_MaxAttempts(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(testRetryTransportCodeRetry)
+	}))
+	defer ts.Close()
+
+	client := ts.Client()
+	client.Transport = (&retryTransport{
+		internal:        http.DefaultTransport,
+		retryPredicates: []RetryErrorPredicateFunc{testRetryTransportRetryPredicate},
+	}).WithMaxAttempts(3)
+
+	resp, err := client.Get(ts.URL)
+	testRetryTransport_checkFailedWhileRetrying(t, resp, err)
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "i/o timeout" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+type testFailingRoundTripper struct {
+	err      error
+	attempts int
+}
+
+func (rt *testFailingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.attempts++
+	return nil, rt.err
+}
+
+// Check that timed out requests are only retried when it's safe to
+func TestRetryTransport_Timeouts(t *testing.T) {
+	readTimeout := &net.OpError{Op: "read", Net: "tcp", Err: testTimeoutError{}}
+	dialTimeout := &net.OpError{Op: "dial", Net: "tcp", Err: testTimeoutError{}}
+	cases := map[string]struct {
+		method   string
+		err      error
+		attempts int
+	}{
+		"GET timeout":            {method: "GET", err: readTimeout, attempts: 3},
+		"DELETE timeout":         {method: "DELETE", err: readTimeout, attempts: 3},
+		"POST timeout":           {method: "POST", err: readTimeout, attempts: 1},
+		"PATCH timeout":          {method: "PATCH", err: readTimeout, attempts: 1},
+		"POST connect timeout":   {method: "POST", err: dialTimeout, attempts: 3},
+		"POST wrapped timeout":   {method: "POST", err: fmt.Errorf("read tcp: i/o timeout"), attempts: 1},
+		"POST handshake timeout": {method: "POST", err: fmt.Errorf("net/http: TLS handshake timeout"), attempts: 3},
+	}
+
+	for tn, tc := range cases {
+		rt := &testFailingRoundTripper{err: tc.err}
+		transport := (&retryTransport{
+			internal:        rt,
+			retryPredicates: []RetryErrorPredicateFunc{isTransientNetworkError},
+		}).WithMaxAttempts(3)
+
+		req, err := http.NewRequest(tc.method, "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := transport.RoundTrip(req); err == nil {
+			t.Errorf("%s: expected error", tn)
+		}
+		if rt.attempts != tc.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", tn, tc.attempts, rt.attempts)
+		}
+	}
+}
+
 // Check for no errors if the request succeeds after a certain amount of time
 func TestRetryTransport_SuccessWithBody(t *testing.T) {
 	ts, client := setUpRetryTransportServerClient(
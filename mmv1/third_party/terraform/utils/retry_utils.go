@@ -1,11 +1,7 @@
 package google
 
 import (
-	"log"
 	"time"
-
-	"github.com/hashicorp/errwrap"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
 func retry(retryFunc func() error) error {
@@ -17,38 +13,36 @@ func retryTime(retryFunc func() error, minutes int) error {
 }
 
 func retryTimeDuration(retryFunc func() error, duration time.Duration, errorRetryPredicates ...RetryErrorPredicateFunc) error {
-	return resource.Retry(duration, func() *resource.RetryError {
-		err := retryFunc()
-		if err == nil {
-			return nil
-		}
-		if isRetryableError(err, errorRetryPredicates...) {
-			return resource.RetryableError(err)
-		}
-		return resource.NonRetryableError(err)
+	return retryTimeDurationWithClock(systemClock{}, retryFunc, duration, errorRetryPredicates...)
+}
+
+// retryTimeDurationWithClock is retryTimeDuration, waiting for the delays
+// asked for by the server on clock. See retryWithOptions.
+func retryTimeDurationWithClock(clock Clock, retryFunc func() error, duration time.Duration, errorRetryPredicates ...RetryErrorPredicateFunc) error {
+	return retryWithOptions(RetryOptions{
+		RetryFunc:            retryFunc,
+		Timeout:              duration,
+		ErrorRetryPredicates: errorRetryPredicates,
+		Clock:                clock,
 	})
 }
 
+// deleteWithDependentRetry retries a delete call while the API reports that the
+// resource is still in use by a dependent resource, up until timeout.
+func deleteWithDependentRetry(deleteFunc func() error, timeout time.Duration) error {
+	return retryTimeDuration(deleteFunc, timeout, isResourceInUseError)
+}
+
 func isRetryableError(topErr error, customPredicates ...RetryErrorPredicateFunc) bool {
 	if topErr == nil {
 		return false
 	}
 
-	retryPredicates := append(
-		// Global error retry predicates are registered in this default list.
-		defaultErrorRetryPredicates,
-		customPredicates...)
-
-	// Check all wrapped errors for a retryable error status.
-	isRetryable := false
-	errwrap.Walk(topErr, func(werr error) {
-		for _, pred := range retryPredicates {
-			if predRetry, predReason := pred(werr); predRetry {
-				log.Printf("[DEBUG] Dismissed an error as retryable. %s - %s", predReason, werr)
-				isRetryable = true
-				return
-			}
-		}
-	})
+	// Global error retry predicates are registered in defaultRetryPredicates.
+	retryPredicates := defaultRetryPredicates()
+	for _, pred := range customPredicates {
+		retryPredicates = append(retryPredicates, pred)
+	}
+	isRetryable, _, _ := checkRetryPredicates(topErr, retryPredicates)
 	return isRetryable
 }
@@ -0,0 +1,216 @@
+// Generic conversion between Terraform state (snake_case) and API JSON
+// (camelCase) driven by a resource schema. Intended for handwritten resources
+// and utilities whose API objects map directly onto their schema; fields that
+// need custom handling should be expanded/flattened separately.
+
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// fieldNameMapper converts between Terraform and API field names. Names are
+// converted between snake_case and camelCase unless an override is given.
+type fieldNameMapper struct {
+	// Terraform field name -> API field name
+	overrides map[string]string
+	// API field name -> Terraform field name
+	reverse map[string]string
+}
+
+// newFieldNameMapper creates a mapper with overrides keyed by Terraform field
+// name, eg {"ip_cidr_range": "ipCidrRange", "os_type": "osType"}.
+func newFieldNameMapper(overrides map[string]string) *fieldNameMapper {
+	if overrides == nil {
+		overrides = map[string]string{}
+	}
+	return &fieldNameMapper{
+		overrides: overrides,
+		reverse:   reverseStringMap(overrides),
+	}
+}
+
+func (m *fieldNameMapper) ToApi(tfName string) string {
+	if m != nil {
+		if v, ok := m.overrides[tfName]; ok {
+			return v
+		}
+	}
+	return SnakeToCamelCase(tfName)
+}
+
+func (m *fieldNameMapper) ToTerraform(apiName string) string {
+	if m != nil {
+		if v, ok := m.reverse[apiName]; ok {
+			return v
+		}
+	}
+	return CamelToSnakeCase(apiName)
+}
+
+func SnakeToCamelCase(s string) string {
+	p := SnakeToPascalCase(s)
+	if p == "" {
+		return p
+	}
+	return strings.ToLower(p[:1]) + p[1:]
+}
+
+func CamelToSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word unless we're inside an acronym, eg "ipV6Address"
+			// -> "ip_v6_address" but "HTTPProxy" -> "http_proxy".
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// expandObject builds an API object from a Terraform value shaped like a
+// nested block (a []interface{} with a single map, or the map itself) by
+// walking the given schema. Empty values and output-only fields are omitted.
+func expandObject(v interface{}, s map[string]*schema.Schema, m *fieldNameMapper) (map[string]interface{}, error) {
+	raw, ok := singleNestedMap(v)
+	if !ok {
+		return nil, nil
+	}
+
+	obj := make(map[string]interface{})
+	for k, sch := range s {
+		if sch.Computed && !sch.Optional {
+			continue
+		}
+
+		val, err := expandSchemaValue(raw[k], sch, m)
+		if err != nil {
+			return nil, fmt.Errorf("error expanding %s: %s", k, err)
+		}
+		if val == nil || isEmptyValue(reflect.ValueOf(val)) {
+			continue
+		}
+		obj[m.ToApi(k)] = val
+	}
+	return obj, nil
+}
+
+func expandSchemaValue(v interface{}, sch *schema.Schema, m *fieldNameMapper) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch sch.Type {
+	case schema.TypeList, schema.TypeSet:
+		l := v
+		if set, ok := v.(*schema.Set); ok {
+			l = set.List()
+		}
+		items, ok := l.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for list value", v)
+		}
+
+		elem, ok := sch.Elem.(*schema.Resource)
+		if !ok {
+			return items, nil
+		}
+
+		if sch.MaxItems == 1 {
+			return expandObject(items, elem.Schema, m)
+		}
+
+		objs := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			obj, err := expandObject(item, elem.Schema, m)
+			if err != nil {
+				return nil, err
+			}
+			if obj == nil {
+				continue
+			}
+			objs = append(objs, obj)
+		}
+		return objs, nil
+	}
+	return v, nil
+}
+
+// flattenObject converts an API object into the Terraform representation of a
+// single nested block ([]interface{} with one map) by walking the given schema.
+func flattenObject(v interface{}, s map[string]*schema.Schema, m *fieldNameMapper) []interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok || obj == nil {
+		return nil
+	}
+	return []interface{}{flattenObjectFields(obj, s, m)}
+}
+
+func flattenObjectFields(obj map[string]interface{}, s map[string]*schema.Schema, m *fieldNameMapper) map[string]interface{} {
+	transformed := make(map[string]interface{})
+	for k, sch := range s {
+		transformed[k] = flattenSchemaValue(obj[m.ToApi(k)], sch, m)
+	}
+	return transformed
+}
+
+func flattenSchemaValue(v interface{}, sch *schema.Schema, m *fieldNameMapper) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch sch.Type {
+	case schema.TypeInt:
+		return flattenInt64(v)
+	case schema.TypeList, schema.TypeSet:
+		elem, ok := sch.Elem.(*schema.Resource)
+		if !ok {
+			return v
+		}
+
+		if _, ok := v.(map[string]interface{}); ok {
+			return flattenObject(v, elem.Schema, m)
+		}
+
+		l, ok := v.([]interface{})
+		if !ok {
+			return v
+		}
+		transformed := make([]interface{}, 0, len(l))
+		for _, raw := range l {
+			obj, ok := raw.(map[string]interface{})
+			if !ok || len(obj) < 1 {
+				// Do not include empty json objects coming back from the api
+				continue
+			}
+			transformed = append(transformed, flattenObjectFields(obj, elem.Schema, m))
+		}
+		return transformed
+	}
+	return v
+}
+
+func singleNestedMap(v interface{}) (map[string]interface{}, bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return val, true
+	case []interface{}:
+		if len(val) == 0 || val[0] == nil {
+			return nil, false
+		}
+		raw, ok := val[0].(map[string]interface{})
+		return raw, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,127 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestFieldNameMapper(t *testing.T) {
+	m := newFieldNameMapper(map[string]string{"os_type": "OSType"})
+	cases := []struct {
+		Terraform, Api string
+	}{
+		{"boot_disk", "bootDisk"},
+		{"ip_cidr_range", "ipCidrRange"},
+		{"name", "name"},
+		{"os_type", "OSType"},
+	}
+
+	for _, tc := range cases {
+		if got := m.ToApi(tc.Terraform); got != tc.Api {
+			t.Errorf("ToApi(%q): expected %q, got %q", tc.Terraform, tc.Api, got)
+		}
+		if got := m.ToTerraform(tc.Api); got != tc.Terraform {
+			t.Errorf("ToTerraform(%q): expected %q, got %q", tc.Api, tc.Terraform, got)
+		}
+	}
+}
+
+func TestCamelToSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"bootDisk":      "boot_disk",
+		"HTTPProxy":     "http_proxy",
+		"selfLink":      "self_link",
+		"enableIpV6":    "enable_ip_v6",
+		"alreadysnaked": "alreadysnaked",
+	}
+
+	for input, expected := range cases {
+		if got := CamelToSnakeCase(input); got != expected {
+			t.Errorf("CamelToSnakeCase(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestExpandFlattenObject(t *testing.T) {
+	s := map[string]*schema.Schema{
+		"display_name": {Type: schema.TypeString, Optional: true},
+		"node_count":   {Type: schema.TypeInt, Optional: true},
+		"self_link":    {Type: schema.TypeString, Computed: true},
+		"tags": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"auto_scaling": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"min_node_count": {Type: schema.TypeInt, Optional: true},
+					"max_node_count": {Type: schema.TypeInt, Optional: true},
+				},
+			},
+		},
+	}
+
+	state := []interface{}{
+		map[string]interface{}{
+			"display_name": "foo",
+			"node_count":   3,
+			"self_link":    "ignored",
+			"tags":         []interface{}{"a", "b"},
+			"auto_scaling": []interface{}{
+				map[string]interface{}{
+					"min_node_count": 1,
+					"max_node_count": 0,
+				},
+			},
+		},
+	}
+
+	obj, err := expandObject(state, s, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedObj := map[string]interface{}{
+		"displayName": "foo",
+		"nodeCount":   3,
+		"tags":        []interface{}{"a", "b"},
+		"autoScaling": map[string]interface{}{
+			"minNodeCount": 1,
+		},
+	}
+	if !reflect.DeepEqual(obj, expectedObj) {
+		t.Fatalf("bad expand: expected %#v, got %#v", expectedObj, obj)
+	}
+
+	// API returns int64 values as strings and numbers as float64
+	res := map[string]interface{}{
+		"displayName": "foo",
+		"nodeCount":   "3",
+		"selfLink":    "https://example.com/foo",
+		"autoScaling": map[string]interface{}{
+			"minNodeCount": float64(1),
+		},
+	}
+	expectedState := []interface{}{
+		map[string]interface{}{
+			"display_name": "foo",
+			"node_count":   int64(3),
+			"self_link":    "https://example.com/foo",
+			"tags":         nil,
+			"auto_scaling": []interface{}{
+				map[string]interface{}{
+					"min_node_count": 1,
+					"max_node_count": nil,
+				},
+			},
+		},
+	}
+	if got := flattenObject(res, s, nil); !reflect.DeepEqual(got, expectedState) {
+		t.Fatalf("bad flatten: expected %#v, got %#v", expectedState, got)
+	}
+}
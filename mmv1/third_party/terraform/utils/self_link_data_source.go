@@ -0,0 +1,110 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// Matches the self link or relative link of a Compute resource, capturing its
+// project, location (zone or region, empty for global resources), collection
+// and name.
+var computeSelfLinkRegex = regexp.MustCompile(`^(?:.*/)?projects/([^/]+)/(?:global|regions/([^/]+)|zones/([^/]+))/([^/]+)/([^/]+)$`)
+
+// parseComputeSelfLink parses the self link of a Compute resource, of any API
+// version, or its relative link into the resource's components, as a
+// ReferenceField describing its collection and the parts referencing it.
+func parseComputeSelfLink(link string) (ReferenceField, referenceParts, error) {
+	m := computeSelfLinkRegex.FindStringSubmatch(link)
+	if m == nil {
+		return ReferenceField{}, referenceParts{}, fmt.Errorf("%q isn't the self link of a Compute resource", link)
+	}
+
+	f := ReferenceField{ResourceType: m[4], Location: Global}
+	p := referenceParts{Project: m[1], Name: m[5]}
+	switch {
+	case m[2] != "":
+		f.Location = Regional
+		p.Location = m[2]
+	case m[3] != "":
+		f.Location = Zonal
+		p.Location = m[3]
+	}
+	return f, p, nil
+}
+
+// dataSourceFromSelfLink returns a data source reading the Compute resource
+// in collection (eg "instances") at the self link it's given, exposing every
+// field of resource. It backs data sources like
+// google_compute_disk_from_self_link, for configurations only holding a self
+// link, eg an attribute of another resource, which would otherwise have to
+// split it into a name, project and location.
+func dataSourceFromSelfLink(resource *schema.Resource, collection string) *schema.Resource {
+	dsSchema := datasourceSchemaFromResourceSchema(resource.Schema)
+	if _, ok := dsSchema["self_link"]; !ok {
+		dsSchema["self_link"] = &schema.Schema{Type: schema.TypeString}
+	}
+	addRequiredFieldsToSchema(dsSchema, "self_link")
+
+	return &schema.Resource{
+		ReadContext: dataSourceFromSelfLinkRead(resource, collection),
+		Schema:      dsSchema,
+	}
+}
+
+// dataSourceFromSelfLinkRead fills in the fields of the data source naming
+// the resource from its self link, and reads it with whichever of the
+// resource's read functions is set, as wrapped resources only have
+// ReadContext.
+func dataSourceFromSelfLinkRead(resource *schema.Resource, collection string) schema.ReadContextFunc {
+	return func(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		selfLink := d.Get("self_link").(string)
+		f, p, err := parseComputeSelfLink(selfLink)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if f.ResourceType != collection {
+			return diag.Errorf("%q is the self link of a resource in %s, not %s", selfLink, f.ResourceType, collection)
+		}
+
+		fields := map[string]string{
+			"project": p.Project,
+			"name":    p.Name,
+		}
+		switch f.Location {
+		case Zonal:
+			fields["zone"] = p.Location
+		case Regional:
+			fields["region"] = p.Location
+		}
+		for k, v := range fields {
+			if _, ok := resource.Schema[k]; !ok {
+				continue
+			}
+			if err := d.Set(k, v); err != nil {
+				return diag.Errorf("Error setting %s: %s", k, err)
+			}
+		}
+
+		d.SetId(f.relativeLink(p))
+		var diags diag.Diagnostics
+		switch {
+		case resource.ReadContext != nil:
+			diags = resource.ReadContext(ctx, d, meta)
+		case resource.ReadWithoutTimeout != nil:
+			diags = resource.ReadWithoutTimeout(ctx, d, meta)
+		default:
+			diags = diag.FromErr(resource.Read(d, meta))
+		}
+		if diags.HasError() {
+			return diags
+		}
+		if d.Id() == "" {
+			return append(diags, diag.Errorf("%s not found", selfLink)...)
+		}
+		return diags
+	}
+}
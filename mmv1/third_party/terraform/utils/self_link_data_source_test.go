@@ -0,0 +1,124 @@
+package google
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestParseComputeSelfLink(t *testing.T) {
+	cases := map[string]struct {
+		link         string
+		collection   string
+		location     LocationType
+		parts        referenceParts
+		relativeLink string
+		err          bool
+	}{
+		"zonal self link": {
+			link:         "https://compute.googleapis.com/compute/beta/projects/p/zones/us-central1-a/instances/i",
+			collection:   "instances",
+			location:     Zonal,
+			parts:        referenceParts{Project: "p", Location: "us-central1-a", Name: "i"},
+			relativeLink: "projects/p/zones/us-central1-a/instances/i",
+		},
+		"regional relative link": {
+			link:         "projects/p/regions/us-central1/subnetworks/s",
+			collection:   "subnetworks",
+			location:     Regional,
+			parts:        referenceParts{Project: "p", Location: "us-central1", Name: "s"},
+			relativeLink: "projects/p/regions/us-central1/subnetworks/s",
+		},
+		"global self link": {
+			link:         "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+			collection:   "networks",
+			location:     Global,
+			parts:        referenceParts{Project: "p", Name: "n"},
+			relativeLink: "projects/p/global/networks/n",
+		},
+		"name": {
+			link: "n",
+			err:  true,
+		},
+		"partial link": {
+			link: "zones/us-central1-a/instances/i",
+			err:  true,
+		},
+	}
+	for tn, tc := range cases {
+		f, p, err := parseComputeSelfLink(tc.link)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if f.ResourceType != tc.collection || f.Location != tc.location || p != tc.parts {
+			t.Errorf("%s: expected %s %v %+v, got %s %v %+v", tn, tc.collection, tc.location, tc.parts, f.ResourceType, f.Location, p)
+		}
+		if got := f.relativeLink(p); got != tc.relativeLink {
+			t.Errorf("%s: expected relative link %s, got %s", tn, tc.relativeLink, got)
+		}
+	}
+}
+
+func TestDataSourceFromSelfLink(t *testing.T) {
+	var readId, readZone string
+	resource := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name":         {Type: schema.TypeString, Required: true},
+			"zone":         {Type: schema.TypeString, Optional: true},
+			"project":      {Type: schema.TypeString, Optional: true},
+			"machine_type": {Type: schema.TypeString, Required: true},
+			"self_link":    {Type: schema.TypeString, Computed: true},
+		},
+		Read: func(d *schema.ResourceData, meta interface{}) error {
+			readId, readZone = d.Id(), d.Get("zone").(string)
+			return d.Set("machine_type", "e2-medium")
+		},
+	}
+	ds := dataSourceFromSelfLink(resource, "instances")
+
+	d := schema.TestResourceDataRaw(t, ds.Schema, map[string]interface{}{
+		"self_link": "https://compute.googleapis.com/compute/v1/projects/p/zones/us-central1-a/instances/i",
+	})
+	if diags := ds.ReadContext(context.Background(), d, &Config{}); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if readId != "projects/p/zones/us-central1-a/instances/i" || readZone != "us-central1-a" {
+		t.Errorf("expected the resource to be read by its relative link and zone, got %q and %q", readId, readZone)
+	}
+	if got := d.Get("machine_type"); got != "e2-medium" {
+		t.Errorf("expected the resource's fields to be exposed, got machine_type %q", got)
+	}
+
+	d = schema.TestResourceDataRaw(t, ds.Schema, map[string]interface{}{
+		"self_link": "projects/p/global/networks/n",
+	})
+	if diags := ds.ReadContext(context.Background(), d, &Config{}); !diags.HasError() {
+		t.Errorf("expected an error reading a link to another collection")
+	}
+
+	// Wrapped resources only have context-aware functions
+	read := resource.Read
+	resource.Read = nil
+	resource.ReadContext = func(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+		return diag.FromErr(read(d, meta))
+	}
+	ds = dataSourceFromSelfLink(resource, "instances")
+	d = schema.TestResourceDataRaw(t, ds.Schema, map[string]interface{}{
+		"self_link": "projects/p/zones/us-central1-b/instances/i",
+	})
+	if diags := ds.ReadContext(context.Background(), d, &Config{}); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+	if readZone != "us-central1-b" {
+		t.Errorf("expected the resource to be read with its ReadContext, got zone %q", readZone)
+	}
+}
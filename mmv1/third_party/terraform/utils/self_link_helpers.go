@@ -37,20 +37,100 @@ func compareSelfLinkRelativePaths(_, old, new string, _ *schema.ResourceData) bo
 // compareSelfLinkOrResourceName checks if two resources are the same resource
 //
 // Use this method when the field accepts either a name or a self_link referencing a resource.
-// The value we store (i.e. `old` in this method), must be a self_link.
-func compareSelfLinkOrResourceName(_, old, new string, _ *schema.ResourceData) bool {
-	newParts := strings.Split(new, "/")
-
-	if len(newParts) == 1 {
-		// `new` is a name
-		// `old` is always a self_link
-		if GetResourceNameFromSelfLink(old) == newParts[0] {
-			return true
-		}
+// The value we store (i.e. `old` in this method), must be a self_link. A name or
+// a path omitting the project refers to a resource in the region of the
+// resource d, if it's known. Projects are only compared if the field has a
+// sibling `{k}_project` field that's set, eg the subnetwork_project of a Shared
+// VPC subnetwork. Otherwise a name may refer to a resource in another project,
+// eg a network in the Shared VPC host project of d's project, so the project
+// of the stored self link isn't checked.
+func compareSelfLinkOrResourceName(k, old, new string, d *schema.ResourceData) bool {
+	return compareSelfLinkOrResourceNameWithDefaults(old, new, selfLinkDefaultsFromResourceData(k, d))
+}
+
+// selfLinkDefaults is the context references missing parts of a self link are
+// resolved in, ie the project and region of the referencing resource. Empty
+// fields are unknown.
+type selfLinkDefaults struct {
+	Project string
+	Region  string
+}
+
+// selfLinkDefaultsFromResourceData returns the project and region references
+// in the field k of d are resolved in, from the `{k}_project` field and the
+// region or zone field of d. The project is unknown if k has no such sibling.
+// d may be nil.
+func selfLinkDefaultsFromResourceData(k string, d *schema.ResourceData) selfLinkDefaults {
+	defaults := selfLinkDefaults{}
+	if d == nil {
+		return defaults
+	}
+	if v, ok := d.GetOk(k + "_project"); ok && k != "" {
+		defaults.Project, _ = v.(string)
+	}
+	if v, ok := d.GetOk("region"); ok {
+		region, _ := v.(string)
+		defaults.Region = GetResourceNameFromSelfLink(region)
+	} else if v, ok := d.GetOk("zone"); ok {
+		zone, _ := v.(string)
+		defaults.Region = getRegionFromZone(GetResourceNameFromSelfLink(zone))
+	}
+	return defaults
+}
+
+var (
+	projectRelativePathRegex = regexp.MustCompile("^projects/([^/]+)/")
+	regionRelativePathRegex  = regexp.MustCompile("/regions/([^/]+)/")
+)
+
+// compareSelfLinkOrResourceNameWithDefaults checks if a and b, each a self
+// link, a path relative to the project, or a name, reference the same
+// resource, resolving missing projects and regions from defaults.
+func compareSelfLinkOrResourceNameWithDefaults(a, b string, defaults selfLinkDefaults) bool {
+	a, b = qualifySelfLink(a, defaults), qualifySelfLink(b, defaults)
+	if a == b {
+		return true
+	}
+	if !strings.HasPrefix(a, "projects/") {
+		a, b = b, a
+	}
+	if !strings.HasPrefix(a, "projects/") {
+		// Neither includes a project
+		return false
+	}
+
+	if strings.HasPrefix(b, "projects/") {
+		return false
+	}
+	if strings.Contains(b, "/") {
+		// A path omitting an unknown project
+		return strings.HasSuffix(a, "/"+b)
 	}
 
-	// The `new` string is a self_link
-	return compareSelfLinkRelativePaths("", old, new, nil)
+	// b is a name, which refers to a resource in the default project and
+	// region
+	if GetResourceNameFromSelfLink(a) != b {
+		return false
+	}
+	if m := projectRelativePathRegex.FindStringSubmatch(a); m != nil && defaults.Project != "" && m[1] != defaults.Project {
+		return false
+	}
+	if m := regionRelativePathRegex.FindStringSubmatch(a); m != nil && defaults.Region != "" && m[1] != defaults.Region {
+		return false
+	}
+	return true
+}
+
+// qualifySelfLink returns the path of link relative to the API, starting with
+// projects/, if it can be resolved, or link itself otherwise.
+func qualifySelfLink(link string, defaults selfLinkDefaults) string {
+	if path, err := getRelativePath(link); err == nil {
+		return path
+	}
+	if strings.Contains(link, "/") && defaults.Project != "" {
+		return "projects/" + defaults.Project + "/" + strings.TrimPrefix(link, "/")
+	}
+	return link
 }
 
 // Hash the relative path of a self link.
@@ -1,6 +1,10 @@
 package google
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
 
 func TestCompareSelfLinkOrResourceName(t *testing.T) {
 	cases := map[string]struct {
@@ -66,6 +70,135 @@ func TestCompareSelfLinkOrResourceName(t *testing.T) {
 	}
 }
 
+func TestCompareSelfLinkOrResourceNameWithDefaults(t *testing.T) {
+	subnetwork := "https://www.googleapis.com/compute/v1/projects/your-project/regions/us-central1/subnetworks/a-subnet"
+	cases := map[string]struct {
+		Old, New string
+		Defaults selfLinkDefaults
+		Expect   bool
+	}{
+		"name only, same project": {
+			Old:      subnetwork,
+			New:      "a-subnet",
+			Defaults: selfLinkDefaults{Project: "your-project", Region: "us-central1"},
+			Expect:   true,
+		},
+		"name only, unknown project": {
+			Old:    subnetwork,
+			New:    "a-subnet",
+			Expect: true,
+		},
+		"name only, different project": {
+			Old:      subnetwork,
+			New:      "a-subnet",
+			Defaults: selfLinkDefaults{Project: "another-project", Region: "us-central1"},
+			Expect:   false,
+		},
+		"name only, different region": {
+			Old:      subnetwork,
+			New:      "a-subnet",
+			Defaults: selfLinkDefaults{Project: "your-project", Region: "europe-west1"},
+			Expect:   false,
+		},
+		"path without project, same project": {
+			Old:      subnetwork,
+			New:      "regions/us-central1/subnetworks/a-subnet",
+			Defaults: selfLinkDefaults{Project: "your-project"},
+			Expect:   true,
+		},
+		"path without project, unknown project": {
+			Old:    subnetwork,
+			New:    "regions/us-central1/subnetworks/a-subnet",
+			Expect: true,
+		},
+		"path without project, different project": {
+			Old:      subnetwork,
+			New:      "regions/us-central1/subnetworks/a-subnet",
+			Defaults: selfLinkDefaults{Project: "another-project"},
+			Expect:   false,
+		},
+		"path without project, different region": {
+			Old:    subnetwork,
+			New:    "regions/europe-west1/subnetworks/a-subnet",
+			Expect: false,
+		},
+		"cross-project path": {
+			Old:      subnetwork,
+			New:      "projects/your-project/regions/us-central1/subnetworks/a-subnet",
+			Defaults: selfLinkDefaults{Project: "another-project"},
+			Expect:   true,
+		},
+		"cross-project path, different project": {
+			Old:      subnetwork,
+			New:      "projects/third-project/regions/us-central1/subnetworks/a-subnet",
+			Defaults: selfLinkDefaults{Project: "your-project"},
+			Expect:   false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if compareSelfLinkOrResourceNameWithDefaults(tc.Old, tc.New, tc.Defaults) != tc.Expect {
+			t.Errorf("bad: %s, expected %t for old = %q and new = %q", tn, tc.Expect, tc.Old, tc.New)
+		}
+		if compareSelfLinkOrResourceNameWithDefaults(tc.New, tc.Old, tc.Defaults) != tc.Expect {
+			t.Errorf("bad: %s, expected %t for old = %q and new = %q", tn, tc.Expect, tc.New, tc.Old)
+		}
+	}
+}
+
+func TestCompareSelfLinkOrResourceName_resourceData(t *testing.T) {
+	s := map[string]*schema.Schema{
+		"project":    {Type: schema.TypeString, Optional: true},
+		"zone":       {Type: schema.TypeString, Optional: true},
+		"subnetwork": {Type: schema.TypeString, Optional: true},
+		"network_interface": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"subnetwork":         {Type: schema.TypeString, Optional: true},
+					"subnetwork_project": {Type: schema.TypeString, Optional: true},
+				},
+			},
+		},
+	}
+	old := "https://www.googleapis.com/compute/v1/projects/your-project/regions/us-central1/subnetworks/a-subnet"
+
+	d := schema.TestResourceDataRaw(t, s, map[string]interface{}{"project": "your-project", "zone": "us-central1-a"})
+	if !compareSelfLinkOrResourceName("subnetwork", old, "a-subnet", d) {
+		t.Errorf("expected a name to match a subnetwork in the project and region of the resource")
+	}
+	if compareSelfLinkOrResourceName("subnetwork", old, "regions/europe-west1/subnetworks/a-subnet", d) {
+		t.Errorf("expected a path not to match a subnetwork in another region")
+	}
+
+	// Without a subnetwork_project, the name may refer to a subnetwork in
+	// another project, eg the Shared VPC host project
+	d = schema.TestResourceDataRaw(t, s, map[string]interface{}{"project": "another-project", "zone": "us-central1-a"})
+	if !compareSelfLinkOrResourceName("subnetwork", old, "a-subnet", d) {
+		t.Errorf("expected the project of the resource not to be compared")
+	}
+
+	// A Shared VPC subnetwork in the host project named by subnetwork_project
+	hostOld := "https://www.googleapis.com/compute/v1/projects/host-project/regions/us-central1/subnetworks/a-subnet"
+	d = schema.TestResourceDataRaw(t, s, map[string]interface{}{
+		"project": "your-project",
+		"zone":    "us-central1-a",
+		"network_interface": []interface{}{
+			map[string]interface{}{"subnetwork": "a-subnet", "subnetwork_project": "host-project"},
+		},
+	})
+	if !compareSelfLinkOrResourceName("network_interface.0.subnetwork", hostOld, "a-subnet", d) {
+		t.Errorf("expected a name to match a subnetwork in the project of subnetwork_project")
+	}
+	if compareSelfLinkOrResourceName("network_interface.0.subnetwork", old, "a-subnet", d) {
+		t.Errorf("expected a name not to match a subnetwork in the project of the resource when subnetwork_project is set")
+	}
+	if !compareOptionalSubnet("network_interface.0.subnetwork", hostOld, "a-subnet", d) {
+		t.Errorf("expected compareOptionalSubnet to resolve names in the project of subnetwork_project")
+	}
+}
+
 func TestGetResourceNameFromSelfLink(t *testing.T) {
 	cases := map[string]struct {
 		SelfLink, ExpectedName string
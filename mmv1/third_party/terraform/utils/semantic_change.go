@@ -0,0 +1,134 @@
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// semanticComparator returns whether the old and new values of a field, as
+// returned by GetChange, are equivalent.
+type semanticComparator func(old, new interface{}) bool
+
+// semanticChangeData is the part of ResourceData and ResourceDiff read by
+// hasSemanticChange.
+type semanticChangeData interface {
+	HasChange(string) bool
+	GetChange(string) (interface{}, interface{})
+}
+
+// hasSemanticChange returns whether key changed according to equal, rather
+// than d.HasChange, which reports nested blocks whose elements were only
+// reordered as changed. A nil equal uses semanticallyEqual.
+func hasSemanticChange(d semanticChangeData, key string, equal semanticComparator) bool {
+	if !d.HasChange(key) {
+		return false
+	}
+	if equal == nil {
+		equal = semanticallyEqual
+	}
+	old, new := d.GetChange(key)
+	return !equal(old, new)
+}
+
+// semanticallyEqual compares values of the schema, ignoring the order of sets
+// and maps but not of lists, and treating empty lists, sets and maps as
+// unset.
+func semanticallyEqual(old, new interface{}) bool {
+	return reflect.DeepEqual(normalizeSemanticValue(old), normalizeSemanticValue(new))
+}
+
+// unorderedListComparator returns a comparator of lists of blocks ignoring
+// their order. Blocks are matched by the key returned by keyFunc, eg their
+// name, then compared with semanticallyEqual. A nil keyFunc matches blocks by
+// their whole value.
+func unorderedListComparator(keyFunc func(interface{}) string) semanticComparator {
+	return func(old, new interface{}) bool {
+		oldBlocks, newBlocks := semanticBlocksByKey(old, keyFunc), semanticBlocksByKey(new, keyFunc)
+		if len(oldBlocks) != len(newBlocks) {
+			return false
+		}
+		for k, o := range oldBlocks {
+			n, ok := newBlocks[k]
+			if !ok || !reflect.DeepEqual(o, n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// semanticKeyField returns a key function for unorderedListComparator
+// matching blocks by their field.
+func semanticKeyField(field string) func(interface{}) string {
+	return func(v interface{}) string {
+		if m, ok := v.(map[string]interface{}); ok {
+			return fmt.Sprint(m[field])
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+// semanticBlocksByKey groups the normalized elements of the list or set v by
+// key. Blocks sharing a key are kept in a stable order, so duplicates are
+// compared regardless of their order too.
+func semanticBlocksByKey(v interface{}, keyFunc func(interface{}) string) map[string][]interface{} {
+	elems, _ := normalizeSemanticValue(v).([]interface{})
+	blocks := make(map[string][]interface{}, len(elems))
+	for _, e := range elems {
+		k := fmt.Sprintf("%#v", e)
+		if keyFunc != nil {
+			k = keyFunc(e)
+		}
+		blocks[k] = append(blocks[k], e)
+	}
+	for _, b := range blocks {
+		sort.SliceStable(b, func(i, j int) bool {
+			return fmt.Sprintf("%#v", b[i]) < fmt.Sprintf("%#v", b[j])
+		})
+	}
+	return blocks
+}
+
+// normalizeSemanticValue returns v with sets converted to lists in their
+// hash order, and empty lists and maps to nil.
+func normalizeSemanticValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *schema.Set:
+		if v == nil {
+			return nil
+		}
+		return normalizeSemanticValue(v.List())
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = normalizeSemanticValue(e)
+		}
+		return l
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = normalizeSemanticValue(e)
+		}
+		return m
+	}
+	return v
+}
+
+// semanticDiffSuppress returns a DiffSuppressFunc for the fields of the
+// blocks of the list key, suppressing their diffs unless key changed
+// according to equal, see hasSemanticChange. Suppressing the diff of the list
+// itself isn't enough, as reordered blocks show up as diffs of their fields.
+func semanticDiffSuppress(key string, equal semanticComparator) schema.SchemaDiffSuppressFunc {
+	return func(_, _, _ string, d *schema.ResourceData) bool {
+		return !hasSemanticChange(d, key, equal)
+	}
+}
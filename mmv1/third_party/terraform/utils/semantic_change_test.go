@@ -0,0 +1,144 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestSemanticComparators(t *testing.T) {
+	exclusion := func(name, filter string) interface{} {
+		return map[string]interface{}{"name": name, "filter": filter, "disabled": false}
+	}
+	byName := unorderedListComparator(semanticKeyField("name"))
+
+	cases := map[string]struct {
+		old, new interface{}
+		equal    semanticComparator
+		expected bool
+	}{
+		"reordered blocks": {
+			old:      []interface{}{exclusion("a", "x"), exclusion("b", "y")},
+			new:      []interface{}{exclusion("b", "y"), exclusion("a", "x")},
+			equal:    byName,
+			expected: true,
+		},
+		"changed block": {
+			old:      []interface{}{exclusion("a", "x"), exclusion("b", "y")},
+			new:      []interface{}{exclusion("b", "z"), exclusion("a", "x")},
+			equal:    byName,
+			expected: false,
+		},
+		"renamed block": {
+			old:      []interface{}{exclusion("a", "x")},
+			new:      []interface{}{exclusion("c", "x")},
+			equal:    byName,
+			expected: false,
+		},
+		"added block": {
+			old:      []interface{}{exclusion("a", "x")},
+			new:      []interface{}{exclusion("a", "x"), exclusion("b", "y")},
+			equal:    byName,
+			expected: false,
+		},
+		"reordered duplicates without a key": {
+			old:      []interface{}{"a", "b", "a"},
+			new:      []interface{}{"a", "a", "b"},
+			equal:    unorderedListComparator(nil),
+			expected: true,
+		},
+		"different duplicates without a key": {
+			old:      []interface{}{"a", "b", "a"},
+			new:      []interface{}{"a", "b", "b"},
+			equal:    unorderedListComparator(nil),
+			expected: false,
+		},
+		"reordered list": {
+			old:      []interface{}{"a", "b"},
+			new:      []interface{}{"b", "a"},
+			equal:    semanticallyEqual,
+			expected: false,
+		},
+		"nested sets": {
+			old: []interface{}{map[string]interface{}{
+				"ports": schema.NewSet(schema.HashString, []interface{}{"80", "443"}),
+			}},
+			new: []interface{}{map[string]interface{}{
+				"ports": schema.NewSet(schema.HashString, []interface{}{"443", "80"}),
+			}},
+			equal:    semanticallyEqual,
+			expected: true,
+		},
+		"empty and unset": {
+			old:      []interface{}{},
+			new:      nil,
+			equal:    semanticallyEqual,
+			expected: true,
+		},
+		"maps": {
+			old:      map[string]interface{}{"a": "1", "b": "2"},
+			new:      map[string]interface{}{"b": "2", "a": "1"},
+			equal:    semanticallyEqual,
+			expected: true,
+		},
+	}
+	for tn, tc := range cases {
+		if got := tc.equal(tc.old, tc.new); got != tc.expected {
+			t.Errorf("%s: expected equal to be %t, got %t", tn, tc.expected, got)
+		}
+	}
+}
+
+// resourceDataChangeMock is a ResourceDataMock also returning the previous
+// values of its fields.
+type resourceDataChangeMock struct {
+	*ResourceDataMock
+	old map[string]interface{}
+}
+
+func (d *resourceDataChangeMock) GetChange(key string) (interface{}, interface{}) {
+	return d.old[key], d.FieldsInSchema[key]
+}
+
+func TestBuildUpdateMaskWithComparators(t *testing.T) {
+	old := []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}}
+	reordered := []interface{}{map[string]interface{}{"name": "b"}, map[string]interface{}{"name": "a"}}
+	d := &resourceDataChangeMock{
+		ResourceDataMock: &ResourceDataMock{
+			FieldsInSchema:      map[string]interface{}{"exclusions": reordered, "description": "new"},
+			FieldsWithHasChange: []string{"exclusions", "description"},
+		},
+		old: map[string]interface{}{"exclusions": old, "description": "old"},
+	}
+	fieldMap := map[string]string{"exclusions": "", "description": ""}
+	comparators := map[string]semanticComparator{"exclusions": unorderedListComparator(semanticKeyField("name"))}
+
+	if got := buildUpdateMaskWithComparators(d, fieldMap, comparators); got != "description" {
+		t.Errorf("expected reordered exclusions to be left out of the mask, got %q", got)
+	}
+	if got := buildUpdateMask(d, fieldMap); got != "description,exclusions" {
+		t.Errorf("expected every changed field in the mask without comparators, got %q", got)
+	}
+}
+
+func TestHasSemanticChange(t *testing.T) {
+	equal := unorderedListComparator(semanticKeyField("name"))
+	a := map[string]interface{}{"name": "a", "filter": "severity < ERROR"}
+	b := map[string]interface{}{"name": "b", "filter": "severity < WARNING"}
+	d := &resourceDataChangeMock{
+		ResourceDataMock: &ResourceDataMock{
+			FieldsInSchema:      map[string]interface{}{"exclusions": []interface{}{b, a}},
+			FieldsWithHasChange: []string{"exclusions"},
+		},
+		old: map[string]interface{}{"exclusions": []interface{}{a, b}},
+	}
+
+	if hasSemanticChange(d, "exclusions", equal) {
+		t.Errorf("expected reordering the exclusions not to be a change")
+	}
+
+	d.FieldsInSchema["exclusions"] = []interface{}{b}
+	if !hasSemanticChange(d, "exclusions", equal) {
+		t.Errorf("expected removing an exclusion to be a change")
+	}
+}
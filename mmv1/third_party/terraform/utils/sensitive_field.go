@@ -0,0 +1,96 @@
+// Helpers for sensitive string fields whose value the API returns, such as
+// the value of a Runtime Configurator variable. With the
+// hash_sensitive_values feature, state holds a salted hash of these fields
+// rather than their plaintext, which is only kept by the API. Configured
+// values are compared to the hash by sensitiveHashDiffSuppress, and values
+// read from the API by flattenSensitiveValue, so drift is still detected.
+
+package google
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	// sensitiveHashPrefix starts the hashes of sensitive values, followed by
+	// the salt and the hash, each base64 encoded and separated by a colon
+	sensitiveHashPrefix    = "hashed:sha256:"
+	sensitiveHashSaltBytes = 16
+)
+
+// hashSensitiveValue returns the hash of v with a random salt.
+func hashSensitiveValue(v string) (string, error) {
+	salt := make([]byte, sensitiveHashSaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("Error generating a salt: %s", err)
+	}
+	return hashSensitiveValueWithSalt(v, salt), nil
+}
+
+func hashSensitiveValueWithSalt(v string, salt []byte) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(v))
+	return sensitiveHashPrefix + base64.RawStdEncoding.EncodeToString(salt) + ":" + base64.RawStdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sensitiveHashSalt returns the salt of hashed, or false if it isn't a hash
+// returned by hashSensitiveValue.
+func sensitiveHashSalt(hashed string) ([]byte, bool) {
+	if !strings.HasPrefix(hashed, sensitiveHashPrefix) {
+		return nil, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(hashed, sensitiveHashPrefix), ":", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[0])
+	if err != nil || len(salt) == 0 {
+		return nil, false
+	}
+	return salt, true
+}
+
+// sensitiveValueMatches returns whether stored, a value of state which may
+// be a hash or plaintext, holds v.
+func sensitiveValueMatches(stored, v string) bool {
+	salt, ok := sensitiveHashSalt(stored)
+	if !ok {
+		return stored == v
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSensitiveValueWithSalt(v, salt)), []byte(stored)) == 1
+}
+
+// sensitiveHashDiffSuppress suppresses diffs between a hash in state and the
+// configured value it's the hash of.
+func sensitiveHashDiffSuppress(_, old, new string, _ *schema.ResourceData) bool {
+	return old == new || sensitiveValueMatches(old, new)
+}
+
+// flattenSensitiveValue returns v, the value of the field key returned by an
+// API, or its hash if hash is set. The hash in d is kept if it still matches
+// v, so state doesn't change on every read, and a new one is returned if v
+// changed, which shows up as drift from the configured value.
+func flattenSensitiveValue(v interface{}, d TerraformResourceData, key string, hash bool) (interface{}, error) {
+	s, ok := v.(string)
+	if !hash || !ok || s == "" {
+		return v, nil
+	}
+	if current, ok := d.Get(key).(string); ok {
+		if _, isHash := sensitiveHashSalt(current); isHash && sensitiveValueMatches(current, s) {
+			return current, nil
+		}
+	}
+	hashed, err := hashSensitiveValue(s)
+	if err != nil {
+		return nil, fmt.Errorf("Error hashing %s: %s", key, err)
+	}
+	return hashed, nil
+}
@@ -0,0 +1,79 @@
+package google
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSensitiveValueMatches(t *testing.T) {
+	hashed, err := hashSensitiveValue("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(hashed, sensitiveHashPrefix) || strings.Contains(hashed, "secret") {
+		t.Fatalf("expected a hash of the value, got %q", hashed)
+	}
+	other, err := hashSensitiveValue("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if hashed == other {
+		t.Errorf("expected hashes of the same value to use different salts")
+	}
+
+	cases := map[string]struct {
+		stored, value string
+		expected      bool
+	}{
+		"hash of the value": {stored: hashed, value: "secret", expected: true},
+		"hash of another":   {stored: hashed, value: "other", expected: false},
+		"plaintext":         {stored: "secret", value: "secret", expected: true},
+		"changed plaintext": {stored: "secret", value: "other", expected: false},
+		"malformed hash":    {stored: sensitiveHashPrefix + "!", value: "secret", expected: false},
+		"hash as plaintext": {stored: hashed, value: hashed, expected: false},
+	}
+	for tn, tc := range cases {
+		if got := sensitiveValueMatches(tc.stored, tc.value); got != tc.expected {
+			t.Errorf("%s: expected match to be %t, got %t", tn, tc.expected, got)
+		}
+		suppress := tc.expected || tc.stored == tc.value
+		if got := sensitiveHashDiffSuppress("", tc.stored, tc.value, nil); got != suppress {
+			t.Errorf("%s: expected suppress to be %t, got %t", tn, suppress, got)
+		}
+	}
+}
+
+func TestFlattenSensitiveValue(t *testing.T) {
+	d := &ResourceDataMock{FieldsInSchema: map[string]interface{}{"text": "secret"}}
+
+	v, err := flattenSensitiveValue("secret", d, "text", false)
+	if err != nil || v != "secret" {
+		t.Fatalf("expected the plaintext without hashing, got %v, %v", v, err)
+	}
+
+	v, err = flattenSensitiveValue("secret", d, "text", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	hashed := v.(string)
+	if !sensitiveValueMatches(hashed, "secret") || hashed == "secret" {
+		t.Fatalf("expected plaintext in state to be replaced by its hash, got %q", hashed)
+	}
+
+	d.FieldsInSchema["text"] = hashed
+	if v, _ := flattenSensitiveValue("secret", d, "text", true); v != hashed {
+		t.Errorf("expected the hash in state to be kept, got %v", v)
+	}
+
+	v, err = flattenSensitiveValue("changed", d, "text", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v == hashed || !sensitiveValueMatches(v.(string), "changed") {
+		t.Errorf("expected a new hash of the changed value, got %v", v)
+	}
+
+	if v, _ := flattenSensitiveValue("", d, "text", true); v != "" {
+		t.Errorf("expected an unset value to stay unset, got %v", v)
+	}
+}
@@ -0,0 +1,66 @@
+package google
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// Delays asked for by the server longer than this are assumed to be bogus,
+	// and capped to it.
+	serverRetryDelayMax = time.Minute
+	// Up to this fraction of a server retry delay is added to it, so clients
+	// told to retry at the same time don't all do so at once.
+	serverRetryDelayJitter = 0.1
+)
+
+// serverRetryDelay returns how long the server asked to wait before retrying
+// the request that failed with err, a 429 or 503 googleapi.Error. The delay is
+// read from the Retry-After header, or else from the google.rpc.RetryInfo
+// details of the body, and capped to serverRetryDelayMax.
+func serverRetryDelay(err error) (time.Duration, bool) {
+	if !isGoogleApiErrorWithCode(err, 429) && !isGoogleApiErrorWithCode(err, 503) {
+		return 0, false
+	}
+
+	d, ok := retryAfterFromError(err)
+	if !ok {
+		d, ok = retryInfoDelayFromError(err)
+	}
+	if !ok {
+		return 0, false
+	}
+	if d > serverRetryDelayMax {
+		d = serverRetryDelayMax
+	}
+	return d, true
+}
+
+// retryInfoDelayFromError returns the retryDelay of the google.rpc.RetryInfo
+// details of err's body, eg
+//
+//	{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "30s"}
+func retryInfoDelayFromError(err error) (time.Duration, bool) {
+	body, ok := parseGoogleApiErrorBody(err)
+	if !ok {
+		return 0, false
+	}
+	for _, detail := range body.Details {
+		if detail.Type != "type.googleapis.com/google.rpc.RetryInfo" || detail.RetryDelay == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(detail.RetryDelay); err == nil && d > 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// jitterServerRetryDelay adds up to serverRetryDelayJitter of d to it. The
+// result is never shorter than d, which the server asked for.
+func jitterServerRetryDelay(d time.Duration) time.Duration {
+	if max := int64(float64(d) * serverRetryDelayJitter); max > 0 {
+		d += time.Duration(rand.Int63n(max))
+	}
+	return d
+}
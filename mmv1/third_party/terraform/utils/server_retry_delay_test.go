@@ -0,0 +1,86 @@
+package google
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"google.golang.org/api/googleapi"
+)
+
+func TestServerRetryDelay(t *testing.T) {
+	retryInfo := func(delay string) string {
+		return fmt.Sprintf(`{
+  "error": {
+    "code": 429,
+    "message": "Quota exceeded",
+    "status": "RESOURCE_EXHAUSTED",
+    "details": [
+      {
+        "@type": "type.googleapis.com/google.rpc.RetryInfo",
+        "retryDelay": %q
+      }
+    ]
+  }
+}`, delay)
+	}
+
+	cases := map[string]struct {
+		err   error
+		delay time.Duration
+		ok    bool
+	}{
+		"Retry-After": {
+			err:   &googleapi.Error{Code: 429, Header: http.Header{"Retry-After": []string{"7"}}},
+			delay: 7 * time.Second,
+			ok:    true,
+		},
+		"RetryInfo": {
+			err:   &googleapi.Error{Code: 429, Body: retryInfo("1.5s")},
+			delay: 1500 * time.Millisecond,
+			ok:    true,
+		},
+		"Retry-After before RetryInfo": {
+			err:   &googleapi.Error{Code: 503, Header: http.Header{"Retry-After": []string{"2"}}, Body: retryInfo("30s")},
+			delay: 2 * time.Second,
+			ok:    true,
+		},
+		"wrapped": {
+			err:   errwrap.Wrapf("Error reading thing: {{err}}", &googleapi.Error{Code: 503, Body: retryInfo("3s")}),
+			delay: 3 * time.Second,
+			ok:    true,
+		},
+		"capped": {
+			err:   &googleapi.Error{Code: 429, Body: retryInfo("3600s")},
+			delay: serverRetryDelayMax,
+			ok:    true,
+		},
+		"invalid RetryInfo": {
+			err: &googleapi.Error{Code: 429, Body: retryInfo("soon")},
+		},
+		"other codes": {
+			err: &googleapi.Error{Code: 500, Header: http.Header{"Retry-After": []string{"7"}}},
+		},
+		"no delay": {
+			err: &googleapi.Error{Code: 429},
+		},
+	}
+
+	for tn, tc := range cases {
+		d, ok := serverRetryDelay(tc.err)
+		if ok != tc.ok || d != tc.delay {
+			t.Errorf("%s: expected %s, %t, got %s, %t", tn, tc.delay, tc.ok, d, ok)
+		}
+	}
+}
+
+func TestJitterServerRetryDelay(t *testing.T) {
+	d := 10 * time.Second
+	for i := 0; i < 100; i++ {
+		if got := jitterServerRetryDelay(d); got < d || got >= d+time.Second {
+			t.Fatalf("expected a delay in [%s, %s), got %s", d, d+time.Second, got)
+		}
+	}
+}
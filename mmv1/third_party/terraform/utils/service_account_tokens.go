@@ -0,0 +1,153 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	iamcredentials "google.golang.org/api/iamcredentials/v1"
+)
+
+const (
+	// Lifetime of tokens generated through the IAM Credentials API if none is
+	// requested
+	serviceAccountTokenDefaultLifetime = time.Hour
+	// Tokens may live up to 12 hours if the
+	// constraints/iam.allowServiceAccountCredentialLifetimeExtension org
+	// policy allows it for the service account, and up to 1 hour otherwise.
+	serviceAccountTokenMaxLifetime = 12 * time.Hour
+)
+
+// ephemeralToken is a short-lived credential generated for a service account.
+// It deliberately can't be formatted or encoded with its value, so it can't
+// be logged or persisted by mistake; the value must be read from Value.
+type ephemeralToken struct {
+	value  string
+	Expiry time.Time
+}
+
+func (t ephemeralToken) Value() string {
+	return t.value
+}
+
+func (t ephemeralToken) String() string {
+	if t.Expiry.IsZero() {
+		return "<redacted token>"
+	}
+	return fmt.Sprintf("<redacted token, expires %s>", t.Expiry.Format(time.RFC3339))
+}
+
+func (t ephemeralToken) GoString() string {
+	return t.String()
+}
+
+func (t ephemeralToken) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// serviceAccountTokenName returns the resource name the IAM Credentials API
+// uses for a service account, given its email or unique id. The "-" wildcard
+// makes the API infer the project from the account.
+func serviceAccountTokenName(account string) string {
+	if strings.HasPrefix(account, "projects/") {
+		return account
+	}
+	return fmt.Sprintf("projects/-/serviceAccounts/%s", account)
+}
+
+// serviceAccountTokenDelegates returns the delegation chain in the form the
+// API expects, and checks that no account appears twice, which the API would
+// reject with a less helpful error. The data sources take delegates as a set,
+// so there's no order to preserve; the chain is sorted so the same delegates
+// always make the same request.
+func serviceAccountTokenDelegates(target string, delegates []string) ([]string, error) {
+	seen := map[string]struct{}{
+		serviceAccountTokenName(target): {},
+	}
+	chain := make([]string, 0, len(delegates))
+	for _, d := range delegates {
+		name := serviceAccountTokenName(d)
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%s appears more than once in the delegation chain for %s", d, target)
+		}
+		seen[name] = struct{}{}
+		chain = append(chain, name)
+	}
+	sort.Strings(chain)
+	return chain, nil
+}
+
+// validateServiceAccountTokenLifetime validates the requested lifetime of a
+// generated token, eg "3600s". Lifetimes over an hour produce a warning, as
+// they need an org policy exception.
+func validateServiceAccountTokenLifetime(v interface{}, k string) (ws []string, errs []error) {
+	s, ok := v.(string)
+	if !ok {
+		errs = append(errs, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	lifetime, err := time.ParseDuration(s)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("expected %s to be a duration, but parsing gave an error: %s", k, err))
+		return
+	}
+	if lifetime <= 0 || lifetime > serviceAccountTokenMaxLifetime {
+		errs = append(errs, fmt.Errorf("expected %s to be greater than 0s and at most %s, got %s", k, serviceAccountTokenMaxLifetime, s))
+		return
+	}
+	if lifetime > serviceAccountTokenDefaultLifetime {
+		ws = append(ws, fmt.Sprintf("%s is over 1 hour, which requires the constraints/iam.allowServiceAccountCredentialLifetimeExtension org policy to allow the service account", k))
+	}
+	return
+}
+
+// generateServiceAccountAccessToken generates an OAuth 2.0 access token for
+// target, impersonated through the delegates chain.
+func generateServiceAccountAccessToken(config *Config, userAgent, target string, scopes, delegates []string, lifetime string) (ephemeralToken, error) {
+	chain, err := serviceAccountTokenDelegates(target, delegates)
+	if err != nil {
+		return ephemeralToken{}, err
+	}
+
+	log.Printf("[INFO] Generating an access token for %s", target)
+	req := &iamcredentials.GenerateAccessTokenRequest{
+		Lifetime:  lifetime,
+		Delegates: chain,
+		Scope:     canonicalizeServiceScopes(scopes),
+	}
+	res, err := config.NewIamCredentialsClient(userAgent).Projects.ServiceAccounts.GenerateAccessToken(serviceAccountTokenName(target), req).Do()
+	if err != nil {
+		return ephemeralToken{}, fmt.Errorf("Error generating an access token for %s: %s", target, err)
+	}
+
+	token := ephemeralToken{value: res.AccessToken}
+	if expiry, err := time.Parse(time.RFC3339, res.ExpireTime); err == nil {
+		token.Expiry = expiry
+	}
+	return token, nil
+}
+
+// generateServiceAccountIdToken generates an OpenID Connect ID token for
+// target with the given audience, impersonated through the delegates chain.
+func generateServiceAccountIdToken(config *Config, userAgent, target, audience string, delegates []string, includeEmail bool) (ephemeralToken, error) {
+	chain, err := serviceAccountTokenDelegates(target, delegates)
+	if err != nil {
+		return ephemeralToken{}, err
+	}
+
+	log.Printf("[INFO] Generating an ID token for %s", target)
+	req := &iamcredentials.GenerateIdTokenRequest{
+		Audience:     audience,
+		IncludeEmail: includeEmail,
+		Delegates:    chain,
+	}
+	res, err := config.NewIamCredentialsClient(userAgent).Projects.ServiceAccounts.GenerateIdToken(serviceAccountTokenName(target), req).Do()
+	if err != nil {
+		return ephemeralToken{}, fmt.Errorf("Error generating an ID token for %s: %s", target, err)
+	}
+	return ephemeralToken{value: res.Token}, nil
+}
@@ -0,0 +1,75 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateServiceAccountTokenLifetime(t *testing.T) {
+	cases := map[string]struct {
+		Lifetime      string
+		ExpectWarning bool
+		ExpectError   bool
+	}{
+		"default":      {Lifetime: "3600s"},
+		"short":        {Lifetime: "300s"},
+		"extended":     {Lifetime: "43200s", ExpectWarning: true},
+		"too long":     {Lifetime: "43201s", ExpectError: true},
+		"zero":         {Lifetime: "0s", ExpectError: true},
+		"negative":     {Lifetime: "-60s", ExpectError: true},
+		"not duration": {Lifetime: "1 hour", ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		ws, errs := validateServiceAccountTokenLifetime(tc.Lifetime, "lifetime")
+		if (len(ws) > 0) != tc.ExpectWarning {
+			t.Errorf("%s: expected warning to be %t, got %v", tn, tc.ExpectWarning, ws)
+		}
+		if (len(errs) > 0) != tc.ExpectError {
+			t.Errorf("%s: expected error to be %t, got %v", tn, tc.ExpectError, errs)
+		}
+	}
+}
+
+func TestServiceAccountTokenDelegates(t *testing.T) {
+	target := "target@my-project.iam.gserviceaccount.com"
+
+	chain, err := serviceAccountTokenDelegates(target, []string{
+		"projects/-/serviceAccounts/delegate-2@my-project.iam.gserviceaccount.com",
+		"delegate-1@my-project.iam.gserviceaccount.com",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{
+		"projects/-/serviceAccounts/delegate-1@my-project.iam.gserviceaccount.com",
+		"projects/-/serviceAccounts/delegate-2@my-project.iam.gserviceaccount.com",
+	}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("expected %v, got %v", expected, chain)
+	}
+
+	if _, err := serviceAccountTokenDelegates(target, []string{"projects/-/serviceAccounts/" + target}); err == nil {
+		t.Errorf("expected an error when the target is a delegate")
+	}
+}
+
+func TestEphemeralTokenIsRedacted(t *testing.T) {
+	token := ephemeralToken{value: "ya29.secret"}
+
+	b, err := json.Marshal(struct{ Token ephemeralToken }{token})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, s := range []string{fmt.Sprintf("%s", token), fmt.Sprintf("%v", token), fmt.Sprintf("%#v", token), string(b)} {
+		if strings.Contains(s, "secret") {
+			t.Errorf("expected token to be redacted, got %s", s)
+		}
+	}
+	if token.Value() != "ya29.secret" {
+		t.Errorf("expected Value to return the token")
+	}
+}
@@ -0,0 +1,133 @@
+package google
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long requests failing because an API isn't enabled are retried after
+// the provider enabled it. Enabling an API completes before every Google
+// backend sees it, and requests made in the meantime fail with
+// accessNotConfigured (SERVICE_DISABLED) errors, usually for a minute or two.
+const serviceEnablementPropagationMaxWait = 5 * time.Minute
+
+// The console link in accessNotConfigured errors, eg
+// https://console.developers.google.com/apis/api/run.googleapis.com/overview?project=123456
+var serviceDisabledLinkRegex = regexp.MustCompile(`apis/api/([a-z0-9.-]+)/overview\?project=([a-z0-9:.-]+)`)
+
+// serviceEnablements remembers when the provider enabled services, so
+// requests failing while the enablement propagates can be retried. Projects
+// are recorded by both ID and number, as errors name projects by number.
+type serviceEnablements struct {
+	clock Clock
+
+	mu      sync.Mutex
+	enabled map[string]time.Time
+}
+
+func newServiceEnablements(clock Clock) *serviceEnablements {
+	return &serviceEnablements{
+		clock:   clock,
+		enabled: make(map[string]time.Time),
+	}
+}
+
+func serviceEnablementKey(project, service string) string {
+	return fmt.Sprintf("projects/%s/services/%s", project, service)
+}
+
+func (s *serviceEnablements) record(project, service string) {
+	if s == nil || project == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled[serviceEnablementKey(project, service)] = s.clock.Now()
+}
+
+// propagating returns whether the service was enabled on project less than
+// serviceEnablementPropagationMaxWait ago.
+func (s *serviceEnablements) propagating(project, service string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok := s.enabled[serviceEnablementKey(project, service)]
+	return ok && s.clock.Now().Sub(at) < serviceEnablementPropagationMaxWait
+}
+
+// isPropagatingError is a RetryErrorPredicateFunc retrying errors reporting
+// that a service recently enabled by the provider is disabled.
+func (s *serviceEnablements) isPropagatingError(err error) (bool, string) {
+	project, service, ok := serviceDisabledError(err)
+	if ok && s.propagating(project, service) {
+		return true, fmt.Sprintf("Waiting for the enablement of %s to propagate", service)
+	}
+	return false, ""
+}
+
+// serviceDisabledError returns the project and the service err reports as
+// not enabled on it. The project is usually a project number.
+func serviceDisabledError(err error) (project, service string, ok bool) {
+	body, ok := parseGoogleApiErrorBody(err)
+	if !ok || body.Code != 403 || !body.HasReason(errorReasonAccessNotConfigured, "SERVICE_DISABLED") {
+		return "", "", false
+	}
+	for _, d := range body.Details {
+		if d.Metadata["service"] != "" && d.Metadata["consumer"] != "" {
+			return strings.TrimPrefix(d.Metadata["consumer"], "projects/"), d.Metadata["service"], true
+		}
+	}
+	if m := body.FindStringSubmatch(serviceDisabledLinkRegex); m != nil {
+		return m[2], m[1], true
+	}
+	return "", "", false
+}
+
+// serviceNotEnabledYetError is returned while waiting for a service that
+// isn't reported as enabled yet.
+type serviceNotEnabledYetError struct {
+	service, state, project string
+}
+
+func (e *serviceNotEnabledYetError) Error() string {
+	return fmt.Sprintf("service %s is %s on project %s", e.service, e.state, e.project)
+}
+
+func isServiceNotEnabledYetError(err error) (bool, string) {
+	if _, ok := err.(*serviceNotEnabledYetError); ok {
+		return true, "waiting for the service to be enabled"
+	}
+	return false, ""
+}
+
+// waitForServiceEnablementPropagation waits for service to be reported as
+// enabled on project, and records that the provider enabled it. Requests
+// failing because the service isn't enabled yet are then retried for up to
+// serviceEnablementPropagationMaxWait, rather than failing the resources
+// depending on the service.
+func waitForServiceEnablementPropagation(config *Config, userAgent, project, service string, timeout time.Duration) error {
+	name := serviceEnablementKey(project, service)
+	return retryWithOptions(RetryOptions{
+		RetryFunc: func() error {
+			srv, err := config.NewServiceUsageClient(userAgent).Services.Get(name).Do()
+			if err != nil {
+				return err
+			}
+			if srv.State != "ENABLED" {
+				return &serviceNotEnabledYetError{service: service, state: srv.State, project: project}
+			}
+			config.serviceEnablements.record(project, service)
+			// The parent is projects/{number}
+			config.serviceEnablements.record(GetResourceNameFromSelfLink(srv.Parent), service)
+			return nil
+		},
+		Timeout:              timeout,
+		ErrorRetryPredicates: []RetryErrorPredicateFunc{isServiceNotEnabledYetError},
+		Clock:                config.getClock(),
+	})
+}
@@ -0,0 +1,87 @@
+package google
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestServiceEnablements_isPropagatingError(t *testing.T) {
+	errorInfo := &googleapi.Error{
+		Code: 403,
+		Body: `{"error": {"code": 403, "message": "Cloud Run Admin API has not been used in project 123456 before or it is disabled.", "status": "PERMISSION_DENIED", "details": [{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "SERVICE_DISABLED", "domain": "googleapis.com", "metadata": {"consumer": "projects/123456", "service": "run.googleapis.com"}}]}}`,
+	}
+	link := &googleapi.Error{
+		Code:    403,
+		Message: "Cloud Run Admin API has not been used in project 123456 before or it is disabled. Enable it by visiting https://console.developers.google.com/apis/api/run.googleapis.com/overview?project=123456 then retry.",
+		Errors:  []googleapi.ErrorItem{{Reason: errorReasonAccessNotConfigured}},
+	}
+	otherService := &googleapi.Error{
+		Code:    403,
+		Message: "Enable it by visiting https://console.developers.google.com/apis/api/pubsub.googleapis.com/overview?project=123456 then retry.",
+		Errors:  []googleapi.ErrorItem{{Reason: errorReasonAccessNotConfigured}},
+	}
+	denied := &googleapi.Error{Code: 403, Message: "Permission 'run.services.create' denied"}
+
+	clock := &fakeClock{now: time.Now()}
+	s := newServiceEnablements(clock)
+	s.record("123456", "run.googleapis.com")
+
+	cases := map[string]struct {
+		err   error
+		retry bool
+	}{
+		"ErrorInfo":     {err: errorInfo, retry: true},
+		"console link":  {err: link, retry: true},
+		"other service": {err: otherService},
+		"not disabled":  {err: denied},
+	}
+	for tn, tc := range cases {
+		if retry, _ := s.isPropagatingError(tc.err); retry != tc.retry {
+			t.Errorf("%s: expected retry to be %t, got %t", tn, tc.retry, retry)
+		}
+	}
+
+	clock.Sleep(serviceEnablementPropagationMaxWait)
+	if retry, _ := s.isPropagatingError(errorInfo); retry {
+		t.Errorf("expected errors not to be retried past the maximum wait")
+	}
+
+	var nilEnablements *serviceEnablements
+	if retry, _ := nilEnablements.isPropagatingError(errorInfo); retry {
+		t.Errorf("expected errors not to be retried without enablements")
+	}
+}
+
+func TestWaitForServiceEnablementPropagation(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/v1/projects/p/services/run.googleapis.com",
+		fakeAPIResponse{Body: map[string]interface{}{"state": "DISABLED", "parent": "projects/123456"}},
+		fakeAPIResponse{Body: map[string]interface{}{"state": "ENABLED", "parent": "projects/123456"}},
+	)
+
+	clock := &fakeClock{now: time.Now()}
+	config := s.Config()
+	config.context = context.Background()
+	config.ServiceUsageBasePath = s.URL + "/"
+	config.clock = clock
+	config.serviceEnablements = newServiceEnablements(clock)
+
+	start := clock.Now()
+	if err := waitForServiceEnablementPropagation(config, "", "p", "run.googleapis.com", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.Requests("GET", "/v1/projects/p/services/run.googleapis.com"); n != 2 {
+		t.Errorf("expected the service to be read until it's enabled, got %d requests", n)
+	}
+	if !clock.Now().After(start) {
+		t.Errorf("expected retries to wait on the config's clock")
+	}
+	for _, project := range []string{"p", "123456"} {
+		if !config.serviceEnablements.propagating(project, "run.googleapis.com") {
+			t.Errorf("expected the enablement to be recorded for %s", project)
+		}
+	}
+}
@@ -31,5 +31,5 @@ func serviceNetworkingOperationWaitTime(config *Config, op *servicenetworking.Op
 	if err := w.SetOp(op); err != nil {
 		return err
 	}
-	return OperationWait(w, activity, timeout, config.PollInterval)
+	return OperationWaitWithClock(w, activity, timeout, config.PollInterval, config.getClock())
 }
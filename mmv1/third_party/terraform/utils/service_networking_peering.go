@@ -0,0 +1,63 @@
+package google
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/servicenetworking/v1"
+)
+
+// networkPeeringMutexKey returns the key of the lock held while changing the
+// VPC peerings of a network, including those backing private services access
+// connections. Compute Engine and Service Networking both reject peering
+// changes while another one is in progress on the network (see
+// isPeeringOperationInProgress and isServiceNetworkingOperationInProgressError),
+// so changes made by this provider process are serialized instead. It's the
+// same key google_compute_route locks while adding a route to network.
+func networkPeeringMutexKey(project, network string) string {
+	return fmt.Sprintf("projects/%s/global/networks/%s/peerings", project, GetResourceNameFromSelfLink(network))
+}
+
+// reconcileReservedPeeringRanges returns actual, the ranges reserved for a
+// private services access connection, in the order of configured, with the
+// ranges missing from configured after them. The API returns the ranges in
+// its own order, which isn't a change.
+func reconcileReservedPeeringRanges(configured, actual []string) []string {
+	remaining := make(map[string]bool, len(actual))
+	for _, r := range actual {
+		remaining[r] = true
+	}
+
+	ranges := make([]string, 0, len(actual))
+	for _, r := range configured {
+		if remaining[r] {
+			ranges = append(ranges, r)
+			delete(remaining, r)
+		}
+	}
+	for _, r := range actual {
+		if remaining[r] {
+			ranges = append(ranges, r)
+			delete(remaining, r)
+		}
+	}
+	return ranges
+}
+
+// checkReservedPeeringRange returns an error if allocatedRange, the name of an
+// allocated IP range a resource connected through private services access
+// (eg a Cloud SQL or Memorystore instance) was configured with, isn't
+// reserved by any of connections, so the resource would fail to be created
+// after a long wait.
+func checkReservedPeeringRange(connections []*servicenetworking.Connection, allocatedRange string) error {
+	var reserved []string
+	for _, c := range connections {
+		for _, r := range c.ReservedPeeringRanges {
+			if r == allocatedRange {
+				return nil
+			}
+			reserved = append(reserved, r)
+		}
+	}
+	return fmt.Errorf("the allocated IP range %q isn't reserved by the private services access connection of the network, which reserves [%s]. Add it to the reserved_peering_ranges of the google_service_networking_connection.", allocatedRange, strings.Join(reserved, ", "))
+}
@@ -0,0 +1,63 @@
+package google
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/servicenetworking/v1"
+)
+
+func TestNetworkPeeringMutexKey(t *testing.T) {
+	expected := "projects/p/global/networks/default/peerings"
+	if key := networkPeeringMutexKey("p", "default"); key != expected {
+		t.Errorf("expected %s, got %s", expected, key)
+	}
+	if key := networkPeeringMutexKey("p", "https://www.googleapis.com/compute/v1/projects/p/global/networks/default"); key != expected {
+		t.Errorf("expected the key of a self link to be %s, got %s", expected, key)
+	}
+}
+
+func TestReconcileReservedPeeringRanges(t *testing.T) {
+	cases := map[string]struct {
+		configured, actual, expected []string
+	}{
+		"reordered": {
+			configured: []string{"a", "b", "c"},
+			actual:     []string{"c", "a", "b"},
+			expected:   []string{"a", "b", "c"},
+		},
+		"added out of band": {
+			configured: []string{"a", "b"},
+			actual:     []string{"d", "b", "a"},
+			expected:   []string{"a", "b", "d"},
+		},
+		"removed out of band": {
+			configured: []string{"a", "b"},
+			actual:     []string{"b"},
+			expected:   []string{"b"},
+		},
+		"imported": {
+			actual:   []string{"b", "a"},
+			expected: []string{"b", "a"},
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := reconcileReservedPeeringRanges(tc.configured, tc.actual); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestCheckReservedPeeringRange(t *testing.T) {
+	connections := []*servicenetworking.Connection{
+		{ReservedPeeringRanges: []string{"sql-range"}},
+		{ReservedPeeringRanges: []string{"redis-range", "other-range"}},
+	}
+	if err := checkReservedPeeringRange(connections, "redis-range"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := checkReservedPeeringRange(connections, "missing-range"); err == nil {
+		t.Errorf("expected an error for a range that isn't reserved")
+	}
+}
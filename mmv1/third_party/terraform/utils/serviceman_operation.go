@@ -29,7 +29,7 @@ func serviceManagementOperationWaitTime(config *Config, op *servicemanagement.Op
 		return nil, err
 	}
 
-	if err := OperationWait(w, activity, timeout, config.PollInterval); err != nil {
+	if err := OperationWaitWithClock(w, activity, timeout, config.PollInterval, config.getClock()); err != nil {
 		return nil, err
 	}
 	return w.Op.Response, nil
@@ -9,7 +9,9 @@ import (
 )
 
 const (
-	batchKeyTmplServiceUsageEnableServices = "project/%s/services:batchEnable"
+	// Requests are only combined if they're billed to the same project, as the
+	// combined request is sent with the billing project of the first one.
+	batchKeyTmplServiceUsageEnableServices = "project/%s/services:batchEnable/billing/%s"
 	batchKeyTmplServiceUsageListServices   = "project/%s/services"
 )
 
@@ -42,7 +44,7 @@ func BatchRequestEnableService(service string, project string, d *schema.Resourc
 	}
 
 	_, err = config.requestBatcherServiceUsage.SendRequestWithTimeout(
-		fmt.Sprintf(batchKeyTmplServiceUsageEnableServices, project),
+		fmt.Sprintf(batchKeyTmplServiceUsageEnableServices, project, billingProject),
 		req,
 		d.Timeout(schema.TimeoutCreate))
 	return err
@@ -112,7 +114,15 @@ func combineServiceUsageServicesBatches(srvsRaw interface{}, toAddRaw interface{
 		return nil, fmt.Errorf("Expected new request body type to be []string, got %v. This is a provider error.", toAdd)
 	}
 
-	return append(srvs, toAdd...), nil
+	// Several resources can enable the same service, eg in different modules,
+	// but each service only needs to be listed in the request once.
+	combined := append([]string{}, srvs...)
+	for _, srv := range toAdd {
+		if !stringInSlice(combined, srv) {
+			combined = append(combined, srv)
+		}
+	}
+	return combined, nil
 }
 
 func sendBatchFuncEnableServices(config *Config, userAgent, billingProject string, timeout time.Duration) BatcherSendFunc {
@@ -0,0 +1,111 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCombineServiceUsageServicesBatches(t *testing.T) {
+	cases := map[string]struct {
+		Services []string
+		ToAdd    []string
+		Expected []string
+	}{
+		"new services": {
+			Services: []string{"compute.googleapis.com"},
+			ToAdd:    []string{"storage.googleapis.com"},
+			Expected: []string{"compute.googleapis.com", "storage.googleapis.com"},
+		},
+		"duplicate service": {
+			Services: []string{"compute.googleapis.com", "storage.googleapis.com"},
+			ToAdd:    []string{"compute.googleapis.com"},
+			Expected: []string{"compute.googleapis.com", "storage.googleapis.com"},
+		},
+		"duplicates in the added services": {
+			Services: []string{"compute.googleapis.com"},
+			ToAdd:    []string{"storage.googleapis.com", "storage.googleapis.com"},
+			Expected: []string{"compute.googleapis.com", "storage.googleapis.com"},
+		},
+	}
+
+	for tn, tc := range cases {
+		services := append([]string{}, tc.Services...)
+		combined, err := combineServiceUsageServicesBatches(services, tc.ToAdd)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if !reflect.DeepEqual(combined, tc.Expected) {
+			t.Errorf("%s: expected %v, got %v", tn, tc.Expected, combined)
+		}
+		if !reflect.DeepEqual(services, tc.Services) {
+			t.Errorf("%s: expected the batch body not to be modified, got %v", tn, services)
+		}
+	}
+
+	if _, err := combineServiceUsageServicesBatches([]string{}, "compute.googleapis.com"); err == nil {
+		t.Errorf("expected an error combining a body of the wrong type")
+	}
+}
+
+func TestServiceUsageBatching_dedupesServices(t *testing.T) {
+	batcher := NewRequestBatcher("testServiceUsage", context.Background(), &batchingConfig{
+		sendAfter:      100 * time.Millisecond,
+		enableBatching: true,
+	})
+
+	var mu sync.Mutex
+	sent := make(map[string][]string)
+	send := func(key string) BatcherSendFunc {
+		return func(project string, body interface{}) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			sent[key] = append(sent[key], body.([]string)...)
+			return nil, nil
+		}
+	}
+
+	requests := []struct {
+		Service, BillingProject string
+	}{
+		{"compute.googleapis.com", "p"},
+		{"compute.googleapis.com", "p"},
+		{"storage.googleapis.com", "p"},
+		// Billed to another project, so sent separately
+		{"compute.googleapis.com", "billing"},
+	}
+	var wg sync.WaitGroup
+	for i, r := range requests {
+		wg.Add(1)
+		go func(i int, service, billingProject string) {
+			defer wg.Done()
+			key := fmt.Sprintf(batchKeyTmplServiceUsageEnableServices, "p", billingProject)
+			req := &BatchRequest{
+				ResourceName: "p",
+				Body:         []string{service},
+				CombineF:     combineServiceUsageServicesBatches,
+				SendF:        send(key),
+				DebugId:      fmt.Sprintf("Enable Project Service %q #%d", service, i),
+			}
+			if _, err := batcher.SendRequestWithTimeout(key, req, time.Second); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}(i, r.Service, r.BillingProject)
+	}
+	wg.Wait()
+
+	sameBilling := sent[fmt.Sprintf(batchKeyTmplServiceUsageEnableServices, "p", "p")]
+	sort.Strings(sameBilling)
+	if expected := []string{"compute.googleapis.com", "storage.googleapis.com"}; !reflect.DeepEqual(sameBilling, expected) {
+		t.Errorf("expected %v to be enabled once each, got %v", expected, sameBilling)
+	}
+	otherBilling := sent[fmt.Sprintf(batchKeyTmplServiceUsageEnableServices, "p", "billing")]
+	if expected := []string{"compute.googleapis.com"}; !reflect.DeepEqual(otherBilling, expected) {
+		t.Errorf("expected %v to be enabled separately, got %v", expected, otherBilling)
+	}
+}
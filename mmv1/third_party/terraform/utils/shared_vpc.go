@@ -0,0 +1,158 @@
+package google
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long the Shared VPC host project of a project is remembered. Projects
+// rarely change host, and google_compute_shared_vpc_service_project forgets
+// the host of the projects it attaches or detaches.
+const sharedVpcHostCacheTTL = 10 * time.Minute
+
+// sharedVpcHostCache remembers the Shared VPC host projects of projects, so
+// resources placing networks and subnetworks in the host project don't each
+// look it up. Projects that aren't service projects are cached with an empty
+// host. Errors aren't cached.
+type sharedVpcHostCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[string]sharedVpcHostEntry
+}
+
+type sharedVpcHostEntry struct {
+	host    string
+	expires time.Time
+}
+
+func newSharedVpcHostCache(ttl time.Duration, clock Clock) *sharedVpcHostCache {
+	return &sharedVpcHostCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]sharedVpcHostEntry),
+	}
+}
+
+func (c *sharedVpcHostCache) get(project string) (host string, ok bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[project]
+	if ok && !c.clock.Now().Before(e.expires) {
+		delete(c.entries, project)
+		return "", false
+	}
+	return e.host, ok
+}
+
+func (c *sharedVpcHostCache) set(project, host string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[project] = sharedVpcHostEntry{host: host, expires: c.clock.Now().Add(c.ttl)}
+}
+
+// forget removes project from the cache, eg after it's attached to a host.
+func (c *sharedVpcHostCache) forget(project string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, project)
+}
+
+// getSharedVpcHostProject returns the ID of the Shared VPC host project of
+// project, or "" if project isn't a Shared VPC service project.
+func getSharedVpcHostProject(config *Config, project string) (string, error) {
+	if host, ok := config.sharedVpcHosts.get(project); ok {
+		return host, nil
+	}
+
+	url := fmt.Sprintf("%sprojects/%s/getXpnHost", config.ComputeBasePath, project)
+	res, err := sendRequest(config, "GET", project, url, config.userAgent, nil)
+	if err != nil {
+		return "", fmt.Errorf("Error reading the Shared VPC host project of %s: %s", project, err)
+	}
+
+	// The response is empty if project isn't a service project
+	host, _ := res["name"].(string)
+	config.sharedVpcHosts.set(project, host)
+	return host, nil
+}
+
+// isSharedVpcServiceProject returns whether project is attached to a Shared
+// VPC host project.
+func isSharedVpcServiceProject(config *Config, project string) (bool, error) {
+	host, err := getSharedVpcHostProject(config, project)
+	return host != "", err
+}
+
+// sharedVpcNetworkLink returns the relative link of the network named network
+// used by the project of config, which callers scope to their resource with
+// resourceConfig: its own network of that name if it has one, or the one in
+// its Shared VPC host project if it's a service project. Values that are
+// already links are returned unchanged.
+func sharedVpcNetworkLink(config *Config, network string) string {
+	if network == "" || strings.Contains(network, "/") {
+		return network
+	}
+	networkProject := sharedVpcReferenceProject(config, func(p string) string {
+		return fmt.Sprintf("projects/%s/global/networks/%s", p, network)
+	})
+	return fmt.Sprintf("projects/%s/global/networks/%s", networkProject, network)
+}
+
+// sharedVpcSubnetworkLink returns the relative link of the subnetwork named
+// subnetwork in region used by the project of config, found like
+// sharedVpcNetworkLink finds networks. Values that are already links are
+// returned unchanged.
+func sharedVpcSubnetworkLink(config *Config, region, subnetwork string) string {
+	if subnetwork == "" || strings.Contains(subnetwork, "/") {
+		return subnetwork
+	}
+	networkProject := sharedVpcReferenceProject(config, func(p string) string {
+		return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", p, region, subnetwork)
+	})
+	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", networkProject, region, subnetwork)
+}
+
+// sharedVpcReferenceProject returns the project a network or subnetwork
+// named in the project of config is in, given its relative link in a
+// project. It's the host project only if that project is a service project
+// without a resource of that name itself, so names that resolved to the
+// project before it was attached keep doing so. Both lookups are cached on
+// config, so resources naming the same network many times only look it up
+// once. If either lookup fails, eg for lack of permission, the project is
+// used, as it was before Shared VPC hosts were looked up.
+func sharedVpcReferenceProject(config *Config, relativeLink func(project string) string) string {
+	project := config.Project
+	host, err := getSharedVpcHostProject(config, project)
+	if err != nil {
+		log.Printf("[WARN] Assuming %s is in %s: %s", relativeLink(project), project, err)
+		return project
+	}
+	if host == "" {
+		return project
+	}
+
+	exists, err := checkResourceExists(config, config.ComputeBasePath+relativeLink(project))
+	if err != nil {
+		log.Printf("[WARN] Assuming %s is in %s: %s", relativeLink(project), project, err)
+		return project
+	}
+	if exists {
+		return project
+	}
+	log.Printf("[DEBUG] %s wasn't found, using the one in Shared VPC host project %s", relativeLink(project), host)
+	return host
+}
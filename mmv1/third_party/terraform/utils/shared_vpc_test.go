@@ -0,0 +1,100 @@
+package google
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSharedVpcHostProject(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/compute/v1/projects/service/getXpnHost", fakeAPIResponse{Body: map[string]interface{}{"name": "host"}})
+	s.Script("GET", "/compute/v1/projects/standalone/getXpnHost", fakeAPIResponse{Body: map[string]interface{}{}})
+
+	config := s.Config()
+	config.ComputeBasePath = s.URL + "/compute/v1/"
+	config.sharedVpcHosts = newSharedVpcHostCache(sharedVpcHostCacheTTL, systemClock{})
+
+	for i := 0; i < 2; i++ {
+		if host, err := getSharedVpcHostProject(config, "service"); err != nil || host != "host" {
+			t.Errorf("expected host project host, got %q and %v", host, err)
+		}
+		if ok, err := isSharedVpcServiceProject(config, "standalone"); err != nil || ok {
+			t.Errorf("expected standalone not to be a service project, got %t and %v", ok, err)
+		}
+	}
+	for _, project := range []string{"service", "standalone"} {
+		if n := s.Requests("GET", "/compute/v1/projects/"+project+"/getXpnHost"); n != 1 {
+			t.Errorf("expected the host of %s to be looked up once, got %d requests", project, n)
+		}
+	}
+}
+
+func TestSharedVpcNetworkLinks(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/compute/v1/projects/service/getXpnHost", fakeAPIResponse{Body: map[string]interface{}{"name": "host"}})
+	s.Script("GET", "/compute/v1/projects/standalone/getXpnHost", fakeAPIResponse{Body: map[string]interface{}{}})
+	s.Script("GET", "/compute/v1/projects/forbidden/getXpnHost", fakeAPIError(403, "Required 'compute.projects.get' permission"))
+	s.Script("GET", "/compute/v1/projects/service/global/networks/shared", fakeAPIError(404, "not found"))
+	s.Script("GET", "/compute/v1/projects/service/global/networks/default", fakeAPIResponse{Body: map[string]interface{}{"name": "default"}})
+	s.Script("GET", "/compute/v1/projects/service/regions/us-central1/subnetworks/sub", fakeAPIError(404, "not found"))
+
+	config := s.Config()
+	config.ComputeBasePath = s.URL + "/compute/v1/"
+	config.sharedVpcHosts = newSharedVpcHostCache(sharedVpcHostCacheTTL, systemClock{})
+	config.resourceExists = newResourceExistsCache(resourceExistsCacheTTL, systemClock{})
+
+	cases := map[string]struct {
+		got, want string
+	}{
+		"service network":       {got: sharedVpcNetworkLink(config.WithOverrides("service", "", ""), "shared"), want: "projects/host/global/networks/shared"},
+		"service's own network": {got: sharedVpcNetworkLink(config.WithOverrides("service", "", ""), "default"), want: "projects/service/global/networks/default"},
+		"standalone network":    {got: sharedVpcNetworkLink(config.WithOverrides("standalone", "", ""), "default"), want: "projects/standalone/global/networks/default"},
+		"unknown host":          {got: sharedVpcNetworkLink(config.WithOverrides("forbidden", "", ""), "default"), want: "projects/forbidden/global/networks/default"},
+		"network link":          {got: sharedVpcNetworkLink(config.WithOverrides("service", "", ""), "projects/other/global/networks/n"), want: "projects/other/global/networks/n"},
+		"service subnetwork":    {got: sharedVpcSubnetworkLink(config.WithOverrides("service", "", ""), "us-central1", "sub"), want: "projects/host/regions/us-central1/subnetworks/sub"},
+		"standalone subnetwork": {got: sharedVpcSubnetworkLink(config.WithOverrides("standalone", "", ""), "us-central1", "sub"), want: "projects/standalone/regions/us-central1/subnetworks/sub"},
+		"unset subnetwork":      {got: sharedVpcSubnetworkLink(config.WithOverrides("service", "", ""), "us-central1", ""), want: ""},
+	}
+	for tn, tc := range cases {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tn, tc.want, tc.got)
+		}
+	}
+
+	// Names used by several interfaces are only looked up once
+	for i := 0; i < 2; i++ {
+		if got := sharedVpcNetworkLink(config.WithOverrides("service", "", ""), "shared"); got != "projects/host/global/networks/shared" {
+			t.Errorf("expected projects/host/global/networks/shared, got %s", got)
+		}
+	}
+	if n := s.Requests("GET", "/compute/v1/projects/service/global/networks/shared"); n != 1 {
+		t.Errorf("expected the network to be looked up once, got %d requests", n)
+	}
+}
+
+func TestSharedVpcHostCache(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	c := newSharedVpcHostCache(time.Minute, clock)
+
+	c.set("service", "host")
+	if host, ok := c.get("service"); !ok || host != "host" {
+		t.Errorf("expected the host to be cached, got %q", host)
+	}
+	c.forget("service")
+	if _, ok := c.get("service"); ok {
+		t.Errorf("expected the host to be forgotten")
+	}
+
+	c.set("service", "host")
+	clock.Sleep(time.Minute)
+	if _, ok := c.get("service"); ok {
+		t.Errorf("expected the entry to expire")
+	}
+
+	var nilCache *sharedVpcHostCache
+	nilCache.set("service", "host")
+	nilCache.forget("service")
+	if _, ok := nilCache.get("service"); ok {
+		t.Errorf("expected a nil cache to be empty")
+	}
+}
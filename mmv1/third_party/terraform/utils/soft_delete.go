@@ -0,0 +1,126 @@
+// Creating resources whose name is held by a soft-deleted resource. Some
+// resources (eg projects, IAM custom roles, Secret Manager secret versions,
+// Cloud Storage buckets with soft delete) aren't removed right away when
+// deleted, and their name can't be reused until they're purged. Recreating
+// them after a destroy fails with an error that rarely says so.
+
+package google
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// softDeletedErrorMessages are lowercase phrases of the messages of errors
+// returned when a name is held by a soft-deleted resource, or when using a
+// soft-deleted resource.
+var softDeletedErrorMessages = []string{
+	// IAM custom roles
+	"in a deleted state",
+	// Secret Manager secret versions and KMS crypto key versions
+	"destroyed state",
+	"destroy_scheduled state",
+	// Cloud Storage buckets and objects
+	"soft-deleted",
+	"soft deleted",
+	// Projects, service accounts and others waiting to be purged
+	"pending deletion",
+}
+
+// isSoftDeletedError returns whether err says a name is held by, or a request
+// used, a soft-deleted resource.
+func isSoftDeletedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	contains := func(s string) bool { return strings.Contains(strings.ToLower(err.Error()), s) }
+	var operr *CommonOpError
+	if errors.As(err, &operr) {
+		message := strings.ToLower(operr.Message)
+		contains = func(s string) bool { return strings.Contains(message, s) }
+	} else if body, ok := parseGoogleApiErrorBody(err); ok {
+		contains = body.ContainsFold
+	}
+
+	for _, m := range softDeletedErrorMessages {
+		if contains(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// softDeleteRecovery describes how recoverOrRecreate recovers a resource whose
+// name is held by a soft-deleted resource.
+type softDeleteRecovery struct {
+	// Resource describes the resource in errors, eg `project "my-project"`
+	Resource string
+	// IsSoftDeleted returns whether err, returned by create, means the name
+	// is held by a soft-deleted resource, for APIs returning the same error
+	// as for a resource that exists (eg a 409). It may look the resource up.
+	// Errors isSoftDeletedError matches are soft-deleted regardless.
+	IsSoftDeleted func(err error) (bool, error)
+	// Undelete recovers the soft-deleted resource, so it can be updated to
+	// match the configuration. If it's nil, the provider doesn't undelete
+	// the resource on its own and create fails with UndeleteHint.
+	Undelete func() error
+	// UndeleteHint tells users how to undelete the resource themselves, eg a
+	// gcloud command. If it's empty, the resource can't be undeleted.
+	UndeleteHint string
+}
+
+// recoverOrRecreate calls create, and if it fails as the name is held by a
+// soft-deleted resource, undeletes it with recovery. recovered is true if the
+// resource was undeleted rather than created, in which case the caller should
+// update it. Otherwise the error offers the way to undelete it, if any.
+func recoverOrRecreate(create func() error, recovery softDeleteRecovery) (recovered bool, err error) {
+	createErr := create()
+	if createErr == nil {
+		return false, nil
+	}
+
+	softDeleted := isSoftDeletedError(createErr)
+	if !softDeleted && recovery.IsSoftDeleted != nil {
+		softDeleted, err = recovery.IsSoftDeleted(createErr)
+		if err != nil {
+			log.Printf("[WARN] Unable to check whether %s is soft-deleted: %s", recovery.Resource, err)
+			return false, createErr
+		}
+	}
+	if !softDeleted {
+		return false, createErr
+	}
+
+	if recovery.Undelete != nil {
+		log.Printf("[DEBUG] Undeleting soft-deleted %s rather than creating it", recovery.Resource)
+		if err := recovery.Undelete(); err != nil {
+			return false, fmt.Errorf("Error undeleting soft-deleted %s: %s", recovery.Resource, err)
+		}
+		return true, nil
+	}
+	return false, softDeletedResourceError(recovery.Resource, recovery.UndeleteHint, createErr)
+}
+
+// softDeletedResourceError returns the error for a resource that can't be
+// created as its name is held by a soft-deleted resource.
+func softDeletedResourceError(resource, undeleteHint string, err error) error {
+	if undeleteHint == "" {
+		undeleteHint = "It can't be undeleted, so choose another name or wait for it to be purged."
+	}
+	return fmt.Errorf("%s can't be created as its name is held by a soft-deleted resource. %s Error: %s", resource, undeleteHint, err)
+}
+
+// softDeletedAccessError returns err, explaining that resource can't be used
+// as it's soft-deleted if isSoftDeletedError matches it, and offering
+// undeleteHint, if any, to undelete it.
+func softDeletedAccessError(resource, undeleteHint string, err error) error {
+	if !isSoftDeletedError(err) {
+		return err
+	}
+	if undeleteHint == "" {
+		undeleteHint = "It can't be undeleted."
+	}
+	return fmt.Errorf("%s can't be used as it's soft-deleted. %s Error: %s", resource, undeleteHint, err)
+}
@@ -0,0 +1,100 @@
+package google
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsSoftDeletedError(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"custom role": {
+			err:      &googleapi.Error{Code: 400, Body: `{"error": {"code": 400, "message": "You can't create a role with role_id (myRole) where there is an existing role with that role_id in a deleted state.", "status": "FAILED_PRECONDITION"}}`},
+			expected: true,
+		},
+		"secret version": {
+			err:      &googleapi.Error{Code: 400, Body: `{"error": {"code": 400, "message": "projects/1/secrets/s/versions/1 is in DESTROYED state.", "status": "FAILED_PRECONDITION"}}`},
+			expected: true,
+		},
+		"already exists": {
+			err:      &googleapi.Error{Code: 409, Body: `{"error": {"code": 409, "message": "Requested entity already exists", "status": "ALREADY_EXISTS"}}`},
+			expected: false,
+		},
+		"nil": {
+			expected: false,
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := isSoftDeletedError(tc.err); got != tc.expected {
+			t.Errorf("%s: expected %t, got %t", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestRecoverOrRecreate(t *testing.T) {
+	softDeleted := &googleapi.Error{Code: 400, Message: "The role is in a deleted state."}
+	alreadyExists := &googleapi.Error{Code: 409, Message: "Requested entity already exists"}
+
+	// Created
+	recovered, err := recoverOrRecreate(func() error { return nil }, softDeleteRecovery{Resource: "role"})
+	if recovered || err != nil {
+		t.Errorf("expected the resource to be created, got %t, %v", recovered, err)
+	}
+
+	// Undeleted
+	undeleted := false
+	recovered, err = recoverOrRecreate(func() error { return softDeleted }, softDeleteRecovery{
+		Resource: "role",
+		Undelete: func() error { undeleted = true; return nil },
+	})
+	if !recovered || err != nil || !undeleted {
+		t.Errorf("expected the resource to be undeleted, got %t, %v", recovered, err)
+	}
+
+	// Offered the way to undelete it
+	_, err = recoverOrRecreate(func() error { return alreadyExists }, softDeleteRecovery{
+		Resource:      `project "p"`,
+		IsSoftDeleted: func(error) (bool, error) { return true, nil },
+		UndeleteHint:  "Undelete it with `gcloud projects undelete p` and import it, or choose another project_id.",
+	})
+	if err == nil || !strings.Contains(err.Error(), "gcloud projects undelete p") {
+		t.Errorf("expected an error offering to undelete the resource, got %v", err)
+	}
+
+	// Not soft-deleted, or unable to tell
+	for _, isSoftDeleted := range []func(error) (bool, error){
+		func(error) (bool, error) { return false, nil },
+		func(error) (bool, error) { return false, errors.New("permission denied") },
+	} {
+		_, err = recoverOrRecreate(func() error { return alreadyExists }, softDeleteRecovery{
+			Resource:      `project "p"`,
+			IsSoftDeleted: isSoftDeleted,
+		})
+		if err != alreadyExists {
+			t.Errorf("expected the error of create, got %v", err)
+		}
+	}
+}
+
+func TestSoftDeletedAccessError(t *testing.T) {
+	destroyed := &googleapi.Error{Code: 400, Message: "projects/1/secrets/s/versions/1 is in DESTROYED state."}
+	if err := softDeletedAccessError("secret version 1", "", destroyed); err == nil || !strings.Contains(err.Error(), "can't be undeleted") {
+		t.Errorf("expected an error saying the version can't be undeleted, got %v", err)
+	}
+
+	scheduled := &googleapi.Error{Code: 400, Message: "projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1 is in DESTROY_SCHEDULED state."}
+	if err := softDeletedAccessError("crypto key k", "Restore it with `gcloud kms keys versions restore`.", scheduled); err == nil || !strings.Contains(err.Error(), "gcloud kms keys versions restore") {
+		t.Errorf("expected an error offering to restore the version, got %v", err)
+	}
+
+	notFound := &googleapi.Error{Code: 404, Message: "not found"}
+	if err := softDeletedAccessError("secret version 1", "", notFound); err != notFound {
+		t.Errorf("expected other errors to be returned unchanged, got %v", err)
+	}
+}
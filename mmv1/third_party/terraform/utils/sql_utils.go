@@ -1,6 +1,7 @@
 package google
 
 import (
+	"fmt"
 	"log"
 	"strings"
 
@@ -24,3 +25,11 @@ func transformSQLDatabaseReadError(err error) error {
 
 	return err
 }
+
+// sqlInstanceMutexKey returns the key changes to an instance's users,
+// databases and certs are serialized on, as Cloud SQL only runs one operation
+// per instance at a time. It must match the mutex of google_sql_database in
+// products/sql/terraform.yaml.
+func sqlInstanceMutexKey(project, instance string) string {
+	return fmt.Sprintf("google-sql-database-instance-%s-%s", project, instance)
+}
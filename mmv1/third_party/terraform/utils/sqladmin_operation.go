@@ -115,7 +115,7 @@ func sqlAdminOperationWaitTime(config *Config, res interface{}, project, activit
 	if err := w.SetOp(op); err != nil {
 		return err
 	}
-	return OperationWait(w, activity, timeout, config.PollInterval)
+	return OperationWaitWithClock(w, activity, timeout, config.PollInterval, config.getClock())
 }
 
 // SqlAdminOperationError wraps sqladmin.OperationError and implements the
@@ -0,0 +1,60 @@
+package google
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StateSetter sets fields in state, recording the ones that fail rather than
+// stopping at the first, so that reads setting many fields report every field
+// that's broken at once. Callers must call Flush once they're done setting
+// fields.
+//
+//	s := NewStateSetter(d)
+//	s.Set("name", res.Name)
+//	s.Set("labels", res.Labels)
+//	if err := s.Flush(); err != nil {
+//		return err
+//	}
+type StateSetter struct {
+	d      TerraformResourceData
+	failed []stateSetterError
+}
+
+type stateSetterError struct {
+	key string
+	err error
+}
+
+func NewStateSetter(d TerraformResourceData) *StateSetter {
+	return &StateSetter{d: d}
+}
+
+// Set sets key to value, recording the error if it can't be set.
+func (s *StateSetter) Set(key string, value interface{}) {
+	if err := s.d.Set(key, value); err != nil {
+		s.failed = append(s.failed, stateSetterError{key: key, err: err})
+	}
+}
+
+// Flush returns an error listing every field that failed to set, or nil if
+// they were all set. The failures are forgotten, so the setter can be reused.
+func (s *StateSetter) Flush() error {
+	failed := s.failed
+	s.failed = nil
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("Error setting %s: %s", failed[0].key, failed[0].err)
+	}
+
+	keys := make([]string, 0, len(failed))
+	errs := make([]string, 0, len(failed))
+	for _, f := range failed {
+		keys = append(keys, f.key)
+		errs = append(errs, fmt.Sprintf("\t* %s: %s", f.key, f.err))
+	}
+	return fmt.Errorf("Error setting %s:\n%s", strings.Join(keys, ", "), strings.Join(errs, "\n"))
+}
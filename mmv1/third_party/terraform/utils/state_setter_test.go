@@ -0,0 +1,53 @@
+package google
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestStateSetter(t *testing.T) {
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name":   {Type: schema.TypeString, Optional: true},
+			"size":   {Type: schema.TypeInt, Optional: true},
+			"labels": {Type: schema.TypeMap, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+		},
+	}
+	d := r.TestResourceData()
+	s := NewStateSetter(d)
+
+	s.Set("name", "foo")
+	s.Set("labels", map[string]string{"env": "prod"})
+	if err := s.Flush(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Get("name") != "foo" || d.Get("labels.env") != "prod" {
+		t.Errorf("expected the fields to be set, got %v and %v", d.Get("name"), d.Get("labels"))
+	}
+
+	// Fields after a failure are still set
+	s.Set("size", "large")
+	s.Set("name", "bar")
+	err := s.Flush()
+	if err == nil || !strings.HasPrefix(err.Error(), "Error setting size: ") {
+		t.Errorf("expected an error setting size, got %v", err)
+	}
+	if d.Get("name") != "bar" {
+		t.Errorf("expected name to be set after the failure, got %v", d.Get("name"))
+	}
+
+	// Every failure is reported
+	s.Set("size", "large")
+	s.Set("labels", []string{"env"})
+	err = s.Flush()
+	if err == nil || !strings.HasPrefix(err.Error(), "Error setting size, labels:\n\t* size: ") || !strings.Contains(err.Error(), "\n\t* labels: ") {
+		t.Errorf("expected errors setting size and labels, got %v", err)
+	}
+
+	// Flush forgets the failures
+	if err := s.Flush(); err != nil {
+		t.Errorf("expected no errors after a flush, got %s", err)
+	}
+}
@@ -0,0 +1,270 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// The functions in this file build the Upgrade functions of
+// schema.StateUpgraders from common steps, so a schema version bump is
+// mostly declarative:
+//
+//	StateUpgraders: []schema.StateUpgrader{
+//		{
+//			Type:    resourceFooResourceV0().CoreConfigSchema().ImpliedType(),
+//			Upgrade: chainStateUpgrades(
+//				renameStateField("zone", "location"),
+//				changeStateFieldType("settings.disk_size", stateValueToInt),
+//			),
+//			Version: 0,
+//		},
+//	}
+//
+// Paths are field names separated by dots, eg "settings.backup.enabled".
+// Blocks are lists in the raw state, and a path through a block applies to
+// every element of it. Steps do nothing where a path isn't in the state.
+
+// chainStateUpgrades returns an Upgrade function running steps in order.
+func chainStateUpgrades(steps ...schema.StateUpgradeFunc) schema.StateUpgradeFunc {
+	return func(ctx context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+		log.Printf("[DEBUG] Attributes before migration: %#v", rawState)
+		for _, step := range steps {
+			var err error
+			rawState, err = step(ctx, rawState, meta)
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.Printf("[DEBUG] Attributes after migration: %#v", rawState)
+		return rawState, nil
+	}
+}
+
+// stateFieldParents returns the objects of rawState holding the last field
+// of path, and that field's name.
+func stateFieldParents(rawState map[string]interface{}, path string) ([]map[string]interface{}, string) {
+	parts := strings.Split(path, ".")
+	parents := []map[string]interface{}{rawState}
+	for _, part := range parts[:len(parts)-1] {
+		var next []map[string]interface{}
+		for _, p := range parents {
+			switch v := p[part].(type) {
+			case map[string]interface{}:
+				next = append(next, v)
+			case []interface{}:
+				for _, e := range v {
+					if m, ok := e.(map[string]interface{}); ok {
+						next = append(next, m)
+					}
+				}
+			}
+		}
+		parents = next
+	}
+	return parents, parts[len(parts)-1]
+}
+
+// renameStateField moves the field at path to newName, in the same block.
+func renameStateField(path, newName string) schema.StateUpgradeFunc {
+	return func(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+		parents, name := stateFieldParents(rawState, path)
+		for _, p := range parents {
+			if v, ok := p[name]; ok {
+				delete(p, name)
+				p[newName] = v
+			}
+		}
+		return rawState, nil
+	}
+}
+
+// changeStateFieldType replaces the value of the field at path with its
+// conversion by convert. Null values aren't converted.
+func changeStateFieldType(path string, convert func(v interface{}) (interface{}, error)) schema.StateUpgradeFunc {
+	return func(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+		parents, name := stateFieldParents(rawState, path)
+		for _, p := range parents {
+			v, ok := p[name]
+			if !ok || v == nil {
+				continue
+			}
+			converted, err := convert(v)
+			if err != nil {
+				return nil, fmt.Errorf("Error migrating %s: %s", path, err)
+			}
+			p[name] = converted
+		}
+		return rawState, nil
+	}
+}
+
+// stateValueToString converts a scalar value of the raw state to a string.
+func stateValueToString(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to a string", v)
+}
+
+// stateValueToInt converts a number or a string holding one to an integer.
+// Empty strings are converted to 0, the zero value of unset fields.
+func stateValueToInt(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		if v == "" {
+			return int64(0), nil
+		}
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return nil, fmt.Errorf("cannot convert %T to an integer", v)
+}
+
+// stateValueToBool converts a bool or a string holding one to a bool. Empty
+// strings are converted to false, the zero value of unset fields.
+func stateValueToBool(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if v == "" {
+			return false, nil
+		}
+		return strconv.ParseBool(v)
+	}
+	return nil, fmt.Errorf("cannot convert %T to a bool", v)
+}
+
+// stateValueToList converts a value to a list holding it, for fields that
+// became lists. Lists are kept as they are.
+func stateValueToList(v interface{}) (interface{}, error) {
+	if l, ok := v.([]interface{}); ok {
+		return l, nil
+	}
+	return []interface{}{v}, nil
+}
+
+// splitStateId sets fields from the "id" of the state, using the first of
+// patterns that matches it. Patterns are regular expressions with named
+// groups, eg `projects/(?P<project>[^/]+)/locations/(?P<location>[^/]+)`,
+// and set the fields named by their groups. Fields that are already set are
+// kept.
+func splitStateId(patterns ...string) schema.StateUpgradeFunc {
+	regexes := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		regexes[i] = regexp.MustCompile("^" + p + "$")
+	}
+
+	return func(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+		id, _ := rawState["id"].(string)
+		for _, re := range regexes {
+			m := re.FindStringSubmatch(id)
+			if m == nil {
+				continue
+			}
+			for i, field := range re.SubexpNames() {
+				if field == "" {
+					continue
+				}
+				if v, ok := rawState[field]; !ok || v == nil || v == "" {
+					rawState[field] = m[i]
+				}
+			}
+			return rawState, nil
+		}
+		return nil, fmt.Errorf("Error migrating id %q: it doesn't match any of %q", id, patterns)
+	}
+}
+
+// formatStateId sets the "id" of the state from format, replacing
+// {{field}} with the value of field, eg "{{project}}/{{name}}".
+func formatStateId(format string) schema.StateUpgradeFunc {
+	re := regexp.MustCompile(`{{([[:word:]]+)}}`)
+	return func(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+		var missing []string
+		id := re.ReplaceAllStringFunc(format, func(m string) string {
+			field := re.FindStringSubmatch(m)[1]
+			v, _ := rawState[field].(string)
+			if v == "" {
+				missing = append(missing, field)
+			}
+			return v
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("Error migrating id to %q: %s not set", format, strings.Join(missing, ", "))
+		}
+		rawState["id"] = id
+		return rawState, nil
+	}
+}
+
+// nestStateBlock moves fields into the block at path, which has MaxItems: 1
+// in the new schema. The fields are siblings of the block. The block isn't
+// created if none of the fields are set.
+func nestStateBlock(path string, fields ...string) schema.StateUpgradeFunc {
+	return func(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+		parents, name := stateFieldParents(rawState, path)
+		for _, p := range parents {
+			block := make(map[string]interface{})
+			for _, f := range fields {
+				if v, ok := p[f]; ok {
+					delete(p, f)
+					if v != nil {
+						block[f] = v
+					}
+				}
+			}
+			if len(block) > 0 {
+				p[name] = []interface{}{block}
+			}
+		}
+		return rawState, nil
+	}
+}
+
+// flattenStateBlock moves the fields of the block at path, which had
+// MaxItems: 1 in the old schema, next to it and removes the block.
+func flattenStateBlock(path string) schema.StateUpgradeFunc {
+	return func(_ context.Context, rawState map[string]interface{}, _ interface{}) (map[string]interface{}, error) {
+		parents, name := stateFieldParents(rawState, path)
+		for _, p := range parents {
+			v, ok := p[name]
+			if !ok {
+				continue
+			}
+			delete(p, name)
+			l, _ := v.([]interface{})
+			if len(l) == 0 {
+				continue
+			}
+			if len(l) > 1 {
+				return nil, fmt.Errorf("Error migrating %s: expected at most 1 block, got %d", path, len(l))
+			}
+			block, _ := l[0].(map[string]interface{})
+			for k, fv := range block {
+				p[k] = fv
+			}
+		}
+		return rawState, nil
+	}
+}
@@ -0,0 +1,161 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestStateUpgraders(t *testing.T) {
+	cases := map[string]struct {
+		upgrade   schema.StateUpgradeFunc
+		before    string
+		after     string
+		expectErr bool
+	}{
+		"rename field": {
+			upgrade: renameStateField("zone", "location"),
+			before:  `{"id": "a", "zone": "us-central1-a"}`,
+			after:   `{"id": "a", "location": "us-central1-a"}`,
+		},
+		"rename missing field": {
+			upgrade: renameStateField("zone", "location"),
+			before:  `{"id": "a"}`,
+			after:   `{"id": "a"}`,
+		},
+		"rename field in every block": {
+			upgrade: renameStateField("rules.action.kind", "type"),
+			before:  `{"rules": [{"action": [{"kind": "allow"}]}, {"action": [{"kind": "deny"}]}]}`,
+			after:   `{"rules": [{"action": [{"type": "allow"}]}, {"action": [{"type": "deny"}]}]}`,
+		},
+		"string to int": {
+			upgrade: changeStateFieldType("settings.disk_size", stateValueToInt),
+			before:  `{"settings": [{"disk_size": "10"}]}`,
+			after:   `{"settings": [{"disk_size": 10}]}`,
+		},
+		"empty string to int": {
+			upgrade: changeStateFieldType("disk_size", stateValueToInt),
+			before:  `{"disk_size": ""}`,
+			after:   `{"disk_size": 0}`,
+		},
+		"invalid int": {
+			upgrade:   changeStateFieldType("disk_size", stateValueToInt),
+			before:    `{"disk_size": "ten"}`,
+			expectErr: true,
+		},
+		"number to string": {
+			upgrade: changeStateFieldType("port", stateValueToString),
+			before:  `{"port": 8080}`,
+			after:   `{"port": "8080"}`,
+		},
+		"string to bool": {
+			upgrade: changeStateFieldType("enabled", stateValueToBool),
+			before:  `{"enabled": "true"}`,
+			after:   `{"enabled": true}`,
+		},
+		"null isn't converted": {
+			upgrade: changeStateFieldType("enabled", stateValueToBool),
+			before:  `{"enabled": null}`,
+			after:   `{"enabled": null}`,
+		},
+		"scalar to list": {
+			upgrade: changeStateFieldType("members", stateValueToList),
+			before:  `{"members": "user:a@example.com"}`,
+			after:   `{"members": ["user:a@example.com"]}`,
+		},
+		"split id": {
+			upgrade: splitStateId(
+				`projects/(?P<project>[^/]+)/locations/(?P<location>[^/]+)/things/(?P<name>[^/]+)`,
+				`(?P<project>[^/]+)/(?P<name>[^/]+)`,
+			),
+			before: `{"id": "projects/p/locations/l/things/a", "name": "a", "location": ""}`,
+			after:  `{"id": "projects/p/locations/l/things/a", "name": "a", "location": "l", "project": "p"}`,
+		},
+		"split id with a later pattern": {
+			upgrade: splitStateId(
+				`projects/(?P<project>[^/]+)/locations/(?P<location>[^/]+)/things/(?P<name>[^/]+)`,
+				`(?P<project>[^/]+)/(?P<name>[^/]+)`,
+			),
+			before: `{"id": "p/a"}`,
+			after:  `{"id": "p/a", "project": "p", "name": "a"}`,
+		},
+		"split unmatched id": {
+			upgrade:   splitStateId(`(?P<project>[^/]+)/(?P<name>[^/]+)`),
+			before:    `{"id": "a"}`,
+			expectErr: true,
+		},
+		"format id": {
+			upgrade: formatStateId("projects/{{project}}/things/{{name}}"),
+			before:  `{"id": "a", "project": "p", "name": "a"}`,
+			after:   `{"id": "projects/p/things/a", "project": "p", "name": "a"}`,
+		},
+		"format id with missing field": {
+			upgrade:   formatStateId("projects/{{project}}/things/{{name}}"),
+			before:    `{"id": "a", "name": "a"}`,
+			expectErr: true,
+		},
+		"nest block": {
+			upgrade: nestStateBlock("backup", "backup_enabled", "backup_start_time"),
+			before:  `{"name": "a", "backup_enabled": true, "backup_start_time": "01:00"}`,
+			after:   `{"name": "a", "backup": [{"backup_enabled": true, "backup_start_time": "01:00"}]}`,
+		},
+		"nest block without fields": {
+			upgrade: nestStateBlock("backup", "backup_enabled"),
+			before:  `{"name": "a", "backup_enabled": null}`,
+			after:   `{"name": "a"}`,
+		},
+		"flatten block": {
+			upgrade: flattenStateBlock("settings.backup"),
+			before:  `{"settings": [{"tier": "f1", "backup": [{"enabled": true}]}]}`,
+			after:   `{"settings": [{"tier": "f1", "enabled": true}]}`,
+		},
+		"flatten empty block": {
+			upgrade: flattenStateBlock("backup"),
+			before:  `{"name": "a", "backup": []}`,
+			after:   `{"name": "a"}`,
+		},
+		"chained": {
+			upgrade: chainStateUpgrades(
+				renameStateField("zone", "location"),
+				splitStateId(`(?P<project>[^/]+)/(?P<name>[^/]+)`),
+				formatStateId("projects/{{project}}/locations/{{location}}/things/{{name}}"),
+			),
+			before: `{"id": "p/a", "zone": "us-central1-a"}`,
+			after:  `{"id": "projects/p/locations/us-central1-a/things/a", "location": "us-central1-a", "project": "p", "name": "a"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		var rawState map[string]interface{}
+		if err := json.Unmarshal([]byte(tc.before), &rawState); err != nil {
+			t.Fatalf("%s: invalid state: %s", tn, err)
+		}
+
+		got, err := tc.upgrade(context.Background(), rawState, nil)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+
+		// Compare as JSON, as converted numbers are ints rather than float64s.
+		gotJSON, err := json.Marshal(got)
+		if err != nil {
+			t.Fatalf("%s: %s", tn, err)
+		}
+		var gotState, wantState interface{}
+		json.Unmarshal(gotJSON, &gotState)
+		json.Unmarshal([]byte(tc.after), &wantState)
+		if !reflect.DeepEqual(gotState, wantState) {
+			t.Errorf("%s: expected %s, got %s", tn, tc.after, gotJSON)
+		}
+	}
+}
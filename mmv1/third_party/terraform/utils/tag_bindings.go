@@ -0,0 +1,259 @@
+// Helpers for binding Resource Manager tags to resources other than projects,
+// folders and organizations, which have their own google_tags_tag_binding
+// resource. Resources opt in by adding tagBindingsSchema() to their schema,
+// calling updateTagBindings in Create and Update once the resource exists and
+// calling readTagBindings in Read. Tag values can be configured by name
+// (tagValues/456) or namespaced name (my-project/env/prod).
+
+package google
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const tagBindingsField = "tag_values"
+
+var tagValueNameRegex = regexp.MustCompile(`^tagValues/[0-9]+$`)
+
+// Namespaced names are {parent}/{key short name}/{value short name}, where the
+// parent is an organization id or a project id.
+var tagValueNamespacedNameRegex = regexp.MustCompile(`^[^/]+/[^/]+/[^/]+$`)
+
+func tagBindingsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeSet,
+		Optional: true,
+		Elem: &schema.Schema{
+			Type:         schema.TypeString,
+			ValidateFunc: validateTagValue,
+		},
+		Set:         schema.HashString,
+		Description: `Resource Manager tag values bound to the resource, either by name (tagValues/456) or by namespaced name (my-project/env/prod).`,
+	}
+}
+
+func validateTagValue(v interface{}, k string) (ws []string, errs []error) {
+	value := v.(string)
+	if !tagValueNameRegex.MatchString(value) && !tagValueNamespacedNameRegex.MatchString(value) {
+		errs = append(errs, fmt.Errorf("%s: %q is not a tag value name (tagValues/456) or namespaced name (my-project/env/prod)", k, value))
+	}
+	return
+}
+
+// tagBindingsBasePath returns the endpoint managing the tag bindings of
+// resources in location. Bindings of regional and zonal resources are managed
+// by location specific endpoints.
+func tagBindingsBasePath(config *Config, location string) string {
+	if location == "" || location == "global" {
+		return config.TagsBasePath
+	}
+	return fmt.Sprintf("https://%s-cloudresourcemanager.googleapis.com/v3/", strings.ToLower(location))
+}
+
+// tagBindingName returns the name of the binding of tagValue to parent, the
+// full resource name of the resource, eg
+// //compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/123.
+func tagBindingName(parent, tagValue string) string {
+	return fmt.Sprintf("tagBindings/%s/%s", url.PathEscape(parent), tagValue)
+}
+
+// resolveTagValue returns the name of a tag value given by name or namespaced
+// name. Like the other tag binding helpers, it sends requests with the user
+// agent of config, which callers scope to their resource with resourceConfig.
+func resolveTagValue(config *Config, value string) (string, error) {
+	if tagValueNameRegex.MatchString(value) {
+		return value, nil
+	}
+
+	u, err := addQueryParams(config.TagsBasePath+"tagValues/namespaced", map[string]string{"name": value})
+	if err != nil {
+		return "", err
+	}
+	res, err := sendRequest(config, "GET", "", u, config.userAgent, nil)
+	if err != nil {
+		return "", fmt.Errorf("Error looking up tag value %q: %s", value, err)
+	}
+	name, ok := res["name"].(string)
+	if !ok || !tagValueNameRegex.MatchString(name) {
+		return "", fmt.Errorf("Error looking up tag value %q: unexpected name %v", value, res["name"])
+	}
+	return name, nil
+}
+
+// resolveTagValues returns the names of values, which are given by name or
+// namespaced name, and a map from each name to the form it was given in.
+func resolveTagValues(config *Config, values []string) ([]string, map[string]string, error) {
+	names := make([]string, 0, len(values))
+	forms := make(map[string]string, len(values))
+	for _, v := range values {
+		name, err := resolveTagValue(config, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+		forms[name] = v
+	}
+	return names, forms, nil
+}
+
+// isTagBindingParentNotFoundError matches the errors returned when binding
+// tags to a resource that was only just created, which isn't visible to
+// Resource Manager yet.
+func isTagBindingParentNotFoundError(err error) (bool, string) {
+	if isGoogleApiErrorWithCode(err, 404) {
+		return true, "Resource not visible to Resource Manager yet"
+	}
+	if isGoogleApiErrorWithCode(err, 400) && strings.Contains(err.Error(), "not found") {
+		return true, "Resource not visible to Resource Manager yet"
+	}
+	return false, ""
+}
+
+// listTagBindings returns the names of the tag values bound to parent.
+func listTagBindings(config *Config, location, parent string, timeout time.Duration) ([]string, error) {
+	var values []string
+	err := retryTimeDuration(func() error {
+		bindings, err := paginatedList(config, "", tagBindingsBasePath(config, location)+"tagBindings", config.userAgent, ListRequest{
+			ItemsPath:   "tagBindings",
+			QueryParams: map[string]string{"parent": parent},
+		})
+		if err != nil {
+			return err
+		}
+		values = make([]string, 0, len(bindings))
+		for _, b := range bindings {
+			if v, ok := b.(map[string]interface{})["tagValue"].(string); ok {
+				values = append(values, v)
+			}
+		}
+		return nil
+	}, timeout, isTagBindingParentNotFoundError)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing tag bindings of %s: %s", parent, err)
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+// readTagBindings sets the tag values bound to parent in state. Values are set
+// in the form they were configured in where possible, so values configured by
+// namespaced name don't produce diffs. Values bound outside of Terraform are
+// set by name.
+func readTagBindings(d *schema.ResourceData, config *Config, location, parent string) error {
+	bound, err := listTagBindings(config, location, parent, d.Timeout(schema.TimeoutRead))
+	if err != nil {
+		return err
+	}
+
+	var configured []string
+	if v, ok := d.GetOk(tagBindingsField); ok {
+		configured = convertStringArr(v.(*schema.Set).List())
+	}
+	_, forms, err := resolveTagValues(config, configured)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, 0, len(bound))
+	for _, name := range bound {
+		if form, ok := forms[name]; ok {
+			values = append(values, form)
+		} else {
+			values = append(values, name)
+		}
+	}
+	if err := d.Set(tagBindingsField, values); err != nil {
+		return fmt.Errorf("Error setting %s: %s", tagBindingsField, err)
+	}
+	return nil
+}
+
+// updateTagBindings reconciles the tag values bound to parent with the ones
+// configured, removing bindings that are no longer configured and adding new
+// ones. It's safe to call for new resources, whose tags may not be bindable
+// until Resource Manager sees them.
+func updateTagBindings(d *schema.ResourceData, config *Config, location, parent string, timeout time.Duration) error {
+	old, new := d.GetChange(tagBindingsField)
+	if d.IsNewResource() {
+		old = schema.NewSet(schema.HashString, nil)
+	}
+	from, _, err := resolveTagValues(config, convertStringArr(old.(*schema.Set).List()))
+	if err != nil {
+		return err
+	}
+	to, _, err := resolveTagValues(config, convertStringArr(new.(*schema.Set).List()))
+	if err != nil {
+		return err
+	}
+
+	add, remove := calcAddRemove(from, to)
+	basePath := tagBindingsBasePath(config, location)
+	for _, value := range remove {
+		log.Printf("[DEBUG] Removing tag value %s from %s", value, parent)
+		op, err := sendRequestWithTimeout(config, "DELETE", "", basePath+tagBindingName(parent, value), config.userAgent, nil, timeout)
+		if err != nil {
+			if isGoogleApiErrorWithCode(err, 404) {
+				continue
+			}
+			return fmt.Errorf("Error removing tag value %s from %s: %s", value, parent, err)
+		}
+		if err := tagBindingsOperationWaitTime(config, op, basePath, fmt.Sprintf("Removing tag value %s", value), config.userAgent, timeout); err != nil {
+			return err
+		}
+	}
+	for _, value := range add {
+		log.Printf("[DEBUG] Adding tag value %s to %s", value, parent)
+		body := map[string]interface{}{
+			"parent":   parent,
+			"tagValue": value,
+		}
+		op, err := sendRequestWithTimeout(config, "POST", "", basePath+"tagBindings", config.userAgent, body, timeout, isTagBindingParentNotFoundError)
+		if err != nil {
+			return fmt.Errorf("Error adding tag value %s to %s: %s", value, parent, err)
+		}
+		if err := tagBindingsOperationWaitTime(config, op, basePath, fmt.Sprintf("Adding tag value %s", value), config.userAgent, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagBindingsOperationWaiter waits for operations of location specific
+// Resource Manager endpoints, which the generated Tags waiter doesn't support.
+type TagBindingsOperationWaiter struct {
+	Config    *Config
+	UserAgent string
+	BasePath  string
+	// Timeout is the timeout of the operation, which polls time out after
+	Timeout time.Duration
+	CommonOperationWaiter
+}
+
+func (w *TagBindingsOperationWaiter) QueryOp() (interface{}, error) {
+	if w == nil {
+		return nil, fmt.Errorf("Cannot query operation, it's unset or nil.")
+	}
+	url := fmt.Sprintf("%s%s", w.BasePath, w.CommonOperationWaiter.Op.Name)
+	return sendPollingRequest(w.Config, "", url, w.UserAgent, w.Timeout)
+}
+
+func tagBindingsOperationWaitTime(config *Config, op map[string]interface{}, basePath, activity, userAgent string, timeout time.Duration) error {
+	w := &TagBindingsOperationWaiter{
+		Config:    config,
+		UserAgent: userAgent,
+		BasePath:  basePath,
+		Timeout:   timeout,
+	}
+	if err := w.CommonOperationWaiter.SetOp(op); err != nil {
+		return err
+	}
+	return OperationWaitWithClock(w, activity, timeout, config.PollInterval, config.getClock())
+}
@@ -0,0 +1,75 @@
+package google
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestValidateTagValue(t *testing.T) {
+	cases := map[string]bool{
+		"tagValues/456":           true,
+		"my-project/env/prod":     true,
+		"123456789/env/prod":      true,
+		"tagValues/prod":          false,
+		"tagKeys/123":             false,
+		"env/prod":                false,
+		"my-project/env/prod/foo": false,
+	}
+	for value, valid := range cases {
+		_, errs := validateTagValue(value, tagBindingsField)
+		if valid && len(errs) > 0 {
+			t.Errorf("%q: expected to be valid, got %v", value, errs)
+		}
+		if !valid && len(errs) == 0 {
+			t.Errorf("%q: expected to be invalid", value)
+		}
+	}
+}
+
+func TestTagBindingName(t *testing.T) {
+	got := tagBindingName("//compute.googleapis.com/projects/my-project/zones/us-central1-a/instances/123", "tagValues/456")
+	expected := "tagBindings/%2F%2Fcompute.googleapis.com%2Fprojects%2Fmy-project%2Fzones%2Fus-central1-a%2Finstances%2F123/tagValues/456"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestResolveTagValues(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Script("GET", "/v3/tagValues/namespaced", fakeAPIResponse{Body: map[string]interface{}{"name": "tagValues/789"}})
+	config := s.Config()
+	config.TagsBasePath = s.URL + "/v3/"
+
+	names, forms, err := resolveTagValues(config, []string{"tagValues/456", "my-project/env/prod"})
+	if err != nil {
+		t.Fatalf("unexpected error resolving tag values: %s", err)
+	}
+	if got := fmt.Sprint(names); got != "[tagValues/456 tagValues/789]" {
+		t.Errorf("expected tag value names, got %s", got)
+	}
+	if forms["tagValues/789"] != "my-project/env/prod" {
+		t.Errorf("expected tagValues/789 to map to its namespaced name, got %v", forms)
+	}
+	if n := s.Requests("GET", "/v3/tagValues/namespaced"); n != 1 {
+		t.Errorf("expected only the namespaced name to be looked up, got %d requests", n)
+	}
+}
+
+func TestListTagBindings(t *testing.T) {
+	s := newFakeAPIServer(t)
+	s.Pages("/v3/tagBindings", "tagBindings",
+		[]interface{}{map[string]interface{}{"tagValue": "tagValues/9"}},
+		[]interface{}{map[string]interface{}{"tagValue": "tagValues/1"}},
+	)
+	config := s.Config()
+	config.TagsBasePath = s.URL + "/v3/"
+
+	values, err := listTagBindings(config, "global", "//storage.googleapis.com/projects/_/buckets/my-bucket", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error listing tag bindings: %s", err)
+	}
+	if got := fmt.Sprint(values); got != "[tagValues/1 tagValues/9]" {
+		t.Errorf("expected bound tag values from every page, got %s", got)
+	}
+}
@@ -0,0 +1,116 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test configs too long to be read inline are kept in testConfigFixturesDir,
+// as a template <name>.tf.tmpl using Nprintf placeholders, and optionally the
+// values of its placeholders shared by every test using it, <name>.json.
+const testConfigFixturesDir = "test-fixtures/configs"
+
+// loadTestConfig returns the test config name rendered with the values of
+// <name>.json, overridden by params. Values which differ between tests or
+// runs, eg random_suffix, are passed as params. The test fails if the config
+// has a placeholder without a value, or a value isn't used.
+func loadTestConfig(t *testing.T, name string, params map[string]interface{}) string {
+	t.Helper()
+
+	tmpl, err := ioutil.ReadFile(filepath.Join(testConfigFixturesDir, name+".tf.tmpl"))
+	if err != nil {
+		t.Fatalf("error reading test config %s: %s", name, err)
+	}
+
+	values := make(map[string]interface{})
+	raw, err := ioutil.ReadFile(filepath.Join(testConfigFixturesDir, name+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("error reading the values of test config %s: %s", name, err)
+	}
+	if err == nil {
+		// Numbers are kept as written rather than formatted as floats
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			t.Fatalf("error parsing the values of test config %s: %s", name, err)
+		}
+	}
+	for k, v := range params {
+		values[k] = v
+	}
+
+	config, err := NprintfStrict(string(tmpl), values)
+	if err != nil {
+		t.Fatalf("error rendering test config %s: %s", name, err)
+	}
+	return config
+}
+
+func TestLoadTestConfig(t *testing.T) {
+	config := loadTestConfig(t, "compute_network_subnetwork", map[string]interface{}{
+		"random_suffix": "abc123",
+		"region":        "europe-west1",
+	})
+
+	for _, expected := range []string{
+		`name                    = "tf-test-network-abc123"`,
+		`region        = "europe-west1"`,
+		`ip_cidr_range = "10.0.0.0/16"`,
+	} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("expected the config to contain %q, got:\n%s", expected, config)
+		}
+	}
+}
+
+func TestNprintfStrict(t *testing.T) {
+	cases := map[string]struct {
+		format      string
+		params      map[string]interface{}
+		expected    string
+		expectedErr string
+	}{
+		"all used": {
+			format:   "%{a}-%{b}-%{a}",
+			params:   map[string]interface{}{"a": "x", "b": 10},
+			expected: "x-10-x",
+		},
+		"values aren't substituted again": {
+			format:   "%{a}",
+			params:   map[string]interface{}{"a": "%{a}"},
+			expected: "%{a}",
+		},
+		"missing": {
+			format:      "%{a}-%{b}-%{b}",
+			params:      map[string]interface{}{"a": "x"},
+			expectedErr: "no values for %{b}",
+		},
+		"unused": {
+			format:      "%{a}",
+			params:      map[string]interface{}{"a": "x", "c": "y", "b": "z"},
+			expectedErr: "values for b, c aren't used",
+		},
+	}
+
+	for tn, tc := range cases {
+		s, err := NprintfStrict(tc.format, tc.params)
+		if tc.expectedErr != "" {
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Errorf("%s: expected error %q, got %v", tn, tc.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if s != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tn, tc.expected, s)
+		}
+	}
+}
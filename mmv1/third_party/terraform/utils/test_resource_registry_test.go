@@ -0,0 +1,194 @@
+package google
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests that leak resources (eg because they failed midway through, or the
+// test binary was killed) leave them behind in the test project. Names created
+// through randResourceName are recorded in the file named by this env var so
+// that sweepers can clean them up even if they don't use one of the standard
+// test prefixes, and marked destroyed once their test passes, as its
+// CheckDestroy has verified they're gone. Run the sweepers with
+// `go test -sweep=<region>` to clean up.
+const testResourceRegistryEnvVar = "TF_TEST_RESOURCE_REGISTRY"
+
+var (
+	generatedNamesLock sync.Mutex
+	generatedNames     = map[string]string{}
+)
+
+// randResourceName generates a resource name of the form
+// {prefix}-{timestamp}-{random}, records it in the resource registry and
+// guards against two tests in the same run generating the same name. Tests
+// using it must check their resources are destroyed, as the name is marked
+// destroyed in the registry when the test passes.
+//
+// In VCR mode the timestamp is omitted so recorded names can be replayed.
+func randResourceName(t *testing.T, prefix string, length int) string {
+	for attempt := 0; ; attempt++ {
+		suffix := randString(t, length)
+		name := fmt.Sprintf("%s-%s", prefix, suffix)
+		if !isVcrEnabled() {
+			name = fmt.Sprintf("%s-%s-%s", prefix, time.Now().UTC().Format("0102150405"), suffix)
+		}
+
+		generatedNamesLock.Lock()
+		owner, collision := generatedNames[name]
+		if !collision {
+			generatedNames[name] = t.Name()
+		}
+		generatedNamesLock.Unlock()
+
+		if !collision {
+			if err := recordTestResourceName(t.Name(), name, ""); err != nil {
+				log.Printf("[WARN] Unable to record test resource name %q: %s", name, err)
+			}
+			t.Cleanup(func() {
+				if t.Failed() {
+					return
+				}
+				if err := recordTestResourceName(t.Name(), name, testResourceDestroyed); err != nil {
+					log.Printf("[WARN] Unable to record test resource name %q as destroyed: %s", name, err)
+				}
+			})
+			return name
+		}
+
+		// Retrying changes the sequence of names in VCR mode, so fail loudly
+		// instead of producing a cassette that can't be replayed.
+		if isVcrEnabled() || attempt >= 5 {
+			t.Fatalf("generated resource name %q for %s collides with the name generated for %s", name, t.Name(), owner)
+		}
+		log.Printf("[DEBUG] Generated resource name %q collides with one used by %s, regenerating", name, owner)
+	}
+}
+
+// testResourceDestroyed is the status of registry entries of names whose
+// resources were destroyed.
+const testResourceDestroyed = "destroyed"
+
+// recordTestResourceName appends a name to the resource registry, if one is
+// configured. Entries are tab separated: timestamp, test name, resource name
+// and, for destroyed resources, testResourceDestroyed.
+func recordTestResourceName(testName, name, status string) error {
+	path := os.Getenv(testResourceRegistryEnvVar)
+	if path == "" {
+		return nil
+	}
+
+	generatedNamesLock.Lock()
+	defer generatedNamesLock.Unlock()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entry := []string{time.Now().UTC().Format(time.RFC3339), testName, name}
+	if status != "" {
+		entry = append(entry, status)
+	}
+	_, err = fmt.Fprintln(f, strings.Join(entry, "\t"))
+	return err
+}
+
+// readTestResourceRegistry returns the set of resource names recorded in the
+// resource registry file at path that weren't marked destroyed since.
+func readTestResourceRegistry(path string) (map[string]struct{}, error) {
+	names := map[string]struct{}{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) < 3 || parts[2] == "" {
+			continue
+		}
+		if len(parts) > 3 && parts[3] == testResourceDestroyed {
+			delete(names, parts[2])
+			continue
+		}
+		names[parts[2]] = struct{}{}
+	}
+	return names, scanner.Err()
+}
+
+var (
+	registeredTestResourcesOnce sync.Once
+	registeredTestResources     map[string]struct{}
+)
+
+// isRegisteredTestResource returns whether name was recorded in the resource
+// registry by a previous test run.
+func isRegisteredTestResource(name string) bool {
+	registeredTestResourcesOnce.Do(func() {
+		registeredTestResources = map[string]struct{}{}
+
+		path := os.Getenv(testResourceRegistryEnvVar)
+		if path == "" {
+			return
+		}
+
+		names, err := readTestResourceRegistry(path)
+		if err != nil {
+			log.Printf("[WARN] Unable to read test resource registry %s: %s", path, err)
+			return
+		}
+		log.Printf("[INFO][SWEEPER_LOG] Loaded %d names from test resource registry %s", len(names), path)
+		registeredTestResources = names
+	})
+
+	_, ok := registeredTestResources[name]
+	return ok
+}
+
+func TestTestResourceRegistry(t *testing.T) {
+	path := t.TempDir() + "/registry"
+	old := os.Getenv(testResourceRegistryEnvVar)
+	os.Setenv(testResourceRegistryEnvVar, path)
+	defer os.Setenv(testResourceRegistryEnvVar, old)
+
+	var first, second string
+	t.Run("passed", func(t *testing.T) {
+		first = randResourceName(t, "tf-test", 10)
+		second = randResourceName(t, "tf-test", 10)
+		if first == second {
+			t.Fatalf("expected unique names, got %q twice", first)
+		}
+
+		names, err := readTestResourceRegistry(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, n := range []string{first, second} {
+			if _, ok := names[n]; !ok {
+				t.Errorf("expected %q to be recorded in the registry, got %v", n, names)
+			}
+		}
+	})
+
+	names, err := readTestResourceRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected the names of a passed test to be marked destroyed, got %v", names)
+	}
+}
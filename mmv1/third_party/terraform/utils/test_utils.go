@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
@@ -84,7 +87,7 @@ func (d *ResourceDiffMock) GetChange(key string) (interface{}, interface{}) {
 
 func (d *ResourceDiffMock) HasChange(key string) bool {
 	old, new := d.GetChange(key)
-	return old != new
+	return !reflect.DeepEqual(old, new)
 }
 
 func (d *ResourceDiffMock) Get(key string) interface{} {
@@ -109,6 +112,129 @@ func (d *ResourceDiffMock) ForceNew(key string) error {
 	return nil
 }
 
+// newResourceDataMockFromFixture returns a ResourceDataMock holding fixture,
+// a map of field values shaped as they are in the schema. Nested values can
+// also be read by their full key, eg "node_config.0.machine_type", as they
+// can from a ResourceData. Fields in changed are reported by HasChange.
+func newResourceDataMockFromFixture(fixture map[string]interface{}, changed ...string) *ResourceDataMock {
+	fields := make(map[string]interface{})
+	for k, v := range fixture {
+		flattenAttributePaths(fields, k, v)
+	}
+	return &ResourceDataMock{
+		FieldsInSchema:      fields,
+		FieldsWithHasChange: changed,
+	}
+}
+
+// testErrorReporter is the subset of *testing.T used by the assertion
+// helpers, so this file doesn't depend on the testing package.
+type testErrorReporter interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// assertExpandsTo runs expand on input and reports any difference between the
+// result and want.
+func assertExpandsTo(t testErrorReporter, expand func(interface{}, TerraformResourceData, *Config) (interface{}, error), input interface{}, d TerraformResourceData, config *Config, want interface{}) {
+	t.Helper()
+	got, err := expand(input, d, config)
+	if err != nil {
+		t.Errorf("unexpected error expanding %#v: %s", input, err)
+		return
+	}
+	if diffs := diffTestValues(got, want); len(diffs) > 0 {
+		t.Errorf("unexpected result expanding %#v (-got +want):\n%s", input, strings.Join(diffs, "\n"))
+	}
+}
+
+// assertFlattensTo runs flatten on input and reports any difference between
+// the result and want.
+func assertFlattensTo(t testErrorReporter, flatten func(interface{}, *schema.ResourceData, *Config) interface{}, input interface{}, d *schema.ResourceData, config *Config, want interface{}) {
+	t.Helper()
+	got := flatten(input, d, config)
+	if diffs := diffTestValues(got, want); len(diffs) > 0 {
+		t.Errorf("unexpected result flattening %#v (-got +want):\n%s", input, strings.Join(diffs, "\n"))
+	}
+}
+
+// diffTestValues compares got and want, walking nested maps and lists, and
+// returns a line for each path where they differ. Sets are compared as the
+// lists of their elements.
+func diffTestValues(got, want interface{}) []string {
+	return appendTestValueDiffs(nil, "", got, want)
+}
+
+func appendTestValueDiffs(diffs []string, path string, got, want interface{}) []string {
+	if s, ok := got.(*schema.Set); ok {
+		got = s.List()
+	}
+	if s, ok := want.(*schema.Set); ok {
+		want = s.List()
+	}
+
+	gotMap, gotIsMap := got.(map[string]interface{})
+	wantMap, wantIsMap := want.(map[string]interface{})
+	if gotIsMap && wantIsMap {
+		keys := make(map[string]struct{})
+		for k := range gotMap {
+			keys[k] = struct{}{}
+		}
+		for k := range wantMap {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			g, gok := gotMap[k]
+			w, wok := wantMap[k]
+			p := strings.TrimPrefix(path+"."+k, ".")
+			switch {
+			case !gok:
+				diffs = append(diffs, fmt.Sprintf("+ %s: %#v", p, w))
+			case !wok:
+				diffs = append(diffs, fmt.Sprintf("- %s: %#v", p, g))
+			default:
+				diffs = appendTestValueDiffs(diffs, p, g, w)
+			}
+		}
+		return diffs
+	}
+
+	gotList, gotIsList := got.([]interface{})
+	wantList, wantIsList := want.([]interface{})
+	if gotIsList && wantIsList {
+		for i := 0; i < len(gotList) || i < len(wantList); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(gotList):
+				diffs = append(diffs, fmt.Sprintf("+ %s: %#v", p, wantList[i]))
+			case i >= len(wantList):
+				diffs = append(diffs, fmt.Sprintf("- %s: %#v", p, gotList[i]))
+			default:
+				diffs = appendTestValueDiffs(diffs, p, gotList[i], wantList[i])
+			}
+		}
+		return diffs
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		if path == "" {
+			path = "(root)"
+		}
+		g, w := fmt.Sprintf("%#v", got), fmt.Sprintf("%#v", want)
+		if g == w {
+			// eg float64(1) and int(1)
+			g, w = fmt.Sprintf("%T(%s)", got, g), fmt.Sprintf("%T(%s)", want, w)
+		}
+		diffs = append(diffs, fmt.Sprintf("- %s: %s", path, g), fmt.Sprintf("+ %s: %s", path, w))
+	}
+	return diffs
+}
+
 func checkDataSourceStateMatchesResourceState(dataSourceName, resourceName string) func(*terraform.State) error {
 	return checkDataSourceStateMatchesResourceStateWithIgnores(dataSourceName, resourceName, map[string]struct{}{})
 }
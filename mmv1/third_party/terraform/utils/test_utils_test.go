@@ -0,0 +1,126 @@
+package google
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNewResourceDataMockFromFixture(t *testing.T) {
+	d := newResourceDataMockFromFixture(map[string]interface{}{
+		"name": "foo",
+		"node_config": []interface{}{
+			map[string]interface{}{
+				"machine_type": "e2-medium",
+				"labels": map[string]interface{}{
+					"env": "test",
+				},
+			},
+		},
+	}, "node_config")
+
+	expected := map[string]interface{}{
+		"name":                       "foo",
+		"node_config.#":              1,
+		"node_config.0.machine_type": "e2-medium",
+		"node_config.0.labels.env":   "test",
+	}
+	for k, v := range expected {
+		if got := d.Get(k); !reflect.DeepEqual(got, v) {
+			t.Errorf("expected %s to be %#v, got %#v", k, v, got)
+		}
+	}
+
+	if !d.HasChange("node_config") || d.HasChange("name") {
+		t.Errorf("expected only node_config to have changed")
+	}
+}
+
+func TestDiffTestValues(t *testing.T) {
+	cases := map[string]struct {
+		Got, Want interface{}
+		Expected  []string
+	}{
+		"equal": {
+			Got:  map[string]interface{}{"a": []interface{}{"b"}},
+			Want: map[string]interface{}{"a": []interface{}{"b"}},
+		},
+		"nested value": {
+			Got:  map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "x"}}},
+			Want: map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "y"}}},
+			Expected: []string{
+				`- a[0].b: "x"`,
+				`+ a[0].b: "y"`,
+			},
+		},
+		"missing and extra keys": {
+			Got:  map[string]interface{}{"a": "x", "b": "y"},
+			Want: map[string]interface{}{"b": "y", "c": "z"},
+			Expected: []string{
+				`- a: "x"`,
+				`+ c: "z"`,
+			},
+		},
+		"list lengths": {
+			Got:  []interface{}{"a"},
+			Want: []interface{}{"a", "b"},
+			Expected: []string{
+				`+ [1]: "b"`,
+			},
+		},
+		"types": {
+			Got:  float64(1),
+			Want: 1,
+			Expected: []string{
+				`- (root): float64(1)`,
+				`+ (root): int(1)`,
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		got := diffTestValues(tc.Got, tc.Want)
+		if len(got) != len(tc.Expected) {
+			t.Errorf("bad: %s, expected %q, got %q", tn, tc.Expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.Expected[i] {
+				t.Errorf("bad: %s, expected %q, got %q", tn, tc.Expected, got)
+				break
+			}
+		}
+	}
+}
+
+type recordingErrorReporter struct {
+	errors []string
+}
+
+func (r *recordingErrorReporter) Helper() {}
+
+func (r *recordingErrorReporter) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertExpandsTo(t *testing.T) {
+	d := newResourceDataMockFromFixture(map[string]interface{}{})
+
+	r := &recordingErrorReporter{}
+	assertExpandsTo(r, expandIpField, "2001:DB8:0:0::1", d, nil, "2001:db8::1")
+	if len(r.errors) != 0 {
+		t.Errorf("expected no errors, got %q", r.errors)
+	}
+
+	r = &recordingErrorReporter{}
+	assertExpandsTo(r, expandIpField, "10.0.0.1", d, nil, "10.0.0.2")
+	if len(r.errors) != 1 {
+		t.Errorf("expected an error, got %q", r.errors)
+	}
+
+	r = &recordingErrorReporter{}
+	assertFlattensTo(r, flattenIpField, "10.0.0.0/24", nil, nil, "10.0.0.0/24")
+	if len(r.errors) != 0 {
+		t.Errorf("expected no errors, got %q", r.errors)
+	}
+}
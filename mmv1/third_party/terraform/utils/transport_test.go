@@ -1,10 +1,13 @@
 package google
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
@@ -181,3 +184,109 @@ func TestReplaceVars(t *testing.T) {
 		}
 	}
 }
+
+func TestSendRequestWithOptions_Headers(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"name": "foo"}`))
+	}))
+	defer ts.Close()
+
+	config := &Config{
+		client:              ts.Client(),
+		UserProjectOverride: true,
+	}
+	headers := make(http.Header)
+	headers.Set("X-Goog-Request-Params", "name=projects/my-project/locations/us-central1")
+	headers.Set("Content-Type", "application/merge-patch+json")
+
+	res, err := sendRequestWithOptions(SendRequestOptions{
+		Config:    config,
+		Method:    "GET",
+		Project:   "my-project",
+		RawURL:    ts.URL + "/v1/foo",
+		UserAgent: "test-agent",
+		Headers:   headers,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res["name"] != "foo" {
+		t.Errorf("expected response name foo, got %v", res["name"])
+	}
+
+	expected := map[string]string{
+		"User-Agent":            "test-agent",
+		"X-Goog-User-Project":   "my-project",
+		"X-Goog-Request-Params": "name=projects/my-project/locations/us-central1",
+		"Content-Type":          "application/merge-patch+json",
+	}
+	for k, v := range expected {
+		if got.Get(k) != v {
+			t.Errorf("expected header %s to be %q, got %q", k, v, got.Get(k))
+		}
+	}
+}
+
+func TestSendRequestWithOptions_UseNumber(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"fingerprint": 9007199254740993}`))
+	}))
+	defer ts.Close()
+
+	res, err := sendRequestWithOptions(SendRequestOptions{
+		Config:    &Config{client: ts.Client()},
+		Method:    "GET",
+		RawURL:    ts.URL + "/v1/foo",
+		UseNumber: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := flattenInt64String(res["fingerprint"]); got != "9007199254740993" {
+		t.Errorf("expected the fingerprint to round-trip exactly, got %v", got)
+	}
+}
+
+func TestSendRequestWithOptions_RequestIdParam(t *testing.T) {
+	var ids []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.URL.Query().Get("requestId"))
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": {"code": 503, "message": "unavailable"}}`))
+			return
+		}
+		w.Write([]byte(`{"name": "foo"}`))
+	}))
+	defer ts.Close()
+
+	_, err := sendRequestWithOptions(SendRequestOptions{
+		Config:         &Config{client: ts.Client()},
+		Method:         "POST",
+		RawURL:         ts.URL + "/v1/foo",
+		Body:           map[string]interface{}{"name": "foo"},
+		Timeout:        time.Minute,
+		RequestIdParam: "requestId",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected the request to be retried once, got %d requests", len(ids))
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Errorf("expected retries to reuse the same request ID, got %q", ids)
+	}
+}
+
+func TestWithRequestId(t *testing.T) {
+	u, err := withRequestId("https://compute.googleapis.com/compute/v1/projects/p/global/networks?requestId=abc", "requestId")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.HasSuffix(u, "requestId=abc") {
+		t.Errorf("expected an existing request ID to be kept, got %s", u)
+	}
+}
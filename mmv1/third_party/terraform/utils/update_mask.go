@@ -0,0 +1,76 @@
+package google
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// buildUpdateMask returns the comma separated updateMask listing the API
+// fields of the changed fields in d, for APIs whose PATCH methods only update
+// the fields named in the mask.
+//
+// fieldMap maps Terraform field paths, eg "retention_days" or
+// "settings.0.backup_configuration", to the API field paths they're sent as.
+// An empty API path is derived from the Terraform path by converting each
+// segment to camelCase and dropping list indexes, so the example above becomes
+// "settings.backupConfiguration". A field sent as several API fields can list
+// them separated by commas. The mask is ordered by Terraform field path so it
+// is stable across runs.
+func buildUpdateMask(d TerraformResourceData, fieldMap map[string]string) string {
+	return buildUpdateMaskWithComparators(d, fieldMap, nil)
+}
+
+// buildUpdateMaskWithComparators is buildUpdateMask, checking the Terraform
+// fields of comparators for changes with hasSemanticChange, eg to leave lists
+// of blocks that were only reordered out of the mask. The schema of those
+// fields must suppress the same differences, eg with a DiffSuppressFunc using
+// the same comparator, or the changes left out are planned again forever.
+func buildUpdateMaskWithComparators(d TerraformResourceData, fieldMap map[string]string, comparators map[string]semanticComparator) string {
+	fields := make([]string, 0, len(fieldMap))
+	for field := range fieldMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var mask []string
+	seen := make(map[string]struct{})
+	for _, field := range fields {
+		if !d.HasChange(field) {
+			continue
+		}
+		if equal, ok := comparators[field]; ok {
+			if cd, ok := d.(semanticChangeData); ok && !hasSemanticChange(cd, field, equal) {
+				continue
+			}
+		}
+
+		apiPaths := fieldMap[field]
+		if apiPaths == "" {
+			apiPaths = updateMaskPath(field)
+		}
+		for _, p := range strings.Split(apiPaths, ",") {
+			p = strings.TrimSpace(p)
+			if _, ok := seen[p]; ok || p == "" {
+				continue
+			}
+			seen[p] = struct{}{}
+			mask = append(mask, p)
+		}
+	}
+	return strings.Join(mask, ",")
+}
+
+// updateMaskPath converts a Terraform field path to the path of the
+// corresponding API field.
+func updateMaskPath(field string) string {
+	var parts []string
+	for _, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			// list index
+			continue
+		}
+		parts = append(parts, SnakeToCamelCase(part))
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,61 @@
+package google
+
+import (
+	"testing"
+)
+
+func TestBuildUpdateMask(t *testing.T) {
+	fieldMap := map[string]string{
+		"description":                     "",
+		"retention_days":                  "",
+		"labels":                          "",
+		"settings.0.backup_configuration": "",
+		"settings.0.ip_configuration.0.authorized_networks": "",
+		"event_trigger": "eventTrigger,eventTrigger.failurePolicy.retry",
+		"display_name":  "display_name",
+		"source_bucket": "sourceArchiveUrl",
+		"source_object": "sourceArchiveUrl",
+	}
+
+	cases := map[string]struct {
+		changed  []string
+		expected string
+	}{
+		"no changes": {
+			expected: "",
+		},
+		"top level fields": {
+			changed:  []string{"retention_days", "description"},
+			expected: "description,retentionDays",
+		},
+		"map field": {
+			changed:  []string{"labels"},
+			expected: "labels",
+		},
+		"nested fields": {
+			changed:  []string{"settings.0.backup_configuration", "settings.0.ip_configuration.0.authorized_networks"},
+			expected: "settings.backupConfiguration,settings.ipConfiguration.authorizedNetworks",
+		},
+		"explicit paths": {
+			changed:  []string{"event_trigger", "display_name"},
+			expected: "display_name,eventTrigger,eventTrigger.failurePolicy.retry",
+		},
+		"fields sharing a path": {
+			changed:  []string{"source_bucket", "source_object"},
+			expected: "sourceArchiveUrl",
+		},
+		"unmapped fields are ignored": {
+			changed:  []string{"name", "labels"},
+			expected: "labels",
+		},
+	}
+
+	for tn, tc := range cases {
+		d := &ResourceDataMock{
+			FieldsWithHasChange: tc.changed,
+		}
+		if got := buildUpdateMask(d, fieldMap); got != tc.expected {
+			t.Errorf("%s: expected update mask %q, got %q", tn, tc.expected, got)
+		}
+	}
+}
@@ -3,9 +3,10 @@
 package google
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"sort"
 	"strconv"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 	"google.golang.org/api/googleapi"
@@ -93,9 +95,24 @@ func getRouterLockName(region string, router string) string {
 }
 
 func handleNotFoundError(err error, d *schema.ResourceData, resource string) error {
+	// Nested objects weren't requested by their own URL, so unlike other
+	// 404s there's no URL to remember as not found.
+	var nested *NestedObjectNotFound
+	if errors.As(err, &nested) {
+		switch nested.Reason {
+		case nestedObjectNotFoundInParent:
+			log.Printf("[WARN] Removing %s because %s %s is no longer in %s", resource, nested.ChildType, nested.Name, nested.Parent)
+		default:
+			log.Printf("[WARN] Removing %s because %s %s was discarded when decoding %s", resource, nested.ChildType, nested.Name, nested.Parent)
+		}
+		d.SetId("")
+		return nil
+	}
+
 	if isGoogleApiErrorWithCode(err, 404) {
 		log.Printf("[WARN] Removing %s because it's gone", resource)
 		// The resource doesn't exist anymore
+		rememberNotFound(err)
 		d.SetId("")
 
 		return nil
@@ -106,46 +123,67 @@ func handleNotFoundError(err error, d *schema.ResourceData, resource string) err
 }
 
 func isGoogleApiErrorWithCode(err error, errCode int) bool {
+	// The errors of an operation made up of several requests only have the
+	// code if all of them do, eg a delete isn't a 404 because one of its
+	// requests was.
+	if merr, ok := errwrap.GetType(err, &MultiError{}).(*MultiError); ok && merr != nil {
+		for _, e := range merr.Errors {
+			if !isGoogleApiErrorWithCode(e, errCode) {
+				return false
+			}
+		}
+		return len(merr.Errors) > 0
+	}
 	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
 	return ok && gerr != nil && gerr.Code == errCode
 }
 
-func isApiNotEnabledError(err error) bool {
+// Known values of the reason field of the errors in a googleapi.Error. Reasons
+// are more specific than HTTP codes, which many unrelated errors share. See
+// https://cloud.google.com/storage/docs/json_api/v1/status-codes for a
+// description of most of them.
+const (
+	// The API isn't enabled on the project (403)
+	errorReasonAccessNotConfigured = "accessNotConfigured"
+	// The resource isn't in a state that allows the request (400)
+	errorReasonFailedPrecondition = "failedPrecondition"
+	// A per-project or per-user rate limit was hit (403 or 429)
+	errorReasonRateLimitExceeded     = "rateLimitExceeded"
+	errorReasonUserRateLimitExceeded = "userRateLimitExceeded"
+	// A quota, usually a daily or allocation quota, was exhausted (403)
+	errorReasonQuotaExceeded = "quotaExceeded"
+	// A precondition such as an etag or generation match failed (412)
+	errorReasonConditionNotMet = "conditionNotMet"
+	// The resource is referenced by another resource (400)
+	errorReasonResourceInUse = "resourceInUseByAnotherResource"
+)
+
+// errorHasReason returns whether err is, or wraps, a googleapi.Error with the
+// given HTTP code and one of the given reasons. A code of 0 matches any code.
+func errorHasReason(err error, code int, reasons ...string) bool {
 	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
-	if !ok {
+	if !ok || gerr == nil {
 		return false
 	}
-	if gerr == nil {
-		return false
-	}
-	if gerr.Code != 403 {
+	if code != 0 && gerr.Code != code {
 		return false
 	}
 	for _, e := range gerr.Errors {
-		if e.Reason == "accessNotConfigured" {
-			return true
+		for _, reason := range reasons {
+			if e.Reason == reason {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+func isApiNotEnabledError(err error) bool {
+	return errorHasReason(err, 403, errorReasonAccessNotConfigured)
+}
+
 func isFailedPreconditionError(err error) bool {
-	gerr, ok := errwrap.GetType(err, &googleapi.Error{}).(*googleapi.Error)
-	if !ok {
-		return false
-	}
-	if gerr == nil {
-		return false
-	}
-	if gerr.Code != 400 {
-		return false
-	}
-	for _, e := range gerr.Errors {
-		if e.Reason == "failedPrecondition" {
-			return true
-		}
-	}
-	return false
+	return errorHasReason(err, 400, errorReasonFailedPrecondition)
 }
 
 func isConflictError(err error) bool {
@@ -186,33 +224,117 @@ func expandStringMap(d TerraformResourceData, key string) map[string]string {
 	return convertStringMap(v.(map[string]interface{}))
 }
 
+// stringMapKeyFunc validates a key of a string map, returning the key to send
+// to the API.
+type stringMapKeyFunc func(key string) (string, error)
+
+// stringMapValueFunc validates a value of a string map.
+type stringMapValueFunc func(value string) error
+
+// expandStringMapWithValidation pulls the value of key out of a
+// TerraformResourceData as a map[string]string like expandStringMap, but
+// checks each entry first so that invalid entries fail before the request is
+// sent rather than with a less specific error from the API. Errors name the
+// offending entry, eg labels["Env"], and all invalid entries are reported at
+// once. Either validator may be nil.
+func expandStringMapWithValidation(d TerraformResourceData, key string, validateKey stringMapKeyFunc, validateValue stringMapValueFunc) (map[string]string, error) {
+	v, ok := d.GetOk(key)
+	if !ok {
+		return map[string]string{}, nil
+	}
+
+	raw := v.(map[string]interface{})
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs *multierror.Error
+	m := make(map[string]string, len(raw))
+	source := make(map[string]string, len(raw))
+	for _, k := range keys {
+		val := raw[k].(string)
+		apiKey := k
+		if validateKey != nil {
+			var err error
+			if apiKey, err = validateKey(k); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s[%q]: invalid key: %s", key, k, err))
+				continue
+			}
+		}
+		if validateValue != nil {
+			if err := validateValue(val); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s[%q]: invalid value %q: %s", key, k, val, err))
+				continue
+			}
+		}
+		if prev, ok := source[apiKey]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("%s[%q]: key is the same as %s[%q] once normalized to %q", key, k, key, prev, apiKey))
+			continue
+		}
+		source[apiKey] = k
+		m[apiKey] = val
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// lowercaseStringMapKey lowercases keys before validating them with
+// validateKey, for APIs that store keys in lowercase anyway.
+func lowercaseStringMapKey(validateKey stringMapKeyFunc) stringMapKeyFunc {
+	return func(key string) (string, error) {
+		return validateKey(strings.ToLower(key))
+	}
+}
+
 func convertStringMap(v map[string]interface{}) map[string]string {
-	m := make(map[string]string)
+	m := make(map[string]string, len(v))
 	for k, val := range v {
 		m[k] = val.(string)
 	}
 	return m
 }
 
+// convertStringArr converts a list of strings read from the schema, skipping
+// nil elements. It returns nil if there are no strings.
 func convertStringArr(ifaceArr []interface{}) []string {
-	return convertAndMapStringArr(ifaceArr, func(s string) string { return s })
+	return convertAndMapStringArr(ifaceArr, nil)
 }
 
+// convertAndMapStringArr is convertStringArr, mapping the strings with f if
+// it isn't nil.
 func convertAndMapStringArr(ifaceArr []interface{}, f func(string) string) []string {
-	var arr []string
+	if len(ifaceArr) == 0 {
+		return nil
+	}
+	arr := make([]string, 0, len(ifaceArr))
 	for _, v := range ifaceArr {
 		if v == nil {
 			continue
 		}
-		arr = append(arr, f(v.(string)))
+		s := v.(string)
+		if f != nil {
+			s = f(s)
+		}
+		arr = append(arr, s)
+	}
+	if len(arr) == 0 {
+		return nil
 	}
 	return arr
 }
 
 func mapStringArr(original []string, f func(string) string) []string {
-	var arr []string
-	for _, v := range original {
-		arr = append(arr, f(v))
+	if len(original) == 0 {
+		return nil
+	}
+	arr := make([]string, len(original))
+	for i, v := range original {
+		arr[i] = f(v)
 	}
 	return arr
 }
@@ -325,7 +447,15 @@ func extractFirstMapConfig(m []interface{}) map[string]interface{} {
 }
 
 func lockedCall(lockKey string, f func() error) error {
-	mutexKV.Lock(lockKey)
+	return lockedCallContext(context.Background(), lockKey, f)
+}
+
+// lockedCallContext calls f while holding the lock for lockKey, returning an
+// error without calling f if ctx is done before the lock is acquired.
+func lockedCallContext(ctx context.Context, lockKey string, f func() error) error {
+	if err := mutexKV.LockContext(ctx, lockKey); err != nil {
+		return err
+	}
 	defer mutexKV.Unlock(lockKey)
 
 	return f()
@@ -342,6 +472,45 @@ func Nprintf(format string, params map[string]interface{}) string {
 	return format
 }
 
+var nprintfPlaceholderRegex = regexp.MustCompile(`%\{([^}]*)\}`)
+
+// NprintfStrict is Nprintf returning an error if format has a placeholder
+// missing from params, or params has a value format doesn't use, rather than
+// leaving the placeholder in the result. Values are substituted once, so a
+// value containing "%{...}" is left as is.
+func NprintfStrict(format string, params map[string]interface{}) (string, error) {
+	var missing []string
+	used := make(map[string]bool)
+	s := nprintfPlaceholderRegex.ReplaceAllStringFunc(format, func(p string) string {
+		key := nprintfPlaceholderRegex.FindStringSubmatch(p)[1]
+		val, ok := params[key]
+		if !ok {
+			if !stringInSlice(missing, key) {
+				missing = append(missing, key)
+			}
+			return p
+		}
+		used[key] = true
+		return fmt.Sprintf("%v", val)
+	})
+
+	var unused []string
+	for key := range params {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no values for %%{%s}", strings.Join(missing, "}, %{"))
+	}
+	if len(unused) > 0 {
+		return "", fmt.Errorf("values for %s aren't used", strings.Join(unused, ", "))
+	}
+	return s, nil
+}
+
 // serviceAccountFQN will attempt to generate the fully qualified name in the format of:
 // "projects/(-|<project>)/serviceAccounts/<service_account_id>@<project>.iam.gserviceaccount.com"
 // A project is required if we are trying to build the FQN from a service account id and
@@ -368,30 +537,6 @@ func serviceAccountFQN(serviceAccount string, d TerraformResourceData, config *C
 	return fmt.Sprintf("projects/-/serviceAccounts/%s@%s.iam.gserviceaccount.com", serviceAccount, project), nil
 }
 
-func paginatedListRequest(project, baseUrl, userAgent string, config *Config, flattener func(map[string]interface{}) []interface{}) ([]interface{}, error) {
-	res, err := sendRequest(config, "GET", project, baseUrl, userAgent, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	ls := flattener(res)
-	pageToken, ok := res["pageToken"]
-	for ok {
-		if pageToken.(string) == "" {
-			break
-		}
-		url := fmt.Sprintf("%s?pageToken=%s", baseUrl, pageToken.(string))
-		res, err = sendRequest(config, "GET", project, url, userAgent, nil)
-		if err != nil {
-			return nil, err
-		}
-		ls = append(ls, flattener(res))
-		pageToken, ok = res["pageToken"]
-	}
-
-	return ls, nil
-}
-
 func getInterconnectAttachmentLink(config *Config, project, region, ic, userAgent string) (string, error) {
 	if !strings.Contains(ic, "/") {
 		icData, err := config.NewComputeClient(userAgent).InterconnectAttachments.Get(
@@ -477,11 +622,49 @@ func generateUserAgentString(d TerraformResourceData, currentUserAgent string) (
 		return currentUserAgent, err
 	}
 
-	if m.ModuleName != "" {
-		return strings.Join([]string{currentUserAgent, m.ModuleName}, " "), nil
+	parts := []string{currentUserAgent}
+	if m.ModuleName != nil && *m.ModuleName != "" {
+		parts = append(parts, *m.ModuleName)
+	}
+	parts = append(parts, providerMetaUserAgentTokens(m)...)
+
+	return strings.Join(parts, " "), nil
+}
+
+var invalidUserAgentTokenChars = regexp.MustCompile("[^A-Za-z0-9!#$%&'*+.^_`|~-]")
+
+// providerMetaUserAgentTokens returns the team, cost center and request tags
+// set in provider_meta as User-Agent product tokens, eg "team/payments". The
+// User-Agent is recorded in Cloud Audit Logs, which lets requests made by a
+// module be attributed without every API supporting request labels.
+func providerMetaUserAgentTokens(m providerMeta) []string {
+	tags := make(map[string]string, len(m.RequestTags)+2)
+	for k, v := range m.RequestTags {
+		tags[k] = v
+	}
+	if m.Team != nil && *m.Team != "" {
+		tags["team"] = *m.Team
+	}
+	if m.CostCenter != nil && *m.CostCenter != "" {
+		tags["cost-center"] = *m.CostCenter
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	return currentUserAgent, nil
+	tokens := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k == "" || tags[k] == "" {
+			continue
+		}
+		tokens = append(tokens, fmt.Sprintf("%s/%s",
+			invalidUserAgentTokenChars.ReplaceAllString(k, "_"),
+			invalidUserAgentTokenChars.ReplaceAllString(tags[k], "_")))
+	}
+	return tokens
 }
 
 func SnakeToPascalCase(s string) string {
@@ -492,15 +675,6 @@ func SnakeToPascalCase(s string) string {
 	return strings.Join(split, "")
 }
 
-func multiEnvSearch(ks []string) string {
-	for _, k := range ks {
-		if v := os.Getenv(k); v != "" {
-			return v
-		}
-	}
-	return ""
-}
-
 func GetCurrentUserEmail(config *Config, userAgent string) (string, error) {
 	// See https://github.com/golang/oauth2/issues/306 for a recommendation to do this from a Go maintainer
 	// URL retrieved from https://accounts.google.com/.well-known/openid-configuration
@@ -519,35 +693,108 @@ func checkStringMap(v interface{}) map[string]string {
 	return convertStringMap(v.(map[string]interface{}))
 }
 
-// return a fake 404 so requests get retried or nested objects are considered deleted
-func fake404(reasonResourceType, resourceName string) *googleapi.Error {
+// Reasons a NestedObjectNotFound wasn't found.
+const (
+	// The object isn't in the list of objects of its parent
+	nestedObjectNotFoundInParent = "nested"
+	// The decoder of the object's resource discarded it
+	nestedObjectNotFoundDecoded = "decoded"
+)
+
+// NestedObjectNotFound is the error of an object that's part of a parent
+// resource, eg an entry of a list in it, not being found in its parent. It's
+// a 404 to isGoogleApiErrorWithCode, so polling for the absence of the object
+// and handleNotFoundError treat it as deleted.
+type NestedObjectNotFound struct {
+	// Reason is why the object wasn't found, eg nestedObjectNotFoundInParent
+	Reason string
+	// Parent is the URL of the resource the object is part of
+	Parent string
+	// ChildType is the resource type of the object, eg ComputeRouterNat
+	ChildType string
+	// Name identifies the object, usually by its ID
+	Name string
+}
+
+func (e *NestedObjectNotFound) Error() string {
+	return e.googleApiError().Error()
+}
+
+func (e *NestedObjectNotFound) googleApiError() *googleapi.Error {
 	return &googleapi.Error{
 		Code:    404,
-		Message: fmt.Sprintf("%v object %v not found", reasonResourceType, resourceName),
+		Message: fmt.Sprintf("%v object %v %v not found in %v", e.Reason, e.ChildType, e.Name, e.Parent),
+	}
+}
+
+// WrappedErrors lets errwrap.GetType, used by isGoogleApiErrorWithCode, find
+// the 404.
+func (e *NestedObjectNotFound) WrappedErrors() []error {
+	return []error{e.googleApiError()}
+}
+
+// return a fake 404 so requests get retried or nested objects are considered deleted
+func fake404(reason, parent, childType, name string) *NestedObjectNotFound {
+	return &NestedObjectNotFound{
+		Reason:    reason,
+		Parent:    parent,
+		ChildType: childType,
+		Name:      name,
 	}
 }
 
-// validate name of the gcs bucket.
+const (
+	gcsNameMaxLength          = 63
+	gcsDottedNameMaxLength    = 222
+	gcsNameComponentMaxLength = 63
+)
+
+var (
+	gcsNameCharsRegexp     = regexp.MustCompile("^[a-z0-9_.-]+$")
+	gcsNameGoogleRegexp    = regexp.MustCompile("g[o0][o0]g[l1]e")
+	gcsNameIPAddressRegexp = regexp.MustCompile(`^[0-9]{1,3}(\.[0-9]{1,3}){3}$`)
+)
+
+// checkGCSName returns why name isn't a valid name of a Cloud Storage bucket,
+// following https://cloud.google.com/storage/docs/naming-buckets
 func checkGCSName(name string) error {
-	MAX_LENGTH := 63
-	var err error
-	for _, str := range strings.Split(name, ".") {
-		strLen := len(str)
-		fmt.Println(str)
-		if strLen > MAX_LENGTH {
-			return fmt.Errorf("error: maximum length exceeded %v\n", str)
+	if !gcsNameCharsRegexp.MatchString(name) {
+		return fmt.Errorf("bucket name %q may only contain lowercase letters, numbers, dashes, underscores and dots", name)
+	}
+	if !isGCSNameAlphanumeric(name[0]) || !isGCSNameAlphanumeric(name[len(name)-1]) {
+		return fmt.Errorf("bucket name %q must start and end with a letter or number", name)
+	}
+
+	if !strings.Contains(name, ".") {
+		if len(name) < 3 || len(name) > gcsNameMaxLength {
+			return fmt.Errorf("bucket name %q must be between 3 and %d characters long, it is %d", name, gcsNameMaxLength, len(name))
 		}
-		valid, _ := regexp.MatchString("^[a-z0-9_]+(-[a-z0-9]+)*$", str)
-		if !valid {
-			return fmt.Errorf("error: string validation failed %v\n", str)
+	} else {
+		if len(name) < 3 || len(name) > gcsDottedNameMaxLength {
+			return fmt.Errorf("bucket name %q containing dots must be between 3 and %d characters long, it is %d", name, gcsDottedNameMaxLength, len(name))
 		}
-		gPrefix := strings.HasPrefix(str, "goog")
-		if gPrefix {
-			return fmt.Errorf("error: string cannot start with %q %v\n", "goog", str)
+		if gcsNameIPAddressRegexp.MatchString(name) {
+			return fmt.Errorf("bucket name %q cannot be an IP address", name)
 		}
-		if err != nil {
-			break
+		for _, component := range strings.Split(name, ".") {
+			if component == "" {
+				return fmt.Errorf("bucket name %q cannot contain consecutive dots", name)
+			}
+			if len(component) > gcsNameComponentMaxLength {
+				return fmt.Errorf("component %q of bucket name %q must be at most %d characters long, it is %d", component, name, gcsNameComponentMaxLength, len(component))
+			}
 		}
 	}
+
+	if strings.HasPrefix(name, "goog") {
+		return fmt.Errorf("bucket name %q cannot start with %q", name, "goog")
+	}
+	if gcsNameGoogleRegexp.MatchString(name) {
+		return fmt.Errorf("bucket name %q cannot contain %q or a close misspelling of it", name, "google")
+	}
 	return nil
 }
+
+func isGCSNameAlphanumeric(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('0' <= c && c <= '9')
+}
@@ -1,6 +1,7 @@
 package google
 
 import (
+	"fmt"
 	"net/url"
 	"reflect"
 	"strings"
@@ -8,6 +9,8 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/go-cty/cty/gocty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"google.golang.org/api/googleapi"
 )
@@ -26,6 +29,18 @@ func TestConvertStringArr(t *testing.T) {
 	}
 }
 
+func TestConvertStringArr_nil(t *testing.T) {
+	if actual := convertStringArr([]interface{}{"aaa", nil, "bbb"}); !reflect.DeepEqual(actual, []string{"aaa", "bbb"}) {
+		t.Errorf("expected nil elements to be skipped, got %q", actual)
+	}
+	if actual := convertStringArr([]interface{}{nil}); actual != nil {
+		t.Errorf("expected nil for a list without strings, got %q", actual)
+	}
+	if actual := convertStringArr(nil); actual != nil {
+		t.Errorf("expected nil for an empty list, got %q", actual)
+	}
+}
+
 func TestConvertAndMapStringArr(t *testing.T) {
 	input := make([]interface{}, 3)
 	input[0] = "aaa"
@@ -54,6 +69,72 @@ func TestConvertStringMap(t *testing.T) {
 	}
 }
 
+func TestExpandStringMapWithValidation(t *testing.T) {
+	cases := map[string]struct {
+		Labels      map[string]interface{}
+		ValidateKey stringMapKeyFunc
+		Expected    map[string]string
+		ExpectErrs  []string
+	}{
+		"valid": {
+			Labels:      map[string]interface{}{"env": "prod", "team_name": "", "ключ": "значение"},
+			ValidateKey: validateLabelKey,
+			Expected:    map[string]string{"env": "prod", "team_name": "", "ключ": "значение"},
+		},
+		"empty": {
+			Labels:      map[string]interface{}{},
+			ValidateKey: validateLabelKey,
+			Expected:    map[string]string{},
+		},
+		"invalid entries": {
+			Labels:      map[string]interface{}{"Env": "prod", "1team": "a", "tier": "Gold"},
+			ValidateKey: validateLabelKey,
+			ExpectErrs:  []string{`labels["1team"]: invalid key`, `labels["Env"]: invalid key`, `labels["tier"]: invalid value "Gold"`},
+		},
+		"international first letter": {
+			Labels:      map[string]interface{}{"日本": "東京"},
+			ValidateKey: validateLabelKey,
+			Expected:    map[string]string{"日本": "東京"},
+		},
+		"lowercased keys": {
+			Labels:      map[string]interface{}{"Env": "prod"},
+			ValidateKey: lowercaseStringMapKey(validateLabelKey),
+			Expected:    map[string]string{"env": "prod"},
+		},
+		"lowercased keys collide": {
+			Labels:      map[string]interface{}{"Env": "prod", "env": "prod"},
+			ValidateKey: lowercaseStringMapKey(validateLabelKey),
+			ExpectErrs:  []string{`labels["env"]: key is the same as labels["Env"]`},
+		},
+	}
+
+	for tn, tc := range cases {
+		d := &ResourceDataMock{
+			FieldsInSchema: map[string]interface{}{"labels": tc.Labels},
+		}
+		actual, err := expandStringMapWithValidation(d, "labels", tc.ValidateKey, validateLabelValue)
+		if len(tc.ExpectErrs) > 0 {
+			if err == nil {
+				t.Errorf("%s: expected errors, got %v", tn, actual)
+				continue
+			}
+			for _, e := range tc.ExpectErrs {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("%s: expected error to contain %q, got %s", tn, e, err)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tn, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc.Expected, actual) {
+			t.Errorf("%s: expected %v, got %v", tn, tc.Expected, actual)
+		}
+	}
+}
+
 func TestIpCidrRangeDiffSuppress(t *testing.T) {
 	cases := map[string]struct {
 		Old, New           string
@@ -598,6 +679,174 @@ func TestConflictError(t *testing.T) {
 	// skipping negative tests as other cases may be added later.
 }
 
+// API errors as returned by real services, keyed by a description of each.
+var errorReasonFixtures = map[string]*googleapi.Error{
+	"api not enabled": {
+		Code:    403,
+		Message: "Compute Engine API has not been used in project 123 before or it is disabled.",
+		Errors:  []googleapi.ErrorItem{{Reason: errorReasonAccessNotConfigured}},
+	},
+	"failed precondition": {
+		Code:   400,
+		Errors: []googleapi.ErrorItem{{Reason: errorReasonFailedPrecondition}},
+	},
+	"rate limited": {
+		Code:   403,
+		Errors: []googleapi.ErrorItem{{Reason: errorReasonRateLimitExceeded}},
+	},
+	"user rate limited": {
+		Code:   403,
+		Errors: []googleapi.ErrorItem{{Reason: "forbidden"}, {Reason: errorReasonUserRateLimitExceeded}},
+	},
+	"quota exceeded": {
+		Code:   403,
+		Errors: []googleapi.ErrorItem{{Reason: errorReasonQuotaExceeded}},
+	},
+	"condition not met": {
+		Code:   412,
+		Errors: []googleapi.ErrorItem{{Reason: errorReasonConditionNotMet}},
+	},
+	"permission denied": {
+		Code:   403,
+		Errors: []googleapi.ErrorItem{{Reason: "forbidden"}},
+	},
+}
+
+func TestErrorHasReason(t *testing.T) {
+	cases := []struct {
+		fixture  string
+		code     int
+		reasons  []string
+		expected bool
+	}{
+		{"api not enabled", 403, []string{errorReasonAccessNotConfigured}, true},
+		{"api not enabled", 400, []string{errorReasonAccessNotConfigured}, false},
+		{"api not enabled", 0, []string{errorReasonAccessNotConfigured}, true},
+		{"rate limited", 403, []string{errorReasonRateLimitExceeded, errorReasonUserRateLimitExceeded}, true},
+		{"user rate limited", 403, []string{errorReasonRateLimitExceeded, errorReasonUserRateLimitExceeded}, true},
+		{"quota exceeded", 403, []string{errorReasonRateLimitExceeded, errorReasonUserRateLimitExceeded}, false},
+		{"condition not met", 412, []string{errorReasonConditionNotMet}, true},
+		{"permission denied", 403, []string{errorReasonAccessNotConfigured}, false},
+	}
+
+	for _, tc := range cases {
+		err := errorReasonFixtures[tc.fixture]
+		if got := errorHasReason(err, tc.code, tc.reasons...); got != tc.expected {
+			t.Errorf("%s: expected errorHasReason(%d, %v) to be %t, got %t", tc.fixture, tc.code, tc.reasons, tc.expected, got)
+		}
+		if got := errorHasReason(errwrap.Wrapf("wrapped: {{err}}", err), tc.code, tc.reasons...); got != tc.expected {
+			t.Errorf("%s: expected errorHasReason(%d, %v) of wrapped error to be %t, got %t", tc.fixture, tc.code, tc.reasons, tc.expected, got)
+		}
+	}
+
+	if errorHasReason(nil, 0, errorReasonQuotaExceeded) {
+		t.Error("expected nil error not to have a reason")
+	}
+}
+
+func TestErrorReasonPredicates(t *testing.T) {
+	for name, err := range errorReasonFixtures {
+		if got, expected := isApiNotEnabledError(err), name == "api not enabled"; got != expected {
+			t.Errorf("%s: expected isApiNotEnabledError to be %t, got %t", name, expected, got)
+		}
+		if got, expected := isFailedPreconditionError(err), name == "failed precondition"; got != expected {
+			t.Errorf("%s: expected isFailedPreconditionError to be %t, got %t", name, expected, got)
+		}
+		if got, _ := is403QuotaExceededPerMinuteError(err); got != (name == "rate limited" || name == "user rate limited") {
+			t.Errorf("%s: unexpected is403QuotaExceededPerMinuteError result %t", name, got)
+		}
+		if got, _ := isFingerprintError(err); got != (name == "condition not met") {
+			t.Errorf("%s: unexpected isFingerprintError result %t", name, got)
+		}
+	}
+}
+
+func TestHandleNotFoundError_nestedObject(t *testing.T) {
+	err := fake404(nestedObjectNotFoundInParent, "https://compute.googleapis.com/compute/v1/projects/p/regions/r/routers/router", "ComputeRouterNat", "p/r/router/nat")
+	if !isGoogleApiErrorWithCode(err, 404) {
+		t.Errorf("expected a missing nested object to be a 404")
+	}
+	if !isGoogleApiErrorWithCode(errwrap.Wrapf("wrapped: {{err}}", err), 404) {
+		t.Errorf("expected a wrapped missing nested object to be a 404")
+	}
+
+	s := map[string]*schema.Schema{"name": {Type: schema.TypeString, Optional: true}}
+	d := schema.TestResourceDataRaw(t, s, map[string]interface{}{})
+	d.SetId("p/r/router/nat")
+	if err := handleNotFoundError(fmt.Errorf("deleting: %w", err), d, "RouterNat"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Id() != "" {
+		t.Errorf("expected the missing nested object to be removed from state")
+	}
+}
+
+func TestProviderMetaUserAgentTokens(t *testing.T) {
+	moduleName := "my-module"
+	team := "payments"
+	costCenter := "cc 1234"
+	empty := ""
+
+	cases := map[string]struct {
+		meta     providerMeta
+		expected []string
+	}{
+		"module name only": {
+			meta:     providerMeta{ModuleName: &moduleName},
+			expected: []string{},
+		},
+		"team and cost center": {
+			meta:     providerMeta{Team: &team, CostCenter: &costCenter},
+			expected: []string{"cost-center/cc_1234", "team/payments"},
+		},
+		"request tags": {
+			meta: providerMeta{
+				Team:        &empty,
+				RequestTags: map[string]string{"env": "prod", "owner": "a@b.com", "empty": ""},
+			},
+			expected: []string{"env/prod", "owner/a_b.com"},
+		},
+		"team overrides tag": {
+			meta: providerMeta{
+				Team:        &team,
+				RequestTags: map[string]string{"team": "other"},
+			},
+			expected: []string{"team/payments"},
+		},
+	}
+
+	for tn, tc := range cases {
+		if got := providerMetaUserAgentTokens(tc.meta); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tn, tc.expected, got)
+		}
+	}
+}
+
+func TestProviderMeta_decodesNullAttributes(t *testing.T) {
+	// provider_meta blocks are decoded like this by GetProviderMeta, with
+	// unset attributes null
+	v := cty.ObjectVal(map[string]cty.Value{
+		"module_name":  cty.NullVal(cty.String),
+		"team":         cty.StringVal("payments"),
+		"cost_center":  cty.NullVal(cty.String),
+		"request_tags": cty.NullVal(cty.Map(cty.String)),
+	})
+
+	var m providerMeta
+	if err := gocty.FromCtyValue(v, &m); err != nil {
+		t.Fatalf("unexpected error decoding provider_meta: %s", err)
+	}
+	if m.ModuleName != nil {
+		t.Errorf("expected no module name, got %q", *m.ModuleName)
+	}
+	if m.Team == nil || *m.Team != "payments" {
+		t.Errorf("expected team payments, got %v", m.Team)
+	}
+	if got := providerMetaUserAgentTokens(m); !reflect.DeepEqual(got, []string{"team/payments"}) {
+		t.Errorf("expected tokens [team/payments], got %v", got)
+	}
+}
+
 func TestSnakeToPascalCase(t *testing.T) {
 	input := "boot_disk"
 	expected := "BootDisk"
@@ -607,3 +856,44 @@ func TestSnakeToPascalCase(t *testing.T) {
 		t.Fatalf("(%s) did not match expected value: %s", actual, expected)
 	}
 }
+
+// Large inputs, as read from eg GKE node pools or instance metadata.
+const benchmarkConvertSize = 10000
+
+func benchmarkStringList() []interface{} {
+	l := make([]interface{}, benchmarkConvertSize)
+	for i := range l {
+		l[i] = fmt.Sprintf("value-%d", i)
+	}
+	return l
+}
+
+func BenchmarkConvertStringMap(b *testing.B) {
+	m := make(map[string]interface{}, benchmarkConvertSize)
+	for i := 0; i < benchmarkConvertSize; i++ {
+		m[fmt.Sprintf("key-%d", i)] = fmt.Sprintf("value-%d", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertStringMap(m)
+	}
+}
+
+func BenchmarkConvertStringArr(b *testing.B) {
+	l := benchmarkStringList()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertStringArr(l)
+	}
+}
+
+func BenchmarkConvertStringSet(b *testing.B) {
+	set := schema.NewSet(schema.HashString, benchmarkStringList())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		convertStringSet(set)
+	}
+}
@@ -188,6 +188,21 @@ func validateIAMCustomRoleID(v interface{}, k string) (warnings []string, errors
 	return
 }
 
+// validateGCSName validates the name of a Cloud Storage bucket, see
+// checkGCSName. Names containing dots are domain names, so it warns that
+// creating them requires verifying the ownership of the domain.
+func validateGCSName(v interface{}, k string) (warnings []string, errors []error) {
+	value := v.(string)
+	if err := checkGCSName(value); err != nil {
+		errors = append(errors, fmt.Errorf("%q: %s", k, err))
+		return
+	}
+	if strings.Contains(value, ".") {
+		warnings = append(warnings, fmt.Sprintf("%q (%q) contains dots, so creating it requires the ownership of the domain to be verified, see https://cloud.google.com/storage/docs/domain-name-verification", k, value))
+	}
+	return
+}
+
 func orEmpty(f schema.SchemaValidateFunc) schema.SchemaValidateFunc {
 	return func(i interface{}, k string) ([]string, []error) {
 		v, ok := i.(string)
@@ -339,3 +354,28 @@ func validateADDomainName() schema.SchemaValidateFunc {
 		return
 	}
 }
+
+var (
+	// https://cloud.google.com/resource-manager/docs/creating-managing-labels#requirements
+	labelKeyFormatRegex   = regexp.MustCompile(`^[\p{Ll}\p{Lo}][\p{Ll}\p{Lo}\p{N}_-]{0,62}$`)
+	labelValueFormatRegex = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}_-]{0,63}$`)
+)
+
+// validateLabelKey is a stringMapKeyFunc for label keys, which must be 1-63
+// lowercase letters, digits, underscores and hyphens, starting with a
+// lowercase or international letter.
+func validateLabelKey(key string) (string, error) {
+	if !labelKeyFormatRegex.MatchString(key) {
+		return "", fmt.Errorf("label keys must be 1-63 lowercase letters, digits, underscores or hyphens, starting with a lowercase or international letter")
+	}
+	return key, nil
+}
+
+// validateLabelValue is a stringMapValueFunc for label values, which may be
+// empty or up to 63 lowercase letters, digits, underscores and hyphens.
+func validateLabelValue(value string) error {
+	if !labelValueFormatRegex.MatchString(value) {
+		return fmt.Errorf("label values must be at most 63 lowercase letters, digits, underscores or hyphens")
+	}
+	return nil
+}
@@ -322,3 +322,50 @@ func TestValidateIAMCustomRoleIDRegex(t *testing.T) {
 		t.Errorf("Failed to validate IAMCustomRole IDs: %v", es)
 	}
 }
+
+func TestValidateGCSName(t *testing.T) {
+	x := []StringValidationTestCase{
+		// No errors
+		{TestName: "basic", Value: "foobar"},
+		{TestName: "short", Value: "foo"},
+		{TestName: "long", Value: strings.Repeat("f", 63)},
+		{TestName: "has a hyphen", Value: "foo-bar"},
+		{TestName: "has an underscore", Value: "foo_bar"},
+		{TestName: "starts with a number", Value: "1foobar"},
+		{TestName: "domain", Value: "image-store.com"},
+		{TestName: "long domain", Value: strings.Repeat(strings.Repeat("f", 63)+".", 3) + strings.Repeat("f", 30)},
+		{TestName: "numbers with dots", Value: "1.2.3.4.5"},
+		{TestName: "component ends with a hyphen", Value: "foo-.bar"},
+		{TestName: "component starts with an underscore", Value: "foo._bar"},
+
+		// With errors
+		{TestName: "empty", Value: "", ExpectError: true},
+		{TestName: "too short", Value: "fo", ExpectError: true},
+		{TestName: "too long", Value: strings.Repeat("f", 64), ExpectError: true},
+		{TestName: "has a capital", Value: "fooBar", ExpectError: true},
+		{TestName: "has a space", Value: "foo bar", ExpectError: true},
+		{TestName: "starts with a hyphen", Value: "-foobar", ExpectError: true},
+		{TestName: "ends with an underscore", Value: "foobar_", ExpectError: true},
+		{TestName: "consecutive dots", Value: "foo..bar", ExpectError: true},
+		{TestName: "long component", Value: strings.Repeat("f", 64) + ".com", ExpectError: true},
+		{TestName: "too long domain", Value: strings.Repeat(strings.Repeat("f", 63)+".", 3) + strings.Repeat("f", 31), ExpectError: true},
+		{TestName: "IP address", Value: "192.168.5.4", ExpectError: true},
+		{TestName: "starts with goog", Value: "goog-bucket", ExpectError: true},
+		{TestName: "contains google", Value: "my-google-bucket", ExpectError: true},
+		{TestName: "contains a misspelling of google", Value: "my-g00gle-bucket", ExpectError: true},
+	}
+
+	es := testStringValidationCases(x, validateGCSName)
+	if len(es) > 0 {
+		t.Errorf("Failed to validate GCS names: %v", es)
+	}
+
+	ws, _ := validateGCSName("image-store.com", "name")
+	if len(ws) != 1 {
+		t.Errorf("Expected a warning to verify the domain of a name containing dots, got %v", ws)
+	}
+	ws, _ = validateGCSName("image-store", "name")
+	if len(ws) != 0 {
+		t.Errorf("Expected no warnings for a name without dots, got %v", ws)
+	}
+}
@@ -100,19 +100,14 @@ func assetName(d TerraformResourceData, config *Config, linkTmpl string) (string
 	re := regexp.MustCompile("{{([[:word:]]+)}}")
 
 	// workaround for empty project
-	placeholderSet := false
 	if config.Project == "" {
-		config.Project = fmt.Sprintf("placeholder-%s", randString(8))
-		placeholderSet = true
+		config = config.WithOverrides(fmt.Sprintf("placeholder-%s", randString(8)), "", "")
 	}
 
 	f, err := buildReplacementFunc(re, d, config, linkTmpl, false)
 	if err != nil {
 		return "", err
 	}
-	if placeholderSet {
-		config.Project = ""
-	}
 
 	fWithPlaceholder := func(key string) string {
 		val := f(key)